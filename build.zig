@@ -83,32 +83,147 @@ pub fn build(b: *std.Build) void {
     const run_step = b.step("run", "Run Rewind");
     run_step.dependOn(&run_rewind.step);
 
-    // Core module with ECS and rollback (will be used later for display)
-    _ = b.createModule(.{
-        .root_source_file = b.path("src/core/ecs.zig"),
+    // Core module with ECS, rollback and debugging tools - imported by tools outside src/core,
+    // and exported as `rewind-core` so dependent packages can `b.dependency("rewind", ...).module("rewind-core")`
+    const core_module = b.addModule("rewind-core", .{
+        .root_source_file = b.path("src/core/root.zig"),
     });
 
-    // ECS Test
-    const ecs_test = b.addTest(.{
-        .root_source_file = b.path("src/core/ecs_test.zig"),
+    // Core tests - each gets its own step plus membership in `zig build test`
+    const test_all_step = b.step("test", "Run all tests");
+    const core_tests = [_]struct { step: []const u8, path: []const u8, description: []const u8 }{
+        .{ .step = "test-ecs", .path = "src/core/ecs_test.zig", .description = "Run ECS tests" },
+        .{ .step = "test-rollback", .path = "src/core/rollback_test.zig", .description = "Run rollback tests" },
+        .{ .step = "test-trace", .path = "src/core/trace_test.zig", .description = "Run structural trace log tests" },
+        .{ .step = "test-query-analyzer", .path = "src/core/query_analyzer_test.zig", .description = "Run query analyzer tests" },
+        .{ .step = "test-metrics", .path = "src/core/metrics_test.zig", .description = "Run metrics export tests" },
+        .{ .step = "test-schedule", .path = "src/core/schedule_test.zig", .description = "Run system schedule tests" },
+        .{ .step = "test-leak-detector", .path = "src/core/leak_detector_test.zig", .description = "Run leak detector tests" },
+        .{ .step = "test-memory-report", .path = "src/core/memory_report_test.zig", .description = "Run memory report tests" },
+        .{ .step = "test-spatial", .path = "src/core/spatial_test.zig", .description = "Run spatial index tests" },
+        .{ .step = "test-collision", .path = "src/core/collision_test.zig", .description = "Run collision tests" },
+        .{ .step = "test-raycast", .path = "src/core/raycast_test.zig", .description = "Run raycast and shapecast tests" },
+        .{ .step = "test-physics", .path = "src/core/physics_test.zig", .description = "Run physics tests" },
+        .{ .step = "test-hierarchy", .path = "src/core/hierarchy_test.zig", .description = "Run transform hierarchy tests" },
+        .{ .step = "test-steering", .path = "src/core/steering_test.zig", .description = "Run steering behavior tests" },
+        .{ .step = "test-pathfinding", .path = "src/core/pathfinding_test.zig", .description = "Run pathfinding tests" },
+        .{ .step = "test-timers", .path = "src/core/timers_test.zig", .description = "Run timer tests" },
+        .{ .step = "test-animation", .path = "src/core/animation_test.zig", .description = "Run animation state machine tests" },
+        .{ .step = "test-particles", .path = "src/core/particles_test.zig", .description = "Run particle tests" },
+        .{ .step = "test-lag-compensation", .path = "src/core/lag_compensation_test.zig", .description = "Run lag compensation tests" },
+        .{ .step = "test-movement", .path = "src/core/movement_test.zig", .description = "Run movement integration tests" },
+        .{ .step = "test-schema", .path = "src/core/schema_test.zig", .description = "Run component schema registry tests" },
+        .{ .step = "test-script", .path = "src/core/script_test.zig", .description = "Run scripting VM tests" },
+        .{ .step = "test-mirror", .path = "src/core/mirror_test.zig", .description = "Run TypeScript/C# mirror generation tests" },
+        .{ .step = "test-tiled", .path = "src/core/tiled_test.zig", .description = "Run Tiled map importer tests" },
+        .{ .step = "test-arrow", .path = "src/core/arrow_test.zig", .description = "Run Arrow export tests" },
+        .{ .step = "test-bitset-kernels", .path = "src/core/bitset_kernels_test.zig", .description = "Run bitset kernel tests" },
+        .{ .step = "test-strict-allocator", .path = "src/core/strict_allocator_test.zig", .description = "Run strict allocation mode tests" },
+        .{ .step = "test-pipeline", .path = "src/core/pipeline_test.zig", .description = "Run pipelined snapshot tests" },
+        .{ .step = "test-command-inbox", .path = "src/core/command_inbox_test.zig", .description = "Run command inbox tests" },
+        .{ .step = "test-packing", .path = "src/core/packing_test.zig", .description = "Run hot component packing tests" },
+        .{ .step = "test-memory-budget", .path = "src/core/memory_budget_test.zig", .description = "Run memory budget tests" },
+        .{ .step = "test-logger", .path = "src/core/logger_test.zig", .description = "Run structured logging tests" },
+        .{ .step = "test-clock", .path = "src/core/clock_test.zig", .description = "Run clock and tick pacing tests" },
+        .{ .step = "test-config", .path = "src/core/config_test.zig", .description = "Run config loading tests" },
+        .{ .step = "test-frame-history", .path = "src/core/frame_history_test.zig", .description = "Run frame history rollback tests" },
+        .{ .step = "test-command-buffer", .path = "src/core/command_buffer_test.zig", .description = "Run deferred command buffer tests" },
+        .{ .step = "test-events", .path = "src/core/events_test.zig", .description = "Run event channel tests" },
+        .{ .step = "test-replay", .path = "src/core/replay_test.zig", .description = "Run input log and replay tests" },
+        .{ .step = "test-netcode", .path = "src/core/netcode_test.zig", .description = "Run rollback and lockstep netcode session tests" },
+        .{ .step = "test-random", .path = "src/core/random_test.zig", .description = "Run deterministic RNG tests" },
+        .{ .step = "test-diff", .path = "src/core/diff_test.zig", .description = "Run frame state diff tests" },
+        .{ .step = "test-flatbuffers", .path = "src/core/flatbuffers_test.zig", .description = "Run FlatBuffers snapshot codec tests" },
+        .{ .step = "test-compression", .path = "src/core/compression_test.zig", .description = "Run snapshot and packet compression tests" },
+        .{ .step = "test-relevancy", .path = "src/core/relevancy_test.zig", .description = "Run interest management tests" },
+        .{ .step = "test-prediction", .path = "src/core/prediction_test.zig", .description = "Run client-side prediction tests" },
+        .{ .step = "test-names", .path = "src/core/names_test.zig", .description = "Run entity name tests" },
+        .{ .step = "test-websocket", .path = "src/core/websocket_test.zig", .description = "Run WebSocket transport tests" },
+    };
+    for (core_tests) |core_test| {
+        const test_exe = b.addTest(.{
+            .root_source_file = b.path(core_test.path),
+            .target = target,
+            .optimize = optimize,
+        });
+
+        const run_test = b.addRunArtifact(test_exe);
+        const test_step = b.step(core_test.step, core_test.description);
+        test_step.dependOn(&run_test.step);
+        test_all_step.dependOn(&run_test.step);
+    }
+
+    // C API for embedding the simulation core in native engines
+    const capi_lib = b.addSharedLibrary(.{
+        .name = "rewind",
+        .root_source_file = b.path("src/capi/rewind_c.zig"),
+        .target = target,
+        .optimize = optimize,
+    });
+    capi_lib.root_module.addImport("rewind-core", core_module);
+    capi_lib.linkLibC();
+    const install_capi = b.addInstallArtifact(capi_lib, .{});
+    const install_header = b.addInstallHeaderFile(b.path("src/capi/rewind.h"), "rewind.h");
+    const capi_step = b.step("capi", "Build the C API shared library and header");
+    capi_step.dependOn(&install_capi.step);
+    capi_step.dependOn(&install_header.step);
+
+    const capi_tests = b.addTest(.{
+        .root_source_file = b.path("src/capi/rewind_c_test.zig"),
         .target = target,
         .optimize = optimize,
     });
+    capi_tests.root_module.addImport("rewind-core", core_module);
+    capi_tests.linkLibC();
+    const run_capi_tests = b.addRunArtifact(capi_tests);
+    const capi_test_step = b.step("test-capi", "Run C API tests");
+    capi_test_step.dependOn(&run_capi_tests.step);
+    test_all_step.dependOn(&run_capi_tests.step);
 
-    const run_ecs_test = b.addRunArtifact(ecs_test);
-    const ecs_test_step = b.step("test-ecs", "Run ECS tests");
-    ecs_test_step.dependOn(&run_ecs_test.step);
+    // Inspector CLI
+    const inspect_exe = b.addExecutable(.{
+        .name = "rewind-inspect",
+        .root_source_file = b.path("src/tools/inspect.zig"),
+        .target = target,
+        .optimize = optimize,
+    });
+    inspect_exe.root_module.addImport("rewind-core", core_module);
+    b.installArtifact(inspect_exe);
+
+    const run_inspect = b.addRunArtifact(inspect_exe);
+    if (b.args) |args| run_inspect.addArgs(args);
+    const inspect_step = b.step("inspect", "Run rewind-inspect (pass the command after --)");
+    inspect_step.dependOn(&run_inspect.step);
+
+    // Headless dedicated server
+    const server_exe = b.addExecutable(.{
+        .name = "rewind-server",
+        .root_source_file = b.path("src/tools/server.zig"),
+        .target = target,
+        .optimize = optimize,
+    });
+    server_exe.root_module.addImport("rewind-core", core_module);
+    b.installArtifact(server_exe);
 
-    // Rollback Test
-    const rollback_test = b.addTest(.{
-        .root_source_file = b.path("src/core/rollback_test.zig"),
+    const run_server = b.addRunArtifact(server_exe);
+    if (b.args) |args| run_server.addArgs(args);
+    const server_step = b.step("server", "Run rewind-server (pass options after --)");
+    server_step.dependOn(&run_server.step);
+
+    // Cross-implementation determinism harness
+    const parity_exe = b.addExecutable(.{
+        .name = "rewind-parity",
+        .root_source_file = b.path("src/tools/parity.zig"),
         .target = target,
         .optimize = optimize,
     });
+    parity_exe.root_module.addImport("rewind-core", core_module);
+    b.installArtifact(parity_exe);
 
-    const run_rollback_test = b.addRunArtifact(rollback_test);
-    const rollback_test_step = b.step("test-rollback", "Run rollback tests");
-    rollback_test_step.dependOn(&run_rollback_test.step);
+    const run_parity = b.addRunArtifact(parity_exe);
+    if (b.args) |args| run_parity.addArgs(args);
+    const parity_step = b.step("parity", "Run rewind-parity (pass options and the peer command after --)");
+    parity_step.dependOn(&run_parity.step);
 
     // ECS Performance Test
     const ecs_perf_exe = b.addExecutable(.{
@@ -134,8 +249,51 @@ pub fn build(b: *std.Build) void {
     const rollback_perf_step = b.step("perf-rollback", "Run rollback performance test");
     rollback_perf_step.dependOn(&run_rollback_perf.step);
 
-    // All tests
-    const test_all_step = b.step("test", "Run all tests");
-    test_all_step.dependOn(&run_ecs_test.step);
-    test_all_step.dependOn(&run_rollback_test.step);
+    // Spatial Index Performance Test
+    const spatial_perf_exe = b.addExecutable(.{
+        .name = "spatial-perf",
+        .root_source_file = b.path("src/core/spatial_perf_test.zig"),
+        .target = target,
+        .optimize = .ReleaseFast,
+    });
+
+    const run_spatial_perf = b.addRunArtifact(spatial_perf_exe);
+    const spatial_perf_step = b.step("perf-spatial", "Compare grid and quadtree under uniform and clustered worlds");
+    spatial_perf_step.dependOn(&run_spatial_perf.step);
+
+    // Physics Performance Test
+    const physics_perf_exe = b.addExecutable(.{
+        .name = "physics-perf",
+        .root_source_file = b.path("src/core/physics_perf_test.zig"),
+        .target = target,
+        .optimize = .ReleaseFast,
+    });
+
+    const run_physics_perf = b.addRunArtifact(physics_perf_exe);
+    const physics_perf_step = b.step("perf-physics", "Benchmark physics steps at 1k to 10k bodies");
+    physics_perf_step.dependOn(&run_physics_perf.step);
+
+    // Bitset Kernel Performance Test
+    const bitset_perf_exe = b.addExecutable(.{
+        .name = "bitset-perf",
+        .root_source_file = b.path("src/core/bitset_perf_test.zig"),
+        .target = target,
+        .optimize = .ReleaseFast,
+    });
+
+    const run_bitset_perf = b.addRunArtifact(bitset_perf_exe);
+    const bitset_perf_step = b.step("perf-bitset", "Compare scalar and vector bitset kernels at massive entity scale");
+    bitset_perf_step.dependOn(&run_bitset_perf.step);
+
+    // Entity Scale Performance Test
+    const scale_perf_exe = b.addExecutable(.{
+        .name = "scale-perf",
+        .root_source_file = b.path("src/core/scale_perf_test.zig"),
+        .target = target,
+        .optimize = .ReleaseFast,
+    });
+
+    const run_scale_perf = b.addRunArtifact(scale_perf_exe);
+    const scale_perf_step = b.step("perf-scale", "Benchmark worlds of 100k and 1M entities");
+    scale_perf_step.dependOn(&run_scale_perf.step);
 }
\ No newline at end of file
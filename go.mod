@@ -0,0 +1,3 @@
+module github.com/ryanharbert/rewind
+
+go 1.24
@@ -0,0 +1,663 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryanharbert/rewind/legacy/ecs-perf-test/ecs"
+)
+
+// What the harness needs from an ECS implementation; setup, timing and verification are shared
+type benchWorld interface {
+	// Spawn entity number i from a prefab
+	Spawn(i int, prefab ecs.Prefab, overrides ...ecs.PrefabOverride) error
+	// Run the transform and damage systems once
+	Step()
+	// X position and health of the first spawned entity (health -1 when it has none)
+	Probe() (x float64, health float64)
+	// The systems of Step one by one, so profiles can attribute time to each
+	Systems() []benchSystem
+}
+
+// One system of a frame, named for pprof labels and trace regions
+type benchSystem struct {
+	name string
+	run  func()
+}
+
+// Implementations selectable with -impl, keyed by name
+var implementations = map[string]func(capacity int) benchWorld{
+	"ultra":     newUltraBench,
+	"query":     newQueryBench,
+	"cached":    newCachedBench,
+	"join":      newJoinBench,
+	"range":     newRangeBench,
+	"archetype": func(capacity int) benchWorld { return newWorldBench(ecs.NewWorld(capacity, ecs.WithArchetypes())) },
+}
+
+type benchConfig struct {
+	impl        string
+	entities    []int
+	frames      int
+	warmup      int
+	velocityPct int
+	healthPct   int
+	format      string
+	label       string
+	cpuProfile  string
+	memProfile  string
+	tracePath   string
+}
+
+// Profiling and tracing label every system of the timed frames
+func (cfg benchConfig) labeled() bool {
+	return cfg.cpuProfile != "" || cfg.tracePath != ""
+}
+
+func implementationNames() []string {
+	names := make([]string, 0, len(implementations))
+	for name := range implementations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseFlags(args []string, output io.Writer) (benchConfig, error) {
+	cfg := benchConfig{}
+	flags := flag.NewFlagSet("ecs-bench", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&cfg.impl, "impl", "ultra", "ECS implementation: "+strings.Join(implementationNames(), "|"))
+	entities := flags.String("entities", "100,250,500,750,1000", "comma-separated entity counts")
+	flags.IntVar(&cfg.frames, "frames", 10000, "frames to time per entity count")
+	flags.IntVar(&cfg.warmup, "warmup", 100, "untimed frames before timing")
+	flags.IntVar(&cfg.velocityPct, "velocity", 60, "percentage of entities that move")
+	flags.IntVar(&cfg.healthPct, "health", 40, "percentage of entities with health")
+	flags.StringVar(&cfg.format, "format", "text", "output: text|csv|json")
+	flags.StringVar(&cfg.label, "label", "", "free-form tag stored with csv/json results, e.g. the commit")
+	flags.StringVar(&cfg.cpuProfile, "cpuprofile", "", "write a CPU profile of the run to this file")
+	flags.StringVar(&cfg.memProfile, "memprofile", "", "write a heap profile at the end of the run to this file")
+	flags.StringVar(&cfg.tracePath, "trace", "", "write an execution trace of the run to this file")
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if _, ok := implementations[cfg.impl]; !ok {
+		return cfg, fmt.Errorf("unknown -impl %q (available: %s)", cfg.impl, strings.Join(implementationNames(), ", "))
+	}
+	for _, field := range strings.Split(*entities, ",") {
+		count, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || count <= 0 {
+			return cfg, fmt.Errorf("invalid entity count %q", field)
+		}
+		cfg.entities = append(cfg.entities, count)
+	}
+	if cfg.format != "text" && cfg.format != "csv" && cfg.format != "json" {
+		return cfg, fmt.Errorf("unknown -format %q (text, csv or json)", cfg.format)
+	}
+	if cfg.frames <= 0 || cfg.warmup < 0 {
+		return cfg, errors.New("-frames must be positive and -warmup not negative")
+	}
+	for _, pct := range []int{cfg.velocityPct, cfg.healthPct} {
+		if pct < 0 || pct > 100 {
+			return cfg, fmt.Errorf("percentage %d outside 0-100", pct)
+		}
+	}
+	return cfg, nil
+}
+
+// True for pct percent of indices, spread evenly instead of clustered at the start (and
+// always for the first entity unless pct is 0, so its values can be verified)
+func inShare(i, pct int) bool {
+	return i*pct%100 < pct
+}
+
+// Every benchmark entity; the configured shares drop velocity and health from some of them
+const benchPrefabJSON = `[{
+	"name": "unit",
+	"transform": {},
+	"velocity": {"DX": 1.0, "DY": 0.5, "DZ": 0.25},
+	"health": {"Value": 100.0}
+}]`
+
+var benchPrefab = func() ecs.Prefab {
+	prefabs, err := ecs.LoadPrefabs(strings.NewReader(benchPrefabJSON))
+	if err != nil {
+		panic(err)
+	}
+	return prefabs["unit"]
+}()
+
+// A fresh world of the implementation with entityCount entities in the configured mix
+func spawnWorld(impl string, entityCount, velocityPct, healthPct int) benchWorld {
+	world := implementations[impl](entityCount + 100)
+	for i := 0; i < entityCount; i++ {
+		overrides := []ecs.PrefabOverride{ecs.WithTransform(ecs.Transform{X: float32(i % 100), Y: float32(i / 100)})}
+		if !inShare(i, velocityPct) {
+			overrides = append(overrides, ecs.Without(ecs.VelocityComponent))
+		}
+		if !inShare(i, healthPct) {
+			overrides = append(overrides, ecs.Without(ecs.HealthComponent))
+		}
+		// Bench worlds have no entity limit, so spawning only fails on a broken implementation
+		if err := world.Spawn(i, benchPrefab, overrides...); err != nil {
+			panic(err)
+		}
+	}
+	return world
+}
+
+// One entity count's measurements
+type benchResult struct {
+	Implementation string  `json:"implementation"`
+	Entities       int     `json:"entities"`
+	Frames         int     `json:"frames"`
+	SetupMs        float64 `json:"setup_ms"`
+	TotalMs        float64 `json:"total_ms"`
+	AvgFrameMs     float64 `json:"avg_frame_ms"`
+	P99FrameMs     float64 `json:"p99_frame_ms"`
+	AllocsPerFrame float64 `json:"allocs_per_frame"`
+	BytesPerFrame  float64 `json:"bytes_per_frame"`
+	GCCycles       uint32  `json:"gc_cycles"`
+	GCPauseMs      float64 `json:"gc_pause_ms"`
+
+	initialX, finalX           float64
+	initialHealth, finalHealth float64
+}
+
+// Where a run happened, so results from different machines and commits can sit side by side
+type machineInfo struct {
+	Label      string `json:"label,omitempty"`
+	Timestamp  string `json:"timestamp"`
+	GoVersion  string `json:"go_version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	NumCPU     int    `json:"num_cpu"`
+	CPU        string `json:"cpu"`
+}
+
+func currentMachine(label string) machineInfo {
+	return machineInfo{
+		Label:      label,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		CPU:        cpuModel(),
+	}
+}
+
+// CPU model name from /proc/cpuinfo where there is one, the architecture otherwise
+func cpuModel() string {
+	info, err := os.ReadFile("/proc/cpuinfo")
+	if err == nil {
+		for _, line := range strings.Split(string(info), "\n") {
+			key, value, found := strings.Cut(line, ":")
+			if found && strings.TrimSpace(key) == "model name" {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return runtime.GOARCH
+}
+
+// Time every frame on its own for the percentile; allocation and GC counts cover the whole loop
+func measure(cfg benchConfig, entityCount int) benchResult {
+	result := benchResult{Implementation: cfg.impl, Entities: entityCount, Frames: cfg.frames}
+
+	setupStart := time.Now()
+	world := spawnWorld(cfg.impl, entityCount, cfg.velocityPct, cfg.healthPct)
+	result.SetupMs = float64(time.Since(setupStart).Nanoseconds()) / 1e6
+
+	for i := 0; i < cfg.warmup; i++ {
+		world.Step()
+	}
+	result.initialX, result.initialHealth = world.Probe()
+
+	// Profiled runs tag samples with the implementation, entity count and system; the labels
+	// cost a few allocations per frame, which show up in the allocation columns
+	var ctx context.Context
+	var systems []benchSystem
+	if cfg.labeled() {
+		var task *trace.Task
+		ctx, task = trace.NewTask(context.Background(), fmt.Sprintf("%s/%d", cfg.impl, entityCount))
+		defer task.End()
+		ctx = pprof.WithLabels(ctx, pprof.Labels("impl", cfg.impl, "entities", strconv.Itoa(entityCount)))
+		systems = world.Systems()
+	}
+
+	frameTimes := make([]time.Duration, cfg.frames)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := range frameTimes {
+		frameStart := time.Now()
+		if systems != nil {
+			stepLabeled(ctx, systems)
+		} else {
+			world.Step()
+		}
+		frameTimes[i] = time.Since(frameStart)
+	}
+	runtime.ReadMemStats(&after)
+	result.finalX, result.finalHealth = world.Probe()
+
+	var total time.Duration
+	for _, frameTime := range frameTimes {
+		total += frameTime
+	}
+	sort.Slice(frameTimes, func(a, b int) bool { return frameTimes[a] < frameTimes[b] })
+	result.TotalMs = float64(total.Nanoseconds()) / 1e6
+	result.AvgFrameMs = result.TotalMs / float64(cfg.frames)
+	result.P99FrameMs = float64(frameTimes[(len(frameTimes)*99+99)/100-1].Nanoseconds()) / 1e6
+	result.AllocsPerFrame = float64(after.Mallocs-before.Mallocs) / float64(cfg.frames)
+	result.BytesPerFrame = float64(after.TotalAlloc-before.TotalAlloc) / float64(cfg.frames)
+	result.GCCycles = after.NumGC - before.NumGC
+	result.GCPauseMs = float64(after.PauseTotalNs-before.PauseTotalNs) / 1e6
+	return result
+}
+
+// One frame with every system under its own pprof label and trace region
+func stepLabeled(ctx context.Context, systems []benchSystem) {
+	for _, system := range systems {
+		pprof.Do(ctx, pprof.Labels("system", system.name), func(ctx context.Context) {
+			trace.WithRegion(ctx, system.name, system.run)
+		})
+	}
+}
+
+// Run with the profiles and trace the flags asked for; the heap profile is taken after run
+func withProfiles(cfg benchConfig, run func() error) error {
+	if cfg.cpuProfile != "" {
+		file, err := os.Create(cfg.cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+	if cfg.tracePath != "" {
+		file, err := os.Create(cfg.tracePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := trace.Start(file); err != nil {
+			return err
+		}
+		defer trace.Stop()
+	}
+
+	if err := run(); err != nil {
+		return err
+	}
+
+	if cfg.memProfile != "" {
+		file, err := os.Create(cfg.memProfile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		// Up-to-date allocation counts rather than those of the last collection
+		runtime.GC()
+		return pprof.WriteHeapProfile(file)
+	}
+	return nil
+}
+
+func runBench(cfg benchConfig, out io.Writer) error {
+	switch cfg.format {
+	case "text":
+		writeText(cfg, out)
+		return nil
+	case "csv":
+		return writeCSV(cfg, out)
+	default:
+		return writeJSON(cfg, out)
+	}
+}
+
+func writeText(cfg benchConfig, out io.Writer) {
+	fmt.Fprintf(out, "=== Go ECS Performance Test (%s) ===\n", cfg.impl)
+	fmt.Fprintf(out, "%d%% moving, %d%% with health\n", cfg.velocityPct, cfg.healthPct)
+
+	for _, entityCount := range cfg.entities {
+		fmt.Fprintf(out, "\n--- Testing %d entities for %d frames ---\n", entityCount, cfg.frames)
+		result := measure(cfg, entityCount)
+
+		fmt.Fprintf(out, "Setup time: %.3fms\n", result.SetupMs)
+		fmt.Fprintf(out, "Total benchmark time: %.3fms\n", result.TotalMs)
+		fmt.Fprintf(out, "Average frame time: %.3fms (p99 %.3fms)\n", result.AvgFrameMs, result.P99FrameMs)
+		fmt.Fprintf(out, "FPS: %.1f\n", 1000.0/result.AvgFrameMs)
+		fmt.Fprintf(out, "Allocations per frame: %.2f (%.0f bytes)\n", result.AllocsPerFrame, result.BytesPerFrame)
+		fmt.Fprintf(out, "GC: %d cycles, %.3fms paused\n", result.GCCycles, result.GCPauseMs)
+
+		// Same verification lines as the Zig test, so results can be compared across ports
+		fmt.Fprintf(out, "Transform verification - Initial X: %.2f, Final X: %.2f, Delta: %.2f\n", result.initialX, result.finalX, result.finalX-result.initialX)
+		if result.initialHealth >= 0 {
+			fmt.Fprintf(out, "Health verification - Initial: %.0f, Final: %.0f\n", result.initialHealth, result.finalHealth)
+		}
+	}
+
+	fmt.Fprintln(out, "\n=== End of Go ECS Performance Test ===")
+}
+
+var csvHeader = []string{
+	"label", "timestamp", "go_version", "os", "arch", "gomaxprocs", "num_cpu", "cpu",
+	"implementation", "entities", "frames", "setup_ms", "total_ms", "avg_frame_ms", "p99_frame_ms", "allocs_per_frame", "bytes_per_frame",
+	"gc_cycles", "gc_pause_ms",
+}
+
+// One row per entity count with the machine repeated on each, ready to append runs together
+func writeCSV(cfg benchConfig, out io.Writer) error {
+	machine := currentMachine(cfg.label)
+	writer := csv.NewWriter(out)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	float := func(value float64) string { return strconv.FormatFloat(value, 'f', -1, 64) }
+	for _, entityCount := range cfg.entities {
+		result := measure(cfg, entityCount)
+		err := writer.Write([]string{
+			machine.Label, machine.Timestamp, machine.GoVersion, machine.OS, machine.Arch,
+			strconv.Itoa(machine.GOMAXPROCS), strconv.Itoa(machine.NumCPU), machine.CPU,
+			result.Implementation, strconv.Itoa(result.Entities), strconv.Itoa(result.Frames),
+			float(result.SetupMs), float(result.TotalMs), float(result.AvgFrameMs), float(result.P99FrameMs),
+			float(result.AllocsPerFrame), float(result.BytesPerFrame),
+			strconv.FormatUint(uint64(result.GCCycles), 10), float(result.GCPauseMs),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSON(cfg benchConfig, out io.Writer) error {
+	report := struct {
+		Machine     machineInfo   `json:"machine"`
+		VelocityPct int           `json:"velocity_pct"`
+		HealthPct   int           `json:"health_pct"`
+		Results     []benchResult `json:"results"`
+	}{Machine: currentMachine(cfg.label), VelocityPct: cfg.velocityPct, HealthPct: cfg.healthPct}
+	for _, entityCount := range cfg.entities {
+		report.Results = append(report.Results, measure(cfg, entityCount))
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := withProfiles(cfg, func() error { return runBench(cfg, os.Stdout) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// The bitset ECS in ecs/go_ultra_optimized.go
+type ultraBench struct {
+	world *ecs.UltraOptimizedECS
+	first uint32
+}
+
+func newUltraBench(capacity int) benchWorld {
+	return &ultraBench{world: ecs.NewUltraOptimizedECS(capacity)}
+}
+
+func (u *ultraBench) Spawn(i int, prefab ecs.Prefab, overrides ...ecs.PrefabOverride) error {
+	entity, err := u.world.Spawn(prefab, overrides...)
+	if i == 0 {
+		u.first = entity
+	}
+	return err
+}
+
+func (u *ultraBench) Step() {
+	u.world.UpdateTransformSystem()
+	u.world.UpdateDamageSystem()
+}
+
+func (u *ultraBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", u.world.UpdateTransformSystem}, {"damage", u.world.UpdateDamageSystem}}
+}
+
+func (u *ultraBench) Probe() (float64, float64) {
+	transform, _ := u.world.GetTransform(u.first)
+	health, ok := u.world.GetHealth(u.first)
+	if !ok {
+		return float64(transform.X), -1
+	}
+	return float64(transform.X), float64(health.Value)
+}
+
+// The per-entity work of the transform and damage systems, shared by the benches that drive them
+// through queries, joins and iterators so they all measure the same thing
+func moveTransform(transform *ecs.Transform, velocity *ecs.Velocity) {
+	transform.X += velocity.DX
+	transform.Y += velocity.DY
+	transform.Z += velocity.DZ
+	transform.RotationX += 0.01
+	transform.RotationY += 0.02
+	transform.RotationZ += 0.03
+}
+
+func tickHealth(health *ecs.Health) {
+	health.Value -= 1.0
+	if health.Value <= 0 {
+		health.Value = 100.0
+	}
+}
+
+// The same ECS driven through reusable Query objects instead of hand-written word loops
+type queryBench struct {
+	ultraBench
+	moving  *ecs.Query
+	damaged *ecs.Query
+}
+
+func newQueryBench(capacity int) benchWorld {
+	return &queryBench{ultraBench: ultraBench{world: ecs.NewUltraOptimizedECS(capacity)}}
+}
+
+func (q *queryBench) Step() {
+	q.transform()
+	q.damage()
+}
+
+func (q *queryBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", q.transform}, {"damage", q.damage}}
+}
+
+func (q *queryBench) transform() {
+	world := q.world
+	transforms, velocities := ecs.StorageOf[ecs.Transform](world), ecs.StorageOf[ecs.Velocity](world)
+	if q.moving == nil {
+		q.moving = world.NewQuery(ecs.TransformComponent, ecs.VelocityComponent)
+	}
+
+	q.moving.Refresh()
+	for entity, ok := q.moving.Next(); ok; entity, ok = q.moving.Next() {
+		moveTransform(transforms.GetDirectUnsafe(entity), velocities.GetDirectUnsafe(entity))
+	}
+}
+
+func (q *queryBench) damage() {
+	world := q.world
+	healths := ecs.StorageOf[ecs.Health](world)
+	if q.damaged == nil {
+		q.damaged = world.NewQuery(ecs.HealthComponent)
+	}
+
+	q.damaged.Refresh()
+	for entity, ok := q.damaged.Next(); ok; entity, ok = q.damaged.Next() {
+		tickHealth(healths.GetDirectUnsafe(entity))
+	}
+}
+
+// The same systems on the world's cached queries, recomputed only when membership changes
+type cachedBench struct {
+	ultraBench
+}
+
+func newCachedBench(capacity int) benchWorld {
+	return &cachedBench{ultraBench: ultraBench{world: ecs.NewUltraOptimizedECS(capacity)}}
+}
+
+func (c *cachedBench) Step() {
+	c.transform()
+	c.damage()
+}
+
+func (c *cachedBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", c.transform}, {"damage", c.damage}}
+}
+
+func (c *cachedBench) transform() {
+	world := c.world
+	transforms, velocities := ecs.StorageOf[ecs.Transform](world), ecs.StorageOf[ecs.Velocity](world)
+	moving := world.CachedQuery(ecs.TransformComponent, ecs.VelocityComponent)
+	for entity, ok := moving.Next(); ok; entity, ok = moving.Next() {
+		moveTransform(transforms.GetDirectUnsafe(entity), velocities.GetDirectUnsafe(entity))
+	}
+}
+
+func (c *cachedBench) damage() {
+	world := c.world
+	healths := ecs.StorageOf[ecs.Health](world)
+	damaged := world.CachedQuery(ecs.HealthComponent)
+	for entity, ok := damaged.Next(); ok; entity, ok = damaged.Next() {
+		tickHealth(healths.GetDirectUnsafe(entity))
+	}
+}
+
+// The same systems written with the typed joins of ecs/go_join.go
+type joinBench struct {
+	ultraBench
+}
+
+func newJoinBench(capacity int) benchWorld {
+	return &joinBench{ultraBench: ultraBench{world: ecs.NewUltraOptimizedECS(capacity)}}
+}
+
+func (j *joinBench) Step() {
+	j.transform()
+	j.damage()
+}
+
+func (j *joinBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", j.transform}, {"damage", j.damage}}
+}
+
+func (j *joinBench) transform() {
+	ecs.For2(j.world, func(entity uint32, transform *ecs.Transform, velocity *ecs.Velocity) {
+		moveTransform(transform, velocity)
+	})
+}
+
+func (j *joinBench) damage() {
+	ecs.For1(j.world, func(entity uint32, health *ecs.Health) {
+		tickHealth(health)
+	})
+}
+
+// The same systems as range-over-func loops (ecs/go_iter.go)
+type rangeBench struct {
+	ultraBench
+	moving *ecs.Query
+}
+
+func newRangeBench(capacity int) benchWorld {
+	return &rangeBench{ultraBench: ultraBench{world: ecs.NewUltraOptimizedECS(capacity)}}
+}
+
+func (r *rangeBench) Step() {
+	r.transform()
+	r.damage()
+}
+
+func (r *rangeBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", r.transform}, {"damage", r.damage}}
+}
+
+func (r *rangeBench) transform() {
+	world := r.world
+	transforms, velocities := ecs.StorageOf[ecs.Transform](world), ecs.StorageOf[ecs.Velocity](world)
+	if r.moving == nil {
+		r.moving = world.NewQuery(ecs.TransformComponent, ecs.VelocityComponent)
+	}
+
+	r.moving.Update()
+	for entity := range r.moving.All() {
+		moveTransform(transforms.GetDirectUnsafe(entity), velocities.GetDirectUnsafe(entity))
+	}
+}
+
+func (r *rangeBench) damage() {
+	for _, health := range ecs.Each[ecs.Health](r.world) {
+		tickHealth(health)
+	}
+}
+
+// Any engine behind the World interface
+type worldBench struct {
+	world ecs.World
+	first uint32
+}
+
+func newWorldBench(world ecs.World) benchWorld {
+	return &worldBench{world: world}
+}
+
+func (w *worldBench) Spawn(i int, prefab ecs.Prefab, overrides ...ecs.PrefabOverride) error {
+	entity, err := w.world.Spawn(prefab, overrides...)
+	if i == 0 {
+		w.first = entity
+	}
+	return err
+}
+
+func (w *worldBench) Step() {
+	w.world.UpdateTransformSystem()
+	w.world.UpdateDamageSystem()
+}
+
+func (w *worldBench) Systems() []benchSystem {
+	return []benchSystem{{"transform", w.world.UpdateTransformSystem}, {"damage", w.world.UpdateDamageSystem}}
+}
+
+func (w *worldBench) Probe() (float64, float64) {
+	transform, _ := w.world.GetTransform(w.first)
+	health, ok := w.world.GetHealth(w.first)
+	if !ok {
+		return float64(transform.X), -1
+	}
+	return float64(transform.X), float64(health.Value)
+}
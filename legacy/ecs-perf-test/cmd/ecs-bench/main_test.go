@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseFlagsDefaultsMatchTheOriginalRun(t *testing.T) {
+	cfg, err := parseFlags(nil, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.impl != "ultra" || cfg.frames != 10000 || cfg.velocityPct != 60 || cfg.healthPct != 40 {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+	if len(cfg.entities) != 5 || cfg.entities[4] != 1000 {
+		t.Fatalf("entities = %v, want 100..1000", cfg.entities)
+	}
+}
+
+func TestParseFlagsRejectsBadInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"-impl=missing"},
+		{"-entities=100,x"},
+		{"-entities=0"},
+		{"-frames=0"},
+		{"-velocity=120"},
+		{"-format=xml"},
+	} {
+		if _, err := parseFlags(args, io.Discard); err == nil {
+			t.Errorf("parseFlags(%v) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestInShareSpreadsThePercentageEvenly(t *testing.T) {
+	for _, pct := range []int{0, 25, 40, 60, 100} {
+		count := 0
+		for i := 0; i < 200; i++ {
+			if inShare(i, pct) {
+				count++
+			}
+		}
+		if count != 2*pct {
+			t.Errorf("inShare(_, %d) picked %d of 200, want %d", pct, count, 2*pct)
+		}
+	}
+}
+
+func TestRunBenchReportsEveryEntityCount(t *testing.T) {
+	cfg, err := parseFlags([]string{"-entities=10,20", "-frames=5", "-warmup=0", "-velocity=100", "-health=100"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := runBench(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	report := out.String()
+	for _, want := range []string{"Testing 10 entities for 5 frames", "Testing 20 entities for 5 frames", "Delta: 5.00", "Health verification - Initial: 100, Final: 95"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestStructuredOutputCarriesMachineAndPercentiles(t *testing.T) {
+	args := []string{"-entities=10,20", "-frames=50", "-warmup=0", "-label=abc123"}
+
+	cfg, err := parseFlags(append(args, "-format=json"), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := runBench(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Machine machineInfo
+		Results []benchResult
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if report.Machine.Label != "abc123" || report.Machine.GOMAXPROCS < 1 || report.Machine.CPU == "" {
+		t.Errorf("machine = %+v", report.Machine)
+	}
+	if len(report.Results) != 2 || report.Results[1].Entities != 20 || report.Results[1].Frames != 50 {
+		t.Fatalf("results = %+v", report.Results)
+	}
+	for _, result := range report.Results {
+		// The bitset systems never allocate; allow for the runtime's own background allocations
+		if result.P99FrameMs <= 0 || result.AvgFrameMs <= 0 || result.AllocsPerFrame > 1 {
+			t.Errorf("result = %+v", result)
+		}
+	}
+
+	cfg, err = parseFlags(append(args, "-format=csv"), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runBench(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || len(rows[0]) != len(csvHeader) || len(rows[1]) != len(csvHeader) || rows[1][0] != "abc123" || rows[2][9] != "20" {
+		t.Fatalf("rows = %v", rows)
+	}
+}
+
+func TestSystemsMatchStepForEveryImplementation(t *testing.T) {
+	for _, impl := range implementationNames() {
+		stepped := spawnWorld(impl, 50, 60, 40)
+		split := spawnWorld(impl, 50, 60, 40)
+		systems := split.Systems()
+		for i := 0; i < 3; i++ {
+			stepped.Step()
+			stepLabeled(context.Background(), systems)
+		}
+
+		x, health := stepped.Probe()
+		splitX, splitHealth := split.Probe()
+		if len(systems) != 2 || x != splitX || health != splitHealth {
+			t.Errorf("%s: systems reached (%v, %v), Step (%v, %v)", impl, splitX, splitHealth, x, health)
+		}
+	}
+}
+
+func TestProfileFlagsWriteEveryProfile(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{
+		"cpuprofile": filepath.Join(dir, "cpu.pprof"),
+		"memprofile": filepath.Join(dir, "mem.pprof"),
+		"trace":      filepath.Join(dir, "run.trace"),
+	}
+	args := []string{"-entities=10", "-frames=20", "-warmup=0"}
+	for flag, path := range paths {
+		args = append(args, "-"+flag+"="+path)
+	}
+	cfg, err := parseFlags(args, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.labeled() {
+		t.Fatal("a CPU profile or trace should label the systems")
+	}
+
+	var out strings.Builder
+	if err := withProfiles(cfg, func() error { return runBench(cfg, &out) }); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Delta: 20.00") {
+		t.Errorf("labeled frames changed the result:\n%s", out.String())
+	}
+	for flag, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == 0 {
+			t.Errorf("-%s wrote nothing to %s (%v)", flag, path, err)
+		}
+	}
+
+	cfg.cpuProfile = filepath.Join(dir, "missing", "cpu.pprof")
+	if err := withProfiles(cfg, func() error { return nil }); err == nil {
+		t.Error("an unwritable profile path should fail")
+	}
+}
+
+// Entity counts of the original runs
+var benchEntityCounts = []int{100, 250, 500, 750, 1000}
+
+// One sub-benchmark per entity count, timing a frame (both systems) per op
+func benchmarkFrames(b *testing.B, impl string) {
+	for _, entityCount := range benchEntityCounts {
+		b.Run(fmt.Sprintf("%dentities", entityCount), func(b *testing.B) {
+			world := spawnWorld(impl, entityCount, 60, 40)
+			for i := 0; i < 100; i++ {
+				world.Step()
+			}
+			b.ReportAllocs()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				world.Step()
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(entityCount), "ns/entity")
+			b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+		})
+	}
+}
+
+// Spawning entityCount entities into a fresh world per op
+func benchmarkSetup(b *testing.B, impl string) {
+	for _, entityCount := range benchEntityCounts {
+		b.Run(fmt.Sprintf("%dentities", entityCount), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				spawnWorld(impl, entityCount, 60, 40)
+			}
+		})
+	}
+}
+
+func BenchmarkBitsetECS(b *testing.B)         { benchmarkFrames(b, "ultra") }
+func BenchmarkBitsetECSSetup(b *testing.B)    { benchmarkSetup(b, "ultra") }
+func BenchmarkQueryECS(b *testing.B)          { benchmarkFrames(b, "query") }
+func BenchmarkCachedQueryECS(b *testing.B)    { benchmarkFrames(b, "cached") }
+func BenchmarkJoinECS(b *testing.B)           { benchmarkFrames(b, "join") }
+func BenchmarkRangeECS(b *testing.B)          { benchmarkFrames(b, "range") }
+func BenchmarkArchetypeECS(b *testing.B)      { benchmarkFrames(b, "archetype") }
+func BenchmarkArchetypeECSSetup(b *testing.B) { benchmarkSetup(b, "archetype") }
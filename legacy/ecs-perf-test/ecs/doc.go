@@ -0,0 +1,14 @@
+// Package ecs is the Go port of the rewind bitset ECS: one dense Storage per component type with a
+// membership bitset, queries that AND those bitsets word by word, and an archetype engine behind
+// the same World interface. The benchmark harness in cmd/ecs-bench is built on it.
+//
+//	world := ecs.NewWorld(1000)
+//	player, err := world.CreateEntity()
+//	world.AddTransform(player, ecs.Transform{})
+package ecs
+
+// Entity ids as CreateEntity hands them out
+type Entity = uint32
+
+// Dense component array of a bitset world, see StorageOf
+type Storage[T any] = UltraOptimizedComponentStorage[T]
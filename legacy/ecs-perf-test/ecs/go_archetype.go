@@ -0,0 +1,387 @@
+package ecs
+
+// Storage engines behind NewWorld: the bitset ECS (default) or archetype chunks
+type World interface {
+	CreateEntity() (uint32, error)
+	DestroyEntity(entity uint32)
+	AddTransform(entity uint32, transform Transform)
+	AddVelocity(entity uint32, velocity Velocity)
+	AddHealth(entity uint32, health Health)
+	RemoveTransform(entity uint32) bool
+	RemoveVelocity(entity uint32) bool
+	RemoveHealth(entity uint32) bool
+	GetTransform(entity uint32) (*Transform, bool)
+	GetHealth(entity uint32) (*Health, bool)
+	// Create an entity from a prefab, see go_prefab.go
+	Spawn(prefab Prefab, overrides ...PrefabOverride) (uint32, error)
+	UpdateTransformSystem()
+	UpdateDamageSystem()
+}
+
+type worldOptions struct {
+	archetypes bool
+	limit      uint32
+}
+
+type WorldOption func(*worldOptions)
+
+// Store entities by archetype in SoA chunks instead of one sparse set per component
+func WithArchetypes() WorldOption {
+	return func(options *worldOptions) { options.archetypes = true }
+}
+
+// Refuse to create more than limit entities, failing CreateEntity with ErrEntityLimit, instead of
+// growing without bound
+func WithEntityLimit(limit int) WorldOption {
+	return func(options *worldOptions) { options.limit = uint32(limit) }
+}
+
+// A world with room for capacity entities before it first grows
+func NewWorld(capacity int, options ...WorldOption) World {
+	var chosen worldOptions
+	for _, option := range options {
+		option(&chosen)
+	}
+	if chosen.archetypes {
+		ecs := NewArchetypeECS(capacity)
+		ecs.entityLimit = chosen.limit
+		return ecs
+	}
+	ecs := NewUltraOptimizedECS(capacity)
+	ecs.entityLimit = chosen.limit
+	return ecs
+}
+
+func (ecs *UltraOptimizedECS) GetTransform(entity uint32) (*Transform, bool) {
+	if !ecs.transforms.Has(entity) {
+		return nil, false
+	}
+	return ecs.transforms.GetDirectUnsafe(entity), true
+}
+
+func (ecs *UltraOptimizedECS) GetHealth(entity uint32) (*Health, bool) {
+	if !ecs.healths.Has(entity) {
+		return nil, false
+	}
+	return ecs.healths.GetDirectUnsafe(entity), true
+}
+
+// Component bits of an archetype mask
+const (
+	transformBit uint32 = 1 << TransformComponent
+	velocityBit  uint32 = 1 << VelocityComponent
+	healthBit    uint32 = 1 << HealthComponent
+)
+
+// Rows per chunk; a chunk's columns are allocated once at full size
+const archetypeChunkSize = 256
+
+// One column per component of the archetype (nil for the others), row i of every column
+// belonging to entities[i]
+type archetypeChunk struct {
+	entities   []uint32
+	transforms []Transform
+	velocities []Velocity
+	healths    []Health
+}
+
+// All entities with exactly one component set
+type archetype struct {
+	mask   uint32
+	chunks []*archetypeChunk
+	// The last chunk that emptied, kept so an entity bouncing in and out doesn't allocate
+	spare *archetypeChunk
+}
+
+type entityLocation struct {
+	archetype *archetype
+	chunk     int
+	row       int
+}
+
+// Entities grouped by component set: systems walk the matching archetypes chunk by chunk over
+// packed columns with no per-entity lookup, while adding or removing a component moves the
+// entity's row to another archetype.
+type ArchetypeECS struct {
+	archetypes []*archetype
+	byMask     map[uint32]*archetype
+	locations  []entityLocation
+	alive      []bool
+	nextEntity uint32
+	// Most entities CreateEntity hands out, 0 for no limit
+	entityLimit uint32
+}
+
+func NewArchetypeECS(maxEntities int) *ArchetypeECS {
+	return &ArchetypeECS{
+		byMask:    make(map[uint32]*archetype),
+		locations: make([]entityLocation, maxEntities),
+		alive:     make([]bool, maxEntities),
+	}
+}
+
+func (ecs *ArchetypeECS) archetypeFor(mask uint32) *archetype {
+	if found, ok := ecs.byMask[mask]; ok {
+		return found
+	}
+	created := &archetype{mask: mask}
+	ecs.byMask[mask] = created
+	ecs.archetypes = append(ecs.archetypes, created)
+	return created
+}
+
+// Append a zeroed row for entity, starting a new chunk when the last one is full
+func (a *archetype) push(entity uint32) entityLocation {
+	last := len(a.chunks) - 1
+	if last < 0 || len(a.chunks[last].entities) == archetypeChunkSize {
+		if a.spare != nil {
+			a.chunks = append(a.chunks, a.spare)
+			a.spare = nil
+		} else {
+			a.chunks = append(a.chunks, a.newChunk())
+		}
+		last++
+	}
+
+	chunk := a.chunks[last]
+	chunk.entities = append(chunk.entities, entity)
+	if chunk.transforms != nil {
+		chunk.transforms = append(chunk.transforms, Transform{})
+	}
+	if chunk.velocities != nil {
+		chunk.velocities = append(chunk.velocities, Velocity{})
+	}
+	if chunk.healths != nil {
+		chunk.healths = append(chunk.healths, Health{})
+	}
+	return entityLocation{archetype: a, chunk: last, row: len(chunk.entities) - 1}
+}
+
+func (a *archetype) newChunk() *archetypeChunk {
+	chunk := &archetypeChunk{entities: make([]uint32, 0, archetypeChunkSize)}
+	if a.mask&transformBit != 0 {
+		chunk.transforms = make([]Transform, 0, archetypeChunkSize)
+	}
+	if a.mask&velocityBit != 0 {
+		chunk.velocities = make([]Velocity, 0, archetypeChunkSize)
+	}
+	if a.mask&healthBit != 0 {
+		chunk.healths = make([]Health, 0, archetypeChunkSize)
+	}
+	return chunk
+}
+
+// Swap-remove a row: the archetype's last row moves into it. Returns the entity that moved
+// (and whether one did) so its location can be fixed.
+func (a *archetype) remove(at entityLocation) (uint32, bool) {
+	lastChunk := a.chunks[len(a.chunks)-1]
+	lastRow := len(lastChunk.entities) - 1
+	chunk := a.chunks[at.chunk]
+
+	moved := lastChunk.entities[lastRow]
+	didMove := chunk != lastChunk || at.row != lastRow
+	if didMove {
+		chunk.entities[at.row] = moved
+		if chunk.transforms != nil {
+			chunk.transforms[at.row] = lastChunk.transforms[lastRow]
+		}
+		if chunk.velocities != nil {
+			chunk.velocities[at.row] = lastChunk.velocities[lastRow]
+		}
+		if chunk.healths != nil {
+			chunk.healths[at.row] = lastChunk.healths[lastRow]
+		}
+	}
+
+	lastChunk.entities = lastChunk.entities[:lastRow]
+	if lastChunk.transforms != nil {
+		lastChunk.transforms = lastChunk.transforms[:lastRow]
+	}
+	if lastChunk.velocities != nil {
+		lastChunk.velocities = lastChunk.velocities[:lastRow]
+	}
+	if lastChunk.healths != nil {
+		lastChunk.healths = lastChunk.healths[:lastRow]
+	}
+	if lastRow == 0 {
+		a.chunks = a.chunks[:len(a.chunks)-1]
+		a.spare = lastChunk
+	}
+	return moved, didMove
+}
+
+// Hands out the next entity id, doubling the entity-indexed arrays when they run out of room.
+// Ids are not reused, as in the bitset world.
+func (ecs *ArchetypeECS) CreateEntity() (uint32, error) {
+	entity := ecs.nextEntity
+	if ecs.entityLimit != 0 && entity >= ecs.entityLimit {
+		return 0, ErrEntityLimit
+	}
+	if int(entity) >= len(ecs.alive) {
+		capacity := grownCapacity(uint32(len(ecs.alive)), entity)
+		if ecs.entityLimit != 0 && capacity > ecs.entityLimit {
+			capacity = ecs.entityLimit
+		}
+		ecs.locations = append(ecs.locations, make([]entityLocation, int(capacity)-len(ecs.locations))...)
+		ecs.alive = append(ecs.alive, make([]bool, int(capacity)-len(ecs.alive))...)
+	}
+
+	ecs.nextEntity++
+	ecs.alive[entity] = true
+	ecs.locations[entity] = ecs.archetypeFor(0).push(entity)
+	return entity, nil
+}
+
+func (ecs *ArchetypeECS) DestroyEntity(entity uint32) {
+	if int(entity) >= len(ecs.alive) || !ecs.alive[entity] {
+		return
+	}
+	ecs.detach(entity)
+	ecs.alive[entity] = false
+}
+
+func (ecs *ArchetypeECS) detach(entity uint32) {
+	at := ecs.locations[entity]
+	if moved, ok := at.archetype.remove(at); ok {
+		ecs.locations[moved] = at
+	}
+}
+
+// Move entity to the archetype of mask, carrying over the components both have
+func (ecs *ArchetypeECS) move(entity uint32, mask uint32) entityLocation {
+	from := ecs.locations[entity]
+	fromChunk := from.archetype.chunks[from.chunk]
+	to := ecs.archetypeFor(mask).push(entity)
+	toChunk := to.archetype.chunks[to.chunk]
+
+	if fromChunk.transforms != nil && toChunk.transforms != nil {
+		toChunk.transforms[to.row] = fromChunk.transforms[from.row]
+	}
+	if fromChunk.velocities != nil && toChunk.velocities != nil {
+		toChunk.velocities[to.row] = fromChunk.velocities[from.row]
+	}
+	if fromChunk.healths != nil && toChunk.healths != nil {
+		toChunk.healths[to.row] = fromChunk.healths[from.row]
+	}
+
+	ecs.detach(entity)
+	ecs.locations[entity] = to
+	return to
+}
+
+// Give a live entity the component bit; true with its new location when it did not have it yet
+func (ecs *ArchetypeECS) withComponent(entity uint32, bit uint32) (entityLocation, bool) {
+	if int(entity) >= len(ecs.alive) || !ecs.alive[entity] {
+		return entityLocation{}, false
+	}
+	at := ecs.locations[entity]
+	if at.archetype.mask&bit != 0 {
+		return at, false
+	}
+	return ecs.move(entity, at.archetype.mask|bit), true
+}
+
+// Adding a component the entity already has keeps the old value, like the bitset ECS
+func (ecs *ArchetypeECS) AddTransform(entity uint32, transform Transform) {
+	if at, added := ecs.withComponent(entity, transformBit); added {
+		at.archetype.chunks[at.chunk].transforms[at.row] = transform
+	}
+}
+
+func (ecs *ArchetypeECS) AddVelocity(entity uint32, velocity Velocity) {
+	if at, added := ecs.withComponent(entity, velocityBit); added {
+		at.archetype.chunks[at.chunk].velocities[at.row] = velocity
+	}
+}
+
+func (ecs *ArchetypeECS) AddHealth(entity uint32, health Health) {
+	if at, added := ecs.withComponent(entity, healthBit); added {
+		at.archetype.chunks[at.chunk].healths[at.row] = health
+	}
+}
+
+func (ecs *ArchetypeECS) removeComponent(entity uint32, bit uint32) bool {
+	if int(entity) >= len(ecs.alive) || !ecs.alive[entity] {
+		return false
+	}
+	mask := ecs.locations[entity].archetype.mask
+	if mask&bit == 0 {
+		return false
+	}
+	ecs.move(entity, mask&^bit)
+	return true
+}
+
+func (ecs *ArchetypeECS) RemoveTransform(entity uint32) bool {
+	return ecs.removeComponent(entity, transformBit)
+}
+
+func (ecs *ArchetypeECS) RemoveVelocity(entity uint32) bool {
+	return ecs.removeComponent(entity, velocityBit)
+}
+
+func (ecs *ArchetypeECS) RemoveHealth(entity uint32) bool {
+	return ecs.removeComponent(entity, healthBit)
+}
+
+func (ecs *ArchetypeECS) GetTransform(entity uint32) (*Transform, bool) {
+	if int(entity) >= len(ecs.alive) || !ecs.alive[entity] {
+		return nil, false
+	}
+	at := ecs.locations[entity]
+	chunk := at.archetype.chunks[at.chunk]
+	if chunk.transforms == nil {
+		return nil, false
+	}
+	return &chunk.transforms[at.row], true
+}
+
+func (ecs *ArchetypeECS) GetHealth(entity uint32) (*Health, bool) {
+	if int(entity) >= len(ecs.alive) || !ecs.alive[entity] {
+		return nil, false
+	}
+	at := ecs.locations[entity]
+	chunk := at.archetype.chunks[at.chunk]
+	if chunk.healths == nil {
+		return nil, false
+	}
+	return &chunk.healths[at.row], true
+}
+
+func (ecs *ArchetypeECS) UpdateTransformSystem() {
+	for _, a := range ecs.archetypes {
+		if a.mask&(transformBit|velocityBit) != transformBit|velocityBit {
+			continue
+		}
+		for _, chunk := range a.chunks {
+			velocities := chunk.velocities
+			for i := range chunk.transforms {
+				transform := &chunk.transforms[i]
+				velocity := &velocities[i]
+				transform.X += velocity.DX
+				transform.Y += velocity.DY
+				transform.Z += velocity.DZ
+				transform.RotationX += 0.01
+				transform.RotationY += 0.02
+				transform.RotationZ += 0.03
+			}
+		}
+	}
+}
+
+func (ecs *ArchetypeECS) UpdateDamageSystem() {
+	for _, a := range ecs.archetypes {
+		if a.mask&healthBit == 0 {
+			continue
+		}
+		for _, chunk := range a.chunks {
+			for i := range chunk.healths {
+				health := &chunk.healths[i]
+				health.Value -= 1.0
+				if health.Value <= 0 {
+					health.Value = 100.0
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ecs
+
+import (
+	"errors"
+	"testing"
+)
+
+// Runs the same script against both engines and expects identical component values
+func TestArchetypeWorldMatchesTheBitsetWorld(t *testing.T) {
+	worlds := map[string]World{
+		"bitset":    NewWorld(1200),
+		"archetype": NewWorld(1200, WithArchetypes()),
+	}
+	for _, world := range worlds {
+		// More entities than one chunk holds, spread over several archetypes
+		for i := 0; i < 1000; i++ {
+			entity := mustCreateEntity(t, world)
+			world.AddTransform(entity, Transform{X: float32(i)})
+			if i%2 == 0 {
+				world.AddVelocity(entity, Velocity{DX: 1, DY: 2})
+			}
+			if i%3 == 0 {
+				world.AddHealth(entity, Health{Value: float32(i%7 + 1)})
+			}
+		}
+		for entity := uint32(0); entity < 1000; entity += 10 {
+			world.RemoveVelocity(entity)
+		}
+		for entity := uint32(5); entity < 1000; entity += 50 {
+			world.DestroyEntity(entity)
+		}
+		world.AddTransform(1, Transform{X: -1}) // already present: keeps the old value
+		for frame := 0; frame < 20; frame++ {
+			world.UpdateTransformSystem()
+			world.UpdateDamageSystem()
+		}
+	}
+
+	bitset, archetypes := worlds["bitset"], worlds["archetype"]
+	for entity := uint32(0); entity < 1000; entity++ {
+		want, wantOK := bitset.GetTransform(entity)
+		got, gotOK := archetypes.GetTransform(entity)
+		if wantOK != gotOK || (wantOK && *want != *got) {
+			t.Fatalf("entity %d transform: bitset %v %v, archetype %v %v", entity, want, wantOK, got, gotOK)
+		}
+		wantHealth, wantOK := bitset.GetHealth(entity)
+		gotHealth, gotOK := archetypes.GetHealth(entity)
+		if wantOK != gotOK || (wantOK && *wantHealth != *gotHealth) {
+			t.Fatalf("entity %d health: bitset %v %v, archetype %v %v", entity, wantHealth, wantOK, gotHealth, gotOK)
+		}
+	}
+}
+
+func TestArchetypeRowsMoveBetweenChunks(t *testing.T) {
+	ecs := NewArchetypeECS(600)
+	for i := 0; i < 600; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+	}
+	moving := ecs.archetypeFor(transformBit)
+	if len(moving.chunks) != 3 {
+		t.Fatalf("600 rows in %d chunks, want 3", len(moving.chunks))
+	}
+
+	// Removing from the first chunk pulls the archetype's last row in; emptied chunks are dropped
+	for entity := uint32(0); entity < 100; entity++ {
+		if !ecs.RemoveTransform(entity) {
+			t.Fatalf("RemoveTransform(%d) = false", entity)
+		}
+	}
+	if len(moving.chunks) != 2 {
+		t.Fatalf("500 rows in %d chunks, want 2", len(moving.chunks))
+	}
+	for entity := uint32(100); entity < 600; entity++ {
+		transform, ok := ecs.GetTransform(entity)
+		if !ok || transform.X != float32(entity) {
+			t.Fatalf("entity %d transform %v %v after the moves", entity, transform, ok)
+		}
+	}
+	if ecs.RemoveTransform(0) {
+		t.Fatal("second RemoveTransform(0) = true")
+	}
+}
+
+func TestArchetypeWorldGrowsUpToItsLimit(t *testing.T) {
+	world := NewWorld(2, WithArchetypes(), WithEntityLimit(10))
+	for i := 0; i < 10; i++ {
+		entity := mustCreateEntity(t, world)
+		world.AddTransform(entity, Transform{X: float32(i)})
+	}
+	archetypes := world.(*ArchetypeECS)
+	if len(archetypes.alive) != 10 || len(archetypes.locations) != 10 {
+		t.Fatalf("capacity %d, locations %d, want the limit of 10", len(archetypes.alive), len(archetypes.locations))
+	}
+	for entity := uint32(0); entity < 10; entity++ {
+		if transform, ok := world.GetTransform(entity); !ok || transform.X != float32(entity) {
+			t.Fatalf("entity %d transform %v %v after growing", entity, transform, ok)
+		}
+	}
+
+	if _, err := world.CreateEntity(); !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("CreateEntity past the limit: %v, want ErrEntityLimit", err)
+	}
+	if _, err := world.Spawn(Prefab{Health: &Health{Value: 1}}); !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("Spawn past the limit: %v, want ErrEntityLimit", err)
+	}
+
+	// Without a limit the arrays keep doubling
+	unlimited := NewWorld(2, WithArchetypes())
+	for i := 0; i < 5; i++ {
+		mustCreateEntity(t, unlimited)
+	}
+	if capacity := len(unlimited.(*ArchetypeECS).alive); capacity != 8 {
+		t.Fatalf("capacity %d after 5 entities, want 8", capacity)
+	}
+}
@@ -0,0 +1,62 @@
+package ecs
+
+// The last two snapshots of the world, for rendering between simulation ticks. A 60Hz simulation
+// drawn at 144Hz draws each frame at alpha = time since the last tick / tick length, blending the
+// previous tick's transforms into the latest ones instead of snapping to them.
+//
+//	interp.Push(ecs.Snapshot()) // after every tick
+//	transform, ok := interp.LerpedTransform(entity, alpha)
+//
+// Push releases the snapshot that falls out, so the pair recycles its buffers. An Interpolation
+// is not synchronized: keep Push and the lookups on one goroutine (hand it the snapshots over a
+// channel when the simulation runs elsewhere).
+type Interpolation struct {
+	previous, current *WorldView
+}
+
+// Make view the latest tick
+func (in *Interpolation) Push(view *WorldView) {
+	if in.previous != nil {
+		in.previous.Release()
+	}
+	in.previous, in.current = in.current, view
+}
+
+// Entity's transform alpha (0..1) of the way from the previous tick to the latest. Entities that
+// only exist in the latest tick are drawn where they are; false when the latest tick has no
+// transform for the entity.
+func (in *Interpolation) LerpedTransform(entity uint32, alpha float32) (Transform, bool) {
+	if in.current == nil {
+		return Transform{}, false
+	}
+	to, ok := in.current.Transform(entity)
+	if !ok || in.previous == nil {
+		return to, ok
+	}
+	from, ok := in.previous.Transform(entity)
+	if !ok {
+		return to, true
+	}
+	return Transform{
+		X:         lerp(from.X, to.X, alpha),
+		Y:         lerp(from.Y, to.Y, alpha),
+		Z:         lerp(from.Z, to.Z, alpha),
+		RotationX: lerp(from.RotationX, to.RotationX, alpha),
+		RotationY: lerp(from.RotationY, to.RotationY, alpha),
+		RotationZ: lerp(from.RotationZ, to.RotationZ, alpha),
+	}, true
+}
+
+// Release both snapshots
+func (in *Interpolation) Reset() {
+	for _, view := range []*WorldView{in.previous, in.current} {
+		if view != nil {
+			view.Release()
+		}
+	}
+	in.previous, in.current = nil, nil
+}
+
+func lerp(from, to, alpha float32) float32 {
+	return from + (to-from)*alpha
+}
@@ -0,0 +1,41 @@
+package ecs
+
+import "testing"
+
+func TestInterpolationBlendsTheLastTwoTicks(t *testing.T) {
+	ecs := NewUltraOptimizedECS(16)
+	mover := mustCreateEntity(t, ecs)
+	ecs.AddTransform(mover, Transform{})
+	ecs.AddVelocity(mover, Velocity{DX: 2, DY: -1})
+
+	var interp Interpolation
+	if _, ok := interp.LerpedTransform(mover, 0.5); ok {
+		t.Fatal("no snapshot pushed yet")
+	}
+	interp.Push(ecs.Snapshot())
+	ecs.UpdateTransformSystem()
+	spawned := mustCreateEntity(t, ecs)
+	ecs.AddTransform(spawned, Transform{X: 9})
+	interp.Push(ecs.Snapshot())
+
+	for _, alpha := range []float32{0, 0.25, 1} {
+		got, ok := interp.LerpedTransform(mover, alpha)
+		if !ok || got.X != 2*alpha || got.Y != -alpha || got.RotationZ != 0.03*alpha {
+			t.Fatalf("alpha %v: %+v", alpha, got)
+		}
+	}
+	if got, ok := interp.LerpedTransform(spawned, 0.5); !ok || got.X != 9 {
+		t.Fatalf("new entity drawn at %+v, want where it spawned", got)
+	}
+
+	// The oldest snapshot goes back to the world on every push
+	ecs.DestroyEntity(mover)
+	interp.Push(ecs.Snapshot())
+	if _, ok := interp.LerpedTransform(mover, 0.5); ok {
+		t.Fatal("destroyed entity still drawn")
+	}
+	interp.Reset()
+	if len(ecs.views.free) != 3 {
+		t.Fatalf("%d snapshots back in the pool, want 3", len(ecs.views.free))
+	}
+}
@@ -0,0 +1,45 @@
+package ecs
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// Range-over-func views of the world, for plain for loops instead of Next() pairs:
+//
+//	for entity, health := range Each[Health](ecs) { health.Value-- }
+//	for entity := range moving.All() { ... }
+//
+// Breaking out of the loop stops the walk; nothing is allocated per frame.
+
+// Every live entity with component T and a pointer into its dense array, in ascending entity order
+func Each[T any](ecs *UltraOptimizedECS) iter.Seq2[uint32, *T] {
+	return func(yield func(uint32, *T) bool) {
+		storage := StorageOf[T](ecs)
+		active, holders := ecs.activeEntities.words, storage.entityBitset.words
+		dense, index := storage.dense, storage.entityToIndex
+		for wordIndex := range active {
+			word := active[wordIndex] & holders[wordIndex]
+			base := uint32(wordIndex << 6)
+			for word != 0 {
+				entity := base + uint32(bits.TrailingZeros64(word))
+				if !yield(entity, &dense[index[entity]]) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// The entities of the query's current result; rewinds the query first, like a fresh Next() walk
+func (q *Query) All() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		q.Rewind()
+		for entity, ok := q.Next(); ok; entity, ok = q.Next() {
+			if !yield(entity) {
+				return
+			}
+		}
+	}
+}
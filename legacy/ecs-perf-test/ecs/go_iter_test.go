@@ -0,0 +1,54 @@
+package ecs
+
+import "testing"
+
+func TestRangeIteratorsMatchTheQueries(t *testing.T) {
+	ecs := NewUltraOptimizedECS(200)
+	for i := 0; i < 150; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+		if i%3 == 0 {
+			ecs.AddVelocity(entity, Velocity{DX: 1})
+		}
+	}
+	ecs.DestroyEntity(3)
+
+	visited := 0
+	for entity, transform := range Each[Transform](ecs) {
+		if transform.X != float32(entity) {
+			t.Fatalf("entity %d got the transform of %v", entity, transform.X)
+		}
+		transform.Y = 1
+		visited++
+	}
+	if visited != 149 || ecs.transforms.GetDirectUnsafe(149).Y != 1 {
+		t.Fatalf("visited %d transforms, want 149 with writes landing in the storage", visited)
+	}
+
+	moving := ecs.NewQuery(TransformComponent, VelocityComponent)
+	var fromRange []uint32
+	for entity := range moving.All() {
+		fromRange = append(fromRange, entity)
+		if len(fromRange) == 10 {
+			break
+		}
+	}
+	moving.Rewind()
+	for i := 0; i < 10; i++ {
+		if entity, _ := moving.Next(); entity != fromRange[i] {
+			t.Fatalf("range yielded %v, Next() disagrees at %d", fromRange, i)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for entity := range moving.All() {
+			ecs.transforms.GetDirectUnsafe(entity).X += ecs.velocities.GetDirectUnsafe(entity).DX
+		}
+		for _, transform := range Each[Transform](ecs) {
+			transform.Z++
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("range loops allocated %v times per run, want 0", allocs)
+	}
+}
@@ -0,0 +1,61 @@
+package ecs
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Typed joins: call fn with pointers straight into the dense arrays for every live entity that has
+// all the listed components, in ascending entity order. The membership words are ANDed as the
+// loop goes, so there is no scratch bitset, no Query to keep and nothing allocated; fn is only
+// called, never stored, so a closure passed in stays on the caller's stack.
+//
+//	For2(ecs, func(entity uint32, t *Transform, v *Velocity) { t.X += v.DX })
+//
+// Adding or removing components of the joined types inside fn is not allowed - it moves the
+// dense arrays under the loop.
+func For1[A any](ecs *UltraOptimizedECS, fn func(entity uint32, a *A)) {
+	storeA := StorageOf[A](ecs)
+	active, inA := ecs.activeEntities.words, storeA.entityBitset.words
+	denseA, indexA := storeA.dense, storeA.entityToIndex
+	for wordIndex := range active {
+		word := active[wordIndex] & inA[wordIndex]
+		base := uint32(wordIndex << 6)
+		for word != 0 {
+			entity := base + uint32(bits.TrailingZeros64(word))
+			fn(entity, &denseA[indexA[entity]])
+			word &= word - 1
+		}
+	}
+}
+
+func For2[A, B any](ecs *UltraOptimizedECS, fn func(entity uint32, a *A, b *B)) {
+	storeA, storeB := StorageOf[A](ecs), StorageOf[B](ecs)
+	active, inA, inB := ecs.activeEntities.words, storeA.entityBitset.words, storeB.entityBitset.words
+	denseA, indexA := storeA.dense, storeA.entityToIndex
+	denseB, indexB := storeB.dense, storeB.entityToIndex
+	for wordIndex := range active {
+		word := active[wordIndex] & inA[wordIndex] & inB[wordIndex]
+		base := uint32(wordIndex << 6)
+		for word != 0 {
+			entity := base + uint32(bits.TrailingZeros64(word))
+			fn(entity, &denseA[indexA[entity]], &denseB[indexB[entity]])
+			word &= word - 1
+		}
+	}
+}
+
+// The world's storage of component type T, for systems that look components up by entity
+func StorageOf[T any](ecs *UltraOptimizedECS) *Storage[T] {
+	if storage, ok := any(ecs.transforms).(*UltraOptimizedComponentStorage[T]); ok {
+		return storage
+	}
+	if storage, ok := any(ecs.velocities).(*UltraOptimizedComponentStorage[T]); ok {
+		return storage
+	}
+	if storage, ok := any(ecs.healths).(*UltraOptimizedComponentStorage[T]); ok {
+		return storage
+	}
+	var zero T
+	panic(fmt.Sprintf("no storage for component %T", zero))
+}
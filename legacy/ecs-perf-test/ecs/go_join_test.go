@@ -0,0 +1,58 @@
+package ecs
+
+import "testing"
+
+func TestJoinsVisitEveryMatchAndNeverAllocate(t *testing.T) {
+	ecs := NewUltraOptimizedECS(200)
+	for i := 0; i < 150; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+		if i%3 == 0 {
+			ecs.AddVelocity(entity, Velocity{DX: 1})
+		}
+		if i%5 == 0 {
+			ecs.AddHealth(entity, Health{Value: 10})
+		}
+	}
+	ecs.DestroyEntity(3)
+
+	previous, visited := -1, 0
+	For2(ecs, func(entity uint32, transform *Transform, velocity *Velocity) {
+		if int(entity) <= previous || entity%3 != 0 || entity == 3 || transform.X != float32(entity) {
+			t.Fatalf("unexpected entity %d (X %v) after %d", entity, transform.X, previous)
+		}
+		transform.X += velocity.DX
+		previous = int(entity)
+		visited++
+	})
+	if visited != 49 || ecs.transforms.GetDirectUnsafe(147).X != 148 {
+		t.Fatalf("visited %d, want 49 with writes landing in the storage", visited)
+	}
+
+	// Type order is free, and the joined storages are found by type
+	visited = 0
+	For2(ecs, func(entity uint32, health *Health, transform *Transform) { visited++ })
+	if visited != 30 {
+		t.Fatalf("health join visited %d, want 30", visited)
+	}
+
+	var total float32
+	allocs := testing.AllocsPerRun(100, func() {
+		For2(ecs, func(entity uint32, transform *Transform, velocity *Velocity) {
+			transform.X += velocity.DX
+		})
+		For1(ecs, func(entity uint32, health *Health) { total += health.Value })
+	})
+	if allocs != 0 {
+		t.Fatalf("joins allocated %v times per run, want 0", allocs)
+	}
+}
+
+func TestJoinOfAnUnknownComponentPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("For1 over an unregistered type did not panic")
+		}
+	}()
+	For1(NewUltraOptimizedECS(8), func(entity uint32, count *int) {})
+}
@@ -0,0 +1,100 @@
+package ecs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// An entity template: the components it spawns with and their default values. Nil components are
+// left off. Prefabs are plain values, so they can be written in Go or loaded with LoadPrefabs:
+//
+//	[{"name": "mover", "transform": {}, "velocity": {"DX": 1}}]
+type Prefab struct {
+	Name      string     `json:"name"`
+	Transform *Transform `json:"transform,omitempty"`
+	Velocity  *Velocity  `json:"velocity,omitempty"`
+	Health    *Health    `json:"health,omitempty"`
+}
+
+// Adjusts one spawn's copy of a prefab; the prefab itself is never modified
+type PrefabOverride func(*Prefab)
+
+// Spawn with this transform instead of the default (adding one if the prefab has none)
+func WithTransform(transform Transform) PrefabOverride {
+	return func(prefab *Prefab) { prefab.Transform = &transform }
+}
+
+func WithVelocity(velocity Velocity) PrefabOverride {
+	return func(prefab *Prefab) { prefab.Velocity = &velocity }
+}
+
+func WithHealth(health Health) PrefabOverride {
+	return func(prefab *Prefab) { prefab.Health = &health }
+}
+
+// Spawn without a component the prefab has
+func Without(kind ComponentKind) PrefabOverride {
+	return func(prefab *Prefab) {
+		switch kind {
+		case TransformComponent:
+			prefab.Transform = nil
+		case VelocityComponent:
+			prefab.Velocity = nil
+		case HealthComponent:
+			prefab.Health = nil
+		}
+	}
+}
+
+// Read a JSON array of prefabs, keyed by name. Unknown fields and missing or duplicate names are
+// errors, so a typo in a data file fails loudly instead of spawning a component short.
+func LoadPrefabs(r io.Reader) (map[string]Prefab, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var list []Prefab
+	if err := decoder.Decode(&list); err != nil {
+		return nil, fmt.Errorf("prefabs: %w", err)
+	}
+
+	prefabs := make(map[string]Prefab, len(list))
+	for _, prefab := range list {
+		if prefab.Name == "" {
+			return nil, errors.New("prefabs: prefab without a name")
+		}
+		if _, duplicate := prefabs[prefab.Name]; duplicate {
+			return nil, fmt.Errorf("prefabs: %q defined twice", prefab.Name)
+		}
+		prefabs[prefab.Name] = prefab
+	}
+	return prefabs, nil
+}
+
+func (ecs *UltraOptimizedECS) Spawn(prefab Prefab, overrides ...PrefabOverride) (uint32, error) {
+	return spawnPrefab(ecs, prefab, overrides)
+}
+
+func (ecs *ArchetypeECS) Spawn(prefab Prefab, overrides ...PrefabOverride) (uint32, error) {
+	return spawnPrefab(ecs, prefab, overrides)
+}
+
+func spawnPrefab(world World, prefab Prefab, overrides []PrefabOverride) (uint32, error) {
+	for _, override := range overrides {
+		override(&prefab)
+	}
+	entity, err := world.CreateEntity()
+	if err != nil {
+		return 0, err
+	}
+	if prefab.Transform != nil {
+		world.AddTransform(entity, *prefab.Transform)
+	}
+	if prefab.Velocity != nil {
+		world.AddVelocity(entity, *prefab.Velocity)
+	}
+	if prefab.Health != nil {
+		world.AddHealth(entity, *prefab.Health)
+	}
+	return entity, nil
+}
@@ -0,0 +1,59 @@
+package ecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrefabsLoadFromJSONAndSpawnWithOverrides(t *testing.T) {
+	prefabs, err := LoadPrefabs(strings.NewReader(`[
+		{"name": "rock", "transform": {"X": 3}},
+		{"name": "grunt", "transform": {}, "velocity": {"DX": 2}, "health": {"Value": 50}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, world := range []World{NewWorld(16), NewWorld(16, WithArchetypes())} {
+		rock, err := world.Spawn(prefabs["rock"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transform, ok := world.GetTransform(rock); !ok || transform.X != 3 {
+			t.Fatalf("rock transform = %v, %v", transform, ok)
+		}
+		if _, ok := world.GetHealth(rock); ok {
+			t.Fatal("rock got health it does not define")
+		}
+
+		grunt, err := world.Spawn(prefabs["grunt"], WithHealth(Health{Value: 80}), WithTransform(Transform{Y: 1}), Without(VelocityComponent))
+		if err != nil {
+			t.Fatal(err)
+		}
+		world.UpdateTransformSystem()
+		if health, _ := world.GetHealth(grunt); health.Value != 80 {
+			t.Fatalf("grunt health = %v, want the override", health.Value)
+		}
+		if transform, _ := world.GetTransform(grunt); transform.Y != 1 || transform.X != 0 {
+			t.Fatalf("grunt moved to %+v without a velocity", transform)
+		}
+	}
+
+	// Overrides never leak into the prefab
+	if prefabs["grunt"].Health.Value != 50 || prefabs["grunt"].Velocity == nil {
+		t.Fatalf("prefab changed: %+v", prefabs["grunt"])
+	}
+}
+
+func TestLoadPrefabsRejectsBadDefinitions(t *testing.T) {
+	for _, input := range []string{
+		`[{"transform": {}}]`,
+		`[{"name": "a"}, {"name": "a"}]`,
+		`[{"name": "a", "helth": {"Value": 1}}]`,
+		`{"name": "a"}`,
+	} {
+		if _, err := LoadPrefabs(strings.NewReader(input)); err == nil {
+			t.Errorf("LoadPrefabs(%s) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Struct-of-arrays component storage: one dense float32 column per field of T instead of one
+// slice of T. A pass that touches a few fields (rotation only, say) streams just those columns,
+// and a column loop is plain float32 arithmetic the compiler can keep in registers. The column
+// layout is built by reflection once, at construction; T must be a struct of float32 fields.
+//
+//	transforms := NewStorageSoA[Transform](maxEntities)
+//	transforms.Add(entity, Transform{X: 1})
+//	rotation := transforms.Column("RotationZ")
+//	for i := range rotation { rotation[i] += 0.03 }
+type StorageSoA[T any] struct {
+	columns       [][]float32
+	names         []string
+	offsets       []uintptr
+	entityBitset  *UltraOptimizedBitSet
+	entityToIndex []uint32
+	indexToEntity []uint32
+	count         uint32
+}
+
+func NewStorageSoA[T any](maxEntities int) *StorageSoA[T] {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("StorageSoA needs a struct, got %v", typ))
+	}
+
+	s := &StorageSoA[T]{
+		entityBitset:  NewUltraOptimizedBitSet(uint32(maxEntities)),
+		entityToIndex: make([]uint32, maxEntities),
+		indexToEntity: make([]uint32, 0, maxEntities),
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type.Kind() != reflect.Float32 {
+			panic(fmt.Sprintf("StorageSoA[%v]: field %s is %v, only float32 fields can be split into columns", typ, field.Name, field.Type))
+		}
+		s.columns = append(s.columns, make([]float32, 0, maxEntities))
+		s.names = append(s.names, field.Name)
+		s.offsets = append(s.offsets, field.Offset)
+	}
+	return s
+}
+
+func (s *StorageSoA[T]) Add(entity uint32, component T) {
+	if s.entityBitset.IsSet(entity) {
+		return
+	}
+
+	base := unsafe.Pointer(&component)
+	for f, offset := range s.offsets {
+		s.columns[f] = append(s.columns[f], *(*float32)(unsafe.Add(base, offset)))
+	}
+	s.indexToEntity = append(s.indexToEntity, entity)
+	s.entityToIndex[entity] = s.count
+	s.entityBitset.Set(entity)
+	s.count++
+}
+
+// Swap-remove in every column, like the AoS storage
+func (s *StorageSoA[T]) Remove(entity uint32) bool {
+	if !s.entityBitset.IsSet(entity) {
+		return false
+	}
+
+	index := s.entityToIndex[entity]
+	last := s.count - 1
+	if index != last {
+		moved := s.indexToEntity[last]
+		for f := range s.columns {
+			s.columns[f][index] = s.columns[f][last]
+		}
+		s.indexToEntity[index] = moved
+		s.entityToIndex[moved] = index
+	}
+
+	for f := range s.columns {
+		s.columns[f] = s.columns[f][:last]
+	}
+	s.indexToEntity = s.indexToEntity[:last]
+	s.entityBitset.Unset(entity)
+	s.count--
+	return true
+}
+
+func (s *StorageSoA[T]) Has(entity uint32) bool {
+	return s.entityBitset.IsSet(entity)
+}
+
+// The component gathered back from its columns
+func (s *StorageSoA[T]) Get(entity uint32) T {
+	var component T
+	index := s.entityToIndex[entity]
+	base := unsafe.Pointer(&component)
+	for f, offset := range s.offsets {
+		*(*float32)(unsafe.Add(base, offset)) = s.columns[f][index]
+	}
+	return component
+}
+
+// Scatter a whole component into the entity's row
+func (s *StorageSoA[T]) Set(entity uint32, component T) {
+	index := s.entityToIndex[entity]
+	base := unsafe.Pointer(&component)
+	for f, offset := range s.offsets {
+		s.columns[f][index] = *(*float32)(unsafe.Add(base, offset))
+	}
+}
+
+func (s *StorageSoA[T]) Len() int {
+	return int(s.count)
+}
+
+// Dense row of entity in every column
+func (s *StorageSoA[T]) Index(entity uint32) uint32 {
+	return s.entityToIndex[entity]
+}
+
+// Entity owning each row
+func (s *StorageSoA[T]) Entities() []uint32 {
+	return s.indexToEntity
+}
+
+// The live column of a field, row i belonging to Entities()[i]. Writes go straight to the storage;
+// the slice is only valid until the next Add or Remove.
+func (s *StorageSoA[T]) Column(name string) []float32 {
+	for f, field := range s.names {
+		if field == name {
+			return s.columns[f]
+		}
+	}
+	panic(fmt.Sprintf("StorageSoA has no column %q", name))
+}
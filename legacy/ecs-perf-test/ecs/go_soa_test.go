@@ -0,0 +1,120 @@
+package ecs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStorageSoASplitsAndGathersComponents(t *testing.T) {
+	storage := NewStorageSoA[Transform](16)
+	for entity := uint32(0); entity < 4; entity++ {
+		storage.Add(entity, Transform{X: float32(entity), RotationZ: float32(entity) * 10})
+	}
+	if got := storage.Get(2); got != (Transform{X: 2, RotationZ: 20}) {
+		t.Fatalf("Get(2) = %+v", got)
+	}
+
+	// Columns alias the storage
+	storage.Column("Y")[storage.Index(1)] = 7
+	if got := storage.Get(1).Y; got != 7 {
+		t.Fatalf("Y of entity 1 = %v after a column write, want 7", got)
+	}
+	storage.Set(3, Transform{Z: 5})
+	if got := storage.Get(3); got != (Transform{Z: 5}) {
+		t.Fatalf("Get(3) = %+v after Set", got)
+	}
+
+	// Entity 3 moves into entity 0's row in every column
+	if !storage.Remove(0) || storage.Remove(0) || storage.Has(0) {
+		t.Fatal("Remove(0) should succeed once")
+	}
+	if storage.Len() != 3 || storage.Index(3) != 0 || storage.Entities()[0] != 3 {
+		t.Fatalf("Len() = %d, Index(3) = %d, Entities() = %v", storage.Len(), storage.Index(3), storage.Entities())
+	}
+	if got := storage.Get(3); got != (Transform{Z: 5}) {
+		t.Fatalf("Get(3) = %+v after the move", got)
+	}
+	if got := len(storage.Column("RotationX")); got != 3 {
+		t.Fatalf("column length %d, want 3", got)
+	}
+}
+
+func TestStorageSoARejectsFieldsItCannotSplit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a struct with an int field was accepted")
+		}
+	}()
+	NewStorageSoA[struct{ Count int }](4)
+}
+
+// A rotation-only pass (touches 3 of 6 fields) and a full integrate (all fields plus velocity),
+// over the same values stored AoS and SoA
+func BenchmarkTransformLayout(b *testing.B) {
+	for _, entityCount := range []int{1000, 10000} {
+		aos := NewUltraOptimizedComponentStorage[Transform](entityCount)
+		velocitiesAoS := NewUltraOptimizedComponentStorage[Velocity](entityCount)
+		soa := NewStorageSoA[Transform](entityCount)
+		velocitiesSoA := NewStorageSoA[Velocity](entityCount)
+		for entity := uint32(0); entity < uint32(entityCount); entity++ {
+			aos.Add(entity, Transform{X: float32(entity)})
+			soa.Add(entity, Transform{X: float32(entity)})
+			velocitiesAoS.Add(entity, Velocity{DX: 1, DY: 0.5, DZ: 0.25})
+			velocitiesSoA.Add(entity, Velocity{DX: 1, DY: 0.5, DZ: 0.25})
+		}
+
+		b.Run(fmt.Sprintf("spin/AoS/%dentities", entityCount), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for i := range aos.dense {
+					transform := &aos.dense[i]
+					transform.RotationX += 0.01
+					transform.RotationY += 0.02
+					transform.RotationZ += 0.03
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("spin/SoA/%dentities", entityCount), func(b *testing.B) {
+			rx, ry, rz := soa.Column("RotationX"), soa.Column("RotationY"), soa.Column("RotationZ")
+			for n := 0; n < b.N; n++ {
+				for i := range rx {
+					rx[i] += 0.01
+				}
+				for i := range ry {
+					ry[i] += 0.02
+				}
+				for i := range rz {
+					rz[i] += 0.03
+				}
+			}
+		})
+
+		// Both storages were filled in entity order, so rows line up without a lookup
+		b.Run(fmt.Sprintf("integrate/AoS/%dentities", entityCount), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				velocities := velocitiesAoS.dense[:len(aos.dense)]
+				for i := range aos.dense {
+					transform, velocity := &aos.dense[i], &velocities[i]
+					transform.X += velocity.DX
+					transform.Y += velocity.DY
+					transform.Z += velocity.DZ
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("integrate/SoA/%dentities", entityCount), func(b *testing.B) {
+			x, y, z := soa.Column("X"), soa.Column("Y"), soa.Column("Z")
+			dx, dy, dz := velocitiesSoA.Column("DX"), velocitiesSoA.Column("DY"), velocitiesSoA.Column("DZ")
+			for n := 0; n < b.N; n++ {
+				addColumn(x, dx)
+				addColumn(y, dy)
+				addColumn(z, dz)
+			}
+		})
+	}
+}
+
+func addColumn(target, delta []float32) {
+	delta = delta[:len(target)]
+	for i := range target {
+		target[i] += delta[i]
+	}
+}
@@ -0,0 +1,88 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Tag (marker) components such as Frozen{} or PlayerControlled{} carry no data, so their storage
+// is only the membership bitset: no dense array, no index maps. A registered tag is a
+// ComponentKind like any other and can be required by NewQuery and CachedQuery.
+//
+//	frozen := RegisterTag[Frozen](ecs)
+//	AddTag[Frozen](ecs, entity)
+//	still := ecs.CachedQuery(TransformComponent, frozen)
+type TagStorage struct {
+	entityBitset *UltraOptimizedBitSet
+	count        uint32
+	// Bumped by every add and remove, like the component storages
+	version uint64
+}
+
+func NewTagStorage(maxEntities int) *TagStorage {
+	return &TagStorage{entityBitset: NewUltraOptimizedBitSet(uint32(maxEntities))}
+}
+
+func (s *TagStorage) Add(entity uint32) {
+	if s.entityBitset.IsSet(entity) {
+		return
+	}
+	s.entityBitset.Set(entity)
+	s.count++
+	s.version++
+}
+
+func (s *TagStorage) Remove(entity uint32) bool {
+	if !s.entityBitset.IsSet(entity) {
+		return false
+	}
+	s.entityBitset.Unset(entity)
+	s.count--
+	s.version++
+	return true
+}
+
+func (s *TagStorage) Has(entity uint32) bool {
+	return s.entityBitset.IsSet(entity)
+}
+
+func (s *TagStorage) Len() int {
+	return int(s.count)
+}
+
+// CachedQuery keys its queries by a 32-bit kind mask
+const maxComponentKinds = 32
+
+// The query kind of tag T, registering its storage the first time. T must be zero-size.
+func RegisterTag[T any](ecs *UltraOptimizedECS) ComponentKind {
+	typ := reflect.TypeFor[T]()
+	if kind, found := ecs.tagKinds[typ]; found {
+		return kind
+	}
+	if typ.Size() != 0 {
+		panic(fmt.Sprintf("tag %v has %d bytes of data; tags must be zero-size", typ, typ.Size()))
+	}
+	kind := firstTagComponent + ComponentKind(len(ecs.tags))
+	if kind >= maxComponentKinds {
+		panic(fmt.Sprintf("tag %v: at most %d component kinds", typ, maxComponentKinds))
+	}
+	ecs.tags = append(ecs.tags, NewTagStorage(int(ecs.activeEntities.size)))
+	ecs.tagKinds[typ] = kind
+	return kind
+}
+
+func tagStorage[T any](ecs *UltraOptimizedECS) *TagStorage {
+	return ecs.tags[RegisterTag[T](ecs)-firstTagComponent]
+}
+
+func AddTag[T any](ecs *UltraOptimizedECS, entity uint32) {
+	tagStorage[T](ecs).Add(entity)
+}
+
+func RemoveTag[T any](ecs *UltraOptimizedECS, entity uint32) bool {
+	return tagStorage[T](ecs).Remove(entity)
+}
+
+func HasTag[T any](ecs *UltraOptimizedECS, entity uint32) bool {
+	return tagStorage[T](ecs).Has(entity)
+}
@@ -0,0 +1,52 @@
+package ecs
+
+import "testing"
+
+type Frozen struct{}
+type PlayerControlled struct{}
+
+func TestTagsQueryLikeDataComponents(t *testing.T) {
+	ecs := NewUltraOptimizedECS(64)
+	frozen := RegisterTag[Frozen](ecs)
+	if RegisterTag[Frozen](ecs) != frozen || RegisterTag[PlayerControlled](ecs) == frozen {
+		t.Fatal("each tag type should get one kind of its own")
+	}
+	for i := 0; i < 6; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+		if i%2 == 0 {
+			AddTag[Frozen](ecs, entity)
+		}
+	}
+	AddTag[PlayerControlled](ecs, 3)
+
+	still := ecs.CachedQuery(TransformComponent, frozen)
+	var got []uint32
+	for entity, ok := still.Next(); ok; entity, ok = still.Next() {
+		got = append(got, entity)
+	}
+	if len(got) != 3 || got[0] != 0 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("frozen transforms = %v, want [0 2 4]", got)
+	}
+
+	// Tag changes invalidate cached queries; destroying an entity drops its tags
+	if !RemoveTag[Frozen](ecs, 2) || RemoveTag[Frozen](ecs, 2) || HasTag[Frozen](ecs, 2) {
+		t.Fatal("RemoveTag should succeed once")
+	}
+	ecs.DestroyEntity(4)
+	if ecs.CachedQuery(TransformComponent, frozen).Count() != 1 || still.Rebuilds != 2 {
+		t.Fatalf("count = %d after %d rebuilds, want 1 after 2", still.Count(), still.Rebuilds)
+	}
+	if tagStorage[Frozen](ecs).Len() != 1 || !HasTag[PlayerControlled](ecs, 3) {
+		t.Fatal("tags of other entities should be untouched")
+	}
+}
+
+func TestRegisterTagRejectsDataComponents(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Health was registered as a tag")
+		}
+	}()
+	RegisterTag[Health](NewUltraOptimizedECS(8))
+}
@@ -0,0 +1,532 @@
+package ecs
+
+import (
+	"errors"
+	"math/bits"
+	"reflect"
+	"unsafe"
+)
+
+// Component types
+type Transform struct {
+	X, Y, Z                 float32
+	RotationX, RotationY, RotationZ float32
+}
+
+type Velocity struct {
+	DX, DY, DZ float32
+}
+
+type Health struct {
+	Value float32
+}
+
+// Ultra-optimized BitSet using bit shifts
+type UltraOptimizedBitSet struct {
+	words []uint64
+	size  uint32
+}
+
+func NewUltraOptimizedBitSet(size uint32) *UltraOptimizedBitSet {
+	wordCount := (size + 63) >> 6 // Bit shift instead of division
+	return &UltraOptimizedBitSet{
+		words: make([]uint64, wordCount),
+		size:  size,
+	}
+}
+
+// Extend the set to hold indices below size, keeping the bits already set
+func (b *UltraOptimizedBitSet) Grow(size uint32) {
+	if size <= b.size {
+		return
+	}
+	wordCount := int((size + 63) >> 6)
+	b.words = append(b.words, make([]uint64, wordCount-len(b.words))...)
+	b.size = size
+}
+
+// Capacity that fits index: double the current one, or exactly enough when that falls short
+func grownCapacity(current, index uint32) uint32 {
+	capacity := current * 2
+	if capacity <= index {
+		capacity = index + 1
+	}
+	return capacity
+}
+
+func (b *UltraOptimizedBitSet) Set(index uint32) {
+	if index >= b.size {
+		return
+	}
+	wordIndex := index >> 6 // Bit shift instead of division
+	bitIndex := index & 63  // Bit mask instead of modulus
+	b.words[wordIndex] |= 1 << bitIndex
+}
+
+func (b *UltraOptimizedBitSet) IsSet(index uint32) bool {
+	if index >= b.size {
+		return false
+	}
+	wordIndex := index >> 6
+	bitIndex := index & 63
+	return (b.words[wordIndex] & (1 << bitIndex)) != 0
+}
+
+func (b *UltraOptimizedBitSet) Unset(index uint32) {
+	if index >= b.size {
+		return
+	}
+	b.words[index>>6] &^= 1 << (index & 63)
+}
+
+func (b *UltraOptimizedBitSet) Clear() {
+	for i := range b.words {
+		b.words[i] = 0
+	}
+}
+
+// Triple intersection with manual operation
+func (b *UltraOptimizedBitSet) TripleIntersection(set1, set2, set3 *UltraOptimizedBitSet) {
+	minLen := len(b.words)
+	if len(set1.words) < minLen {
+		minLen = len(set1.words)
+	}
+	if len(set2.words) < minLen {
+		minLen = len(set2.words)
+	}
+	if len(set3.words) < minLen {
+		minLen = len(set3.words)
+	}
+
+	// Manual triple intersection
+	for i := 0; i < minLen; i++ {
+		b.words[i] = set1.words[i] & set2.words[i] & set3.words[i]
+	}
+	
+	// Clear remaining words
+	for i := minLen; i < len(b.words); i++ {
+		b.words[i] = 0
+	}
+}
+
+// Ultra-optimized component storage using generics
+type UltraOptimizedComponentStorage[T any] struct {
+	dense         []T
+	entityBitset  *UltraOptimizedBitSet
+	entityToIndex []uint32
+	indexToEntity []uint32
+	count         uint32
+	// Bumped by every add and remove, so cached queries know when membership moved
+	version uint64
+}
+
+func NewUltraOptimizedComponentStorage[T any](maxEntities int) *UltraOptimizedComponentStorage[T] {
+	return &UltraOptimizedComponentStorage[T]{
+		dense:         make([]T, 0, maxEntities),
+		entityBitset:  NewUltraOptimizedBitSet(uint32(maxEntities)),
+		entityToIndex: make([]uint32, maxEntities),
+		indexToEntity: make([]uint32, 0, maxEntities),
+		count:         0,
+	}
+}
+
+// Make room for entities below capacity; dense grows on its own as components are appended
+func (s *UltraOptimizedComponentStorage[T]) grow(capacity uint32) {
+	if capacity <= uint32(len(s.entityToIndex)) {
+		return
+	}
+	s.entityToIndex = append(s.entityToIndex, make([]uint32, int(capacity)-len(s.entityToIndex))...)
+	s.entityBitset.Grow(capacity)
+}
+
+func (s *UltraOptimizedComponentStorage[T]) Add(entity uint32, component T) {
+	if s.entityBitset.IsSet(entity) {
+		return
+	}
+	if entity >= uint32(len(s.entityToIndex)) {
+		s.grow(grownCapacity(uint32(len(s.entityToIndex)), entity))
+	}
+
+	index := s.count
+	s.dense = append(s.dense, component)
+	s.indexToEntity = append(s.indexToEntity, entity)
+	s.entityToIndex[entity] = index
+	s.entityBitset.Set(entity)
+	s.count++
+	s.version++
+}
+
+// Swap-remove: the last component moves into the freed slot so dense stays packed
+func (s *UltraOptimizedComponentStorage[T]) Remove(entity uint32) bool {
+	if !s.entityBitset.IsSet(entity) {
+		return false
+	}
+
+	index := s.entityToIndex[entity]
+	last := s.count - 1
+	if index != last {
+		moved := s.indexToEntity[last]
+		s.dense[index] = s.dense[last]
+		s.indexToEntity[index] = moved
+		s.entityToIndex[moved] = index
+	}
+
+	var zero T
+	s.dense[last] = zero
+	s.dense = s.dense[:last]
+	s.indexToEntity = s.indexToEntity[:last]
+	s.entityBitset.Unset(entity)
+	s.count--
+	s.version++
+	return true
+}
+
+func (s *UltraOptimizedComponentStorage[T]) Has(entity uint32) bool {
+	return s.entityBitset.IsSet(entity)
+}
+
+// Direct unsafe access for maximum performance
+func (s *UltraOptimizedComponentStorage[T]) GetDirectUnsafe(entity uint32) *T {
+	index := s.entityToIndex[entity]
+	return &s.dense[index]
+}
+
+// Ultra-optimized ECS
+type UltraOptimizedECS struct {
+	transforms    *UltraOptimizedComponentStorage[Transform]
+	velocities    *UltraOptimizedComponentStorage[Velocity]
+	healths       *UltraOptimizedComponentStorage[Health]
+	activeEntities *UltraOptimizedBitSet
+	queryResult   *UltraOptimizedBitSet
+	nextEntity    uint32
+	// Most entities CreateEntity hands out, 0 for no limit
+	entityLimit uint32
+	// Bumped by every entity creation and destruction
+	entityVersion uint64
+	// Shared queries of CachedQuery, keyed by component mask
+	queries map[uint32]*Query
+	// Tag storages in registration order, tag i being ComponentKind firstTagComponent+i
+	tags     []*TagStorage
+	tagKinds map[reflect.Type]ComponentKind
+	// Released WorldViews for Snapshot to reuse
+	views viewPool
+}
+
+func NewUltraOptimizedECS(maxEntities int) *UltraOptimizedECS {
+	return &UltraOptimizedECS{
+		transforms:    NewUltraOptimizedComponentStorage[Transform](maxEntities),
+		velocities:    NewUltraOptimizedComponentStorage[Velocity](maxEntities),
+		healths:       NewUltraOptimizedComponentStorage[Health](maxEntities),
+		activeEntities: NewUltraOptimizedBitSet(uint32(maxEntities)),
+		queryResult:   NewUltraOptimizedBitSet(uint32(maxEntities)),
+		nextEntity:    0,
+		queries:       make(map[uint32]*Query),
+		tagKinds:      make(map[reflect.Type]ComponentKind),
+	}
+}
+
+// CreateEntity fails with this once the world's entity limit is reached
+var ErrEntityLimit = errors.New("ecs: entity limit reached")
+
+// Hands out the next entity id, doubling every entity-indexed array when it runs out of room.
+// Ids are not reused, so a world with a limit refuses new entities after limit creations.
+func (ecs *UltraOptimizedECS) CreateEntity() (uint32, error) {
+	entity := ecs.nextEntity
+	if ecs.entityLimit != 0 && entity >= ecs.entityLimit {
+		return 0, ErrEntityLimit
+	}
+	if entity >= ecs.activeEntities.size {
+		capacity := grownCapacity(ecs.activeEntities.size, entity)
+		if ecs.entityLimit != 0 && capacity > ecs.entityLimit {
+			capacity = ecs.entityLimit
+		}
+		ecs.grow(capacity)
+	}
+
+	ecs.nextEntity++
+	ecs.activeEntities.Set(entity)
+	ecs.entityVersion++
+	return entity, nil
+}
+
+// Resize the active set, the scratch result and every storage together, so systems can walk
+// their words side by side
+func (ecs *UltraOptimizedECS) grow(capacity uint32) {
+	ecs.activeEntities.Grow(capacity)
+	ecs.queryResult.Grow(capacity)
+	ecs.transforms.grow(capacity)
+	ecs.velocities.grow(capacity)
+	ecs.healths.grow(capacity)
+	for _, tags := range ecs.tags {
+		tags.entityBitset.Grow(capacity)
+	}
+}
+
+// Removes the entity from every storage and the active set
+func (ecs *UltraOptimizedECS) DestroyEntity(entity uint32) {
+	if !ecs.activeEntities.IsSet(entity) {
+		return
+	}
+	ecs.transforms.Remove(entity)
+	ecs.velocities.Remove(entity)
+	ecs.healths.Remove(entity)
+	for _, tags := range ecs.tags {
+		tags.Remove(entity)
+	}
+	ecs.activeEntities.Unset(entity)
+	ecs.entityVersion++
+}
+
+func (ecs *UltraOptimizedECS) AddTransform(entity uint32, transform Transform) {
+	ecs.transforms.Add(entity, transform)
+}
+
+func (ecs *UltraOptimizedECS) AddVelocity(entity uint32, velocity Velocity) {
+	ecs.velocities.Add(entity, velocity)
+}
+
+func (ecs *UltraOptimizedECS) AddHealth(entity uint32, health Health) {
+	ecs.healths.Add(entity, health)
+}
+
+func (ecs *UltraOptimizedECS) RemoveTransform(entity uint32) bool {
+	return ecs.transforms.Remove(entity)
+}
+
+func (ecs *UltraOptimizedECS) RemoveVelocity(entity uint32) bool {
+	return ecs.velocities.Remove(entity)
+}
+
+func (ecs *UltraOptimizedECS) RemoveHealth(entity uint32) bool {
+	return ecs.healths.Remove(entity)
+}
+
+// Component kinds a Query can require; RegisterTag hands out the kinds from firstTagComponent on
+type ComponentKind int
+
+const (
+	TransformComponent ComponentKind = iota
+	VelocityComponent
+	HealthComponent
+	firstTagComponent
+)
+
+func (ecs *UltraOptimizedECS) componentBitset(kind ComponentKind) *UltraOptimizedBitSet {
+	switch kind {
+	case TransformComponent:
+		return ecs.transforms.entityBitset
+	case VelocityComponent:
+		return ecs.velocities.entityBitset
+	case HealthComponent:
+		return ecs.healths.entityBitset
+	default:
+		return ecs.tags[kind-firstTagComponent].entityBitset
+	}
+}
+
+func (ecs *UltraOptimizedECS) componentVersion(kind ComponentKind) uint64 {
+	switch kind {
+	case TransformComponent:
+		return ecs.transforms.version
+	case VelocityComponent:
+		return ecs.velocities.version
+	case HealthComponent:
+		return ecs.healths.version
+	default:
+		return ecs.tags[kind-firstTagComponent].version
+	}
+}
+
+// Reusable query: the caller keeps it across frames, Refresh recomputes the result bitset in
+// place and Next yields entity ids by value, so a frame of queries allocates nothing.
+//
+//	moving := ecs.NewQuery(TransformComponent, VelocityComponent) // once
+//	moving.Refresh()                                             // every frame
+//	for entity, ok := moving.Next(); ok; entity, ok = moving.Next() { ... }
+type Query struct {
+	ecs      *UltraOptimizedECS
+	kinds    []ComponentKind
+	required []*UltraOptimizedBitSet
+	result   *UltraOptimizedBitSet
+	// Entity version, then one storage version per required kind, as of the last Refresh
+	versions []uint64
+	// How many times the result was recomputed
+	Rebuilds int
+	// Iteration cursor: the word being drained and its remaining bits
+	wordIndex int
+	word      uint64
+}
+
+// Allocates the query's result bitset; everything after reuses it
+func (ecs *UltraOptimizedECS) NewQuery(kinds ...ComponentKind) *Query {
+	q := &Query{
+		ecs:      ecs,
+		kinds:    append([]ComponentKind(nil), kinds...),
+		required: make([]*UltraOptimizedBitSet, len(kinds)),
+		result:   NewUltraOptimizedBitSet(ecs.activeEntities.size),
+		versions: make([]uint64, len(kinds)+1),
+	}
+	for i, kind := range kinds {
+		q.required[i] = ecs.componentBitset(kind)
+	}
+	q.Refresh()
+	return q
+}
+
+// Recompute the matching entities from the current component sets and rewind the cursor
+func (q *Query) Refresh() {
+	q.result.Grow(q.ecs.activeEntities.size)
+	copy(q.result.words, q.ecs.activeEntities.words)
+	for _, set := range q.required {
+		for i := range q.result.words {
+			q.result.words[i] &= set.words[i]
+		}
+	}
+	q.versions[0] = q.ecs.entityVersion
+	for i, kind := range q.kinds {
+		q.versions[i+1] = q.ecs.componentVersion(kind)
+	}
+	q.Rebuilds++
+	q.Rewind()
+}
+
+// Refresh only if an entity was created or destroyed or a required component added or removed
+// since the last one; otherwise just rewind. Returns whether the result was recomputed.
+func (q *Query) Update() bool {
+	stale := q.versions[0] != q.ecs.entityVersion
+	for i, kind := range q.kinds {
+		stale = stale || q.versions[i+1] != q.ecs.componentVersion(kind)
+	}
+	if stale {
+		q.Refresh()
+	} else {
+		q.Rewind()
+	}
+	return stale
+}
+
+// The world's shared query for this component set, brought up to date with Update. Steady-state
+// frames, where entities only change component values, skip the intersection entirely. Queries
+// for the same set are one object, so finish iterating it before asking for it again.
+func (ecs *UltraOptimizedECS) CachedQuery(kinds ...ComponentKind) *Query {
+	var mask uint32
+	for _, kind := range kinds {
+		mask |= 1 << uint(kind)
+	}
+	q, found := ecs.queries[mask]
+	if !found {
+		q = ecs.NewQuery(kinds...)
+		ecs.queries[mask] = q
+		return q
+	}
+	q.Update()
+	return q
+}
+
+// Start iterating the current result again without recomputing it
+func (q *Query) Rewind() {
+	q.wordIndex = 0
+	q.word = 0
+	if len(q.result.words) > 0 {
+		q.word = q.result.words[0]
+	}
+}
+
+// Next matching entity in ascending order; ok is false once the result is exhausted
+func (q *Query) Next() (entity uint32, ok bool) {
+	for q.word == 0 {
+		q.wordIndex++
+		if q.wordIndex >= len(q.result.words) {
+			return 0, false
+		}
+		q.word = q.result.words[q.wordIndex]
+	}
+	bit := bits.TrailingZeros64(q.word)
+	q.word &= q.word - 1
+	return uint32(q.wordIndex<<6 + bit), true
+}
+
+// Matching entities as of the last Refresh
+func (q *Query) Count() int {
+	count := 0
+	for _, word := range q.result.words {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Ultra-fast system with manual bitset iteration (Zig-style)
+func (ecs *UltraOptimizedECS) UpdateTransformSystem() {
+	// Triple intersection
+	ecs.queryResult.TripleIntersection(ecs.activeEntities, ecs.transforms.entityBitset, ecs.velocities.entityBitset)
+	
+	// Manual word iteration for maximum performance
+	words := ecs.queryResult.words
+	transformsSlice := ecs.transforms.dense
+	velocitiesSlice := ecs.velocities.dense
+	transformEntityToIndex := ecs.transforms.entityToIndex
+	velocityEntityToIndex := ecs.velocities.entityToIndex
+	
+	for wordIndex, word := range words {
+		if word == 0 {
+			continue
+		}
+		
+		baseEntity := uint32(wordIndex << 6) // Bit shift for multiplication
+		
+		for word != 0 {
+			bitIndex := bits.TrailingZeros64(word)
+			entity := baseEntity + uint32(bitIndex)
+			
+			// Direct slice access for maximum performance
+			transformIndex := transformEntityToIndex[entity]
+			velocityIndex := velocityEntityToIndex[entity]
+			
+			// Use unsafe pointers for even faster access
+			transformPtr := (*Transform)(unsafe.Pointer(&transformsSlice[transformIndex]))
+			velocityPtr := (*Velocity)(unsafe.Pointer(&velocitiesSlice[velocityIndex]))
+			
+			// Inline math operations
+			transformPtr.X += velocityPtr.DX
+			transformPtr.Y += velocityPtr.DY
+			transformPtr.Z += velocityPtr.DZ
+			transformPtr.RotationX += 0.01
+			transformPtr.RotationY += 0.02
+			transformPtr.RotationZ += 0.03
+			
+			word &= word - 1 // Clear lowest bit
+		}
+	}
+}
+
+func (ecs *UltraOptimizedECS) UpdateDamageSystem() {
+	// Intersection for health entities
+	ecs.queryResult.TripleIntersection(ecs.activeEntities, ecs.healths.entityBitset, ecs.healths.entityBitset)
+	
+	words := ecs.queryResult.words
+	healthsSlice := ecs.healths.dense
+	healthEntityToIndex := ecs.healths.entityToIndex
+	
+	for wordIndex, word := range words {
+		if word == 0 {
+			continue
+		}
+		
+		baseEntity := uint32(wordIndex << 6)
+		
+		for word != 0 {
+			bitIndex := bits.TrailingZeros64(word)
+			entity := baseEntity + uint32(bitIndex)
+			
+			healthIndex := healthEntityToIndex[entity]
+			healthPtr := (*Health)(unsafe.Pointer(&healthsSlice[healthIndex]))
+			
+			healthPtr.Value -= 1.0
+			if healthPtr.Value <= 0 {
+				healthPtr.Value = 100.0
+			}
+			
+			word &= word - 1
+		}
+	}
+}
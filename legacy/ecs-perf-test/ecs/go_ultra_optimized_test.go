@@ -0,0 +1,234 @@
+package ecs
+
+import (
+	"errors"
+	"testing"
+)
+
+// CreateEntity on a world with no entity limit, which never fails
+func mustCreateEntity(t *testing.T, world World) uint32 {
+	t.Helper()
+	entity, err := world.CreateEntity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+func TestRemoveRemapsTheMovedComponent(t *testing.T) {
+	storage := NewUltraOptimizedComponentStorage[Health](16)
+	for entity := uint32(0); entity < 4; entity++ {
+		storage.Add(entity, Health{Value: float32(entity)})
+	}
+
+	// Entity 3 sits last in dense and moves into entity 1's slot
+	if !storage.Remove(1) {
+		t.Fatal("Remove(1) = false, want true")
+	}
+	if storage.Remove(1) {
+		t.Fatal("second Remove(1) = true, want false")
+	}
+	if storage.count != 3 || len(storage.dense) != 3 {
+		t.Fatalf("count = %d, len(dense) = %d, want 3", storage.count, len(storage.dense))
+	}
+	if storage.entityToIndex[3] != 1 || storage.indexToEntity[1] != 3 {
+		t.Fatalf("entity 3 mapped to index %d, index 1 holds entity %d", storage.entityToIndex[3], storage.indexToEntity[1])
+	}
+	for _, entity := range []uint32{0, 2, 3} {
+		if got := storage.GetDirectUnsafe(entity).Value; got != float32(entity) {
+			t.Errorf("entity %d health = %v, want %v", entity, got, float32(entity))
+		}
+	}
+
+	// Entity 2 now holds the last slot; removing it moves nothing
+	storage.Remove(2)
+	if storage.entityToIndex[3] != 1 || storage.indexToEntity[0] != 0 {
+		t.Fatal("removing the last slot remapped a survivor")
+	}
+}
+
+func TestDestroyEntityClearsEveryStorage(t *testing.T) {
+	ecs := NewUltraOptimizedECS(16)
+	a := mustCreateEntity(t, ecs)
+	b := mustCreateEntity(t, ecs)
+	for _, entity := range []uint32{a, b} {
+		ecs.AddTransform(entity, Transform{X: float32(entity)})
+		ecs.AddVelocity(entity, Velocity{DX: 1})
+		ecs.AddHealth(entity, Health{Value: 10})
+	}
+
+	ecs.DestroyEntity(a)
+	if ecs.activeEntities.IsSet(a) {
+		t.Error("destroyed entity is still active")
+	}
+	if ecs.transforms.Has(a) || ecs.velocities.Has(a) || ecs.healths.Has(a) {
+		t.Error("destroyed entity still has components")
+	}
+	if got := ecs.transforms.GetDirectUnsafe(b).X; got != float32(b) {
+		t.Errorf("survivor transform X = %v, want %v", got, float32(b))
+	}
+
+	// Systems skip the destroyed entity and keep updating the survivor
+	ecs.UpdateTransformSystem()
+	ecs.UpdateDamageSystem()
+	if ecs.transforms.count != 1 || ecs.healths.count != 1 {
+		t.Fatalf("transforms = %d, healths = %d, want 1 each", ecs.transforms.count, ecs.healths.count)
+	}
+
+	ecs.DestroyEntity(a) // already gone
+	if !ecs.RemoveHealth(b) || ecs.RemoveHealth(b) {
+		t.Error("RemoveHealth should succeed once")
+	}
+}
+
+func TestReusedQueriesMatchAndNeverAllocate(t *testing.T) {
+	ecs := NewUltraOptimizedECS(200)
+	for i := 0; i < 150; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{})
+		if i%3 == 0 {
+			ecs.AddVelocity(entity, Velocity{DX: 1})
+		}
+	}
+	ecs.DestroyEntity(3)
+
+	moving := ecs.NewQuery(TransformComponent, VelocityComponent)
+	if got := moving.Count(); got != 49 {
+		t.Fatalf("Count() = %d, want 49", got)
+	}
+	previous := -1
+	for entity, ok := moving.Next(); ok; entity, ok = moving.Next() {
+		if int(entity) <= previous || entity%3 != 0 || entity == 3 {
+			t.Fatalf("unexpected entity %d after %d", entity, previous)
+		}
+		previous = int(entity)
+	}
+	if previous != 147 {
+		t.Fatalf("last entity %d, want 147", previous)
+	}
+
+	// Membership changes show up after Refresh
+	ecs.RemoveVelocity(0)
+	moving.Refresh()
+	if got := moving.Count(); got != 48 {
+		t.Fatalf("Count() after removal = %d, want 48", got)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		moving.Refresh()
+		for entity, ok := moving.Next(); ok; entity, ok = moving.Next() {
+			ecs.transforms.GetDirectUnsafe(entity).X += 1
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("query frame allocated %v times, want 0", allocs)
+	}
+}
+
+func TestCachedQueriesRebuildOnlyOnMembershipChanges(t *testing.T) {
+	ecs := NewUltraOptimizedECS(64)
+	for i := 0; i < 10; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{})
+		ecs.AddVelocity(entity, Velocity{})
+	}
+
+	moving := ecs.CachedQuery(TransformComponent, VelocityComponent)
+	if again := ecs.CachedQuery(VelocityComponent, TransformComponent); again != moving {
+		t.Fatal("the same component set gave two different queries")
+	}
+	if moving.Rebuilds != 1 || moving.Count() != 10 {
+		t.Fatalf("Rebuilds = %d, Count() = %d, want 1 and 10", moving.Rebuilds, moving.Count())
+	}
+
+	// Writing values is not a structural change
+	ecs.transforms.GetDirectUnsafe(4).X = 9
+	ecs.CachedQuery(TransformComponent, VelocityComponent)
+	if moving.Rebuilds != 1 {
+		t.Fatalf("Rebuilds = %d after a value write, want 1", moving.Rebuilds)
+	}
+
+	// Health is not part of the query; velocity and entity changes are
+	ecs.AddHealth(2, Health{Value: 1})
+	ecs.CachedQuery(TransformComponent, VelocityComponent)
+	ecs.RemoveVelocity(4)
+	ecs.CachedQuery(TransformComponent, VelocityComponent)
+	ecs.DestroyEntity(5)
+	ecs.CachedQuery(TransformComponent, VelocityComponent)
+	if moving.Rebuilds != 3 || moving.Count() != 8 {
+		t.Fatalf("Rebuilds = %d, Count() = %d, want 3 and 8", moving.Rebuilds, moving.Count())
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		q := ecs.CachedQuery(TransformComponent, VelocityComponent)
+		for entity, ok := q.Next(); ok; entity, ok = q.Next() {
+			ecs.transforms.GetDirectUnsafe(entity).X += 1
+		}
+	})
+	if allocs != 0 || moving.Rebuilds != 3 {
+		t.Fatalf("steady-state frame allocated %v times and rebuilt to %d", allocs, moving.Rebuilds)
+	}
+}
+
+func TestWorldsGrowPastTheirInitialCapacity(t *testing.T) {
+	ecs := NewUltraOptimizedECS(4)
+	moving := ecs.NewQuery(TransformComponent, VelocityComponent)
+	for i := 0; i < 300; i++ {
+		entity := mustCreateEntity(t, ecs)
+		if entity == 4 && ecs.activeEntities.size != 8 {
+			t.Fatalf("capacity %d after the fifth entity, want 8", ecs.activeEntities.size)
+		}
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+		ecs.AddVelocity(entity, Velocity{DX: 1})
+		if i%2 == 0 {
+			ecs.AddHealth(entity, Health{Value: 10})
+		}
+	}
+	if ecs.activeEntities.size != 512 || len(ecs.healths.entityToIndex) != 512 {
+		t.Fatalf("capacity %d, health lookup %d, want 512 each", ecs.activeEntities.size, len(ecs.healths.entityToIndex))
+	}
+
+	// Queries made while the world was small pick up the grown sets
+	moving.Refresh()
+	if got := moving.Count(); got != 300 {
+		t.Fatalf("Count() = %d, want 300", got)
+	}
+	ecs.UpdateTransformSystem()
+	ecs.UpdateDamageSystem()
+	if x := ecs.transforms.GetDirectUnsafe(299).X; x != 300 {
+		t.Fatalf("last entity X = %v, want 300", x)
+	}
+	if health := ecs.healths.GetDirectUnsafe(298).Value; health != 9 {
+		t.Fatalf("entity 298 health = %v, want 9", health)
+	}
+
+	// A storage on its own grows as far as the entity it is given
+	storage := NewUltraOptimizedComponentStorage[Health](16)
+	storage.Add(1000, Health{Value: 5})
+	if !storage.Has(1000) || storage.GetDirectUnsafe(1000).Value != 5 {
+		t.Fatal("entity past the storage's capacity was not stored")
+	}
+}
+
+func TestEntityLimitRefusesNewEntities(t *testing.T) {
+	world := NewWorld(4, WithEntityLimit(10))
+	for i := 0; i < 10; i++ {
+		mustCreateEntity(t, world)
+	}
+	if _, err := world.CreateEntity(); !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("CreateEntity past the limit: %v, want ErrEntityLimit", err)
+	}
+	if _, err := world.Spawn(Prefab{Transform: &Transform{}}); !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("Spawn past the limit: %v, want ErrEntityLimit", err)
+	}
+
+	// Ids are not reused, so destroying an entity frees no room
+	world.DestroyEntity(3)
+	if _, err := world.CreateEntity(); !errors.Is(err, ErrEntityLimit) {
+		t.Fatalf("CreateEntity after a destroy: %v, want ErrEntityLimit", err)
+	}
+	// Growth stops at the limit instead of doubling past it
+	if size := world.(*UltraOptimizedECS).activeEntities.size; size != 10 {
+		t.Fatalf("capacity %d, want the limit of 10", size)
+	}
+}
@@ -0,0 +1,138 @@
+package ecs
+
+import (
+	"iter"
+	"math/bits"
+	"sync"
+)
+
+// A frozen copy of the world's entities and components, taken with Snapshot between ticks. A view
+// never changes, so any number of goroutines (render, audio, network) can read it while the
+// simulation goroutine advances the live world - no lock around the world. Copying is a memcpy of
+// the dense arrays; Release hands the buffers back so the next Snapshot reuses them.
+//
+//	view := ecs.Snapshot() // simulation goroutine, after a tick
+//	go func() {
+//		defer view.Release()
+//		for entity, transform := range ViewEach[Transform](view) { draw(entity, transform) }
+//	}()
+type WorldView struct {
+	owner      *UltraOptimizedECS
+	active     []uint64
+	transforms frozenStorage[Transform]
+	velocities frozenStorage[Velocity]
+	healths    frozenStorage[Health]
+}
+
+type frozenStorage[T any] struct {
+	dense         []T
+	members       []uint64
+	entityToIndex []uint32
+}
+
+func (f *frozenStorage[T]) capture(s *UltraOptimizedComponentStorage[T]) {
+	f.dense = append(f.dense[:0], s.dense...)
+	f.members = append(f.members[:0], s.entityBitset.words...)
+	f.entityToIndex = append(f.entityToIndex[:0], s.entityToIndex...)
+}
+
+func (f *frozenStorage[T]) get(entity uint32) (T, bool) {
+	word := int(entity >> 6)
+	if word >= len(f.members) || f.members[word]&(1<<(entity&63)) == 0 {
+		var zero T
+		return zero, false
+	}
+	return f.dense[f.entityToIndex[entity]], true
+}
+
+// Released views waiting for reuse; Release may run on any goroutine
+type viewPool struct {
+	mu   sync.Mutex
+	free []*WorldView
+}
+
+// Copy the current state into a view. Call it from the goroutine that mutates the world, while it
+// is not mutating it.
+func (ecs *UltraOptimizedECS) Snapshot() *WorldView {
+	ecs.views.mu.Lock()
+	var view *WorldView
+	if last := len(ecs.views.free) - 1; last >= 0 {
+		view = ecs.views.free[last]
+		ecs.views.free = ecs.views.free[:last]
+	}
+	ecs.views.mu.Unlock()
+	if view == nil {
+		view = &WorldView{owner: ecs}
+	}
+
+	view.active = append(view.active[:0], ecs.activeEntities.words...)
+	view.transforms.capture(ecs.transforms)
+	view.velocities.capture(ecs.velocities)
+	view.healths.capture(ecs.healths)
+	return view
+}
+
+// Give the view's buffers back to its world. The view must not be read afterwards.
+func (v *WorldView) Release() {
+	pool := &v.owner.views
+	pool.mu.Lock()
+	pool.free = append(pool.free, v)
+	pool.mu.Unlock()
+}
+
+func (v *WorldView) Alive(entity uint32) bool {
+	word := int(entity >> 6)
+	return word < len(v.active) && v.active[word]&(1<<(entity&63)) != 0
+}
+
+func (v *WorldView) EntityCount() int {
+	count := 0
+	for _, word := range v.active {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+func (v *WorldView) Transform(entity uint32) (Transform, bool) {
+	return v.transforms.get(entity)
+}
+
+func (v *WorldView) Velocity(entity uint32) (Velocity, bool) {
+	return v.velocities.get(entity)
+}
+
+func (v *WorldView) Health(entity uint32) (Health, bool) {
+	return v.healths.get(entity)
+}
+
+// Every entity of the view with component T and its value, in ascending entity order
+func ViewEach[T any](v *WorldView) iter.Seq2[uint32, T] {
+	return func(yield func(uint32, T) bool) {
+		frozen := frozenFor[T](v)
+		for wordIndex, word := range v.active {
+			word &= frozen.members[wordIndex]
+			base := uint32(wordIndex << 6)
+			for word != 0 {
+				entity := base + uint32(bits.TrailingZeros64(word))
+				if !yield(entity, frozen.dense[frozen.entityToIndex[entity]]) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+func frozenFor[T any](v *WorldView) *frozenStorage[T] {
+	if frozen, ok := any(&v.transforms).(*frozenStorage[T]); ok {
+		return frozen
+	}
+	if frozen, ok := any(&v.velocities).(*frozenStorage[T]); ok {
+		return frozen
+	}
+	if frozen, ok := any(&v.healths).(*frozenStorage[T]); ok {
+		return frozen
+	}
+	StorageOf[T](v.owner) // panics with the component's name
+	return nil
+}
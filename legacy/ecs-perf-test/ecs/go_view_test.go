@@ -0,0 +1,75 @@
+package ecs
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnapshotsStayFrozenWhileTheWorldMoves(t *testing.T) {
+	ecs := NewUltraOptimizedECS(200)
+	for i := 0; i < 100; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{X: float32(i)})
+		if i%2 == 0 {
+			ecs.AddVelocity(entity, Velocity{DX: 1})
+		}
+	}
+
+	view := ecs.Snapshot()
+	ecs.UpdateTransformSystem()
+	ecs.DestroyEntity(4)
+	ecs.AddHealth(6, Health{Value: 5})
+
+	if transform, ok := view.Transform(4); !ok || transform.X != 4 || !view.Alive(4) {
+		t.Fatalf("view lost entity 4 or saw the tick: %+v, %v", transform, ok)
+	}
+	if _, ok := view.Health(6); ok || view.EntityCount() != 100 {
+		t.Fatal("view saw changes made after the snapshot")
+	}
+	sum := float32(0)
+	for entity, velocity := range ViewEach[Velocity](view) {
+		if entity%2 != 0 {
+			t.Fatalf("entity %d has no velocity", entity)
+		}
+		sum += velocity.DX
+	}
+	if sum != 50 {
+		t.Fatalf("velocity sum %v, want 50", sum)
+	}
+
+	// Released buffers are reused: steady-state snapshots don't allocate
+	view.Release()
+	allocs := testing.AllocsPerRun(100, func() {
+		ecs.Snapshot().Release()
+	})
+	if allocs != 0 {
+		t.Fatalf("snapshot allocated %v times, want 0", allocs)
+	}
+}
+
+func TestReadersIterateWhileTheSimulationTicks(t *testing.T) {
+	ecs := NewUltraOptimizedECS(300)
+	for i := 0; i < 256; i++ {
+		entity := mustCreateEntity(t, ecs)
+		ecs.AddTransform(entity, Transform{})
+		ecs.AddVelocity(entity, Velocity{DX: 1})
+	}
+
+	var readers sync.WaitGroup
+	for tick := 1; tick <= 50; tick++ {
+		ecs.UpdateTransformSystem()
+		view := ecs.Snapshot()
+		readers.Add(1)
+		go func(tick int) {
+			defer readers.Done()
+			defer view.Release()
+			for entity, transform := range ViewEach[Transform](view) {
+				if transform.X != float32(tick) {
+					t.Errorf("tick %d: entity %d at %v", tick, entity, transform.X)
+					return
+				}
+			}
+		}(tick)
+	}
+	readers.Wait()
+}
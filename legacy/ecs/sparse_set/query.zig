@@ -1,6 +1,7 @@
 const std = @import("std");
 const World = @import("world.zig").World;
 const EntityID = @import("world.zig").EntityID;
+const alive_bit = @import("world.zig").alive_bit;
 const components = @import("../components/mod.zig");
 
 pub fn Query(comptime component_types: anytype) type {
@@ -9,53 +10,43 @@ pub fn Query(comptime component_types: anytype) type {
         
         world: *World,
         required_mask: u64,
-        entities: std.ArrayList(EntityID),
-        current_index: usize,
+        // Next entity to test - walking the mask array yields matches in ascending entity order
+        current_entity: EntityID,
         
         pub fn init(world: *World) Self {
-            // Calculate required component mask
-            var required_mask: u64 = 0;
+            // Calculate required component mask; destroyed entities never match
+            var required_mask: u64 = alive_bit;
             inline for (component_types) |ComponentType| {
                 required_mask |= components.componentBit(ComponentType);
             }
             
-            var query = Self{
+            return Self{
                 .world = world,
                 .required_mask = required_mask,
-                .entities = std.ArrayList(EntityID).init(world.allocator),
-                .current_index = 0,
+                .current_entity = 1,
             };
-            
-            // Find entities that match the query
-            var entity_iter = world.component_masks.iterator();
-            while (entity_iter.next()) |entry| {
-                const entity = entry.key_ptr.*;
-                const mask = entry.value_ptr.*;
-                
-                if ((mask & required_mask) == required_mask) {
-                    query.entities.append(entity) catch {}; // TODO: Handle error properly
-                }
-            }
-            
-            return query;
         }
         
         pub fn deinit(self: *Self) void {
-            self.entities.deinit();
+            _ = self;
         }
         
         pub fn next(self: *Self) ?EntityID {
-            if (self.current_index >= self.entities.items.len) {
-                return null;
+            const masks = self.world.component_masks.items;
+            while (self.current_entity < masks.len) {
+                const entity = self.current_entity;
+                self.current_entity += 1;
+                if ((masks[entity] & self.required_mask) == self.required_mask) return entity;
             }
-            
-            const entity = self.entities.items[self.current_index];
-            self.current_index += 1;
-            return entity;
+            return null;
         }
         
         pub fn count(self: *Self) u32 {
-            return @intCast(self.entities.items.len);
+            var total: u32 = 0;
+            for (self.world.component_masks.items) |mask| {
+                if ((mask & self.required_mask) == self.required_mask) total += 1;
+            }
+            return total;
         }
     };
 }
\ No newline at end of file
@@ -3,6 +3,91 @@ const components = @import("../components/mod.zig");
 
 pub const EntityID = u32;
 
+// Sparse arrays are split into pages allocated on first use, so a component only a handful of
+// entities carry costs one page instead of a slot per entity
+const page_bits = 10;
+const page_size = 1 << page_bits;
+const empty_slot = std.math.maxInt(u32);
+
+// Set in an entity's mask while it is alive; the low bits are component bits
+pub const alive_bit: u64 = 1 << 63;
+
+/// Dense component array with a paged entity -> dense index lookup
+fn Storage(comptime T: type) type {
+    return struct {
+        const Self = @This();
+        const Page = [page_size]u32;
+
+        dense: std.ArrayList(T),
+        // Owner of each dense slot, for fixing up the lookup on swap-remove
+        entities: std.ArrayList(EntityID),
+        pages: std.ArrayList(?*Page),
+
+        fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .dense = std.ArrayList(T).init(allocator),
+                .entities = std.ArrayList(EntityID).init(allocator),
+                .pages = std.ArrayList(?*Page).init(allocator),
+            };
+        }
+
+        fn deinit(self: *Self) void {
+            const allocator = self.pages.allocator;
+            for (self.pages.items) |page| {
+                if (page) |p| allocator.destroy(p);
+            }
+            self.pages.deinit();
+            self.entities.deinit();
+            self.dense.deinit();
+        }
+
+        fn indexOf(self: *const Self, entity: EntityID) ?u32 {
+            const page_index = entity >> page_bits;
+            if (page_index >= self.pages.items.len) return null;
+            const page = self.pages.items[page_index] orelse return null;
+            const index = page[entity & (page_size - 1)];
+            return if (index == empty_slot) null else index;
+        }
+
+        fn slot(self: *Self, entity: EntityID) !*u32 {
+            const page_index = entity >> page_bits;
+            if (page_index >= self.pages.items.len) {
+                try self.pages.appendNTimes(null, page_index + 1 - self.pages.items.len);
+            }
+            const page = self.pages.items[page_index] orelse blk: {
+                const fresh = try self.pages.allocator.create(Page);
+                @memset(fresh, empty_slot);
+                self.pages.items[page_index] = fresh;
+                break :blk fresh;
+            };
+            return &page[entity & (page_size - 1)];
+        }
+
+        fn put(self: *Self, entity: EntityID, component: T) !void {
+            const entry = try self.slot(entity);
+            try self.dense.ensureUnusedCapacity(1);
+            try self.entities.ensureUnusedCapacity(1);
+            entry.* = @intCast(self.dense.items.len);
+            self.dense.appendAssumeCapacity(component);
+            self.entities.appendAssumeCapacity(entity);
+        }
+
+        fn remove(self: *Self, entity: EntityID) void {
+            const index = self.indexOf(entity) orelse return;
+            const last: u32 = @intCast(self.dense.items.len - 1);
+            if (index != last) {
+                const moved = self.entities.items[last];
+                self.dense.items[index] = self.dense.items[last];
+                self.entities.items[index] = moved;
+                (self.slot(moved) catch unreachable).* = index;
+            }
+            _ = self.dense.pop();
+            _ = self.entities.pop();
+            (self.slot(entity) catch unreachable).* = empty_slot;
+        }
+    };
+}
+
 pub const World = struct {
     allocator: std.mem.Allocator,
     
@@ -10,22 +95,15 @@ pub const World = struct {
     next_entity_id: EntityID,
     entity_count: u32,
     
-    // Component storage - dense arrays
-    transforms: std.ArrayList(components.Transform),
-    physics: std.ArrayList(components.Physics),
-    sprites: std.ArrayList(components.Sprite),
-    players: std.ArrayList(components.Player),
-    enemies: std.ArrayList(components.Enemy),
+    // Component storage - dense arrays behind paged sparse lookups
+    transforms: Storage(components.Transform),
+    physics: Storage(components.Physics),
+    sprites: Storage(components.Sprite),
+    players: Storage(components.Player),
+    enemies: Storage(components.Enemy),
     
-    // Entity -> component index mappings (sparse sets)
-    entity_to_transform: std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80),
-    entity_to_physics: std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80),
-    entity_to_sprite: std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80),
-    entity_to_player: std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80),
-    entity_to_enemy: std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80),
-    
-    // Component masks for fast queries
-    component_masks: std.HashMap(EntityID, u64, std.hash_map.AutoContext(EntityID), 80),
+    // Component masks indexed by entity, for fast and ordered queries
+    component_masks: std.ArrayList(u64),
     
     pub fn init(allocator: std.mem.Allocator) World {
         return World{
@@ -33,19 +111,13 @@ pub const World = struct {
             .next_entity_id = 1, // Start from 1 so 0 can be invalid
             .entity_count = 0,
             
-            .transforms = std.ArrayList(components.Transform).init(allocator),
-            .physics = std.ArrayList(components.Physics).init(allocator),
-            .sprites = std.ArrayList(components.Sprite).init(allocator),
-            .players = std.ArrayList(components.Player).init(allocator),
-            .enemies = std.ArrayList(components.Enemy).init(allocator),
-            
-            .entity_to_transform = std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80).init(allocator),
-            .entity_to_physics = std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80).init(allocator),
-            .entity_to_sprite = std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80).init(allocator),
-            .entity_to_player = std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80).init(allocator),
-            .entity_to_enemy = std.HashMap(EntityID, u32, std.hash_map.AutoContext(EntityID), 80).init(allocator),
+            .transforms = Storage(components.Transform).init(allocator),
+            .physics = Storage(components.Physics).init(allocator),
+            .sprites = Storage(components.Sprite).init(allocator),
+            .players = Storage(components.Player).init(allocator),
+            .enemies = Storage(components.Enemy).init(allocator),
             
-            .component_masks = std.HashMap(EntityID, u64, std.hash_map.AutoContext(EntityID), 80).init(allocator),
+            .component_masks = std.ArrayList(u64).init(allocator),
         };
     }
     
@@ -56,28 +128,28 @@ pub const World = struct {
         self.players.deinit();
         self.enemies.deinit();
         
-        self.entity_to_transform.deinit();
-        self.entity_to_physics.deinit();
-        self.entity_to_sprite.deinit();
-        self.entity_to_player.deinit();
-        self.entity_to_enemy.deinit();
-        
         self.component_masks.deinit();
     }
     
     pub fn createEntity(self: *World) !EntityID {
         const entity_id = self.next_entity_id;
+
+        // Slot 0 stays unused so masks can be indexed by entity directly
+        try self.component_masks.ensureTotalCapacity(entity_id + 1);
+        while (self.component_masks.items.len <= entity_id) self.component_masks.appendAssumeCapacity(0);
+        self.component_masks.items[entity_id] = alive_bit;
+
         self.next_entity_id += 1;
         self.entity_count += 1;
-        
-        // Initialize with empty component mask
-        try self.component_masks.put(entity_id, 0);
-        
         return entity_id;
     }
     
+    pub fn isAlive(self: *const World, entity: EntityID) bool {
+        return self.maskOf(entity) & alive_bit != 0;
+    }
+
     pub fn destroyEntity(self: *World, entity: EntityID) void {
-        if (!self.component_masks.contains(entity)) return;
+        if (!self.isAlive(entity)) return;
         
         // Remove all components
         self.removeComponent(entity, components.Transform);
@@ -86,148 +158,59 @@ pub const World = struct {
         self.removeComponent(entity, components.Player);
         self.removeComponent(entity, components.Enemy);
         
-        _ = self.component_masks.remove(entity);
+        self.component_masks.items[entity] = 0;
         self.entity_count -= 1;
     }
     
     pub fn addComponent(self: *World, entity: EntityID, component: anytype) !void {
         const T = @TypeOf(component);
         
-        if (!self.component_masks.contains(entity)) {
+        if (!self.isAlive(entity)) {
             return error.InvalidEntity;
         }
+        if (self.hasComponent(entity, T)) return; // Already has component
         
-        switch (T) {
-            components.Transform => {
-                if (self.entity_to_transform.contains(entity)) return; // Already has component
-                
-                const index = @as(u32, @intCast(self.transforms.items.len));
-                try self.transforms.append(component);
-                try self.entity_to_transform.put(entity, index);
-                
-                // Update component mask
-                const current_mask = self.component_masks.get(entity) orelse 0;
-                try self.component_masks.put(entity, current_mask | components.componentBit(T));
-            },
-            components.Physics => {
-                if (self.entity_to_physics.contains(entity)) return;
-                
-                const index = @as(u32, @intCast(self.physics.items.len));
-                try self.physics.append(component);
-                try self.entity_to_physics.put(entity, index);
-                
-                const current_mask = self.component_masks.get(entity) orelse 0;
-                try self.component_masks.put(entity, current_mask | components.componentBit(T));
-            },
-            components.Sprite => {
-                if (self.entity_to_sprite.contains(entity)) return;
-                
-                const index = @as(u32, @intCast(self.sprites.items.len));
-                try self.sprites.append(component);
-                try self.entity_to_sprite.put(entity, index);
-                
-                const current_mask = self.component_masks.get(entity) orelse 0;
-                try self.component_masks.put(entity, current_mask | components.componentBit(T));
-            },
-            components.Player => {
-                if (self.entity_to_player.contains(entity)) return;
-                
-                const index = @as(u32, @intCast(self.players.items.len));
-                try self.players.append(component);
-                try self.entity_to_player.put(entity, index);
-                
-                const current_mask = self.component_masks.get(entity) orelse 0;
-                try self.component_masks.put(entity, current_mask | components.componentBit(T));
-            },
-            components.Enemy => {
-                if (self.entity_to_enemy.contains(entity)) return;
-                
-                const index = @as(u32, @intCast(self.enemies.items.len));
-                try self.enemies.append(component);
-                try self.entity_to_enemy.put(entity, index);
-                
-                const current_mask = self.component_masks.get(entity) orelse 0;
-                try self.component_masks.put(entity, current_mask | components.componentBit(T));
-            },
-            else => @compileError("Unknown component type: " ++ @typeName(T)),
-        }
+        try self.storage(T).put(entity, component);
+        self.component_masks.items[entity] |= components.componentBit(T);
     }
     
     pub fn removeComponent(self: *World, entity: EntityID, comptime T: type) void {
-        if (!self.component_masks.contains(entity)) return;
+        if (!self.hasComponent(entity, T)) return;
         
-        switch (T) {
-            components.Transform => {
-                if (self.entity_to_transform.remove(entity)) {
-                    // TODO: Implement efficient removal (swap with last element)
-                    const current_mask = self.component_masks.get(entity) orelse 0;
-                    _ = self.component_masks.put(entity, current_mask & ~components.componentBit(T)) catch {};
-                }
-            },
-            components.Physics => {
-                if (self.entity_to_physics.remove(entity)) {
-                    const current_mask = self.component_masks.get(entity) orelse 0;
-                    _ = self.component_masks.put(entity, current_mask & ~components.componentBit(T)) catch {};
-                }
-            },
-            components.Sprite => {
-                if (self.entity_to_sprite.remove(entity)) {
-                    const current_mask = self.component_masks.get(entity) orelse 0;
-                    _ = self.component_masks.put(entity, current_mask & ~components.componentBit(T)) catch {};
-                }
-            },
-            components.Player => {
-                if (self.entity_to_player.remove(entity)) {
-                    const current_mask = self.component_masks.get(entity) orelse 0;
-                    _ = self.component_masks.put(entity, current_mask & ~components.componentBit(T)) catch {};
-                }
-            },
-            components.Enemy => {
-                if (self.entity_to_enemy.remove(entity)) {
-                    const current_mask = self.component_masks.get(entity) orelse 0;
-                    _ = self.component_masks.put(entity, current_mask & ~components.componentBit(T)) catch {};
-                }
-            },
-            else => @compileError("Unknown component type: " ++ @typeName(T)),
-        }
+        self.storage(T).remove(entity);
+        self.component_masks.items[entity] &= ~components.componentBit(T);
     }
     
-    pub fn hasComponent(self: *World, entity: EntityID, comptime T: type) bool {
-        const mask = self.component_masks.get(entity) orelse 0;
-        return (mask & components.componentBit(T)) != 0;
+    pub fn hasComponent(self: *const World, entity: EntityID, comptime T: type) bool {
+        return (self.maskOf(entity) & components.componentBit(T)) != 0;
     }
     
     pub fn getComponent(self: *World, entity: EntityID, comptime T: type) ?*T {
         if (!self.hasComponent(entity, T)) return null;
         
-        return switch (T) {
-            components.Transform => {
-                const index = self.entity_to_transform.get(entity) orelse return null;
-                return &self.transforms.items[index];
-            },
-            components.Physics => {
-                const index = self.entity_to_physics.get(entity) orelse return null;
-                return &self.physics.items[index];
-            },
-            components.Sprite => {
-                const index = self.entity_to_sprite.get(entity) orelse return null;
-                return &self.sprites.items[index];
-            },
-            components.Player => {
-                const index = self.entity_to_player.get(entity) orelse return null;
-                return &self.players.items[index];
-            },
-            components.Enemy => {
-                const index = self.entity_to_enemy.get(entity) orelse return null;
-                return &self.enemies.items[index];
-            },
-            else => @compileError("Unknown component type: " ++ @typeName(T)),
-        };
+        const component_storage = self.storage(T);
+        const index = component_storage.indexOf(entity) orelse return null;
+        return &component_storage.dense.items[index];
     }
     
     pub fn query(self: *World, comptime component_types: anytype) @import("query.zig").Query(component_types) {
         return @import("query.zig").Query(component_types).init(self);
     }
+
+    pub fn maskOf(self: *const World, entity: EntityID) u64 {
+        return if (entity < self.component_masks.items.len) self.component_masks.items[entity] else 0;
+    }
+
+    fn storage(self: *World, comptime T: type) *Storage(T) {
+        return switch (T) {
+            components.Transform => &self.transforms,
+            components.Physics => &self.physics,
+            components.Sprite => &self.sprites,
+            components.Player => &self.players,
+            components.Enemy => &self.enemies,
+            else => @compileError("Unknown component type: " ++ @typeName(T)),
+        };
+    }
 };
 
 pub fn runCompatibilityTest(allocator: std.mem.Allocator) !void {
@@ -253,5 +236,96 @@ pub fn runCompatibilityTest(allocator: std.mem.Allocator) !void {
         std.debug.print("Transform position: ({d}, {d})\n", .{ transform.position.x, transform.position.y });
     }
     
+    // Removing swaps the last dense element into the hole
+    const other = try world.createEntity();
+    try world.addComponent(other, components.Transform{ .position = .{ .x = 1.0, .y = 2.0 } });
+    world.removeComponent(entity, components.Transform);
+    std.debug.print("Moved transform position: ({d}, {d})\n", .{ world.getComponent(other, components.Transform).?.position.x, world.getComponent(other, components.Transform).?.position.y });
+
     std.debug.print("Sparse Set ECS compatibility test passed!\n", .{});
+}
+
+test "Components are added once and read back by entity" {
+    var world = World.init(std.testing.allocator);
+    defer world.deinit();
+
+    const entity = try world.createEntity();
+    try std.testing.expect(world.isAlive(entity));
+    try world.addComponent(entity, components.Transform{ .position = .{ .x = 3.0, .y = 4.0 } });
+    try world.addComponent(entity, components.Transform{ .position = .{ .x = 9.0, .y = 9.0 } });
+    try world.addComponent(entity, components.Physics{});
+
+    try std.testing.expectEqual(@as(usize, 1), world.transforms.dense.items.len);
+    try std.testing.expectEqual(@as(f32, 3.0), world.getComponent(entity, components.Transform).?.position.x);
+    try std.testing.expect(world.hasComponent(entity, components.Physics));
+    try std.testing.expect(!world.hasComponent(entity, components.Enemy));
+    try std.testing.expectEqual(@as(?*components.Enemy, null), world.getComponent(entity, components.Enemy));
+    try std.testing.expectError(error.InvalidEntity, world.addComponent(999, components.Enemy{}));
+}
+
+test "Removing a component swaps the last dense element into its slot" {
+    var world = World.init(std.testing.allocator);
+    defer world.deinit();
+
+    var entities: [3]EntityID = undefined;
+    for (&entities, 0..) |*entity, i| {
+        entity.* = try world.createEntity();
+        try world.addComponent(entity.*, components.Transform{ .position = .{ .x = @floatFromInt(i), .y = 0.0 } });
+    }
+
+    world.removeComponent(entities[0], components.Transform);
+    try std.testing.expect(!world.hasComponent(entities[0], components.Transform));
+    try std.testing.expectEqual(@as(?*components.Transform, null), world.getComponent(entities[0], components.Transform));
+    try std.testing.expectEqual(@as(usize, 2), world.transforms.dense.items.len);
+    try std.testing.expectEqual(entities[2], world.transforms.entities.items[0]);
+    try std.testing.expectEqual(@as(f32, 2.0), world.getComponent(entities[2], components.Transform).?.position.x);
+    try std.testing.expectEqual(@as(f32, 1.0), world.getComponent(entities[1], components.Transform).?.position.x);
+
+    // Removing the last element needs no swap
+    world.removeComponent(entities[1], components.Transform);
+    try std.testing.expectEqual(@as(f32, 2.0), world.getComponent(entities[2], components.Transform).?.position.x);
+    world.removeComponent(entities[1], components.Transform);
+    try std.testing.expectEqual(@as(usize, 1), world.transforms.dense.items.len);
+}
+
+test "Destroyed entities lose their components and drop out of queries" {
+    var world = World.init(std.testing.allocator);
+    defer world.deinit();
+
+    const first = try world.createEntity();
+    const second = try world.createEntity();
+    try world.addComponent(first, components.Physics{});
+    try world.addComponent(second, components.Physics{});
+
+    world.destroyEntity(first);
+    try std.testing.expect(!world.isAlive(first));
+    try std.testing.expectEqual(@as(u32, 1), world.entity_count);
+    try std.testing.expectEqual(@as(usize, 1), world.physics.dense.items.len);
+
+    var physics_query = world.query(.{components.Physics});
+    defer physics_query.deinit();
+    try std.testing.expectEqual(@as(u32, 1), physics_query.count());
+    try std.testing.expectEqual(@as(?EntityID, second), physics_query.next());
+    try std.testing.expectEqual(@as(?EntityID, null), physics_query.next());
+}
+
+test "Sparse pages are allocated only for entity ranges in use" {
+    var world = World.init(std.testing.allocator);
+    defer world.deinit();
+
+    var last: EntityID = 0;
+    for (0..page_size * 2 + 1) |_| last = try world.createEntity();
+    try world.addComponent(last, components.Enemy{});
+
+    // One page covers the enemy; the ones below it stay unallocated
+    const pages = world.enemies.pages.items;
+    try std.testing.expectEqual(@as(usize, 3), pages.len);
+    try std.testing.expectEqual(@as(?*[page_size]u32, null), pages[0]);
+    try std.testing.expectEqual(@as(?*[page_size]u32, null), pages[1]);
+    try std.testing.expect(pages[2] != null);
+    try std.testing.expectEqual(@as(usize, 0), world.transforms.pages.items.len);
+
+    world.removeComponent(last, components.Enemy);
+    try std.testing.expectEqual(@as(?u32, null), world.enemies.indexOf(last));
+    try std.testing.expectEqual(@as(?u32, null), world.enemies.indexOf(7));
 }
\ No newline at end of file
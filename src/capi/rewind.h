@@ -0,0 +1,92 @@
+/*
+ * rewind - C API for embedding the deterministic simulation core.
+ *
+ * Build with `zig build capi`; this installs librewind (shared) and this header.
+ *
+ * All functions returning int use 0 for success and a negative REWIND_ERR_* code on failure.
+ * Fixed-point values (positions, rotations, velocities) are raw 48.16 integers: 1.0 == 65536.
+ */
+#ifndef REWIND_H
+#define REWIND_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define REWIND_OK 0
+#define REWIND_ERR_INVALID_ARGUMENT -1
+#define REWIND_ERR_OUT_OF_MEMORY -2
+#define REWIND_ERR_ENTITY_LIMIT -3
+#define REWIND_ERR_NO_ENTITY -4
+#define REWIND_ERR_COMPONENT_LIMIT -5
+#define REWIND_ERR_EMPTY_SLOT -6
+
+#define REWIND_INVALID_ENTITY UINT32_MAX
+#define REWIND_MAX_ENTITIES 2048
+#define REWIND_MAX_COMPONENTS 32
+#define REWIND_MAX_INPUT_SIZE 256
+#define REWIND_SNAPSHOT_SLOTS 64
+
+typedef struct rewind_transform {
+    int64_t x, y;
+    int64_t rotation;
+} rewind_transform;
+
+typedef struct rewind_velocity {
+    int64_t x, y;
+    int64_t angular;
+} rewind_velocity;
+
+typedef struct rewind_world rewind_world;
+typedef uint32_t rewind_entity;
+typedef void (*rewind_system_fn)(rewind_world *world, void *user_data);
+
+rewind_world *rewind_world_create(void);
+void rewind_world_destroy(rewind_world *world);
+
+/* Register a plain-data component of `size` bytes. Returns its id (>= 0) or an error. */
+int rewind_component_register(rewind_world *world, size_t size);
+
+rewind_entity rewind_entity_create(rewind_world *world);
+void rewind_entity_destroy(rewind_world *world, rewind_entity entity);
+int rewind_entity_alive(const rewind_world *world, rewind_entity entity);
+
+/* Copies `data` (the component's registered size) into the entity's slot; replaces an existing value */
+int rewind_component_set(rewind_world *world, rewind_entity entity, int component, const void *data);
+/* Pointer to the entity's component, valid until the next structural change, or NULL */
+void *rewind_component_get(rewind_world *world, rewind_entity entity, int component);
+int rewind_component_remove(rewind_world *world, rewind_entity entity, int component);
+
+/* Built-in Transform and Velocity (used by the movement, physics and spatial modules) */
+int rewind_transform_get(rewind_world *world, rewind_entity entity, rewind_transform *out);
+int rewind_transform_set(rewind_world *world, rewind_entity entity, const rewind_transform *value);
+int rewind_velocity_get(rewind_world *world, rewind_entity entity, rewind_velocity *out);
+int rewind_velocity_set(rewind_world *world, rewind_entity entity, const rewind_velocity *value);
+
+/*
+ * Whole table of a registered component: `data` holds REWIND_MAX_ENTITIES slots of `stride`
+ * bytes indexed by entity, `present` one bit per entity (word = entity / 64, bit = entity % 64).
+ */
+int rewind_component_table(rewind_world *world, int component, const void **data, size_t *stride, const uint64_t **present);
+
+/* Systems run in registration order on every step. REWIND_ERR_OUT_OF_MEMORY past 32 systems. */
+int rewind_system_add(rewind_world *world, rewind_system_fn system, void *user_data);
+/* Input for the next step, copied; read back inside systems with rewind_input */
+int rewind_input_push(rewind_world *world, const void *data, size_t size);
+const void *rewind_input(const rewind_world *world, size_t *size);
+/* Advance the tick (by 1/60 s), then run the systems - rewind_tick inside them is the new tick */
+void rewind_step(rewind_world *world);
+uint64_t rewind_tick(const rewind_world *world);
+
+/* Snapshot the whole world (entities, built-in and registered components, input, tick) */
+int rewind_save(rewind_world *world, uint32_t slot);
+int rewind_restore(rewind_world *world, uint32_t slot);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* REWIND_H */
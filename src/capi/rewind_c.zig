@@ -0,0 +1,308 @@
+//! C ABI over the simulation core - see rewind.h for the contract.
+//!
+//! Native hosts cannot instantiate the comptime-typed ECS with their own structs, so components
+//! registered through the C API are raw byte tables indexed by entity. Entity lifetime, the
+//! built-in Transform/Velocity and snapshots still go through the core ECS.
+
+const std = @import("std");
+const core = @import("rewind-core");
+
+const FP = core.FP;
+const FPVector2 = core.FPVector2;
+const Transform = core.components.Transform;
+const Velocity = core.components.Velocity;
+const EntityID = core.EntityID;
+
+pub const MAX_COMPONENTS = 32;
+pub const MAX_SYSTEMS = 32;
+pub const MAX_INPUT_SIZE = 256;
+const SNAPSHOT_SLOTS = 64;
+/// Tick length `rewind_step` moves the world's clock by
+const STEP_DELTA_TIME: f32 = 1.0 / 60.0;
+
+pub const OK: c_int = 0;
+pub const ERR_INVALID_ARGUMENT: c_int = -1;
+pub const ERR_OUT_OF_MEMORY: c_int = -2;
+pub const ERR_ENTITY_LIMIT: c_int = -3;
+pub const ERR_NO_ENTITY: c_int = -4;
+pub const ERR_COMPONENT_LIMIT: c_int = -5;
+pub const ERR_EMPTY_SLOT: c_int = -6;
+
+const Input = struct {
+    bytes: [MAX_INPUT_SIZE]u8 = undefined,
+    len: usize = 0,
+};
+
+const WorldECS = core.ECS(.{
+    .components = &.{ Transform, Velocity },
+    .input = Input,
+    .max_entities = .huge,
+});
+pub const MAX_ENTITIES = WorldECS.max_entities;
+
+comptime {
+    if (MAX_ENTITIES != 2048) @compileError("rewind.h declares REWIND_MAX_ENTITIES as 2048 - keep it in sync with the entity limit");
+}
+
+pub const CTransform = extern struct {
+    x: i64,
+    y: i64,
+    rotation: i64,
+};
+
+pub const CVelocity = extern struct {
+    x: i64,
+    y: i64,
+    angular: i64,
+};
+
+pub const SystemFn = *const fn (world: *World, user_data: ?*anyopaque) callconv(.C) void;
+
+/// Registered component: one `size`-byte slot per entity
+const RawTable = struct {
+    size: usize,
+    data: []u8,
+    present: WorldECS.EntityBitSet,
+
+    fn slot(self: *const RawTable, entity: EntityID) []u8 {
+        return self.data[entity * self.size ..][0..self.size];
+    }
+};
+
+const Snapshot = struct {
+    frame: WorldECS.Frame,
+    tables: [MAX_COMPONENTS]RawTable,
+    table_count: usize,
+};
+
+pub const World = struct {
+    allocator: std.mem.Allocator,
+    ecs: WorldECS,
+    tables: std.BoundedArray(RawTable, MAX_COMPONENTS),
+    systems: std.BoundedArray(struct { run: SystemFn, user_data: ?*anyopaque }, MAX_SYSTEMS),
+    snapshots: [SNAPSHOT_SLOTS]?Snapshot,
+
+    fn frame(self: *World) *WorldECS.Frame {
+        return self.ecs.getFrame();
+    }
+
+    fn table(self: *World, component: c_int) ?*RawTable {
+        if (component < 0 or component >= self.tables.len) return null;
+        return &self.tables.slice()[@intCast(component)];
+    }
+
+    fn alive(self: *const World, entity: EntityID) bool {
+        return entity < MAX_ENTITIES and self.ecs.current_frame.state.active_entities.isSet(entity);
+    }
+
+    fn freeSnapshot(self: *World, slot: usize) void {
+        const snapshot = if (self.snapshots[slot]) |*saved| saved else return;
+        WorldECS.freeSavedFrame(&snapshot.frame);
+        for (snapshot.tables[0..snapshot.table_count]) |saved| self.allocator.free(saved.data);
+        self.snapshots[slot] = null;
+    }
+};
+
+const allocator = std.heap.c_allocator;
+
+pub export fn rewind_world_create() ?*World {
+    const world = allocator.create(World) catch return null;
+    world.* = .{
+        .allocator = allocator,
+        .ecs = WorldECS.init(allocator) catch {
+            allocator.destroy(world);
+            return null;
+        },
+        .tables = .{},
+        .systems = .{},
+        .snapshots = [_]?Snapshot{null} ** SNAPSHOT_SLOTS,
+    };
+    return world;
+}
+
+pub export fn rewind_world_destroy(world: ?*World) void {
+    const w = world orelse return;
+    for (0..SNAPSHOT_SLOTS) |slot| w.freeSnapshot(slot);
+    for (w.tables.slice()) |raw| allocator.free(raw.data);
+    w.ecs.deinit();
+    allocator.destroy(w);
+}
+
+pub export fn rewind_component_register(world: *World, size: usize) c_int {
+    if (size == 0) return ERR_INVALID_ARGUMENT;
+    if (world.tables.len == MAX_COMPONENTS) return ERR_COMPONENT_LIMIT;
+    const data = allocator.alloc(u8, size * MAX_ENTITIES) catch return ERR_OUT_OF_MEMORY;
+    @memset(data, 0);
+    world.tables.appendAssumeCapacity(.{ .size = size, .data = data, .present = WorldECS.EntityBitSet.initEmpty() });
+    return @intCast(world.tables.len - 1);
+}
+
+pub export fn rewind_entity_create(world: *World) EntityID {
+    return world.frame().createEntity() catch core.INVALID_ENTITY;
+}
+
+pub export fn rewind_entity_destroy(world: *World, entity: EntityID) void {
+    if (!world.alive(entity)) return;
+    for (world.tables.slice()) |*raw| raw.present.unset(entity);
+    world.frame().destroyEntity(entity);
+}
+
+pub export fn rewind_entity_alive(world: *const World, entity: EntityID) c_int {
+    return @intFromBool(world.alive(entity));
+}
+
+pub export fn rewind_component_set(world: *World, entity: EntityID, component: c_int, data: ?*const anyopaque) c_int {
+    if (!world.alive(entity)) return ERR_NO_ENTITY;
+    const raw = world.table(component) orelse return ERR_INVALID_ARGUMENT;
+    const source = data orelse return ERR_INVALID_ARGUMENT;
+    @memcpy(raw.slot(entity), @as([*]const u8, @ptrCast(source))[0..raw.size]);
+    raw.present.set(entity);
+    return OK;
+}
+
+pub export fn rewind_component_get(world: *World, entity: EntityID, component: c_int) ?*anyopaque {
+    if (!world.alive(entity)) return null;
+    const raw = world.table(component) orelse return null;
+    if (!raw.present.isSet(entity)) return null;
+    return @ptrCast(raw.slot(entity).ptr);
+}
+
+pub export fn rewind_component_remove(world: *World, entity: EntityID, component: c_int) c_int {
+    if (!world.alive(entity)) return ERR_NO_ENTITY;
+    const raw = world.table(component) orelse return ERR_INVALID_ARGUMENT;
+    raw.present.unset(entity);
+    return OK;
+}
+
+pub export fn rewind_component_table(world: *World, component: c_int, data: *?*const anyopaque, stride: *usize, present: *?[*]const u64) c_int {
+    const raw = world.table(component) orelse return ERR_INVALID_ARGUMENT;
+    data.* = @ptrCast(raw.data.ptr);
+    stride.* = raw.size;
+    present.* = &raw.present.words;
+    return OK;
+}
+
+pub export fn rewind_transform_get(world: *World, entity: EntityID, out: *CTransform) c_int {
+    const transform = world.frame().getComponent(entity, Transform) orelse return ERR_NO_ENTITY;
+    out.* = .{
+        .x = transform.position.x.raw_value,
+        .y = transform.position.y.raw_value,
+        .rotation = transform.rotation.raw_value,
+    };
+    return OK;
+}
+
+pub export fn rewind_transform_set(world: *World, entity: EntityID, value: *const CTransform) c_int {
+    if (!world.alive(entity)) return ERR_NO_ENTITY;
+    const transform = Transform{
+        .position = FPVector2.new(FP.fromRaw(value.x), FP.fromRaw(value.y)),
+        .rotation = FP.fromRaw(value.rotation),
+    };
+    return setBuiltin(world, entity, transform);
+}
+
+pub export fn rewind_velocity_get(world: *World, entity: EntityID, out: *CVelocity) c_int {
+    const velocity = world.frame().getComponent(entity, Velocity) orelse return ERR_NO_ENTITY;
+    out.* = .{
+        .x = velocity.linear.x.raw_value,
+        .y = velocity.linear.y.raw_value,
+        .angular = velocity.angular.raw_value,
+    };
+    return OK;
+}
+
+pub export fn rewind_velocity_set(world: *World, entity: EntityID, value: *const CVelocity) c_int {
+    if (!world.alive(entity)) return ERR_NO_ENTITY;
+    const velocity = Velocity{
+        .linear = FPVector2.new(FP.fromRaw(value.x), FP.fromRaw(value.y)),
+        .angular = FP.fromRaw(value.angular),
+    };
+    return setBuiltin(world, entity, velocity);
+}
+
+fn setBuiltin(world: *World, entity: EntityID, component: anytype) c_int {
+    const frame = world.frame();
+    if (frame.getComponent(entity, @TypeOf(component))) |existing| {
+        existing.* = component;
+        return OK;
+    }
+    frame.addComponent(entity, component) catch |err| return switch (err) {
+        error.OutOfMemory => ERR_OUT_OF_MEMORY,
+        error.EntityLimitExceeded => ERR_ENTITY_LIMIT,
+        else => ERR_NO_ENTITY,
+    };
+    return OK;
+}
+
+pub export fn rewind_system_add(world: *World, system: ?SystemFn, user_data: ?*anyopaque) c_int {
+    const run = system orelse return ERR_INVALID_ARGUMENT;
+    world.systems.append(.{ .run = run, .user_data = user_data }) catch return ERR_OUT_OF_MEMORY;
+    return OK;
+}
+
+pub export fn rewind_input_push(world: *World, data: ?*const anyopaque, size: usize) c_int {
+    if (size > MAX_INPUT_SIZE) return ERR_INVALID_ARGUMENT;
+    var input = &world.frame().input;
+    if (size > 0) {
+        const source = data orelse return ERR_INVALID_ARGUMENT;
+        @memcpy(input.bytes[0..size], @as([*]const u8, @ptrCast(source))[0..size]);
+    }
+    input.len = size;
+    return OK;
+}
+
+pub export fn rewind_input(world: *const World, size: ?*usize) ?*const anyopaque {
+    const input = &world.ecs.current_frame.input;
+    if (size) |out| out.* = input.len;
+    return &input.bytes;
+}
+
+pub export fn rewind_step(world: *World) void {
+    // Start the tick like any other world does - change ticks, observers and the budget with it
+    const current = &world.ecs.current_frame;
+    world.ecs.update(current.input, STEP_DELTA_TIME, current.time + STEP_DELTA_TIME);
+    for (world.systems.slice()) |system| system.run(world, system.user_data);
+}
+
+pub export fn rewind_tick(world: *const World) u64 {
+    return world.ecs.current_frame.frame_number;
+}
+
+pub export fn rewind_save(world: *World, slot: u32) c_int {
+    if (slot >= SNAPSHOT_SLOTS) return ERR_INVALID_ARGUMENT;
+    world.freeSnapshot(slot);
+
+    var snapshot = Snapshot{
+        .frame = world.ecs.saveFrame(allocator) catch return ERR_OUT_OF_MEMORY,
+        .tables = undefined,
+        .table_count = 0,
+    };
+    for (world.tables.slice()) |raw| {
+        const data = allocator.dupe(u8, raw.data) catch {
+            for (snapshot.tables[0..snapshot.table_count]) |saved| allocator.free(saved.data);
+            WorldECS.freeSavedFrame(&snapshot.frame);
+            return ERR_OUT_OF_MEMORY;
+        };
+        snapshot.tables[snapshot.table_count] = .{ .size = raw.size, .data = data, .present = raw.present };
+        snapshot.table_count += 1;
+    }
+    world.snapshots[slot] = snapshot;
+    return OK;
+}
+
+pub export fn rewind_restore(world: *World, slot: u32) c_int {
+    if (slot >= SNAPSHOT_SLOTS) return ERR_INVALID_ARGUMENT;
+    const snapshot = if (world.snapshots[slot]) |*saved| saved else return ERR_EMPTY_SLOT;
+
+    world.ecs.restoreFrame(&snapshot.frame) catch return ERR_OUT_OF_MEMORY;
+    // Tables registered after the save keep their data but lose every entry
+    for (world.tables.slice(), 0..) |*raw, i| {
+        if (i < snapshot.table_count) {
+            @memcpy(raw.data, snapshot.tables[i].data);
+            raw.present = snapshot.tables[i].present;
+        } else {
+            raw.present = WorldECS.EntityBitSet.initEmpty();
+        }
+    }
+    return OK;
+}
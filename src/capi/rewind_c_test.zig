@@ -0,0 +1,128 @@
+const std = @import("std");
+const testing = std.testing;
+const capi = @import("rewind_c.zig");
+
+const World = capi.World;
+
+const Health = extern struct {
+    current: i32,
+    max: i32,
+};
+
+fn healthOf(world: *World, entity: u32, component: c_int) ?Health {
+    const data = capi.rewind_component_get(world, entity, component) orelse return null;
+    return @as(*const Health, @ptrCast(@alignCast(data))).*;
+}
+
+test "Entities carry registered and built-in components" {
+    const world = capi.rewind_world_create().?;
+    defer capi.rewind_world_destroy(world);
+
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_component_register(world, 0));
+    const health = capi.rewind_component_register(world, @sizeOf(Health));
+    try testing.expectEqual(@as(c_int, 0), health);
+
+    const entity = capi.rewind_entity_create(world);
+    try testing.expectEqual(@as(c_int, 1), capi.rewind_entity_alive(world, entity));
+    try testing.expectEqual(@as(?Health, null), healthOf(world, entity, health));
+
+    try testing.expectEqual(capi.OK, capi.rewind_component_set(world, entity, health, &Health{ .current = 80, .max = 100 }));
+    try testing.expectEqual(Health{ .current = 80, .max = 100 }, healthOf(world, entity, health).?);
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_component_set(world, entity, 7, &Health{ .current = 1, .max = 1 }));
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_component_set(world, entity, health, null));
+    try testing.expectEqual(capi.OK, capi.rewind_component_remove(world, entity, health));
+    try testing.expectEqual(@as(?Health, null), healthOf(world, entity, health));
+
+    var transform: capi.CTransform = undefined;
+    try testing.expectEqual(capi.ERR_NO_ENTITY, capi.rewind_transform_get(world, entity, &transform));
+    try testing.expectEqual(capi.OK, capi.rewind_transform_set(world, entity, &.{ .x = 1 << 16, .y = -3 << 16, .rotation = 0 }));
+    try testing.expectEqual(capi.OK, capi.rewind_transform_get(world, entity, &transform));
+    try testing.expectEqual(@as(i64, -3 << 16), transform.y);
+
+    capi.rewind_entity_destroy(world, entity);
+    try testing.expectEqual(@as(c_int, 0), capi.rewind_entity_alive(world, entity));
+    try testing.expectEqual(capi.ERR_NO_ENTITY, capi.rewind_component_set(world, entity, health, &Health{ .current = 1, .max = 1 }));
+    try testing.expectEqual(capi.ERR_NO_ENTITY, capi.rewind_transform_set(world, entity, &transform));
+}
+
+test "Component registration stops at the limit" {
+    const world = capi.rewind_world_create().?;
+    defer capi.rewind_world_destroy(world);
+
+    for (0..capi.MAX_COMPONENTS) |i| {
+        try testing.expectEqual(@as(c_int, @intCast(i)), capi.rewind_component_register(world, 4));
+    }
+    try testing.expectEqual(capi.ERR_COMPONENT_LIMIT, capi.rewind_component_register(world, 4));
+}
+
+fn countRuns(_: *World, user_data: ?*anyopaque) callconv(.C) void {
+    const runs: *u32 = @ptrCast(@alignCast(user_data.?));
+    runs.* += 1;
+}
+
+fn recordTick(world: *World, user_data: ?*anyopaque) callconv(.C) void {
+    const tick: *u64 = @ptrCast(@alignCast(user_data.?));
+    tick.* = capi.rewind_tick(world);
+}
+
+test "Steps start the tick before the systems run" {
+    const world = capi.rewind_world_create().?;
+    defer capi.rewind_world_destroy(world);
+
+    var seen: u64 = 0;
+    try testing.expectEqual(capi.OK, capi.rewind_system_add(world, &recordTick, &seen));
+    capi.rewind_step(world);
+    try testing.expectEqual(@as(u64, 1), seen);
+    capi.rewind_step(world);
+    try testing.expectEqual(@as(u64, 2), seen);
+    try testing.expect(world.ecs.current_frame.time > 0);
+    // Changes made by the systems are stamped with the tick they ran in
+    try testing.expectEqual(@as(u64, 2), world.ecs.current_frame.state.components[0].tick);
+}
+
+test "Systems run on every step until the system table is full" {
+    const world = capi.rewind_world_create().?;
+    defer capi.rewind_world_destroy(world);
+
+    var runs: u32 = 0;
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_system_add(world, null, &runs));
+    for (0..capi.MAX_SYSTEMS) |_| try testing.expectEqual(capi.OK, capi.rewind_system_add(world, &countRuns, &runs));
+    try testing.expectEqual(capi.ERR_OUT_OF_MEMORY, capi.rewind_system_add(world, &countRuns, &runs));
+
+    capi.rewind_step(world);
+    capi.rewind_step(world);
+    try testing.expectEqual(@as(u32, 2 * capi.MAX_SYSTEMS), runs);
+    try testing.expectEqual(@as(u64, 2), capi.rewind_tick(world));
+
+    var oversized: [capi.MAX_INPUT_SIZE + 1]u8 = undefined;
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_input_push(world, &oversized, oversized.len));
+    try testing.expectEqual(capi.OK, capi.rewind_input_push(world, &oversized, capi.MAX_INPUT_SIZE));
+}
+
+test "Snapshots restore entities, built-ins and registered tables" {
+    const world = capi.rewind_world_create().?;
+    defer capi.rewind_world_destroy(world);
+
+    const health = capi.rewind_component_register(world, @sizeOf(Health));
+    const entity = capi.rewind_entity_create(world);
+    try testing.expectEqual(capi.OK, capi.rewind_component_set(world, entity, health, &Health{ .current = 100, .max = 100 }));
+    try testing.expectEqual(capi.OK, capi.rewind_transform_set(world, entity, &.{ .x = 0, .y = 0, .rotation = 0 }));
+    try testing.expectEqual(capi.OK, capi.rewind_save(world, 3));
+
+    try testing.expectEqual(capi.OK, capi.rewind_component_set(world, entity, health, &Health{ .current = 10, .max = 100 }));
+    try testing.expectEqual(capi.OK, capi.rewind_transform_set(world, entity, &.{ .x = 5 << 16, .y = 0, .rotation = 0 }));
+    const spawned = capi.rewind_entity_create(world);
+    capi.rewind_step(world);
+
+    try testing.expectEqual(capi.OK, capi.rewind_restore(world, 3));
+    try testing.expectEqual(@as(u64, 0), capi.rewind_tick(world));
+    try testing.expectEqual(@as(c_int, 0), capi.rewind_entity_alive(world, spawned));
+    try testing.expectEqual(@as(i32, 100), healthOf(world, entity, health).?.current);
+    var transform: capi.CTransform = undefined;
+    try testing.expectEqual(capi.OK, capi.rewind_transform_get(world, entity, &transform));
+    try testing.expectEqual(@as(i64, 0), transform.x);
+
+    try testing.expectEqual(capi.ERR_EMPTY_SLOT, capi.rewind_restore(world, 4));
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_save(world, 64));
+    try testing.expectEqual(capi.ERR_INVALID_ARGUMENT, capi.rewind_restore(world, 64));
+}
@@ -0,0 +1,140 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+const EntityID = ecs.EntityID;
+
+/// Transition source that matches every state
+pub const ANY_STATE: u8 = std.math.maxInt(u8);
+
+pub const AnimationState = struct {
+    name: []const u8,
+    /// Sprite/atlas index of the first frame
+    first_frame: u16,
+    /// Both at least 1 - `AnimationSystem.init` rejects graphs with a 0
+    frame_count: u16 = 1,
+    ticks_per_frame: u16 = 1,
+    looping: bool = true,
+
+    fn durationTicks(self: AnimationState) u32 {
+        return @as(u32, self.frame_count) * self.ticks_per_frame;
+    }
+};
+
+/// Per-entity animation state, advanced by AnimationSystem. `frame` is what the renderer draws.
+pub const Animator = struct {
+    /// Index into the graphs passed to AnimationSystem
+    graph: u8 = 0,
+    state: u8 = 0,
+    /// Ticks spent in the current state
+    ticks_in_state: u32 = 0,
+    frame: u16 = 0,
+};
+
+/// States plus the transitions between them. Graphs are static data shared by every entity that
+/// uses them; only the Animator component changes, so animation snapshots with the frame and
+/// replays identically after a rollback.
+pub fn AnimationGraph(comptime EcsType: type) type {
+    return struct {
+        pub const Condition = *const fn (frame: *EcsType.Frame, entity: EntityID) bool;
+
+        /// Checked in declaration order - the first transition that passes wins
+        pub const Transition = struct {
+            /// Source state, or ANY_STATE
+            from: u8,
+            to: u8,
+            /// Component or input check, e.g. "Velocity is non-zero" or "jump pressed"
+            condition: ?Condition = null,
+            /// Only once a non-looping source state has played through
+            on_finish: bool = false,
+            /// Only after this many ticks in the source state
+            min_ticks: u32 = 0,
+        };
+
+        states: []const AnimationState,
+        transitions: []const Transition,
+
+        /// Fails with error.InvalidAnimationState for a state with no frames or a 0 frame length,
+        /// and error.InvalidTransition for a transition naming a state the graph doesn't have
+        pub fn validate(self: @This()) !void {
+            if (self.states.len == 0 or self.states.len > ANY_STATE) return error.InvalidAnimationState;
+            for (self.states) |state| {
+                if (state.frame_count == 0 or state.ticks_per_frame == 0) return error.InvalidAnimationState;
+            }
+            for (self.transitions) |transition| {
+                if (transition.from != ANY_STATE and transition.from >= self.states.len) return error.InvalidTransition;
+                if (transition.to >= self.states.len) return error.InvalidTransition;
+            }
+        }
+    };
+}
+
+/// Advances every Animator by one tick: takes at most one transition, then derives the frame.
+pub fn AnimationSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+        pub const Graph = AnimationGraph(EcsType);
+
+        graphs: []const Graph,
+
+        /// Checks every graph up front, so ticking never divides by a zero frame length
+        pub fn init(graphs: []const Graph) !Self {
+            for (graphs) |graph| try graph.validate();
+            return Self{ .graphs = graphs };
+        }
+
+        pub fn tick(self: *const Self, frame: *EcsType.Frame) !void {
+            var query = try frame.query(&.{Animator});
+            while (query.nextFast()) |result| {
+                const animator = result.get(Animator);
+                const graph = &self.graphs[animator.graph];
+
+                animator.ticks_in_state += 1;
+                if (nextState(frame, result.entity, graph, animator.*)) |to| {
+                    animator.state = to;
+                    animator.ticks_in_state = 0;
+                }
+                animator.frame = frameOf(graph.states[animator.state], animator.ticks_in_state);
+            }
+        }
+
+        /// Switch immediately (e.g. on spawn or on a gameplay event) without waiting for a transition
+        pub fn play(self: *const Self, animator: *Animator, state: u8) void {
+            animator.state = state;
+            animator.ticks_in_state = 0;
+            animator.frame = frameOf(self.graphs[animator.graph].states[state], 0);
+        }
+
+        /// True once a non-looping state has shown its last frame for its full duration
+        pub fn isFinished(self: *const Self, animator: Animator) bool {
+            const state = self.graphs[animator.graph].states[animator.state];
+            return !state.looping and animator.ticks_in_state >= state.durationTicks();
+        }
+
+        fn nextState(frame: *EcsType.Frame, entity: EntityID, graph: *const Graph, animator: Animator) ?u8 {
+            const state = graph.states[animator.state];
+            const finished = !state.looping and animator.ticks_in_state >= state.durationTicks();
+
+            for (graph.transitions) |transition| {
+                if (transition.from != animator.state and transition.from != ANY_STATE) continue;
+                if (transition.to == animator.state) continue;
+                if (transition.on_finish and !finished) continue;
+                if (animator.ticks_in_state < transition.min_ticks) continue;
+                if (transition.condition) |condition| {
+                    if (!condition(frame, entity)) continue;
+                }
+                return transition.to;
+            }
+            return null;
+        }
+
+        fn frameOf(state: AnimationState, ticks: u32) u16 {
+            var index = ticks / state.ticks_per_frame;
+            if (state.looping) {
+                index %= state.frame_count;
+            } else {
+                index = @min(index, state.frame_count - 1);
+            }
+            return state.first_frame + @as(u16, @intCast(index));
+        }
+    };
+}
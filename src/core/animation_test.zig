@@ -0,0 +1,129 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const animation = @import("animation.zig");
+
+const Animator = animation.Animator;
+
+const Moving = struct {
+    speed: i32 = 0,
+};
+
+const TestInput = struct {
+    jump: bool = false,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Animator, Moving },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestAnimation = animation.AnimationSystem(TestECS);
+const Graph = TestAnimation.Graph;
+
+const IDLE = 0;
+const RUN = 1;
+const JUMP = 2;
+
+fn isMoving(frame: *TestECS.Frame, entity: ecs.EntityID) bool {
+    return frame.getComponent(entity, Moving).?.speed != 0;
+}
+
+fn isStill(frame: *TestECS.Frame, entity: ecs.EntityID) bool {
+    return !isMoving(frame, entity);
+}
+
+fn jumpPressed(frame: *TestECS.Frame, _: ecs.EntityID) bool {
+    return frame.input.jump;
+}
+
+const character = Graph{
+    .states = &.{
+        .{ .name = "idle", .first_frame = 0 },
+        .{ .name = "run", .first_frame = 10, .frame_count = 4, .ticks_per_frame = 2 },
+        .{ .name = "jump", .first_frame = 20, .frame_count = 3, .looping = false },
+    },
+    .transitions = &.{
+        .{ .from = animation.ANY_STATE, .to = JUMP, .condition = jumpPressed },
+        .{ .from = IDLE, .to = RUN, .condition = isMoving },
+        .{ .from = RUN, .to = IDLE, .condition = isStill },
+        .{ .from = JUMP, .to = IDLE, .on_finish = true },
+    },
+};
+
+fn tickFrames(system: *const TestAnimation, frame: *TestECS.Frame, entity: ecs.EntityID, out: []u16) !void {
+    for (out) |*shown| {
+        try system.tick(frame);
+        shown.* = frame.getComponent(entity, Animator).?.frame;
+    }
+}
+
+test "Transitions follow conditions and frames advance per tick" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const system = try TestAnimation.init(&.{character});
+
+    const frame = test_ecs.getFrame();
+    const hero = try frame.createEntity();
+    try frame.addComponent(hero, Animator{});
+    try frame.addComponent(hero, Moving{});
+
+    var frames: [10]u16 = undefined;
+    try tickFrames(&system, frame, hero, frames[0..1]);
+    try testing.expectEqual(@as(u16, 0), frames[0]);
+
+    // Start running: two ticks per frame, looping over 10..13
+    frame.getComponent(hero, Moving).?.speed = 3;
+    try tickFrames(&system, frame, hero, &frames);
+    try testing.expectEqualSlices(u16, &.{ 10, 10, 11, 11, 12, 12, 13, 13, 10, 10 }, &frames);
+
+    // Jump interrupts from any state, plays once and returns to idle
+    frame.input.jump = true;
+    try tickFrames(&system, frame, hero, frames[0..1]);
+    frame.input.jump = false;
+    frame.getComponent(hero, Moving).?.speed = 0;
+    try tickFrames(&system, frame, hero, frames[1..5]);
+    try testing.expectEqualSlices(u16, &.{ 20, 21, 22, 0, 0 }, frames[0..5]);
+    try testing.expectEqual(@as(u8, IDLE), frame.getComponent(hero, Animator).?.state);
+}
+
+test "Animation replays identically after rollback" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const system = try TestAnimation.init(&.{character});
+
+    const frame = test_ecs.getFrame();
+    const hero = try frame.createEntity();
+    try frame.addComponent(hero, Animator{});
+    try frame.addComponent(hero, Moving{ .speed = 1 });
+    var warmup: [3]u16 = undefined;
+    try tickFrames(&system, frame, hero, &warmup);
+
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TestECS.freeSavedFrame(&saved);
+
+    var first: [12]u16 = undefined;
+    try tickFrames(&system, frame, hero, &first);
+
+    try test_ecs.restoreFrame(&saved);
+    var second: [12]u16 = undefined;
+    try tickFrames(&system, frame, hero, &second);
+    try testing.expectEqualSlices(u16, &first, &second);
+}
+
+test "Graphs with empty states or unknown transition targets are rejected" {
+    try testing.expectError(error.InvalidAnimationState, TestAnimation.init(&.{Graph{
+        .states = &.{.{ .name = "blink", .first_frame = 0, .ticks_per_frame = 0 }},
+        .transitions = &.{},
+    }}));
+    try testing.expectError(error.InvalidAnimationState, TestAnimation.init(&.{Graph{
+        .states = &.{.{ .name = "empty", .first_frame = 0, .frame_count = 0 }},
+        .transitions = &.{},
+    }}));
+    try testing.expectError(error.InvalidAnimationState, TestAnimation.init(&.{Graph{ .states = &.{}, .transitions = &.{} }}));
+    try testing.expectError(error.InvalidTransition, TestAnimation.init(&.{Graph{
+        .states = &.{.{ .name = "idle", .first_frame = 0 }},
+        .transitions = &.{.{ .from = animation.ANY_STATE, .to = 1 }},
+    }}));
+}
@@ -0,0 +1,272 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const EntityID = ecs.EntityID;
+
+/// `struct ArrowSchema` from the Arrow C data interface
+/// (https://arrow.apache.org/docs/format/CDataInterface.html)
+pub const ArrowSchema = extern struct {
+    format: [*:0]const u8,
+    name: ?[*:0]const u8 = null,
+    metadata: ?[*]const u8 = null,
+    flags: i64 = 0,
+    n_children: i64 = 0,
+    children: ?[*]*ArrowSchema = null,
+    dictionary: ?*ArrowSchema = null,
+    release: ?*const fn (*ArrowSchema) callconv(.C) void = null,
+    private_data: ?*anyopaque = null,
+};
+
+/// `struct ArrowArray` from the Arrow C data interface
+pub const ArrowArray = extern struct {
+    length: i64 = 0,
+    null_count: i64 = 0,
+    offset: i64 = 0,
+    n_buffers: i64 = 0,
+    n_children: i64 = 0,
+    buffers: ?[*]?*const anyopaque = null,
+    children: ?[*]*ArrowArray = null,
+    dictionary: ?*ArrowArray = null,
+    release: ?*const fn (*ArrowArray) callconv(.C) void = null,
+    private_data: ?*anyopaque = null,
+};
+
+/// Export component tables as Arrow record batches through the C data interface, for handing a
+/// snapshot to analytics or visualization code (pyarrow, DuckDB, Polars) without a file format.
+///
+/// Each component becomes one batch - a struct array with an `entity` column followed by one
+/// column per field, rows in dense-array order. The entity column points straight at the
+/// storage's dense entity array; field columns are gathered out of the dense component array in
+/// one pass, since Arrow columns cannot stride over interleaved structs. Batches therefore stay
+/// valid only while the frame is not structurally changed - export from a saved frame
+/// (`saveFrame`) when the simulation keeps running.
+///
+/// Fixed-point fields export as float64 and vectors as two columns (`field.x`, `field.y`).
+/// Floats keep their width; composite fields (arrays, nested structs, optionals, unions) are
+/// left out.
+///
+/// Usage:
+///   var batch_schema: ArrowSchema = undefined;
+///   var batch: ArrowArray = undefined;
+///   try ArrowExport(GameECS).exportComponent(allocator, &snapshot, Transform, &batch_schema, &batch);
+///   // hand both to the consumer, which calls their release callbacks when done
+pub fn ArrowExport(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+
+    return struct {
+        /// Export every component table of the frame, in registration order
+        pub fn exportFrame(allocator: std.mem.Allocator, frame: *EcsType.Frame, schemas: *[Types.len]ArrowSchema, arrays: *[Types.len]ArrowArray) !void {
+            inline for (Types, 0..) |T, i| {
+                errdefer for (0..i) |done| {
+                    schemas[done].release.?(&schemas[done]);
+                    arrays[done].release.?(&arrays[done]);
+                };
+                try exportComponent(allocator, frame, T, &schemas[i], &arrays[i]);
+            }
+        }
+
+        /// Export one component table as a record batch. Both outputs own their memory and are
+        /// freed by their release callbacks.
+        pub fn exportComponent(allocator: std.mem.Allocator, frame: *EcsType.Frame, comptime T: type, schema_out: *ArrowSchema, array_out: *ArrowArray) !void {
+            const storage = frame.getComponentStorage(T);
+            schema_out.* = try batchSchema(allocator, T);
+            errdefer schema_out.release.?(schema_out);
+            array_out.* = try batchArray(allocator, T, storage.getDenseArray(), storage.getDenseEntities());
+        }
+    };
+}
+
+/// Arrow format string for the columns a field exports as, or null when it is left out
+fn columnFormat(comptime F: type) ?[:0]const u8 {
+    if (F == FP or F == FPVector2) return "g";
+    return switch (@typeInfo(F)) {
+        .bool => "b",
+        .int => |info| intFormat(info.signedness, info.bits),
+        .@"enum" => |info| columnFormat(info.tag_type),
+        .float => |info| if (info.bits == 32) "f" else "g",
+        else => null,
+    };
+}
+
+fn intFormat(comptime signedness: std.builtin.Signedness, comptime bits: u16) ?[:0]const u8 {
+    const signed = signedness == .signed;
+    if (bits <= 8) return if (signed) "c" else "C";
+    if (bits <= 16) return if (signed) "s" else "S";
+    if (bits <= 32) return if (signed) "i" else "I";
+    if (bits <= 64) return if (signed) "l" else "L";
+    return null;
+}
+
+/// Zig type of one value in a column of the given format
+fn ColumnValue(comptime format: [:0]const u8) type {
+    return switch (format[0]) {
+        'c' => i8,
+        'C' => u8,
+        's' => i16,
+        'S' => u16,
+        'i' => i32,
+        'I' => u32,
+        'l' => i64,
+        'L' => u64,
+        'f' => f32,
+        'g' => f64,
+        else => unreachable,
+    };
+}
+
+fn columnCount(comptime T: type) usize {
+    var count: usize = 1;
+    for (schema.runtimeFields(T)) |field| {
+        if (columnFormat(field.type) != null) count += if (field.type == FPVector2) 2 else 1;
+    }
+    return count;
+}
+
+fn createArena(allocator: std.mem.Allocator) !*std.heap.ArenaAllocator {
+    const arena = try allocator.create(std.heap.ArenaAllocator);
+    arena.* = std.heap.ArenaAllocator.init(allocator);
+    return arena;
+}
+
+fn freeArena(private_data: ?*anyopaque) void {
+    const arena: *std.heap.ArenaAllocator = @ptrCast(@alignCast(private_data.?));
+    const allocator = arena.child_allocator;
+    arena.deinit();
+    allocator.destroy(arena);
+}
+
+fn releaseSchema(self: *ArrowSchema) callconv(.C) void {
+    freeArena(self.private_data);
+    self.release = null;
+}
+
+fn releaseArray(self: *ArrowArray) callconv(.C) void {
+    freeArena(self.private_data);
+    self.release = null;
+}
+
+// Children share the parent's arena and are freed with it
+fn releaseChildSchema(self: *ArrowSchema) callconv(.C) void {
+    self.release = null;
+}
+
+fn releaseChildArray(self: *ArrowArray) callconv(.C) void {
+    self.release = null;
+}
+
+fn batchSchema(allocator: std.mem.Allocator, comptime T: type) !ArrowSchema {
+    const arena = try createArena(allocator);
+    errdefer freeArena(arena);
+    const memory = arena.allocator();
+
+    const count = comptime columnCount(T);
+    const children = try memory.alloc(ArrowSchema, count);
+    const pointers = try memory.alloc(*ArrowSchema, count);
+    children[0] = .{ .format = "I", .name = "entity", .release = releaseChildSchema };
+
+    var index: usize = 1;
+    inline for (comptime schema.runtimeFields(T)) |field| {
+        const format = comptime columnFormat(field.type) orelse continue;
+        if (field.type == FPVector2) {
+            children[index] = .{ .format = format, .name = std.fmt.comptimePrint("{s}.x", .{field.name}), .release = releaseChildSchema };
+            children[index + 1] = .{ .format = format, .name = std.fmt.comptimePrint("{s}.y", .{field.name}), .release = releaseChildSchema };
+            index += 2;
+        } else {
+            children[index] = .{ .format = format, .name = field.name, .release = releaseChildSchema };
+            index += 1;
+        }
+    }
+    for (children, pointers) |*child, *pointer| pointer.* = child;
+
+    return .{
+        .format = "+s",
+        .name = std.fmt.comptimePrint("{s}", .{ecs.shortTypeName(T)}),
+        .n_children = @intCast(count),
+        .children = pointers.ptr,
+        .release = releaseSchema,
+        .private_data = arena,
+    };
+}
+
+fn batchArray(allocator: std.mem.Allocator, comptime T: type, dense: []const T, entities: []const EntityID) !ArrowArray {
+    const arena = try createArena(allocator);
+    errdefer freeArena(arena);
+    const memory = arena.allocator();
+
+    const count = comptime columnCount(T);
+    const children = try memory.alloc(ArrowArray, count);
+    const pointers = try memory.alloc(*ArrowArray, count);
+    // Struct arrays have a validity buffer only; null means every row is valid
+    const parent_buffers = try memory.alloc(?*const anyopaque, 1);
+    parent_buffers[0] = null;
+
+    children[0] = try column(memory, entities.ptr, dense.len);
+
+    var index: usize = 1;
+    inline for (comptime schema.runtimeFields(T)) |field| {
+        const format = comptime columnFormat(field.type) orelse continue;
+        if (field.type == FPVector2) {
+            const xs = try memory.alloc(f64, dense.len);
+            const ys = try memory.alloc(f64, dense.len);
+            for (dense, xs, ys) |value, *x, *y| {
+                x.* = @field(value, field.name).x.toFloat(f64);
+                y.* = @field(value, field.name).y.toFloat(f64);
+            }
+            children[index] = try column(memory, xs.ptr, dense.len);
+            children[index + 1] = try column(memory, ys.ptr, dense.len);
+            index += 2;
+        } else if (field.type == bool) {
+            // Booleans are bit-packed, least significant bit first
+            const bits = try memory.alloc(u8, (dense.len + 7) / 8);
+            @memset(bits, 0);
+            for (dense, 0..) |value, row| {
+                if (@field(value, field.name)) bits[row / 8] |= @as(u8, 1) << @intCast(row % 8);
+            }
+            children[index] = try column(memory, bits.ptr, dense.len);
+            index += 1;
+        } else {
+            const values = try memory.alloc(ColumnValue(format), dense.len);
+            for (dense, values) |value, *out| out.* = convert(ColumnValue(format), @field(value, field.name));
+            children[index] = try column(memory, values.ptr, dense.len);
+            index += 1;
+        }
+    }
+    for (children, pointers) |*child, *pointer| pointer.* = child;
+
+    return .{
+        .length = @intCast(dense.len),
+        .n_buffers = 1,
+        .n_children = @intCast(count),
+        .buffers = parent_buffers.ptr,
+        .children = pointers.ptr,
+        .release = releaseArray,
+        .private_data = arena,
+    };
+}
+
+/// Primitive column over `values`: no validity buffer, one data buffer
+fn column(memory: std.mem.Allocator, values: *const anyopaque, length: usize) !ArrowArray {
+    const buffers = try memory.alloc(?*const anyopaque, 2);
+    buffers[0] = null;
+    buffers[1] = values;
+    return .{
+        .length = @intCast(length),
+        .n_buffers = 2,
+        .buffers = buffers.ptr,
+        .release = releaseChildArray,
+    };
+}
+
+fn convert(comptime Out: type, value: anytype) Out {
+    const In = @TypeOf(value);
+    if (In == FP) return value.toFloat(f64);
+    return switch (@typeInfo(In)) {
+        .int => value,
+        .@"enum" => @intFromEnum(value),
+        .float => @floatCast(value),
+        else => unreachable,
+    };
+}
@@ -0,0 +1,103 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const arrow = @import("arrow.zig");
+const components = @import("components.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+
+const Team = enum(u8) { red, blue };
+
+const Unit = struct {
+    health: i32 = 100,
+    alive: bool = true,
+    team: Team = .red,
+    slots: [3]u8 = .{ 0, 0, 0 },
+    weight: f32 = 1,
+};
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Unit },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const Export = arrow.ArrowExport(TestECS);
+
+fn columnData(comptime T: type, array: *const arrow.ArrowArray, child: usize) []const T {
+    const column = array.children.?[child];
+    const data: [*]const T = @ptrCast(@alignCast(column.buffers.?[1].?));
+    return data[0..@intCast(column.length)];
+}
+
+test "Component tables export as Arrow record batches" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        if (i % 3 == 0) try frame.addComponent(entity, Unit{ .health = @intCast(i * 10), .alive = i != 3, .team = .blue });
+        try frame.addComponent(entity, Transform{ .position = fpVec2(1.5, -2), .rotation = fp(0.25) });
+    }
+
+    var schema: arrow.ArrowSchema = undefined;
+    var array: arrow.ArrowArray = undefined;
+    try Export.exportComponent(testing.allocator, frame, Unit, &schema, &array);
+    defer schema.release.?(&schema);
+    defer array.release.?(&array);
+
+    // entity, health, alive, team, weight - the array field has no Arrow column here
+    try testing.expectEqualStrings("+s", std.mem.span(schema.format));
+    try testing.expectEqualStrings("Unit", std.mem.span(schema.name.?));
+    try testing.expectEqual(@as(i64, 5), schema.n_children);
+    const expected = [_][2][]const u8{ .{ "entity", "I" }, .{ "health", "i" }, .{ "alive", "b" }, .{ "team", "C" }, .{ "weight", "f" } };
+    for (expected, 0..) |column, i| {
+        try testing.expectEqualStrings(column[0], std.mem.span(schema.children.?[i].name.?));
+        try testing.expectEqualStrings(column[1], std.mem.span(schema.children.?[i].format));
+    }
+
+    try testing.expectEqual(@as(i64, 4), array.length);
+    try testing.expectEqualSlices(u32, &.{ 0, 3, 6, 9 }, columnData(u32, &array, 0));
+    try testing.expectEqualSlices(i32, &.{ 0, 30, 60, 90 }, columnData(i32, &array, 1));
+    try testing.expectEqual(@as(u8, 0b1101), columnData(u8, &array, 2)[0]);
+    try testing.expectEqualSlices(u8, &.{ 1, 1, 1, 1 }, columnData(u8, &array, 3));
+
+    // The entity column is the storage's own dense array
+    const storage = frame.getComponentStorage(Unit);
+    try testing.expectEqual(@intFromPtr(storage.getDenseEntities().ptr), @intFromPtr(array.children.?[0].buffers.?[1].?));
+}
+
+test "Frames export one batch per component" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = fpVec2(1.5, -2), .rotation = fp(0.25) });
+
+    var schemas: [2]arrow.ArrowSchema = undefined;
+    var arrays: [2]arrow.ArrowArray = undefined;
+    try Export.exportFrame(testing.allocator, frame, &schemas, &arrays);
+    defer for (&schemas, &arrays) |*schema, *array| {
+        schema.release.?(schema);
+        array.release.?(array);
+    };
+
+    // Vectors split into two float64 columns
+    try testing.expectEqualStrings("position.x", std.mem.span(schemas[0].children.?[1].name.?));
+    try testing.expectEqualStrings("position.y", std.mem.span(schemas[0].children.?[2].name.?));
+    try testing.expectEqualSlices(f64, &.{1.5}, columnData(f64, &arrays[0], 1));
+    try testing.expectEqualSlices(f64, &.{-2}, columnData(f64, &arrays[0], 2));
+    try testing.expectEqualSlices(f64, &.{0.25}, columnData(f64, &arrays[0], 3));
+
+    // No Unit rows, but the batch still carries its schema
+    try testing.expectEqual(@as(i64, 0), arrays[1].length);
+    try testing.expectEqual(@as(i64, 5), arrays[1].n_children);
+    try testing.expect(schemas[1].release != null);
+}
@@ -0,0 +1,109 @@
+const std = @import("std");
+
+/// u64 lanes per vector on the compilation target: 4 with AVX2, 2 with SSE2 or NEON, 1 (scalar)
+/// when the target has no vector unit. Picked at compile time - there is no runtime dispatch, so
+/// build for the machine (`-Dcpu=native` or an explicit feature set) to get the wide kernels.
+pub const vector_words: usize = std.simd.suggestVectorLength(u64) orelse 1;
+
+/// Word-slice kernels for entity bitsets, vectorized for the target
+pub const vectorized = Kernels(vector_words);
+
+/// Plain word-at-a-time loops - the reference the vector kernels are tested and benchmarked against
+pub const scalar = Kernels(1);
+
+/// Kernels processing `lanes` words per step, with a scalar tail for lengths that are not a
+/// multiple of `lanes`. All destination slices may alias their inputs.
+pub fn Kernels(comptime lanes: usize) type {
+    return struct {
+        const Lanes = @Vector(lanes, u64);
+
+        /// dest = a & b
+        pub fn intersect(dest: []u64, a: []const u64, b: []const u64) void {
+            std.debug.assert(a.len == dest.len and b.len == dest.len);
+            var i: usize = 0;
+            if (lanes > 1) {
+                while (i + lanes <= dest.len) : (i += lanes) {
+                    const result: Lanes = load(a, i) & load(b, i);
+                    store(dest, i, result);
+                }
+            }
+            while (i < dest.len) : (i += 1) dest[i] = a[i] & b[i];
+        }
+
+        /// dest = a & b & c - one pass instead of two for the common three-component query
+        pub fn intersect3(dest: []u64, a: []const u64, b: []const u64, c: []const u64) void {
+            std.debug.assert(a.len == dest.len and b.len == dest.len and c.len == dest.len);
+            var i: usize = 0;
+            if (lanes > 1) {
+                while (i + lanes <= dest.len) : (i += lanes) {
+                    const result: Lanes = load(a, i) & load(b, i) & load(c, i);
+                    store(dest, i, result);
+                }
+            }
+            while (i < dest.len) : (i += 1) dest[i] = a[i] & b[i] & c[i];
+        }
+
+        /// dest = a | b
+        pub fn unite(dest: []u64, a: []const u64, b: []const u64) void {
+            std.debug.assert(a.len == dest.len and b.len == dest.len);
+            var i: usize = 0;
+            if (lanes > 1) {
+                while (i + lanes <= dest.len) : (i += lanes) {
+                    const result: Lanes = load(a, i) | load(b, i);
+                    store(dest, i, result);
+                }
+            }
+            while (i < dest.len) : (i += 1) dest[i] = a[i] | b[i];
+        }
+
+        /// dest = a & ~b
+        pub fn subtract(dest: []u64, a: []const u64, b: []const u64) void {
+            std.debug.assert(a.len == dest.len and b.len == dest.len);
+            var i: usize = 0;
+            if (lanes > 1) {
+                while (i + lanes <= dest.len) : (i += lanes) {
+                    const result: Lanes = load(a, i) & ~load(b, i);
+                    store(dest, i, result);
+                }
+            }
+            while (i < dest.len) : (i += 1) dest[i] = a[i] & ~b[i];
+        }
+
+        /// dest = a ^ b
+        pub fn symmetricDifference(dest: []u64, a: []const u64, b: []const u64) void {
+            std.debug.assert(a.len == dest.len and b.len == dest.len);
+            var i: usize = 0;
+            if (lanes > 1) {
+                while (i + lanes <= dest.len) : (i += lanes) {
+                    const result: Lanes = load(a, i) ^ load(b, i);
+                    store(dest, i, result);
+                }
+            }
+            while (i < dest.len) : (i += 1) dest[i] = a[i] ^ b[i];
+        }
+
+        /// Number of set bits
+        pub fn popCount(words: []const u64) u32 {
+            var total: u32 = 0;
+            var i: usize = 0;
+            if (lanes > 1) {
+                var counts: @Vector(lanes, u64) = @splat(0);
+                while (i + lanes <= words.len) : (i += lanes) {
+                    const bits: @Vector(lanes, u64) = @intCast(@popCount(load(words, i)));
+                    counts += bits;
+                }
+                total = @intCast(@reduce(.Add, counts));
+            }
+            while (i < words.len) : (i += 1) total += @popCount(words[i]);
+            return total;
+        }
+
+        inline fn load(words: []const u64, index: usize) Lanes {
+            return words[index..][0..lanes].*;
+        }
+
+        inline fn store(words: []u64, index: usize, value: Lanes) void {
+            words[index..][0..lanes].* = value;
+        }
+    };
+}
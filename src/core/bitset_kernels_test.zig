@@ -0,0 +1,62 @@
+const std = @import("std");
+const testing = std.testing;
+const bitset_kernels = @import("bitset_kernels.zig");
+
+const scalar = bitset_kernels.scalar;
+const vectorized = bitset_kernels.vectorized;
+// Forced widths, so the vector paths are covered whatever the test machine has
+const wide = bitset_kernels.Kernels(4);
+const narrow = bitset_kernels.Kernels(2);
+
+fn randomWords(random: std.Random, words: []u64) void {
+    for (words) |*word| word.* = random.int(u64) & random.int(u64);
+}
+
+test "Vector kernels match the scalar loops" {
+    var prng = std.Random.DefaultPrng.init(42);
+    const random = prng.random();
+
+    // Lengths around the lane widths exercise the scalar tails
+    for ([_]usize{ 0, 1, 3, 4, 5, 8, 17, 64 }) |len| {
+        var a: [64]u64 = undefined;
+        var b: [64]u64 = undefined;
+        var c: [64]u64 = undefined;
+        randomWords(random, a[0..len]);
+        randomWords(random, b[0..len]);
+        randomWords(random, c[0..len]);
+
+        var expected: [64]u64 = undefined;
+        var actual: [64]u64 = undefined;
+        inline for (.{ vectorized, wide, narrow }) |K| {
+            scalar.intersect(expected[0..len], a[0..len], b[0..len]);
+            K.intersect(actual[0..len], a[0..len], b[0..len]);
+            try testing.expectEqualSlices(u64, expected[0..len], actual[0..len]);
+
+            scalar.intersect3(expected[0..len], a[0..len], b[0..len], c[0..len]);
+            K.intersect3(actual[0..len], a[0..len], b[0..len], c[0..len]);
+            try testing.expectEqualSlices(u64, expected[0..len], actual[0..len]);
+
+            scalar.unite(expected[0..len], a[0..len], b[0..len]);
+            K.unite(actual[0..len], a[0..len], b[0..len]);
+            try testing.expectEqualSlices(u64, expected[0..len], actual[0..len]);
+
+            scalar.subtract(expected[0..len], a[0..len], b[0..len]);
+            K.subtract(actual[0..len], a[0..len], b[0..len]);
+            try testing.expectEqualSlices(u64, expected[0..len], actual[0..len]);
+
+            scalar.symmetricDifference(expected[0..len], a[0..len], b[0..len]);
+            K.symmetricDifference(actual[0..len], a[0..len], b[0..len]);
+            try testing.expectEqualSlices(u64, expected[0..len], actual[0..len]);
+
+            try testing.expectEqual(scalar.popCount(a[0..len]), K.popCount(a[0..len]));
+        }
+    }
+}
+
+test "Kernels accept a destination aliasing an input" {
+    var a = [_]u64{ 0b1100, 0b1010, std.math.maxInt(u64), 7, 1 };
+    const b = [_]u64{ 0b0110, 0b0011, 0xF0, 5, 0 };
+    wide.intersect(&a, &a, &b);
+    try testing.expectEqualSlices(u64, &.{ 0b0100, 0b0010, 0xF0, 5, 0 }, &a);
+    try testing.expectEqual(@as(u32, 1 + 1 + 4 + 2), wide.popCount(&a));
+}
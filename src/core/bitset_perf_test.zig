@@ -0,0 +1,62 @@
+const std = @import("std");
+const bitset_kernels = @import("bitset_kernels.zig");
+
+// EntityLimit.massive - 4096 entities
+const WORD_COUNT = 64;
+const ITERATIONS = 1_000_000;
+
+pub fn main() !void {
+    var prng = std.Random.DefaultPrng.init(1234);
+    const random = prng.random();
+
+    var a: [WORD_COUNT]u64 = undefined;
+    var b: [WORD_COUNT]u64 = undefined;
+    var c: [WORD_COUNT]u64 = undefined;
+    for (&a, &b, &c) |*x, *y, *z| {
+        x.* = random.int(u64);
+        y.* = random.int(u64);
+        z.* = random.int(u64);
+    }
+
+    std.debug.print("=== Bitset Kernel Performance Test ===\n", .{});
+    std.debug.print("{} words ({} entities), {} iterations, {} u64 lanes per vector\n\n", .{
+        WORD_COUNT,
+        WORD_COUNT * 64,
+        ITERATIONS,
+        bitset_kernels.vector_words,
+    });
+    std.debug.print("{s:<12} {s:>12} {s:>12} {s:>8}\n", .{ "kernel", "scalar ns", "vector ns", "speedup" });
+
+    inline for (.{ "intersect", "intersect3", "unite", "popCount" }) |name| {
+        const scalar_ns = runKernel(bitset_kernels.scalar, name, &a, &b, &c);
+        const vector_ns = runKernel(bitset_kernels.vectorized, name, &a, &b, &c);
+        std.debug.print("{s:<12} {d:>12.2} {d:>12.2} {d:>7.2}x\n", .{
+            name,
+            scalar_ns,
+            vector_ns,
+            scalar_ns / vector_ns,
+        });
+    }
+}
+
+/// Average nanoseconds per call
+fn runKernel(comptime K: type, comptime name: []const u8, a: *[WORD_COUNT]u64, b: *const [WORD_COUNT]u64, c: *const [WORD_COUNT]u64) f64 {
+    var dest: [WORD_COUNT]u64 = undefined;
+    var sink: u64 = 0;
+    const start = std.time.nanoTimestamp();
+    for (0..ITERATIONS) |i| {
+        // Vary an input so the loop is not hoisted
+        a[i % WORD_COUNT] +%= 1;
+        if (comptime std.mem.eql(u8, name, "intersect")) K.intersect(&dest, a, b);
+        if (comptime std.mem.eql(u8, name, "intersect3")) K.intersect3(&dest, a, b, c);
+        if (comptime std.mem.eql(u8, name, "unite")) K.unite(&dest, a, b);
+        if (comptime std.mem.eql(u8, name, "popCount")) {
+            sink +%= K.popCount(a);
+        } else {
+            sink +%= dest[i % WORD_COUNT];
+        }
+    }
+    const elapsed = std.time.nanoTimestamp() - start;
+    std.mem.doNotOptimizeAway(sink);
+    return @as(f64, @floatFromInt(elapsed)) / ITERATIONS;
+}
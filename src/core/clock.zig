@@ -0,0 +1,162 @@
+const std = @import("std");
+
+/// Where everything outside the fixed tick reads time from - loop pacing, tick timing, profiling.
+///
+/// The simulation itself only ever sees tick counts and the fixed `dt`; a `Clock` decides when
+/// ticks happen and how long they took. Production uses `Clock.real`, tests and replays a
+/// `ManualClock` they step by hand, and slow-motion or fast-forward a `ScaledClock` over either.
+/// `now` is monotonic nanoseconds from an arbitrary origin - only differences mean anything.
+pub const Clock = struct {
+    ptr: *anyopaque,
+    vtable: *const VTable,
+
+    pub const VTable = struct {
+        now: *const fn (ptr: *anyopaque) u64,
+        sleep: *const fn (ptr: *anyopaque, ns: u64) void,
+    };
+
+    /// The wall clock and the OS scheduler
+    pub const real = Clock{ .ptr = undefined, .vtable = &.{ .now = realNow, .sleep = realSleep } };
+
+    pub fn now(self: Clock) u64 {
+        return self.vtable.now(self.ptr);
+    }
+
+    /// Wait `ns` nanoseconds of this clock's time
+    pub fn sleep(self: Clock, ns: u64) void {
+        self.vtable.sleep(self.ptr, ns);
+    }
+
+    /// Nanoseconds since an earlier `now`
+    pub fn since(self: Clock, start: u64) u64 {
+        return self.now() -| start;
+    }
+
+    fn realNow(_: *anyopaque) u64 {
+        return @intCast(@max(std.time.nanoTimestamp(), 0));
+    }
+
+    fn realSleep(_: *anyopaque, ns: u64) void {
+        std.time.sleep(ns);
+    }
+};
+
+/// Time that only moves when told to. `sleep` advances it instantly, so a paced loop runs as
+/// fast as the CPU allows while seeing exactly the timeline it would see in real time.
+pub const ManualClock = struct {
+    now_ns: u64 = 0,
+
+    pub fn clock(self: *ManualClock) Clock {
+        return .{ .ptr = self, .vtable = &.{ .now = now, .sleep = sleep } };
+    }
+
+    pub fn advance(self: *ManualClock, ns: u64) void {
+        self.now_ns += ns;
+    }
+
+    fn now(ptr: *anyopaque) u64 {
+        const self: *ManualClock = @ptrCast(@alignCast(ptr));
+        return self.now_ns;
+    }
+
+    fn sleep(ptr: *anyopaque, ns: u64) void {
+        const self: *ManualClock = @ptrCast(@alignCast(ptr));
+        self.now_ns += ns;
+    }
+};
+
+/// Another clock running `numerator / denominator` times as fast - 1/2 for slow motion, 4/1 to
+/// fast-forward a replay, 0/1 to freeze. A rational speed keeps scaled time exact, and changing
+/// speed never makes time jump.
+pub const ScaledClock = struct {
+    source: Clock,
+    numerator: u32 = 1,
+    denominator: u32 = 1,
+    /// Source and scaled time when the current speed took effect
+    source_origin: u64,
+    scaled_origin: u64 = 0,
+
+    pub fn init(source: Clock) ScaledClock {
+        return .{ .source = source, .source_origin = source.now() };
+    }
+
+    pub fn clock(self: *ScaledClock) Clock {
+        return .{ .ptr = self, .vtable = &.{ .now = now, .sleep = sleep } };
+    }
+
+    pub fn setSpeed(self: *ScaledClock, numerator: u32, denominator: u32) void {
+        std.debug.assert(denominator != 0);
+        const source_now = self.source.now();
+        self.scaled_origin = self.scaledAt(source_now);
+        self.source_origin = source_now;
+        self.numerator = numerator;
+        self.denominator = denominator;
+    }
+
+    fn scaledAt(self: *const ScaledClock, source_now: u64) u64 {
+        const elapsed: u128 = source_now -| self.source_origin;
+        return self.scaled_origin + @as(u64, @intCast(elapsed * self.numerator / self.denominator));
+    }
+
+    fn now(ptr: *anyopaque) u64 {
+        const self: *ScaledClock = @ptrCast(@alignCast(ptr));
+        return self.scaledAt(self.source.now());
+    }
+
+    fn sleep(ptr: *anyopaque, ns: u64) void {
+        const self: *ScaledClock = @ptrCast(@alignCast(ptr));
+        // Frozen time never passes - wait the source's worth so callers still yield
+        if (self.numerator == 0) return self.source.sleep(ns);
+        const source_ns: u128 = @as(u128, ns) * self.denominator / self.numerator;
+        self.source.sleep(@intCast(@min(source_ns, std.math.maxInt(u64))));
+    }
+};
+
+/// Fixed-rate tick pacing on a `Clock` - the loop driver of servers and headless runs.
+///
+/// Usage:
+///   var pacer = TickPacer.init(clock, 60);
+///   while (running) {
+///       if (pacer.poll(std.time.ns_per_ms)) try tick();
+///   }
+pub const TickPacer = struct {
+    clock: Clock,
+    tick_ns: u64,
+    /// When the next tick is due
+    next_tick: u64,
+    /// Backlog (in ticks) past which the pacer stops catching up and resyncs
+    max_backlog_ticks: u64 = 10,
+    /// Times a backlog was dropped (host suspended, debugger attached)
+    overruns: u64 = 0,
+
+    pub fn init(clock: Clock, tick_rate: u32) TickPacer {
+        return .{
+            .clock = clock,
+            .tick_ns = std.time.ns_per_s / tick_rate,
+            .next_tick = clock.now(),
+        };
+    }
+
+    /// True when a tick is due now (and schedules the following one); otherwise sleeps up to
+    /// `max_sleep_ns` towards it and returns false, so the caller can serve IO in between
+    pub fn poll(self: *TickPacer, max_sleep_ns: u64) bool {
+        const now = self.clock.now();
+        if (now < self.next_tick) {
+            self.clock.sleep(@min(self.next_tick - now, max_sleep_ns));
+            return false;
+        }
+
+        self.next_tick += self.tick_ns;
+        // Far behind - drop the backlog instead of spiralling
+        if (now > self.next_tick and now - self.next_tick > self.tick_ns * self.max_backlog_ticks) {
+            self.overruns += 1;
+            self.next_tick = now + self.tick_ns;
+        }
+        return true;
+    }
+
+    /// Make the next tick due now, e.g. after a pause
+    pub fn restart(self: *TickPacer) void {
+        self.next_tick = self.clock.now();
+    }
+};
@@ -0,0 +1,54 @@
+const std = @import("std");
+const testing = std.testing;
+const clock_module = @import("clock.zig");
+const ManualClock = clock_module.ManualClock;
+const ScaledClock = clock_module.ScaledClock;
+const TickPacer = clock_module.TickPacer;
+
+test "Scaled clocks change speed without jumping" {
+    var manual = ManualClock{};
+    var scaled = ScaledClock.init(manual.clock());
+    const clock = scaled.clock();
+
+    manual.advance(1000);
+    try testing.expectEqual(@as(u64, 1000), clock.now());
+
+    scaled.setSpeed(1, 2);
+    try testing.expectEqual(@as(u64, 1000), clock.now());
+    manual.advance(1000);
+    try testing.expectEqual(@as(u64, 1500), clock.now());
+
+    // Sleeping a scaled second takes two source seconds
+    clock.sleep(500);
+    try testing.expectEqual(@as(u64, 3000), manual.now_ns);
+    try testing.expectEqual(@as(u64, 2000), clock.now());
+
+    scaled.setSpeed(0, 1);
+    manual.advance(5000);
+    try testing.expectEqual(@as(u64, 2000), clock.now());
+}
+
+test "Tick pacers tick on schedule and drop large backlogs" {
+    var manual = ManualClock{};
+    var pacer = TickPacer.init(manual.clock(), 100);
+
+    // Polling sleeps the manual clock forward - ten ticks take exactly 100ms of its time
+    var ticks: u32 = 0;
+    while (ticks < 10) {
+        if (pacer.poll(std.time.ns_per_ms)) ticks += 1;
+    }
+    try testing.expectEqual(@as(u64, 90 * std.time.ns_per_ms), manual.now_ns);
+
+    // A short stall is caught up tick by tick
+    manual.advance(50 * std.time.ns_per_ms);
+    var caught_up: u32 = 0;
+    while (pacer.poll(0)) caught_up += 1;
+    try testing.expectEqual(@as(u32, 5), caught_up);
+    try testing.expectEqual(@as(u64, 0), pacer.overruns);
+
+    // A long one is dropped
+    manual.advance(std.time.ns_per_s);
+    try testing.expect(pacer.poll(0));
+    try testing.expectEqual(@as(u64, 1), pacer.overruns);
+    try testing.expect(!pacer.poll(0));
+}
@@ -0,0 +1,441 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+const Events = @import("events.zig").Events;
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Collider = components.Collider;
+const Shape = components.Shape;
+
+pub const CollisionPhase = enum(u8) {
+    /// First tick the pair overlaps
+    began,
+    /// Pair overlapped last tick and still does
+    stay,
+    /// Pair overlapped last tick and no longer does (or one side lost its collider)
+    ended,
+};
+
+/// One collision pair event. `a` is always the lower entity id.
+pub const CollisionEvent = struct {
+    phase: CollisionPhase,
+    a: EntityID,
+    b: EntityID,
+};
+
+/// World-space axis-aligned box
+pub const AABB = struct {
+    min: FPVector2,
+    max: FPVector2,
+
+    /// Bounding box of a collider's shape
+    pub fn of(transform: Transform, collider: Collider) AABB {
+        return fromCenter(colliderCenter(transform, collider), collider.shape.boundingHalfExtents());
+    }
+
+    pub fn fromCenter(center: FPVector2, half_extents: FPVector2) AABB {
+        return .{
+            .min = center.sub(half_extents),
+            .max = center.add(half_extents),
+        };
+    }
+
+    /// Strict overlap - boxes that only touch do not collide
+    pub fn overlaps(a: AABB, b: AABB) bool {
+        return a.min.x.lt(b.max.x) and b.min.x.lt(a.max.x) and
+            a.min.y.lt(b.max.y) and b.min.y.lt(a.max.y);
+    }
+};
+
+pub fn layersCollide(a: Collider, b: Collider) bool {
+    return (a.mask & b.layer) != 0 and (b.mask & a.layer) != 0;
+}
+
+/// How two overlapping shapes touch: `normal` points from the first shape toward the second and
+/// moving the second shape by `normal * penetration` separates them.
+pub const Manifold = struct {
+    normal: FPVector2,
+    penetration: FP,
+
+    fn flipped(self: Manifold) Manifold {
+        return .{ .normal = self.normal.negate(), .penetration = self.penetration };
+    }
+};
+
+/// Contact between two shapes, or null when they do not overlap. Exact for boxes and circles;
+/// capsules are resolved as the circle at the point of their segment closest to the other shape.
+pub fn contact(a_center: FPVector2, a: Shape, b_center: FPVector2, b: Shape) ?Manifold {
+    return switch (a) {
+        .box => |a_half| switch (b) {
+            .box => |b_half| boxBoxContact(a_center, a_half, b_center, b_half),
+            .circle => |b_radius| boxCircleContact(a_center, a_half, b_center, b_radius),
+            .capsule => |cap| boxCircleContact(a_center, a_half, closestPointOnSegment(a_center, b_center.sub(cap.half_segment), b_center.add(cap.half_segment)), cap.radius),
+        },
+        .circle => |a_radius| switch (b) {
+            .box => |b_half| if (boxCircleContact(b_center, b_half, a_center, a_radius)) |m| m.flipped() else null,
+            .circle => |b_radius| circleCircleContact(a_center, a_radius, b_center, b_radius),
+            .capsule => |cap| circleCircleContact(a_center, a_radius, closestPointOnSegment(a_center, b_center.sub(cap.half_segment), b_center.add(cap.half_segment)), cap.radius),
+        },
+        .capsule => |cap| switch (b) {
+            .box, .circle => if (contact(b_center, b, a_center, a)) |m| m.flipped() else null,
+            .capsule => |other| blk: {
+                const other_point = closestPointOnSegment(a_center, b_center.sub(other.half_segment), b_center.add(other.half_segment));
+                const point = closestPointOnSegment(other_point, a_center.sub(cap.half_segment), a_center.add(cap.half_segment));
+                break :blk circleCircleContact(point, cap.radius, closestPointOnSegment(point, b_center.sub(other.half_segment), b_center.add(other.half_segment)), other.radius);
+            },
+        },
+    };
+}
+
+fn boxBoxContact(a_center: FPVector2, a_half: FPVector2, b_center: FPVector2, b_half: FPVector2) ?Manifold {
+    const delta = b_center.sub(a_center);
+    const overlap_x = a_half.x.add(b_half.x).sub(delta.x.abs());
+    const overlap_y = a_half.y.add(b_half.y).sub(delta.y.abs());
+    if (overlap_x.raw_value <= 0 or overlap_y.raw_value <= 0) return null;
+
+    // Separate along the axis of least penetration (ties go to y so stacked boxes rest on each other)
+    if (overlap_x.lt(overlap_y)) {
+        return .{ .normal = FPVector2.new(delta.x.sign(), FP.fromRaw(0)), .penetration = overlap_x };
+    }
+    return .{ .normal = FPVector2.new(FP.fromRaw(0), delta.y.sign()), .penetration = overlap_y };
+}
+
+fn boxCircleContact(box_center: FPVector2, half: FPVector2, circle_center: FPVector2, radius: FP) ?Manifold {
+    const box = AABB.fromCenter(box_center, half);
+    const closest = circle_center.clamp(box.min, box.max);
+
+    if (closest.eq(circle_center)) {
+        // Circle center inside the box - push out through the nearest face
+        const local = circle_center.sub(box_center);
+        const face_x = half.x.sub(local.x.abs());
+        const face_y = half.y.sub(local.y.abs());
+        if (face_x.lt(face_y)) {
+            return .{ .normal = FPVector2.new(local.x.sign(), FP.fromRaw(0)), .penetration = face_x.add(radius) };
+        }
+        return .{ .normal = FPVector2.new(FP.fromRaw(0), local.y.sign()), .penetration = face_y.add(radius) };
+    }
+
+    const delta = circle_center.sub(closest);
+    const distance_sq = delta.sqrMagnitude();
+    if (!withinDistance(distance_sq, radius)) return null;
+    const distance = distance_sq.sqrt();
+    return .{ .normal = delta.div(distance), .penetration = radius.sub(distance) };
+}
+
+fn circleCircleContact(a_center: FPVector2, a_radius: FP, b_center: FPVector2, b_radius: FP) ?Manifold {
+    const delta = b_center.sub(a_center);
+    const reach = a_radius.add(b_radius);
+    const distance_sq = delta.sqrMagnitude();
+    if (!withinDistance(distance_sq, reach)) return null;
+
+    if (distance_sq.raw_value == 0) {
+        // Concentric - any direction separates them, pick a fixed one
+        return .{ .normal = FPVector2.UP, .penetration = reach };
+    }
+    const distance = distance_sq.sqrt();
+    return .{ .normal = delta.div(distance), .penetration = reach.sub(distance) };
+}
+
+/// Largest distance from an entity's position to the far edge of its collider, per axis.
+/// Point-based spatial lookups widen their box by this to find every collider that could touch it.
+pub fn maxColliderReach(comptime EcsType: type, frame: *EcsType.Frame) FPVector2 {
+    var reach = FPVector2.ZERO;
+    const storage = frame.getComponentStorage(Collider);
+    for (storage.getDenseArray()) |collider| {
+        reach = FPVector2.max(reach, collider.offset.abs().add(collider.shape.boundingHalfExtents()));
+    }
+    return reach;
+}
+
+pub fn colliderCenter(transform: Transform, collider: Collider) FPVector2 {
+    return transform.position.add(collider.offset);
+}
+
+/// Exact overlap test between two colliders (layers not considered). Like `AABB.overlaps`,
+/// shapes that only touch do not overlap.
+pub fn collidersOverlap(a_transform: Transform, a: Collider, b_transform: Transform, b: Collider) bool {
+    return shapesOverlap(colliderCenter(a_transform, a), a.shape, colliderCenter(b_transform, b), b.shape);
+}
+
+pub fn shapesOverlap(a_center: FPVector2, a: Shape, b_center: FPVector2, b: Shape) bool {
+    return switch (a) {
+        .box => |a_half| switch (b) {
+            .box => |b_half| AABB.fromCenter(a_center, a_half).overlaps(AABB.fromCenter(b_center, b_half)),
+            .circle => |b_radius| circleOverlapsBox(b_center, b_radius, AABB.fromCenter(a_center, a_half)),
+            .capsule => |cap| capsuleOverlapsBox(b_center.sub(cap.half_segment), b_center.add(cap.half_segment), cap.radius, AABB.fromCenter(a_center, a_half)),
+        },
+        .circle => |a_radius| switch (b) {
+            .box => |b_half| circleOverlapsBox(a_center, a_radius, AABB.fromCenter(b_center, b_half)),
+            .circle => |b_radius| withinDistance(FPVector2.distanceSquared(a_center, b_center), a_radius.add(b_radius)),
+            .capsule => |cap| withinDistance(pointSegmentDistanceSquared(a_center, b_center.sub(cap.half_segment), b_center.add(cap.half_segment)), a_radius.add(cap.radius)),
+        },
+        .capsule => |cap| switch (b) {
+            // Capsule-vs-anything is symmetric with the cases above
+            .box, .circle => shapesOverlap(b_center, b, a_center, a),
+            .capsule => |other| withinDistance(
+                segmentSegmentDistanceSquared(a_center.sub(cap.half_segment), a_center.add(cap.half_segment), b_center.sub(other.half_segment), b_center.add(other.half_segment)),
+                cap.radius.add(other.radius),
+            ),
+        },
+    };
+}
+
+pub fn circleOverlapsBox(center: FPVector2, radius: FP, box: AABB) bool {
+    const closest = center.clamp(box.min, box.max);
+    return withinDistance(FPVector2.distanceSquared(center, closest), radius);
+}
+
+/// Capsule (segment p-q swept by `radius`) against a box
+pub fn capsuleOverlapsBox(p: FPVector2, q: FPVector2, radius: FP, box: AABB) bool {
+    if (segmentIntersectsBox(p, q, box)) return true;
+
+    // Disjoint convex shapes in 2D: the closest pair involves a segment endpoint or a box corner
+    var best = FPVector2.distanceSquared(p, p.clamp(box.min, box.max));
+    best = FP.min(best, FPVector2.distanceSquared(q, q.clamp(box.min, box.max)));
+    const corners = [_]FPVector2{ box.min, box.max, FPVector2.new(box.min.x, box.max.y), FPVector2.new(box.max.x, box.min.y) };
+    for (corners) |corner| {
+        best = FP.min(best, pointSegmentDistanceSquared(corner, p, q));
+    }
+    return withinDistance(best, radius);
+}
+
+/// Squared distance from point `c` to segment p-q
+pub fn pointSegmentDistanceSquared(c: FPVector2, p: FPVector2, q: FPVector2) FP {
+    return FPVector2.distanceSquared(c, closestPointOnSegment(c, p, q));
+}
+
+pub fn closestPointOnSegment(c: FPVector2, p: FPVector2, q: FPVector2) FPVector2 {
+    const pq = q.sub(p);
+    const length_sq = pq.sqrMagnitude();
+    if (length_sq.raw_value == 0) return p;
+    const t = c.sub(p).dot(pq).div(length_sq).clamp01();
+    return p.add(pq.mul(t));
+}
+
+/// Squared distance between segments p1-q1 and p2-q2
+pub fn segmentSegmentDistanceSquared(p1: FPVector2, q1: FPVector2, p2: FPVector2, q2: FPVector2) FP {
+    if (segmentsIntersect(p1, q1, p2, q2)) return FP.fromRaw(0);
+    var best = pointSegmentDistanceSquared(p1, p2, q2);
+    best = FP.min(best, pointSegmentDistanceSquared(q1, p2, q2));
+    best = FP.min(best, pointSegmentDistanceSquared(p2, p1, q1));
+    best = FP.min(best, pointSegmentDistanceSquared(q2, p1, q1));
+    return best;
+}
+
+fn segmentsIntersect(p1: FPVector2, q1: FPVector2, p2: FPVector2, q2: FPVector2) bool {
+    const d1 = orientation(p2, q2, p1);
+    const d2 = orientation(p2, q2, q1);
+    const d3 = orientation(p1, q1, p2);
+    const d4 = orientation(p1, q1, q2);
+    // Collinear or touching cases fall through to the endpoint distance checks
+    return d1 * d2 < 0 and d3 * d4 < 0;
+}
+
+// Sign of the turn a -> b -> c (-1, 0, 1)
+fn orientation(a: FPVector2, b: FPVector2, c: FPVector2) i8 {
+    const turn = b.sub(a).cross(c.sub(a));
+    if (turn.raw_value > 0) return 1;
+    if (turn.raw_value < 0) return -1;
+    return 0;
+}
+
+/// Slab test: does segment p-q pass through the interior of the box
+fn segmentIntersectsBox(p: FPVector2, q: FPVector2, box: AABB) bool {
+    if (p.x.gt(box.min.x) and p.x.lt(box.max.x) and p.y.gt(box.min.y) and p.y.lt(box.max.y)) return true;
+
+    const d = q.sub(p);
+    var t_enter = FP.fromRaw(0);
+    var t_exit = FP.fromInt(1);
+    if (!clipAxis(p.x, d.x, box.min.x, box.max.x, &t_enter, &t_exit)) return false;
+    if (!clipAxis(p.y, d.y, box.min.y, box.max.y, &t_enter, &t_exit)) return false;
+    return true;
+}
+
+/// Narrow [t_enter, t_exit] to the part of `origin + t * delta` strictly inside [min, max] on one
+/// axis. Returns false once the interval is empty.
+pub fn clipAxis(origin: FP, delta: FP, min: FP, max: FP, t_enter: *FP, t_exit: *FP) bool {
+    if (delta.raw_value == 0) {
+        return origin.gt(min) and origin.lt(max);
+    }
+    var t0 = min.sub(origin).div(delta);
+    var t1 = max.sub(origin).div(delta);
+    if (t0.gt(t1)) std.mem.swap(FP, &t0, &t1);
+    t_enter.* = FP.max(t_enter.*, t0);
+    t_exit.* = FP.min(t_exit.*, t1);
+    return t_enter.lt(t_exit.*);
+}
+
+// Strict: distance_sq < reach^2
+fn withinDistance(distance_sq: FP, reach: FP) bool {
+    return distance_sq.lt(reach.mul(reach));
+}
+
+/// Collision detection for entities with `Transform` and `Collider`.
+///
+/// Broadphase asks a spatial index (`spatial.Grid`, `spatial.Quadtree` or `spatial.SpatialIndex`,
+/// synced by the caller this tick) for entity positions near each box. Because the index stores
+/// positions, the lookup box is widened by the largest collider reach in the world.
+/// Narrowphase checks the layer masks, the bounding boxes, then the exact shapes
+/// (box, circle, capsule).
+///
+/// Events come out sorted by phase, then `a`, then `b`, so two peers simulating the same
+/// tick see the same event order. With a `bus` they are also emitted into that channel under the
+/// frame's tick, for the systems of the next tick to `drain` - a resimulated tick replaces its
+/// events there like any other channel's.
+///
+/// The contacts carried from one update to the next live outside the frame, so after restoring an
+/// older frame call `rollbackTo` before simulating again - otherwise the first resimulated tick
+/// compares against the contacts of the abandoned future.
+///
+/// Usage:
+///   var contacts = try Events(CollisionEvent).init(allocator, rollback_window + 1);
+///   var collisions = CollisionSystem(GameECS).init(allocator);
+///   collisions.bus = &contacts;
+///   // every tick, after the spatial index is synced
+///   try collisions.update(frame, &index);
+///   // next tick
+///   for (contacts.drain(frame.frame_number)) |contact| if (contact.phase == .began) applyHit(contact);
+pub fn CollisionSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const Pair = struct {
+            a: EntityID,
+            b: EntityID,
+
+            fn lessThan(lhs: Pair, rhs: Pair) bool {
+                if (lhs.a != rhs.a) return lhs.a < rhs.a;
+                return lhs.b < rhs.b;
+            }
+        };
+
+        allocator: std.mem.Allocator,
+        /// Overlapping pairs from the previous update, sorted
+        previous: std.ArrayList(Pair),
+        current: std.ArrayList(Pair),
+        /// Events produced by the last update
+        events: std.ArrayList(CollisionEvent),
+        /// Channel that receives every event too
+        bus: ?*Events(CollisionEvent) = null,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .allocator = allocator,
+                .previous = std.ArrayList(Pair).init(allocator),
+                .current = std.ArrayList(Pair).init(allocator),
+                .events = std.ArrayList(CollisionEvent).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.previous.deinit();
+            self.current.deinit();
+            self.events.deinit();
+        }
+
+        /// Detect overlaps for this tick and rebuild `events`
+        pub fn update(self: *Self, frame: *EcsType.Frame, index: anytype) !void {
+            self.events.clearRetainingCapacity();
+            try self.detect(frame, index);
+
+            try self.diff();
+            std.mem.swap(std.ArrayList(Pair), &self.previous, &self.current);
+
+            if (self.bus) |bus| {
+                for (self.events.items) |event| try bus.emit(frame.frame_number, event);
+            }
+        }
+
+        /// Take the contacts of a restored frame as the ones the next update compares against,
+        /// without emitting events. `index` must be synced to the restored frame. Exact when
+        /// `update` runs after everything that moves colliders in a tick, so the restored frame
+        /// is the state that tick's update saw.
+        pub fn rollbackTo(self: *Self, frame: *EcsType.Frame, index: anytype) !void {
+            self.events.clearRetainingCapacity();
+            try self.detect(frame, index);
+            std.mem.swap(std.ArrayList(Pair), &self.previous, &self.current);
+        }
+
+        // Fill `current` with this frame's overlapping pairs
+        fn detect(self: *Self, frame: *EcsType.Frame, index: anytype) !void {
+            self.current.clearRetainingCapacity();
+            const reach = maxColliderReach(EcsType, frame);
+
+            var query = try frame.query(&.{ Transform, Collider });
+            while (query.nextFast()) |result| {
+                const collider = result.get(Collider).*;
+                const transform = result.get(Transform).*;
+                const box = AABB.of(transform, collider);
+
+                var candidates = EcsType.EntityBitSet.initEmpty();
+                index.queryAABB(box.min.sub(reach), box.max.add(reach), &candidates);
+
+                var iter = candidates.fastIterator();
+                while (iter.next()) |other| {
+                    if (other <= result.entity) continue;
+                    const other_collider = frame.getComponent(other, Collider) orelse continue;
+                    const other_transform = frame.getComponent(other, Transform) orelse continue;
+
+                    if (!layersCollide(collider, other_collider.*)) continue;
+                    if (!box.overlaps(AABB.of(other_transform.*, other_collider.*))) continue;
+                    if (!collidersOverlap(transform, collider, other_transform.*, other_collider.*)) continue;
+
+                    try self.current.append(.{ .a = result.entity, .b = other });
+                }
+            }
+
+            // Outer loop is ascending by `a` and the bitset yields ascending `b`, so this is
+            // already sorted - assert rather than pay for a sort
+            std.debug.assert(std.sort.isSorted(Pair, self.current.items, {}, pairLessThan));
+        }
+
+        /// Pairs currently overlapping (after the last update)
+        pub fn overlapping(self: *const Self) []const Pair {
+            return self.previous.items;
+        }
+
+        /// Forget all contacts without emitting ended events (e.g. after a level reload)
+        pub fn reset(self: *Self) void {
+            self.previous.clearRetainingCapacity();
+            self.current.clearRetainingCapacity();
+            self.events.clearRetainingCapacity();
+        }
+
+        // Merge previous and current (both sorted) into began/stay/ended events
+        fn diff(self: *Self) !void {
+            const old = self.previous.items;
+            const new = self.current.items;
+            const start = self.events.items.len;
+
+            var i: usize = 0;
+            var j: usize = 0;
+            while (i < old.len or j < new.len) {
+                if (j >= new.len or (i < old.len and Pair.lessThan(old[i], new[j]))) {
+                    try self.events.append(.{ .phase = .ended, .a = old[i].a, .b = old[i].b });
+                    i += 1;
+                } else if (i >= old.len or Pair.lessThan(new[j], old[i])) {
+                    try self.events.append(.{ .phase = .began, .a = new[j].a, .b = new[j].b });
+                    j += 1;
+                } else {
+                    try self.events.append(.{ .phase = .stay, .a = new[j].a, .b = new[j].b });
+                    i += 1;
+                    j += 1;
+                }
+            }
+
+            std.sort.insertion(CollisionEvent, self.events.items[start..], {}, eventLessThan);
+        }
+
+        fn pairLessThan(_: void, lhs: Pair, rhs: Pair) bool {
+            return Pair.lessThan(lhs, rhs);
+        }
+
+        fn eventLessThan(_: void, lhs: CollisionEvent, rhs: CollisionEvent) bool {
+            // Stable sort over a list already ordered by (a, b) - only the phase needs comparing
+            return @intFromEnum(lhs.phase) < @intFromEnum(rhs.phase);
+        }
+    };
+}
@@ -0,0 +1,266 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const components = @import("components.zig");
+const collision = @import("collision.zig");
+const Events = @import("events.zig").Events;
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Collider = components.Collider;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Collider },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestCollisions = collision.CollisionSystem(TestECS);
+
+fn expectEvent(event: collision.CollisionEvent, phase: collision.CollisionPhase, a: ecs.EntityID, b: ecs.EntityID) !void {
+    try testing.expectEqual(phase, event.phase);
+    try testing.expectEqual(a, event.a);
+    try testing.expectEqual(b, event.b);
+}
+
+test "Collision system emits began, stay and ended" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(2));
+    defer grid.deinit();
+
+    var collisions = TestCollisions.init(testing.allocator);
+    defer collisions.deinit();
+
+    const frame = test_ecs.getFrame();
+    const box = Collider.box(fpVec2(1, 1));
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(a, box);
+    try frame.addComponent(b, Transform{ .position = fpVec2(5, 0) });
+    try frame.addComponent(b, box);
+
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 0), collisions.events.items.len);
+
+    frame.getComponent(b, Transform).?.position = fpVec2(1.5, 0.5);
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 1), collisions.events.items.len);
+    try expectEvent(collisions.events.items[0], .began, a, b);
+
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try expectEvent(collisions.events.items[0], .stay, a, b);
+
+    // Touching edges do not count as overlap
+    frame.getComponent(b, Transform).?.position = fpVec2(2, 0);
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try expectEvent(collisions.events.items[0], .ended, a, b);
+    try testing.expectEqual(@as(usize, 0), collisions.overlapping().len);
+}
+
+test "Collision layers and deterministic event order" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    var collisions = TestCollisions.init(testing.allocator);
+    defer collisions.deinit();
+
+    const frame = test_ecs.getFrame();
+    const player = try frame.createEntity();
+    const enemy = try frame.createEntity();
+    const pickup = try frame.createEntity();
+    const ghost = try frame.createEntity();
+
+    // Player collides with everything; the ghost only with layer 4, which nobody is on
+    try frame.addComponent(player, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(player, Collider{ .shape = .{ .box = fpVec2(1, 1) }, .layer = 1 });
+    try frame.addComponent(enemy, Transform{ .position = fpVec2(1, 0) });
+    try frame.addComponent(enemy, Collider{ .shape = .{ .box = fpVec2(1, 1) }, .layer = 2 });
+    try frame.addComponent(pickup, Transform{ .position = fpVec2(0, 1) });
+    try frame.addComponent(pickup, Collider{ .shape = .{ .box = fpVec2(0.5, 0.5) }, .layer = 2, .mask = 1 });
+    try frame.addComponent(ghost, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(ghost, Collider{ .shape = .{ .box = fpVec2(1, 1) }, .layer = 1, .mask = 4 });
+
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+
+    // player-enemy, player-pickup; pickup masks out the enemy, ghost masks out everyone
+    try testing.expectEqual(@as(usize, 2), collisions.events.items.len);
+    try expectEvent(collisions.events.items[0], .began, player, enemy);
+    try expectEvent(collisions.events.items[1], .began, player, pickup);
+
+    // Destroying the enemy ends its contact; the pickup contact stays. Ended sorts after stay.
+    frame.destroyEntity(enemy);
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 2), collisions.events.items.len);
+    try expectEvent(collisions.events.items[0], .stay, player, pickup);
+    try expectEvent(collisions.events.items[1], .ended, player, enemy);
+}
+
+test "Circle and capsule narrowphase" {
+    const origin = fpVec2(0, 0);
+    const circle = components.Shape{ .circle = fp(1) };
+    const box = components.Shape{ .box = fpVec2(1, 1) };
+    const capsule = components.Shape{ .capsule = .{ .half_segment = fpVec2(2, 0), .radius = fp(0.5) } };
+
+    // Circle vs circle: radii sum to 2
+    try testing.expect(collision.shapesOverlap(origin, circle, fpVec2(1.9, 0), circle));
+    try testing.expect(!collision.shapesOverlap(origin, circle, fpVec2(2, 0), circle));
+
+    // Circle vs box corner: box corner at (1,1), circle center at (1.6,1.6) is ~0.85 away
+    try testing.expect(collision.shapesOverlap(fpVec2(1.6, 1.6), circle, origin, box));
+    try testing.expect(!collision.shapesOverlap(fpVec2(1.8, 1.8), circle, origin, box));
+
+    // Capsule spans x in [-2.5, 2.5] along y = 0 with 0.5 thickness
+    try testing.expect(collision.shapesOverlap(origin, capsule, fpVec2(2.9, 0), components.Shape{ .circle = fp(0.5) }));
+    try testing.expect(!collision.shapesOverlap(origin, capsule, fpVec2(0, 1.6), circle));
+    try testing.expect(collision.shapesOverlap(origin, capsule, fpVec2(0, 1.4), box));
+    try testing.expect(collision.shapesOverlap(fpVec2(0, 1.4), box, origin, capsule));
+
+    // Crossing capsules
+    const vertical = components.Shape{ .capsule = .{ .half_segment = fpVec2(0, 2), .radius = fp(0.1) } };
+    try testing.expect(collision.shapesOverlap(origin, capsule, fpVec2(1, 0), vertical));
+    try testing.expect(!collision.shapesOverlap(origin, capsule, fpVec2(3, 0), vertical));
+}
+
+test "Collision system uses exact shapes after the bounding boxes" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    var collisions = TestCollisions.init(testing.allocator);
+    defer collisions.deinit();
+
+    const frame = test_ecs.getFrame();
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(a, Collider.circle(fp(1)));
+    // Bounding boxes overlap near the corner but the circles do not
+    try frame.addComponent(b, Transform{ .position = fpVec2(1.5, 1.5) });
+    try frame.addComponent(b, Collider.circle(fp(1)));
+
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 0), collisions.events.items.len);
+
+    frame.getComponent(b, Transform).?.position = fpVec2(1, 1);
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 1), collisions.events.items.len);
+}
+
+test "Collision events go out on the event bus under the frame's tick" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(2));
+    defer grid.deinit();
+
+    var contacts = try Events(collision.CollisionEvent).init(testing.allocator, 4);
+    defer contacts.deinit();
+    var collisions = TestCollisions.init(testing.allocator);
+    defer collisions.deinit();
+    collisions.bus = &contacts;
+
+    const frame = test_ecs.getFrame();
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(a, Collider.circle(fp(1)));
+    try frame.addComponent(b, Transform{ .position = fpVec2(1, 0) });
+    try frame.addComponent(b, Collider.circle(fp(1)));
+
+    for (0..2) |_| {
+        test_ecs.update(.{}, 1.0 / 60.0, frame.time + 1.0 / 60.0);
+        try grid.sync(frame);
+        try collisions.update(frame, &grid);
+    }
+
+    // Each tick reads what the one before it emitted
+    try expectEvent(contacts.emitted(1)[0], .began, a, b);
+    const previous = contacts.drain(3);
+    try testing.expectEqual(@as(usize, 1), previous.len);
+    try expectEvent(previous[0], .stay, a, b);
+
+    test_ecs.update(.{}, 1.0 / 60.0, frame.time + 1.0 / 60.0);
+    frame.getComponent(b, Transform).?.position = fpVec2(5, 0);
+    try grid.sync(frame);
+    try collisions.update(frame, &grid);
+    try testing.expectEqual(@as(usize, 1), contacts.emitted(3).len);
+    try expectEvent(contacts.drain(4)[0], .ended, a, b);
+}
+
+test "Rolled back collisions resimulate the events they first emitted" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(2));
+    defer grid.deinit();
+    var history = try FrameHistory(TestECS).init(testing.allocator, 8);
+    defer history.deinit();
+    var collisions = TestCollisions.init(testing.allocator);
+    defer collisions.deinit();
+
+    const frame = test_ecs.getFrame();
+    const box = Collider.box(fpVec2(1, 1));
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(a, box);
+    try frame.addComponent(b, Transform{ .position = fpVec2(5, 0) });
+    try frame.addComponent(b, box);
+    try history.record(&test_ecs);
+
+    // Where b is on ticks 1 to 4: apart, touching a, still touching, apart again
+    const path = [_]f32{ 5, 1.5, 1.5, 5 };
+    const expected = [_]?collision.CollisionPhase{ null, .began, .stay, .ended };
+
+    for (path, expected) |x, phase| {
+        test_ecs.update(.{}, 1.0 / 60.0, frame.time + 1.0 / 60.0);
+        frame.getComponent(b, Transform).?.position = fpVec2(x, 0);
+        try grid.sync(frame);
+        try collisions.update(frame, &grid);
+        try history.record(&test_ecs);
+        if (phase) |began_or_after| {
+            try expectEvent(collisions.events.items[0], began_or_after, a, b);
+        } else {
+            try testing.expectEqual(@as(usize, 0), collisions.events.items.len);
+        }
+    }
+
+    // Back to tick 2, while the boxes overlap - the contact has to come back with the frame
+    try history.rollbackTo(&test_ecs, 2);
+    try grid.sync(frame);
+    try collisions.rollbackTo(frame, &grid);
+    try testing.expectEqual(@as(usize, 1), collisions.overlapping().len);
+    try testing.expectEqual(@as(usize, 0), collisions.events.items.len);
+
+    for (path[2..], expected[2..]) |x, phase| {
+        test_ecs.update(.{}, 1.0 / 60.0, frame.time + 1.0 / 60.0);
+        frame.getComponent(b, Transform).?.position = fpVec2(x, 0);
+        try grid.sync(frame);
+        try collisions.update(frame, &grid);
+        try testing.expectEqual(@as(usize, 1), collisions.events.items.len);
+        try expectEvent(collisions.events.items[0], phase.?, a, b);
+    }
+}
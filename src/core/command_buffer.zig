@@ -0,0 +1,114 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+const EntityID = ecs.EntityID;
+
+/// Structural changes recorded while iterating and applied later, at a safe point.
+///
+/// Destroying an entity or adding or removing a component swap-removes dense slots and moves
+/// membership bits under a live query. Systems record those changes here instead and the schedule
+/// owner flushes the buffer once the systems are done; commands apply in recording order. Give
+/// every system that runs in parallel its own buffer and flush them in schedule order to keep the
+/// result deterministic.
+///
+/// Usage:
+///   var commands = CommandBuffer(GameECS).init(allocator);
+///   while (query.next()) |result| {
+///       if (result.get(Health).current <= 0) try commands.destroyEntity(result.entity);
+///   }
+///   try commands.flush(frame);
+pub fn CommandBuffer(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+
+    return struct {
+        const Self = @This();
+
+        const Kind = enum(u8) { destroy, add, remove };
+
+        // Fixed part of every command, followed by the component value for `add`
+        const Header = struct {
+            entity: EntityID,
+            kind: Kind,
+            component: u8 = 0,
+        };
+
+        /// Encoded commands - headers and component values back to back
+        bytes: std.ArrayList(u8),
+        /// Commands waiting for the next flush
+        len: u32 = 0,
+        /// Commands dropped at flush because their entity was already gone
+        skipped: u32 = 0,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return .{ .bytes = std.ArrayList(u8).init(allocator) };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.bytes.deinit();
+        }
+
+        pub fn destroyEntity(self: *Self, entity: EntityID) !void {
+            try self.push(.{ .entity = entity, .kind = .destroy }, &.{});
+        }
+
+        /// Add `component`, or overwrite it when the entity already has one by flush time
+        pub fn addComponent(self: *Self, entity: EntityID, component: anytype) !void {
+            const T = @TypeOf(component);
+            try self.push(.{ .entity = entity, .kind = .add, .component = EcsType.componentId(T) }, std.mem.asBytes(&component));
+        }
+
+        pub fn removeComponent(self: *Self, entity: EntityID, comptime T: type) !void {
+            try self.push(.{ .entity = entity, .kind = .remove, .component = EcsType.componentId(T) }, &.{});
+        }
+
+        fn push(self: *Self, header: Header, payload: []const u8) !void {
+            try self.bytes.ensureUnusedCapacity(@sizeOf(Header) + payload.len);
+            self.bytes.appendSliceAssumeCapacity(std.mem.asBytes(&header));
+            self.bytes.appendSliceAssumeCapacity(payload);
+            self.len += 1;
+        }
+
+        /// Apply every command in recording order and empty the buffer (also when applying fails).
+        /// Commands for entities that are no longer alive - destroyed earlier in the same flush,
+        /// say - are skipped.
+        pub fn flush(self: *Self, frame: *EcsType.Frame) !void {
+            defer self.clear();
+
+            var offset: usize = 0;
+            while (offset < self.bytes.items.len) {
+                const header = std.mem.bytesToValue(Header, self.bytes.items[offset..][0..@sizeOf(Header)]);
+                offset += @sizeOf(Header);
+
+                const alive = header.entity < EcsType.max_entities and frame.state.active_entities.isSet(header.entity);
+                if (!alive) self.skipped += 1;
+                switch (header.kind) {
+                    .destroy => if (alive) frame.destroyEntity(header.entity),
+                    .add => inline for (Types, 0..) |T, i| {
+                        if (header.component == i) {
+                            const value = std.mem.bytesToValue(T, self.bytes.items[offset..][0..@sizeOf(T)]);
+                            offset += @sizeOf(T);
+                            if (alive) {
+                                // A relation is re-added so the frame's target index follows the new target
+                                if (comptime ecs.isRelation(T)) _ = frame.removeComponent(header.entity, T);
+                                if (frame.getComponentMut(header.entity, T)) |existing| {
+                                    existing.* = value;
+                                } else {
+                                    try frame.addComponent(header.entity, value);
+                                }
+                            }
+                        }
+                    },
+                    .remove => inline for (Types, 0..) |T, i| {
+                        if (alive and header.component == i) _ = frame.removeComponent(header.entity, T);
+                    },
+                }
+            }
+        }
+
+        /// Drop every queued command
+        pub fn clear(self: *Self) void {
+            self.bytes.clearRetainingCapacity();
+            self.len = 0;
+        }
+    };
+}
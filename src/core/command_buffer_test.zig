@@ -0,0 +1,92 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const CommandBuffer = @import("command_buffer.zig").CommandBuffer;
+
+const Health = struct { current: i32 };
+const Burning = struct { damage: i32 };
+const Dead = struct {};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Health, Burning, Dead },
+    .input = struct {},
+    .max_entities = .tiny,
+});
+
+test "Structural changes queued during iteration apply at flush" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    var entities: [4]ecs.EntityID = undefined;
+    for (&entities, 0..) |*entity, i| {
+        entity.* = try frame.createEntity();
+        try frame.addComponent(entity.*, Health{ .current = @intCast(i * 10) });
+        try frame.addComponent(entity.*, Burning{ .damage = 10 });
+    }
+
+    var commands = CommandBuffer(TestECS).init(testing.allocator);
+    defer commands.deinit();
+
+    var visited: u32 = 0;
+    var query = try frame.query(&.{ Health, Burning });
+    while (query.next()) |result| {
+        visited += 1;
+        const health = result.get(Health);
+        health.current -= result.get(Burning).damage;
+        if (health.current <= 0) {
+            try commands.addComponent(result.entity, Dead{});
+            try commands.destroyEntity(result.entity);
+        } else {
+            try commands.removeComponent(result.entity, Burning);
+        }
+    }
+
+    // Nothing moved under the query
+    try testing.expectEqual(@as(u32, 4), visited);
+    try testing.expectEqual(@as(u32, 4), frame.getEntityCount());
+    try testing.expectEqual(@as(u32, 6), commands.len);
+
+    try commands.flush(frame);
+    try testing.expectEqual(@as(u32, 0), commands.len);
+    try testing.expectEqual(@as(u32, 0), commands.skipped);
+
+    try testing.expectEqual(@as(u32, 2), frame.getEntityCount());
+    try testing.expect(!frame.hasComponent(entities[1], Dead));
+    try testing.expect(!frame.hasComponent(entities[2], Burning));
+    try testing.expectEqual(@as(i32, 20), frame.getComponent(entities[3], Health).?.current);
+}
+
+test "Commands apply in recording order and skip entities gone by then" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Health{ .current = 1 });
+
+    var commands = CommandBuffer(TestECS).init(testing.allocator);
+    defer commands.deinit();
+
+    // A later add overwrites an earlier one
+    try commands.addComponent(entity, Health{ .current = 5 });
+    try commands.addComponent(entity, Health{ .current = 7 });
+    try commands.flush(frame);
+    try testing.expectEqual(@as(i32, 7), frame.getComponent(entity, Health).?.current);
+
+    // Everything queued after the destroy targets a dead entity
+    try commands.destroyEntity(entity);
+    try commands.addComponent(entity, Burning{ .damage = 3 });
+    try commands.removeComponent(entity, Health);
+    try commands.destroyEntity(entity);
+    try commands.flush(frame);
+    try testing.expectEqual(@as(u32, 0), frame.getEntityCount());
+    try testing.expectEqual(@as(u32, 3), commands.skipped);
+
+    // Cleared commands never apply
+    const other = try frame.createEntity();
+    try commands.destroyEntity(other);
+    commands.clear();
+    try commands.flush(frame);
+    try testing.expectEqual(@as(u32, 1), frame.getEntityCount());
+}
@@ -0,0 +1,97 @@
+const std = @import("std");
+
+const cache_line = std.atomic.cache_line;
+
+/// Lock-free multi-producer single-consumer queue for commands arriving from outside the
+/// simulation - network receive threads, IO callbacks, admin endpoints.
+///
+/// A bounded ring of preallocated slots, each carrying a sequence number that says whose turn it
+/// is (Vyukov's bounded queue): producers claim a slot with one compare-and-swap on the shared
+/// tail and publish it by bumping its sequence, the tick thread drains published slots without
+/// any atomic read-modify-write. Nothing allocates after `init` and no producer ever waits on a
+/// lock the frame holds. When the tick falls a full ring behind, `push` fails instead of blocking.
+///
+/// Commands from one producer come out in the order it pushed them; commands from different
+/// producers interleave in claim order, which depends on thread timing. Record what the tick
+/// drained (e.g. in the replay) rather than expecting the same interleaving twice.
+pub fn CommandInbox(comptime T: type) type {
+    return struct {
+        const Self = @This();
+
+        const Slot = struct {
+            /// `position + 1` once the value at `position` is published, `position + capacity`
+            /// once the consumer freed it for the next lap
+            sequence: std.atomic.Value(usize),
+            value: T,
+        };
+
+        allocator: std.mem.Allocator,
+        slots: []Slot,
+        mask: usize,
+        // Producers and the consumer each get their own cache line
+        tail: std.atomic.Value(usize) align(cache_line) = std.atomic.Value(usize).init(0),
+        head: usize align(cache_line) = 0,
+
+        /// `capacity` is rounded up to a power of two
+        pub fn init(allocator: std.mem.Allocator, capacity: usize) !Self {
+            const len = try std.math.ceilPowerOfTwo(usize, @max(capacity, 2));
+            const slots = try allocator.alloc(Slot, len);
+            for (slots, 0..) |*slot, position| slot.sequence = std.atomic.Value(usize).init(position);
+            return Self{
+                .allocator = allocator,
+                .slots = slots,
+                .mask = len - 1,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.allocator.free(self.slots);
+        }
+
+        /// Enqueue from any thread
+        pub fn push(self: *Self, value: T) error{InboxFull}!void {
+            var position = self.tail.load(.monotonic);
+            while (true) {
+                const slot = &self.slots[position & self.mask];
+                const sequence = slot.sequence.load(.acquire);
+                const lag: isize = @bitCast(sequence -% position);
+                if (lag == 0) {
+                    // Our turn for this slot - claim it unless another producer got there first
+                    position = self.tail.cmpxchgWeak(position, position +% 1, .monotonic, .monotonic) orelse {
+                        slot.value = value;
+                        slot.sequence.store(position +% 1, .release);
+                        return;
+                    };
+                } else if (lag < 0) {
+                    // Still holds last lap's command - the consumer is a full ring behind
+                    return error.InboxFull;
+                } else {
+                    position = self.tail.load(.monotonic);
+                }
+            }
+        }
+
+        /// Dequeue the oldest published command. Consumer thread only.
+        pub fn pop(self: *Self) ?T {
+            const slot = &self.slots[self.head & self.mask];
+            if (slot.sequence.load(.acquire) != self.head +% 1) return null;
+            const value = slot.value;
+            slot.sequence.store(self.head +% self.slots.len, .release);
+            self.head +%= 1;
+            return value;
+        }
+
+        /// Dequeue up to `out.len` commands into `out`. Consumer thread only.
+        pub fn drain(self: *Self, out: []T) []T {
+            var len: usize = 0;
+            while (len < out.len) : (len += 1) {
+                out[len] = self.pop() orelse break;
+            }
+            return out[0..len];
+        }
+
+        pub fn capacity(self: *const Self) usize {
+            return self.slots.len;
+        }
+    };
+}
@@ -0,0 +1,69 @@
+const std = @import("std");
+const testing = std.testing;
+const CommandInbox = @import("command_inbox.zig").CommandInbox;
+
+const Command = struct {
+    producer: u32,
+    sequence: u32,
+};
+
+const Inbox = CommandInbox(Command);
+
+test "Inbox is FIFO and refuses commands when a full ring behind" {
+    var inbox = try Inbox.init(testing.allocator, 3);
+    defer inbox.deinit();
+    try testing.expectEqual(@as(usize, 4), inbox.capacity());
+
+    for (0..4) |i| try inbox.push(.{ .producer = 0, .sequence = @intCast(i) });
+    try testing.expectError(error.InboxFull, inbox.push(.{ .producer = 0, .sequence = 4 }));
+
+    try testing.expectEqual(@as(u32, 0), inbox.pop().?.sequence);
+    try inbox.push(.{ .producer = 0, .sequence = 4 });
+
+    var out: [8]Command = undefined;
+    const drained = inbox.drain(&out);
+    try testing.expectEqual(@as(usize, 4), drained.len);
+    for (drained, 1..) |command, expected| try testing.expectEqual(@as(u32, @intCast(expected)), command.sequence);
+    try testing.expectEqual(@as(?Command, null), inbox.pop());
+}
+
+const producers = 4;
+const per_producer = 20_000;
+
+fn produce(inbox: *Inbox, producer: u32) void {
+    var sequence: u32 = 0;
+    while (sequence < per_producer) {
+        inbox.push(.{ .producer = producer, .sequence = sequence }) catch {
+            std.atomic.spinLoopHint();
+            continue;
+        };
+        sequence += 1;
+    }
+}
+
+test "Concurrent producers keep their own order" {
+    if (@import("builtin").single_threaded) return error.SkipZigTest;
+
+    var inbox = try Inbox.init(testing.allocator, 256);
+    defer inbox.deinit();
+
+    var threads: [producers]std.Thread = undefined;
+    for (&threads, 0..) |*thread, producer| thread.* = try std.Thread.spawn(.{}, produce, .{ &inbox, @as(u32, @intCast(producer)) });
+
+    // Next expected sequence per producer
+    var expected = [_]u32{0} ** producers;
+    var received: u32 = 0;
+    while (received < producers * per_producer) {
+        const command = inbox.pop() orelse {
+            std.atomic.spinLoopHint();
+            continue;
+        };
+        try testing.expectEqual(expected[command.producer], command.sequence);
+        expected[command.producer] += 1;
+        received += 1;
+    }
+    for (threads) |thread| thread.join();
+
+    for (expected) |count| try testing.expectEqual(@as(u32, per_producer), count);
+    try testing.expectEqual(@as(?Command, null), inbox.pop());
+}
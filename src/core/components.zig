@@ -0,0 +1,186 @@
+//! Built-in components shared by the spatial, physics and gameplay modules.
+//! Register the ones you use in your ECS config alongside your own components.
+
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+/// World-space placement of an entity
+pub const Transform = struct {
+    position: FPVector2 = FPVector2.ZERO,
+    /// Radians, counter-clockwise
+    rotation: FP = fp(0),
+};
+
+/// Placement relative to `parent` (or to the world when there is no parent).
+/// The hierarchy system turns this into a WorldTransform each tick.
+pub const LocalTransform = struct {
+    position: FPVector2 = FPVector2.ZERO,
+    /// Radians, counter-clockwise, added to the parent's rotation
+    rotation: FP = fp(0),
+    parent: ecs.EntityID = ecs.INVALID_ENTITY,
+
+    pub fn eql(a: LocalTransform, b: LocalTransform) bool {
+        return a.position.eq(b.position) and a.rotation.eq(b.rotation) and a.parent == b.parent;
+    }
+};
+
+/// World-space result of a LocalTransform chain, written by the hierarchy system.
+/// The bookkeeping fields let it skip clean subtrees and live in the component so they roll back with it.
+pub const WorldTransform = struct {
+    position: FPVector2 = FPVector2.ZERO,
+    rotation: FP = fp(0),
+    /// Inputs of the last recompute - while they match, the entity is skipped
+    source: LocalTransform = .{},
+    parent_position: FPVector2 = FPVector2.ZERO,
+    parent_rotation: FP = fp(0),
+    computed: bool = false,
+};
+
+/// Per-second linear and angular velocity
+pub const Velocity = struct {
+    linear: FPVector2 = FPVector2.ZERO,
+    angular: FP = fp(0),
+};
+
+/// Collision shape, centered on the entity's position plus the collider offset.
+/// Rotation is ignored - boxes stay axis aligned and capsule segments keep their direction.
+pub const Shape = union(enum) {
+    /// Axis-aligned box given by its half extents
+    box: FPVector2,
+    /// Circle given by its radius
+    circle: FP,
+    /// Circle swept along the segment from `-half_segment` to `+half_segment`
+    capsule: struct {
+        half_segment: FPVector2,
+        radius: FP,
+    },
+
+    /// Half extents of the shape's axis-aligned bounding box
+    pub fn boundingHalfExtents(self: Shape) FPVector2 {
+        return switch (self) {
+            .box => |half_extents| half_extents,
+            .circle => |radius| FPVector2.new(radius, radius),
+            .capsule => |capsule| capsule.half_segment.abs().add(FPVector2.new(capsule.radius, capsule.radius)),
+        };
+    }
+};
+
+/// Collision shape plus filtering. A pair collides only when each side's mask includes
+/// the other's layer.
+pub const Collider = struct {
+    shape: Shape,
+    offset: FPVector2 = FPVector2.ZERO,
+    /// Layers this collider belongs to
+    layer: u32 = 1,
+    /// Layers this collider collides with
+    mask: u32 = std.math.maxInt(u32),
+
+    pub fn box(half_extents: FPVector2) Collider {
+        return .{ .shape = .{ .box = half_extents } };
+    }
+
+    pub fn circle(radius: FP) Collider {
+        return .{ .shape = .{ .circle = radius } };
+    }
+
+    pub fn capsule(half_segment: FPVector2, radius: FP) Collider {
+        return .{ .shape = .{ .capsule = .{ .half_segment = half_segment, .radius = radius } } };
+    }
+};
+
+pub const BodyKind = enum(u8) {
+    /// Moved by velocity, gravity and contacts
+    dynamic,
+    /// Moved by velocity only - pushes dynamic bodies but is never pushed back
+    kinematic,
+    /// Never moves
+    static,
+};
+
+/// Physics body. Entities need Transform and Collider as well, and Velocity unless static.
+/// Colliders without a RigidBody act as static geometry.
+pub const RigidBody = struct {
+    kind: BodyKind = .dynamic,
+    /// 1 / mass - ignored for kinematic and static bodies
+    inverse_mass: FP = fp(1),
+    /// Bounciness, 0 = no bounce, 1 = perfectly elastic
+    restitution: FP = fp(0),
+    /// Coulomb friction coefficient
+    friction: FP = fp(0.5),
+    gravity_scale: FP = fp(1),
+
+    /// Effective inverse mass used by the solver
+    pub fn solverInverseMass(self: RigidBody) FP {
+        return if (self.kind == .dynamic) self.inverse_mass else fp(0);
+    }
+};
+
+/// Constant per-second acceleration of a dynamic body on top of gravity - thrust, wind, a
+/// conveyor. Read by the physics module when the world registers it.
+pub const Acceleration = struct {
+    linear: FPVector2 = FPVector2.ZERO,
+    angular: FP = fp(0),
+};
+
+/// Velocity damping and speed limit of a dynamic or kinematic body. Read by the physics module
+/// when the world registers it.
+pub const Drag = struct {
+    /// Fraction of linear velocity lost per second, 0 = none
+    linear: FP = fp(0),
+    /// Fraction of angular velocity lost per second, 0 = none
+    angular: FP = fp(0),
+    /// Linear speed limit, 0 = unlimited
+    max_speed: FP = fp(0),
+};
+
+pub const SteeringBehavior = enum(u8) {
+    none,
+    /// Head for `target` at full speed
+    seek,
+    /// Run directly away from `target`
+    flee,
+    /// Head for `target`, slowing down inside `slowing_radius`
+    arrive,
+};
+
+/// Per-entity steering setup read by the steering system. The flocking weights combine with the
+/// main behavior; leave them at zero for a lone agent.
+pub const Steering = struct {
+    behavior: SteeringBehavior = .none,
+    target: FPVector2 = FPVector2.ZERO,
+    max_speed: FP = fp(5),
+    /// Largest velocity change per second
+    max_force: FP = fp(10),
+    slowing_radius: FP = fp(2),
+
+    /// Only agents in the same flock see each other as neighbors
+    flock: u8 = 0,
+    neighbor_radius: FP = fp(3),
+    /// Neighbors closer than this are pushed away from
+    separation_radius: FP = fp(1),
+    separation_weight: FP = fp(0),
+    alignment_weight: FP = fp(0),
+    cohesion_weight: FP = fp(0),
+};
+
+pub const IntegrationScheme = enum(u8) {
+    /// Position moves by the old velocity, then velocity takes the acceleration
+    explicit_euler,
+    /// Velocity takes the acceleration first and position moves by the new velocity - more stable
+    semi_implicit_euler,
+};
+
+/// Data-driven movement for entities that are not physics bodies: integration scheme,
+/// constant acceleration, drag and a speed limit. Read by the movement system.
+pub const Movement = struct {
+    scheme: IntegrationScheme = .semi_implicit_euler,
+    /// Per-second acceleration (gravity, thrust), applied every tick
+    acceleration: FPVector2 = FPVector2.ZERO,
+    /// Fraction of velocity lost per second, 0 = none
+    drag: FP = fp(0),
+    /// Speed limit, 0 = unlimited
+    max_speed: FP = fp(0),
+};
@@ -0,0 +1,247 @@
+const std = @import("std");
+const schema = @import("schema.zig");
+const netcode = @import("netcode.zig");
+
+/// A byte-level codec that snapshots and network packets are squeezed through.
+///
+/// Implementations only transform bytes into a caller-provided buffer: `compress` returns null
+/// when the result does not fit, `decompress` when the input is corrupt or decompresses past the
+/// buffer. `compress` and `decompress` below wrap them in a small header and fall back to storing
+/// the bytes as they are, so a compressor never makes anything bigger than `bound`.
+///
+/// Two come built in: `zero_runs`, which collapses runs of zero bytes - bitset words, unused
+/// generations and mostly-unchanged state are mostly zeros - cheaply enough for every packet, and
+/// `deflate` from the standard library for save files. LZ4, snappy or zstd bindings plug in the
+/// same way through a `Compressor` of their own.
+pub const Compressor = struct {
+    ptr: *anyopaque,
+    vtable: *const VTable,
+
+    pub const VTable = struct {
+        compress: *const fn (ptr: *anyopaque, input: []const u8, output: []u8) ?usize,
+        decompress: *const fn (ptr: *anyopaque, input: []const u8, output: []u8) ?usize,
+    };
+
+    /// Runs of 2 to 128 zero bytes become one byte; everything else is copied in chunks
+    pub const zero_runs = Compressor{ .ptr = undefined, .vtable = &.{ .compress = zeroRunsCompress, .decompress = zeroRunsDecompress } };
+
+    /// Raw DEFLATE at the default level
+    pub const deflate = Compressor{ .ptr = undefined, .vtable = &.{ .compress = deflateCompress, .decompress = deflateDecompress } };
+
+    /// Length of the compressed `input` written to `output`, or null when it does not fit
+    pub fn compress(self: Compressor, input: []const u8, output: []u8) ?usize {
+        return self.vtable.compress(self.ptr, input, output);
+    }
+
+    /// Length of the decompressed `input` written to `output`, or null when the input is
+    /// corrupt or does not fit
+    pub fn decompress(self: Compressor, input: []const u8, output: []u8) ?usize {
+        return self.vtable.decompress(self.ptr, input, output);
+    }
+};
+
+/// u8 method (0 stored, 1 compressed) then the u32 little-endian uncompressed length
+pub const header_size = 5;
+
+/// Largest `compress` output for `len` input bytes
+pub fn bound(len: usize) usize {
+    return header_size + len;
+}
+
+/// Compress `input` into `output` (at least `bound(input.len)` bytes), storing it unchanged when
+/// the compressor does not make it smaller. Returns the length written.
+pub fn compress(compressor: Compressor, input: []const u8, output: []u8) usize {
+    std.debug.assert(output.len >= bound(input.len));
+    std.mem.writeInt(u32, output[1..header_size], @intCast(input.len), .little);
+    const body = output[header_size..][0..input.len];
+    if (compressor.compress(input, body)) |len| {
+        if (len < input.len) {
+            output[0] = 1;
+            return header_size + len;
+        }
+    }
+    output[0] = 0;
+    @memcpy(body, input);
+    return header_size + input.len;
+}
+
+/// Uncompressed length of a `compress` output, for sizing the buffer handed to `decompress`
+pub fn decompressedLen(input: []const u8) !usize {
+    if (input.len < header_size) return error.InvalidData;
+    return std.mem.readInt(u32, input[1..header_size], .little);
+}
+
+/// The bytes `compress` was given, decompressed into `output`. Fails with error.InvalidData on a
+/// corrupt input and error.NoSpaceLeft when `output` is shorter than `decompressedLen`.
+pub fn decompress(compressor: Compressor, input: []const u8, output: []u8) ![]u8 {
+    const len = try decompressedLen(input);
+    if (len > output.len) return error.NoSpaceLeft;
+    const body = input[header_size..];
+    switch (input[0]) {
+        0 => {
+            if (body.len != len) return error.InvalidData;
+            @memcpy(output[0..len], body);
+        },
+        1 => {
+            const written = compressor.decompress(body, output[0..len]) orelse return error.InvalidData;
+            if (written != len) return error.InvalidData;
+        },
+        else => return error.InvalidData,
+    }
+    return output[0..len];
+}
+
+/// Sizes before and after compression, per snapshot or packet and in total
+pub const CompressionStats = struct {
+    count: u64 = 0,
+    raw_bytes: u64 = 0,
+    compressed_bytes: u64 = 0,
+    last_raw: usize = 0,
+    last_compressed: usize = 0,
+
+    pub fn record(self: *CompressionStats, raw: usize, compressed: usize) void {
+        self.count += 1;
+        self.raw_bytes += raw;
+        self.compressed_bytes += compressed;
+        self.last_raw = raw;
+        self.last_compressed = compressed;
+    }
+
+    /// Compressed bytes per raw byte so far, 1 before anything was recorded
+    pub fn ratio(self: CompressionStats) f64 {
+        if (self.raw_bytes == 0) return 1;
+        return @as(f64, @floatFromInt(self.compressed_bytes)) / @as(f64, @floatFromInt(self.raw_bytes));
+    }
+};
+
+/// `schema.Registry(EcsType).encodeFrame` compressed with `compressor`, owned by the caller
+pub fn encodeFrame(comptime EcsType: type, allocator: std.mem.Allocator, frame: *EcsType.Frame, compressor: Compressor, stats: ?*CompressionStats) ![]u8 {
+    var raw = std.ArrayList(u8).init(allocator);
+    defer raw.deinit();
+    try schema.Registry(EcsType).encodeFrame(frame, raw.writer());
+
+    const output = try allocator.alloc(u8, bound(raw.items.len));
+    errdefer allocator.free(output);
+    const len = compress(compressor, raw.items, output);
+    if (stats) |s| s.record(raw.items.len, len);
+    return allocator.realloc(output, len);
+}
+
+/// Load an `encodeFrame` snapshot into the frame, with the errors of both `decompress` and
+/// `schema.Registry(EcsType).decodeFrame`. Snapshots claiming more than `max_len` uncompressed
+/// bytes fail with error.InvalidData before anything is allocated.
+pub fn decodeFrame(comptime EcsType: type, allocator: std.mem.Allocator, frame: *EcsType.Frame, bytes: []const u8, compressor: Compressor, max_len: usize) !void {
+    // The length is untrusted - a corrupt header must not pick the allocation size
+    const len = try decompressedLen(bytes);
+    if (len > max_len) return error.InvalidData;
+    const raw = try allocator.alloc(u8, len);
+    defer allocator.free(raw);
+    var stream = std.io.fixedBufferStream(try decompress(compressor, bytes, raw));
+    try schema.Registry(EcsType).decodeFrame(frame, stream.reader());
+}
+
+/// Wraps another transport, compressing every packet sent and decompressing every packet
+/// received. Both peers need the same compressor; packets that fail to decompress are dropped
+/// and counted.
+///
+/// Usage:
+///   var compressed = CompressedTransport{ .inner = udp.transport(), .compressor = Compressor.zero_runs };
+///   var session = try Session(GameECS).init(allocator, &world, compressed.transport(), .{ .local_player = 0 });
+pub const CompressedTransport = struct {
+    inner: netcode.Transport,
+    compressor: Compressor,
+    sent: CompressionStats = .{},
+    received: CompressionStats = .{},
+    invalid_packets: u64 = 0,
+
+    pub fn transport(self: *CompressedTransport) netcode.Transport {
+        return .{ .ptr = self, .vtable = &.{ .send = send, .receive = receive } };
+    }
+
+    fn send(ptr: *anyopaque, player: u8, bytes: []const u8) void {
+        const self: *CompressedTransport = @ptrCast(@alignCast(ptr));
+        std.debug.assert(bytes.len <= netcode.max_packet_size);
+        var packet: [bound(netcode.max_packet_size)]u8 = undefined;
+        const len = compress(self.compressor, bytes, &packet);
+        self.sent.record(bytes.len, len);
+        self.inner.send(player, packet[0..len]);
+    }
+
+    fn receive(ptr: *anyopaque, buffer: []u8) ?[]u8 {
+        const self: *CompressedTransport = @ptrCast(@alignCast(ptr));
+        var packet: [bound(netcode.max_packet_size)]u8 = undefined;
+        while (self.inner.receive(&packet)) |compressed| {
+            const raw = decompress(self.compressor, compressed, buffer) catch {
+                self.invalid_packets += 1;
+                continue;
+            };
+            self.received.record(raw.len, compressed.len);
+            return raw;
+        }
+        return null;
+    }
+};
+
+// Control byte: 0..127 copies the next n + 1 bytes, 128..255 writes (n & 127) + 1 zeros
+fn zeroRunsCompress(_: *anyopaque, input: []const u8, output: []u8) ?usize {
+    var in: usize = 0;
+    var out: usize = 0;
+    while (in < input.len) {
+        var zeros: usize = 0;
+        while (in + zeros < input.len and input[in + zeros] == 0 and zeros < 128) zeros += 1;
+        if (zeros >= 2) {
+            if (out >= output.len) return null;
+            output[out] = @intCast(0x80 | (zeros - 1));
+            out += 1;
+            in += zeros;
+            continue;
+        }
+
+        // Literals up to the next pair of zeros
+        var end = in;
+        while (end < input.len and end - in < 128) : (end += 1) {
+            if (input[end] == 0 and end + 1 < input.len and input[end + 1] == 0) break;
+        }
+        const len = end - in;
+        if (out + 1 + len > output.len) return null;
+        output[out] = @intCast(len - 1);
+        @memcpy(output[out + 1 ..][0..len], input[in..end]);
+        out += 1 + len;
+        in = end;
+    }
+    return out;
+}
+
+fn zeroRunsDecompress(_: *anyopaque, input: []const u8, output: []u8) ?usize {
+    var in: usize = 0;
+    var out: usize = 0;
+    while (in < input.len) {
+        const control = input[in];
+        in += 1;
+        const len = @as(usize, control & 0x7f) + 1;
+        if (out + len > output.len) return null;
+        if (control & 0x80 != 0) {
+            @memset(output[out..][0..len], 0);
+        } else {
+            if (in + len > input.len) return null;
+            @memcpy(output[out..][0..len], input[in..][0..len]);
+            in += len;
+        }
+        out += len;
+    }
+    return out;
+}
+
+fn deflateCompress(_: *anyopaque, input: []const u8, output: []u8) ?usize {
+    var source = std.io.fixedBufferStream(input);
+    var sink = std.io.fixedBufferStream(output);
+    std.compress.flate.compress(source.reader(), sink.writer(), .{}) catch return null;
+    return sink.pos;
+}
+
+fn deflateDecompress(_: *anyopaque, input: []const u8, output: []u8) ?usize {
+    var source = std.io.fixedBufferStream(input);
+    var sink = std.io.fixedBufferStream(output);
+    std.compress.flate.decompress(source.reader(), sink.writer()) catch return null;
+    return sink.pos;
+}
@@ -0,0 +1,131 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const compression = @import("compression.zig");
+const netcode = @import("netcode.zig");
+
+const Compressor = compression.Compressor;
+
+const Position = struct { x: i32, y: i32 };
+
+const TestInput = struct {
+    value: u8 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Position},
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+fn roundTrip(compressor: Compressor, input: []const u8) !usize {
+    var output: [compression.bound(4096)]u8 = undefined;
+    const len = compression.compress(compressor, input, &output);
+    var restored: [4096]u8 = undefined;
+    try testing.expectEqualSlices(u8, input, try compression.decompress(compressor, output[0..len], &restored));
+    return len;
+}
+
+test "Compressors round-trip and never grow past the header" {
+    // Bitset words: a few set bits in a sea of zeros
+    var sparse = [_]u8{0} ** 2048;
+    for (0..2048) |i| {
+        if (i % 97 == 0) sparse[i] = @intCast(i % 251 + 1);
+    }
+    var noise: [1000]u8 = undefined;
+    var rng = std.Random.DefaultPrng.init(42);
+    rng.random().bytes(&noise);
+
+    for ([_]Compressor{ Compressor.zero_runs, Compressor.deflate }) |compressor| {
+        try testing.expect(try roundTrip(compressor, &sparse) < sparse.len / 10);
+        try testing.expectEqual(compression.bound(noise.len), try roundTrip(compressor, &noise));
+        try testing.expectEqual(compression.bound(0), try roundTrip(compressor, ""));
+        _ = try roundTrip(compressor, "abc\x00def\x00\x00\x00ghi\x00");
+    }
+
+    // Runs longer than one control byte covers
+    const zeros = [_]u8{0} ** 1000;
+    try testing.expect(try roundTrip(Compressor.zero_runs, &zeros) <= compression.header_size + 8);
+}
+
+test "Corrupt compressed bytes are refused" {
+    var output: [compression.bound(64)]u8 = undefined;
+    const input = [_]u8{0} ** 64;
+    const len = compression.compress(Compressor.zero_runs, &input, &output);
+    var restored: [64]u8 = undefined;
+
+    try testing.expectError(error.InvalidData, compression.decompress(Compressor.zero_runs, output[0..3], &restored));
+    try testing.expectError(error.NoSpaceLeft, compression.decompress(Compressor.zero_runs, output[0..len], restored[0..10]));
+
+    // A run claiming more zeros than the header announced
+    output[compression.header_size] = 0xff;
+    try testing.expectError(error.InvalidData, compression.decompress(Compressor.zero_runs, output[0..len], &restored));
+
+    output[0] = 9;
+    try testing.expectError(error.InvalidData, compression.decompress(Compressor.zero_runs, output[0..len], &restored));
+}
+
+test "Snapshots compress with per-frame statistics" {
+    var source = try TestECS.init(testing.allocator);
+    defer source.deinit();
+    for (0..200) |i| {
+        const entity = try source.getFrame().createEntity();
+        if (i % 4 == 0) try source.getFrame().addComponent(entity, Position{ .x = @intCast(i), .y = 0 });
+    }
+
+    var stats = compression.CompressionStats{};
+    for ([_]Compressor{ Compressor.zero_runs, Compressor.deflate }) |compressor| {
+        const bytes = try compression.encodeFrame(TestECS, testing.allocator, source.getFrame(), compressor, &stats);
+        defer testing.allocator.free(bytes);
+        try testing.expectEqual(bytes.len, stats.last_compressed);
+        try testing.expect(stats.last_compressed * 2 < stats.last_raw);
+
+        var dest = try TestECS.init(testing.allocator);
+        defer dest.deinit();
+        try compression.decodeFrame(TestECS, testing.allocator, dest.getFrame(), bytes, compressor, stats.last_raw);
+        try testing.expectEqual(source.getFrame().checksum(), dest.getFrame().checksum());
+    }
+    try testing.expectEqual(@as(u64, 2), stats.count);
+    try testing.expect(stats.ratio() < 0.5);
+}
+
+test "Snapshots claiming more than the limit are rejected before allocating" {
+    var source = try TestECS.init(testing.allocator);
+    defer source.deinit();
+    _ = try source.getFrame().createEntity();
+
+    var stats = compression.CompressionStats{};
+    const bytes = try compression.encodeFrame(TestECS, testing.allocator, source.getFrame(), Compressor.zero_runs, &stats);
+    defer testing.allocator.free(bytes);
+
+    var dest = try TestECS.init(testing.allocator);
+    defer dest.deinit();
+    try testing.expectError(error.InvalidData, compression.decodeFrame(TestECS, testing.allocator, dest.getFrame(), bytes, Compressor.zero_runs, stats.last_raw - 1));
+
+    // A corrupt length would otherwise size the allocation
+    std.mem.writeInt(u32, bytes[1..compression.header_size], std.math.maxInt(u32), .little);
+    var failing = std.testing.FailingAllocator.init(testing.allocator, .{ .fail_index = 0 });
+    try testing.expectError(error.InvalidData, compression.decodeFrame(TestECS, failing.allocator(), dest.getFrame(), bytes, Compressor.zero_runs, 1 << 20));
+}
+
+test "Compressed transports carry packets and count what they saved" {
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+    var host = compression.CompressedTransport{ .inner = network.transport(0), .compressor = Compressor.zero_runs };
+    var guest = compression.CompressedTransport{ .inner = network.transport(1), .compressor = Compressor.zero_runs };
+
+    const payload = "RWNC" ++ [_]u8{0} ** 200 ++ "tick";
+    host.transport().send(1, payload);
+
+    var buffer: [netcode.max_packet_size]u8 = undefined;
+    try testing.expectEqualStrings(payload, guest.transport().receive(&buffer).?);
+    try testing.expectEqual(@as(?[]u8, null), guest.transport().receive(&buffer));
+    try testing.expectEqual(@as(usize, payload.len), host.sent.last_raw);
+    try testing.expect(host.sent.last_compressed < 20);
+    try testing.expectEqual(host.sent.compressed_bytes, guest.received.compressed_bytes);
+
+    // Packets from a peer that does not compress are dropped
+    network.transport(0).send(1, "plain");
+    try testing.expectEqual(@as(?[]u8, null), guest.transport().receive(&buffer));
+    try testing.expectEqual(@as(u64, 1), guest.invalid_packets);
+}
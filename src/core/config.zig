@@ -0,0 +1,195 @@
+const std = @import("std");
+const TickPacer = @import("clock.zig").TickPacer;
+const Clock = @import("clock.zig").Clock;
+const schedule_module = @import("schedule.zig");
+
+/// World options a binary can pick at startup. The component set and the entity limit are fixed
+/// at compile time; `max_entities` states what the config expects so a mismatch is caught at
+/// load instead of as `EntityLimitExceeded` mid-match.
+pub const WorldConfig = struct {
+    max_entities: u32 = 512,
+    /// Carve every storage up front (`initPreallocated`) instead of growing on demand
+    preallocate: bool = false,
+    /// Snapshot frames carved alongside a preallocated world
+    snapshot_slots: u32 = 0,
+    /// Memory budget limits in bytes (0 = unlimited)
+    memory_budget_total: u64 = 0,
+    memory_budget_per_frame: u64 = 0,
+};
+
+pub const ScheduleConfig = struct {
+    /// Chunks each chunked system is split into
+    workers: u32 = 1,
+    entity_warn_percent: u8 = 90,
+    /// Systems registered in code but switched off
+    disabled: []const []const u8 = &.{},
+};
+
+pub const NetcodeConfig = struct {
+    port: u16 = 7777,
+    tick_rate: u32 = 60,
+    /// Ticks of history kept for rollback
+    rollback_window: u32 = 8,
+    /// Ticks local input is delayed by; must fit the rollback window
+    input_delay: u32 = 2,
+    /// Ticks between state hash exchanges (0 = never)
+    hash_interval: u32 = 60,
+};
+
+/// Fixed-size run for benchmarks and soak tests
+pub const Scenario = struct {
+    name: []const u8,
+    entities: u32,
+    ticks: u64,
+    seed: u64 = 0,
+};
+
+/// Everything a server or benchmark run reads from its config file. Every field has a default,
+/// so a file only lists what it changes; unknown fields are rejected so typos fail loudly.
+///
+/// ```json
+/// {
+///   "version": 1,
+///   "world": { "max_entities": 1024, "preallocate": true, "snapshot_slots": 8 },
+///   "schedule": { "workers": 4, "disabled": ["debug_draw"] },
+///   "netcode": { "tick_rate": 30, "rollback_window": 12 },
+///   "scenarios": [{ "name": "swarm", "entities": 1000, "ticks": 600 }]
+/// }
+/// ```
+pub const Config = struct {
+    /// Format version - files from a newer engine are refused rather than half understood
+    version: u32 = current_version,
+    world: WorldConfig = .{},
+    schedule: ScheduleConfig = .{},
+    netcode: NetcodeConfig = .{},
+    scenarios: []const Scenario = &.{},
+
+    pub fn scenario(self: *const Config, name: []const u8) ?Scenario {
+        for (self.scenarios) |candidate| {
+            if (std.mem.eql(u8, candidate.name, name)) return candidate;
+        }
+        return null;
+    }
+};
+
+pub const current_version = 1;
+
+/// Why a config was refused
+pub const Diagnostic = struct {
+    /// Position of a syntax or type error in the source (0 for validation errors)
+    line: u64 = 0,
+    column: u64 = 0,
+    message_buffer: [128]u8 = undefined,
+    message_len: usize = 0,
+
+    pub fn message(self: *const Diagnostic) []const u8 {
+        return self.message_buffer[0..self.message_len];
+    }
+
+    fn set(self: *Diagnostic, comptime fmt: []const u8, args: anytype) void {
+        self.message_len = (std.fmt.bufPrint(&self.message_buffer, fmt, args) catch &self.message_buffer).len;
+    }
+};
+
+/// Parse and validate a JSON config. The result owns every string; `deinit` it when done.
+pub fn parse(allocator: std.mem.Allocator, source: []const u8, diagnostic: ?*Diagnostic) !std.json.Parsed(Config) {
+    var scanner = std.json.Scanner.initCompleteInput(allocator, source);
+    defer scanner.deinit();
+    var json_diagnostics = std.json.Diagnostics{};
+    scanner.enableDiagnostics(&json_diagnostics);
+
+    const parsed = std.json.parseFromTokenSource(Config, allocator, &scanner, .{ .allocate = .alloc_always }) catch |err| {
+        if (diagnostic) |d| {
+            d.line = json_diagnostics.getLine();
+            d.column = json_diagnostics.getColumn();
+            d.set("{s}", .{@errorName(err)});
+        }
+        return error.InvalidConfig;
+    };
+    errdefer parsed.deinit();
+
+    var scratch = Diagnostic{};
+    validate(&parsed.value, diagnostic orelse &scratch) catch return error.InvalidConfig;
+    return parsed;
+}
+
+/// Read and parse a config file relative to `dir`
+pub fn load(allocator: std.mem.Allocator, dir: std.fs.Dir, path: []const u8, diagnostic: ?*Diagnostic) !std.json.Parsed(Config) {
+    const source = try dir.readFileAlloc(allocator, path, 1 << 20);
+    defer allocator.free(source);
+    return parse(allocator, source, diagnostic);
+}
+
+/// Range and consistency checks on a parsed config
+pub fn validate(config: *const Config, diagnostic: *Diagnostic) error{InvalidConfig}!void {
+    const fail = struct {
+        fn fail(d: *Diagnostic, comptime fmt: []const u8, args: anytype) error{InvalidConfig} {
+            d.set(fmt, args);
+            return error.InvalidConfig;
+        }
+    }.fail;
+
+    if (config.version > current_version) {
+        return fail(diagnostic, "version {d} is newer than this build understands ({d})", .{ config.version, current_version });
+    }
+
+    const world = config.world;
+    if (world.max_entities < 64 or world.max_entities > 1 << 20 or !std.math.isPowerOfTwo(world.max_entities)) {
+        return fail(diagnostic, "world.max_entities must be a power of two from 64 to 1048576, got {d}", .{world.max_entities});
+    }
+    if (world.snapshot_slots > 0 and !world.preallocate) {
+        return fail(diagnostic, "world.snapshot_slots needs world.preallocate", .{});
+    }
+
+    const schedule = config.schedule;
+    if (schedule.workers == 0 or schedule.workers > schedule_module.max_workers) {
+        return fail(diagnostic, "schedule.workers must be 1 to {d}, got {d}", .{ schedule_module.max_workers, schedule.workers });
+    }
+    if (schedule.entity_warn_percent == 0 or schedule.entity_warn_percent > 100) {
+        return fail(diagnostic, "schedule.entity_warn_percent must be 1 to 100, got {d}", .{schedule.entity_warn_percent});
+    }
+
+    const netcode = config.netcode;
+    if (netcode.tick_rate == 0 or netcode.tick_rate > 1000) {
+        return fail(diagnostic, "netcode.tick_rate must be 1 to 1000, got {d}", .{netcode.tick_rate});
+    }
+    if (netcode.rollback_window == 0) {
+        return fail(diagnostic, "netcode.rollback_window must be at least 1", .{});
+    }
+    if (netcode.input_delay >= netcode.rollback_window) {
+        return fail(diagnostic, "netcode.input_delay ({d}) must be below netcode.rollback_window ({d})", .{ netcode.input_delay, netcode.rollback_window });
+    }
+
+    for (config.scenarios, 0..) |scenario, i| {
+        if (scenario.name.len == 0) return fail(diagnostic, "scenarios[{d}] has no name", .{i});
+        if (scenario.entities > world.max_entities) {
+            return fail(diagnostic, "scenario '{s}' needs {d} entities but world.max_entities is {d}", .{ scenario.name, scenario.entities, world.max_entities });
+        }
+        if (scenario.ticks == 0) return fail(diagnostic, "scenario '{s}' runs no ticks", .{scenario.name});
+        for (config.scenarios[0..i]) |earlier| {
+            if (std.mem.eql(u8, earlier.name, scenario.name)) return fail(diagnostic, "scenario '{s}' is defined twice", .{scenario.name});
+        }
+    }
+}
+
+/// Create the world the config describes. Fails when the binary's entity limit is below the
+/// configured one.
+pub fn initWorld(comptime EcsType: type, allocator: std.mem.Allocator, world: WorldConfig) !EcsType {
+    if (world.max_entities > EcsType.max_entities) return error.EntityLimitTooLow;
+    return if (world.preallocate)
+        EcsType.initPreallocated(allocator, world.snapshot_slots)
+    else
+        EcsType.init(allocator);
+}
+
+/// Apply worker count, warning threshold and disabled systems to a `Schedule`
+pub fn applySchedule(schedule: anytype, config: ScheduleConfig) !void {
+    schedule.workers = config.workers;
+    schedule.entity_warn_percent = config.entity_warn_percent;
+    for (config.disabled) |name| try schedule.setEnabled(name, false);
+}
+
+/// Tick pacer running at the configured tick rate
+pub fn pacer(clock: Clock, netcode: NetcodeConfig) TickPacer {
+    return TickPacer.init(clock, netcode.tick_rate);
+}
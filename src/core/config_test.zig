@@ -0,0 +1,89 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const config = @import("config.zig");
+const Schedule = @import("schedule.zig").Schedule;
+
+const Position = struct { x: i32, y: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Position},
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+fn noop(_: *TestECS.Frame) anyerror!void {}
+
+const example =
+    \\{
+    \\  "version": 1,
+    \\  "world": { "max_entities": 256, "preallocate": true, "snapshot_slots": 2 },
+    \\  "schedule": { "workers": 4, "disabled": ["debug_draw"] },
+    \\  "netcode": { "tick_rate": 30, "rollback_window": 12 },
+    \\  "scenarios": [
+    \\    { "name": "swarm", "entities": 200, "ticks": 600 },
+    \\    { "name": "idle", "entities": 10, "ticks": 60, "seed": 7 }
+    \\  ]
+    \\}
+;
+
+test "Configs load with defaults for everything left out" {
+    const parsed = try config.parse(testing.allocator, example, null);
+    defer parsed.deinit();
+    const loaded = parsed.value;
+
+    try testing.expectEqual(@as(u32, 256), loaded.world.max_entities);
+    try testing.expectEqual(@as(u32, 4), loaded.schedule.workers);
+    try testing.expectEqual(@as(u8, 90), loaded.schedule.entity_warn_percent);
+    try testing.expectEqual(@as(u32, 30), loaded.netcode.tick_rate);
+    try testing.expectEqual(@as(u32, 2), loaded.netcode.input_delay);
+    try testing.expectEqual(@as(u64, 7), loaded.scenario("idle").?.seed);
+    try testing.expect(loaded.scenario("missing") == null);
+
+    var world = try config.initWorld(TestECS, testing.allocator, loaded.world);
+    defer world.deinit();
+    try testing.expectEqual(@as(usize, 2), world.snapshots.len);
+
+    var schedule = Schedule(TestECS).init(testing.allocator);
+    defer schedule.deinit();
+    try schedule.add(.{ .name = "movement", .run = noop });
+    try schedule.add(.{ .name = "debug_draw", .run = noop });
+    try config.applySchedule(&schedule, loaded.schedule);
+    try testing.expectEqual(@as(u32, 4), schedule.workers);
+    try testing.expect(schedule.systems.items[0].enabled);
+    try testing.expect(!schedule.systems.items[1].enabled);
+
+    const empty = try config.parse(testing.allocator, "{}", null);
+    defer empty.deinit();
+    try testing.expectEqual(@as(u16, 7777), empty.value.netcode.port);
+    try testing.expectEqual(@as(usize, 0), empty.value.scenarios.len);
+}
+
+test "Invalid configs are refused with a reason" {
+    var diagnostic = config.Diagnostic{};
+
+    try testing.expectError(error.InvalidConfig, config.parse(testing.allocator, "{\n  \"wrold\": {}\n}", &diagnostic));
+    try testing.expectEqual(@as(u64, 2), diagnostic.line);
+    try testing.expectEqualStrings("UnknownField", diagnostic.message());
+
+    try testing.expectError(error.InvalidConfig, config.parse(testing.allocator,
+        \\{ "netcode": { "rollback_window": 4, "input_delay": 4 } }
+    , &diagnostic));
+    try testing.expectEqualStrings("netcode.input_delay (4) must be below netcode.rollback_window (4)", diagnostic.message());
+
+    try testing.expectError(error.InvalidConfig, config.parse(testing.allocator,
+        \\{ "scenarios": [{ "name": "big", "entities": 5000, "ticks": 1 }] }
+    , &diagnostic));
+    try testing.expectEqualStrings("scenario 'big' needs 5000 entities but world.max_entities is 512", diagnostic.message());
+
+    try testing.expectError(error.InvalidConfig, config.parse(testing.allocator,
+        \\{ "world": { "max_entities": 1000 } }
+    , &diagnostic));
+
+    // Valid on its own, but more than this binary was built for
+    try testing.expectError(error.EntityLimitTooLow, config.initWorld(TestECS, testing.allocator, .{ .max_entities = 1024 }));
+}
@@ -0,0 +1,195 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
+
+const EntityID = ecs.EntityID;
+
+pub const DiffOptions = struct {
+    /// Floats this close count as equal - for comparing against a host with different float
+    /// codegen. Fixed-point and integer fields always compare exactly.
+    float_tolerance: f64 = 0,
+    /// Stop collecting after this many divergences
+    max_divergences: usize = 256,
+};
+
+/// One difference between two frame states
+pub const Divergence = struct {
+    kind: Kind,
+    /// `ecs.INVALID_ENTITY` for resources and the free list
+    entity: EntityID = ecs.INVALID_ENTITY,
+    /// Component or resource name, empty for entity-level differences
+    component: []const u8 = "",
+    /// Top-level field that differs, empty when the whole component does
+    field: []const u8 = "",
+
+    pub const Kind = enum {
+        /// The entity is alive in the first state only
+        entity_only_in_a,
+        entity_only_in_b,
+        /// Alive in both, but with different generations - a different entity in the same slot
+        generation,
+        component_only_in_a,
+        component_only_in_b,
+        /// A component field holds different values
+        field,
+        /// A resource field holds different values
+        resource,
+        /// `next_entity` or the free list differ - later spawns will get different ids
+        free_list,
+    };
+
+    pub fn format(self: Divergence, comptime _: []const u8, _: std.fmt.FormatOptions, writer: anytype) !void {
+        try writer.print("{s}", .{@tagName(self.kind)});
+        if (self.entity != ecs.INVALID_ENTITY) try writer.print(" entity={d}", .{self.entity});
+        if (self.component.len > 0) try writer.print(" component={s}", .{self.component});
+        if (self.field.len > 0) try writer.print(" field={s}", .{self.field});
+    }
+};
+
+/// Field-level comparison of two frame states of one world type, for desync debugging: where a
+/// checksum only says that two peers diverged, `diff` says which entities, components and fields
+/// did. Works on any two states - a test's expected and actual frames, a `FrameHistory` slot
+/// against the live frame, or the local confirmed tick against a peer's decoded `encodeFrame`
+/// snapshot once `NetcodeRollback.confirmHash` reported a mismatch:
+///
+///   const divergences = try StateDiff(GameECS).diff(allocator, &local.state, &remote.state, .{});
+///   defer allocator.free(divergences);
+///   StateDiff(GameECS).report(logger, tick, divergences);
+///
+/// Entities are visited in ascending id order, so the output doesn't depend on storage layout.
+pub fn StateDiff(comptime EcsType: type) type {
+    return struct {
+        const FrameState = EcsType.FrameState;
+
+        /// Every difference between `a` and `b`, up to `options.max_divergences`. Caller owns the slice.
+        pub fn diff(allocator: std.mem.Allocator, a: *const FrameState, b: *const FrameState, options: DiffOptions) ![]Divergence {
+            var found = std.ArrayList(Divergence).init(allocator);
+            errdefer found.deinit();
+            var out = Collector{ .list = &found, .limit = options.max_divergences };
+
+            const either = a.active_entities.unionWith(&b.active_entities);
+            var entities = either.fastIterator();
+            while (entities.next()) |entity| {
+                if (out.full()) break;
+                const in_a = a.active_entities.isSet(entity);
+                const in_b = b.active_entities.isSet(entity);
+                if (in_a != in_b) {
+                    try out.add(.{ .kind = if (in_a) .entity_only_in_a else .entity_only_in_b, .entity = entity });
+                    continue;
+                }
+                if (a.generations.items[entity] != b.generations.items[entity]) {
+                    try out.add(.{ .kind = .generation, .entity = entity });
+                }
+
+                inline for (EcsType.component_types, 0..) |T, i| {
+                    const storage_a = &a.components[i];
+                    const storage_b = &b.components[i];
+                    const has_a = storage_a.entity_bitset.isSet(entity);
+                    const has_b = storage_b.entity_bitset.isSet(entity);
+                    const name = EcsType.component_names[i];
+                    if (has_a and has_b) {
+                        try diffValue(T, &out, .field, entity, name, storage_a.getDirectConst(entity), storage_b.getDirectConst(entity), options);
+                    } else if (has_a != has_b) {
+                        try out.add(.{ .kind = if (has_a) .component_only_in_a else .component_only_in_b, .entity = entity, .component = name });
+                    }
+                }
+            }
+
+            inline for (EcsType.resource_types, 0..) |R, i| {
+                try diffValue(R, &out, .resource, ecs.INVALID_ENTITY, ecs.shortTypeName(R), &a.resources[i], &b.resources[i], options);
+            }
+
+            if (a.next_entity != b.next_entity or !std.mem.eql(EntityID, a.free_entities.items, b.free_entities.items)) {
+                try out.add(.{ .kind = .free_list });
+            }
+            return found.toOwnedSlice();
+        }
+
+        /// Log each divergence as a `state_divergence` warning
+        pub fn report(logger: Logger, frame_number: u64, divergences: []const Divergence) void {
+            for (divergences) |divergence| {
+                logger.warn("state_divergence", &.{
+                    field("frame", frame_number),
+                    field("kind", @tagName(divergence.kind)),
+                    field("entity", divergence.entity),
+                    field("component", divergence.component),
+                    field("field", divergence.field),
+                });
+            }
+        }
+
+        const Collector = struct {
+            list: *std.ArrayList(Divergence),
+            limit: usize,
+
+            fn full(self: *const Collector) bool {
+                return self.list.items.len >= self.limit;
+            }
+
+            fn add(self: *Collector, divergence: Divergence) !void {
+                if (!self.full()) try self.list.append(divergence);
+            }
+        };
+
+        // One divergence per differing top-level field (one for the whole value if it has none)
+        fn diffValue(comptime T: type, out: *Collector, kind: Divergence.Kind, entity: EntityID, name: []const u8, a: *const T, b: *const T, options: DiffOptions) !void {
+            const fields = comptime schema.runtimeFields(T);
+            if (fields.len == 0) {
+                if (!equalValue(T, a, b, options.float_tolerance)) try out.add(.{ .kind = kind, .entity = entity, .component = name });
+                return;
+            }
+            inline for (fields) |struct_field| {
+                if (!equalValue(struct_field.type, &@field(a.*, struct_field.name), &@field(b.*, struct_field.name), options.float_tolerance)) {
+                    try out.add(.{ .kind = kind, .entity = entity, .component = name, .field = struct_field.name });
+                }
+            }
+        }
+    };
+}
+
+/// Value equality as the checksum sees it - slices by content, floats within `tolerance`
+fn equalValue(comptime T: type, a: *const T, b: *const T, tolerance: f64) bool {
+    switch (@typeInfo(T)) {
+        .float => |info| {
+            const Bits = std.meta.Int(.unsigned, info.bits);
+            if (@as(Bits, @bitCast(a.*)) == @as(Bits, @bitCast(b.*))) return true;
+            return @abs(@as(f64, @floatCast(a.*)) - @as(f64, @floatCast(b.*))) <= tolerance;
+        },
+        .@"struct" => |info| {
+            if (info.backing_integer != null) return std.meta.eql(a.*, b.*);
+            inline for (info.fields) |struct_field| {
+                if (struct_field.is_comptime) continue;
+                if (!equalValue(struct_field.type, &@field(a.*, struct_field.name), &@field(b.*, struct_field.name), tolerance)) return false;
+            }
+            return true;
+        },
+        .array => |info| {
+            for (a, b) |*x, *y| {
+                if (!equalValue(info.child, x, y, tolerance)) return false;
+            }
+            return true;
+        },
+        .optional => |info| {
+            if (a.* == null or b.* == null) return a.* == null and b.* == null;
+            return equalValue(info.child, &a.*.?, &b.*.?, tolerance);
+        },
+        .@"union" => {
+            if (std.meta.activeTag(a.*) != std.meta.activeTag(b.*)) return false;
+            switch (a.*) {
+                inline else => |*payload, tag| return equalValue(@TypeOf(payload.*), payload, &@field(b.*, @tagName(tag)), tolerance),
+            }
+        },
+        .pointer => |info| {
+            if (info.size != .slice) @compileError("'" ++ @typeName(T) ++ "' points into memory a diff can't follow; store the value or an id instead");
+            if (a.len != b.len) return false;
+            for (a.*, b.*) |*x, *y| {
+                if (!equalValue(info.child, x, y, tolerance)) return false;
+            }
+            return true;
+        },
+        else => return std.meta.eql(a.*, b.*),
+    }
+}
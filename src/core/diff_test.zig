@@ -0,0 +1,120 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const diff = @import("diff.zig");
+const logger_module = @import("logger.zig");
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+
+const Position = struct { x: f32 = 0, y: f32 = 0 };
+const Health = struct { value: i32 = 100, max: i32 = 100 };
+const Name = struct { value: []const u8 = "" };
+const Weather = struct { wind: i32 = 0 };
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Health, Name },
+    .input = u8,
+    .resources = &.{Weather},
+    .max_entities = .tiny,
+});
+
+const Diff = diff.StateDiff(TestECS);
+
+fn spawn(world: *TestECS, count: usize) !void {
+    const frame = world.getFrame();
+    for (0..count) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = @floatFromInt(i) });
+        try frame.addComponent(entity, Health{});
+        try frame.addComponent(entity, Name{ .value = "unit" });
+    }
+}
+
+test "Equal states have no divergences whatever their dense layout" {
+    var a = try TestECS.init(testing.allocator);
+    defer a.deinit();
+    var b = try TestECS.init(testing.allocator);
+    defer b.deinit();
+    try spawn(&a, 4);
+    try spawn(&b, 4);
+    // Same components, different dense order
+    _ = b.getFrame().removeComponent(0, Health);
+    try b.getFrame().addComponent(0, Health{});
+
+    const divergences = try Diff.diff(testing.allocator, &a.getFrame().state, &b.getFrame().state, .{});
+    defer testing.allocator.free(divergences);
+    try testing.expectEqual(@as(usize, 0), divergences.len);
+}
+
+test "Divergences name the entity, component and field" {
+    var a = try TestECS.init(testing.allocator);
+    defer a.deinit();
+    var b = try TestECS.init(testing.allocator);
+    defer b.deinit();
+    try spawn(&a, 4);
+    try spawn(&b, 5);
+
+    b.getFrame().getComponent(1, Position).?.y = 0.5;
+    b.getFrame().getComponent(2, Health).?.max = 90;
+    _ = b.getFrame().removeComponent(3, Name);
+    b.setResource(Weather{ .wind = 3 });
+
+    const divergences = try Diff.diff(testing.allocator, &a.getFrame().state, &b.getFrame().state, .{});
+    defer testing.allocator.free(divergences);
+    const expected = [_]diff.Divergence{
+        .{ .kind = .field, .entity = 1, .component = "Position", .field = "y" },
+        .{ .kind = .field, .entity = 2, .component = "Health", .field = "max" },
+        .{ .kind = .component_only_in_a, .entity = 3, .component = "Name" },
+        .{ .kind = .entity_only_in_b, .entity = 4 },
+        .{ .kind = .resource, .component = "Weather", .field = "wind" },
+        .{ .kind = .free_list },
+    };
+    try testing.expectEqual(expected.len, divergences.len);
+    for (expected, divergences) |want, got| {
+        try testing.expectEqual(want.kind, got.kind);
+        try testing.expectEqual(want.entity, got.entity);
+        try testing.expectEqualStrings(want.component, got.component);
+        try testing.expectEqualStrings(want.field, got.field);
+    }
+
+    var buffer: [64]u8 = undefined;
+    try testing.expectEqualStrings("field entity=1 component=Position field=y", try std.fmt.bufPrint(&buffer, "{}", .{divergences[0]}));
+
+    // Tolerance hides float noise; the limit caps the list
+    b.getFrame().getComponent(1, Position).?.y = 1e-6;
+    const tolerant = try Diff.diff(testing.allocator, &a.getFrame().state, &b.getFrame().state, .{ .float_tolerance = 1e-3, .max_divergences = 2 });
+    defer testing.allocator.free(tolerant);
+    try testing.expectEqual(@as(usize, 2), tolerant.len);
+    try testing.expectEqual(@as(EntityID, 2), tolerant[0].entity);
+}
+
+const EntityID = ecs.EntityID;
+
+test "A history slot diffs against the live frame and reports through the logger" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var history = try FrameHistory(TestECS).init(testing.allocator, 4);
+    defer history.deinit();
+    try spawn(&world, 2);
+    world.update(0, 1.0 / 60.0, 0);
+    try history.record(&world);
+
+    world.getFrame().destroyEntity(0);
+    const divergences = try Diff.diff(testing.allocator, &history.get(1).?.state, &world.getFrame().state, .{});
+    defer testing.allocator.free(divergences);
+    try testing.expectEqual(diff.Divergence.Kind.entity_only_in_a, divergences[0].kind);
+
+    const Count = struct {
+        events: usize = 0,
+        fn write(ptr: *anyopaque, _: logger_module.Level, event: []const u8, _: []const logger_module.Field) void {
+            const self: *@This() = @ptrCast(@alignCast(ptr));
+            if (std.mem.eql(u8, event, "state_divergence")) self.events += 1;
+        }
+    };
+    var count = Count{};
+    Diff.report(.{ .ptr = &count, .vtable = &.{ .log = Count.write } }, 1, divergences);
+    try testing.expectEqual(divergences.len, count.events);
+}
+
+test {
+    std.testing.refAllDecls(@This());
+}
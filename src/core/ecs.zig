@@ -1,40 +1,299 @@
 const std = @import("std");
 const builtin = @import("builtin");
+const QueryAnalyzer = @import("query_analyzer.zig").QueryAnalyzer;
+const MemoryBudget = @import("memory_budget.zig").MemoryBudget;
+const kernels = @import("bitset_kernels.zig").vectorized;
+const Name = @import("names.zig").Name;
+const NameTable = @import("names.zig").NameTable;
 
 pub const EntityID = u32;
+/// Library-facing name of `EntityID`
+pub const Entity = EntityID;
 pub const INVALID_ENTITY: EntityID = std.math.maxInt(EntityID);
 
+/// Components a query selects on. Entities must hold every `with` type and none of the `without`
+/// types - markers like `Frozen` or `Dead` are excluded in the bitset pass instead of by a branch
+/// per entity. `optional` types don't narrow the match; they declare what the system reads through
+/// `result.tryGet` when present.
+///
+/// Usage:
+///   var query = try frame.queryFiltered(.{ .with = &.{Transform}, .without = &.{Frozen}, .optional = &.{Health} });
+pub const QueryFilter = struct {
+    with: []const type = &.{},
+    without: []const type = &.{},
+    optional: []const type = &.{},
+};
+
+/// Entity reference that stays safe across ticks: the entity's index plus the generation of its
+/// slot. Destroying an entity bumps the slot's generation, so handles held by other entities,
+/// UI or network state stop resolving instead of pointing at whatever takes the slot next.
+/// Systems resolve a handle to an `EntityID` once (`frame.resolve`) and work with that.
+pub const EntityHandle = packed struct(u64) {
+    index: EntityID,
+    generation: u32,
+
+    pub const invalid = EntityHandle{ .index = INVALID_ENTITY, .generation = 0 };
+};
+
+/// Component pointing its entity at one target per relationship kind - `Relation(TargetOf)`,
+/// `Relation(MemberOf)`. `Kind` is any type and only tells relationships apart. Register the
+/// component like any other; `Frame.relate` sets it, `Frame.relatedTo` finds every entity pointing
+/// at a target, and destroying either endpoint removes the relation. Retarget through `relate`
+/// rather than by writing `target` - the frame keeps a target -> sources index next to it.
+pub fn Relation(comptime Kind: type) type {
+    return struct {
+        target: EntityHandle = EntityHandle.invalid,
+
+        pub const relation_kind = Kind;
+    };
+}
+
+/// Whether `T` is a `Relation(...)` component
+pub fn isRelation(comptime T: type) bool {
+    return switch (@typeInfo(T)) {
+        .@"struct" => @hasDecl(T, "relation_kind"),
+        else => false,
+    };
+}
+
+/// Whether values of component `T` go into frame checksums. A component opts out by declaring
+/// `pub const checksummed = false` - debug labels like `Name`, or local-only state peers may
+/// legitimately disagree on.
+pub fn isChecksummed(comptime T: type) bool {
+    return switch (@typeInfo(T)) {
+        .@"struct", .@"enum", .@"union" => !@hasDecl(T, "checksummed") or T.checksummed,
+        else => true,
+    };
+}
+
+// Ordering of `Query.sortByField` keys. Floats are refused: NaN has no place in an order and
+// simulations that care about determinism keep positions in fixed point anyway.
+fn keyLessThan(comptime Key: type, a: Key, b: Key) bool {
+    switch (@typeInfo(Key)) {
+        .int, .comptime_int => return a < b,
+        .bool => return @intFromBool(a) < @intFromBool(b),
+        .@"enum" => return @intFromEnum(a) < @intFromEnum(b),
+        .@"struct" => {
+            if (!@hasDecl(Key, "compare")) @compileError("Sort key '" ++ @typeName(Key) ++ "' needs a compare(a, b) std.math.Order");
+            return Key.compare(a, b) == .lt;
+        },
+        else => @compileError("Type '" ++ @typeName(Key) ++ "' can't be a sort key"),
+    }
+}
+
+/// One 64-entity word of query results: bit i set = entity `base + i` matched
+pub const EntityWord = struct {
+    base: EntityID,
+    bits: u64,
+};
+
+/// Consecutive matching entities `first .. first + len`
+pub const EntityRun = struct {
+    first: EntityID,
+    len: u32,
+};
+
+/// Maximum number of observers that can be attached to a single ECS at once
+pub const MAX_OBSERVERS = 4;
+
+/// Kinds of structural change reported to observers
+pub const ChangeKind = enum(u8) {
+    create_entity,
+    destroy_entity,
+    add_component,
+    remove_component,
+};
+
+/// A single structural change (entity or component membership) made to a FrameState
+pub const StructuralChange = struct {
+    kind: ChangeKind,
+    entity: EntityID,
+    /// Index of the component in the ECS components list, null for entity create/destroy
+    component: ?u8 = null,
+    /// Return address of the code that requested the change
+    call_site: usize,
+};
+
+/// Opt-in hook notified of every structural change and tick boundary.
+/// Used by debugging tools (trace log, leak detector) - costs one branch when none are attached.
+pub const Observer = struct {
+    ptr: *anyopaque,
+    vtable: *const VTable,
+
+    pub const VTable = struct {
+        onTick: *const fn (ptr: *anyopaque, tick: u64) void,
+        onChange: *const fn (ptr: *anyopaque, change: StructuralChange) void,
+    };
+};
+
+/// Strip the namespace from a type name ("ecs_test.Position" -> "Position")
+pub fn shortTypeName(comptime T: type) []const u8 {
+    // "Relation(ecs_test.TargetOf)" would not survive code generators - call it "TargetOfRelation"
+    if (comptime isRelation(T)) return comptime shortTypeName(T.relation_kind) ++ "Relation";
+    const full = @typeName(T);
+    const dot = comptime std.mem.lastIndexOfScalar(u8, full, '.');
+    return if (dot) |i| full[i + 1 ..] else full;
+}
+
+/// Feed `value` to `hasher` field by field - little-endian integers, floats by their bits, slices
+/// by their contents - so padding and dense layout never reach the hash and peers on different
+/// hosts agree on it
+pub fn hashCanonical(hasher: *std.hash.XxHash64, comptime T: type, value: *const T) void {
+    switch (@typeInfo(T)) {
+        .void => {},
+        .bool => hasher.update(&.{@intFromBool(value.*)}),
+        .int => |info| {
+            const Wide = if (info.bits <= 64) u64 else u128;
+            const Signed = std.meta.Int(.signed, @bitSizeOf(Wide));
+            const wide: Wide = if (info.signedness == .signed) @bitCast(@as(Signed, value.*)) else value.*;
+            hasher.update(&std.mem.toBytes(std.mem.nativeToLittle(Wide, wide)));
+        },
+        .float => |info| {
+            const Bits = std.meta.Int(.unsigned, info.bits);
+            const bits: Bits = @bitCast(value.*);
+            hashCanonical(hasher, Bits, &bits);
+        },
+        .@"enum" => |info| {
+            const tag: info.tag_type = @intFromEnum(value.*);
+            hashCanonical(hasher, info.tag_type, &tag);
+        },
+        .@"struct" => |info| {
+            if (info.backing_integer) |Backing| {
+                const bits: Backing = @bitCast(value.*);
+                return hashCanonical(hasher, Backing, &bits);
+            }
+            inline for (info.fields) |struct_field| {
+                if (!struct_field.is_comptime) hashCanonical(hasher, struct_field.type, &@field(value.*, struct_field.name));
+            }
+        },
+        .array => |info| for (value) |*element| hashCanonical(hasher, info.child, element),
+        .vector => |info| {
+            const elements: [info.len]info.child = value.*;
+            hashCanonical(hasher, [info.len]info.child, &elements);
+        },
+        .optional => |info| {
+            const present = value.* != null;
+            hashCanonical(hasher, bool, &present);
+            if (value.*) |*payload| hashCanonical(hasher, info.child, payload);
+        },
+        .@"union" => |info| {
+            const Tag = info.tag_type orelse @compileError("untagged union '" ++ @typeName(T) ++ "' can't be hashed canonically");
+            const tag: Tag = value.*;
+            hashCanonical(hasher, Tag, &tag);
+            switch (value.*) {
+                inline else => |*payload| hashCanonical(hasher, @TypeOf(payload.*), payload),
+            }
+        },
+        .pointer => |info| {
+            if (info.size != .slice) @compileError("'" ++ @typeName(T) ++ "' points into memory a checksum can't follow; store the value or an id instead");
+            const len: u64 = value.len;
+            hashCanonical(hasher, u64, &len);
+            for (value.*) |*element| hashCanonical(hasher, info.child, element);
+        },
+        else => @compileError("'" ++ @typeName(T) ++ "' can't be hashed canonically"),
+    }
+}
+
+fn carvedAlloc(_: *anyopaque, _: usize, _: std.mem.Alignment, _: usize) ?[*]u8 {
+    return null;
+}
+
+/// Allocator of storages carved out of a preallocated block. They start at full capacity, so a
+/// request to grow is a bug and fails; frees are no-ops - the block is released as a whole.
+const carved_allocator = std.mem.Allocator{
+    .ptr = undefined,
+    .vtable = &.{
+        .alloc = carvedAlloc,
+        .resize = std.mem.Allocator.noResize,
+        .remap = std.mem.Allocator.noRemap,
+        .free = std.mem.Allocator.noFree,
+    },
+};
+
+fn strictCarvedAlloc(_: *anyopaque, len: usize, _: std.mem.Alignment, ret_addr: usize) ?[*]u8 {
+    if (std.debug.runtime_safety) {
+        std.debug.panic("strict world storage grew by {} bytes past its preallocated capacity (from 0x{x})", .{ len, ret_addr });
+    }
+    return null;
+}
+
+/// `carved_allocator` for `initStrict` worlds: growing a storage panics in safe builds
+const strict_carved_allocator = std.mem.Allocator{
+    .ptr = undefined,
+    .vtable = &.{
+        .alloc = strictCarvedAlloc,
+        .resize = std.mem.Allocator.noResize,
+        .remap = std.mem.Allocator.noRemap,
+        .free = std.mem.Allocator.noFree,
+    },
+};
+
+/// Source of membership versions. Every change draws a fresh value, so equal versions mean equal
+/// membership even across frames copied from one another - a cached query stays valid through a
+/// rollback that restores the membership it was built from. 0 is the version of an empty set.
+///
+/// Values are unique across every world in the process, so frames can be copied between worlds
+/// (a confirmed world feeding a predicted one) without a cached query mistaking one membership for
+/// another. This is the only state worlds share: each thread reserves versions in blocks, so
+/// worlds simulated on different threads don't contend on the counter.
+var membership_versions = std.atomic.Value(u64).init(1);
+
+const membership_version_block = 1024;
+threadlocal var reserved_versions: struct { next: u64 = 0, end: u64 = 0 } = .{};
+
+fn nextMembershipVersion() u64 {
+    if (reserved_versions.next == reserved_versions.end) {
+        reserved_versions.next = membership_versions.fetchAdd(membership_version_block, .monotonic);
+        reserved_versions.end = reserved_versions.next + membership_version_block;
+    }
+    defer reserved_versions.next += 1;
+    return reserved_versions.next;
+}
+
 /// Entity count limits - constrained to power-of-2 for optimal bitset performance
-pub const EntityLimit = enum(u16) {
+pub const EntityLimit = enum(u32) {
     tiny = 64, // 1 u64 chunk - good for prototypes, simple games
     small = 256, // 4 u64 chunks - good for puzzle games, small arcade games
     medium = 512, // 8 u64 chunks - good for most indie games (default)
     large = 1024, // 16 u64 chunks - good for complex games with many objects
     huge = 2048, // 32 u64 chunks - good for large worlds, particle systems
     massive = 4096, // 64 u64 chunks - good for RTS games, massive simulations
+    // Past massive, bitsets gain a summary level and entity -> index arrays are paged
+    vast = 131072, // 2048 u64 chunks - bullet storms, RTS armies (100k+)
+    million = 1048576, // 16384 u64 chunks - crowd and swarm simulations
 
-    pub fn toInt(self: EntityLimit) u16 {
+    pub fn toInt(self: EntityLimit) u32 {
         return @intFromEnum(self);
     }
 };
 
-/// High-performance bitset with direct word access
+/// High-performance bitset with direct word access.
+///
+/// Past 64 words (4096 bits) a summary level tracks which words are non-empty - summary bit w is
+/// set exactly when `words[w] != 0` - so intersections, counts and iteration over large sparse
+/// sets skip empty stretches 4096 bits at a time. Such bitsets must only be written through their
+/// methods, which keep both levels in step.
 fn BitSet(comptime size: u32) type {
     return struct {
         const Self = @This();
         const word_count = (size + 63) / 64;
-        
+        const hierarchical = word_count > 64;
+        const summary_count = if (hierarchical) (word_count + 63) / 64 else 0;
+
         words: [word_count]u64,
-        
+        summary: [summary_count]u64,
+
         pub fn initEmpty() Self {
             return Self{
                 .words = [_]u64{0} ** word_count,
+                .summary = [_]u64{0} ** summary_count,
             };
         }
-        
+
         pub fn initFull() Self {
             var self = Self{
                 .words = [_]u64{std.math.maxInt(u64)} ** word_count,
+                .summary = [_]u64{std.math.maxInt(u64)} ** summary_count,
             };
             // Clear bits beyond size in the last word
             const remainder = size % 64;
@@ -42,137 +301,280 @@ fn BitSet(comptime size: u32) type {
                 const mask = (@as(u64, 1) << @intCast(remainder)) - 1;
                 self.words[word_count - 1] &= mask;
             }
+            const summary_remainder = word_count % 64;
+            if (hierarchical and summary_remainder != 0) {
+                self.summary[summary_count - 1] &= (@as(u64, 1) << @intCast(summary_remainder)) - 1;
+            }
             return self;
         }
-        
+
         pub inline fn set(self: *Self, index: u32) void {
             if (index >= size) return;
             const word_index = index >> 6;
             const bit_index = @as(u6, @intCast(index & 63));
             self.words[word_index] |= @as(u64, 1) << bit_index;
+            if (hierarchical) self.markWord(word_index);
         }
-        
+
         pub inline fn unset(self: *Self, index: u32) void {
             if (index >= size) return;
             const word_index = index >> 6;
             const bit_index = @as(u6, @intCast(index & 63));
             self.words[word_index] &= ~(@as(u64, 1) << bit_index);
+            if (hierarchical) self.markWord(word_index);
         }
-        
+
         pub inline fn isSet(self: *const Self, index: u32) bool {
             if (index >= size) return false;
             const word_index = index >> 6;
             const bit_index = @as(u6, @intCast(index & 63));
             return (self.words[word_index] & (@as(u64, 1) << bit_index)) != 0;
         }
-        
+
         pub inline fn toggle(self: *Self, index: u32) void {
             if (index >= size) return;
             const word_index = index >> 6;
             const bit_index = @as(u6, @intCast(index & 63));
             self.words[word_index] ^= @as(u64, 1) << bit_index;
+            if (hierarchical) self.markWord(word_index);
+        }
+
+        // Bring the summary bit of one word in line with its contents
+        inline fn markWord(self: *Self, word_index: usize) void {
+            const bit = @as(u64, 1) << @intCast(word_index & 63);
+            if (self.words[word_index] != 0) {
+                self.summary[word_index >> 6] |= bit;
+            } else {
+                self.summary[word_index >> 6] &= ~bit;
+            }
         }
-        
+
         pub fn clear(self: *Self) void {
             @memset(&self.words, 0);
+            @memset(&self.summary, 0);
         }
-        
+
         pub fn count(self: *const Self) u32 {
+            if (!hierarchical) return kernels.popCount(&self.words);
             var total: u32 = 0;
-            for (self.words) |word| {
-                total += @popCount(word);
+            for (self.summary, 0..) |summary_word, s| {
+                var live = summary_word;
+                while (live != 0) : (live &= live - 1) total += @popCount(self.words[s * 64 + @ctz(live)]);
             }
             return total;
         }
-        
-        pub fn intersectWith(self: *const Self, other: *const Self) Self {
-            var result = Self.initEmpty();
-            for (0..word_count) |i| {
-                result.words[i] = self.words[i] & other.words[i];
+
+        /// Index of the first non-empty word at or after `from`
+        pub fn nextNonEmpty(self: *const Self, from: usize) ?usize {
+            if (from >= word_count) return null;
+            if (!hierarchical) {
+                for (from..word_count) |word_index| {
+                    if (self.words[word_index] != 0) return word_index;
+                }
+                return null;
+            }
+            var s = from >> 6;
+            var live = self.summary[s] & (~@as(u64, 0) << @intCast(from & 63));
+            while (live == 0) {
+                s += 1;
+                if (s >= summary_count) return null;
+                live = self.summary[s];
             }
+            return s * 64 + @ctz(live);
+        }
+
+        /// First set bit at or after `from`, skipping empty words (and empty summary words)
+        pub fn nextSet(self: *const Self, from: u32) ?u32 {
+            if (from >= size) return null;
+            var word_index: usize = from >> 6;
+            var word = self.words[word_index] & (~@as(u64, 0) << @intCast(from & 63));
+            while (word == 0) {
+                word_index = self.nextNonEmpty(word_index + 1) orelse return null;
+                word = self.words[word_index];
+            }
+            return @intCast(word_index * 64 + @ctz(word));
+        }
+
+        // The `...With` forms return a new set by value; the `...Into` forms write into `result`,
+        // which may be `self` or `other`, and are what per-frame query composition should use
+
+        pub fn intersectWith(self: *const Self, other: *const Self) Self {
+            var result: Self = if (hierarchical) initEmpty() else undefined;
+            self.intersectInto(other, &result);
+            return result;
+        }
+
+        pub fn unionWith(self: *const Self, other: *const Self) Self {
+            var result: Self = if (hierarchical) initEmpty() else undefined;
+            self.unionInto(other, &result);
+            return result;
+        }
+
+        /// Entities in `self` but not in `other`
+        pub fn differenceWith(self: *const Self, other: *const Self) Self {
+            var result: Self = if (hierarchical) initEmpty() else undefined;
+            self.subtractInto(other, &result);
+            return result;
+        }
+
+        /// Entities in exactly one of the two sets
+        pub fn symmetricDifferenceWith(self: *const Self, other: *const Self) Self {
+            var result: Self = if (hierarchical) initEmpty() else undefined;
+            self.symmetricDifferenceInto(other, &result);
             return result;
         }
 
         pub fn intersectInto(self: *const Self, other: *const Self, result: *Self) void {
-            for (0..word_count) |i| {
-                result.words[i] = self.words[i] & other.words[i];
+            if (!hierarchical) return kernels.intersect(&result.words, &self.words, &other.words);
+            for (0..summary_count) |s| {
+                const both = self.summary[s] & other.summary[s];
+                result.clearStale(s, both);
+                var live = both;
+                var summary_word: u64 = 0;
+                while (live != 0) : (live &= live - 1) {
+                    const word_index = s * 64 + @ctz(live);
+                    const word = self.words[word_index] & other.words[word_index];
+                    result.words[word_index] = word;
+                    if (word != 0) summary_word |= live & (~live +% 1);
+                }
+                result.summary[s] = summary_word;
+            }
+        }
+
+        pub fn intersect3Into(self: *const Self, b: *const Self, c: *const Self, result: *Self) void {
+            if (!hierarchical) return kernels.intersect3(&result.words, &self.words, &b.words, &c.words);
+            self.intersectInto(b, result);
+            result.intersectInto(c, result);
+        }
+
+        pub fn unionInto(self: *const Self, other: *const Self, result: *Self) void {
+            if (!hierarchical) return kernels.unite(&result.words, &self.words, &other.words);
+            for (0..summary_count) |s| {
+                const any = self.summary[s] | other.summary[s];
+                result.clearStale(s, any);
+                var live = any;
+                while (live != 0) : (live &= live - 1) {
+                    const word_index = s * 64 + @ctz(live);
+                    result.words[word_index] = self.words[word_index] | other.words[word_index];
+                }
+                result.summary[s] = any;
+            }
+        }
+
+        /// Entities in `self` but not in `other`
+        pub fn subtractInto(self: *const Self, other: *const Self, result: *Self) void {
+            if (!hierarchical) return kernels.subtract(&result.words, &self.words, &other.words);
+            for (0..summary_count) |s| {
+                const live_before = self.summary[s];
+                result.clearStale(s, live_before);
+                var live = live_before;
+                var summary_word: u64 = 0;
+                while (live != 0) : (live &= live - 1) {
+                    const word_index = s * 64 + @ctz(live);
+                    const word = self.words[word_index] & ~other.words[word_index];
+                    result.words[word_index] = word;
+                    if (word != 0) summary_word |= live & (~live +% 1);
+                }
+                result.summary[s] = summary_word;
+            }
+        }
+
+        pub fn symmetricDifferenceInto(self: *const Self, other: *const Self, result: *Self) void {
+            if (!hierarchical) return kernels.symmetricDifference(&result.words, &self.words, &other.words);
+            for (0..summary_count) |s| {
+                const any = self.summary[s] | other.summary[s];
+                result.clearStale(s, any);
+                var live = any;
+                var summary_word: u64 = 0;
+                while (live != 0) : (live &= live - 1) {
+                    const word_index = s * 64 + @ctz(live);
+                    const word = self.words[word_index] ^ other.words[word_index];
+                    result.words[word_index] = word;
+                    if (word != 0) summary_word |= live & (~live +% 1);
+                }
+                result.summary[s] = summary_word;
             }
         }
-        
+
+        // Zero the words of summary word `s` that are non-empty now but not in `keep`, so every
+        // word outside the summary stays zero
+        inline fn clearStale(self: *Self, s: usize, keep: u64) void {
+            var stale = self.summary[s] & ~keep;
+            while (stale != 0) : (stale &= stale - 1) self.words[s * 64 + @ctz(stale)] = 0;
+        }
+
         pub fn copyFrom(self: *Self, other: *const Self) void {
-            @memcpy(&self.words, &other.words);
+            if (!hierarchical) return @memcpy(&self.words, &other.words);
+            // Only words live in either set are touched - a world clustered in low ids copies a
+            // handful of words, not the whole array
+            for (0..summary_count) |s| {
+                self.clearStale(s, other.summary[s]);
+                var live = other.summary[s];
+                while (live != 0) : (live &= live - 1) {
+                    const word_index = s * 64 + @ctz(live);
+                    self.words[word_index] = other.words[word_index];
+                }
+                self.summary[s] = other.summary[s];
+            }
         }
-        
+
         // Standard iterator for compatibility
         pub const Iterator = struct {
             bitset: *const Self,
             index: u32,
-            
+
             const IteratorOptions = struct {};
-            
+
             pub fn next(self: *Iterator) ?u32 {
-                while (self.index < size) {
-                    const current = self.index;
-                    self.index += 1;
-                    if (self.bitset.isSet(current)) {
-                        return current;
-                    }
-                }
-                return null;
+                const found = self.bitset.nextSet(self.index) orelse {
+                    self.index = size;
+                    return null;
+                };
+                self.index = found + 1;
+                return found;
             }
         };
-        
+
         pub fn iterator(self: *const Self, _: Iterator.IteratorOptions) Iterator {
             return Iterator{
                 .bitset = self,
                 .index = 0,
             };
         }
-        
-        // Fast word-based iterator
+
+        // Fast word-based iterator - skips empty words (and, past 4096 bits, empty summary words)
         pub const FastIterator = struct {
             bitset: *const Self,
             word_index: usize,
             current_word: u64,
             base_index: u32,
-            
+
             pub fn init(bitset: *const Self) FastIterator {
-                var iter = FastIterator{
+                const first = bitset.nextNonEmpty(0) orelse word_count;
+                return FastIterator{
                     .bitset = bitset,
-                    .word_index = 0,
-                    .current_word = if (word_count > 0) bitset.words[0] else 0,
-                    .base_index = 0,
+                    .word_index = first,
+                    .current_word = if (first < word_count) bitset.words[first] else 0,
+                    .base_index = @intCast(first * 64),
                 };
-                // Skip empty words at start
-                while (iter.word_index < word_count and iter.current_word == 0) {
-                    iter.word_index += 1;
-                    if (iter.word_index < word_count) {
-                        iter.current_word = bitset.words[iter.word_index];
-                        iter.base_index = @intCast(iter.word_index * 64);
-                    }
-                }
-                return iter;
             }
-            
+
             pub inline fn next(self: *FastIterator) ?u32 {
-                while (self.word_index < word_count) {
-                    if (self.current_word != 0) {
-                        const bit_index = @ctz(self.current_word);
-                        const entity = self.base_index + bit_index;
-                        self.current_word &= self.current_word - 1;
-                        return entity;
-                    }
-                    
-                    self.word_index += 1;
-                    if (self.word_index < word_count) {
-                        self.current_word = self.bitset.words[self.word_index];
-                        self.base_index = @intCast(self.word_index * 64);
-                    }
+                while (self.current_word == 0) {
+                    const word_index = self.bitset.nextNonEmpty(self.word_index + 1) orelse {
+                        self.word_index = word_count;
+                        return null;
+                    };
+                    self.word_index = word_index;
+                    self.current_word = self.bitset.words[word_index];
+                    self.base_index = @intCast(word_index * 64);
                 }
-                return null;
+                const bit_index = @ctz(self.current_word);
+                self.current_word &= self.current_word - 1;
+                return self.base_index + bit_index;
             }
         };
-        
+
         pub fn fastIterator(self: *const Self) FastIterator {
             return FastIterator.init(self);
         }
@@ -184,11 +586,18 @@ pub fn ECS(
     comptime config: struct {
         components: []const type,
         input: type,
+        /// World-global singletons - time, RNG state, map data - one value per type. They live in
+        /// the frame, so snapshots, rollback, checksums and `encodeFrame` carry them like components.
+        resources: []const type = &.{},
         max_entities: EntityLimit = .medium,
+        /// Validate storage invariants after every tick and panic on the first violation.
+        /// Expensive (touches every entity) - meant for tests and debug sessions.
+        debug_checks: bool = false,
     },
 ) type {
     const ComponentTypes = config.components;
     const InputType = config.input;
+    const ResourceTypes = config.resources;
     const MAX_ENTITIES = config.max_entities.toInt();
 
     // Compile-time validation
@@ -199,31 +608,135 @@ pub fn ECS(
         if (ComponentTypes.len > 64) {
             @compileError("Maximum 64 component types supported (for bitmask)");
         }
+        for (ResourceTypes, 0..) |R, i| {
+            for (ResourceTypes[0..i]) |Earlier| {
+                if (Earlier == R) @compileError("Resource type '" ++ @typeName(R) ++ "' registered twice");
+            }
+        }
     }
 
     return struct {
         const Self = @This();
-        const EntityBitSet = BitSet(MAX_ENTITIES);
+
+        /// Bitset with one bit per entity slot - the currency of queries and spatial lookups
+        pub const EntityBitSet = BitSet(MAX_ENTITIES);
+        pub const max_entities: u32 = MAX_ENTITIES;
+
+        // Worlds past `EntityLimit.massive` page their entity -> dense index arrays: a page is
+        // allocated the first time one of its entities gains the component, so a sparse component
+        // costs memory for the entity ranges it touches instead of 4 bytes per possible entity
+        const paged_index = MAX_ENTITIES > 4096;
+        const index_page_size = 4096;
+        const index_page_count = (MAX_ENTITIES + index_page_size - 1) / index_page_size;
+        const IndexPage = [index_page_size]u32;
+        const EntityIndexArray = if (paged_index) [index_page_count]?*IndexPage else [MAX_ENTITIES]u32;
+
+        // Reverse index of one relation kind, one entry per entity slot: the slot heads the list
+        // of sources pointing at it, and links its own relation into its target's list. Lists
+        // are kept in ascending source order.
+        const RelationLink = struct {
+            first_source: EntityID = INVALID_ENTITY,
+            next: EntityID = INVALID_ENTITY,
+            prev: EntityID = INVALID_ENTITY,
+        };
+        const RelationLinks = std.ArrayListUnmanaged(RelationLink);
 
         /// Generate component storage type for a specific component
         fn generateComponentStorage(comptime T: type) type {
             return struct {
                 dense: std.ArrayList(T),
+                /// Owner of each dense slot (index -> entity), parallel to `dense`
+                dense_entities: std.ArrayList(EntityID),
                 entity_bitset: EntityBitSet,
-                entity_to_index: [MAX_ENTITIES]u32,
+                entity_to_index: EntityIndexArray,
+                /// Changes whenever an entity gains or loses this component; data writes leave it alone
+                version: u64 = 0,
+
+                // Change detection: writes are stamped with the tick being simulated. Adding a
+                // component counts as a change; plain `get` pointers don't - write through `getMut`
+                // or call `markChanged` so reactive systems and delta snapshots see the write.
+                /// Tick of each dense slot's last change, parallel to `dense`
+                changed_ticks: std.ArrayList(u64),
+                /// Entities changed during the current tick - the dirty set, cleared as the tick advances
+                changed: EntityBitSet,
+                /// Tick being simulated, stamped on every change
+                tick: u64 = 0,
+                /// Version counter for change detection: the tick of the latest change, so a system
+                /// can skip the storage when nothing moved since it last ran
+                last_changed: u64 = 0,
 
                 const ComponentStorage = @This();
 
                 pub fn init(allocator: std.mem.Allocator) ComponentStorage {
                     return ComponentStorage{
                         .dense = std.ArrayList(T).init(allocator),
+                        .dense_entities = std.ArrayList(EntityID).init(allocator),
                         .entity_bitset = EntityBitSet.initEmpty(),
-                        .entity_to_index = [_]u32{0} ** MAX_ENTITIES,
+                        .entity_to_index = if (paged_index) [_]?*IndexPage{null} ** index_page_count else [_]u32{0} ** MAX_ENTITIES,
+                        .changed_ticks = std.ArrayList(u64).init(allocator),
+                        .changed = EntityBitSet.initEmpty(),
                     };
                 }
 
                 pub fn deinit(self: *ComponentStorage) void {
+                    if (paged_index) {
+                        for (self.entity_to_index) |page| {
+                            if (page) |p| self.dense.allocator.destroy(p);
+                        }
+                    }
                     self.dense.deinit();
+                    self.dense_entities.deinit();
+                    self.changed_ticks.deinit();
+                }
+
+                /// Dense index of an entity holding the component
+                pub inline fn indexOf(self: *const ComponentStorage, entity: EntityID) u32 {
+                    return if (paged_index)
+                        self.entity_to_index[entity / index_page_size].?[entity % index_page_size]
+                    else
+                        self.entity_to_index[entity];
+                }
+
+                // Index slot of an entity whose page already exists
+                inline fn indexSlot(self: *ComponentStorage, entity: EntityID) *u32 {
+                    return if (paged_index)
+                        &self.entity_to_index[entity / index_page_size].?[entity % index_page_size]
+                    else
+                        &self.entity_to_index[entity];
+                }
+
+                fn ensureIndexSlot(self: *ComponentStorage, entity: EntityID) !*u32 {
+                    if (paged_index) {
+                        const page = &self.entity_to_index[entity / index_page_size];
+                        if (page.* == null) {
+                            const fresh = try self.dense.allocator.create(IndexPage);
+                            @memset(fresh, 0);
+                            page.* = fresh;
+                        }
+                    }
+                    return self.indexSlot(entity);
+                }
+
+                fn copyIndexFrom(self: *ComponentStorage, other: *const ComponentStorage) !void {
+                    if (paged_index) {
+                        // Pages the source never touched hold no live entries; ours can stay as they are
+                        for (other.entity_to_index, 0..) |other_page, i| {
+                            const source = other_page orelse continue;
+                            _ = try self.ensureIndexSlot(@intCast(i * index_page_size));
+                            self.entity_to_index[i].?.* = source.*;
+                        }
+                    } else {
+                        self.entity_to_index = other.entity_to_index;
+                    }
+                }
+
+                /// Bytes held by the entity -> index lookup
+                pub fn indexBytes(self: *const ComponentStorage) usize {
+                    var pages: usize = 0;
+                    if (paged_index) {
+                        for (self.entity_to_index) |page| pages += @intFromBool(page != null);
+                    }
+                    return @sizeOf(EntityIndexArray) + pages * @sizeOf(IndexPage);
                 }
 
                 pub fn add(self: *ComponentStorage, entity: EntityID, component: T) !void {
@@ -235,17 +748,31 @@ pub fn ECS(
                     if (self.entity_bitset.isSet(entity)) return;
 
                     const index = @as(u32, @intCast(self.dense.items.len));
+                    const slot = try self.ensureIndexSlot(entity);
+                    try self.dense_entities.ensureUnusedCapacity(1);
+                    try self.changed_ticks.ensureUnusedCapacity(1);
                     try self.dense.append(component);
-                    self.entity_to_index[entity] = index;
+                    self.dense_entities.appendAssumeCapacity(entity);
+                    self.changed_ticks.appendAssumeCapacity(self.tick);
+                    slot.* = index;
                     self.entity_bitset.set(entity);
+                    self.version = nextMembershipVersion();
+                    self.changed.set(entity);
+                    self.last_changed = self.tick;
                 }
 
                 pub fn get(self: *ComponentStorage, entity: EntityID) ?*T {
                     if (entity >= MAX_ENTITIES) return null;
                     if (!self.entity_bitset.isSet(entity)) return null;
 
-                    const index = self.entity_to_index[entity];
-                    return &self.dense.items[index];
+                    return &self.dense.items[self.indexOf(entity)];
+                }
+
+                /// `get` for writing: marks the component changed this tick
+                pub fn getMut(self: *ComponentStorage, entity: EntityID) ?*T {
+                    const component = self.get(entity) orelse return null;
+                    self.markChanged(entity);
+                    return component;
                 }
 
                 pub fn has(self: *ComponentStorage, entity: EntityID) bool {
@@ -253,31 +780,64 @@ pub fn ECS(
                     return self.entity_bitset.isSet(entity);
                 }
 
+                /// Record a write made through a plain pointer (`get`, `getDirect`, the dense array)
+                pub fn markChanged(self: *ComponentStorage, entity: EntityID) void {
+                    if (!self.has(entity)) return;
+                    self.changed_ticks.items[self.indexOf(entity)] = self.tick;
+                    self.changed.set(entity);
+                    self.last_changed = self.tick;
+                }
+
+                /// Tick the entity's component last changed, null if it doesn't have one
+                pub fn changedAt(self: *const ComponentStorage, entity: EntityID) ?u64 {
+                    if (entity >= MAX_ENTITIES or !self.entity_bitset.isSet(entity)) return null;
+                    return self.changed_ticks.items[self.indexOf(entity)];
+                }
+
+                /// Entities whose component changed after tick `since`, written into `result`
+                pub fn changedSince(self: *const ComponentStorage, since: u64, result: *EntityBitSet) void {
+                    if (since >= self.last_changed) {
+                        result.clear();
+                    } else if (since + 1 == self.tick) {
+                        // Exactly the current tick - the dirty set already holds the answer
+                        result.copyFrom(&self.changed);
+                    } else {
+                        result.clear();
+                        for (self.changed_ticks.items, self.dense_entities.items) |changed_tick, entity| {
+                            if (changed_tick > since) result.set(entity);
+                        }
+                    }
+                }
+
+                /// Start stamping changes with `tick`; the dirty set is emptied when it moves on
+                pub fn beginTick(self: *ComponentStorage, tick: u64) void {
+                    if (tick == self.tick) return;
+                    if (self.last_changed == self.tick) self.changed.clear();
+                    self.tick = tick;
+                }
+
                 pub fn remove(self: *ComponentStorage, entity: EntityID) bool {
                     if (entity >= MAX_ENTITIES) return false;
                     if (!self.entity_bitset.isSet(entity)) return false;
 
-                    const index = self.entity_to_index[entity];
+                    const index = self.indexOf(entity);
                     const last_index = self.dense.items.len - 1;
 
                     if (index != last_index) {
-                        // Need to find which entity is at the last position
-                        // We'll iterate through active entities to find it
-                        var last_entity: EntityID = 0;
-                        for (0..MAX_ENTITIES) |e| {
-                            const eid = @as(EntityID, @intCast(e));
-                            if (self.entity_bitset.isSet(eid) and self.entity_to_index[eid] == last_index) {
-                                last_entity = eid;
-                                break;
-                            }
-                        }
-                        
+                        // Swap the last entry into the hole
+                        const last_entity = self.dense_entities.items[last_index];
                         self.dense.items[index] = self.dense.items[last_index];
-                        self.entity_to_index[last_entity] = index;
+                        self.dense_entities.items[index] = last_entity;
+                        self.changed_ticks.items[index] = self.changed_ticks.items[last_index];
+                        self.indexSlot(last_entity).* = index;
                     }
 
                     _ = self.dense.pop();
+                    _ = self.dense_entities.pop();
+                    _ = self.changed_ticks.pop();
                     self.entity_bitset.unset(entity);
+                    self.changed.unset(entity);
+                    self.version = nextMembershipVersion();
 
                     return true;
                 }
@@ -288,13 +848,11 @@ pub fn ECS(
 
                 // Direct access methods for hot paths
                 pub inline fn getDirect(self: *ComponentStorage, entity: EntityID) *T {
-                    const index = self.entity_to_index[entity];
-                    return &self.dense.items[index];
+                    return &self.dense.items[self.indexOf(entity)];
                 }
 
                 pub inline fn getDirectConst(self: *const ComponentStorage, entity: EntityID) *const T {
-                    const index = self.entity_to_index[entity];
-                    return &self.dense.items[index];
+                    return &self.dense.items[self.indexOf(entity)];
                 }
 
                 // Expose raw arrays for maximum performance direct access
@@ -303,7 +861,16 @@ pub fn ECS(
                 }
 
                 pub inline fn getEntityToIndexArray(self: *ComponentStorage) *[MAX_ENTITIES]u32 {
-                    return &self.entity_to_index;
+                    if (paged_index) {
+                        @compileError("worlds past EntityLimit.massive page their index - use indexOf");
+                    } else {
+                        return &self.entity_to_index;
+                    }
+                }
+
+                /// Entities owning each dense slot, in the same order as `getDenseArray`
+                pub inline fn getDenseEntities(self: *ComponentStorage) []EntityID {
+                    return self.dense_entities.items;
                 }
             };
         }
@@ -316,6 +883,132 @@ pub fn ECS(
             break :blk storage_types;
         };
 
+        /// Component names in registration order (used by debugging tools and reports)
+        pub const component_names: [ComponentTypes.len][]const u8 = blk: {
+            var names: [ComponentTypes.len][]const u8 = undefined;
+            for (ComponentTypes, 0..) |T, i| {
+                names[i] = shortTypeName(T);
+            }
+            break :blk names;
+        };
+
+        /// Resource types in registration order (schema registry, serializers)
+        pub const resource_types: []const type = ResourceTypes;
+
+        /// One value of every resource type, zeroed until set
+        pub const Resources = std.meta.Tuple(ResourceTypes);
+
+        fn getResourceIndex(comptime T: type) comptime_int {
+            inline for (ResourceTypes, 0..) |ResourceType, i| {
+                if (ResourceType == T) return i;
+            }
+            @compileError("Resource type '" ++ @typeName(T) ++ "' not registered. " ++
+                "Add it to the resources array in ECS config: " ++
+                "resources = &.{ " ++ @typeName(T) ++ " }");
+        }
+
+        /// Component sizes in bytes, in registration order (packing reports)
+        pub const component_sizes: [ComponentTypes.len]usize = blk: {
+            var sizes: [ComponentTypes.len]usize = undefined;
+            for (ComponentTypes, 0..) |T, i| {
+                sizes[i] = @sizeOf(T);
+            }
+            break :blk sizes;
+        };
+
+        /// Component types in registration order (schema registry, serializers)
+        pub const component_types: []const type = ComponentTypes;
+
+        /// Per-part state hashes of a frame, for desync detection. Peers exchange `total()` each tick;
+        /// once totals disagree, comparing the full sets (`firstDivergence`) tells which storage
+        /// went out of step.
+        pub const Checksums = struct {
+            /// Live entities with their generations, the free list and `next_entity`
+            entities: u64,
+            /// One per storage in registration order - entity ids and component values
+            components: [ComponentTypes.len]u64,
+            /// Every resource value in registration order
+            resources: u64,
+
+            pub fn total(self: *const Checksums) u64 {
+                var hasher = std.hash.XxHash64.init(0);
+                hashCanonical(&hasher, Checksums, self);
+                return hasher.final();
+            }
+
+            /// "entities" or the name of the first component storage that differs, null if none
+            pub fn firstDivergence(self: *const Checksums, other: *const Checksums) ?[]const u8 {
+                if (self.entities != other.entities) return "entities";
+                for (self.components, other.components, 0..) |mine, theirs, i| {
+                    if (mine != theirs) return component_names[i];
+                }
+                if (self.resources != other.resources) return "resources";
+                return null;
+            }
+        };
+
+        /// Bitmask with one bit per component type (bit index = registration order)
+        pub fn componentMask(comptime Types: []const type) u64 {
+            comptime {
+                var bits: u64 = 0;
+                for (Types) |T| {
+                    bits |= @as(u64, 1) << getComponentIndex(T);
+                }
+                return bits;
+            }
+        }
+
+        /// ID of component `T` - its position in `config.components`. Any user type becomes a
+        /// component by being listed there; IDs index `component_names`, masks and storages.
+        pub fn componentId(comptime T: type) u6 {
+            return comptime getComponentIndex(T);
+        }
+
+        /// Whether `T` is registered - lets generic systems and plugins adapt to the world they get
+        pub fn isComponent(comptime T: type) bool {
+            inline for (ComponentTypes) |ComponentType| {
+                if (ComponentType == T) return true;
+            }
+            return false;
+        }
+
+        /// Storage type of component `T` - what `getComponentStorage(T)` points at
+        pub fn Storage(comptime T: type) type {
+            return ComponentStorageTypes[getComponentIndex(T)];
+        }
+
+        /// What `frame.relatedTo(Kind, target)` returns - a walk of the target's sources in the
+        /// `Relation(Kind)` index. Removing the source just returned is fine.
+        pub fn RelatedIterator(comptime Kind: type) type {
+            return struct {
+                storage: *const ComponentStorageTypes[getComponentIndex(Relation(Kind))],
+                links: []const RelationLink,
+                cursor: EntityID,
+                target: EntityHandle,
+
+                pub fn next(self: *@This()) ?EntityID {
+                    while (self.cursor != INVALID_ENTITY) {
+                        const source = self.cursor;
+                        self.cursor = self.links[source].next;
+                        // Relations added with a handle of an earlier occupant of the slot don't count
+                        const target: u64 = @bitCast(self.storage.getDirectConst(source).target);
+                        if (target == @as(u64, @bitCast(self.target))) return source;
+                    }
+                    return null;
+                }
+            };
+        }
+
+        /// Type of the query `frame.query(QueryTypes)` returns
+        pub fn Query(comptime QueryTypes: []const type) type {
+            return generateQuery(.{ .with = QueryTypes }, FrameState);
+        }
+
+        /// Type of the query `frame.queryFiltered(filter)` returns
+        pub fn FilteredQuery(comptime filter: QueryFilter) type {
+            return generateQuery(filter, FrameState);
+        }
+
         fn getComponentIndex(comptime T: type) comptime_int {
             inline for (ComponentTypes, 0..) |ComponentType, i| {
                 if (ComponentType == T) return i;
@@ -331,42 +1024,177 @@ pub fn ECS(
             next_entity: EntityID,
             entity_count: u32,
             allocator: std.mem.Allocator,
+            /// Membership version of `active_entities` (see `ComponentStorage.version`)
+            entity_version: u64 = 0,
+            /// Generation of every entity slot used so far, bumped when its entity is destroyed
+            generations: std.ArrayListUnmanaged(u32) = .{},
+            /// Destroyed slots waiting for reuse, most recently destroyed last. Capacity always
+            /// covers `generations`, so destroying never allocates.
+            free_entities: std.ArrayListUnmanaged(EntityID) = .{},
+            /// Target -> sources index of every `Relation(...)` component, empty for the others.
+            /// Covers every slot a relation points at.
+            relation_links: [ComponentTypes.len]RelationLinks = [_]RelationLinks{.{}} ** ComponentTypes.len,
+            resources: Resources = std.mem.zeroes(Resources),
 
             // Pre-allocated bitsets for query operations - no allocations during queries
             query_result: EntityBitSet,
             query_temp: EntityBitSet,
+            /// Bumped whenever a query takes `query_result`, so a query whose results were
+            /// overwritten by a later one trips an assert instead of iterating someone else's set
+            query_generation: u32 = 0,
+
+            // Debug observers - never copied between frames
+            observers: std.BoundedArray(Observer, MAX_OBSERVERS) = .{},
+            query_analyzer: ?*QueryAnalyzer = null,
 
             const FrameStateSelf = @This();
 
-            pub fn createEntity(self: *FrameStateSelf) !EntityID {
-                var entity = self.next_entity;
-                while (entity < MAX_ENTITIES and self.active_entities.isSet(entity)) {
-                    entity += 1;
+            inline fn notify(self: *const FrameStateSelf, change: StructuralChange) void {
+                for (self.observers.constSlice()) |observer| {
+                    observer.vtable.onChange(observer.ptr, change);
                 }
+            }
 
-                if (entity >= MAX_ENTITIES) {
-                    std.log.err("Cannot create entity: would exceed max limit of {} entities. " ++
-                        "Increase max_entities in ECS config (current: {s})", .{ MAX_ENTITIES, @tagName(config.max_entities) });
-                    return error.EntityLimitExceeded;
+            fn notifyTick(self: *const FrameStateSelf, tick: u64) void {
+                for (self.observers.constSlice()) |observer| {
+                    observer.vtable.onTick(observer.ptr, tick);
                 }
+            }
+
+            /// Reuses the most recently destroyed slot (its generation already moved on, so old
+            /// handles stay dead) before taking a fresh id - O(1) under heavy spawn/despawn churn
+            pub fn createEntity(self: *FrameStateSelf) !EntityID {
+                const entity = self.free_entities.pop() orelse fresh: {
+                    var candidate = self.next_entity;
+                    // Loaders may have restored entities past `next_entity`
+                    while (candidate < MAX_ENTITIES and self.active_entities.isSet(candidate)) {
+                        candidate += 1;
+                    }
+
+                    if (candidate >= MAX_ENTITIES) {
+                        std.log.err("Cannot create entity: would exceed max limit of {} entities. " ++
+                            "Increase max_entities in ECS config (current: {s})", .{ MAX_ENTITIES, @tagName(config.max_entities) });
+                        return error.EntityLimitExceeded;
+                    }
+                    try self.ensureSlot(candidate);
+                    self.next_entity = candidate + 1;
+                    break :fresh candidate;
+                };
 
                 self.active_entities.set(entity);
                 self.entity_count += 1;
-                self.next_entity = entity + 1;
+                self.entity_version = nextMembershipVersion();
+
+                self.notify(.{ .kind = .create_entity, .entity = entity, .call_site = @returnAddress() });
 
                 return entity;
             }
 
+            // Give `entity` a generation, and the free list room to take it back later
+            fn ensureSlot(self: *FrameStateSelf, entity: EntityID) !void {
+                try self.ensureRelationSlots(entity + 1);
+                if (entity < self.generations.items.len) return;
+                const used = self.generations.items.len;
+                try self.free_entities.ensureTotalCapacity(self.allocator, entity + 1);
+                try self.generations.resize(self.allocator, entity + 1);
+                @memset(self.generations.items[used..], 0);
+            }
+
+            fn ensureRelationSlots(self: *FrameStateSelf, len: usize) !void {
+                inline for (ComponentTypes, 0..) |T, i| {
+                    if (comptime isRelation(T)) {
+                        const links = &self.relation_links[i];
+                        if (links.items.len < len) {
+                            const used = links.items.len;
+                            try links.resize(self.allocator, len);
+                            @memset(links.items[used..], .{});
+                        }
+                    }
+                }
+            }
+
+            // Put `source`'s relation of component `i` into its target's list
+            fn linkRelation(self: *FrameStateSelf, comptime i: usize, source: EntityID) !void {
+                const target = self.components[i].get(source).?.target.index;
+                if (target >= MAX_ENTITIES) return;
+                try self.ensureRelationSlots(@max(source, target) + 1);
+
+                const links = self.relation_links[i].items;
+                var prev: EntityID = INVALID_ENTITY;
+                var next = links[target].first_source;
+                while (next != INVALID_ENTITY and next < source) {
+                    prev = next;
+                    next = links[next].next;
+                }
+                links[source].prev = prev;
+                links[source].next = next;
+                if (prev != INVALID_ENTITY) links[prev].next = source else links[target].first_source = source;
+                if (next != INVALID_ENTITY) links[next].prev = source;
+            }
+
+            // Take `source`'s relation of component `i`, if it has one, out of its target's list
+            fn unlinkRelation(self: *FrameStateSelf, comptime i: usize, source: EntityID) void {
+                const relation = self.components[i].get(source) orelse return;
+                const target = relation.target.index;
+                const links = self.relation_links[i].items;
+                if (target >= links.len) return;
+
+                const prev = links[source].prev;
+                const next = links[source].next;
+                if (prev != INVALID_ENTITY) {
+                    links[prev].next = next;
+                } else if (links[target].first_source == source) {
+                    links[target].first_source = next;
+                } else return;
+                if (next != INVALID_ENTITY) links[next].prev = prev;
+                links[source].prev = INVALID_ENTITY;
+                links[source].next = INVALID_ENTITY;
+            }
+
+            /// Bring an entity back to life under a known id - for loaders rebuilding a saved frame.
+            /// Leaves `next_entity` alone; the loader restores it with the rest of the counters.
+            /// Takes the slot off the free list if it is there (a linear search).
+            pub fn restoreEntity(self: *FrameStateSelf, entity: EntityID) !void {
+                if (entity >= MAX_ENTITIES) return error.EntityLimitExceeded;
+                if (self.active_entities.isSet(entity)) return error.EntityExists;
+                try self.ensureSlot(entity);
+                for (self.free_entities.items, 0..) |free, i| {
+                    if (free == entity) {
+                        _ = self.free_entities.orderedRemove(i);
+                        break;
+                    }
+                }
+
+                self.active_entities.set(entity);
+                self.entity_count += 1;
+                self.entity_version = nextMembershipVersion();
+
+                self.notify(.{ .kind = .create_entity, .entity = entity, .call_site = @returnAddress() });
+            }
+
             pub fn destroyEntity(self: *FrameStateSelf, entity: EntityID) void {
                 if (entity >= MAX_ENTITIES) return;
                 if (!self.active_entities.isSet(entity)) return;
 
-                inline for (0..ComponentTypes.len) |i| {
+                // Relations pointing at the entity die with it
+                inline for (ComponentTypes) |T| {
+                    if (comptime isRelation(T)) {
+                        var sources = self.relatedTo(T.relation_kind, entity);
+                        while (sources.next()) |source| _ = self.removeComponent(source, T);
+                    }
+                }
+                inline for (ComponentTypes, 0..) |T, i| {
+                    if (comptime isRelation(T)) self.unlinkRelation(i, entity);
                     _ = self.components[i].remove(entity);
                 }
 
                 self.active_entities.unset(entity);
+                self.generations.items[entity] +%= 1;
+                self.free_entities.appendAssumeCapacity(entity);
                 self.entity_count -= 1;
+                self.entity_version = nextMembershipVersion();
+
+                self.notify(.{ .kind = .destroy_entity, .entity = entity, .call_site = @returnAddress() });
             }
 
             pub fn addComponent(self: *FrameStateSelf, entity: EntityID, component: anytype) !void {
@@ -386,7 +1214,17 @@ pub fn ECS(
                 }
 
                 const storage_index = comptime getComponentIndex(T);
-                try self.components[storage_index].add(entity, component);
+                const storage = &self.components[storage_index];
+                if (storage.has(entity)) return;
+
+                try storage.add(entity, component);
+                if (comptime isRelation(T)) {
+                    self.linkRelation(storage_index, entity) catch |err| {
+                        _ = storage.remove(entity);
+                        return err;
+                    };
+                }
+                self.notify(.{ .kind = .add_component, .entity = entity, .component = storage_index, .call_site = @returnAddress() });
             }
 
             pub fn getComponent(self: *FrameStateSelf, entity: EntityID, comptime T: type) ?*T {
@@ -395,19 +1233,175 @@ pub fn ECS(
                 return self.components[storage_index].get(entity);
             }
 
-            pub fn hasComponent(self: *FrameStateSelf, entity: EntityID, comptime T: type) bool {
-                if (entity == INVALID_ENTITY) return false;
+            /// `getComponent` for writing: marks the component changed this tick
+            pub fn getComponentMut(self: *FrameStateSelf, entity: EntityID, comptime T: type) ?*T {
+                if (entity == INVALID_ENTITY) return null;
                 const storage_index = comptime getComponentIndex(T);
-                return self.components[storage_index].has(entity);
+                return self.components[storage_index].getMut(entity);
             }
 
-            pub fn removeComponent(self: *FrameStateSelf, entity: EntityID, comptime T: type) bool {
+            pub fn markChanged(self: *FrameStateSelf, entity: EntityID, comptime T: type) void {
                 const storage_index = comptime getComponentIndex(T);
-                return self.components[storage_index].remove(entity);
-            }
-
-            pub fn query(self: *FrameStateSelf, comptime QueryTypes: []const type) !generateQuery(QueryTypes, FrameStateSelf) {
-                return generateQuery(QueryTypes, FrameStateSelf).init(self);
+                self.components[storage_index].markChanged(entity);
+            }
+
+            pub fn hasComponent(self: *FrameStateSelf, entity: EntityID, comptime T: type) bool {
+                if (entity == INVALID_ENTITY) return false;
+                const storage_index = comptime getComponentIndex(T);
+                return self.components[storage_index].has(entity);
+            }
+
+            /// Stamp changes from here on with `tick` (called by `update` for each new frame)
+            pub fn beginTick(self: *FrameStateSelf, tick: u64) void {
+                inline for (0..ComponentTypes.len) |i| {
+                    self.components[i].beginTick(tick);
+                }
+            }
+
+            pub fn removeComponent(self: *FrameStateSelf, entity: EntityID, comptime T: type) bool {
+                const storage_index = comptime getComponentIndex(T);
+                if (comptime isRelation(T)) self.unlinkRelation(storage_index, entity);
+                const removed = self.components[storage_index].remove(entity);
+                if (removed) {
+                    self.notify(.{ .kind = .remove_component, .entity = entity, .component = storage_index, .call_site = @returnAddress() });
+                }
+                return removed;
+            }
+
+            /// Point `source`'s `Kind` relation at `target`, replacing any previous target. Both must
+            /// be alive; `Relation(Kind)` must be registered.
+            pub fn relate(self: *FrameStateSelf, source: EntityID, comptime Kind: type, target: EntityID) !void {
+                const target_handle = self.handle(target);
+                if (target_handle.index == INVALID_ENTITY) return error.InvalidEntity;
+                if (self.getComponentMut(source, Relation(Kind))) |relation| {
+                    const storage_index = comptime getComponentIndex(Relation(Kind));
+                    self.unlinkRelation(storage_index, source);
+                    const previous = relation.target;
+                    relation.target = target_handle;
+                    self.linkRelation(storage_index, source) catch |err| {
+                        relation.target = previous;
+                        self.linkRelation(storage_index, source) catch unreachable;
+                        return err;
+                    };
+                } else {
+                    try self.addComponent(source, Relation(Kind){ .target = target_handle });
+                }
+            }
+
+            pub fn unrelate(self: *FrameStateSelf, source: EntityID, comptime Kind: type) bool {
+                return self.removeComponent(source, Relation(Kind));
+            }
+
+            /// The entity `source`'s `Kind` relation points at, null without one
+            pub fn relationTarget(self: *FrameStateSelf, source: EntityID, comptime Kind: type) ?EntityID {
+                const relation = self.getComponent(source, Relation(Kind)) orelse return null;
+                return self.resolve(relation.target);
+            }
+
+            /// Entities whose `Kind` relation points at `target`, in ascending id order
+            pub fn relatedTo(self: *const FrameStateSelf, comptime Kind: type, target: EntityID) RelatedIterator(Kind) {
+                const storage_index = comptime getComponentIndex(Relation(Kind));
+                const links = self.relation_links[storage_index].items;
+                return .{
+                    .storage = &self.components[storage_index],
+                    .links = links,
+                    .cursor = if (target < links.len) links[target].first_source else INVALID_ENTITY,
+                    .target = self.handle(target),
+                };
+            }
+
+            pub fn query(self: *FrameStateSelf, comptime QueryTypes: []const type) !generateQuery(.{ .with = QueryTypes }, FrameStateSelf) {
+                return generateQuery(.{ .with = QueryTypes }, FrameStateSelf).init(self);
+            }
+
+            pub fn queryFiltered(self: *FrameStateSelf, comptime filter: QueryFilter) !generateQuery(filter, FrameStateSelf) {
+                return generateQuery(filter, FrameStateSelf).init(self);
+            }
+
+            /// `query` with its results in `out` instead of the frame's scratch set - for queries
+            /// running inside another query's loop. `out` must outlive the query.
+            pub fn queryInto(self: *FrameStateSelf, comptime QueryTypes: []const type, out: *EntityBitSet) !generateQuery(.{ .with = QueryTypes }, FrameStateSelf) {
+                return generateQuery(.{ .with = QueryTypes }, FrameStateSelf).initInto(self, out);
+            }
+
+            pub fn queryFilteredInto(self: *FrameStateSelf, comptime filter: QueryFilter, out: *EntityBitSet) !generateQuery(filter, FrameStateSelf) {
+                return generateQuery(filter, FrameStateSelf).initInto(self, out);
+            }
+
+            /// Canonical hash of the simulation state: every storage is walked in ascending entity
+            /// order and values are hashed field by field, so equal states hash equal whatever their
+            /// dense layout or struct padding. Change-detection ticks, debug state and components
+            /// that opt out (`isChecksummed`) are left out.
+            pub fn checksum(self: *const FrameStateSelf) u64 {
+                return self.checksums().total();
+            }
+
+            /// `checksum` split by part, to find out which storage diverged
+            pub fn checksums(self: *const FrameStateSelf) Checksums {
+                var result: Checksums = undefined;
+
+                var hasher = std.hash.XxHash64.init(0);
+                hashCanonical(&hasher, EntityID, &self.next_entity);
+                var entities = self.active_entities.fastIterator();
+                while (entities.next()) |entity| {
+                    hashCanonical(&hasher, EntityID, &entity);
+                    hashCanonical(&hasher, u32, &self.generations.items[entity]);
+                }
+                const free: []const EntityID = self.free_entities.items;
+                hashCanonical(&hasher, []const EntityID, &free);
+                result.entities = hasher.final();
+
+                inline for (ComponentTypes, 0..) |T, i| {
+                    const storage = &self.components[i];
+                    hasher = std.hash.XxHash64.init(0);
+                    if (comptime isChecksummed(T)) {
+                        var holders = storage.entity_bitset.fastIterator();
+                        while (holders.next()) |entity| {
+                            hashCanonical(&hasher, EntityID, &entity);
+                            hashCanonical(&hasher, T, storage.getDirectConst(entity));
+                        }
+                    }
+                    result.components[i] = hasher.final();
+                }
+
+                hasher = std.hash.XxHash64.init(0);
+                hashCanonical(&hasher, Resources, &self.resources);
+                result.resources = hasher.final();
+                return result;
+            }
+
+            /// Handle of a live entity (`EntityHandle.invalid` if it isn't alive)
+            pub fn handle(self: *const FrameStateSelf, entity: EntityID) EntityHandle {
+                if (entity >= MAX_ENTITIES or !self.active_entities.isSet(entity)) return EntityHandle.invalid;
+                return .{ .index = entity, .generation = self.generations.items[entity] };
+            }
+
+            /// The entity a handle names, or null once it was destroyed
+            pub fn resolve(self: *const FrameStateSelf, entity_handle: EntityHandle) ?EntityID {
+                const index = entity_handle.index;
+                if (index >= self.generations.items.len) return null;
+                if (self.generations.items[index] != entity_handle.generation) return null;
+                if (!self.active_entities.isSet(index)) return null;
+                return index;
+            }
+
+            /// `getComponent` through a handle: null once the handle's entity was destroyed, even
+            /// after its slot went to a new entity
+            pub fn getComponentByHandle(self: *FrameStateSelf, entity_handle: EntityHandle, comptime T: type) ?*T {
+                return self.getComponent(self.resolve(entity_handle) orelse return null, T);
+            }
+
+            pub fn getComponentMutByHandle(self: *FrameStateSelf, entity_handle: EntityHandle, comptime T: type) ?*T {
+                return self.getComponentMut(self.resolve(entity_handle) orelse return null, T);
+            }
+
+            pub fn hasComponentByHandle(self: *FrameStateSelf, entity_handle: EntityHandle, comptime T: type) bool {
+                return self.hasComponent(self.resolve(entity_handle) orelse return false, T);
+            }
+
+            /// `removeComponent` through a handle; a stale handle removes nothing
+            pub fn removeComponentByHandle(self: *FrameStateSelf, entity_handle: EntityHandle, comptime T: type) bool {
+                return self.removeComponent(self.resolve(entity_handle) orelse return false, T);
             }
 
             pub fn getEntityCount(self: *const FrameStateSelf) u32 {
@@ -419,17 +1413,41 @@ pub fn ECS(
                 return &self.components[storage_index];
             }
 
+            pub inline fn getResource(self: *FrameStateSelf, comptime T: type) *T {
+                return &self.resources[comptime getResourceIndex(T)];
+            }
+
             pub fn copyFrom(self: *FrameStateSelf, other: *const FrameStateSelf) !void {
                 self.active_entities.copyFrom(&other.active_entities);
                 self.next_entity = other.next_entity;
                 self.entity_count = other.entity_count;
+                self.entity_version = other.entity_version;
+                self.resources = other.resources;
+
+                try self.generations.ensureTotalCapacity(self.allocator, other.generations.items.len);
+                self.generations.items.len = other.generations.items.len;
+                @memcpy(self.generations.items, other.generations.items);
+
+                try self.free_entities.ensureTotalCapacity(self.allocator, other.generations.items.len);
+                self.free_entities.items.len = other.free_entities.items.len;
+                @memcpy(self.free_entities.items, other.free_entities.items);
+
+                for (&self.relation_links, &other.relation_links) |*links, *other_links| {
+                    try links.ensureTotalCapacity(self.allocator, other_links.items.len);
+                    links.items.len = other_links.items.len;
+                    @memcpy(links.items, other_links.items);
+                }
 
                 inline for (0..ComponentTypes.len) |i| {
                     const other_storage = &other.components[i];
                     var storage = &self.components[i];
 
                     storage.entity_bitset.copyFrom(&other_storage.entity_bitset);
-                    storage.entity_to_index = other_storage.entity_to_index;
+                    try storage.copyIndexFrom(other_storage);
+                    storage.version = other_storage.version;
+                    storage.changed.copyFrom(&other_storage.changed);
+                    storage.tick = other_storage.tick;
+                    storage.last_changed = other_storage.last_changed;
 
                     // Optimized copying using @memcpy - avoid resize() reallocation overhead
                     const other_dense_len = other_storage.dense.items.len;
@@ -443,63 +1461,346 @@ pub fn ECS(
                     if (other_dense_len > 0) {
                         @memcpy(storage.dense.items, other_storage.dense.items);
                     }
+
+                    try storage.dense_entities.ensureTotalCapacity(other_dense_len);
+                    storage.dense_entities.items.len = other_dense_len;
+                    if (other_dense_len > 0) {
+                        @memcpy(storage.dense_entities.items, other_storage.dense_entities.items);
+                    }
+
+                    try storage.changed_ticks.ensureTotalCapacity(other_dense_len);
+                    storage.changed_ticks.items.len = other_dense_len;
+                    if (other_dense_len > 0) {
+                        @memcpy(storage.changed_ticks.items, other_storage.changed_ticks.items);
+                    }
                 }
             }
+
+            /// Describe the first broken storage invariant, or null when the state is consistent.
+            /// The message is formatted into `buffer`.
+            pub fn findViolation(self: *const FrameStateSelf, buffer: []u8) ?[]const u8 {
+                const live = self.active_entities.count();
+                if (live != self.entity_count) {
+                    return fmtViolation(buffer, "entity_count is {} but {} entities are active", .{ self.entity_count, live });
+                }
+                for (self.free_entities.items) |free| {
+                    if (free >= self.generations.items.len or self.active_entities.isSet(free)) {
+                        return fmtViolation(buffer, "free list holds entity {} which is alive or was never created", .{free});
+                    }
+                }
+
+                inline for (0..ComponentTypes.len) |i| {
+                    const storage = &self.components[i];
+                    const name = component_names[i];
+                    const dense_len = storage.dense.items.len;
+
+                    if (storage.dense_entities.items.len != dense_len) {
+                        return fmtViolation(buffer, "{s}: {} dense entries but {} index-to-entity entries", .{ name, dense_len, storage.dense_entities.items.len });
+                    }
+                    if (storage.changed_ticks.items.len != dense_len) {
+                        return fmtViolation(buffer, "{s}: {} dense entries but {} change ticks", .{ name, dense_len, storage.changed_ticks.items.len });
+                    }
+                    const held = storage.entity_bitset.count();
+                    if (held != dense_len) {
+                        return fmtViolation(buffer, "{s}: bitset holds {} entities but dense array has {} entries", .{ name, held, dense_len });
+                    }
+
+                    var iter = storage.entity_bitset.fastIterator();
+                    while (iter.next()) |entity| {
+                        if (!self.active_entities.isSet(entity)) {
+                            return fmtViolation(buffer, "{s}: held by dead entity {}", .{ name, entity });
+                        }
+                        const index = storage.indexOf(entity);
+                        if (index >= dense_len) {
+                            return fmtViolation(buffer, "{s}: entityToIndex[{}] = {} is out of range (dense length {})", .{ name, entity, index, dense_len });
+                        }
+                        const owner = storage.dense_entities.items[index];
+                        if (owner != entity) {
+                            return fmtViolation(buffer, "{s}: entityToIndex[{}] = {} but indexToEntity[{}] = {}", .{ name, entity, index, index, owner });
+                        }
+                    }
+
+                    for (storage.dense_entities.items, 0..) |owner, index| {
+                        if (!storage.entity_bitset.isSet(owner)) {
+                            return fmtViolation(buffer, "{s}: dense slot {} belongs to entity {} which is not in the bitset", .{ name, index, owner });
+                        }
+                    }
+                }
+
+                return null;
+            }
+
+            /// Panic with a precise description if any storage invariant is broken
+            pub fn checkInvariants(self: *const FrameStateSelf) void {
+                var buffer: [256]u8 = undefined;
+                if (self.findViolation(&buffer)) |message| {
+                    std.debug.panic("ECS invariant violated: {s}", .{message});
+                }
+            }
+
+            fn fmtViolation(buffer: []u8, comptime fmt: []const u8, args: anytype) []const u8 {
+                return std.fmt.bufPrint(buffer, fmt, args) catch buffer;
+            }
         };
 
-        fn generateQuery(comptime QueryTypes: []const type, comptime FrameStateType: type) type {
+        /// Queries borrow their result bitset instead of carrying one, so making or returning a
+        /// query never copies a whole entity set. `frame.query` intersects into the frame's scratch
+        /// set, which the frame's next `query` overwrites - a query nested inside another one's loop
+        /// goes through `queryInto` with a bitset of its own. Iterating never touches an allocator.
+        fn generateQuery(comptime filter: QueryFilter, comptime FrameStateType: type) type {
+            comptime {
+                for (filter.without) |T| {
+                    for (filter.with) |Required| {
+                        if (Required == T) @compileError("Component type '" ++ @typeName(T) ++ "' is both required and excluded by the query");
+                    }
+                }
+                // Unregistered optional types fail here rather than at their first `tryGet`
+                _ = componentMask(filter.optional);
+            }
+
             return struct {
                 const QuerySelf = @This();
 
-                result_entities: EntityBitSet,
-                iterator: EntityBitSet.Iterator,
-                fast_iterator: EntityBitSet.FastIterator,
+                result_entities: *const EntityBitSet,
+                /// Where `restrictTo` writes the narrowed results
+                scratch: *EntityBitSet,
+                /// `query_generation` of the frame when this query took its scratch set, null for
+                /// results the query doesn't share with later queries
+                scratch_generation: ?u32,
+                /// Cursor of `next` - the next entity index to test
+                next_index: u32,
+                /// Cursor of `nextFast` - the word being drained and its remaining bits
+                word_index: usize,
+                current_word: u64,
                 frame_state: *FrameStateType,
 
+                /// Bitmask of the components this query requires
+                pub const mask: u64 = componentMask(filter.with);
+                /// Bitmask of the components that exclude an entity
+                pub const exclude_mask: u64 = componentMask(filter.without);
+
+                /// Query over the frame's scratch set, valid until the frame's next `init`
                 pub fn init(frame_state: *FrameStateType) QuerySelf {
-                    var result_entities = frame_state.active_entities;
+                    frame_state.query_generation +%= 1;
+                    compute(frame_state, &frame_state.query_result);
+                    var query = fromResult(frame_state, &frame_state.query_result, &frame_state.query_result);
+                    query.scratch_generation = frame_state.query_generation;
+                    return query;
+                }
+
+                /// Query over `out`, a caller-owned bitset that lives as long as the query
+                pub fn initInto(frame_state: *FrameStateType, out: *EntityBitSet) QuerySelf {
+                    compute(frame_state, out);
+                    return fromResult(frame_state, out, out);
+                }
+
+                /// Intersect the filter's storages into `out`
+                fn compute(frame_state: *FrameStateType, out: *EntityBitSet) void {
+                    const start_time: u64 = if (frame_state.query_analyzer) |analyzer| analyzer.clock.now() else 0;
 
-                    inline for (QueryTypes) |T| {
+                    if (filter.with.len == 0) out.copyFrom(&frame_state.active_entities);
+                    inline for (filter.with, 0..) |T, i| {
+                        const storage_index = comptime getComponentIndex(T);
+                        const component_bitset = &frame_state.components[storage_index].entity_bitset;
+                        const source = if (i == 0) &frame_state.active_entities else out;
+                        source.intersectInto(component_bitset, out);
+                    }
+                    inline for (filter.without) |T| {
                         const storage_index = comptime getComponentIndex(T);
                         const component_bitset = &frame_state.components[storage_index].entity_bitset;
-                        result_entities = result_entities.intersectWith(component_bitset);
+                        out.subtractInto(component_bitset, out);
                     }
 
+                    if (frame_state.query_analyzer) |analyzer| {
+                        analyzer.record(mask, frame_state.entity_count, out.count(), analyzer.clock.since(start_time));
+                    }
+                }
+
+                /// Query over an already computed result set; `restrictTo` narrows into `scratch`
+                fn fromResult(frame_state: *FrameStateType, result_entities: *const EntityBitSet, scratch: *EntityBitSet) QuerySelf {
                     return QuerySelf{
                         .result_entities = result_entities,
-                        .iterator = result_entities.iterator(.{}),
-                        .fast_iterator = result_entities.fastIterator(),
+                        .scratch = scratch,
+                        .scratch_generation = null,
+                        .next_index = 0,
+                        .word_index = 0,
+                        .current_word = result_entities.words[0],
                         .frame_state = frame_state,
                     };
                 }
 
                 pub fn next(self: *QuerySelf) ?generateQueryResult(FrameStateType) {
-                    if (self.iterator.next()) |entity| {
-                        return generateQueryResult(FrameStateType){
-                            .frame_state = self.frame_state,
-                            .entity = entity,
-                        };
-                    }
-                    return null;
+                    self.assertResultsCurrent();
+                    const entity = self.result_entities.nextSet(self.next_index) orelse {
+                        self.next_index = MAX_ENTITIES;
+                        return null;
+                    };
+                    self.next_index = entity + 1;
+                    return generateQueryResult(FrameStateType){
+                        .frame_state = self.frame_state,
+                        .entity = entity,
+                    };
                 }
 
                 pub fn nextFast(self: *QuerySelf) ?generateQueryResult(FrameStateType) {
-                    if (self.fast_iterator.next()) |entity| {
-                        return generateQueryResult(FrameStateType){
-                            .frame_state = self.frame_state,
-                            .entity = entity,
-                        };
+                    if (!self.advance()) return null;
+                    const bit_index = @ctz(self.current_word);
+                    self.current_word &= self.current_word - 1;
+                    return generateQueryResult(FrameStateType){
+                        .frame_state = self.frame_state,
+                        .entity = @intCast(self.word_index * 64 + bit_index),
+                    };
+                }
+
+                /// Remaining matches of the next non-empty word, for systems that walk bits themselves.
+                /// Shares the `nextFast` cursor.
+                pub fn nextWord(self: *QuerySelf) ?EntityWord {
+                    if (!self.advance()) return null;
+                    const word = EntityWord{ .base = @intCast(self.word_index * 64), .bits = self.current_word };
+                    self.current_word = 0;
+                    return word;
+                }
+
+                /// Next run of consecutive matching entities, merged across word boundaries. A run is
+                /// a range of entity ids, for bitset or entity-indexed work; dense component data still
+                /// goes through `indexOf`/`getDirect`. Shares the `nextFast` cursor.
+                pub fn nextRun(self: *QuerySelf) ?EntityRun {
+                    if (!self.advance()) return null;
+                    const start = @ctz(self.current_word);
+                    var run = EntityRun{ .first = @intCast(self.word_index * 64 + start), .len = 0 };
+
+                    while (true) {
+                        const offset = @ctz(self.current_word);
+                        const ones: u32 = @ctz(~(self.current_word >> @intCast(offset)));
+                        run.len += ones;
+                        if (offset + ones < 64) {
+                            // Ends inside this word - drop the run's bits and stop
+                            self.current_word &= ~(((@as(u64, 1) << @intCast(ones)) - 1) << @intCast(offset));
+                            return run;
+                        }
+                        // Reaches bit 63 - continue only if the next word starts with a match
+                        self.current_word = 0;
+                        if (self.word_index + 1 >= EntityBitSet.word_count) return run;
+                        const following = self.result_entities.words[self.word_index + 1];
+                        if (following & 1 == 0) return run;
+                        self.word_index += 1;
+                        self.current_word = following;
                     }
-                    return null;
+                }
+
+                /// Up to `buffer.len` matching entities in ascending order, written into `buffer`;
+                /// empty at the end. Shares the `nextFast` cursor. Hands large worlds to systems a
+                /// slice at a time, so the cursor work is paid per chunk rather than per entity.
+                pub fn nextChunk(self: *QuerySelf, buffer: []EntityID) []EntityID {
+                    var len: usize = 0;
+                    while (len < buffer.len and self.advance()) {
+                        const base: EntityID = @intCast(self.word_index * 64);
+                        while (self.current_word != 0 and len < buffer.len) {
+                            buffer[len] = base + @ctz(self.current_word);
+                            self.current_word &= self.current_word - 1;
+                            len += 1;
+                        }
+                    }
+                    return buffer[0..len];
+                }
+
+                /// Every match written into `buffer` and sorted by `lessThan(context, a, b)`, for
+                /// systems whose outcome depends on visiting order (draw order, turn order, who
+                /// claims a contested pickup first). The sort is stable over ascending entity ids,
+                /// so entities `lessThan` calls equal keep id order and every peer and replay gets
+                /// the same sequence. Leaves the iteration cursors alone.
+                pub fn sortBy(
+                    self: *const QuerySelf,
+                    buffer: []EntityID,
+                    context: anytype,
+                    comptime lessThan: fn (@TypeOf(context), EntityID, EntityID) bool,
+                ) ![]EntityID {
+                    self.assertResultsCurrent();
+                    if (self.result_entities.count() > buffer.len) return error.BufferTooSmall;
+                    var len: usize = 0;
+                    var matches = self.result_entities.fastIterator();
+                    while (matches.next()) |entity| {
+                        buffer[len] = entity;
+                        len += 1;
+                    }
+                    std.sort.block(EntityID, buffer[0..len], context, lessThan);
+                    return buffer[0..len];
+                }
+
+                /// `sortBy` ascending on one field of a required component - `sortByField(&buffer,
+                /// Sprite, "layer")`. Integers, bools, enums and types with a `compare` returning
+                /// `std.math.Order` (like `FP`) make keys.
+                pub fn sortByField(self: *const QuerySelf, buffer: []EntityID, comptime T: type, comptime field_name: []const u8) ![]EntityID {
+                    comptime {
+                        for (filter.with) |Required| {
+                            if (Required == T) break;
+                        } else @compileError("sortByField needs '" ++ @typeName(T) ++ "' among the query's required components");
+                    }
+                    const Key = @FieldType(T, field_name);
+                    const ByField = struct {
+                        storage: *const ComponentStorageTypes[getComponentIndex(T)],
+
+                        fn lessThan(by: @This(), a: EntityID, b: EntityID) bool {
+                            return keyLessThan(Key, @field(by.storage.getDirectConst(a).*, field_name), @field(by.storage.getDirectConst(b).*, field_name));
+                        }
+                    };
+                    const by = ByField{ .storage = &self.frame_state.components[comptime getComponentIndex(T)] };
+                    return self.sortBy(buffer, by, ByField.lessThan);
+                }
+
+                // Load the next non-empty result word once the current one is drained (skipping
+                // empty stretches through the summary level of large bitsets); false at the end
+                inline fn advance(self: *QuerySelf) bool {
+                    if (self.current_word != 0) return true;
+                    self.assertResultsCurrent();
+                    const word_index = self.result_entities.nextNonEmpty(self.word_index + 1) orelse {
+                        self.word_index = EntityBitSet.word_count;
+                        return false;
+                    };
+                    self.word_index = word_index;
+                    self.current_word = self.result_entities.words[word_index];
+                    return true;
                 }
 
                 pub fn count(self: *QuerySelf) u32 {
+                    self.assertResultsCurrent();
                     return self.result_entities.count();
                 }
 
+                // The frame's scratch set is shared - nesting `frame.query` calls overwrites the
+                // outer query's results (use `queryInto` for the inner one)
+                inline fn assertResultsCurrent(self: *const QuerySelf) void {
+                    if (self.scratch_generation) |generation| {
+                        std.debug.assert(generation == self.frame_state.query_generation);
+                    }
+                }
+
                 pub fn reset(self: *QuerySelf) void {
-                    self.iterator = self.result_entities.iterator(.{});
-                    self.fast_iterator = self.result_entities.fastIterator();
+                    self.next_index = 0;
+                    self.word_index = 0;
+                    self.current_word = self.result_entities.words[0];
+                }
+
+                /// Narrow the results to entities also in `filter` (e.g. a spatial index lookup)
+                pub fn restrictTo(self: *QuerySelf, filter: *const EntityBitSet) void {
+                    self.assertResultsCurrent();
+                    self.result_entities.intersectInto(filter, self.scratch);
+                    if (self.result_entities != self.scratch) {
+                        // A cached query's results move into the frame's scratch set, which the
+                        // frame's next query overwrites
+                        self.frame_state.query_generation +%= 1;
+                        self.scratch_generation = self.frame_state.query_generation;
+                        self.result_entities = self.scratch;
+                    }
+                    self.reset();
+                }
+
+                /// Narrow the results to entities whose `T` changed after tick `since` - e.g. re-sync
+                /// only the physics bodies of entities that moved since the last sync
+                pub fn changedSince(self: *QuerySelf, comptime T: type, since: u64) void {
+                    const changed = &self.frame_state.query_temp;
+                    self.frame_state.getComponentStorage(T).changedSince(since, changed);
+                    self.restrictTo(changed);
                 }
 
                 pub const Iterator = struct {
@@ -528,6 +1829,48 @@ pub fn ECS(
             };
         }
 
+        /// Query whose result set is kept between calls and recomputed only when the membership
+        /// version of the active entities or of one of its storages moved. Frames where entities
+        /// only change data - the common case - pay a version compare instead of the intersection,
+        /// and the returned query borrows the kept set. Keep one per system, e.g. as a field of
+        /// the system's state, and don't let it move while one of its queries is in use.
+        pub fn CachedQuery(comptime QueryTypes: []const type) type {
+            return struct {
+                const CacheSelf = @This();
+
+                pub const Query = generateQuery(.{ .with = QueryTypes }, FrameState);
+
+                result_entities: EntityBitSet = EntityBitSet.initEmpty(),
+                /// Entity version, then one storage version per query type, as of the last rebuild
+                versions: [QueryTypes.len + 1]u64 = undefined,
+                valid: bool = false,
+                /// How many times the result set was recomputed
+                rebuilds: u64 = 0,
+
+                pub fn query(self: *CacheSelf, frame: *Frame) Query {
+                    const state = &frame.state;
+                    var current: [QueryTypes.len + 1]u64 = undefined;
+                    current[0] = state.entity_version;
+                    inline for (QueryTypes, 1..) |T, i| {
+                        current[i] = state.getComponentStorage(T).version;
+                    }
+
+                    if (!self.valid or !std.mem.eql(u64, &current, &self.versions)) {
+                        Query.compute(state, &self.result_entities);
+                        self.versions = current;
+                        self.valid = true;
+                        self.rebuilds += 1;
+                    }
+                    return Query.fromResult(state, &self.result_entities, &state.query_result);
+                }
+
+                /// Force the next `query` to recompute
+                pub fn invalidate(self: *CacheSelf) void {
+                    self.valid = false;
+                }
+            };
+        }
+
         fn generateQueryResult(comptime FrameStateType: type) type {
             return struct {
                 frame_state: *FrameStateType,
@@ -536,6 +1879,16 @@ pub fn ECS(
                 pub fn get(self: @This(), comptime T: type) *T {
                     return self.frame_state.getComponent(self.entity, T).?;
                 }
+
+                /// `get` for writing: marks the component changed this tick
+                pub fn getMut(self: @This(), comptime T: type) *T {
+                    return self.frame_state.getComponentMut(self.entity, T).?;
+                }
+
+                /// A component the query doesn't require - e.g. one of its `optional` types
+                pub fn tryGet(self: @This(), comptime T: type) ?*T {
+                    return self.frame_state.getComponent(self.entity, T);
+                }
             };
         }
 
@@ -548,44 +1901,155 @@ pub fn ECS(
 
             const FrameSelf = @This();
 
-            pub fn createEntity(self: *FrameSelf) !EntityID {
+            pub inline fn createEntity(self: *FrameSelf) !EntityID {
                 return self.state.createEntity();
             }
 
-            pub fn destroyEntity(self: *FrameSelf, entity: EntityID) void {
+            pub inline fn destroyEntity(self: *FrameSelf, entity: EntityID) void {
                 self.state.destroyEntity(entity);
             }
 
-            pub fn addComponent(self: *FrameSelf, entity: EntityID, component: anytype) !void {
+            pub inline fn addComponent(self: *FrameSelf, entity: EntityID, component: anytype) !void {
                 return self.state.addComponent(entity, component);
             }
 
+            pub fn handle(self: *const FrameSelf, entity: EntityID) EntityHandle {
+                return self.state.handle(entity);
+            }
+
+            pub fn resolve(self: *const FrameSelf, entity_handle: EntityHandle) ?EntityID {
+                return self.state.resolve(entity_handle);
+            }
+
             pub fn getComponent(self: *FrameSelf, entity: EntityID, comptime T: type) ?*T {
                 return self.state.getComponent(entity, T);
             }
 
+            pub fn getComponentMut(self: *FrameSelf, entity: EntityID, comptime T: type) ?*T {
+                return self.state.getComponentMut(entity, T);
+            }
+
+            pub fn markChanged(self: *FrameSelf, entity: EntityID, comptime T: type) void {
+                self.state.markChanged(entity, T);
+            }
+
             pub fn hasComponent(self: *FrameSelf, entity: EntityID, comptime T: type) bool {
                 return self.state.hasComponent(entity, T);
             }
 
-            pub fn removeComponent(self: *FrameSelf, entity: EntityID, comptime T: type) bool {
+            pub inline fn removeComponent(self: *FrameSelf, entity: EntityID, comptime T: type) bool {
                 return self.state.removeComponent(entity, T);
             }
 
-            pub fn query(self: *FrameSelf, comptime QueryTypes: []const type) !generateQuery(QueryTypes, FrameState) {
+            /// Component access through handles - stale handles read as missing (see `resolve`)
+            pub fn getComponentByHandle(self: *FrameSelf, entity_handle: EntityHandle, comptime T: type) ?*T {
+                return self.state.getComponentByHandle(entity_handle, T);
+            }
+
+            pub fn getComponentMutByHandle(self: *FrameSelf, entity_handle: EntityHandle, comptime T: type) ?*T {
+                return self.state.getComponentMutByHandle(entity_handle, T);
+            }
+
+            pub fn hasComponentByHandle(self: *FrameSelf, entity_handle: EntityHandle, comptime T: type) bool {
+                return self.state.hasComponentByHandle(entity_handle, T);
+            }
+
+            pub inline fn removeComponentByHandle(self: *FrameSelf, entity_handle: EntityHandle, comptime T: type) bool {
+                return self.state.removeComponentByHandle(entity_handle, T);
+            }
+
+            pub fn relate(self: *FrameSelf, source: EntityID, comptime Kind: type, target: EntityID) !void {
+                return self.state.relate(source, Kind, target);
+            }
+
+            pub fn unrelate(self: *FrameSelf, source: EntityID, comptime Kind: type) bool {
+                return self.state.unrelate(source, Kind);
+            }
+
+            pub fn relationTarget(self: *FrameSelf, source: EntityID, comptime Kind: type) ?EntityID {
+                return self.state.relationTarget(source, Kind);
+            }
+
+            pub fn relatedTo(self: *const FrameSelf, comptime Kind: type, target: EntityID) RelatedIterator(Kind) {
+                return self.state.relatedTo(Kind, target);
+            }
+
+            pub fn query(self: *FrameSelf, comptime QueryTypes: []const type) !Query(QueryTypes) {
                 return self.state.query(QueryTypes);
             }
 
+            pub fn queryFiltered(self: *FrameSelf, comptime filter: QueryFilter) !FilteredQuery(filter) {
+                return self.state.queryFiltered(filter);
+            }
+
+            pub fn queryInto(self: *FrameSelf, comptime QueryTypes: []const type, out: *EntityBitSet) !Query(QueryTypes) {
+                return self.state.queryInto(QueryTypes, out);
+            }
+
+            pub fn queryFilteredInto(self: *FrameSelf, comptime filter: QueryFilter, out: *EntityBitSet) !FilteredQuery(filter) {
+                return self.state.queryFilteredInto(filter, out);
+            }
+
             pub fn getEntityCount(self: *const FrameSelf) u32 {
                 return self.state.getEntityCount();
             }
 
+            pub fn checksum(self: *const FrameSelf) u64 {
+                return self.state.checksum();
+            }
+
+            pub fn checksums(self: *const FrameSelf) Checksums {
+                return self.state.checksums();
+            }
+
             pub inline fn getComponentStorage(self: *FrameSelf, comptime T: type) *ComponentStorageTypes[getComponentIndex(T)] {
                 return self.state.getComponentStorage(T);
             }
+
+            /// The frame's value of resource `T` - write through the pointer or use `setResource`
+            pub inline fn getResource(self: *FrameSelf, comptime T: type) *T {
+                return self.state.getResource(T);
+            }
+
+            pub inline fn setResource(self: *FrameSelf, value: anytype) void {
+                self.state.getResource(@TypeOf(value)).* = value;
+            }
+        };
+
+        const block_alignment = blk: {
+            var alignment: usize = @max(@alignOf(EntityID), @alignOf(u64));
+            for (ComponentTypes) |T| alignment = @max(alignment, @alignOf(T));
+            break :blk alignment;
+        };
+
+        /// Bytes of one frame's dense arrays at full capacity - the unit `initPreallocated` carves
+        pub const frame_block_bytes: usize = blk: {
+            var offset: usize = 0;
+            for (ComponentTypes) |T| {
+                offset = std.mem.alignForward(usize, offset, @alignOf(T)) + MAX_ENTITIES * @sizeOf(T);
+                offset = std.mem.alignForward(usize, offset, @alignOf(EntityID)) + MAX_ENTITIES * @sizeOf(EntityID);
+                offset = std.mem.alignForward(usize, offset, @alignOf(u64)) + MAX_ENTITIES * @sizeOf(u64);
+                // Paged worlds carve every index page too
+                if (paged_index) offset = std.mem.alignForward(usize, offset, @alignOf(IndexPage)) + index_page_count * @sizeOf(IndexPage);
+            }
+            // Entity generations, the free list and the relation indexes
+            offset = std.mem.alignForward(usize, offset, @alignOf(u32)) + MAX_ENTITIES * @sizeOf(u32);
+            offset = std.mem.alignForward(usize, offset, @alignOf(EntityID)) + MAX_ENTITIES * @sizeOf(EntityID);
+            for (ComponentTypes) |T| {
+                if (isRelation(T)) offset = std.mem.alignForward(usize, offset, @alignOf(RelationLink)) + MAX_ENTITIES * @sizeOf(RelationLink);
+            }
+            break :blk std.mem.alignForward(usize, offset, block_alignment);
         };
 
         current_frame: Frame,
+        /// Backing memory of a preallocated world: the live frame's dense arrays, then each snapshot's
+        block: []align(block_alignment) u8 = &.{},
+        block_allocator: ?std.mem.Allocator = null,
+        snapshots: []Frame = &.{},
+        /// Closed at every `update` - see `setMemoryBudget`
+        memory_budget: ?*MemoryBudget = null,
+        /// Strings behind the `Name` components - see `setName`
+        names: NameTable = .{},
 
         pub fn init(allocator: std.mem.Allocator) !Self {
             var frame_state = FrameState{
@@ -613,21 +2077,245 @@ pub fn ECS(
             };
         }
 
+        /// Like `init`, but every dense array - the live frame's and those of `snapshot_slots`
+        /// snapshot frames - is carved at full capacity out of one block. Nothing allocates after
+        /// this returns: adding components, snapshots and `reset` only move lengths and bits.
+        /// Costs `frame_block_bytes * (1 + snapshot_slots)` up front whatever the entity count.
+        pub fn initPreallocated(allocator: std.mem.Allocator, snapshot_slots: usize) !Self {
+            return initCarved(allocator, snapshot_slots, carved_allocator);
+        }
+
+        /// `initPreallocated` for latency-critical servers: a storage asked to grow past capacity
+        /// panics (in safe builds) instead of failing the call. Allocate it and the rest of the
+        /// startup state from a `StrictAllocator` locked before the first tick to cover the history
+        /// ring, pools and other systems too.
+        pub fn initStrict(allocator: std.mem.Allocator, snapshot_slots: usize) !Self {
+            return initCarved(allocator, snapshot_slots, strict_carved_allocator);
+        }
+
+        fn initCarved(allocator: std.mem.Allocator, snapshot_slots: usize, storage_allocator: std.mem.Allocator) !Self {
+            const block = try allocator.alignedAlloc(u8, block_alignment, frame_block_bytes * (1 + snapshot_slots));
+            errdefer allocator.free(block);
+            const snapshots = try allocator.alloc(Frame, snapshot_slots);
+
+            for (snapshots, 1..) |*snapshot, slot| {
+                snapshot.* = carvedFrame(@alignCast(block[frame_block_bytes * slot ..][0..frame_block_bytes]), storage_allocator);
+            }
+            return Self{
+                .current_frame = carvedFrame(block[0..frame_block_bytes], storage_allocator),
+                .block = block,
+                .block_allocator = allocator,
+                .snapshots = snapshots,
+            };
+        }
+
+        /// Empty frame whose dense arrays live in `block` at full capacity
+        fn carvedFrame(block: []align(block_alignment) u8, storage_allocator: std.mem.Allocator) Frame {
+            var frame = Frame{
+                .state = FrameState{
+                    .components = undefined,
+                    .active_entities = EntityBitSet.initEmpty(),
+                    .next_entity = 0,
+                    .entity_count = 0,
+                    .allocator = storage_allocator,
+                    .query_result = EntityBitSet.initEmpty(),
+                    .query_temp = EntityBitSet.initEmpty(),
+                },
+                .input = std.mem.zeroes(InputType),
+                .deltaTime = 0.0,
+                .time = 0.0,
+                .frame_number = 0,
+            };
+
+            var offset: usize = 0;
+            inline for (ComponentTypes, 0..) |T, i| {
+                var storage = ComponentStorageTypes[i].init(storage_allocator);
+                // Zero-sized components never allocate - ArrayList reports unlimited capacity
+                if (@sizeOf(T) > 0) {
+                    offset = std.mem.alignForward(usize, offset, @alignOf(T));
+                    const dense: [*]T = @ptrCast(@alignCast(block[offset..].ptr));
+                    storage.dense.items = dense[0..0];
+                    storage.dense.capacity = MAX_ENTITIES;
+                }
+                offset += MAX_ENTITIES * @sizeOf(T);
+                offset = std.mem.alignForward(usize, offset, @alignOf(EntityID));
+                const entities: [*]EntityID = @ptrCast(@alignCast(block[offset..].ptr));
+                storage.dense_entities.items = entities[0..0];
+                storage.dense_entities.capacity = MAX_ENTITIES;
+                offset += MAX_ENTITIES * @sizeOf(EntityID);
+                offset = std.mem.alignForward(usize, offset, @alignOf(u64));
+                const changed_ticks: [*]u64 = @ptrCast(@alignCast(block[offset..].ptr));
+                storage.changed_ticks.items = changed_ticks[0..0];
+                storage.changed_ticks.capacity = MAX_ENTITIES;
+                offset += MAX_ENTITIES * @sizeOf(u64);
+                if (paged_index) {
+                    offset = std.mem.alignForward(usize, offset, @alignOf(IndexPage));
+                    const pages: [*]IndexPage = @ptrCast(@alignCast(block[offset..].ptr));
+                    for (&storage.entity_to_index, 0..) |*page, p| page.* = &pages[p];
+                    offset += index_page_count * @sizeOf(IndexPage);
+                }
+                frame.state.components[i] = storage;
+            }
+            offset = std.mem.alignForward(usize, offset, @alignOf(u32));
+            const generations: [*]u32 = @ptrCast(@alignCast(block[offset..].ptr));
+            frame.state.generations = .{ .items = generations[0..0], .capacity = MAX_ENTITIES };
+            offset += MAX_ENTITIES * @sizeOf(u32);
+            offset = std.mem.alignForward(usize, offset, @alignOf(EntityID));
+            const free_entities: [*]EntityID = @ptrCast(@alignCast(block[offset..].ptr));
+            frame.state.free_entities = .{ .items = free_entities[0..0], .capacity = MAX_ENTITIES };
+            offset += MAX_ENTITIES * @sizeOf(EntityID);
+            inline for (ComponentTypes, 0..) |T, i| {
+                if (comptime isRelation(T)) {
+                    offset = std.mem.alignForward(usize, offset, @alignOf(RelationLink));
+                    const links: [*]RelationLink = @ptrCast(@alignCast(block[offset..].ptr));
+                    frame.state.relation_links[i] = .{ .items = links[0..0], .capacity = MAX_ENTITIES };
+                    offset += MAX_ENTITIES * @sizeOf(RelationLink);
+                }
+            }
+            return frame;
+        }
+
         pub fn deinit(self: *Self) void {
             inline for (0..ComponentTypes.len) |i| {
                 self.current_frame.state.components[i].deinit();
             }
+            self.current_frame.state.generations.deinit(self.current_frame.state.allocator);
+            self.current_frame.state.free_entities.deinit(self.current_frame.state.allocator);
+            for (&self.current_frame.state.relation_links) |*links| links.deinit(self.current_frame.state.allocator);
+            self.names.deinit(self.namesAllocator());
+            if (self.block_allocator) |allocator| {
+                allocator.free(self.snapshots);
+                allocator.free(self.block);
+            }
+        }
+
+        /// Empty the world - entities, components, resources, input and the tick counter - keeping
+        /// every buffer's capacity. Observers, the query analyzer and snapshots are left alone.
+        pub fn reset(self: *Self) void {
+            const state = &self.current_frame.state;
+            state.active_entities = EntityBitSet.initEmpty();
+            state.next_entity = 0;
+            state.entity_count = 0;
+            state.entity_version = 0;
+            // Handles from before the reset must not resolve to the entities created after it
+            for (state.generations.items) |*generation| generation.* +%= 1;
+            state.free_entities.clearRetainingCapacity();
+            for (&state.relation_links) |*links| @memset(links.items, .{});
+            state.resources = std.mem.zeroes(Resources);
+            inline for (0..ComponentTypes.len) |i| {
+                state.components[i].dense.clearRetainingCapacity();
+                state.components[i].dense_entities.clearRetainingCapacity();
+                state.components[i].changed_ticks.clearRetainingCapacity();
+                state.components[i].entity_bitset = EntityBitSet.initEmpty();
+                state.components[i].version = 0;
+                state.components[i].changed = EntityBitSet.initEmpty();
+                state.components[i].tick = 0;
+                state.components[i].last_changed = 0;
+            }
+            self.current_frame.input = std.mem.zeroes(InputType);
+            self.current_frame.deltaTime = 0.0;
+            self.current_frame.time = 0.0;
+            self.current_frame.frame_number = 0;
+        }
+
+        /// Copy the live frame into a snapshot slot of a preallocated world
+        pub fn saveSnapshot(self: *Self, slot: usize) !void {
+            if (slot >= self.snapshots.len) return error.InvalidSnapshotSlot;
+            try self.copyFrameTo(&self.snapshots[slot]);
+        }
+
+        pub fn restoreSnapshot(self: *Self, slot: usize) !void {
+            if (slot >= self.snapshots.len) return error.InvalidSnapshotSlot;
+            try self.restoreFrame(&self.snapshots[slot]);
         }
 
         pub fn getFrame(self: *Self) *Frame {
             return &self.current_frame;
         }
 
+        /// Resource `T` of the live frame
+        pub fn getResource(self: *Self, comptime T: type) *T {
+            return self.current_frame.getResource(T);
+        }
+
+        pub fn setResource(self: *Self, value: anytype) void {
+            self.current_frame.setResource(value);
+        }
+
+        /// Label `entity` with `name`, replacing any name it had. Needs `Name` registered as a
+        /// component; the string is interned once per world however many entities share it.
+        pub fn setName(self: *Self, entity: EntityID, name: []const u8) !void {
+            const id = try self.names.intern(self.namesAllocator(), name);
+            if (self.current_frame.getComponentMut(entity, Name)) |label| {
+                label.id = id;
+            } else {
+                try self.current_frame.addComponent(entity, Name{ .id = id });
+            }
+        }
+
+        /// Name of `entity`, null if it has none
+        pub fn nameOf(self: *Self, entity: EntityID) ?[]const u8 {
+            const label = self.current_frame.getComponent(entity, Name) orelse return null;
+            return self.names.get(label.id);
+        }
+
+        /// Live entity called `name` - the lowest id if several are
+        pub fn findByName(self: *Self, name: []const u8) ?EntityID {
+            const id = self.names.lookup(name) orelse return null;
+            const storage = self.current_frame.getComponentStorage(Name);
+            var holders = storage.entity_bitset.fastIterator();
+            while (holders.next()) |entity| {
+                if (storage.getDirectConst(entity).id == id) return entity;
+            }
+            return null;
+        }
+
+        // Preallocated worlds carve their storages from a fixed allocator; the table grows from
+        // the one the block came from
+        fn namesAllocator(self: *const Self) std.mem.Allocator {
+            return self.block_allocator orelse self.current_frame.state.allocator;
+        }
+
+        /// Attach a debugging observer to the live frame state
+        pub fn addObserver(self: *Self, observer: Observer) !void {
+            self.current_frame.state.observers.append(observer) catch return error.TooManyObservers;
+        }
+
+        /// Detach a previously attached observer (matched by its context pointer)
+        pub fn removeObserver(self: *Self, ptr: *anyopaque) void {
+            const observers = &self.current_frame.state.observers;
+            for (observers.constSlice(), 0..) |observer, i| {
+                if (observer.ptr == ptr) {
+                    _ = observers.orderedRemove(i);
+                    return;
+                }
+            }
+        }
+
+        /// Attach (or detach with null) a per-frame query analyzer
+        pub fn setQueryAnalyzer(self: *Self, analyzer: ?*QueryAnalyzer) void {
+            self.current_frame.state.query_analyzer = analyzer;
+            if (analyzer) |a| a.beginFrame(self.current_frame.frame_number);
+        }
+
+        /// Attach (or detach with null) a memory budget. Each `update` ends a budget frame,
+        /// running its policies while the world and its tracked buffers are over budget.
+        pub fn setMemoryBudget(self: *Self, budget: ?*MemoryBudget) void {
+            self.memory_budget = budget;
+        }
+
         pub fn update(self: *Self, input: InputType, deltaTime: f32, time: f64) void {
             self.current_frame.input = input;
             self.current_frame.deltaTime = deltaTime;
             self.current_frame.time = time;
             self.current_frame.frame_number += 1;
+            self.current_frame.state.beginTick(self.current_frame.frame_number);
+            if (config.debug_checks) self.current_frame.state.checkInvariants();
+            self.current_frame.state.notifyTick(self.current_frame.frame_number);
+            if (self.current_frame.state.query_analyzer) |analyzer| {
+                analyzer.beginFrame(self.current_frame.frame_number);
+            }
+            if (self.memory_budget) |budget| budget.endFrame();
         }
 
         // Calculate the exact size needed for frame data (only used components)
@@ -643,11 +2331,16 @@ pub fn ECS(
             // Component data (only actual used data)
             inline for (0..ComponentTypes.len) |i| {
                 const component_count = self.current_frame.state.components[i].dense.items.len;
-                size += component_count * @sizeOf(ComponentTypes[i]);
+                size += component_count * (@sizeOf(ComponentTypes[i]) + @sizeOf(EntityID) + @sizeOf(u64));
             }
             
             // Entity to component index mappings (fixed size)
             size += @sizeOf([MAX_ENTITIES]u32) * ComponentTypes.len;
+
+            // Entity generations, the free list and the relation indexes
+            size += self.current_frame.state.generations.items.len * @sizeOf(u32);
+            size += self.current_frame.state.free_entities.items.len * @sizeOf(EntityID);
+            for (&self.current_frame.state.relation_links) |*links| size += links.items.len * @sizeOf(RelationLink);
             
             return size;
         }
@@ -691,6 +2384,9 @@ pub fn ECS(
             inline for (0..ComponentTypes.len) |i| {
                 saved_frame.state.components[i].deinit();
             }
+            saved_frame.state.generations.deinit(saved_frame.state.allocator);
+            saved_frame.state.free_entities.deinit(saved_frame.state.allocator);
+            for (&saved_frame.state.relation_links) |*links| links.deinit(saved_frame.state.allocator);
         }
 
         // Efficient frame copying - copy into pre-allocated frame without new allocations
@@ -732,6 +2428,9 @@ pub fn ECS(
             inline for (0..ComponentTypes.len) |i| {
                 frame.state.components[i].deinit();
             }
+            frame.state.generations.deinit(frame.state.allocator);
+            frame.state.free_entities.deinit(frame.state.allocator);
+            for (&frame.state.relation_links) |*links| links.deinit(frame.state.allocator);
         }
     };
 }
\ No newline at end of file
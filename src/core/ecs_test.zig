@@ -58,7 +58,7 @@ test "Entity creation and destruction" {
 
     // Create new entity - should reuse ID
     const e4 = try frame.createEntity();
-    try testing.expectEqual(@as(u32, 3), e4); // Next available ID
+    try testing.expectEqual(e2, e4);
     try testing.expectEqual(@as(u32, 3), frame.getEntityCount());
 }
 
@@ -80,10 +80,6 @@ test "Entity limit enforcement" {
 }
 
 test "Entity ID recycling after destruction" {
-    // This test documents a current limitation - entity IDs are not recycled
-    // TODO: Implement entity ID recycling to handle long-running games
-    if (true) return error.SkipZigTest; // Skip until implemented
-    
     var test_ecs = try TinyECS.init(testing.allocator);
     defer test_ecs.deinit();
 
@@ -773,6 +769,878 @@ test "Complete game example with systems and rollback" {
     try testing.expectEqual(y_before, pos_restored.y);
 }
 
+const CheckedECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity },
+    .input = TestInput,
+    .max_entities = .tiny,
+    .debug_checks = true,
+});
+
+test "Debug checks accept consistent state" {
+    var test_ecs = try CheckedECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    var entities: [8]ecs.EntityID = undefined;
+    for (&entities, 0..) |*entity, i| {
+        entity.* = try frame.createEntity();
+        try frame.addComponent(entity.*, Position{ .x = @floatFromInt(i), .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity.*, Velocity{ .x = 1, .y = 0 });
+    }
+    _ = frame.removeComponent(entities[0], Position);
+    frame.destroyEntity(entities[3]);
+
+    // Panics on violation
+    test_ecs.update(.{}, 0.016, 0.016);
+
+    // Swap-remove keeps the index-to-entity mapping in step
+    const storage = frame.getComponentStorage(Position);
+    for (storage.getDenseEntities(), 0..) |owner, index| {
+        try testing.expectEqual(@as(u32, @intCast(index)), storage.getEntityToIndexArray()[owner]);
+    }
+}
+
+test "Debug checks describe the first violation" {
+    var test_ecs = try CheckedECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    const e0 = try frame.createEntity();
+    const e1 = try frame.createEntity();
+    try frame.addComponent(e0, Position{ .x = 0, .y = 0 });
+    try frame.addComponent(e1, Position{ .x = 1, .y = 1 });
+
+    var buffer: [256]u8 = undefined;
+    try testing.expect(frame.state.findViolation(&buffer) == null);
+
+    // Corrupt entityToIndex
+    frame.getComponentStorage(Position).getEntityToIndexArray()[e1] = 0;
+    try testing.expectEqualStrings(
+        "Position: entityToIndex[1] = 0 but indexToEntity[0] = 0",
+        frame.state.findViolation(&buffer).?,
+    );
+    frame.getComponentStorage(Position).getEntityToIndexArray()[e1] = 1;
+
+    // Component left behind on a dead entity
+    frame.state.active_entities.unset(e1);
+    frame.state.entity_count -= 1;
+    try testing.expectEqualStrings("Position: held by dead entity 1", frame.state.findViolation(&buffer).?);
+}
+
+test "Preallocated worlds do not allocate after init" {
+    // Block and snapshot slots are the only allocations - everything after them must fail
+    var failing = std.testing.FailingAllocator.init(testing.allocator, .{ .fail_index = 2 });
+    var test_ecs = try StandardECS.initPreallocated(failing.allocator(), 2);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..StandardECS.max_entities) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = @floatFromInt(i), .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity, Health{ .value = 10, .max = 10 });
+    }
+    try test_ecs.saveSnapshot(0);
+    frame.destroyEntity(7);
+    frame.getComponent(8, Health).?.value = 1;
+    try test_ecs.restoreSnapshot(0);
+    try testing.expect(frame.hasComponent(7, Position));
+    try testing.expectEqual(@as(i32, 10), frame.getComponent(8, Health).?.value);
+    try testing.expectError(error.InvalidSnapshotSlot, test_ecs.saveSnapshot(2));
+
+    test_ecs.update(.{}, 0.016, 0.016);
+    test_ecs.reset();
+    try testing.expectEqual(@as(u32, 0), frame.getEntityCount());
+    try testing.expectEqual(@as(u64, 0), frame.frame_number);
+    try testing.expect(!frame.hasComponent(8, Health));
+    try testing.expectEqual(@as(u32, 0), try frame.createEntity());
+
+    try testing.expectEqual(@as(usize, 2), failing.allocations);
+}
+
+fn churnTick(test_ecs: *StandardECS) !u32 {
+    test_ecs.update(.{}, 0.016, 0.016);
+    const frame = test_ecs.getFrame();
+    var visited: u32 = 0;
+    var damaged_entities = StandardECS.EntityBitSet.initEmpty();
+
+    var movers = try frame.query(&.{ Position, Velocity });
+    while (movers.nextFast()) |result| {
+        const velocity = result.get(Velocity);
+        result.get(Position).x += velocity.x;
+        visited += 1;
+
+        // Nested query over a different component set, in a bitset of its own
+        var damaged = try frame.queryInto(&.{Health}, &damaged_entities);
+        while (damaged.next()) |inner| {
+            if (inner.entity == result.entity) inner.get(Health).value -= 1;
+        }
+    }
+
+    // Structural churn that stays within capacity
+    var healthy = try frame.query(&.{Health});
+    while (healthy.nextFast()) |result| {
+        if (result.get(Health).value <= 0) {
+            _ = frame.removeComponent(result.entity, Health);
+            try frame.addComponent(result.entity, Health{ .value = 3, .max = 3 });
+        }
+    }
+    return visited;
+}
+
+test "Steady-state ticks do not allocate" {
+    var counting = std.testing.FailingAllocator.init(testing.allocator, .{});
+    var test_ecs = try StandardECS.init(counting.allocator());
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..300) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i % 3 != 0) try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+        try frame.addComponent(entity, Health{ .value = @intCast(i % 4 + 1), .max = 4 });
+    }
+
+    // Warm-up tick settles every array's capacity
+    try testing.expectEqual(@as(u32, 200), try churnTick(&test_ecs));
+    const warm = counting.allocations;
+    for (0..60) |_| try testing.expectEqual(@as(u32, 200), try churnTick(&test_ecs));
+    try testing.expectEqual(warm, counting.allocations);
+}
+
+test "Queries borrow their results instead of copying them" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i < 4) try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+    }
+
+    // A query is cursors and pointers, not an entity set
+    try testing.expect(@sizeOf(StandardECS.Query(&.{Position})) < @sizeOf(StandardECS.EntityBitSet));
+    var positions = try frame.query(&.{Position});
+    try testing.expectEqual(@as(*const StandardECS.EntityBitSet, &frame.state.query_result), positions.result_entities);
+    try testing.expectEqual(@as(u32, 10), positions.count());
+
+    // A caller-owned set survives later queries; narrowing writes into it
+    var own = StandardECS.EntityBitSet.initEmpty();
+    var movers = try frame.queryInto(&.{Position}, &own);
+    var velocities = try frame.query(&.{Velocity});
+    try testing.expectEqual(@as(u32, 4), velocities.count());
+    try testing.expectEqual(@as(u32, 10), movers.count());
+    movers.restrictTo(&frame.getComponentStorage(Velocity).entity_bitset);
+    try testing.expectEqual(@as(*const StandardECS.EntityBitSet, &own), movers.result_entities);
+    try testing.expectEqual(@as(u32, 4), own.count());
+}
+
+test "Queries stay valid after being moved" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..200) |i| {
+        const entity = try frame.createEntity();
+        if (i % 2 == 0) try frame.addComponent(entity, Tag{ .id = @intCast(i) });
+    }
+
+    const query = try frame.query(&.{Tag});
+    var copies = [_]@TypeOf(query){ query, query };
+    var last: u32 = 0;
+    var seen: u32 = 0;
+    while (copies[1].nextFast()) |result| {
+        try testing.expectEqual(last, result.entity);
+        last += 2;
+        seen += 1;
+    }
+    try testing.expectEqual(@as(u32, 100), seen);
+    try testing.expectEqual(@as(u32, 100), copies[0].count());
+}
+
+test "Queries hand out words and runs" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..200) |i| {
+        const entity = try frame.createEntity();
+        // 3..9, 60..69 (crosses the first word boundary), 128, 190..199
+        if ((i >= 3 and i < 10) or (i >= 60 and i < 70) or i == 128 or i >= 190) {
+            try frame.addComponent(entity, Tag{ .id = @intCast(i) });
+        }
+    }
+
+    var runs = try frame.query(&.{Tag});
+    const expected = [_]ecs.EntityRun{
+        .{ .first = 3, .len = 7 },
+        .{ .first = 60, .len = 10 },
+        .{ .first = 128, .len = 1 },
+        .{ .first = 190, .len = 10 },
+    };
+    for (expected) |run| try testing.expectEqual(run, runs.nextRun().?);
+    try testing.expectEqual(@as(?ecs.EntityRun, null), runs.nextRun());
+
+    var words = try frame.query(&.{Tag});
+    var total: u32 = 0;
+    var bases: [4]ecs.EntityID = undefined;
+    var word_count: usize = 0;
+    while (words.nextWord()) |word| {
+        bases[word_count] = word.base;
+        word_count += 1;
+        total += @popCount(word.bits);
+    }
+    try testing.expectEqualSlices(ecs.EntityID, &.{ 0, 64, 128, 192 }, bases[0..word_count]);
+    try testing.expectEqual(words.count(), total);
+}
+
+test "Cached queries rebuild only on membership changes" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+    }
+
+    const Moving = StandardECS.CachedQuery(&.{ Position, Velocity });
+    var moving = Moving{};
+    var query: Moving.Query = moving.query(frame);
+    try testing.expectEqual(@as(u32, 5), query.count());
+
+    // The query borrows the cached set, so other queries don't disturb it
+    try testing.expectEqual(@as(*const StandardECS.EntityBitSet, &moving.result_entities), query.result_entities);
+    var everything = try frame.query(&.{Position});
+    try testing.expectEqual(@as(u32, 10), everything.count());
+    try testing.expectEqual(@as(u32, 5), query.count());
+
+    // Data writes and unrelated storages leave the cache alone
+    while (query.nextFast()) |result| result.get(Position).x += 1;
+    try frame.addComponent(1, Tag{ .id = 1 });
+    try testing.expectEqual(@as(u32, 5), moving.query(frame).count());
+    try testing.expectEqual(@as(u64, 1), moving.rebuilds);
+
+    try frame.addComponent(1, Velocity{ .x = 1, .y = 0 });
+    try testing.expectEqual(@as(u32, 6), moving.query(frame).count());
+    try testing.expectEqual(@as(u64, 2), moving.rebuilds);
+
+    // Restoring a frame brings back its versions with its membership
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer StandardECS.freeSavedFrame(&saved);
+    frame.destroyEntity(0);
+    try testing.expectEqual(@as(u32, 5), moving.query(frame).count());
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(u32, 6), moving.query(frame).count());
+    try testing.expectEqual(@as(u64, 4), moving.rebuilds);
+}
+
+test "Vast worlds page their index and skip empty bitset regions" {
+    const VastECS = ecs.ECS(.{
+        .components = &.{ Position, Tag },
+        .input = TestInput,
+        .max_entities = .vast,
+    });
+    var test_ecs = try VastECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..100_000) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = @floatFromInt(i), .y = 0 });
+        // Two clusters far apart - one index page each
+        if (i < 100 or i >= 99_000) try frame.addComponent(entity, Tag{ .id = @intCast(i) });
+    }
+
+    const tags = frame.getComponentStorage(Tag);
+    try testing.expectEqual(@as(u32, 1100), tags.count());
+    try testing.expect(tags.indexBytes() < frame.getComponentStorage(Position).indexBytes());
+    try testing.expectEqual(@as(i32, 99_500), frame.getComponent(99_500, Tag).?.id);
+
+    var untagged = try frame.queryFiltered(.{ .with = &.{Position}, .without = &.{Tag} });
+    try testing.expectEqual(@as(u32, 98_900), untagged.count());
+    try testing.expectEqual(@as(?u32, 100), untagged.result_entities.nextSet(0));
+    var query = try frame.query(&.{ Position, Tag });
+    try testing.expectEqual(@as(u32, 1100), query.count());
+    var chunk: [512]ecs.EntityID = undefined;
+    var seen: u32 = 0;
+    var previous: ?ecs.EntityID = null;
+    while (true) {
+        const entities = query.nextChunk(&chunk);
+        if (entities.len == 0) break;
+        for (entities) |entity| {
+            if (previous) |p| try testing.expect(entity > p);
+            previous = entity;
+            try testing.expectEqual(@as(u32, entity), frame.getComponent(entity, Tag).?.id);
+        }
+        seen += @intCast(entities.len);
+    }
+    try testing.expectEqual(@as(u32, 1100), seen);
+
+    // Removal keeps the summary exact; snapshots copy only touched pages
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer VastECS.freeSavedFrame(&saved);
+    for (0..100) |i| _ = frame.removeComponent(@intCast(i), Tag);
+    var remaining = try frame.query(&.{Tag});
+    try testing.expectEqual(@as(u32, 99_000), remaining.nextFast().?.entity);
+    try testing.expectEqual(@as(u32, 1000), remaining.count());
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(u32, 1100), frame.getComponentStorage(Tag).count());
+    try testing.expectEqual(@as(u32, 42), frame.getComponent(42, Tag).?.id);
+}
+
+test "Churned vast worlds iterate and copy only live regions" {
+    const VastECS = ecs.ECS(.{
+        .components = &.{Position},
+        .input = TestInput,
+        .max_entities = .vast,
+    });
+    var test_ecs = try VastECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..5000) |_| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+    }
+    var full = try test_ecs.saveFrame(testing.allocator);
+    defer VastECS.freeSavedFrame(&full);
+
+    // Survivors cluster in the lowest ids
+    for (200..5000) |i| frame.destroyEntity(@intCast(i));
+    var churned = try test_ecs.saveFrame(testing.allocator);
+    defer VastECS.freeSavedFrame(&churned);
+
+    var query = try frame.query(&.{Position});
+    var expected: ecs.EntityID = 0;
+    while (query.next()) |result| : (expected += 1) try testing.expectEqual(expected, result.entity);
+    try testing.expectEqual(@as(ecs.EntityID, 200), expected);
+    try testing.expect(query.next() == null);
+
+    var alive: u32 = 0;
+    var iter = frame.state.active_entities.iterator(.{});
+    while (iter.next()) |_| alive += 1;
+    try testing.expectEqual(@as(u32, 200), alive);
+
+    // Copying a sparse frame over a full one clears the words only the full one had
+    try test_ecs.restoreFrame(&full);
+    var restored = try frame.query(&.{Position});
+    try testing.expectEqual(@as(u32, 5000), restored.count());
+    try test_ecs.restoreFrame(&churned);
+    var sparse = try frame.query(&.{Position});
+    try testing.expectEqual(@as(u32, 200), sparse.count());
+    try testing.expect(!frame.state.active_entities.isSet(4500));
+    try testing.expect(frame.getComponent(4500, Position) == null);
+}
+
+test "Library type names match what frames hand out" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const entity: ecs.Entity = try frame.createEntity();
+    try frame.addComponent(entity, Position{ .x = 1, .y = 2 });
+
+    const storage: *StandardECS.Storage(Position) = frame.getComponentStorage(Position);
+    try testing.expectEqual(@as(f32, 2), storage.getDirect(entity).y);
+
+    var query: StandardECS.Query(&.{Position}) = try frame.query(&.{Position});
+    try testing.expectEqual(entity, query.nextFast().?.entity);
+}
+
+test "Component IDs follow registration order" {
+    const Burning = struct { ticks_left: u16 };
+    const Frozen = struct {};
+    const StatusECS = ecs.ECS(.{
+        .components = &.{ Position, Burning },
+        .input = TestInput,
+        .max_entities = .tiny,
+    });
+
+    try testing.expectEqual(@as(u6, 0), StatusECS.componentId(Position));
+    try testing.expectEqual(@as(u6, 1), StatusECS.componentId(Burning));
+    try testing.expect(StatusECS.isComponent(Burning));
+    try testing.expect(!StatusECS.isComponent(Frozen));
+    try testing.expectEqualStrings("Burning", StatusECS.component_names[StatusECS.componentId(Burning)]);
+
+    var test_ecs = try StatusECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Burning{ .ticks_left = 3 });
+    var query = try frame.query(&.{Burning});
+    try testing.expectEqual(@as(u32, 1), query.count());
+}
+
+test "Handles of destroyed entities stop resolving" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const target = try frame.createEntity();
+    try frame.addComponent(target, Health{ .value = 10, .max = 10 });
+    const target_handle = frame.handle(target);
+    try testing.expectEqual(@as(?ecs.EntityID, target), frame.resolve(target_handle));
+
+    // Snapshot before the kill - a rollback brings the target and its handle back
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer StandardECS.freeSavedFrame(&saved);
+
+    frame.destroyEntity(target);
+    try testing.expectEqual(@as(?ecs.EntityID, null), frame.resolve(target_handle));
+    try testing.expectEqual(ecs.EntityHandle.invalid, frame.handle(target));
+    try testing.expectEqual(@as(u32, 1), frame.state.generations.items[target]);
+
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(?ecs.EntityID, target), frame.resolve(target_handle));
+
+    // Handles from before a reset don't name the entities created after it
+    test_ecs.reset();
+    const reused = try frame.createEntity();
+    try testing.expectEqual(target, reused);
+    try testing.expectEqual(@as(?ecs.EntityID, null), frame.resolve(target_handle));
+    try testing.expectEqual(@as(?ecs.EntityID, reused), frame.resolve(frame.handle(reused)));
+}
+
+test "Component access through stale handles misses the slot's new entity" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const first = try frame.createEntity();
+    try frame.addComponent(first, Health{ .value = 10, .max = 10 });
+    const stale = frame.handle(first);
+    try testing.expect(frame.hasComponentByHandle(stale, Health));
+    frame.getComponentMutByHandle(stale, Health).?.value = 7;
+    try testing.expectEqual(@as(i32, 7), frame.getComponentByHandle(stale, Health).?.value);
+
+    // The slot goes to a new entity with the same component type
+    frame.destroyEntity(first);
+    const second = try frame.createEntity();
+    try testing.expectEqual(first, second);
+    try frame.addComponent(second, Health{ .value = 50, .max = 50 });
+
+    try testing.expect(!frame.hasComponentByHandle(stale, Health));
+    try testing.expectEqual(@as(?*Health, null), frame.getComponentByHandle(stale, Health));
+    try testing.expectEqual(@as(?*Health, null), frame.getComponentMutByHandle(stale, Health));
+    try testing.expect(!frame.removeComponentByHandle(stale, Health));
+    try testing.expect(!frame.removeComponentByHandle(ecs.EntityHandle.invalid, Health));
+    try testing.expectEqual(@as(i32, 50), frame.getComponent(second, Health).?.value);
+
+    const current = frame.handle(second);
+    try testing.expect(frame.removeComponentByHandle(current, Health));
+    try testing.expect(!frame.hasComponent(second, Health));
+}
+
+test "Filtered queries exclude marker components and read optional ones" {
+    var test_ecs = try StandardECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    for (0..6) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i % 3 == 0) try frame.addComponent(entity, Tag{ .id = 1 });
+        if (i % 2 == 0) try frame.addComponent(entity, Health{ .value = 5, .max = 5 });
+    }
+
+    var query = try frame.queryFiltered(.{ .with = &.{Position}, .without = &.{Tag}, .optional = &.{Health} });
+    try testing.expectEqual(@as(u32, 4), query.count());
+    var with_health: u32 = 0;
+    while (query.next()) |result| {
+        try testing.expect(!frame.hasComponent(result.entity, Tag));
+        if (result.tryGet(Health)) |health| {
+            health.value -= 1;
+            with_health += 1;
+        }
+    }
+    try testing.expectEqual(@as(u32, 2), with_health);
+    try testing.expectEqual(StandardECS.componentMask(&.{Tag}), @TypeOf(query).exclude_mask);
+}
+
+test "Change detection reports entities written since a tick" {
+    var test_ecs = try TinyECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    const positions = frame.getComponentStorage(Position);
+
+    var entities: [4]ecs.EntityID = undefined;
+    for (&entities) |*entity| {
+        entity.* = try frame.createEntity();
+        try frame.addComponent(entity.*, Position{ .x = 0, .y = 0 });
+        try frame.addComponent(entity.*, Velocity{ .x = 1, .y = 0 });
+    }
+
+    // Tick 1: only even entities move
+    test_ecs.update(.{}, 0.016, 0.016);
+    var movers = try frame.query(&.{ Position, Velocity });
+    while (movers.next()) |result| {
+        if (result.entity % 2 == 0) result.getMut(Position).x += result.get(Velocity).x;
+    }
+    var moved = try frame.query(&.{Position});
+    moved.changedSince(Position, 0);
+    try testing.expectEqual(@as(u32, 2), moved.count());
+    try testing.expectEqual(@as(u64, 1), positions.last_changed);
+    try testing.expectEqual(@as(?u64, 0), positions.changedAt(entities[1]));
+
+    // Tick 2: the dirty set starts empty, older changes are still found by tick
+    test_ecs.update(.{}, 0.016, 0.032);
+    var since_last = try frame.query(&.{Position});
+    since_last.changedSince(Position, 1);
+    try testing.expectEqual(@as(u32, 0), since_last.count());
+
+    frame.markChanged(entities[3], Position);
+    var since_start = try frame.query(&.{Position});
+    since_start.changedSince(Position, 0);
+    try testing.expectEqual(@as(u32, 3), since_start.count());
+    try testing.expect(positions.changed.isSet(entities[3]));
+    try testing.expect(!positions.changed.isSet(entities[0]));
+
+    // Change ticks roll back with the frame
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TinyECS.freeSavedFrame(&saved);
+    test_ecs.update(.{}, 0.016, 0.048);
+    _ = frame.getComponentMut(entities[1], Position);
+    try testing.expectEqual(@as(?u64, 3), positions.changedAt(entities[1]));
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(?u64, 0), positions.changedAt(entities[1]));
+    try testing.expectEqual(@as(u64, 2), positions.last_changed);
+
+    // Swap-remove carries the moved slot's tick along
+    _ = frame.removeComponent(entities[0], Position);
+    try testing.expectEqual(@as(?u64, 2), positions.changedAt(entities[3]));
+    try testing.expectEqual(@as(?u64, null), positions.changedAt(entities[0]));
+    try testing.expect(!positions.changed.isSet(entities[0]));
+}
+
+test "Destroyed slots are reused newest first and roll back with the frame" {
+    var test_ecs = try TinyECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    for (0..8) |_| _ = try frame.createEntity();
+    const old_handle = frame.handle(2);
+    frame.destroyEntity(2);
+    frame.destroyEntity(5);
+
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TinyECS.freeSavedFrame(&saved);
+
+    try testing.expectEqual(@as(ecs.EntityID, 5), try frame.createEntity());
+    try testing.expectEqual(@as(ecs.EntityID, 2), try frame.createEntity());
+    try testing.expectEqual(@as(ecs.EntityID, 8), try frame.createEntity());
+    // The recycled slot is a new entity as far as handles go
+    try testing.expectEqual(@as(?ecs.EntityID, null), frame.resolve(old_handle));
+
+    // A resimulated tick spawns into the same slots again
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(ecs.EntityID, 5), try frame.createEntity());
+
+    // Loaders can claim a free slot directly
+    try frame.state.restoreEntity(2);
+    try testing.expectEqual(@as(ecs.EntityID, 8), try frame.createEntity());
+    var buffer: [128]u8 = undefined;
+    try testing.expect(frame.state.findViolation(&buffer) == null);
+}
+
+fn simulateMatch(world: *TinyECS, seed: u32, result: *f32) void {
+    const frame = world.getFrame();
+    for (0..32) |i| {
+        const entity = frame.createEntity() catch return;
+        frame.addComponent(entity, Position{ .x = @floatFromInt(seed), .y = 0 }) catch return;
+        if (i % 4 == 0) frame.destroyEntity(entity);
+    }
+    var total: f32 = 0;
+    var query = frame.query(&.{Position}) catch return;
+    while (query.next()) |item| total += item.get(Position).x;
+    result.* = total;
+}
+
+test "Worlds of one component set run as independent simulations" {
+    var confirmed = try TinyECS.init(testing.allocator);
+    defer confirmed.deinit();
+    var predicted = try TinyECS.init(testing.allocator);
+    defer predicted.deinit();
+
+    const entity = try confirmed.getFrame().createEntity();
+    try confirmed.getFrame().addComponent(entity, Position{ .x = 1, .y = 1 });
+    try testing.expectEqual(@as(u32, 0), predicted.getFrame().getEntityCount());
+
+    // Predict ahead from the confirmed state; the confirmed world doesn't see it
+    try confirmed.copyFrameTo(predicted.getFrame());
+    const spawned = try predicted.getFrame().createEntity();
+    try predicted.getFrame().addComponent(spawned, Position{ .x = 2, .y = 2 });
+    try testing.expectEqual(@as(u32, 1), confirmed.getFrame().getEntityCount());
+    try testing.expect(!confirmed.getFrame().hasComponent(spawned, Position));
+
+    // A cached query moved between worlds never mistakes one membership for the other
+    var cached = TinyECS.CachedQuery(&.{Position}){};
+    var predicted_matches = cached.query(predicted.getFrame());
+    try testing.expectEqual(@as(u32, 2), predicted_matches.count());
+    var confirmed_matches = cached.query(confirmed.getFrame());
+    try testing.expectEqual(@as(u32, 1), confirmed_matches.count());
+
+    // Matches on separate threads
+    var worlds: [4]TinyECS = undefined;
+    var totals: [4]f32 = undefined;
+    var threads: [4]std.Thread = undefined;
+    for (&worlds) |*world| world.* = try TinyECS.init(testing.allocator);
+    defer for (&worlds) |*world| world.deinit();
+    for (&threads, 0..) |*thread, i| {
+        thread.* = try std.Thread.spawn(.{}, simulateMatch, .{ &worlds[i], @as(u32, @intCast(i + 1)), &totals[i] });
+    }
+    for (threads) |thread| thread.join();
+    for (totals, 1..) |total, seed| {
+        try testing.expectEqual(@as(f32, @floatFromInt(24 * seed)), total);
+    }
+}
+
+test "Checksums agree on equal state and name the storage that diverged" {
+    var local = try StandardECS.init(testing.allocator);
+    defer local.deinit();
+    var remote = try StandardECS.init(testing.allocator);
+    defer remote.deinit();
+
+    // Same state built in a different order - different dense layouts
+    for (0..4) |i| {
+        const entity = try local.getFrame().createEntity();
+        try local.getFrame().addComponent(entity, Position{ .x = @floatFromInt(i), .y = 0 });
+        try local.getFrame().addComponent(entity, Name{ .value = "unit" });
+    }
+    for (0..4) |_| _ = try remote.getFrame().createEntity();
+    var i: u32 = 4;
+    while (i > 0) {
+        i -= 1;
+        try remote.getFrame().addComponent(i, Name{ .value = "unit" });
+        try remote.getFrame().addComponent(i, Position{ .x = @floatFromInt(i), .y = 0 });
+    }
+    try testing.expect(local.getFrame().getComponentStorage(Position).getDenseEntities()[0] !=
+        remote.getFrame().getComponentStorage(Position).getDenseEntities()[0]);
+    try testing.expectEqual(local.getFrame().checksum(), remote.getFrame().checksum());
+
+    remote.getFrame().getComponent(2, Position).?.y = 0.5;
+    const local_sums = local.getFrame().checksums();
+    const remote_sums = remote.getFrame().checksums();
+    try testing.expect(local_sums.total() != remote_sums.total());
+    try testing.expectEqualStrings("Position", local_sums.firstDivergence(&remote_sums).?);
+
+    // Slices hash by content, not address
+    remote.getFrame().getComponent(2, Position).?.y = 0;
+    const copied = try testing.allocator.dupe(u8, "unit");
+    defer testing.allocator.free(copied);
+    remote.getFrame().getComponent(1, Name).?.value = copied;
+    try testing.expectEqual(local.getFrame().checksum(), remote.getFrame().checksum());
+
+    remote.getFrame().destroyEntity(3);
+    const after_destroy = remote.getFrame().checksums();
+    try testing.expectEqualStrings("entities", local_sums.firstDivergence(&after_destroy).?);
+}
+
+const Time = struct { dt: f32 = 0, elapsed: f64 = 0 };
+const Rng = struct { state: u64 = 0 };
+
+test "Resources are per-frame singletons that roll back and feed checksums" {
+    const ResourceECS = ecs.ECS(.{
+        .components = &.{Position},
+        .input = TestInput,
+        .resources = &.{ Time, Rng },
+        .max_entities = .tiny,
+    });
+    var world = try ResourceECS.initPreallocated(testing.allocator, 1);
+    defer world.deinit();
+    var peer = try ResourceECS.init(testing.allocator);
+    defer peer.deinit();
+
+    // Zeroed until set
+    try testing.expectEqual(@as(u64, 0), world.getResource(Rng).state);
+    world.setResource(Time{ .dt = 1.0 / 60.0 });
+    world.getFrame().getResource(Rng).state = 42;
+    try world.saveSnapshot(0);
+
+    world.getResource(Time).elapsed += 1.0 / 60.0;
+    world.getResource(Rng).state = 7;
+    const moved_on = world.getFrame().checksums();
+    const fresh = peer.getFrame().checksums();
+    try testing.expectEqualStrings("resources", moved_on.firstDivergence(&fresh).?);
+
+    try world.restoreSnapshot(0);
+    try testing.expectEqual(@as(f64, 0), world.getResource(Time).elapsed);
+    try testing.expectEqual(@as(u64, 42), world.getResource(Rng).state);
+
+    peer.setResource(Time{ .dt = 1.0 / 60.0 });
+    peer.setResource(Rng{ .state = 42 });
+    try testing.expectEqual(world.getFrame().checksum(), peer.getFrame().checksum());
+
+    world.reset();
+    try testing.expectEqual(Time{}, world.getResource(Time).*);
+}
+
+const TargetOf = struct {};
+const MemberOf = struct {};
+
+test "Relations find their sources and die with either endpoint" {
+    const RelationECS = ecs.ECS(.{
+        .components = &.{ Position, ecs.Relation(TargetOf), ecs.Relation(MemberOf) },
+        .input = TestInput,
+        .max_entities = .tiny,
+    });
+    try testing.expectEqualStrings("TargetOfRelation", RelationECS.component_names[1]);
+
+    var world = try RelationECS.init(testing.allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+    var entities: [5]ecs.EntityID = undefined;
+    for (&entities) |*entity| entity.* = try frame.createEntity();
+    const boss = entities[0];
+    const squad = entities[4];
+
+    // Relating again moves the relation; kinds are independent
+    try frame.relate(entities[3], TargetOf, entities[1]);
+    for (entities[1..4]) |entity| {
+        try frame.relate(entity, TargetOf, boss);
+        try frame.relate(entity, MemberOf, squad);
+    }
+    try testing.expectEqual(@as(?ecs.EntityID, boss), frame.relationTarget(entities[3], TargetOf));
+    try testing.expectEqual(@as(?ecs.EntityID, null), frame.relationTarget(boss, TargetOf));
+    try testing.expectError(error.InvalidEntity, frame.relate(boss, TargetOf, 40));
+
+    var attackers = frame.relatedTo(TargetOf, boss);
+    for (entities[1..4]) |expected| try testing.expectEqual(@as(?ecs.EntityID, expected), attackers.next());
+    try testing.expectEqual(@as(?ecs.EntityID, null), attackers.next());
+    try testing.expect(frame.unrelate(entities[2], TargetOf));
+    try testing.expect(!frame.unrelate(entities[2], TargetOf));
+
+    // A destroyed source takes its relations along, a destroyed target every relation to it
+    frame.destroyEntity(entities[1]);
+    frame.destroyEntity(boss);
+    try testing.expectEqual(@as(u32, 0), frame.getComponentStorage(ecs.Relation(TargetOf)).entity_bitset.count());
+    var members = frame.relatedTo(MemberOf, squad);
+    try testing.expectEqual(@as(?ecs.EntityID, entities[2]), members.next());
+    try testing.expectEqual(@as(?ecs.EntityID, entities[3]), members.next());
+    try testing.expectEqual(@as(?ecs.EntityID, null), members.next());
+
+    // The recycled id of the old target is not related to anything
+    const recycled = try frame.createEntity();
+    try testing.expectEqual(boss, recycled);
+    var none = frame.relatedTo(TargetOf, recycled);
+    try testing.expectEqual(@as(?ecs.EntityID, null), none.next());
+}
+
+test "Relation index matches whole handles and follows restored frames" {
+    const RelationECS = ecs.ECS(.{
+        .components = &.{ Position, ecs.Relation(TargetOf) },
+        .input = TestInput,
+        .max_entities = .tiny,
+    });
+    var world = try RelationECS.initPreallocated(testing.allocator, 1);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    const source = try frame.createEntity();
+    const first = try frame.createEntity();
+    const second = try frame.createEntity();
+
+    // A rollback brings back the old target along with the index
+    try frame.relate(source, TargetOf, first);
+    try world.saveSnapshot(0);
+    try frame.relate(source, TargetOf, second);
+    var targeting = frame.relatedTo(TargetOf, second);
+    try testing.expectEqual(@as(?ecs.EntityID, source), targeting.next());
+    try world.restoreSnapshot(0);
+    targeting = frame.relatedTo(TargetOf, second);
+    try testing.expectEqual(@as(?ecs.EntityID, null), targeting.next());
+    targeting = frame.relatedTo(TargetOf, first);
+    try testing.expectEqual(@as(?ecs.EntityID, source), targeting.next());
+
+    // A relation to the slot's previous occupant is neither found nor removed with the new one
+    const stale = frame.handle(second);
+    frame.destroyEntity(second);
+    const recycled = try frame.createEntity();
+    try testing.expectEqual(second, recycled);
+    try frame.addComponent(first, ecs.Relation(TargetOf){ .target = stale });
+    targeting = frame.relatedTo(TargetOf, recycled);
+    try testing.expectEqual(@as(?ecs.EntityID, null), targeting.next());
+    frame.destroyEntity(recycled);
+    try testing.expect(frame.hasComponent(first, ecs.Relation(TargetOf)));
+    try testing.expectEqual(@as(?ecs.EntityID, null), frame.relationTarget(first, TargetOf));
+}
+
+const ByTagDescending = struct {
+    frame: *StandardECS.Frame,
+
+    fn lessThan(self: ByTagDescending, a: ecs.EntityID, b: ecs.EntityID) bool {
+        return self.frame.getComponent(a, Tag).?.id > self.frame.getComponent(b, Tag).?.id;
+    }
+};
+
+test "Sorted queries order by a key and keep entity order on ties" {
+    var world = try StandardECS.init(testing.allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    const values = [_]i32{ 30, 10, 20, 10, 30, 5 };
+    for (values, 0..) |value, i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Health{ .value = value, .max = 100 });
+        try frame.addComponent(entity, Tag{ .id = @intCast(i % 3) });
+    }
+    _ = try frame.createEntity();
+
+    var query = try frame.query(&.{ Health, Tag });
+    var buffer: [8]ecs.EntityID = undefined;
+    try testing.expectEqualSlices(ecs.EntityID, &.{ 5, 1, 3, 2, 0, 4 }, try query.sortByField(&buffer, Health, "value"));
+    try testing.expectEqualSlices(ecs.EntityID, &.{ 2, 5, 1, 4, 0, 3 }, try query.sortBy(&buffer, ByTagDescending{ .frame = frame }, ByTagDescending.lessThan));
+
+    // Sorting doesn't move the cursor
+    try testing.expectEqual(@as(ecs.EntityID, 0), query.next().?.entity);
+    var small: [4]ecs.EntityID = undefined;
+    try testing.expectError(error.BufferTooSmall, query.sortByField(&small, Tag, "id"));
+}
+
+fn expectSetAlgebra(comptime Set: type, a_ids: []const u32, b_ids: []const u32) !void {
+    var a = Set.initEmpty();
+    var b = Set.initEmpty();
+    for (a_ids) |id| a.set(id);
+    for (b_ids) |id| b.set(id);
+
+    const both = a.intersectWith(&b);
+    const either = a.unionWith(&b);
+    const only_a = a.differenceWith(&b);
+    const one = a.symmetricDifferenceWith(&b);
+    for ([_][]const u32{ a_ids, b_ids }) |ids| {
+        for (ids) |id| {
+            const in_a = a.isSet(id);
+            const in_b = b.isSet(id);
+            try testing.expectEqual(in_a and in_b, both.isSet(id));
+            try testing.expectEqual(in_a or in_b, either.isSet(id));
+            try testing.expectEqual(in_a and !in_b, only_a.isSet(id));
+            try testing.expectEqual(in_a != in_b, one.isSet(id));
+        }
+    }
+    try testing.expectEqual(either.count(), both.count() + one.count());
+
+    // In place, aliasing an input; a set minus itself leaves no live word behind
+    var scratch = a;
+    scratch.symmetricDifferenceInto(&b, &scratch);
+    try testing.expectEqual(one.count(), scratch.count());
+    scratch.symmetricDifferenceInto(&scratch, &scratch);
+    try testing.expectEqual(@as(u32, 0), scratch.count());
+    try testing.expectEqual(@as(?u32, null), scratch.nextSet(0));
+}
+
+test "Entity bitsets compose unions, differences and symmetric differences" {
+    try expectSetAlgebra(TinyECS.EntityBitSet, &.{ 0, 5, 9, 63 }, &.{ 5, 10, 63 });
+
+    const VastECS = ecs.ECS(.{
+        .components = &.{Position},
+        .input = TestInput,
+        .max_entities = .vast,
+    });
+    // Words far apart, so the summary level decides which words are visited
+    try expectSetAlgebra(VastECS.EntityBitSet, &.{ 1, 4100, 70_000, 131_071 }, &.{ 4100, 4101, 90_000 });
+}
+
 // Run all tests
 test {
     std.testing.refAllDecls(@This());
@@ -0,0 +1,103 @@
+const std = @import("std");
+
+/// Typed event channel between systems: what one tick emits, the next tick reads.
+///
+/// Events are kept per tick in a ring of `history` buffers - 2 is plain double buffering; give it
+/// the rollback window plus one to roll back with the world. Buffers are keyed by tick, so the
+/// channel clears itself at tick boundaries: emitting into a tick reuses the slot of the tick
+/// `history` ticks earlier. Asking for a tick before the newest one seen means the simulation was
+/// rewound: that tick and every later one are dropped, and resimulating emits them afresh while
+/// the events of earlier ticks are still there to read. Channels only learn about ticks through
+/// `emit` and `drain`; call `rollbackTo` alongside the world when a rewound tick may pass without
+/// touching the channel.
+///
+/// Usage:
+///   var collisions = try Events(CollisionEvent).init(allocator, 2);
+///   // Physics, tick N
+///   try collisions.emit(frame.frame_number, .{ .a = a, .b = b });
+///   // Damage, tick N + 1
+///   for (collisions.drain(frame.frame_number)) |event| applyHit(event);
+pub fn Events(comptime T: type) type {
+    return struct {
+        const Self = @This();
+
+        const Slot = struct {
+            /// Tick the events were emitted in, null while the slot is unused
+            tick: ?u64 = null,
+            events: std.ArrayListUnmanaged(T) = .{},
+        };
+
+        allocator: std.mem.Allocator,
+        slots: []Slot,
+        /// Latest tick the channel was used in
+        newest: u64 = 0,
+
+        pub fn init(allocator: std.mem.Allocator, history: usize) !Self {
+            std.debug.assert(history >= 2);
+            const slots = try allocator.alloc(Slot, history);
+            @memset(slots, .{});
+            return .{ .allocator = allocator, .slots = slots };
+        }
+
+        pub fn deinit(self: *Self) void {
+            for (self.slots) |*slot| slot.events.deinit(self.allocator);
+            self.allocator.free(self.slots);
+        }
+
+        /// Queue `event` for the systems of the next tick
+        pub fn emit(self: *Self, tick: u64, event: T) !void {
+            self.advance(tick);
+            const slot = &self.slots[tick % self.slots.len];
+            if (slot.tick != tick) {
+                slot.events.clearRetainingCapacity();
+                slot.tick = tick;
+            }
+            try slot.events.append(self.allocator, event);
+        }
+
+        /// Events emitted during the tick before `tick`, in emission order. Every reader gets the
+        /// full list; the slot is reused once the channel moves `history` ticks on.
+        pub fn drain(self: *Self, tick: u64) []const T {
+            self.advance(tick);
+            if (tick == 0) return &.{};
+            return self.emitted(tick - 1);
+        }
+
+        /// Events emitted during `tick` (empty once it left the ring or was rolled back)
+        pub fn emitted(self: *const Self, tick: u64) []const T {
+            const slot = &self.slots[tick % self.slots.len];
+            if (slot.tick != tick) return &.{};
+            return slot.events.items;
+        }
+
+        /// Forget the events of every tick after `tick` - the world was restored to its end
+        pub fn rollbackTo(self: *Self, tick: u64) void {
+            self.dropFrom(tick + 1);
+            self.newest = @min(self.newest, tick);
+        }
+
+        /// Drop every event
+        pub fn clear(self: *Self) void {
+            for (self.slots) |*slot| {
+                slot.events.clearRetainingCapacity();
+                slot.tick = null;
+            }
+            self.newest = 0;
+        }
+
+        // Note the tick being simulated; an earlier one than before is a resimulation of it
+        fn advance(self: *Self, tick: u64) void {
+            if (tick < self.newest) self.dropFrom(tick);
+            self.newest = tick;
+        }
+
+        fn dropFrom(self: *Self, tick: u64) void {
+            for (self.slots) |*slot| {
+                const slot_tick = slot.tick orelse continue;
+                if (slot_tick < tick) continue;
+                slot.events.clearRetainingCapacity();
+                slot.tick = null;
+            }
+        }
+    };
+}
@@ -0,0 +1,55 @@
+const std = @import("std");
+const testing = std.testing;
+const Events = @import("events.zig").Events;
+
+const Hit = struct {
+    target: u32,
+    damage: i32,
+};
+
+test "Events emitted in a tick are read in the next one" {
+    var hits = try Events(Hit).init(testing.allocator, 2);
+    defer hits.deinit();
+
+    try hits.emit(1, .{ .target = 3, .damage = 10 });
+    try hits.emit(1, .{ .target = 4, .damage = 5 });
+    // Same-tick readers don't see them yet
+    try testing.expectEqual(@as(usize, 0), hits.drain(1).len);
+
+    const read = hits.drain(2);
+    try testing.expectEqual(@as(usize, 2), read.len);
+    try testing.expectEqual(@as(u32, 3), read[0].target);
+    try testing.expectEqual(@as(i32, 5), read[1].damage);
+    // Every reader gets the full list
+    try testing.expectEqual(@as(usize, 2), hits.drain(2).len);
+
+    // Tick 3 reuses tick 1's buffer
+    try hits.emit(3, .{ .target = 7, .damage = 1 });
+    try testing.expectEqual(@as(usize, 0), hits.emitted(1).len);
+    try testing.expectEqual(@as(usize, 0), hits.drain(3).len);
+    try testing.expectEqual(@as(usize, 1), hits.drain(4).len);
+}
+
+test "Rewinding drops the resimulated ticks and keeps earlier events" {
+    var hits = try Events(Hit).init(testing.allocator, 4);
+    defer hits.deinit();
+
+    for (1..5) |tick| try hits.emit(tick, .{ .target = @intCast(tick), .damage = 1 });
+
+    // The world rolled back to the end of tick 2 and resimulates tick 3
+    const carried = hits.drain(3);
+    try testing.expectEqual(@as(usize, 1), carried.len);
+    try testing.expectEqual(@as(u32, 2), carried[0].target);
+    try testing.expectEqual(@as(usize, 0), hits.emitted(3).len);
+    try testing.expectEqual(@as(usize, 0), hits.emitted(4).len);
+
+    try hits.emit(3, .{ .target = 30, .damage = 2 });
+    try hits.emit(3, .{ .target = 31, .damage = 2 });
+    try testing.expectEqual(@as(usize, 2), hits.drain(4).len);
+
+    // Explicit rollback for ticks that never touched the channel
+    hits.rollbackTo(1);
+    try testing.expectEqual(@as(usize, 1), hits.emitted(1).len);
+    try testing.expectEqual(@as(usize, 0), hits.emitted(2).len);
+    try testing.expectEqual(@as(usize, 0), hits.drain(4).len);
+}
@@ -0,0 +1,574 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+
+const EntityID = ecs.EntityID;
+
+/// `file_identifier` of the generated schema, found at bytes 4..8 of every snapshot buffer
+pub const file_identifier = "RWFB";
+
+/// Snapshots as FlatBuffers (https://flatbuffers.dev), for servers hosting many sessions that
+/// want to read a saved or mmapped snapshot in place instead of decoding it into a world first.
+///
+/// Each component becomes a table holding its entity ids and a vector of FlatBuffers structs in
+/// ascending entity order, so one component of one entity is a binary search and a fixed-offset
+/// read away. `writeSchema` emits the matching .fbs, from which flatc generates readers for other
+/// languages. The entity bookkeeping (generations, free list) travels alongside; input and
+/// resources are opaque `encodeValue` bytes, since only this codec reads them back.
+///
+/// Component fields must have a FlatBuffers struct equivalent: bools, 8 to 64-bit integers,
+/// enums over those, floats, fixed arrays and nested structs of the same. Optionals, unions and
+/// slices are a compile error - use the `schema` codec for such worlds. Buffers only load into the
+/// exact component layouts they were written from (error.SchemaMismatch otherwise).
+///
+/// Usage:
+///   const Codec = FlatBuffersCodec(GameECS);
+///   const bytes = try Codec.encode(allocator, world.getFrame());
+///   const snapshot = try Codec.view(bytes);
+///   const health = try snapshot.get(Health, player);
+///   try snapshot.load(other_world.getFrame());
+pub fn FlatBuffersCodec(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+    const Input = @FieldType(EcsType.Frame, "input");
+    const Registry = schema.Registry(EcsType);
+
+    // Snapshot table: soffset, next_entity, three 64-bit scalars, then one offset per vector
+    // and component table
+    const root_vectors = 5;
+    const root_size = 32 + 4 * (root_vectors + Types.len);
+    const root_fields: [4 + root_vectors + Types.len]u16 = blk: {
+        var fields: [4 + root_vectors + Types.len]u16 = undefined;
+        fields[0] = 8; // schema_hash
+        fields[1] = 16; // frame_number
+        fields[2] = 24; // time
+        fields[3] = 4; // next_entity
+        for (4..fields.len) |slot| fields[slot] = @intCast(32 + 4 * (slot - 4));
+        break :blk fields;
+    };
+
+    return struct {
+        /// The .fbs schema of this world's snapshots
+        pub fn writeSchema(writer: anytype) !void {
+            try writer.writeAll("// Rewind snapshot schema generated from the registered components\n");
+            try writer.writeAll("namespace rewind;\n\n");
+            const declared = comptime blk: {
+                var types: []const type = &.{};
+                for (Types) |T| types = collectTypes(T, types);
+                break :blk types;
+            };
+            inline for (declared) |T| try writeDeclaration(T, writer);
+
+            inline for (Types) |T| {
+                try writer.print("table {s}Table {{\n  entities: [uint];\n", .{ecs.shortTypeName(T)});
+                if (comptime schema.runtimeFields(T).len > 0) try writer.print("  values: [{s}];\n", .{ecs.shortTypeName(T)});
+                try writer.writeAll("}\n\n");
+            }
+
+            try writer.writeAll(
+                \\table Snapshot {
+                \\  schema_hash: ulong;
+                \\  frame_number: ulong;
+                \\  time: double;
+                \\  next_entity: uint;
+                \\  generations: [uint];
+                \\  entities: [uint];
+                \\  free_entities: [uint];
+                \\  input: [ubyte];
+                \\  resources: [ubyte];
+                \\
+            );
+            inline for (Types) |T| {
+                try writer.print("  {s}: {s}Table;\n", .{ comptime snakeCase(ecs.shortTypeName(T)), ecs.shortTypeName(T) });
+            }
+            try writer.writeAll("}\n\nroot_type Snapshot;\nfile_identifier \"" ++ file_identifier ++ "\";\n");
+        }
+
+        /// The frame as a snapshot buffer, owned by the caller
+        pub fn encode(allocator: std.mem.Allocator, frame: *EcsType.Frame) ![]u8 {
+            var builder = Builder{ .allocator = allocator };
+            errdefer builder.bytes.deinit(allocator);
+
+            const root_offset = try builder.reserve(4);
+            try builder.bytes.appendSlice(allocator, file_identifier);
+            const root = try builder.table(&root_fields, root_size);
+            builder.link(root_offset, root);
+
+            const state = &frame.state;
+            builder.put(u64, root + 8, Registry.schemaHash());
+            builder.put(u64, root + 16, frame.frame_number);
+            builder.put(u64, root + 24, @bitCast(frame.time));
+            builder.put(u32, root + 4, state.next_entity);
+
+            // Component tables come before any vector so every offset points forward
+            var tables: [Types.len]usize = undefined;
+            inline for (Types, 0..) |T, i| {
+                const has_values = comptime schema.runtimeFields(T).len > 0;
+                const fields: []const u16 = if (has_values) &.{ 4, 8 } else &.{4};
+                tables[i] = try builder.table(fields, if (has_values) 12 else 8);
+                builder.link(root + 32 + 4 * (root_vectors + i), tables[i]);
+            }
+
+            const generations = try builder.vector(root + 32, state.generations.items.len, 4, 4);
+            for (state.generations.items, 0..) |generation, i| builder.put(u32, generations + 4 * i, generation);
+
+            const entities = try builder.vector(root + 36, state.entity_count, 4, 4);
+            var active = state.active_entities.fastIterator();
+            var index: usize = 0;
+            while (active.next()) |entity| : (index += 1) builder.put(u32, entities + 4 * index, entity);
+
+            const free = try builder.vector(root + 40, state.free_entities.items.len, 4, 4);
+            for (state.free_entities.items, 0..) |entity, i| builder.put(u32, free + 4 * i, entity);
+
+            try builder.bytesVector(root + 44, Input, frame.input);
+            try builder.bytesVector(root + 48, @TypeOf(state.resources), state.resources);
+
+            inline for (Types, 0..) |T, i| {
+                const storage = frame.getComponentStorage(T);
+                const count = storage.count();
+                const ids = try builder.vector(tables[i] + 4, count, 4, 4);
+                var holders = storage.entity_bitset.fastIterator();
+                var slot: usize = 0;
+                while (holders.next()) |entity| : (slot += 1) builder.put(u32, ids + 4 * slot, entity);
+
+                if (comptime schema.runtimeFields(T).len > 0) {
+                    const size = comptime layoutSize(T);
+                    const values = try builder.vector(tables[i] + 8, count, size, comptime layoutAlign(T));
+                    for (0..count) |at| {
+                        const entity = builder.get(u32, ids + 4 * at);
+                        writeStruct(T, frame.getComponent(entity, T).?.*, builder.bytes.items[values + size * at ..][0..size]);
+                    }
+                }
+            }
+
+            return builder.bytes.toOwnedSlice(allocator);
+        }
+
+        /// Check a snapshot buffer and wrap it for reading in place. Fails with error.InvalidData
+        /// when it is not a well-formed snapshot and error.SchemaMismatch when it was written from
+        /// other component layouts. The buffer must outlive the view.
+        pub fn view(bytes: []const u8) !View {
+            if (bytes.len < 8 or !std.mem.eql(u8, bytes[4..8], file_identifier)) return error.InvalidData;
+            var result = View{ .bytes = bytes, .root = try offsetAt(bytes, 0) };
+            const root = result.root;
+
+            const hash = try scalarField(bytes, root, 0, u64) orelse return error.InvalidData;
+            if (hash != Registry.schemaHash()) return error.SchemaMismatch;
+            result.frame_number = try scalarField(bytes, root, 1, u64) orelse 0;
+            result.time = @bitCast(try scalarField(bytes, root, 2, u64) orelse 0);
+            result.next_entity = try scalarField(bytes, root, 3, u32) orelse 0;
+            result.generations = try vectorField(bytes, root, 4, 4);
+            result.entities = try vectorField(bytes, root, 5, 4);
+            result.free_entities = try vectorField(bytes, root, 6, 4);
+            result.input = try vectorField(bytes, root, 7, 1);
+            result.resources = try vectorField(bytes, root, 8, 1);
+
+            inline for (Types, 0..) |T, i| {
+                const table = try tableField(bytes, root, root_vectors + 4 + i) orelse return error.InvalidData;
+                result.ids[i] = try vectorField(bytes, table, 0, 4);
+                if (comptime schema.runtimeFields(T).len > 0) {
+                    result.values[i] = try vectorField(bytes, table, 1, comptime layoutSize(T));
+                    if (result.values[i].len != result.ids[i].len) return error.InvalidData;
+                }
+            }
+            return result;
+        }
+
+        /// A validated snapshot buffer. Reads decode single components straight out of the buffer.
+        pub const View = struct {
+            bytes: []const u8,
+            root: usize,
+            frame_number: u64 = 0,
+            time: f64 = 0,
+            next_entity: u32 = 0,
+            generations: Vector = .{},
+            entities: Vector = .{},
+            free_entities: Vector = .{},
+            input: Vector = .{},
+            resources: Vector = .{},
+            ids: [Types.len]Vector = [_]Vector{.{}} ** Types.len,
+            values: [Types.len]Vector = [_]Vector{.{}} ** Types.len,
+
+            pub fn entityCount(self: View) usize {
+                return self.entities.len;
+            }
+
+            /// Number of entities holding T
+            pub fn count(self: View, comptime T: type) usize {
+                return self.ids[EcsType.componentId(T)].len;
+            }
+
+            /// The `index`th entity holding T, in ascending id order
+            pub fn entity(self: View, comptime T: type, index: usize) EntityID {
+                return self.ids[EcsType.componentId(T)].int(self.bytes, u32, index);
+            }
+
+            /// The T of the `index`th entity holding it; error.InvalidData when the bytes are not a
+            /// valid T (a bool other than 0/1, an unknown enum value)
+            pub fn component(self: View, comptime T: type, index: usize) !T {
+                if (comptime schema.runtimeFields(T).len == 0) {
+                    return T{};
+                } else {
+                    const size = comptime layoutSize(T);
+                    const start = self.values[EcsType.componentId(T)].start + size * index;
+                    return readStruct(T, self.bytes[start..][0..size]);
+                }
+            }
+
+            /// The entity's T, or null when it has none - a binary search over the entity ids
+            pub fn get(self: View, comptime T: type, target: EntityID) !?T {
+                var low: usize = 0;
+                var high = self.count(T);
+                while (low < high) {
+                    const middle = low + (high - low) / 2;
+                    const found = self.entity(T, middle);
+                    if (found == target) return try self.component(T, middle);
+                    if (found < target) low = middle + 1 else high = middle;
+                }
+                return null;
+            }
+
+            /// Replace the frame's contents with the snapshot, like `Registry.decodeFrame`. The frame
+            /// is left empty or partly loaded on error.
+            pub fn load(self: View, frame: *EcsType.Frame) !void {
+                const input = try decodeBytes(Input, self.input.slice(self.bytes));
+                const resources = try decodeBytes(@TypeOf(frame.state.resources), self.resources.slice(self.bytes));
+
+                const state = &frame.state;
+                var cursor: u32 = 0;
+                while (state.active_entities.nextSet(cursor)) |destroyed| : (cursor = destroyed + 1) {
+                    state.destroyEntity(destroyed);
+                }
+
+                const generation_count = self.generations.len;
+                if (generation_count > EcsType.max_entities or self.next_entity > EcsType.max_entities) return error.InvalidData;
+                try state.free_entities.ensureTotalCapacity(state.allocator, generation_count);
+                state.free_entities.clearRetainingCapacity();
+                try state.generations.resize(state.allocator, generation_count);
+                for (state.generations.items, 0..) |*generation, i| generation.* = self.generations.int(self.bytes, u32, i);
+
+                for (0..self.entities.len) |i| {
+                    const restored = self.entities.int(self.bytes, u32, i);
+                    if (restored >= generation_count) return error.InvalidData;
+                    state.restoreEntity(restored) catch return error.InvalidData;
+                }
+
+                inline for (Types) |T| {
+                    for (0..self.count(T)) |i| {
+                        const holder = self.entity(T, i);
+                        if (holder >= generation_count or !state.active_entities.isSet(holder)) return error.InvalidData;
+                        try frame.addComponent(holder, try self.component(T, i));
+                    }
+                }
+
+                if (self.free_entities.len > generation_count) return error.InvalidData;
+                for (0..self.free_entities.len) |i| {
+                    const free = self.free_entities.int(self.bytes, u32, i);
+                    if (free >= generation_count or state.active_entities.isSet(free)) return error.InvalidData;
+                    state.free_entities.appendAssumeCapacity(free);
+                }
+
+                state.resources = resources;
+                state.next_entity = self.next_entity;
+                frame.frame_number = self.frame_number;
+                frame.time = self.time;
+                frame.input = input;
+            }
+        };
+    };
+}
+
+/// Elements of a vector inside a snapshot buffer
+const Vector = struct {
+    start: usize = 0,
+    len: usize = 0,
+
+    fn int(self: Vector, bytes: []const u8, comptime T: type, index: usize) T {
+        return std.mem.readInt(T, bytes[self.start + @sizeOf(T) * index ..][0..@sizeOf(T)], .little);
+    }
+
+    fn slice(self: Vector, bytes: []const u8) []const u8 {
+        return bytes[self.start..][0..self.len];
+    }
+};
+
+fn decodeBytes(comptime T: type, bytes: []const u8) !T {
+    var stream = std.io.fixedBufferStream(bytes);
+    const value = schema.decodeValue(T, stream.reader()) catch return error.InvalidData;
+    if (stream.pos != bytes.len) return error.InvalidData;
+    return value;
+}
+
+/// Front-to-back FlatBuffers writer. Parents are written before their children, so every uoffset
+/// is positive; vtables precede their tables, which the signed vtable offset allows.
+const Builder = struct {
+    allocator: std.mem.Allocator,
+    bytes: std.ArrayListUnmanaged(u8) = .{},
+
+    fn pad(self: *Builder, alignment: usize) !void {
+        try self.bytes.appendNTimes(self.allocator, 0, std.mem.alignForward(usize, self.bytes.items.len, alignment) - self.bytes.items.len);
+    }
+
+    fn reserve(self: *Builder, len: usize) !usize {
+        const at = self.bytes.items.len;
+        try self.bytes.appendNTimes(self.allocator, 0, len);
+        return at;
+    }
+
+    fn put(self: *Builder, comptime T: type, at: usize, value: T) void {
+        std.mem.writeInt(T, self.bytes.items[at..][0..@sizeOf(T)], value, .little);
+    }
+
+    fn get(self: *Builder, comptime T: type, at: usize) T {
+        return std.mem.readInt(T, self.bytes.items[at..][0..@sizeOf(T)], .little);
+    }
+
+    /// Point the uoffset at `at` to `target`
+    fn link(self: *Builder, at: usize, target: usize) void {
+        self.put(u32, at, @intCast(target - at));
+    }
+
+    /// A vtable listing the fields' offsets followed by the zeroed table; returns the table
+    fn table(self: *Builder, fields: []const u16, size: u16) !usize {
+        try self.pad(2);
+        const vtable = try self.reserve(4 + 2 * fields.len);
+        self.put(u16, vtable, @intCast(4 + 2 * fields.len));
+        self.put(u16, vtable + 2, size);
+        for (fields, 0..) |field, i| self.put(u16, vtable + 4 + 2 * i, field);
+
+        try self.pad(8);
+        const start = try self.reserve(size);
+        self.put(i32, start, @intCast(start - vtable));
+        return start;
+    }
+
+    /// A zeroed vector linked from the offset at `at`; returns its first element
+    fn vector(self: *Builder, at: usize, len: usize, element_size: usize, alignment: usize) !usize {
+        // The length prefix sits right before the aligned elements
+        const aligned = @max(alignment, 4);
+        try self.bytes.appendNTimes(self.allocator, 0, (aligned - (self.bytes.items.len + 4) % aligned) % aligned);
+        const length = try self.reserve(4);
+        self.put(u32, length, @intCast(len));
+        self.link(at, length);
+        return self.reserve(len * element_size);
+    }
+
+    /// A [ubyte] vector holding `values` in the `schema` codec
+    fn bytesVector(self: *Builder, at: usize, comptime T: type, value: T) !void {
+        const start = try self.vector(at, 0, 1, 1);
+        try schema.encodeValue(T, value, self.bytes.writer(self.allocator));
+        self.put(u32, start - 4, @intCast(self.bytes.items.len - start));
+    }
+};
+
+fn readInt(bytes: []const u8, comptime T: type, at: usize) !T {
+    if (at > bytes.len or bytes.len - at < @sizeOf(T)) return error.InvalidData;
+    return std.mem.readInt(T, bytes[at..][0..@sizeOf(T)], .little);
+}
+
+/// Target of the uoffset at `at`
+fn offsetAt(bytes: []const u8, at: usize) !usize {
+    const target = at + try readInt(bytes, u32, at);
+    if (target >= bytes.len) return error.InvalidData;
+    return target;
+}
+
+/// Position of a table field, or null when the table's vtable leaves it out
+fn fieldAt(bytes: []const u8, table: usize, slot: usize) !?usize {
+    const vtable_offset = try readInt(bytes, i32, table);
+    const vtable = std.math.cast(usize, @as(i64, @intCast(table)) - vtable_offset) orelse return error.InvalidData;
+    const vtable_size = try readInt(bytes, u16, vtable);
+    if (4 + 2 * slot + 2 > vtable_size) return null;
+    const offset = try readInt(bytes, u16, vtable + 4 + 2 * slot);
+    if (offset == 0) return null;
+    if (offset >= try readInt(bytes, u16, vtable + 2)) return error.InvalidData;
+    return table + offset;
+}
+
+fn scalarField(bytes: []const u8, table: usize, slot: usize, comptime T: type) !?T {
+    const at = try fieldAt(bytes, table, slot) orelse return null;
+    return try readInt(bytes, T, at);
+}
+
+fn tableField(bytes: []const u8, table: usize, slot: usize) !?usize {
+    const at = try fieldAt(bytes, table, slot) orelse return null;
+    return try offsetAt(bytes, at);
+}
+
+/// A vector field whose elements all lie inside the buffer; absent vectors are empty
+fn vectorField(bytes: []const u8, table: usize, slot: usize, element_size: usize) !Vector {
+    const at = try fieldAt(bytes, table, slot) orelse return .{};
+    const length = try offsetAt(bytes, at);
+    const len = try readInt(bytes, u32, length);
+    const start = length + 4;
+    if (len > (bytes.len - start) / element_size) return error.InvalidData;
+    return .{ .start = start, .len = len };
+}
+
+fn unsupported(comptime T: type) noreturn {
+    @compileError("'" ++ @typeName(T) ++ "' has no FlatBuffers struct equivalent - snapshot such components with the schema codec");
+}
+
+/// Alignment of T in a FlatBuffers struct: scalars align to their size, structs to their widest
+/// field. Packed structs travel as their backing integer, like in the schema codec.
+fn layoutAlign(comptime T: type) usize {
+    return switch (@typeInfo(T)) {
+        .bool => 1,
+        .int => |info| switch (info.bits) {
+            8, 16, 32, 64 => info.bits / 8,
+            else => unsupported(T),
+        },
+        .float => |info| switch (info.bits) {
+            32, 64 => info.bits / 8,
+            else => unsupported(T),
+        },
+        .@"enum" => |info| layoutAlign(info.tag_type),
+        .array => |info| if (@typeInfo(info.child) == .array) unsupported(T) else layoutAlign(info.child),
+        .@"struct" => |info| if (info.backing_integer) |Backing| layoutAlign(Backing) else comptime blk: {
+            const fields = schema.runtimeFields(T);
+            if (fields.len == 0) unsupported(T);
+            var alignment = 1;
+            for (fields) |field| alignment = @max(alignment, layoutAlign(field.type));
+            break :blk alignment;
+        },
+        else => unsupported(T),
+    };
+}
+
+/// Size of T in a FlatBuffers struct: fields in declaration order, each at its alignment, the
+/// whole padded to the struct's alignment
+fn layoutSize(comptime T: type) usize {
+    return switch (@typeInfo(T)) {
+        .array => |info| info.len * layoutSize(info.child),
+        .@"struct" => |info| if (info.backing_integer) |Backing| layoutSize(Backing) else comptime blk: {
+            var size = 0;
+            for (schema.runtimeFields(T)) |field| {
+                size = std.mem.alignForward(usize, size, layoutAlign(field.type)) + layoutSize(field.type);
+            }
+            break :blk std.mem.alignForward(usize, size, layoutAlign(T));
+        },
+        else => layoutAlign(T),
+    };
+}
+
+/// Write `value` into `out` (layoutSize(T) zeroed bytes); padding stays zero
+fn writeStruct(comptime T: type, value: T, out: []u8) void {
+    switch (@typeInfo(T)) {
+        .bool => out[0] = @intFromBool(value),
+        .int => std.mem.writeInt(T, out[0..@sizeOf(T)], value, .little),
+        .float => |info| std.mem.writeInt(std.meta.Int(.unsigned, info.bits), out[0..@sizeOf(T)], @bitCast(value), .little),
+        .@"enum" => |info| writeStruct(info.tag_type, @intFromEnum(value), out),
+        .array => |info| for (value, 0..) |item, i| writeStruct(info.child, item, out[i * layoutSize(info.child) ..]),
+        .@"struct" => |info| {
+            if (info.backing_integer) |Backing| return writeStruct(Backing, @bitCast(value), out);
+            comptime var offset = 0;
+            inline for (schema.runtimeFields(T)) |field| {
+                offset = comptime std.mem.alignForward(usize, offset, layoutAlign(field.type));
+                writeStruct(field.type, @field(value, field.name), out[offset..]);
+                offset += comptime layoutSize(field.type);
+            }
+        },
+        else => unsupported(T),
+    }
+}
+
+fn readStruct(comptime T: type, bytes: []const u8) !T {
+    switch (@typeInfo(T)) {
+        .bool => return switch (bytes[0]) {
+            0 => false,
+            1 => true,
+            else => error.InvalidData,
+        },
+        .int => return std.mem.readInt(T, bytes[0..@sizeOf(T)], .little),
+        .float => |info| return @bitCast(std.mem.readInt(std.meta.Int(.unsigned, info.bits), bytes[0..@sizeOf(T)], .little)),
+        .@"enum" => |info| return std.meta.intToEnum(T, try readStruct(info.tag_type, bytes)) catch return error.InvalidData,
+        .array => |info| {
+            var result: T = undefined;
+            for (&result, 0..) |*item, i| item.* = try readStruct(info.child, bytes[i * layoutSize(info.child) ..]);
+            return result;
+        },
+        .@"struct" => |info| {
+            if (info.backing_integer) |Backing| return @bitCast(try readStruct(Backing, bytes));
+            var result: T = undefined;
+            comptime var offset = 0;
+            inline for (schema.runtimeFields(T)) |field| {
+                offset = comptime std.mem.alignForward(usize, offset, layoutAlign(field.type));
+                @field(result, field.name) = try readStruct(field.type, bytes[offset..]);
+                offset += comptime layoutSize(field.type);
+            }
+            return result;
+        },
+        else => unsupported(T),
+    }
+}
+
+/// Enums and structs T's layout refers to, dependencies first, appended to `seen`
+fn collectTypes(comptime T: type, comptime seen: []const type) []const type {
+    comptime {
+        for (seen) |known| {
+            if (known == T) return seen;
+        }
+        switch (@typeInfo(T)) {
+            .@"enum" => return seen ++ &[_]type{T},
+            .array => |info| return collectTypes(info.child, seen),
+            .@"struct" => |info| {
+                if (info.backing_integer != null) return seen;
+                var result = seen;
+                for (schema.runtimeFields(T)) |field| result = collectTypes(field.type, result);
+                // Tags have no values vector, so no struct either
+                if (schema.runtimeFields(T).len == 0) return result;
+                return result ++ &[_]type{T};
+            },
+            else => return seen,
+        }
+    }
+}
+
+/// Name of T as a field type in the .fbs
+fn typeName(comptime T: type) []const u8 {
+    return switch (@typeInfo(T)) {
+        .bool => "bool",
+        .int => |info| switch (info.bits) {
+            8 => if (info.signedness == .signed) "byte" else "ubyte",
+            16 => if (info.signedness == .signed) "short" else "ushort",
+            32 => if (info.signedness == .signed) "int" else "uint",
+            64 => if (info.signedness == .signed) "long" else "ulong",
+            else => unsupported(T),
+        },
+        .float => |info| if (info.bits == 32) "float" else "double",
+        .array => |info| std.fmt.comptimePrint("[{s}:{d}]", .{ typeName(info.child), info.len }),
+        .@"struct" => |info| if (info.backing_integer) |Backing| typeName(Backing) else ecs.shortTypeName(T),
+        else => ecs.shortTypeName(T),
+    };
+}
+
+fn writeDeclaration(comptime T: type, writer: anytype) !void {
+    switch (@typeInfo(T)) {
+        .@"enum" => |info| {
+            try writer.print("enum {s} : {s} {{\n", .{ typeName(T), typeName(info.tag_type) });
+            inline for (info.fields) |field| try writer.print("  {s} = {d},\n", .{ field.name, field.value });
+        },
+        else => {
+            try writer.print("struct {s} {{\n", .{typeName(T)});
+            inline for (comptime schema.runtimeFields(T)) |field| {
+                try writer.print("  {s}: {s};\n", .{ field.name, comptime typeName(field.type) });
+            }
+        },
+    }
+    try writer.writeAll("}\n\n");
+}
+
+/// "MoveTarget" -> "move_target", the FlatBuffers convention for field names
+fn snakeCase(comptime name: []const u8) []const u8 {
+    comptime {
+        var result: []const u8 = "";
+        for (name, 0..) |c, i| {
+            if (std.ascii.isUpper(c)) {
+                if (i > 0) result = result ++ "_";
+                result = result ++ &[_]u8{std.ascii.toLower(c)};
+            } else {
+                result = result ++ &[_]u8{c};
+            }
+        }
+        return result;
+    }
+}
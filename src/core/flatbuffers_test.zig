@@ -0,0 +1,150 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const flatbuffers = @import("flatbuffers.zig");
+const components = @import("components.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+
+const Team = enum(u8) { red, blue };
+
+const Crate = struct {
+    hp: u16 = 10,
+    team: Team = .red,
+    open: bool = false,
+    slots: [3]u8 = .{ 0, 0, 0 },
+    weight: f32 = 1,
+    owner: ecs.EntityHandle = .invalid,
+};
+
+const Frozen = struct {};
+
+const Wind = struct { speed: i32 = 0 };
+
+const TestInput = struct {
+    jump: bool = false,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Crate, Frozen },
+    .input = TestInput,
+    .resources = &.{Wind},
+    .max_entities = .small,
+});
+
+const Codec = flatbuffers.FlatBuffersCodec(TestECS);
+const Registry = schema.Registry(TestECS);
+
+fn populate(world: *TestECS) !ecs.EntityID {
+    world.update(.{ .jump = true }, 1.0 / 60.0, 2.5);
+    world.setResource(Wind{ .speed = -3 });
+    const frame = world.getFrame();
+    for (0..6) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Transform{ .position = fpVec2(@intCast(i), 2), .rotation = fp(1) });
+        if (i % 2 == 1) try frame.addComponent(entity, Crate{ .hp = @intCast(i * 100), .team = .blue, .open = true, .slots = .{ 1, 2, @intCast(i) }, .weight = 0.5 });
+        if (i == 4) try frame.addComponent(entity, Frozen{});
+    }
+    frame.destroyEntity(2);
+    return 3;
+}
+
+test "Snapshots read in place and load into a world" {
+    var source = try TestECS.init(testing.allocator);
+    defer source.deinit();
+    const crated = try populate(&source);
+    source.getFrame().getComponent(crated, Crate).?.owner = source.getFrame().handle(0);
+
+    const bytes = try Codec.encode(testing.allocator, source.getFrame());
+    defer testing.allocator.free(bytes);
+
+    const snapshot = try Codec.view(bytes);
+    try testing.expectEqual(source.getFrame().frame_number, snapshot.frame_number);
+    try testing.expectEqual(@as(usize, 5), snapshot.entityCount());
+    try testing.expectEqual(@as(usize, 3), snapshot.count(Crate));
+    try testing.expectEqual(@as(ecs.EntityID, 5), snapshot.entity(Crate, 2));
+    const crate = (try snapshot.get(Crate, crated)).?;
+    try testing.expectEqual(@as(u16, 300), crate.hp);
+    try testing.expectEqual(Team.blue, crate.team);
+    try testing.expectEqualSlices(u8, &.{ 1, 2, 3 }, &crate.slots);
+    try testing.expectEqual(source.getFrame().handle(0), crate.owner);
+    try testing.expectEqual(@as(?Crate, null), try snapshot.get(Crate, 0));
+    try testing.expect(try snapshot.get(Frozen, 4) != null);
+
+    var dest = try TestECS.init(testing.allocator);
+    defer dest.deinit();
+    for (0..9) |_| _ = try dest.getFrame().createEntity();
+    try snapshot.load(dest.getFrame());
+
+    const loaded = dest.getFrame();
+    try testing.expectEqual(Registry.hashFrame(source.getFrame()), Registry.hashFrame(loaded));
+    try testing.expectEqual(source.getFrame().checksum(), loaded.checksum());
+    try testing.expect(loaded.input.jump);
+    try testing.expectEqual(@as(i32, -3), dest.getResource(Wind).speed);
+    try testing.expectEqualSlices(ecs.EntityID, source.getFrame().state.free_entities.items, loaded.state.free_entities.items);
+    try testing.expectEqual(try source.getFrame().createEntity(), try loaded.createEntity());
+}
+
+test "Buffers follow the FlatBuffers layout of the generated schema" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    _ = try populate(&world);
+    const bytes = try Codec.encode(testing.allocator, world.getFrame());
+    defer testing.allocator.free(bytes);
+
+    // Root offset, file identifier, then the root table's vtable: 9 fields plus one per component
+    try testing.expectEqualStrings(flatbuffers.file_identifier, bytes[4..8]);
+    const root = std.mem.readInt(u32, bytes[0..4], .little);
+    try testing.expectEqual(@as(u32, 0), root % 8);
+    const vtable = root - @as(u32, @intCast(std.mem.readInt(i32, bytes[root..][0..4], .little)));
+    try testing.expectEqual(@as(u16, 4 + 2 * 12), std.mem.readInt(u16, bytes[vtable..][0..2], .little));
+    // schema_hash, the first field
+    const hash_at = root + std.mem.readInt(u16, bytes[vtable + 4 ..][0..2], .little);
+    try testing.expectEqual(Registry.schemaHash(), std.mem.readInt(u64, bytes[hash_at..][0..8], .little));
+
+    var text = std.ArrayList(u8).init(testing.allocator);
+    defer text.deinit();
+    try Codec.writeSchema(text.writer());
+    for ([_][]const u8{
+        "enum Team : ubyte {\n  red = 0,\n  blue = 1,\n}",
+        "struct FP {\n  raw_value: long;\n}",
+        "struct FPVector2 {\n  x: FP;\n  y: FP;\n}",
+        "struct Crate {\n  hp: ushort;\n  team: Team;\n  open: bool;\n  slots: [ubyte:3];\n  weight: float;\n  owner: ulong;\n}",
+        "table FrozenTable {\n  entities: [uint];\n}",
+        "table CrateTable {\n  entities: [uint];\n  values: [Crate];\n}",
+        "  crate: CrateTable;\n  frozen: FrozenTable;\n}",
+        "root_type Snapshot;\nfile_identifier \"RWFB\";",
+    }) |expected| {
+        try testing.expect(std.mem.indexOf(u8, text.items, expected) != null);
+    }
+    // Dependencies are declared before their users
+    try testing.expect(std.mem.indexOf(u8, text.items, "struct FP {").? < std.mem.indexOf(u8, text.items, "struct FPVector2 {").?);
+}
+
+test "Foreign, corrupt and mismatched buffers are refused" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    const crated = try populate(&world);
+    const bytes = try Codec.encode(testing.allocator, world.getFrame());
+    defer testing.allocator.free(bytes);
+
+    try testing.expectError(error.InvalidData, Codec.view(bytes[0..6]));
+    try testing.expectError(error.InvalidData, Codec.view(bytes[0 .. bytes.len - 40]));
+
+    const foreign = try testing.allocator.dupe(u8, bytes);
+    defer testing.allocator.free(foreign);
+    foreign[4] = 'X';
+    try testing.expectError(error.InvalidData, Codec.view(foreign));
+
+    // Crate.open sits at byte 3 of the struct; a bool other than 0 or 1 is caught on read
+    foreign[4] = 'R';
+    const snapshot = try Codec.view(foreign);
+    foreign[snapshot.values[1].start + 24 * 1 + 3] = 7;
+    try testing.expectError(error.InvalidData, snapshot.get(Crate, crated));
+
+    const OtherECS = ecs.ECS(.{ .components = &.{ Transform, Crate }, .input = TestInput, .max_entities = .small });
+    try testing.expectError(error.SchemaMismatch, flatbuffers.FlatBuffersCodec(OtherECS).view(bytes));
+}
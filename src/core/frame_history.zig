@@ -0,0 +1,135 @@
+const std = @import("std");
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
+
+/// The last `capacity` ticks of a world, one snapshot per tick in a ring - the rewind behind
+/// rollback netcode and instant replay.
+///
+/// `record` copies the live frame into the slot of its frame number (storages keep their
+/// capacity, so steady-state recording doesn't allocate). `rollbackTo` puts the world back to the
+/// end of an earlier tick and forgets everything after it; `resimulate` then replays corrected
+/// inputs through the systems, recording each tick again.
+///
+/// Usage:
+///   var history = try FrameHistory(GameECS).init(allocator, 16);
+///   defer history.deinit();
+///   while (running) {
+///       world.update(input, dt, time);
+///       try schedule.run(world.getFrame());
+///       try history.record(&world);
+///   }
+///   // A late input for tick 40 arrived
+///   try history.rollbackTo(&world, 39);
+///   try history.resimulate(&world, corrected_inputs, &schedule);
+pub fn FrameHistory(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const Input = @FieldType(EcsType.Frame, "input");
+
+        /// What one tick was simulated with
+        pub const InputFrame = struct {
+            input: Input,
+            delta_time: f32,
+        };
+
+        allocator: std.mem.Allocator,
+        slots: []EcsType.Frame,
+        /// Frame number of the newest snapshot
+        newest: u64 = 0,
+        /// Snapshots held, at most `slots.len`; they cover `newest - stored + 1 ..= newest`
+        stored: u64 = 0,
+
+        /// Receives rollbacks past the recorded window
+        logger: Logger = Logger.noop,
+        /// Rollbacks performed and ticks resimulated over the history's life
+        rollbacks: u64 = 0,
+        resimulated: u64 = 0,
+
+        pub fn init(allocator: std.mem.Allocator, slot_count: usize) !Self {
+            std.debug.assert(slot_count > 0);
+            const slots = try allocator.alloc(EcsType.Frame, slot_count);
+            errdefer allocator.free(slots);
+
+            var created: usize = 0;
+            errdefer for (slots[0..created]) |*slot| EcsType.freePreAllocatedFrame(slot);
+            for (slots) |*slot| {
+                slot.* = try EcsType.createPreAllocatedFrame(allocator);
+                created += 1;
+            }
+            return .{ .allocator = allocator, .slots = slots };
+        }
+
+        pub fn deinit(self: *Self) void {
+            for (self.slots) |*slot| EcsType.freePreAllocatedFrame(slot);
+            self.allocator.free(self.slots);
+        }
+
+        pub fn capacity(self: *const Self) usize {
+            return self.slots.len;
+        }
+
+        /// Snapshot the live frame. Frame numbers must continue from the newest snapshot -
+        /// after a `rollbackTo`, recording picks up from the restored tick.
+        pub fn record(self: *Self, world: *const EcsType) !void {
+            const frame_number = world.current_frame.frame_number;
+            if (self.stored > 0 and frame_number != self.newest + 1) return error.FrameOutOfOrder;
+
+            try world.copyFrameTo(&self.slots[frame_number % self.slots.len]);
+            self.newest = frame_number;
+            self.stored = @min(self.stored + 1, self.slots.len);
+        }
+
+        pub fn contains(self: *const Self, frame_number: u64) bool {
+            return self.stored > 0 and frame_number <= self.newest and self.newest - frame_number < self.stored;
+        }
+
+        /// Oldest tick `rollbackTo` can reach (null before the first `record`)
+        pub fn oldest(self: *const Self) ?u64 {
+            if (self.stored == 0) return null;
+            return self.newest + 1 - self.stored;
+        }
+
+        /// Snapshot of `frame_number`, for inspecting or diffing without restoring it
+        pub fn get(self: *Self, frame_number: u64) ?*EcsType.Frame {
+            if (!self.contains(frame_number)) return null;
+            return &self.slots[frame_number % self.slots.len];
+        }
+
+        /// Forget every snapshot; the next `record` may start from any frame number
+        pub fn clear(self: *Self) void {
+            self.stored = 0;
+        }
+
+        /// Restore the world to the end of tick `frame_number`. Snapshots after it describe a
+        /// future that no longer happens and are dropped.
+        pub fn rollbackTo(self: *Self, world: *EcsType, frame_number: u64) !void {
+            if (!self.contains(frame_number)) {
+                self.logger.warn("rollback_depth_exceeded", &.{
+                    field("frame", frame_number),
+                    field("newest", self.newest),
+                    field("stored", self.stored),
+                });
+                return error.FrameNotAvailable;
+            }
+
+            try world.restoreFrame(&self.slots[frame_number % self.slots.len]);
+            self.stored -= self.newest - frame_number;
+            self.newest = frame_number;
+            self.rollbacks += 1;
+        }
+
+        /// Simulate one tick per entry of `inputs` from the world's current frame, running
+        /// `systems` (anything with `run(*Frame) !void`, like a `Schedule`) and recording each tick
+        pub fn resimulate(self: *Self, world: *EcsType, inputs: []const InputFrame, systems: anytype) !void {
+            for (inputs) |tick| {
+                const time = world.current_frame.time + tick.delta_time;
+                world.update(tick.input, tick.delta_time, time);
+                try systems.run(world.getFrame());
+                try self.record(world);
+                self.resimulated += 1;
+            }
+        }
+    };
+}
@@ -0,0 +1,92 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+
+const Position = struct { x: i32 };
+const Velocity = struct { x: i32 };
+
+const TestInput = struct {
+    push: i32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+const History = FrameHistory(TestECS);
+
+// Moves every entity by its velocity plus the tick's push
+const Movement = struct {
+    fn run(_: *const Movement, frame: *TestECS.Frame) !void {
+        var query = try frame.query(&.{ Position, Velocity });
+        while (query.next()) |result| {
+            result.get(Position).x += result.get(Velocity).x + frame.input.push;
+        }
+    }
+};
+
+fn tick(world: *TestECS, history: *History, push: i32) !void {
+    world.update(.{ .push = push }, 1, world.getFrame().time + 1);
+    try (Movement{}).run(world.getFrame());
+    try history.record(world);
+}
+
+test "Rolling back restores a recorded tick and drops the ones after it" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var history = try History.init(testing.allocator, 4);
+    defer history.deinit();
+
+    const entity = try world.getFrame().createEntity();
+    try world.getFrame().addComponent(entity, Position{ .x = 0 });
+    try world.getFrame().addComponent(entity, Velocity{ .x = 1 });
+
+    for (0..6) |_| try tick(&world, &history, 0);
+    try testing.expectEqual(@as(i32, 6), world.getFrame().getComponent(entity, Position).?.x);
+
+    // Only the last four ticks are kept
+    try testing.expectEqual(@as(?u64, 3), history.oldest());
+    try testing.expectError(error.FrameNotAvailable, history.rollbackTo(&world, 2));
+    try testing.expectEqual(@as(i32, 4), history.get(4).?.getComponent(entity, Position).?.x);
+
+    try history.rollbackTo(&world, 4);
+    try testing.expectEqual(@as(u64, 4), world.getFrame().frame_number);
+    try testing.expectEqual(@as(i32, 4), world.getFrame().getComponent(entity, Position).?.x);
+    try testing.expect(!history.contains(5));
+    try testing.expectEqual(@as(?u64, 3), history.oldest());
+}
+
+test "Resimulating with corrected inputs rewrites the future" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var history = try History.init(testing.allocator, 8);
+    defer history.deinit();
+
+    const entity = try world.getFrame().createEntity();
+    try world.getFrame().addComponent(entity, Position{ .x = 0 });
+    try world.getFrame().addComponent(entity, Velocity{ .x = 1 });
+
+    for (0..5) |_| try tick(&world, &history, 0);
+
+    // Tick 3 should have pushed by 10 - rewind to the end of tick 2 and replay 3 to 5
+    try history.rollbackTo(&world, 2);
+    const corrected = [_]History.InputFrame{
+        .{ .input = .{ .push = 10 }, .delta_time = 1 },
+        .{ .input = .{}, .delta_time = 1 },
+        .{ .input = .{}, .delta_time = 1 },
+    };
+    try history.resimulate(&world, &corrected, &Movement{});
+
+    try testing.expectEqual(@as(u64, 5), world.getFrame().frame_number);
+    try testing.expectEqual(@as(i32, 15), world.getFrame().getComponent(entity, Position).?.x);
+    try testing.expectEqual(@as(i32, 13), history.get(3).?.getComponent(entity, Position).?.x);
+    try testing.expectEqual(@as(u64, 3), history.resimulated);
+
+    // A tick that skips ahead would leave a hole in the ring
+    world.update(.{}, 1, 0);
+    world.update(.{}, 1, 0);
+    try testing.expectError(error.FrameOutOfOrder, history.record(&world));
+}
@@ -0,0 +1,99 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+
+const EntityID = ecs.EntityID;
+const LocalTransform = components.LocalTransform;
+const WorldTransform = components.WorldTransform;
+
+/// Propagates LocalTransform chains into WorldTransform.
+///
+/// Entities need both components. Parents are processed before their children and entities at the
+/// same depth in ascending id order, so results never depend on storage layout. An entity is only
+/// recomputed when its local transform or its parent's world transform changed since the last
+/// propagate - moving a tank recomputes the tank and its turret, not the rest of the world.
+///
+/// A parent that is missing (destroyed, or without a WorldTransform) makes the entity a root.
+/// A parent with a WorldTransform but no LocalTransform is used as-is, which lets gameplay code
+/// drive the root of a hierarchy directly.
+pub fn TransformHierarchy(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        const Node = struct {
+            depth: u32,
+            entity: EntityID,
+
+            fn lessThan(_: void, a: Node, b: Node) bool {
+                if (a.depth != b.depth) return a.depth < b.depth;
+                return a.entity < b.entity;
+            }
+        };
+
+        order: std.ArrayList(Node),
+        /// Entities whose WorldTransform was recomputed during the last propagate
+        recomputed_last_propagate: u32,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .order = std.ArrayList(Node).init(allocator),
+                .recomputed_last_propagate = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.order.deinit();
+        }
+
+        /// Bring every WorldTransform up to date. Fails with `error.HierarchyCycle` if a parent
+        /// chain loops back on itself.
+        pub fn propagate(self: *Self, frame: *EcsType.Frame) !void {
+            self.order.clearRetainingCapacity();
+            var query = try frame.query(&.{ LocalTransform, WorldTransform });
+            while (query.nextFast()) |result| {
+                try self.order.append(.{ .depth = try depthOf(frame, result.entity), .entity = result.entity });
+            }
+            std.sort.pdq(Node, self.order.items, {}, Node.lessThan);
+
+            var recomputed: u32 = 0;
+            for (self.order.items) |node| {
+                const local = frame.getComponent(node.entity, LocalTransform).?.*;
+                const world = frame.getComponent(node.entity, WorldTransform).?;
+
+                var parent_position = FPVector2.ZERO;
+                var parent_rotation = FP.fromRaw(0);
+                if (frame.getComponent(local.parent, WorldTransform)) |parent| {
+                    parent_position = parent.position;
+                    parent_rotation = parent.rotation;
+                }
+
+                const clean = world.computed and world.source.eql(local) and
+                    world.parent_position.eq(parent_position) and world.parent_rotation.eq(parent_rotation);
+                if (clean) continue;
+
+                world.position = parent_position.add(local.position.rotate(parent_rotation));
+                world.rotation = parent_rotation.add(local.rotation);
+                world.source = local;
+                world.parent_position = parent_position;
+                world.parent_rotation = parent_rotation;
+                world.computed = true;
+                recomputed += 1;
+            }
+            self.recomputed_last_propagate = recomputed;
+        }
+
+        // Number of LocalTransform ancestors above the entity
+        fn depthOf(frame: *EcsType.Frame, entity: EntityID) !u32 {
+            var depth: u32 = 0;
+            var current = frame.getComponent(entity, LocalTransform).?.parent;
+            while (frame.getComponent(current, LocalTransform)) |parent| {
+                depth += 1;
+                if (depth > EcsType.max_entities) return error.HierarchyCycle;
+                current = parent.parent;
+            }
+            return depth;
+        }
+    };
+}
@@ -0,0 +1,118 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const hierarchy = @import("hierarchy.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const LocalTransform = components.LocalTransform;
+const WorldTransform = components.WorldTransform;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ LocalTransform, WorldTransform },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestHierarchy = hierarchy.TransformHierarchy(TestECS);
+
+fn addNode(frame: *TestECS.Frame, local: LocalTransform) !ecs.EntityID {
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, local);
+    try frame.addComponent(entity, WorldTransform{});
+    return entity;
+}
+
+fn expectWorldPosition(frame: *TestECS.Frame, entity: ecs.EntityID, expected: FPVector2) !void {
+    const actual = frame.getComponent(entity, WorldTransform).?.position;
+    if (!actual.approxEq(expected, fp(0.01))) {
+        std.debug.print("entity {d}: expected {}, got {}\n", .{ entity, expected, actual });
+        return error.TestExpectedEqual;
+    }
+}
+
+test "Children follow their parents and clean subtrees are skipped" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var transforms = TestHierarchy.init(testing.allocator);
+    defer transforms.deinit();
+
+    const frame = test_ecs.getFrame();
+    // Children get lower ids than their parents, so order must come from depth, not id
+    const barrel = try frame.createEntity();
+    const turret = try addNode(frame, .{ .position = fpVec2(0, 1) });
+    const tank = try addNode(frame, .{ .position = fpVec2(10, 0) });
+    const rock = try addNode(frame, .{ .position = fpVec2(-5, -5) });
+    try frame.addComponent(barrel, LocalTransform{ .position = fpVec2(2, 0), .parent = turret });
+    try frame.addComponent(barrel, WorldTransform{});
+    frame.getComponent(turret, LocalTransform).?.parent = tank;
+
+    try transforms.propagate(frame);
+    try testing.expectEqual(@as(u32, 4), transforms.recomputed_last_propagate);
+    try expectWorldPosition(frame, turret, fpVec2(10, 1));
+    try expectWorldPosition(frame, barrel, fpVec2(12, 1));
+    try expectWorldPosition(frame, rock, fpVec2(-5, -5));
+
+    try transforms.propagate(frame);
+    try testing.expectEqual(@as(u32, 0), transforms.recomputed_last_propagate);
+
+    // Moving the tank recomputes its subtree only
+    frame.getComponent(tank, LocalTransform).?.position = fpVec2(20, 0);
+    try transforms.propagate(frame);
+    try testing.expectEqual(@as(u32, 3), transforms.recomputed_last_propagate);
+    try expectWorldPosition(frame, barrel, fpVec2(22, 1));
+
+    // Moving a leaf recomputes just the leaf
+    frame.getComponent(barrel, LocalTransform).?.position = fpVec2(3, 0);
+    try transforms.propagate(frame);
+    try testing.expectEqual(@as(u32, 1), transforms.recomputed_last_propagate);
+    try expectWorldPosition(frame, barrel, fpVec2(23, 1));
+}
+
+test "Parent rotation carries children around" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var transforms = TestHierarchy.init(testing.allocator);
+    defer transforms.deinit();
+
+    const frame = test_ecs.getFrame();
+    const tank = try addNode(frame, .{ .position = fpVec2(10, 0), .rotation = FP.PI_2 });
+    const turret = try addNode(frame, .{ .position = fpVec2(2, 0), .rotation = FP.PI_2, .parent = tank });
+
+    try transforms.propagate(frame);
+    try expectWorldPosition(frame, turret, fpVec2(10, 2));
+    try testing.expect(frame.getComponent(turret, WorldTransform).?.rotation.sub(FP.PI).abs().lt(fp(0.01)));
+}
+
+test "Missing parents make roots and cycles are reported" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var transforms = TestHierarchy.init(testing.allocator);
+    defer transforms.deinit();
+
+    const frame = test_ecs.getFrame();
+    const parent = try addNode(frame, .{ .position = fpVec2(5, 5) });
+    const child = try addNode(frame, .{ .position = fpVec2(1, 0), .parent = parent });
+
+    try transforms.propagate(frame);
+    try expectWorldPosition(frame, child, fpVec2(6, 5));
+
+    frame.destroyEntity(parent);
+    try transforms.propagate(frame);
+    try expectWorldPosition(frame, child, fpVec2(1, 0));
+
+    const a = try addNode(frame, .{});
+    const b = try addNode(frame, .{ .parent = a });
+    frame.getComponent(a, LocalTransform).?.parent = b;
+    try testing.expectError(error.HierarchyCycle, transforms.propagate(frame));
+}
@@ -0,0 +1,132 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+const collision = @import("collision.zig");
+const casts = @import("raycast.zig");
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Collider = components.Collider;
+const Shape = components.Shape;
+
+/// A collider as it stood on one tick
+pub const Hitbox = struct {
+    entity: EntityID,
+    center: FPVector2,
+    shape: Shape,
+    layer: u32,
+};
+
+/// Lag compensation: keeps the last `window` ticks of hitboxes so hit tests can be run against
+/// the world the attacker actually saw.
+///
+/// A client that fired while seeing the world `latency_ticks` old is judged against hitboxes from
+/// that tick rather than the present, so targets are where the shooter aimed at them. Only
+/// Transform + Collider pairs are recorded - a few dozen bytes per collider per tick instead of a
+/// full frame snapshot - and ticks older than the window clamp to the oldest one kept, which caps
+/// how far a laggy client can reach into the past.
+///
+/// Usage:
+///   try history.record(frame);                     // once per tick, after movement
+///   try history.raycast(frame.frame_number, latency, shooter, ray, &hits);
+pub fn HitboxHistory(comptime window: u32) type {
+    if (window == 0) @compileError("HitboxHistory needs a window of at least one tick");
+
+    return struct {
+        const Self = @This();
+        const Snapshot = std.ArrayListUnmanaged(Hitbox);
+
+        allocator: std.mem.Allocator,
+        snapshots: [window]Snapshot,
+        ticks: [window]u64,
+        /// Slot the next record goes into
+        head: u32,
+        count: u32,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .allocator = allocator,
+                .snapshots = [_]Snapshot{.{}} ** window,
+                .ticks = [_]u64{0} ** window,
+                .head = 0,
+                .count = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            for (&self.snapshots) |*snapshot| snapshot.deinit(self.allocator);
+        }
+
+        /// Store every collider's hitbox for the frame's current tick
+        pub fn record(self: *Self, frame: anytype) !void {
+            const snapshot = &self.snapshots[self.head];
+            snapshot.clearRetainingCapacity();
+
+            var query = try frame.query(&.{ Transform, Collider });
+            while (query.nextFast()) |result| {
+                const collider = result.get(Collider).*;
+                try snapshot.append(self.allocator, .{
+                    .entity = result.entity,
+                    .center = collision.colliderCenter(result.get(Transform).*, collider),
+                    .shape = collider.shape,
+                    .layer = collider.layer,
+                });
+            }
+
+            self.ticks[self.head] = frame.frame_number;
+            self.head = (self.head + 1) % window;
+            self.count = @min(self.count + 1, window);
+        }
+
+        /// Hitboxes recorded for `tick`, or the closest tick kept when it is out of range
+        pub fn at(self: *const Self, tick: u64) ![]const Hitbox {
+            if (self.count == 0) return error.NoHistory;
+
+            // Walk back from the newest record to the first one at or before `tick`
+            var back: u32 = 0;
+            while (back + 1 < self.count) : (back += 1) {
+                if (self.ticks[self.slot(back)] <= tick) break;
+            }
+            return self.snapshots[self.slot(back)].items;
+        }
+
+        /// Raycast against the hitboxes the attacker saw `latency_ticks` before `current_tick`.
+        /// Hits are appended to `hits` ordered by distance; `attacker` is never hit.
+        pub fn raycast(self: *const Self, current_tick: u64, latency_ticks: u32, attacker: EntityID, ray: casts.Ray, hits: *std.ArrayList(casts.Hit)) !void {
+            const direction = ray.direction.normalize();
+            if (direction.eq(FPVector2.ZERO)) return error.ZeroDirection;
+
+            const start = hits.items.len;
+            for (try self.at(current_tick -| latency_ticks)) |hitbox| {
+                if (hitbox.entity == attacker or (hitbox.layer & ray.mask) == 0) continue;
+                const contact = casts.castAgainst(ray.origin, direction, FPVector2.ZERO, hitbox.center, hitbox.shape) orelse continue;
+                if (contact.distance.gt(ray.max_distance)) continue;
+
+                try hits.append(.{
+                    .entity = hitbox.entity,
+                    .distance = contact.distance,
+                    .point = ray.origin.add(direction.mul(contact.distance)),
+                    .normal = contact.normal,
+                });
+            }
+            std.sort.pdq(casts.Hit, hits.items[start..], {}, casts.Hit.lessThan);
+        }
+
+        /// Overlap test (melee swings, explosions) against the hitboxes seen `latency_ticks` ago.
+        /// Matching entities are appended to `hits` in ascending id order.
+        pub fn overlap(self: *const Self, current_tick: u64, latency_ticks: u32, attacker: EntityID, center: FPVector2, shape: Shape, mask: u32, hits: *std.ArrayList(EntityID)) !void {
+            for (try self.at(current_tick -| latency_ticks)) |hitbox| {
+                if (hitbox.entity == attacker or (hitbox.layer & mask) == 0) continue;
+                if (collision.shapesOverlap(center, shape, hitbox.center, hitbox.shape)) {
+                    try hits.append(hitbox.entity);
+                }
+            }
+        }
+
+        // Slot of the record `back` steps before the newest
+        fn slot(self: *const Self, back: u32) u32 {
+            return (self.head + window - 1 - back) % window;
+        }
+    };
+}
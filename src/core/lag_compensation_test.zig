@@ -0,0 +1,100 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const lag_compensation = @import("lag_compensation.zig");
+const raycast = @import("raycast.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Collider = components.Collider;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Collider },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const History = lag_compensation.HitboxHistory(8);
+
+// Target walks one unit right per tick: on tick t it stands at x = t
+fn runTicks(test_ecs: *TestECS, history: *History, target: ecs.EntityID, ticks: u64) !void {
+    const frame = test_ecs.getFrame();
+    for (0..ticks) |_| {
+        frame.getComponent(target, Transform).?.position = FPVector2.new(FP.fromInt(@as(i64, @intCast(frame.frame_number))), fp(0));
+        try history.record(frame);
+        frame.frame_number += 1;
+    }
+}
+
+test "Raycasts hit targets where the attacker saw them" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var history = History.init(testing.allocator);
+    defer history.deinit();
+
+    const frame = test_ecs.getFrame();
+    const shooter = try frame.createEntity();
+    const target = try frame.createEntity();
+    try frame.addComponent(shooter, Transform{ .position = fpVec2(3, -5) });
+    try frame.addComponent(shooter, Collider.circle(fp(0.5)));
+    try frame.addComponent(target, Transform{});
+    try frame.addComponent(target, Collider.circle(fp(0.5)));
+
+    try testing.expectError(error.NoHistory, history.at(0));
+    try runTicks(&test_ecs, &history, target, 10);
+    const now = frame.frame_number - 1;
+
+    // Straight up from the shooter, through x = 3
+    const ray = raycast.Ray{ .origin = fpVec2(3, -5), .direction = fpVec2(0, 1), .max_distance = fp(10) };
+    var hits = std.ArrayList(raycast.Hit).init(testing.allocator);
+    defer hits.deinit();
+
+    // In the present the target is at x = 9 - a miss, and the shooter never hits itself
+    try history.raycast(now, 0, shooter, ray, &hits);
+    try testing.expectEqual(@as(usize, 0), hits.items.len);
+
+    // Six ticks of latency: the shooter saw the target at x = 3
+    try history.raycast(now, 6, shooter, ray, &hits);
+    try testing.expectEqual(@as(usize, 1), hits.items.len);
+    try testing.expectEqual(target, hits.items[0].entity);
+    try testing.expectEqual(fp(4.5).raw_value, hits.items[0].distance.raw_value);
+}
+
+test "Overlap tests rewind and latency clamps to the window" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var history = History.init(testing.allocator);
+    defer history.deinit();
+
+    const frame = test_ecs.getFrame();
+    const attacker = try frame.createEntity();
+    const target = try frame.createEntity();
+    try frame.addComponent(attacker, Transform{ .position = fpVec2(2, 0) });
+    try frame.addComponent(attacker, Collider.box(fpVec2(0.5, 0.5)));
+    try frame.addComponent(target, Transform{});
+    try frame.addComponent(target, Collider.box(fpVec2(0.5, 0.5)));
+
+    try runTicks(&test_ecs, &history, target, 10);
+    const now = frame.frame_number - 1;
+    const swing = components.Shape{ .circle = fp(0.75) };
+
+    var hits = std.ArrayList(ecs.EntityID).init(testing.allocator);
+    defer hits.deinit();
+    try history.overlap(now, 0, attacker, fpVec2(2, 0), swing, std.math.maxInt(u32), &hits);
+    try testing.expectEqual(@as(usize, 0), hits.items.len);
+
+    // Only ticks 2..9 are kept, so 20 ticks of latency reaches back to tick 2 and no further
+    try history.overlap(now, 20, attacker, fpVec2(2, 0), swing, std.math.maxInt(u32), &hits);
+    try testing.expectEqualSlices(ecs.EntityID, &.{target}, hits.items);
+    hits.clearRetainingCapacity();
+    try history.overlap(now, 20, attacker, fpVec2(0, 0), swing, std.math.maxInt(u32), &hits);
+    try testing.expectEqual(@as(usize, 0), hits.items.len);
+}
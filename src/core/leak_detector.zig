@@ -0,0 +1,237 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+/// Finds entities that outlive their expected lifetime - usually forgotten despawn logic.
+///
+/// Entities are put in a lifetime class by the first classified component they receive
+/// (e.g. `Projectile` -> "projectile", at most 600 ticks). Every creation records its call site;
+/// every `sample_every`-th creation also captures a short stack so the report can show
+/// where long-lived entities came from.
+///
+/// Usage:
+///   var leaks = LeakDetector.init(allocator, &GameECS.component_names);
+///   defer leaks.deinit();
+///   try leaks.addClass(LeakDetector.lifetimeClass(GameECS, Projectile, "projectile", 10 * 60));
+///   try game_ecs.addObserver(leaks.observer());
+///   ...
+///   try leaks.writeReport(std.io.getStdErr().writer());
+///
+/// Frame restores (rollback) do not notify observers, so entities resurrected by a restore
+/// are not tracked until they are destroyed and created again.
+pub const LeakDetector = struct {
+    allocator: std.mem.Allocator,
+    component_names: []const []const u8,
+    classes: std.ArrayList(LifetimeClass),
+    live: std.AutoHashMap(ecs.EntityID, Entry),
+    current_tick: u64,
+    creations: u64,
+    enabled: bool,
+
+    /// Capture a stack for one in this many creations (1 = every creation, 0 = never)
+    sample_every: u32 = 16,
+    /// Limit for entities that never received a classified component (null = never reported)
+    default_max_ticks: ?u64 = null,
+
+    const Self = @This();
+
+    pub const STACK_DEPTH = 8;
+    pub const NO_CLASS: u8 = std.math.maxInt(u8);
+
+    /// Expected lifetime of entities holding a given component
+    pub const LifetimeClass = struct {
+        name: []const u8,
+        component: u8,
+        max_ticks: u64,
+    };
+
+    /// Tracking data for one live entity
+    pub const Entry = struct {
+        created_tick: u64,
+        call_site: usize,
+        class: u8 = NO_CLASS,
+        stack_len: u8 = 0,
+        stack: [STACK_DEPTH]usize = undefined,
+
+        pub fn stackTrace(self: *const Entry) []const usize {
+            return self.stack[0..self.stack_len];
+        }
+    };
+
+    /// An entity alive longer than its class allows
+    pub const Leak = struct {
+        entity: ecs.EntityID,
+        age_ticks: u64,
+        max_ticks: u64,
+        class_name: []const u8,
+        entry: *const Entry,
+    };
+
+    pub fn init(allocator: std.mem.Allocator, component_names: []const []const u8) Self {
+        return Self{
+            .allocator = allocator,
+            .component_names = component_names,
+            .classes = std.ArrayList(LifetimeClass).init(allocator),
+            .live = std.AutoHashMap(ecs.EntityID, Entry).init(allocator),
+            .current_tick = 0,
+            .creations = 0,
+            .enabled = true,
+        };
+    }
+
+    pub fn deinit(self: *Self) void {
+        self.classes.deinit();
+        self.live.deinit();
+    }
+
+    /// Lifetime class keyed by a component type of `EcsType`
+    pub fn lifetimeClass(comptime EcsType: type, comptime T: type, name: []const u8, max_ticks: u64) LifetimeClass {
+        return .{
+            .name = name,
+            .component = comptime @ctz(EcsType.componentMask(&.{T})),
+            .max_ticks = max_ticks,
+        };
+    }
+
+    /// Register a lifetime class. Earlier classes win when an entity matches several.
+    pub fn addClass(self: *Self, class: LifetimeClass) !void {
+        if (self.classes.items.len >= NO_CLASS) return error.TooManyClasses;
+        try self.classes.append(class);
+    }
+
+    /// Observer to attach to an ECS with `addObserver`
+    pub fn observer(self: *Self) ecs.Observer {
+        return .{ .ptr = self, .vtable = &observer_vtable };
+    }
+
+    const observer_vtable = ecs.Observer.VTable{
+        .onTick = onTick,
+        .onChange = onChange,
+    };
+
+    fn onTick(ptr: *anyopaque, tick: u64) void {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        self.current_tick = tick;
+    }
+
+    fn onChange(ptr: *anyopaque, change: ecs.StructuralChange) void {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        if (!self.enabled) return;
+
+        switch (change.kind) {
+            .create_entity => self.track(change),
+            .destroy_entity => _ = self.live.remove(change.entity),
+            .add_component => {
+                const entry = self.live.getPtr(change.entity) orelse return;
+                if (entry.class != NO_CLASS) return;
+                entry.class = self.classFor(change.component.?);
+            },
+            .remove_component => {},
+        }
+    }
+
+    fn track(self: *Self, change: ecs.StructuralChange) void {
+        var entry = Entry{
+            .created_tick = self.current_tick,
+            .call_site = change.call_site,
+        };
+
+        if (self.sample_every != 0 and self.creations % self.sample_every == 0) {
+            var trace = std.builtin.StackTrace{
+                .index = 0,
+                .instruction_addresses = &entry.stack,
+            };
+            std.debug.captureStackTrace(change.call_site, &trace);
+            entry.stack_len = @intCast(@min(trace.index, STACK_DEPTH));
+        }
+        self.creations += 1;
+
+        self.live.put(change.entity, entry) catch {
+            // Leak tracking must never take the simulation down - stop tracking instead
+            std.log.warn("Leak detector out of memory tracking {} entities, detection disabled", .{self.live.count()});
+            self.enabled = false;
+        };
+    }
+
+    fn classFor(self: *const Self, component: u8) u8 {
+        for (self.classes.items, 0..) |class, i| {
+            if (class.component == component) return @intCast(i);
+        }
+        return NO_CLASS;
+    }
+
+    fn limitFor(self: *const Self, entry: *const Entry) ?u64 {
+        if (entry.class == NO_CLASS) return self.default_max_ticks;
+        return self.classes.items[entry.class].max_ticks;
+    }
+
+    /// Name of a class id ("<unclassified>" for entities without a classified component)
+    pub fn className(self: *const Self, class: u8) []const u8 {
+        if (class == NO_CLASS or class >= self.classes.items.len) return "<unclassified>";
+        return self.classes.items[class].name;
+    }
+
+    /// Number of entities currently tracked
+    pub fn trackedCount(self: *const Self) u32 {
+        return self.live.count();
+    }
+
+    /// Iterate entities that have outlived their class at the current tick
+    pub fn leaks(self: *const Self) LeakIterator {
+        return .{ .detector = self, .inner = self.live.iterator() };
+    }
+
+    pub const LeakIterator = struct {
+        detector: *const Self,
+        inner: std.AutoHashMap(ecs.EntityID, Entry).Iterator,
+
+        pub fn next(self: *LeakIterator) ?Leak {
+            while (self.inner.next()) |kv| {
+                const entry = kv.value_ptr;
+                const limit = self.detector.limitFor(entry) orelse continue;
+                const age = self.detector.current_tick -| entry.created_tick;
+                if (age <= limit) continue;
+
+                return Leak{
+                    .entity = kv.key_ptr.*,
+                    .age_ticks = age,
+                    .max_ticks = limit,
+                    .class_name = self.detector.className(entry.class),
+                    .entry = entry,
+                };
+            }
+            return null;
+        }
+    };
+
+    /// Human-readable report: one line per leaked entity followed by a per-call-site summary
+    pub fn writeReport(self: *const Self, writer: anytype) !void {
+        var sites = std.AutoArrayHashMap(usize, u32).init(self.allocator);
+        defer sites.deinit();
+
+        var total: u32 = 0;
+        var iter = self.leaks();
+        while (iter.next()) |leak| {
+            total += 1;
+            try writer.print("entity {d} [{s}] alive {d} ticks (limit {d}), created on tick {d} at 0x{x}\n", .{
+                leak.entity,
+                leak.class_name,
+                leak.age_ticks,
+                leak.max_ticks,
+                leak.entry.created_tick,
+                leak.entry.call_site,
+            });
+            for (leak.entry.stackTrace()) |address| {
+                try writer.print("    at 0x{x}\n", .{address});
+            }
+
+            const site = try sites.getOrPut(leak.entry.call_site);
+            site.value_ptr.* = if (site.found_existing) site.value_ptr.* + 1 else 1;
+        }
+
+        try writer.print("{d} leaked of {d} tracked entities at tick {d}\n", .{ total, self.live.count(), self.current_tick });
+        var site_iter = sites.iterator();
+        while (site_iter.next()) |site| {
+            try writer.print("  {d:>6} created at 0x{x}\n", .{ site.value_ptr.*, site.key_ptr.* });
+        }
+    }
+};
@@ -0,0 +1,81 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const LeakDetector = @import("leak_detector.zig").LeakDetector;
+
+const Position = struct { x: f32, y: f32 };
+const Projectile = struct { damage: i32 };
+const Player = struct { id: u8 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Projectile, Player },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+test "Leak detector reports entities past their lifetime class" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var leaks = LeakDetector.init(testing.allocator, &TestECS.component_names);
+    defer leaks.deinit();
+    leaks.sample_every = 1;
+    try leaks.addClass(LeakDetector.lifetimeClass(TestECS, Projectile, "projectile", 10));
+    try test_ecs.addObserver(leaks.observer());
+
+    const frame = test_ecs.getFrame();
+
+    const player = try frame.createEntity();
+    try frame.addComponent(player, Player{ .id = 1 });
+
+    const despawned = try frame.createEntity();
+    try frame.addComponent(despawned, Projectile{ .damage = 5 });
+
+    const forgotten = try frame.createEntity();
+    try frame.addComponent(forgotten, Position{ .x = 0, .y = 0 });
+    try frame.addComponent(forgotten, Projectile{ .damage = 5 });
+
+    for (0..5) |_| test_ecs.update(.{}, 0.016, 0);
+    frame.destroyEntity(despawned);
+    for (0..10) |_| test_ecs.update(.{}, 0.016, 0);
+
+    try testing.expectEqual(@as(u32, 2), leaks.trackedCount());
+
+    var iter = leaks.leaks();
+    const leak = iter.next().?;
+    try testing.expectEqual(forgotten, leak.entity);
+    try testing.expectEqualStrings("projectile", leak.class_name);
+    try testing.expectEqual(@as(u64, 15), leak.age_ticks);
+    try testing.expect(leak.entry.call_site != 0);
+    try testing.expect(iter.next() == null);
+
+    var report = std.ArrayList(u8).init(testing.allocator);
+    defer report.deinit();
+    try leaks.writeReport(report.writer());
+    try testing.expect(std.mem.indexOf(u8, report.items, "entity 2 [projectile] alive 15 ticks (limit 10)") != null);
+    try testing.expect(std.mem.indexOf(u8, report.items, "1 leaked of 2 tracked entities at tick 15") != null);
+}
+
+test "Leak detector default limit covers unclassified entities" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var leaks = LeakDetector.init(testing.allocator, &TestECS.component_names);
+    defer leaks.deinit();
+    try test_ecs.addObserver(leaks.observer());
+
+    const frame = test_ecs.getFrame();
+    _ = try frame.createEntity();
+    for (0..3) |_| test_ecs.update(.{}, 0.016, 0);
+
+    var iter = leaks.leaks();
+    try testing.expect(iter.next() == null);
+
+    leaks.default_max_ticks = 2;
+    iter = leaks.leaks();
+    try testing.expectEqualStrings("<unclassified>", iter.next().?.class_name);
+}
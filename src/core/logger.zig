@@ -0,0 +1,110 @@
+const std = @import("std");
+
+pub const Level = enum {
+    debug,
+    info,
+    warn,
+    err,
+};
+
+/// Value of one structured field
+pub const Value = union(enum) {
+    int: i64,
+    uint: u64,
+    float: f64,
+    boolean: bool,
+    string: []const u8,
+
+    pub fn format(self: Value, comptime _: []const u8, _: std.fmt.FormatOptions, writer: anytype) !void {
+        switch (self) {
+            .int => |v| try writer.print("{d}", .{v}),
+            .uint => |v| try writer.print("{d}", .{v}),
+            .float => |v| try writer.print("{d}", .{v}),
+            .boolean => |v| try writer.print("{}", .{v}),
+            .string => |v| try writer.print("{s}", .{v}),
+        }
+    }
+};
+
+pub const Field = struct {
+    key: []const u8,
+    value: Value,
+};
+
+/// Field from any integer, float, bool or string value
+pub fn field(key: []const u8, value: anytype) Field {
+    const T = @TypeOf(value);
+    const converted: Value = switch (@typeInfo(T)) {
+        .comptime_int => if (value < 0) .{ .int = value } else .{ .uint = value },
+        .int => |info| if (info.signedness == .signed) .{ .int = value } else .{ .uint = value },
+        .comptime_float, .float => .{ .float = value },
+        .bool => .{ .boolean = value },
+        else => .{ .string = value },
+    };
+    return .{ .key = key, .value = converted };
+}
+
+/// Where the engine sends diagnostics - entity limit near, rollback depth exceeded, desync
+/// suspected. Events are stable snake_case identifiers with key/value fields, so a host can route
+/// them into its own logging, metrics or alerting without parsing text.
+///
+/// Every component that logs defaults to `Logger.noop`; hand it `Logger.std_log` or your own.
+///
+/// Usage:
+///   schedule.logger = Logger.std_log;
+///   rollback.logger = .{ .ptr = &host_log, .vtable = &.{ .log = HostLog.write } };
+pub const Logger = struct {
+    ptr: *anyopaque,
+    vtable: *const VTable,
+
+    pub const VTable = struct {
+        log: *const fn (ptr: *anyopaque, level: Level, event: []const u8, fields: []const Field) void,
+    };
+
+    /// Drops everything
+    pub const noop = Logger{ .ptr = undefined, .vtable = &.{ .log = noopLog } };
+
+    /// Forwards to `std.log` (scope `.rewind`) as "event key=value ..."
+    pub const std_log = Logger{ .ptr = undefined, .vtable = &.{ .log = stdLog } };
+
+    pub fn log(self: Logger, level: Level, event: []const u8, fields: []const Field) void {
+        self.vtable.log(self.ptr, level, event, fields);
+    }
+
+    pub fn info(self: Logger, event: []const u8, fields: []const Field) void {
+        self.log(.info, event, fields);
+    }
+
+    pub fn warn(self: Logger, event: []const u8, fields: []const Field) void {
+        self.log(.warn, event, fields);
+    }
+
+    pub fn err(self: Logger, event: []const u8, fields: []const Field) void {
+        self.log(.err, event, fields);
+    }
+
+    fn noopLog(_: *anyopaque, _: Level, _: []const u8, _: []const Field) void {}
+
+    fn stdLog(_: *anyopaque, level: Level, event: []const u8, fields: []const Field) void {
+        var buffer: [512]u8 = undefined;
+        const line = formatLine(&buffer, event, fields);
+        const scoped = std.log.scoped(.rewind);
+        switch (level) {
+            .debug => scoped.debug("{s}", .{line}),
+            .info => scoped.info("{s}", .{line}),
+            .warn => scoped.warn("{s}", .{line}),
+            .err => scoped.err("{s}", .{line}),
+        }
+    }
+};
+
+/// "event key=value key=value" into `buffer`, cut off when it does not fit
+pub fn formatLine(buffer: []u8, event: []const u8, fields: []const Field) []const u8 {
+    var stream = std.io.fixedBufferStream(buffer);
+    const writer = stream.writer();
+    writer.writeAll(event) catch return stream.getWritten();
+    for (fields) |f| {
+        writer.print(" {s}={}", .{ f.key, f.value }) catch break;
+    }
+    return stream.getWritten();
+}
@@ -0,0 +1,146 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const Field = logger_module.Field;
+const Level = logger_module.Level;
+const field = logger_module.field;
+const Schedule = @import("schedule.zig").Schedule;
+const NetcodeRollback = @import("rollback.zig").NetcodeRollback;
+const ManualClock = @import("clock.zig").ManualClock;
+
+const Position = struct { x: i32, y: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Position},
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+// Host-side sink keeping each event as its formatted line
+const Capture = struct {
+    levels: std.BoundedArray(Level, 8) = .{},
+    lines: [8][128]u8 = undefined,
+    lengths: [8]usize = undefined,
+
+    fn logger(self: *Capture) Logger {
+        return .{ .ptr = self, .vtable = &.{ .log = write } };
+    }
+
+    fn write(ptr: *anyopaque, level: Level, event: []const u8, fields: []const Field) void {
+        const self: *Capture = @ptrCast(@alignCast(ptr));
+        const index = self.levels.len;
+        self.levels.append(level) catch return;
+        self.lengths[index] = logger_module.formatLine(&self.lines[index], event, fields).len;
+    }
+
+    fn line(self: *const Capture, index: usize) []const u8 {
+        return self.lines[index][0..self.lengths[index]];
+    }
+};
+
+fn failing(_: *TestECS.Frame) anyerror!void {
+    return error.Broken;
+}
+
+fn idle(_: *TestECS.Frame) anyerror!void {}
+
+test "Fields format as key=value" {
+    var buffer: [128]u8 = undefined;
+    const line = logger_module.formatLine(&buffer, "checkpoint", &.{
+        field("tick", @as(u64, 42)),
+        field("delta", -3),
+        field("ratio", 0.5),
+        field("ok", true),
+        field("system", "movement"),
+    });
+    try testing.expectEqualStrings("checkpoint tick=42 delta=-3 ratio=0.5 ok=true system=movement", line);
+
+    // The default sink accepts anything
+    Logger.noop.warn("ignored", &.{field("tick", 1)});
+}
+
+test "Schedules report the entity limit and failing systems" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    var capture = Capture{};
+    var schedule = Schedule(TestECS).init(testing.allocator);
+    defer schedule.deinit();
+    schedule.logger = capture.logger();
+    try schedule.add(.{ .name = "idle", .run = idle });
+
+    for (0..57) |_| _ = try frame.createEntity();
+    try schedule.run(frame);
+    try testing.expectEqual(@as(usize, 0), capture.levels.len);
+
+    // Crossing 90% of 64 warns once, not every tick
+    _ = try frame.createEntity();
+    try schedule.run(frame);
+    try schedule.run(frame);
+    try testing.expectEqual(@as(usize, 1), capture.levels.len);
+    try testing.expectEqual(Level.warn, capture.levels.get(0));
+    try testing.expectEqualStrings("entity_limit_near entities=58 limit=64", capture.line(0));
+
+    try schedule.add(.{ .name = "broken", .run = failing });
+    try testing.expectError(error.Broken, schedule.run(frame));
+    try testing.expectEqual(Level.err, capture.levels.get(1));
+    try testing.expectEqualStrings("system_failed system=broken error=Broken", capture.line(1));
+}
+
+var profile_clock = ManualClock{};
+
+fn timed(_: *TestECS.Frame) anyerror!void {
+    profile_clock.advance(2500);
+}
+
+test "Profiled schedules log a line per system every interval" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    try frame.addComponent(try frame.createEntity(), Position{ .x = 0, .y = 0 });
+
+    var capture = Capture{};
+    var schedule = Schedule(TestECS).init(testing.allocator);
+    defer schedule.deinit();
+    schedule.logger = capture.logger();
+    schedule.profile = true;
+    schedule.clock = profile_clock.clock();
+    schedule.profile_log_interval = 3;
+    try schedule.add(.{ .name = "timed", .run = timed, .writes = &.{Position} });
+    try schedule.add(.{ .name = "idle", .run = idle });
+
+    for (0..2) |_| try schedule.run(frame);
+    try testing.expectEqual(@as(usize, 0), capture.levels.len);
+    try schedule.run(frame);
+    try testing.expectEqual(@as(usize, 2), capture.levels.len);
+    try testing.expectEqual(Level.info, capture.levels.get(0));
+    try testing.expectEqualStrings("system_profile system=timed calls=3 avg_ns=2500 max_ns=2500 entities=1", capture.line(0));
+    try testing.expectEqualStrings("system_profile system=idle calls=3 avg_ns=0 max_ns=0 entities=1", capture.line(1));
+}
+
+test "Rollback reports depth overruns and desyncs" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var capture = Capture{};
+    const rollback = try testing.allocator.create(NetcodeRollback(TestECS, 4, 16 * 1024));
+    defer testing.allocator.destroy(rollback);
+    rollback.* = NetcodeRollback(TestECS, 4, 16 * 1024).init();
+    rollback.logger = capture.logger();
+
+    try rollback.saveFrame(&test_ecs);
+    try testing.expectError(error.FrameNotAvailable, rollback.restoreToFrame(&test_ecs, 3));
+    try testing.expectEqualStrings("rollback_depth_exceeded frames_back=3 frames_stored=1 window=4", capture.line(0));
+
+    try testing.expect(rollback.confirmHash(7, 0xabc, 0xabc));
+    try testing.expect(!rollback.confirmHash(8, 1, 2));
+    try testing.expectEqual(@as(u32, 1), rollback.desyncs);
+    try testing.expectEqualStrings("desync_suspected tick=8 local_hash=1 remote_hash=2", capture.line(1));
+}
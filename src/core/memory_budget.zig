@@ -0,0 +1,249 @@
+const std = @import("std");
+
+/// What a tracked allocation is for
+pub const Category = enum {
+    /// Component storages, entity pools, spatial grids
+    pools,
+    /// Rollback history, replay buffers, pipelined snapshots
+    history,
+    /// Per-tick event and message buffers
+    events,
+};
+
+const category_count = @typeInfo(Category).@"enum".fields.len;
+
+pub const Limits = struct {
+    /// Live bytes across every category
+    total: ?usize = null,
+    /// Bytes newly allocated between two frame boundaries
+    per_frame: ?usize = null,
+    /// Fail allocations that would cross `total` (error.OutOfMemory for the caller) instead of
+    /// letting the frame finish and the policies catch up at its end
+    refuse_over_total: bool = false,
+};
+
+/// Corrective action run at a frame boundary while the budget is exceeded. Frees what it can
+/// (through the tracked allocators) and returns; policies run in registration order until the
+/// budget holds again, so register the cheapest losses first.
+pub const Policy = struct {
+    name: []const u8,
+    context: *anyopaque,
+    apply: *const fn (context: *anyopaque, budget: *MemoryBudget) void,
+};
+
+pub const max_policies = 8;
+
+/// Memory budget for a world and the systems around it.
+///
+/// Hand `allocator(.pools)` to the world, `allocator(.history)` to rollback and replay buffers and
+/// `allocator(.events)` to event queues; the budget counts live and per-frame bytes for each. Attach
+/// it with `setMemoryBudget` and every `update` closes a budget frame: when live bytes exceed
+/// `limits.total` or the frame allocated more than `limits.per_frame`, the registered policies run
+/// - shrink the history depth, drop presentation-only components - instead of letting memory grow
+/// unbounded. Counters are not synchronized; use it from the tick thread.
+///
+/// Usage:
+///   var budget = MemoryBudget.init(std.heap.page_allocator, .{ .total = 64 << 20 });
+///   var world = try GameECS.init(budget.allocator(.pools));
+///   world.setMemoryBudget(&budget);
+///   var drop_sprites = DropComponents(GameECS, Sprite){ .world = &world };
+///   try budget.addPolicy(drop_sprites.policy());
+pub const MemoryBudget = struct {
+    const Self = @This();
+
+    const Tracker = struct {
+        budget: *MemoryBudget,
+        category: Category,
+    };
+
+    child: std.mem.Allocator,
+    limits: Limits,
+    trackers: [category_count]Tracker = undefined,
+
+    /// Live bytes per category
+    live: [category_count]usize = [_]usize{0} ** category_count,
+    /// Highest total of live bytes seen
+    peak: usize = 0,
+    /// Bytes allocated since the last frame boundary
+    frame_bytes: usize = 0,
+    /// Bytes the last closed frame allocated
+    last_frame_bytes: usize = 0,
+    /// Allocations refused under `refuse_over_total`
+    refused: u32 = 0,
+    /// Frames that closed over budget
+    frames_over: u32 = 0,
+
+    policies: std.BoundedArray(Policy, max_policies) = .{},
+    /// How often each policy ran (indexed like `policies`)
+    policy_runs: [max_policies]u32 = [_]u32{0} ** max_policies,
+
+    pub fn init(child: std.mem.Allocator, limits: Limits) Self {
+        return .{ .child = child, .limits = limits };
+    }
+
+    /// Allocator charging `category`. The budget must not move while it is in use.
+    pub fn allocator(self: *Self, category: Category) std.mem.Allocator {
+        const tracker = &self.trackers[@intFromEnum(category)];
+        tracker.* = .{ .budget = self, .category = category };
+        return .{
+            .ptr = tracker,
+            .vtable = &.{
+                .alloc = alloc,
+                .resize = resize,
+                .remap = remap,
+                .free = free,
+            },
+        };
+    }
+
+    pub fn addPolicy(self: *Self, policy: Policy) !void {
+        self.policies.append(policy) catch return error.TooManyPolicies;
+    }
+
+    pub fn liveBytes(self: *const Self, category: Category) usize {
+        return self.live[@intFromEnum(category)];
+    }
+
+    pub fn totalBytes(self: *const Self) usize {
+        var total: usize = 0;
+        for (self.live) |bytes| total += bytes;
+        return total;
+    }
+
+    /// Live bytes as a fraction of `limits.total` (0 without a total limit)
+    pub fn utilization(self: *const Self) f64 {
+        const total = self.limits.total orelse return 0;
+        if (total == 0) return 0;
+        return @as(f64, @floatFromInt(self.totalBytes())) / @as(f64, @floatFromInt(total));
+    }
+
+    /// Bytes allocated in the frame so far as a fraction of `limits.per_frame` (0 without one)
+    pub fn frameUtilization(self: *const Self) f64 {
+        const per_frame = self.limits.per_frame orelse return 0;
+        if (per_frame == 0) return 0;
+        return @as(f64, @floatFromInt(self.frame_bytes)) / @as(f64, @floatFromInt(per_frame));
+    }
+
+    pub fn isOverBudget(self: *const Self) bool {
+        if (self.limits.total) |total| {
+            if (self.totalBytes() > total) return true;
+        }
+        if (self.limits.per_frame) |per_frame| {
+            if (self.frame_bytes > per_frame) return true;
+        }
+        return false;
+    }
+
+    /// Close the frame: run policies while over budget, then start counting the next frame.
+    /// Called by `update` on worlds with a budget attached.
+    pub fn endFrame(self: *Self) void {
+        if (self.isOverBudget()) {
+            self.frames_over += 1;
+            for (self.policies.constSlice(), 0..) |policy, i| {
+                policy.apply(policy.context, self);
+                self.policy_runs[i] += 1;
+                // Bytes the frame already allocated can't be taken back - stop once live bytes fit
+                if (!self.isOverTotal()) break;
+            }
+        }
+        self.last_frame_bytes = self.frame_bytes;
+        self.frame_bytes = 0;
+    }
+
+    fn isOverTotal(self: *const Self) bool {
+        const total = self.limits.total orelse return false;
+        return self.totalBytes() > total;
+    }
+
+    fn charge(self: *Self, category: Category, len: usize) void {
+        self.live[@intFromEnum(category)] += len;
+        self.frame_bytes += len;
+        self.peak = @max(self.peak, self.totalBytes());
+    }
+
+    fn release(self: *Self, category: Category, len: usize) void {
+        self.live[@intFromEnum(category)] -= len;
+    }
+
+    // Whether growing by `len` bytes must be refused
+    fn refuses(self: *Self, len: usize) bool {
+        if (!self.limits.refuse_over_total) return false;
+        const total = self.limits.total orelse return false;
+        if (self.totalBytes() + len <= total) return false;
+        self.refused += 1;
+        return true;
+    }
+
+    fn alloc(ctx: *anyopaque, len: usize, alignment: std.mem.Alignment, ret_addr: usize) ?[*]u8 {
+        const tracker: *Tracker = @ptrCast(@alignCast(ctx));
+        const self = tracker.budget;
+        if (self.refuses(len)) return null;
+        const memory = self.child.rawAlloc(len, alignment, ret_addr) orelse return null;
+        self.charge(tracker.category, len);
+        return memory;
+    }
+
+    fn resize(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, new_len: usize, ret_addr: usize) bool {
+        const tracker: *Tracker = @ptrCast(@alignCast(ctx));
+        const self = tracker.budget;
+        if (new_len > memory.len and self.refuses(new_len - memory.len)) return false;
+        if (!self.child.rawResize(memory, alignment, new_len, ret_addr)) return false;
+        self.account(tracker.category, memory.len, new_len);
+        return true;
+    }
+
+    fn remap(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, new_len: usize, ret_addr: usize) ?[*]u8 {
+        const tracker: *Tracker = @ptrCast(@alignCast(ctx));
+        const self = tracker.budget;
+        if (new_len > memory.len and self.refuses(new_len - memory.len)) return null;
+        const remapped = self.child.rawRemap(memory, alignment, new_len, ret_addr) orelse return null;
+        self.account(tracker.category, memory.len, new_len);
+        return remapped;
+    }
+
+    fn free(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, ret_addr: usize) void {
+        const tracker: *Tracker = @ptrCast(@alignCast(ctx));
+        tracker.budget.child.rawFree(memory, alignment, ret_addr);
+        tracker.budget.release(tracker.category, memory.len);
+    }
+
+    fn account(self: *Self, category: Category, old_len: usize, new_len: usize) void {
+        if (new_len > old_len) {
+            self.charge(category, new_len - old_len);
+        } else {
+            self.release(category, old_len - new_len);
+        }
+    }
+};
+
+/// Policy dropping one presentation-only component type (sprites, particles, debug labels) from
+/// every entity of the live frame and returning its storage memory. Systems that need it add it
+/// back once memory allows.
+pub fn DropComponents(comptime EcsType: type, comptime T: type) type {
+    return struct {
+        const Self = @This();
+
+        world: *EcsType,
+        /// Components dropped over all runs
+        dropped: u32 = 0,
+
+        pub fn policy(self: *Self) Policy {
+            return .{ .name = "drop " ++ @typeName(T), .context = self, .apply = apply };
+        }
+
+        fn apply(context: *anyopaque, _: *MemoryBudget) void {
+            const self: *Self = @ptrCast(@alignCast(context));
+            const frame = self.world.getFrame();
+            const storage = frame.getComponentStorage(T);
+            // Removing from the back keeps the dense array from shuffling
+            while (storage.dense_entities.items.len > 0) {
+                const entity = storage.dense_entities.items[storage.dense_entities.items.len - 1];
+                _ = frame.removeComponent(entity, T);
+                self.dropped += 1;
+            }
+            storage.dense.clearAndFree();
+            storage.dense_entities.clearAndFree();
+            storage.changed_ticks.clearAndFree();
+        }
+    };
+}
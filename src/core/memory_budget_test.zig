@@ -0,0 +1,100 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const memory_budget = @import("memory_budget.zig");
+const MemoryBudget = memory_budget.MemoryBudget;
+const DropComponents = memory_budget.DropComponents;
+
+const Position = struct { x: i32, y: i32 };
+const Sprite = struct { frame: u32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Sprite },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+// Rollback-style history that halves its depth when told to
+const History = struct {
+    frames: std.ArrayList([256]u8),
+    depth: usize,
+
+    fn push(self: *History) !void {
+        if (self.frames.items.len == self.depth) _ = self.frames.orderedRemove(0);
+        try self.frames.append(undefined);
+    }
+
+    fn shrink(context: *anyopaque, _: *MemoryBudget) void {
+        const self: *History = @ptrCast(@alignCast(context));
+        self.depth = @max(1, self.depth / 2);
+        const excess = self.frames.items.len -| self.depth;
+        self.frames.replaceRangeAssumeCapacity(0, excess, &.{});
+        self.frames.shrinkAndFree(self.depth);
+    }
+};
+
+test "Worlds over budget drop presentation components" {
+    var budget = MemoryBudget.init(testing.allocator, .{});
+    var world = try TestECS.init(budget.allocator(.pools));
+    defer world.deinit();
+    world.setMemoryBudget(&budget);
+
+    var drop_sprites = DropComponents(TestECS, Sprite){ .world = &world };
+    try budget.addPolicy(drop_sprites.policy());
+
+    const frame = world.getFrame();
+    for (0..32) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        try frame.addComponent(entity, Sprite{ .frame = @intCast(i) });
+    }
+    const with_sprites = budget.liveBytes(.pools);
+    try testing.expect(with_sprites > 0);
+
+    // No limits yet - nothing happens
+    world.update(.{}, 0.016, 0.016);
+    try testing.expectEqual(@as(u32, 0), drop_sprites.dropped);
+    try testing.expect(budget.last_frame_bytes > 0);
+    try testing.expectEqual(@as(usize, 0), budget.frame_bytes);
+
+    budget.limits.total = with_sprites - 1;
+    try testing.expect(budget.utilization() > 1.0);
+    world.update(.{}, 0.016, 0.032);
+
+    try testing.expectEqual(@as(u32, 32), drop_sprites.dropped);
+    try testing.expectEqual(@as(u32, 1), budget.frames_over);
+    try testing.expect(frame.getComponent(0, Sprite) == null);
+    try testing.expect(frame.getComponent(0, Position) != null);
+    try testing.expect(budget.liveBytes(.pools) < with_sprites);
+    try testing.expect(budget.utilization() <= 1.0);
+}
+
+test "Per-frame overshoot shrinks history and hard limits refuse" {
+    var budget = MemoryBudget.init(testing.allocator, .{ .per_frame = 2048 });
+
+    var history = History{ .frames = std.ArrayList([256]u8).init(budget.allocator(.history)), .depth = 16 };
+    defer history.frames.deinit();
+    try budget.addPolicy(.{ .name = "shrink history", .context = &history, .apply = History.shrink });
+
+    for (0..16) |_| try history.push();
+    try testing.expect(budget.frameUtilization() > 1.0);
+    budget.endFrame();
+
+    try testing.expectEqual(@as(usize, 8), history.depth);
+    try testing.expectEqual(@as(usize, 8), history.frames.items.len);
+    try testing.expectEqual(@as(u32, 1), budget.policy_runs[0]);
+    try testing.expectEqual(@as(usize, 8 * 256), budget.liveBytes(.history));
+
+    // Hard total: the allocation fails instead of landing
+    budget.limits = .{ .total = 4096, .refuse_over_total = true };
+    const events = budget.allocator(.events);
+    const batch = try events.alloc(u8, 1024);
+    try testing.expectError(error.OutOfMemory, events.alloc(u8, 2048));
+    try testing.expectEqual(@as(u32, 1), budget.refused);
+    events.free(batch);
+    try testing.expectEqual(@as(usize, 0), budget.liveBytes(.events));
+}
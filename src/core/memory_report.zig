@@ -0,0 +1,130 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+/// Memory held by one component type's storage
+pub const ComponentMemory = struct {
+    name: []const u8,
+    count: u32,
+    capacity: u32,
+    component_size: usize,
+    /// Dense component array (allocated capacity)
+    dense_bytes: usize,
+    /// entityToIndex table plus the index-to-entity and change tick arrays
+    index_bytes: usize,
+    /// Membership and dirty bitsets
+    bitset_bytes: usize,
+
+    pub fn totalBytes(self: ComponentMemory) usize {
+        return self.dense_bytes + self.index_bytes + self.bitset_bytes;
+    }
+
+    /// Percentage of allocated dense slots in use (100 when nothing is allocated)
+    pub fn utilizationPercent(self: ComponentMemory) f64 {
+        if (self.capacity == 0) return 100.0;
+        return @as(f64, @floatFromInt(self.count)) / @as(f64, @floatFromInt(self.capacity)) * 100.0;
+    }
+};
+
+/// Rollback history buffer usage
+pub const HistoryMemory = struct {
+    total_bytes: usize,
+    used_bytes: usize,
+    frames_stored: u32,
+    avg_frame_bytes: usize,
+};
+
+/// Snapshot of where an ECS world's memory goes: per-component storage, entity bookkeeping and
+/// (optionally) the rollback history buffer.
+pub fn MemoryReport(comptime EcsType: type) type {
+    const component_count = EcsType.component_names.len;
+
+    return struct {
+        const Self = @This();
+
+        components: [component_count]ComponentMemory,
+        /// Active-entity bitset, counters, entity generations and pre-allocated query bitsets
+        entity_bytes: usize,
+        history: ?HistoryMemory = null,
+
+        pub fn collect(world: *const EcsType) Self {
+            const state = &world.current_frame.state;
+            var report = Self{
+                .components = undefined,
+                .entity_bytes = @sizeOf(@TypeOf(state.active_entities)) * 3 +
+                    @sizeOf(@TypeOf(state.next_entity)) + @sizeOf(@TypeOf(state.entity_count)) +
+                    state.generations.capacity * @sizeOf(u32) + state.free_entities.capacity * @sizeOf(ecs.EntityID),
+            };
+
+            inline for (0..component_count) |i| {
+                const storage = &state.components[i];
+                const Component = std.meta.Elem(@TypeOf(storage.dense.items));
+                const entity_size = @sizeOf(std.meta.Elem(@TypeOf(storage.dense_entities.items)));
+
+                report.components[i] = .{
+                    .name = EcsType.component_names[i],
+                    .count = storage.count(),
+                    .capacity = @intCast(storage.dense.capacity),
+                    .component_size = @sizeOf(Component),
+                    .dense_bytes = storage.dense.capacity * @sizeOf(Component),
+                    .index_bytes = storage.indexBytes() + storage.dense_entities.capacity * entity_size +
+                        storage.changed_ticks.capacity * @sizeOf(u64),
+                    .bitset_bytes = @sizeOf(@TypeOf(storage.entity_bitset)) + @sizeOf(@TypeOf(storage.changed)),
+                };
+            }
+            return report;
+        }
+
+        /// Include rollback buffer usage from anything exposing `getStats()` (e.g. NetcodeRollback)
+        pub fn addHistory(self: *Self, rollback: anytype) void {
+            const stats = rollback.getStats();
+            self.history = .{
+                .total_bytes = stats.total_memory,
+                .used_bytes = stats.used_memory,
+                .frames_stored = stats.frames_stored,
+                .avg_frame_bytes = stats.avg_frame_size,
+            };
+        }
+
+        pub fn componentBytes(self: *const Self) usize {
+            var total: usize = 0;
+            for (self.components) |component| total += component.totalBytes();
+            return total;
+        }
+
+        pub fn totalBytes(self: *const Self) usize {
+            const history_bytes = if (self.history) |history| history.total_bytes else 0;
+            return self.componentBytes() + self.entity_bytes + history_bytes;
+        }
+
+        pub fn writeTable(self: *const Self, writer: anytype) !void {
+            try writer.print("{s:<24} {s:>8} {s:>8} {s:>6} {s:>12} {s:>12} {s:>12} {s:>12} {s:>7}\n", .{
+                "component", "count", "capacity", "size", "dense", "index", "bitset", "total", "used%",
+            });
+            for (self.components) |component| {
+                try writer.print("{s:<24} {d:>8} {d:>8} {d:>6} {d:>12} {d:>12} {d:>12} {d:>12} {d:>7.1}\n", .{
+                    component.name,
+                    component.count,
+                    component.capacity,
+                    component.component_size,
+                    component.dense_bytes,
+                    component.index_bytes,
+                    component.bitset_bytes,
+                    component.totalBytes(),
+                    component.utilizationPercent(),
+                });
+            }
+            try writer.print("{s:<24} {d:>76}\n", .{ "components total", self.componentBytes() });
+            try writer.print("{s:<24} {d:>76}\n", .{ "entity bookkeeping", self.entity_bytes });
+
+            if (self.history) |history| {
+                try writer.print("{s:<24} {d:>76}\n", .{ "history buffer", history.total_bytes });
+                try writer.print("  {d} frames stored, {d} bytes used, {d} bytes per frame on average\n", .{
+                    history.frames_stored,
+                    history.used_bytes,
+                    history.avg_frame_bytes,
+                });
+            }
+            try writer.print("{s:<24} {d:>76}\n", .{ "total", self.totalBytes() });
+        }
+    };
+}
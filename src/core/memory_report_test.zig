@@ -0,0 +1,55 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const MemoryReport = @import("memory_report.zig").MemoryReport;
+const NetcodeRollback = @import("rollback.zig").NetcodeRollback;
+
+const Position = struct { x: f32, y: f32 };
+const Health = struct { value: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Health },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+test "Memory report breaks down component storage" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i < 3) try frame.addComponent(entity, Health{ .value = 1 });
+    }
+
+    var rollback = NetcodeRollback(TestECS, 4, 16 * 1024).init();
+    try rollback.saveFrame(&test_ecs);
+
+    var report = MemoryReport(TestECS).collect(&test_ecs);
+    report.addHistory(&rollback);
+
+    const position = report.components[0];
+    try testing.expectEqualStrings("Position", position.name);
+    try testing.expectEqual(@as(u32, 10), position.count);
+    try testing.expectEqual(@as(usize, 8), position.component_size);
+    try testing.expect(position.capacity >= 10);
+    try testing.expectEqual(@as(usize, position.capacity) * @sizeOf(Position), position.dense_bytes);
+    // Membership and dirty bitsets, one word each
+    try testing.expectEqual(@as(usize, 16), position.bitset_bytes);
+    try testing.expect(position.index_bytes >= 64 * @sizeOf(u32));
+
+    try testing.expectEqual(@as(u32, 3), report.components[1].count);
+    try testing.expectEqual(@as(u32, 1), report.history.?.frames_stored);
+    try testing.expectEqual(report.componentBytes() + report.entity_bytes + 4 * 16 * 1024, report.totalBytes());
+
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try report.writeTable(output.writer());
+    try testing.expect(std.mem.indexOf(u8, output.items, "history buffer") != null);
+}
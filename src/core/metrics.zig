@@ -0,0 +1,297 @@
+const std = @import("std");
+
+/// Metric types understood by Prometheus
+pub const MetricKind = enum {
+    gauge,
+    counter,
+};
+
+/// Optional single label attached to a sample (`rewind_component_count{component="Position"}`)
+pub const Label = struct {
+    key: []const u8,
+    value: []const u8,
+};
+
+/// One metric value. Name, help and label strings must outlive the gather/write call.
+pub const Sample = struct {
+    name: []const u8,
+    help: []const u8,
+    kind: MetricKind,
+    label: ?Label = null,
+    value: f64,
+};
+
+/// Receives samples from collectors during a gather
+pub const Sink = struct {
+    samples: *std.ArrayList(Sample),
+
+    pub fn add(self: *Sink, sample: Sample) !void {
+        try self.samples.append(sample);
+    }
+
+    pub fn gauge(self: *Sink, name: []const u8, help: []const u8, value: f64) !void {
+        try self.add(.{ .name = name, .help = help, .kind = .gauge, .value = value });
+    }
+
+    pub fn counter(self: *Sink, name: []const u8, help: []const u8, value: f64) !void {
+        try self.add(.{ .name = name, .help = help, .kind = .counter, .value = value });
+    }
+};
+
+/// Anything that can report metrics. Implemented by `WorldMetrics` and by host applications
+/// that want their own numbers on the same endpoint.
+pub const Collector = struct {
+    ptr: *anyopaque,
+    collectFn: *const fn (ptr: *anyopaque, sink: *Sink) anyerror!void,
+
+    pub fn collect(self: Collector, sink: *Sink) !void {
+        try self.collectFn(self.ptr, sink);
+    }
+};
+
+/// Set of collectors rendered together in Prometheus text format or expvar-style JSON
+pub const Registry = struct {
+    allocator: std.mem.Allocator,
+    collectors: std.ArrayList(Collector),
+
+    const Self = @This();
+
+    pub fn init(allocator: std.mem.Allocator) Self {
+        return Self{
+            .allocator = allocator,
+            .collectors = std.ArrayList(Collector).init(allocator),
+        };
+    }
+
+    pub fn deinit(self: *Self) void {
+        self.collectors.deinit();
+    }
+
+    pub fn register(self: *Self, collector: Collector) !void {
+        try self.collectors.append(collector);
+    }
+
+    /// Collect every sample from every collector. Caller owns the returned list.
+    pub fn gather(self: *const Self, allocator: std.mem.Allocator) !std.ArrayList(Sample) {
+        var samples = std.ArrayList(Sample).init(allocator);
+        errdefer samples.deinit();
+
+        var sink = Sink{ .samples = &samples };
+        for (self.collectors.items) |collector| {
+            try collector.collect(&sink);
+        }
+        return samples;
+    }
+
+    /// Prometheus text exposition format (version 0.0.4). Samples of one family are grouped
+    /// under a single HELP/TYPE header regardless of the order collectors produced them.
+    pub fn writePrometheus(self: *const Self, writer: anytype) !void {
+        var samples = try self.gather(self.allocator);
+        defer samples.deinit();
+
+        for (samples.items, 0..) |sample, i| {
+            if (isRepeatedFamily(samples.items, i)) continue;
+
+            try writer.print("# HELP {s} {s}\n", .{ sample.name, sample.help });
+            try writer.print("# TYPE {s} {s}\n", .{ sample.name, @tagName(sample.kind) });
+
+            for (samples.items[i..]) |member| {
+                if (!std.mem.eql(u8, member.name, sample.name)) continue;
+
+                try writer.writeAll(member.name);
+                if (member.label) |label| {
+                    try writer.print("{{{s}=\"", .{label.key});
+                    try writeEscaped(writer, label.value);
+                    try writer.writeAll("\"}");
+                }
+                try writer.print(" {d}\n", .{member.value});
+            }
+        }
+    }
+
+    /// expvar-style JSON object: unlabeled metrics map to numbers, labeled ones to objects
+    pub fn writeJson(self: *const Self, writer: anytype) !void {
+        var samples = try self.gather(self.allocator);
+        defer samples.deinit();
+
+        try writer.writeByte('{');
+        var first_family = true;
+        for (samples.items, 0..) |sample, i| {
+            if (isRepeatedFamily(samples.items, i)) continue;
+
+            if (!first_family) try writer.writeByte(',');
+            first_family = false;
+            try std.json.stringify(sample.name, .{}, writer);
+            try writer.writeByte(':');
+
+            if (sample.label == null) {
+                try writer.print("{d}", .{sample.value});
+                continue;
+            }
+
+            try writer.writeByte('{');
+            var first_value = true;
+            for (samples.items[i..]) |member| {
+                if (!std.mem.eql(u8, member.name, sample.name)) continue;
+                const label = member.label orelse continue;
+                if (!first_value) try writer.writeByte(',');
+                first_value = false;
+                try std.json.stringify(label.value, .{}, writer);
+                try writer.print(":{d}", .{member.value});
+            }
+            try writer.writeByte('}');
+        }
+        try writer.writeByte('}');
+    }
+};
+
+/// True when an earlier sample already started this metric family
+fn isRepeatedFamily(samples: []const Sample, index: usize) bool {
+    for (samples[0..index]) |previous| {
+        if (std.mem.eql(u8, previous.name, samples[index].name)) return true;
+    }
+    return false;
+}
+
+fn writeEscaped(writer: anytype, value: []const u8) !void {
+    for (value) |c| {
+        switch (c) {
+            '\\' => try writer.writeAll("\\\\"),
+            '"' => try writer.writeAll("\\\""),
+            '\n' => try writer.writeAll("\\n"),
+            else => try writer.writeByte(c),
+        }
+    }
+}
+
+/// Standard world metrics for one ECS instance: entity and component counts, frame number,
+/// per-system time, rollback depth, snapshot bytes and event counts.
+///
+/// Entity/component numbers are read live from the ECS at gather time. The rest is pushed in
+/// by whoever owns that data (system runner, rollback buffer, event channels).
+pub fn WorldMetrics(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        allocator: std.mem.Allocator,
+        world: *const EcsType,
+        systems: std.ArrayList(SystemTiming),
+        events: std.ArrayList(EventCount),
+        rollback_depth: u32,
+        snapshot_bytes: u64,
+
+        pub const SystemTiming = struct {
+            name: []const u8,
+            total_ns: u64,
+            last_ns: u64,
+            calls: u64,
+        };
+
+        pub const EventCount = struct {
+            channel: []const u8,
+            total: u64,
+        };
+
+        pub fn init(allocator: std.mem.Allocator, world: *const EcsType) Self {
+            return Self{
+                .allocator = allocator,
+                .world = world,
+                .systems = std.ArrayList(SystemTiming).init(allocator),
+                .events = std.ArrayList(EventCount).init(allocator),
+                .rollback_depth = 0,
+                .snapshot_bytes = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            for (self.systems.items) |system| self.allocator.free(system.name);
+            for (self.events.items) |event| self.allocator.free(event.channel);
+            self.systems.deinit();
+            self.events.deinit();
+        }
+
+        pub fn collector(self: *Self) Collector {
+            return .{ .ptr = self, .collectFn = collect };
+        }
+
+        /// Record the time one system took this tick
+        pub fn recordSystemTime(self: *Self, name: []const u8, elapsed_ns: u64) !void {
+            for (self.systems.items) |*system| {
+                if (std.mem.eql(u8, system.name, name)) {
+                    system.total_ns += elapsed_ns;
+                    system.last_ns = elapsed_ns;
+                    system.calls += 1;
+                    return;
+                }
+            }
+            const owned = try self.allocator.dupe(u8, name);
+            errdefer self.allocator.free(owned);
+            try self.systems.append(.{ .name = owned, .total_ns = elapsed_ns, .last_ns = elapsed_ns, .calls = 1 });
+        }
+
+        /// Record events emitted on a channel
+        pub fn recordEvents(self: *Self, channel: []const u8, count: u64) !void {
+            for (self.events.items) |*event| {
+                if (std.mem.eql(u8, event.channel, channel)) {
+                    event.total += count;
+                    return;
+                }
+            }
+            const owned = try self.allocator.dupe(u8, channel);
+            errdefer self.allocator.free(owned);
+            try self.events.append(.{ .channel = owned, .total = count });
+        }
+
+        /// Record rollback buffer state from anything exposing `getStats()` with
+        /// `frames_stored` and `avg_frame_size` (e.g. NetcodeRollback)
+        pub fn recordRollback(self: *Self, rollback: anytype) void {
+            const stats = rollback.getStats();
+            self.rollback_depth = stats.frames_stored;
+            self.snapshot_bytes = stats.avg_frame_size;
+        }
+
+        fn collect(ptr: *anyopaque, sink: *Sink) anyerror!void {
+            const self: *Self = @ptrCast(@alignCast(ptr));
+            const world = self.world;
+            const frame = &world.current_frame;
+
+            try sink.gauge("rewind_entities", "Live entities in the world", @floatFromInt(frame.state.entity_count));
+            try sink.counter("rewind_frame_number", "Simulation ticks advanced", @floatFromInt(frame.frame_number));
+
+            inline for (0..EcsType.component_names.len) |i| {
+                try sink.add(.{
+                    .name = "rewind_component_count",
+                    .help = "Entities holding each component type",
+                    .kind = .gauge,
+                    .label = .{ .key = "component", .value = EcsType.component_names[i] },
+                    .value = @floatFromInt(frame.state.components[i].count()),
+                });
+            }
+
+            try sink.gauge("rewind_snapshot_bytes", "Bytes needed to snapshot the current frame", @floatFromInt(world.calculateFrameSize()));
+            try sink.gauge("rewind_rollback_depth", "Frames currently held in the rollback buffer", @floatFromInt(self.rollback_depth));
+            try sink.gauge("rewind_rollback_frame_bytes", "Average stored rollback frame size in bytes", @floatFromInt(self.snapshot_bytes));
+
+            for (self.systems.items) |system| {
+                const label = Label{ .key = "system", .value = system.name };
+                try sink.add(.{ .name = "rewind_system_seconds_total", .help = "Total time spent in each system", .kind = .counter, .label = label, .value = nsToSeconds(system.total_ns) });
+                try sink.add(.{ .name = "rewind_system_last_seconds", .help = "Time spent in each system on the last tick", .kind = .gauge, .label = label, .value = nsToSeconds(system.last_ns) });
+                try sink.add(.{ .name = "rewind_system_calls_total", .help = "Number of times each system ran", .kind = .counter, .label = label, .value = @floatFromInt(system.calls) });
+            }
+
+            for (self.events.items) |event| {
+                try sink.add(.{
+                    .name = "rewind_events_total",
+                    .help = "Events emitted per channel",
+                    .kind = .counter,
+                    .label = .{ .key = "channel", .value = event.channel },
+                    .value = @floatFromInt(event.total),
+                });
+            }
+        }
+
+        fn nsToSeconds(ns: u64) f64 {
+            return @as(f64, @floatFromInt(ns)) / 1_000_000_000.0;
+        }
+    };
+}
@@ -0,0 +1,85 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const metrics = @import("metrics.zig");
+const NetcodeRollback = @import("rollback.zig").NetcodeRollback;
+
+const Position = struct { x: f32, y: f32 };
+const Health = struct { value: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Health },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+test "World metrics render in Prometheus format" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    for (0..3) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i == 0) try frame.addComponent(entity, Health{ .value = 10 });
+    }
+
+    var world_metrics = metrics.WorldMetrics(TestECS).init(testing.allocator, &test_ecs);
+    defer world_metrics.deinit();
+    try world_metrics.recordSystemTime("movement", 1_000_000);
+    try world_metrics.recordSystemTime("movement", 3_000_000);
+    try world_metrics.recordSystemTime("damage", 500_000);
+    try world_metrics.recordEvents("collision", 7);
+
+    var registry = metrics.Registry.init(testing.allocator);
+    defer registry.deinit();
+    try registry.register(world_metrics.collector());
+
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try registry.writePrometheus(output.writer());
+    const text = output.items;
+
+    try testing.expect(std.mem.indexOf(u8, text, "# TYPE rewind_entities gauge\nrewind_entities 3\n") != null);
+    try testing.expect(std.mem.indexOf(u8, text, "rewind_component_count{component=\"Position\"} 3\n") != null);
+    try testing.expect(std.mem.indexOf(u8, text, "rewind_component_count{component=\"Health\"} 1\n") != null);
+    try testing.expect(std.mem.indexOf(u8, text, "rewind_system_calls_total{system=\"movement\"} 2\n") != null);
+    try testing.expect(std.mem.indexOf(u8, text, "rewind_events_total{channel=\"collision\"} 7\n") != null);
+
+    // One HELP line per family even though systems interleave their samples
+    try testing.expectEqual(@as(usize, 1), std.mem.count(u8, text, "# HELP rewind_system_seconds_total"));
+}
+
+test "World metrics render as expvar JSON" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    _ = try test_ecs.getFrame().createEntity();
+
+    var world_metrics = metrics.WorldMetrics(TestECS).init(testing.allocator, &test_ecs);
+    defer world_metrics.deinit();
+
+    var rollback = NetcodeRollback(TestECS, 4, 16 * 1024).init();
+    try rollback.saveFrame(&test_ecs);
+    try rollback.saveFrame(&test_ecs);
+    world_metrics.recordRollback(&rollback);
+
+    var registry = metrics.Registry.init(testing.allocator);
+    defer registry.deinit();
+    try registry.register(world_metrics.collector());
+
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try registry.writeJson(output.writer());
+
+    const parsed = try std.json.parseFromSlice(std.json.Value, testing.allocator, output.items, .{});
+    defer parsed.deinit();
+
+    const root = parsed.value.object;
+    try testing.expectEqual(@as(i64, 1), root.get("rewind_entities").?.integer);
+    try testing.expectEqual(@as(i64, 2), root.get("rewind_rollback_depth").?.integer);
+    try testing.expect(root.get("rewind_component_count").?.object.get("Position") != null);
+}
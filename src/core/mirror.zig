@@ -0,0 +1,379 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const comptimePrint = std.fmt.comptimePrint;
+
+/// TypeScript and C# mirrors of an ECS's components, generated from the Zig structs.
+///
+/// The component structs are the single definition of the data layout. Mirrors contain the same
+/// types plus readers for the byte format written by `schema.encodeValue` / `encodeEntity`, so
+/// clients in other languages decode snapshots and replays without hand-kept copies. Regenerate
+/// with `rewind-inspect mirror ts|cs` whenever a component changes.
+///
+/// Fixed-point values become plain numbers in TypeScript (for display - clients never simulate)
+/// and raw `long`s in C#, where the runtime's own FP type can wrap them losslessly. Tagged unions
+/// become a `{Union}Tag` enum plus, per variant, the payload - a discriminated union type in
+/// TypeScript, a struct with a `tag` and one field per variant in C#. Struct payloads are mirrored
+/// as `{Union}{Variant}`, so payloads declared inline in the union get a name too.
+pub fn Mirror(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+    const nested = comptime collectNested(Types);
+
+    return struct {
+        pub fn writeTypeScript(writer: anytype) !void {
+            try writer.print(ts_prelude, .{FP.ONE_RAW});
+
+            inline for (nested ++ Types) |T| {
+                switch (@typeInfo(T)) {
+                    .@"enum" => |info| {
+                        try writer.print("\nexport enum {s} {{\n", .{ecs.shortTypeName(T)});
+                        inline for (info.fields) |field| try writer.print("  {s} = {d},\n", .{ field.name, field.value });
+                        try writer.writeAll("}\n");
+                    },
+                    .@"struct" => try writeTypeScriptStruct(T, ecs.shortTypeName(T), writer),
+                    .@"union" => |info| {
+                        const name = ecs.shortTypeName(T);
+                        try writer.print("\nexport enum {s}Tag {{\n", .{name});
+                        inline for (info.fields) |field| try writer.print("  {s} = {d},\n", .{ field.name, tagValue(T, field.name) });
+                        try writer.writeAll("}\n");
+                        inline for (info.fields) |field| {
+                            if (comptime isPayloadStruct(field.type)) try writeTypeScriptStruct(field.type, comptime payloadName(T, field), writer);
+                        }
+
+                        try writer.print("\nexport type {s} =\n", .{name});
+                        inline for (info.fields) |field| {
+                            if (field.type == void) {
+                                try writer.print("  | {{ tag: {s}Tag.{s} }}\n", .{ name, field.name });
+                            } else {
+                                try writer.print("  | {{ tag: {s}Tag.{s}; {s}: {s} }}\n", .{ name, field.name, field.name, comptime payloadTsType(T, field) });
+                            }
+                        }
+                        try writer.print(";\n\nexport function read{s}(r: Reader): {s} {{\n  const tag: {s}Tag = {s};\n  switch (tag) {{\n", .{
+                            name,
+                            name,
+                            name,
+                            comptime tsRead(unionTagInt(T)),
+                        });
+                        inline for (info.fields) |field| {
+                            if (field.type == void) {
+                                try writer.print("    case {s}Tag.{s}: return {{ tag }};\n", .{ name, field.name });
+                            } else {
+                                try writer.print("    case {s}Tag.{s}: return {{ tag, {s}: {s} }};\n", .{ name, field.name, field.name, comptime payloadTsRead(T, field) });
+                            }
+                        }
+                        try writer.print("    default: throw new Error(`Invalid {s} tag ${{tag}}`);\n  }}\n}}\n", .{name});
+                    },
+                    else => unreachable,
+                }
+            }
+
+            try writer.writeAll("\nexport interface Entity {\n");
+            inline for (Types) |T| try writer.print("  {s}?: {s};\n", .{ ecs.shortTypeName(T), ecs.shortTypeName(T) });
+            try writer.writeAll("}\n\n/** Record written by `schema.Registry.encodeEntity` */\n");
+            try writer.writeAll("export function readEntity(r: Reader): Entity {\n  const mask = r.u64();\n  const entity: Entity = {};\n");
+            inline for (Types, 0..) |T, i| {
+                try writer.print("  if (mask & (1n << {d}n)) entity.{s} = read{s}(r);\n", .{ i, ecs.shortTypeName(T), ecs.shortTypeName(T) });
+            }
+            try writer.writeAll("  return entity;\n}\n");
+        }
+
+        pub fn writeCSharp(writer: anytype, namespace: []const u8) !void {
+            try writer.print(cs_prelude, .{ namespace, FP.PRECISION });
+
+            inline for (nested ++ Types) |T| {
+                switch (@typeInfo(T)) {
+                    .@"enum" => |info| {
+                        try writer.print("\n    public enum {s} : {s}\n    {{\n", .{ ecs.shortTypeName(T), comptime csType(info.tag_type) });
+                        inline for (info.fields) |field| try writer.print("        {s} = {d},\n", .{ field.name, field.value });
+                        try writer.writeAll("    }\n");
+                    },
+                    .@"struct" => try writeCSharpStruct(T, ecs.shortTypeName(T), writer),
+                    .@"union" => |info| {
+                        const name = ecs.shortTypeName(T);
+                        const Tag = unionTagInt(T);
+                        try writer.print("\n    public enum {s}Tag : {s}\n    {{\n", .{ name, comptime csType(Tag) });
+                        inline for (info.fields) |field| try writer.print("        {s} = {d},\n", .{ field.name, tagValue(T, field.name) });
+                        try writer.writeAll("    }\n");
+                        inline for (info.fields) |field| {
+                            if (comptime isPayloadStruct(field.type)) try writeCSharpStruct(field.type, comptime payloadName(T, field), writer);
+                        }
+
+                        try writer.print("\n    /// <summary>Tagged union - only the field named by tag is set</summary>\n    public struct {s}\n    {{\n        public {s}Tag tag;\n", .{ name, name });
+                        inline for (info.fields) |field| {
+                            if (field.type != void) try writer.print("        public {s} {s};\n", .{ comptime payloadCsType(T, field), field.name });
+                        }
+                        try writer.print("\n        public static {s} Read(BinaryReader r)\n        {{\n            var value = new {s}();\n            value.tag = ({s}Tag){s};\n            switch (value.tag)\n            {{\n", .{
+                            name,
+                            name,
+                            name,
+                            comptime csRead(Tag),
+                        });
+                        inline for (info.fields) |field| {
+                            if (field.type == void) {
+                                try writer.print("                case {s}Tag.{s}: break;\n", .{ name, field.name });
+                            } else {
+                                try writer.print("                case {s}Tag.{s}: value.{s} = {s}; break;\n", .{ name, field.name, field.name, comptime payloadCsRead(T, field) });
+                            }
+                        }
+                        try writer.print("                default: throw new InvalidDataException($\"Invalid {s} tag {{value.tag}}\");\n            }}\n            return value;\n        }}\n    }}\n", .{name});
+                    },
+                    else => unreachable,
+                }
+            }
+
+            try writer.writeAll("\n    /// <summary>Record written by schema.Registry.encodeEntity</summary>\n");
+            try writer.writeAll("    public sealed class Entity\n    {\n");
+            inline for (Types) |T| try writer.print("        public {s}? {s};\n", .{ ecs.shortTypeName(T), ecs.shortTypeName(T) });
+            try writer.writeAll("\n        public static Entity Read(BinaryReader r)\n        {\n            var mask = r.ReadUInt64();\n            var entity = new Entity();\n");
+            inline for (Types, 0..) |T, i| {
+                try writer.print("            if ((mask & (1UL << {d})) != 0) entity.{s} = {s}.Read(r);\n", .{ i, ecs.shortTypeName(T), ecs.shortTypeName(T) });
+            }
+            try writer.writeAll("            return entity;\n        }\n    }\n}\n");
+        }
+    };
+}
+
+fn writeTypeScriptStruct(comptime T: type, comptime name: []const u8, writer: anytype) !void {
+    const fields = comptime schema.runtimeFields(T);
+    try writer.print("\nexport interface {s} {{\n", .{name});
+    inline for (fields) |field| try writer.print("  {s}: {s};\n", .{ field.name, comptime tsType(field.type) });
+    try writer.print("}}\n\nexport function read{s}(r: Reader): {s} {{\n  return {{\n", .{ name, name });
+    inline for (fields) |field| try writer.print("    {s}: {s},\n", .{ field.name, comptime tsRead(field.type) });
+    try writer.writeAll("  };\n}\n");
+}
+
+fn writeCSharpStruct(comptime T: type, comptime name: []const u8, writer: anytype) !void {
+    const fields = comptime schema.runtimeFields(T);
+    try writer.print("\n    public struct {s}\n    {{\n", .{name});
+    inline for (fields) |field| try writer.print("        public {s} {s};\n", .{ comptime csType(field.type), field.name });
+    try writer.print("\n        public static {s} Read(BinaryReader r)\n        {{\n            var value = new {s}();\n", .{ name, name });
+    inline for (fields) |field| try writer.print("            value.{s} = {s};\n", .{ field.name, comptime csRead(field.type) });
+    try writer.writeAll("            return value;\n        }\n    }\n");
+}
+
+const ts_prelude =
+    \\// Generated from the Zig component structs by `rewind-inspect mirror ts` - do not edit.
+    \\
+    \\export type FPVector2 = {{ x: number; y: number }};
+    \\
+    \\/** Little-endian reader over the rewind codec */
+    \\export class Reader {{
+    \\  private offset = 0;
+    \\  constructor(private view: DataView) {{}}
+    \\  private advance(bytes: number): number {{ const at = this.offset; this.offset += bytes; return at; }}
+    \\  bool(): boolean {{ return this.u8() !== 0; }}
+    \\  u8(): number {{ return this.view.getUint8(this.advance(1)); }}
+    \\  i8(): number {{ return this.view.getInt8(this.advance(1)); }}
+    \\  u16(): number {{ return this.view.getUint16(this.advance(2), true); }}
+    \\  i16(): number {{ return this.view.getInt16(this.advance(2), true); }}
+    \\  u32(): number {{ return this.view.getUint32(this.advance(4), true); }}
+    \\  i32(): number {{ return this.view.getInt32(this.advance(4), true); }}
+    \\  u64(): bigint {{ return this.view.getBigUint64(this.advance(8), true); }}
+    \\  i64(): bigint {{ return this.view.getBigInt64(this.advance(8), true); }}
+    \\  f32(): number {{ return this.view.getFloat32(this.advance(4), true); }}
+    \\  f64(): number {{ return this.view.getFloat64(this.advance(8), true); }}
+    \\  fp(): number {{ return Number(this.i64()) / {d}; }}
+    \\  vec2(): FPVector2 {{ return {{ x: this.fp(), y: this.fp() }}; }}
+    \\  optional<T>(read: () => T): T | null {{ return this.u8() === 0 ? null : read(); }}
+    \\  array<T>(length: number, read: () => T): T[] {{ return Array.from({{ length }}, read); }}
+    \\}}
+    \\
+;
+
+const cs_prelude =
+    \\// Generated from the Zig component structs by `rewind-inspect mirror cs` - do not edit.
+    \\using System;
+    \\using System.IO;
+    \\
+    \\namespace {s}
+    \\{{
+    \\    /// <summary>Fixed-point vector as raw values ({d} fractional bits)</summary>
+    \\    public struct FPVector2
+    \\    {{
+    \\        public long x;
+    \\        public long y;
+    \\
+    \\        public static FPVector2 Read(BinaryReader r) => new FPVector2 {{ x = r.ReadInt64(), y = r.ReadInt64() }};
+    \\    }}
+    \\
+    \\    internal static class Codec
+    \\    {{
+    \\        public static T[] ReadArray<T>(int length, Func<T> read)
+    \\        {{
+    \\            var items = new T[length];
+    \\            for (var i = 0; i < length; i++) items[i] = read();
+    \\            return items;
+    \\        }}
+    \\    }}
+    \\
+;
+
+// Enums and structs reachable from the components, dependencies first
+fn collectNested(comptime Types: []const type) []const type {
+    comptime {
+        var found: []const type = &.{};
+        for (Types) |T| {
+            for (schema.runtimeFields(T)) |field| found = addNested(found, field.type);
+        }
+        return found;
+    }
+}
+
+fn addNested(comptime found: []const type, comptime T: type) []const type {
+    comptime {
+        if (T == FP or T == FPVector2) return found;
+        for (found) |existing| {
+            if (existing == T) return found;
+        }
+        return switch (@typeInfo(T)) {
+            .@"enum" => found ++ &[_]type{T},
+            .@"struct" => |info| blk: {
+                if (info.layout == .@"packed") unsupported(T);
+                var with_fields = found;
+                for (schema.runtimeFields(T)) |field| with_fields = addNested(with_fields, field.type);
+                break :blk with_fields ++ &[_]type{T};
+            },
+            .@"union" => |info| blk: {
+                if (info.tag_type == null) unsupported(T);
+                // Struct payloads are written with the union under its own name - only what
+                // their fields use is collected
+                var with_payloads = found;
+                for (info.fields) |field| {
+                    if (isPayloadStruct(field.type)) {
+                        for (schema.runtimeFields(field.type)) |payload_field| with_payloads = addNested(with_payloads, payload_field.type);
+                    } else {
+                        with_payloads = addNested(with_payloads, field.type);
+                    }
+                }
+                break :blk with_payloads ++ &[_]type{T};
+            },
+            .array => |info| addNested(found, info.child),
+            .optional => |info| addNested(found, info.child),
+            else => found,
+        };
+    }
+}
+
+// A union payload mirrored as a `{Union}{Variant}` struct
+fn isPayloadStruct(comptime T: type) bool {
+    if (T == FP or T == FPVector2) return false;
+    return switch (@typeInfo(T)) {
+        .@"struct" => |info| info.layout != .@"packed",
+        else => false,
+    };
+}
+
+fn payloadName(comptime Union: type, comptime field: std.builtin.Type.UnionField) []const u8 {
+    return ecs.shortTypeName(Union) ++ [_]u8{std.ascii.toUpper(field.name[0])} ++ field.name[1..];
+}
+
+// Integer the codec writes a union's tag as
+fn unionTagInt(comptime Union: type) type {
+    const Tag = @typeInfo(Union).@"union".tag_type.?;
+    return @typeInfo(Tag).@"enum".tag_type;
+}
+
+fn tagValue(comptime Union: type, comptime name: []const u8) comptime_int {
+    return @intFromEnum(@field(@typeInfo(Union).@"union".tag_type.?, name));
+}
+
+fn payloadTsType(comptime Union: type, comptime field: std.builtin.Type.UnionField) []const u8 {
+    return if (isPayloadStruct(field.type)) payloadName(Union, field) else tsType(field.type);
+}
+
+fn payloadTsRead(comptime Union: type, comptime field: std.builtin.Type.UnionField) []const u8 {
+    return if (isPayloadStruct(field.type)) "read" ++ payloadName(Union, field) ++ "(r)" else tsRead(field.type);
+}
+
+fn payloadCsType(comptime Union: type, comptime field: std.builtin.Type.UnionField) []const u8 {
+    return if (isPayloadStruct(field.type)) payloadName(Union, field) else csType(field.type);
+}
+
+fn payloadCsRead(comptime Union: type, comptime field: std.builtin.Type.UnionField) []const u8 {
+    return if (isPayloadStruct(field.type)) payloadName(Union, field) ++ ".Read(r)" else csRead(field.type);
+}
+
+fn tsType(comptime T: type) []const u8 {
+    if (T == FP) return "number";
+    if (T == FPVector2) return "FPVector2";
+    return switch (@typeInfo(T)) {
+        .bool => "boolean",
+        .int => if (intBits(T) == 64) "bigint" else "number",
+        .float => "number",
+        .@"enum", .@"struct", .@"union" => ecs.shortTypeName(T),
+        .array => |info| tsType(info.child) ++ "[]",
+        .optional => |info| "(" ++ tsType(info.child) ++ " | null)",
+        else => unsupported(T),
+    };
+}
+
+fn tsRead(comptime T: type) []const u8 {
+    if (T == FP) return "r.fp()";
+    if (T == FPVector2) return "r.vec2()";
+    return switch (@typeInfo(T)) {
+        .bool => "r.bool()",
+        .int => |info| comptimePrint("r.{s}{d}()", .{ if (info.signedness == .signed) "i" else "u", intBits(T) }),
+        .float => |info| comptimePrint("r.f{d}()", .{info.bits}),
+        .@"enum" => |info| tsRead(info.tag_type),
+        .@"struct", .@"union" => "read" ++ ecs.shortTypeName(T) ++ "(r)",
+        .array => |info| comptimePrint("r.array({d}, () => {s})", .{ info.len, tsRead(info.child) }),
+        .optional => |info| comptimePrint("r.optional(() => {s})", .{tsRead(info.child)}),
+        else => unsupported(T),
+    };
+}
+
+fn csType(comptime T: type) []const u8 {
+    if (T == FP) return "long";
+    if (T == FPVector2) return "FPVector2";
+    return switch (@typeInfo(T)) {
+        .bool => "bool",
+        .int => |info| switch (intBits(T)) {
+            8 => if (info.signedness == .signed) "sbyte" else "byte",
+            16 => if (info.signedness == .signed) "short" else "ushort",
+            32 => if (info.signedness == .signed) "int" else "uint",
+            64 => if (info.signedness == .signed) "long" else "ulong",
+            else => unreachable,
+        },
+        .float => |info| if (info.bits == 32) "float" else "double",
+        .@"enum", .@"struct", .@"union" => ecs.shortTypeName(T),
+        .array => |info| csType(info.child) ++ "[]",
+        .optional => |info| csType(info.child) ++ "?",
+        else => unsupported(T),
+    };
+}
+
+fn csRead(comptime T: type) []const u8 {
+    if (T == FP) return "r.ReadInt64()";
+    if (T == FPVector2) return "FPVector2.Read(r)";
+    return switch (@typeInfo(T)) {
+        .bool => "r.ReadBoolean()",
+        .int => |info| switch (intBits(T)) {
+            8 => if (info.signedness == .signed) "r.ReadSByte()" else "r.ReadByte()",
+            16 => if (info.signedness == .signed) "r.ReadInt16()" else "r.ReadUInt16()",
+            32 => if (info.signedness == .signed) "r.ReadInt32()" else "r.ReadUInt32()",
+            64 => if (info.signedness == .signed) "r.ReadInt64()" else "r.ReadUInt64()",
+            else => unreachable,
+        },
+        .float => |info| if (info.bits == 32) "r.ReadSingle()" else "r.ReadDouble()",
+        .@"enum" => |info| "(" ++ ecs.shortTypeName(T) ++ ")" ++ csRead(info.tag_type),
+        .@"struct", .@"union" => ecs.shortTypeName(T) ++ ".Read(r)",
+        .array => |info| comptimePrint("Codec.ReadArray({d}, () => {s})", .{ info.len, csRead(info.child) }),
+        .optional => |info| comptimePrint("(r.ReadByte() == 0 ? ({s})null : {s})", .{ csType(T), csRead(info.child) }),
+        else => unsupported(T),
+    };
+}
+
+// Wire width of an integer - the codec rounds up to whole bytes
+fn intBits(comptime T: type) u16 {
+    const bits = @bitSizeOf(std.math.ByteAlignedInt(T));
+    if (bits != 8 and bits != 16 and bits != 32 and bits != 64) unsupported(T);
+    return bits;
+}
+
+fn unsupported(comptime T: type) noreturn {
+    @compileError("Mirrors support bools, integers up to 64 bits, floats, FP, FPVector2, enums, arrays, " ++
+        "optionals, plain structs and tagged unions - '" ++ @typeName(T) ++ "' is none of these");
+}
@@ -0,0 +1,120 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const mirror = @import("mirror.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const Transform = components.Transform;
+const Collider = components.Collider;
+
+const Team = enum(u8) { red, blue };
+
+const Stats = struct {
+    level: u16 = 1,
+    team: Team = .red,
+};
+
+const Order = union(enum) {
+    idle,
+    move: FPVector2,
+    follow: u32,
+};
+
+const Unit = struct {
+    health: i32 = 100,
+    speed: FP = FP.fromInt(1),
+    stats: Stats = .{},
+    slots: [3]u8 = .{ 0, 0, 0 },
+    target: ?u32 = null,
+    order: Order = .idle,
+};
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Unit, Collider },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestMirror = mirror.Mirror(TestECS);
+
+fn expectInOrder(output: []const u8, expected: []const []const u8) !void {
+    var from: usize = 0;
+    for (expected) |line| {
+        const at = std.mem.indexOfPos(u8, output, from, line) orelse {
+            std.debug.print("missing after offset {d}: {s}\n", .{ from, line });
+            return error.TestExpectedEqual;
+        };
+        from = at + line.len;
+    }
+}
+
+test "TypeScript mirror declares dependencies first and reads the codec layout" {
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try TestMirror.writeTypeScript(output.writer());
+
+    try expectInOrder(output.items, &.{
+        "fp(): number { return Number(this.i64()) / 65536; }",
+        "export enum Team {\n  red = 0,\n  blue = 1,\n}",
+        "export interface Stats {\n  level: number;\n  team: Team;\n}",
+        "export interface Transform {\n  position: FPVector2;\n  rotation: number;\n}",
+        "export interface Unit {",
+        "  slots: number[];\n  target: (number | null);",
+        "    health: r.i32(),\n    speed: r.fp(),\n    stats: readStats(r),\n    slots: r.array(3, () => r.u8()),\n    target: r.optional(() => r.u32()),",
+        "  if (mask & (1n << 1n)) entity.Unit = readUnit(r);",
+    });
+}
+
+test "C# mirror reads fields with BinaryReader" {
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try TestMirror.writeCSharp(output.writer(), "Game.Components");
+
+    try expectInOrder(output.items, &.{
+        "namespace Game.Components",
+        "public enum Team : byte",
+        "public struct Stats",
+        "public struct Unit",
+        "        public long speed;",
+        "            value.stats = Stats.Read(r);",
+        "            value.slots = Codec.ReadArray(3, () => r.ReadByte());",
+        "            value.target = (r.ReadByte() == 0 ? (uint?)null : r.ReadUInt32());",
+        "            if ((mask & (1UL << 0)) != 0) entity.Transform = Transform.Read(r);",
+    });
+}
+
+test "Tagged unions mirror as a tag plus one payload per variant" {
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try TestMirror.writeTypeScript(output.writer());
+
+    try expectInOrder(output.items, &.{
+        "export enum OrderTag {\n  idle = 0,\n  move = 1,\n  follow = 2,\n}",
+        "export type Order =\n  | { tag: OrderTag.idle }\n  | { tag: OrderTag.move; move: FPVector2 }\n  | { tag: OrderTag.follow; follow: number }\n;",
+        "  const tag: OrderTag = r.u8();",
+        "    case OrderTag.idle: return { tag };\n    case OrderTag.move: return { tag, move: r.vec2() };",
+        // The capsule payload is declared inline in `Shape` and named after its variant
+        "export interface ShapeCapsule {\n  half_segment: FPVector2;\n  radius: number;\n}",
+        "    case ShapeTag.capsule: return { tag, capsule: readShapeCapsule(r) };",
+        "    order: readOrder(r),",
+        "export interface Collider {\n  shape: Shape;",
+        "  if (mask & (1n << 2n)) entity.Collider = readCollider(r);",
+    });
+
+    output.clearRetainingCapacity();
+    try TestMirror.writeCSharp(output.writer(), "Game.Components");
+    try expectInOrder(output.items, &.{
+        "public enum ShapeTag : byte",
+        "public struct ShapeCapsule",
+        "    public struct Shape\n    {\n        public ShapeTag tag;\n        public FPVector2 box;\n        public long circle;\n        public ShapeCapsule capsule;",
+        "            value.tag = (ShapeTag)r.ReadByte();",
+        "                case ShapeTag.capsule: value.capsule = ShapeCapsule.Read(r); break;",
+        "            value.shape = Shape.Read(r);",
+    });
+}
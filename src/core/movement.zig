@@ -0,0 +1,75 @@
+const std = @import("std");
+const EntityID = @import("ecs.zig").EntityID;
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Movement = components.Movement;
+
+/// Advance one entity by `dt` under `movement`
+pub fn integrate(transform: *Transform, velocity: *Velocity, movement: Movement, dt: FP) void {
+    const previous = velocity.linear;
+
+    var linear = previous.add(movement.acceleration.mul(dt));
+    if (movement.drag.raw_value != 0) {
+        // Linear drag, never reversing the direction of travel
+        linear = linear.mul(FP.max(fp(0), fp(1).sub(movement.drag.mul(dt))));
+    }
+    if (movement.max_speed.raw_value != 0) {
+        linear = linear.clampMagnitude(movement.max_speed);
+    }
+    velocity.linear = linear;
+
+    const step = switch (movement.scheme) {
+        .explicit_euler => previous,
+        .semi_implicit_euler => linear,
+    };
+    transform.position = transform.position.add(step.mul(dt));
+    transform.rotation = transform.rotation.add(velocity.angular.mul(dt));
+}
+
+/// Advance parallel slices - index i of each belongs to the same entity
+pub fn integrateBatch(transforms: []Transform, velocities: []Velocity, movements: []const Movement, dt: FP) void {
+    for (transforms, velocities, movements) |*transform, *velocity, movement| {
+        integrate(transform, velocity, movement, dt);
+    }
+}
+
+/// Integrates every Transform + Velocity + Movement entity. Entities driven by the physics module
+/// should not also carry a Movement component, or they will be moved twice.
+///
+/// When the three storages hold the same entities in the same dense order - the usual case when
+/// movers are spawned with all three and despawned with `destroyEntity` - the dense arrays are
+/// walked directly as parallel slices, with no bitset query or entity-to-index lookups. Any other
+/// layout falls back to the query. Each entity only touches its own components, so both paths
+/// produce identical state.
+pub fn MovementSystem(comptime EcsType: type) type {
+    return struct {
+        pub fn step(frame: *EcsType.Frame, dt: FP) !void {
+            if (aligned(frame)) {
+                integrateBatch(
+                    frame.getComponentStorage(Transform).getDenseArray(),
+                    frame.getComponentStorage(Velocity).getDenseArray(),
+                    frame.getComponentStorage(Movement).getDenseArray(),
+                    dt,
+                );
+                return;
+            }
+
+            var query = try frame.query(&.{ Transform, Velocity, Movement });
+            while (query.nextFast()) |result| {
+                integrate(result.get(Transform), result.get(Velocity), result.get(Movement).*, dt);
+            }
+        }
+
+        /// True when Transform, Velocity and Movement storages line up slot for slot
+        pub fn aligned(frame: *EcsType.Frame) bool {
+            const movers = frame.getComponentStorage(Movement).getDenseEntities();
+            return std.mem.eql(EntityID, movers, frame.getComponentStorage(Transform).getDenseEntities()) and
+                std.mem.eql(EntityID, movers, frame.getComponentStorage(Velocity).getDenseEntities());
+        }
+    };
+}
@@ -0,0 +1,121 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const movement = @import("movement.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Movement = components.Movement;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Movement },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+fn expectVec(expected: FPVector2, actual: FPVector2) !void {
+    try testing.expectEqual(expected.x.raw_value, actual.x.raw_value);
+    try testing.expectEqual(expected.y.raw_value, actual.y.raw_value);
+}
+
+test "Explicit and semi-implicit Euler" {
+    const falling = Movement{ .acceleration = fpVec2(0, -10) };
+
+    var explicit_transform = Transform{};
+    var explicit_velocity = Velocity{};
+    movement.integrate(&explicit_transform, &explicit_velocity, Movement{ .scheme = .explicit_euler, .acceleration = falling.acceleration }, fp(1));
+    try expectVec(fpVec2(0, 0), explicit_transform.position);
+    try expectVec(fpVec2(0, -10), explicit_velocity.linear);
+
+    var implicit_transform = Transform{};
+    var implicit_velocity = Velocity{};
+    movement.integrate(&implicit_transform, &implicit_velocity, falling, fp(1));
+    try expectVec(fpVec2(0, -10), implicit_transform.position);
+    try expectVec(fpVec2(0, -10), implicit_velocity.linear);
+}
+
+test "Drag and speed limit" {
+    var transform = Transform{};
+    var velocity = Velocity{ .linear = fpVec2(10, 0) };
+    movement.integrate(&transform, &velocity, .{ .drag = fp(0.5) }, fp(1));
+    try expectVec(fpVec2(5, 0), velocity.linear);
+
+    // Drag larger than 1/dt stops the entity instead of reversing it
+    movement.integrate(&transform, &velocity, .{ .drag = fp(4) }, fp(1));
+    try expectVec(fpVec2(0, 0), velocity.linear);
+
+    velocity.linear = fpVec2(0, -10);
+    movement.integrate(&transform, &velocity, .{ .max_speed = fp(5) }, fp(0.5));
+    try expectVec(fpVec2(0, -5), velocity.linear);
+}
+
+test "Movement system integrates only entities with a Movement component" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    const mover = try frame.createEntity();
+    const other = try frame.createEntity();
+    for ([_]ecs.EntityID{ mover, other }) |entity| {
+        try frame.addComponent(entity, Transform{});
+        try frame.addComponent(entity, Velocity{ .linear = fpVec2(2, 0) });
+    }
+    try frame.addComponent(mover, Movement{});
+
+    try movement.MovementSystem(TestECS).step(frame, fp(0.5));
+    try expectVec(fpVec2(1, 0), frame.getComponent(mover, Transform).?.position);
+    try expectVec(fpVec2(0, 0), frame.getComponent(other, Transform).?.position);
+}
+
+test "Aligned storages take the batch path with identical results" {
+    var batch_ecs = try TestECS.init(testing.allocator);
+    defer batch_ecs.deinit();
+    var query_ecs = try TestECS.init(testing.allocator);
+    defer query_ecs.deinit();
+
+    const batch = batch_ecs.getFrame();
+    const queried = query_ecs.getFrame();
+    for (0..20) |i| {
+        const mover = Movement{ .acceleration = fpVec2(0, -1), .drag = fp(0.1), .max_speed = fp(5) };
+        const velocity = Velocity{ .linear = FPVector2.new(FP.fromInt(@as(i32, @intCast(i))), fp(2)) };
+
+        const a = try batch.createEntity();
+        try batch.addComponent(a, Transform{});
+        try batch.addComponent(a, velocity);
+        try batch.addComponent(a, mover);
+
+        // Same entities, Movement added in reverse - the storages no longer line up
+        _ = try queried.createEntity();
+        try queried.addComponent(@intCast(i), Transform{});
+        try queried.addComponent(@intCast(i), velocity);
+    }
+    for (0..20) |i| {
+        const entity: ecs.EntityID = @intCast(19 - i);
+        try queried.addComponent(entity, batch.getComponent(entity, Movement).?.*);
+    }
+    batch.destroyEntity(4);
+    queried.destroyEntity(4);
+
+    try testing.expect(movement.MovementSystem(TestECS).aligned(batch));
+    try testing.expect(!movement.MovementSystem(TestECS).aligned(queried));
+
+    for (0..30) |_| {
+        try movement.MovementSystem(TestECS).step(batch, fp(0.1));
+        try movement.MovementSystem(TestECS).step(queried, fp(0.1));
+    }
+    for (0..20) |i| {
+        const entity: ecs.EntityID = @intCast(i);
+        if (entity == 4) continue;
+        try testing.expectEqual(queried.getComponent(entity, Transform).?.*, batch.getComponent(entity, Transform).?.*);
+        try testing.expectEqual(queried.getComponent(entity, Velocity).?.*, batch.getComponent(entity, Velocity).?.*);
+    }
+}
@@ -0,0 +1,56 @@
+const std = @import("std");
+
+/// Debug label of an entity - an id into its world's `NameTable`. Register it like any other
+/// component and set it with `world.setName`; `world.findByName("player")` goes the other way.
+///
+/// Names are for tools and scripts, not gameplay: the component is left out of checksums, so a
+/// peer labelling entities differently (or not at all) doesn't desync. Ids are local to the world
+/// that interned them - frames saved or sent elsewhere carry the id, not the string.
+pub const Name = struct {
+    id: u32 = 0,
+
+    pub const checksummed = false;
+};
+
+/// Interned strings, one copy each, looked up by id or by content. Append-only: an id stays valid
+/// for the table's life, so rolling a world back never leaves a `Name` pointing at nothing.
+pub const NameTable = struct {
+    const Self = @This();
+
+    ids: std.StringHashMapUnmanaged(u32) = .{},
+    strings: std.ArrayListUnmanaged([]const u8) = .{},
+
+    pub fn deinit(self: *Self, allocator: std.mem.Allocator) void {
+        for (self.strings.items) |string| allocator.free(string);
+        self.strings.deinit(allocator);
+        self.ids.deinit(allocator);
+    }
+
+    /// Id of `string`, copying it into the table the first time it is seen
+    pub fn intern(self: *Self, allocator: std.mem.Allocator, string: []const u8) !u32 {
+        if (self.ids.get(string)) |id| return id;
+
+        const owned = try allocator.dupe(u8, string);
+        errdefer allocator.free(owned);
+        const id: u32 = @intCast(self.strings.items.len);
+        try self.strings.append(allocator, owned);
+        errdefer _ = self.strings.pop();
+        try self.ids.put(allocator, owned, id);
+        return id;
+    }
+
+    /// Id of an already interned `string`
+    pub fn lookup(self: *const Self, string: []const u8) ?u32 {
+        return self.ids.get(string);
+    }
+
+    /// String behind `id` (null for ids this table never handed out)
+    pub fn get(self: *const Self, id: u32) ?[]const u8 {
+        if (id >= self.strings.items.len) return null;
+        return self.strings.items[id];
+    }
+
+    pub fn count(self: *const Self) usize {
+        return self.strings.items.len;
+    }
+};
@@ -0,0 +1,77 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const names = @import("names.zig");
+
+const Name = names.Name;
+
+const Health = struct { value: i32 = 100 };
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Health, Name },
+    .input = struct {},
+    .max_entities = .tiny,
+});
+
+test "Interned strings keep one copy and a stable id" {
+    var table = names.NameTable{};
+    defer table.deinit(testing.allocator);
+
+    const player = try table.intern(testing.allocator, "player");
+    const boss = try table.intern(testing.allocator, "boss");
+    try testing.expect(player != boss);
+    try testing.expectEqual(player, try table.intern(testing.allocator, "player"));
+    try testing.expectEqual(@as(usize, 2), table.count());
+
+    try testing.expectEqual(@as(?u32, boss), table.lookup("boss"));
+    try testing.expectEqual(@as(?u32, null), table.lookup("camera"));
+    try testing.expectEqualStrings("player", table.get(player).?);
+    try testing.expectEqual(@as(?[]const u8, null), table.get(7));
+}
+
+test "Entities are found by name and renamed in place" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    const grunt = try frame.createEntity();
+    const player = try frame.createEntity();
+    const other_grunt = try frame.createEntity();
+    try world.setName(grunt, "grunt");
+    try world.setName(player, "player");
+    try world.setName(other_grunt, "grunt");
+
+    try testing.expectEqual(@as(?ecs.EntityID, player), world.findByName("player"));
+    try testing.expectEqual(@as(?ecs.EntityID, grunt), world.findByName("grunt"));
+    try testing.expectEqual(@as(?ecs.EntityID, null), world.findByName("camera"));
+    try testing.expectEqualStrings("grunt", world.nameOf(other_grunt).?);
+    try testing.expectEqual(@as(usize, 2), world.names.count());
+
+    try world.setName(grunt, "sergeant");
+    try testing.expectEqualStrings("sergeant", world.nameOf(grunt).?);
+    try testing.expectEqual(@as(?ecs.EntityID, other_grunt), world.findByName("grunt"));
+
+    frame.destroyEntity(player);
+    try testing.expectEqual(@as(?ecs.EntityID, null), world.findByName("player"));
+    try testing.expectEqual(@as(?[]const u8, null), world.nameOf(player));
+}
+
+test "Names stay out of checksums" {
+    try testing.expect(!ecs.isChecksummed(Name));
+    try testing.expect(ecs.isChecksummed(Health));
+
+    var labelled = try TestECS.init(testing.allocator);
+    defer labelled.deinit();
+    var plain = try TestECS.init(testing.allocator);
+    defer plain.deinit();
+
+    for ([_]*TestECS{ &labelled, &plain }) |world| {
+        const entity = try world.getFrame().createEntity();
+        try world.getFrame().addComponent(entity, Health{});
+    }
+    try labelled.setName(0, "player");
+    try testing.expectEqual(plain.getFrame().checksum(), labelled.getFrame().checksum());
+
+    plain.getFrame().getComponent(0, Health).?.value = 50;
+    try testing.expectEqualStrings("Health", labelled.getFrame().checksums().firstDivergence(&plain.getFrame().checksums()).?);
+}
@@ -0,0 +1,836 @@
+const std = @import("std");
+const schema = @import("schema.zig");
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+const Clock = @import("clock.zig").Clock;
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
+
+/// Most players a session or transport addresses
+pub const max_players = 8;
+/// Largest datagram a session sends - below common path MTUs, so nothing gets fragmented
+pub const max_packet_size = 1200;
+
+/// Leading bytes and protocol version of every session packet
+pub const packet_magic = "RWNC";
+pub const protocol_version: u8 = 1;
+
+/// Unreliable datagram link to the other players of a session, addressed by player index.
+/// Like UDP, `send` may drop, duplicate or reorder packets and never reports failure - the session
+/// resends whatever a peer has not acknowledged.
+pub const Transport = struct {
+    ptr: *anyopaque,
+    vtable: *const VTable,
+
+    pub const VTable = struct {
+        send: *const fn (ptr: *anyopaque, player: u8, bytes: []const u8) void,
+        receive: *const fn (ptr: *anyopaque, buffer: []u8) ?[]u8,
+    };
+
+    pub fn send(self: Transport, player: u8, bytes: []const u8) void {
+        self.vtable.send(self.ptr, player, bytes);
+    }
+
+    /// Next waiting packet copied into `buffer`, or null when none arrived
+    pub fn receive(self: Transport, buffer: []u8) ?[]u8 {
+        return self.vtable.receive(self.ptr, buffer);
+    }
+};
+
+/// Non-blocking UDP socket with one address per remote player.
+///
+/// Usage:
+///   var udp = try UdpTransport.open(try std.net.Address.parseIp4("0.0.0.0", 7000));
+///   defer udp.close();
+///   udp.setPeer(1, try std.net.Address.parseIp4("203.0.113.7", 7000));
+///   var session = try Session(GameECS).init(allocator, &world, udp.transport(), .{ .local_player = 0 });
+pub const UdpTransport = struct {
+    socket: std.posix.socket_t,
+    peers: [max_players]?std.net.Address = [_]?std.net.Address{null} ** max_players,
+
+    /// Bind a socket to `address` (port 0 picks a free one, see `localAddress`)
+    pub fn open(address: std.net.Address) !UdpTransport {
+        const socket = try std.posix.socket(
+            address.any.family,
+            std.posix.SOCK.DGRAM | std.posix.SOCK.NONBLOCK | std.posix.SOCK.CLOEXEC,
+            std.posix.IPPROTO.UDP,
+        );
+        errdefer std.posix.close(socket);
+        try std.posix.bind(socket, &address.any, address.getOsSockLen());
+        return .{ .socket = socket };
+    }
+
+    pub fn close(self: *UdpTransport) void {
+        std.posix.close(self.socket);
+    }
+
+    /// Address the socket is bound to
+    pub fn localAddress(self: *const UdpTransport) !std.net.Address {
+        var address: std.net.Address = undefined;
+        var len: std.posix.socklen_t = @sizeOf(std.net.Address);
+        try std.posix.getsockname(self.socket, &address.any, &len);
+        return address;
+    }
+
+    pub fn setPeer(self: *UdpTransport, player: u8, address: std.net.Address) void {
+        self.peers[player] = address;
+    }
+
+    pub fn transport(self: *UdpTransport) Transport {
+        return .{ .ptr = self, .vtable = &.{ .send = send, .receive = receive } };
+    }
+
+    fn send(ptr: *anyopaque, player: u8, bytes: []const u8) void {
+        const self: *UdpTransport = @ptrCast(@alignCast(ptr));
+        const peer = self.peers[player] orelse return;
+        // A full socket buffer or an unreachable host loses the packet, as the network could
+        _ = std.posix.sendto(self.socket, bytes, 0, &peer.any, peer.getOsSockLen()) catch {};
+    }
+
+    fn receive(ptr: *anyopaque, buffer: []u8) ?[]u8 {
+        const self: *UdpTransport = @ptrCast(@alignCast(ptr));
+        const len = std.posix.recvfrom(self.socket, buffer, 0, null, null) catch return null;
+        return buffer[0..len];
+    }
+};
+
+/// In-process network between the players of several sessions, for tests and local play. Packets
+/// arrive `latency` steps after they were sent; `drop_every` loses every n-th one. The network
+/// must not move once transports were handed out.
+///
+/// Usage:
+///   var network = LoopbackNetwork.init(allocator);
+///   network.latency = 3;
+///   var host = try Session(GameECS).init(allocator, &host_world, network.transport(0), .{ .local_player = 0 });
+///   var guest = try Session(GameECS).init(allocator, &guest_world, network.transport(1), .{ .local_player = 1 });
+///   // every tick: advance both sessions, then network.step()
+pub const LoopbackNetwork = struct {
+    const Packet = struct {
+        to: u8,
+        due: u64,
+        bytes: []u8,
+    };
+
+    const Endpoint = struct {
+        network: *LoopbackNetwork,
+        player: u8,
+    };
+
+    allocator: std.mem.Allocator,
+    pending: std.ArrayListUnmanaged(Packet) = .{},
+    endpoints: [max_players]Endpoint = undefined,
+    /// Steps taken so far
+    now: u64 = 0,
+    /// Steps between sending a packet and it becoming receivable
+    latency: u64 = 0,
+    /// Lose every n-th packet sent (0 delivers everything)
+    drop_every: u32 = 0,
+    sent: u64 = 0,
+    dropped: u64 = 0,
+
+    pub fn init(allocator: std.mem.Allocator) LoopbackNetwork {
+        return .{ .allocator = allocator };
+    }
+
+    pub fn deinit(self: *LoopbackNetwork) void {
+        for (self.pending.items) |packet| self.allocator.free(packet.bytes);
+        self.pending.deinit(self.allocator);
+    }
+
+    /// Transport of `player` on this network
+    pub fn transport(self: *LoopbackNetwork, player: u8) Transport {
+        self.endpoints[player] = .{ .network = self, .player = player };
+        return .{ .ptr = &self.endpoints[player], .vtable = &.{ .send = send, .receive = receive } };
+    }
+
+    /// Let one step of time pass
+    pub fn step(self: *LoopbackNetwork) void {
+        self.now += 1;
+    }
+
+    fn send(ptr: *anyopaque, player: u8, bytes: []const u8) void {
+        const endpoint: *Endpoint = @ptrCast(@alignCast(ptr));
+        const self = endpoint.network;
+        self.sent += 1;
+        if (self.drop_every != 0 and self.sent % self.drop_every == 0) {
+            self.dropped += 1;
+            return;
+        }
+        // Out of memory loses the packet, like a full socket buffer would
+        const copy = self.allocator.dupe(u8, bytes) catch return;
+        self.pending.append(self.allocator, .{ .to = player, .due = self.now + self.latency, .bytes = copy }) catch self.allocator.free(copy);
+    }
+
+    fn receive(ptr: *anyopaque, buffer: []u8) ?[]u8 {
+        const endpoint: *Endpoint = @ptrCast(@alignCast(ptr));
+        const self = endpoint.network;
+        for (self.pending.items, 0..) |packet, i| {
+            if (packet.to != endpoint.player or packet.due > self.now) continue;
+            _ = self.pending.orderedRemove(i);
+            defer self.allocator.free(packet.bytes);
+            const len = @min(packet.bytes.len, buffer.len);
+            @memcpy(buffer[0..len], packet.bytes[0..len]);
+            return buffer[0..len];
+        }
+        return null;
+    }
+};
+
+/// How a session's link to one remote player is doing
+pub const NetworkStats = struct {
+    /// Smoothed round trip, 0 until the first reply
+    rtt_ns: u64,
+    /// Ticks the local simulation runs ahead of the peer's (negative: behind), allowing for the
+    /// half round trip the peer's last report spent in flight. A player persistently ahead forces
+    /// the other to roll back more and should slow down.
+    frame_advantage: i64,
+    /// Newest tick we have the peer's input for
+    confirmed_frame: u64,
+    /// Local inputs the peer has not acknowledged yet
+    pending_inputs: u64,
+};
+
+// The per-player array a session's frame input has to be
+fn playerInputs(comptime FrameInput: type) std.builtin.Type.Array {
+    const info = switch (@typeInfo(FrameInput)) {
+        .array => |info| info,
+        else => @compileError("A netcode session needs the frame input to hold one input per player, like [2]PadInput"),
+    };
+    if (info.len < 2 or info.len > max_players) @compileError("A netcode session has 2 to 8 players");
+    return info;
+}
+
+// Packet layout shared by `Session` and `LockstepSession`, little-endian:
+//
+//   magic  u8 protocol_version  u8 sender
+//   u64 sender's tick  u64 newest input of the recipient the sender has
+//   fields of the session kind
+//   u64 first input tick  u16 input count, inputs (encodeValue)
+const PacketHeader = struct {
+    sender: u8,
+    tick: u64,
+    ack: u64,
+
+    fn write(self: PacketHeader, comptime magic: []const u8, writer: anytype) void {
+        // The header always fits
+        writer.writeAll(magic) catch unreachable;
+        writer.writeByte(protocol_version) catch unreachable;
+        writer.writeByte(self.sender) catch unreachable;
+        writer.writeInt(u64, self.tick, .little) catch unreachable;
+        writer.writeInt(u64, self.ack, .little) catch unreachable;
+    }
+
+    // Fails on other packet kinds, other versions and senders that aren't a remote player
+    fn read(comptime magic: []const u8, reader: anytype, players: usize, local_player: u8) !PacketHeader {
+        var found: [magic.len]u8 = undefined;
+        reader.readNoEof(&found) catch return error.InvalidPacket;
+        if (!std.mem.eql(u8, &found, magic)) return error.InvalidPacket;
+        if (try reader.readByte() != protocol_version) return error.UnsupportedVersion;
+        const sender = try reader.readByte();
+        if (sender >= players or sender == local_player) return error.InvalidPacket;
+        return .{
+            .sender = sender,
+            .tick = try reader.readInt(u64, .little),
+            .ack = try reader.readInt(u64, .little),
+        };
+    }
+
+    // Note the sender's progress on its peer state - acknowledgements never pass what we sent
+    fn apply(self: PacketHeader, peer: anytype, local_confirmed: u64) void {
+        peer.remote_frame = @max(peer.remote_frame, self.tick);
+        peer.acked = @max(peer.acked, @min(self.ack, local_confirmed));
+    }
+};
+
+// Append the inputs of ticks `first` through `last` from `inputs`, a ring indexed by tick -
+// oldest first, as far as they fit the packet
+fn writeInputs(comptime Input: type, stream: *std.io.FixedBufferStream([]u8), inputs: []const Input, first: u64, last: u64) void {
+    const writer = stream.writer();
+    writer.writeInt(u64, first, .little) catch unreachable;
+    const count_at = stream.pos;
+    writer.writeInt(u16, 0, .little) catch unreachable;
+    const ring: u64 = inputs.len;
+    var count: u16 = 0;
+    var tick = first;
+    while (tick <= last) : (tick += 1) {
+        const mark = stream.pos;
+        schema.encodeValue(Input, inputs[@intCast(tick % ring)], writer) catch {
+            stream.pos = mark;
+            break;
+        };
+        count += 1;
+    }
+    std.mem.writeInt(u16, stream.buffer[count_at..][0..2], count, .little);
+}
+
+// Read an input run into `inputs`, a ring indexed by tick, moving `confirmed` over every tick
+// that continues it and handing each to `accepted`. Ticks already known are skipped; a gap
+// (reordered packet) or a tick too far past `current` for the ring waits for a resend.
+fn readInputs(
+    comptime Input: type,
+    reader: anytype,
+    inputs: []Input,
+    confirmed: *u64,
+    current: u64,
+    context: anytype,
+    comptime accepted: fn (@TypeOf(context), u64, Input) void,
+) !void {
+    const ring: u64 = inputs.len;
+    const first = try reader.readInt(u64, .little);
+    const count = try reader.readInt(u16, .little);
+    for (0..count) |i| {
+        const input = try schema.decodeValue(Input, reader);
+        const tick = first + i;
+        if (tick <= confirmed.*) continue;
+        if (tick > confirmed.* + 1 or tick > current + ring / 2) break;
+
+        inputs[@intCast(tick % ring)] = input;
+        confirmed.* = tick;
+        accepted(context, tick, input);
+    }
+}
+
+// Take in every packet waiting on a session's transport, counting and logging the ones it rejects
+fn pollPackets(session: anytype) void {
+    var buffer: [max_packet_size]u8 = undefined;
+    while (session.transport.receive(&buffer)) |bytes| {
+        session.handlePacket(bytes) catch |err| {
+            session.invalid_packets += 1;
+            session.logger.warn("netcode_invalid_packet", &.{
+                field("error", @errorName(err)),
+                field("bytes", bytes.len),
+            });
+        };
+    }
+}
+
+/// Rollback netcode session in the style of GGPO for a fixed set of players, each on their own
+/// machine running the same simulation.
+///
+/// The frame input must be an array with one entry per player (`.input = [2]PadInput`). Every
+/// tick `advance` takes the local player's input, sends it to the peers and simulates with the
+/// newest input known for everyone else - a remote player's last confirmed input is repeated as
+/// the prediction. When a remote input arrives that differs from what was predicted, the world is
+/// rolled back to the tick before it and the ticks since are simulated again. `input_delay` holds
+/// local inputs back a few ticks so short round trips never roll back at all; `max_prediction`
+/// bounds how far the session runs ahead of a silent peer before it waits for it.
+///
+/// All sessions must start from identical worlds and use identical systems. Frames before the
+/// first input use the all-zero input. Joining, disconnects and desync reporting are left to the
+/// host: compare `frame.checksum()` of confirmed ticks with `NetcodeRollback.confirmHash`.
+///
+/// Usage:
+///   var session = try Session(GameECS).init(allocator, &world, udp.transport(), .{ .local_player = 0 });
+///   defer session.deinit();
+///   while (running) {
+///       if (pacer.poll(std.time.ns_per_ms)) _ = try session.advance(readPad(), &schedule);
+///   }
+pub fn Session(comptime EcsType: type) type {
+    const FrameInput = @FieldType(EcsType.Frame, "input");
+    const input_info = playerInputs(FrameInput);
+    const players = input_info.len;
+
+    return struct {
+        const Self = @This();
+        const History = FrameHistory(EcsType);
+
+        pub const Input = input_info.child;
+
+        pub const Options = struct {
+            /// Player whose input `advance` takes
+            local_player: u8,
+            /// Ticks between reading a local input and simulating it, at most 16
+            input_delay: u8 = 2,
+            /// Ticks the simulation may run past the newest input of a peer, 1 to 32
+            max_prediction: u8 = 8,
+            /// Fixed tick length
+            delta_time: f32 = 1.0 / 60.0,
+        };
+
+        // Input ticks kept per player - enough for the furthest a peer's acknowledgement can
+        // trail with the largest delay and prediction allowed
+        const ring_size = 128;
+        const neutral = std.mem.zeroes(Input);
+
+        const Peer = struct {
+            /// Input of every tick up to `confirmed`
+            inputs: [ring_size]Input = [_]Input{neutral} ** ring_size,
+            /// Input each tick was simulated with - a prediction while after `confirmed`
+            used: [ring_size]Input = [_]Input{neutral} ** ring_size,
+            /// Newest tick whose input, and every one before it, is known
+            confirmed: u64,
+            /// Newest tick of our input the peer has (remote peers only)
+            acked: u64,
+            /// The peer's tick as of its latest packet
+            remote_frame: u64,
+            rtt_ns: u64 = 0,
+            /// Send time of the peer's latest packet and when we got it, echoed back for the RTT
+            echo: ?u64 = null,
+            echo_received: u64 = 0,
+
+            fn inputFor(self: *const Peer, frame: u64) Input {
+                return self.inputs[slot(@min(frame, self.confirmed))];
+            }
+        };
+
+        world: *EcsType,
+        transport: Transport,
+        options: Options,
+        history: History,
+        peers: [players]Peer,
+        /// Oldest simulated tick a confirmed input proved mispredicted
+        first_incorrect: ?u64 = null,
+
+        /// Time source for round trips
+        clock: Clock = Clock.real,
+        /// Receives malformed packets
+        logger: Logger = Logger.noop,
+        /// Rollbacks, ticks simulated again, ticks spent waiting on a peer and packets rejected
+        rollbacks: u64 = 0,
+        resimulated: u64 = 0,
+        stalls: u64 = 0,
+        invalid_packets: u64 = 0,
+
+        /// Start a session from the world's current frame
+        pub fn init(allocator: std.mem.Allocator, world: *EcsType, transport: Transport, options: Options) !Self {
+            if (options.local_player >= players or options.input_delay > 16 or
+                options.max_prediction == 0 or options.max_prediction > 32) return error.InvalidOptions;
+
+            var history = try History.init(allocator, @as(usize, options.max_prediction) + 2);
+            errdefer history.deinit();
+            try history.record(world);
+
+            // The delay ticks are known to everyone up front
+            const start = world.current_frame.frame_number;
+            const initial = Peer{
+                .confirmed = start + options.input_delay,
+                .acked = start + options.input_delay,
+                .remote_frame = start,
+            };
+            return .{
+                .world = world,
+                .transport = transport,
+                .options = options,
+                .history = history,
+                .peers = [_]Peer{initial} ** players,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.history.deinit();
+        }
+
+        /// Tick the world is at
+        pub fn frame(self: *const Self) u64 {
+            return self.world.current_frame.frame_number;
+        }
+
+        /// Newest tick every player's input is known for - it will never be rolled back
+        pub fn confirmedFrame(self: *const Self) u64 {
+            var confirmed = self.frame();
+            for (&self.peers) |*peer| confirmed = @min(confirmed, peer.confirmed);
+            return confirmed;
+        }
+
+        /// Simulate the next tick through `systems` (anything with `run(*Frame) !void`, like a
+        /// `Schedule`), after receiving packets and correcting mispredicted ticks. `input` is the
+        /// local player's input for the tick `input_delay` ticks ahead. Returns false without
+        /// simulating when a peer fell `max_prediction` ticks behind - pass the same input again
+        /// on the next tick.
+        pub fn advance(self: *Self, input: Input, systems: anytype) !bool {
+            self.poll();
+            try self.correct(systems);
+
+            const next = self.frame() + 1;
+            var newest_remote: u64 = std.math.maxInt(u64);
+            for (&self.peers, 0..) |*peer, player| {
+                if (player != self.options.local_player) newest_remote = @min(newest_remote, peer.confirmed);
+            }
+            if (next > newest_remote + self.options.max_prediction) {
+                self.stalls += 1;
+                self.sendInputs();
+                return false;
+            }
+
+            const local = &self.peers[self.options.local_player];
+            local.confirmed += 1;
+            local.inputs[slot(local.confirmed)] = input;
+            try self.simulate(next, systems);
+            self.sendInputs();
+            return true;
+        }
+
+        /// Take in every waiting packet. `advance` polls on its own; call this between ticks to
+        /// keep round trips accurate when ticks are far apart.
+        pub fn poll(self: *Self) void {
+            pollPackets(self);
+        }
+
+        pub fn stats(self: *const Self, player: u8) NetworkStats {
+            const peer = &self.peers[player];
+            const tick_ns: u64 = @intFromFloat(@as(f64, self.options.delta_time) * std.time.ns_per_s);
+            const in_flight = if (tick_ns == 0) 0 else peer.rtt_ns / 2 / tick_ns;
+            const local = &self.peers[self.options.local_player];
+            return .{
+                .rtt_ns = peer.rtt_ns,
+                .frame_advantage = @as(i64, @intCast(self.frame())) - @as(i64, @intCast(peer.remote_frame + in_flight)),
+                .confirmed_frame = peer.confirmed,
+                .pending_inputs = local.confirmed - @min(peer.acked, local.confirmed),
+            };
+        }
+
+        fn slot(frame_number: u64) usize {
+            return @intCast(frame_number % ring_size);
+        }
+
+        fn simulate(self: *Self, frame_number: u64, systems: anytype) !void {
+            var input: FrameInput = undefined;
+            for (&self.peers, &input) |*peer, *player_input| {
+                player_input.* = peer.inputFor(frame_number);
+                peer.used[slot(frame_number)] = player_input.*;
+            }
+            const time = self.world.current_frame.time + self.options.delta_time;
+            self.world.update(input, self.options.delta_time, time);
+            try systems.run(self.world.getFrame());
+            try self.history.record(self.world);
+        }
+
+        // Roll back to before the oldest mispredicted tick and simulate up to where we were
+        fn correct(self: *Self, systems: anytype) !void {
+            const first = self.first_incorrect orelse return;
+            self.first_incorrect = null;
+
+            const current = self.frame();
+            try self.history.rollbackTo(self.world, first - 1);
+            self.rollbacks += 1;
+            var frame_number = first;
+            while (frame_number <= current) : (frame_number += 1) {
+                try self.simulate(frame_number, systems);
+                self.resimulated += 1;
+            }
+        }
+
+        // Packet fields after the shared header (see `PacketHeader`), little-endian:
+        //
+        //   u64 send time  ?u64 echoed send time (encodeValue)  u64 time the echo was held
+        fn sendInputs(self: *Self) void {
+            const local_player = self.options.local_player;
+            const local = &self.peers[local_player];
+            const now = self.clock.now();
+
+            for (&self.peers, 0..) |*peer, player| {
+                if (player == local_player) continue;
+
+                var buffer: [max_packet_size]u8 = undefined;
+                var stream = std.io.fixedBufferStream(&buffer);
+                const writer = stream.writer();
+                const header = PacketHeader{ .sender = local_player, .tick = self.frame(), .ack = peer.confirmed };
+                header.write(packet_magic, writer);
+                writer.writeInt(u64, now, .little) catch unreachable;
+                schema.encodeValue(?u64, peer.echo, writer) catch unreachable;
+                writer.writeInt(u64, now -| peer.echo_received, .little) catch unreachable;
+
+                // Everything the peer has not acknowledged
+                writeInputs(Input, &stream, &local.inputs, peer.acked + 1, local.confirmed);
+                self.transport.send(@intCast(player), buffer[0..stream.pos]);
+            }
+        }
+
+        fn handlePacket(self: *Self, bytes: []const u8) !void {
+            var stream = std.io.fixedBufferStream(bytes);
+            const reader = stream.reader();
+
+            const header = try PacketHeader.read(packet_magic, reader, players, self.options.local_player);
+            const sent_at = try reader.readInt(u64, .little);
+            const echo = try schema.decodeValue(?u64, reader);
+            const echo_held = try reader.readInt(u64, .little);
+
+            const peer = &self.peers[header.sender];
+            const now = self.clock.now();
+            header.apply(peer, self.peers[self.options.local_player].confirmed);
+            if (echo) |echoed| {
+                const sample = now -| echoed -| echo_held;
+                peer.rtt_ns = if (peer.rtt_ns == 0) sample else (peer.rtt_ns * 7 + sample) / 8;
+            }
+            peer.echo = sent_at;
+            peer.echo_received = now;
+
+            try readInputs(Input, reader, &peer.inputs, &peer.confirmed, self.frame(), Confirmation{ .session = self, .peer = peer }, Confirmation.check);
+        }
+
+        // Flags an arriving input that differs from the one a simulated tick predicted
+        const Confirmation = struct {
+            session: *Self,
+            peer: *const Peer,
+
+            fn check(self: Confirmation, frame_number: u64, input: Input) void {
+                if (frame_number > self.session.frame() or std.meta.eql(self.peer.used[slot(frame_number)], input)) return;
+                self.session.first_incorrect = @min(self.session.first_incorrect orelse frame_number, frame_number);
+            }
+        };
+    };
+}
+
+/// Leading bytes of every lockstep session packet - distinct from `packet_magic`, so a rollback
+/// and a lockstep peer never mistake each other's inputs
+pub const lockstep_magic = "RWLS";
+
+/// Deterministic lockstep session for a fixed set of players: no tick is simulated before every
+/// player's input for it arrived, so nothing is ever predicted or rolled back. The price is
+/// latency - `input_delay` ticks pass between reading a local input and simulating it, and a
+/// peer whose input is late stalls everyone. Suits strategy games and anything whose world is
+/// too big to snapshot every tick.
+///
+/// As with `Session`, the frame input is an array with one entry per player, all sessions start
+/// from identical worlds with identical systems, and frames before the first input use the
+/// all-zero input. Every `checksum_interval` ticks the peers exchange `frame.checksum()` of that
+/// tick; a mismatch is counted in `desyncs` and logged as `desync_detected`. A peer that leaves
+/// the session waiting for more than `timeout_ns` makes `advance` fail with
+/// error.PeerTimedOut - which player to drop, and from which tick, is for the host to agree on.
+///
+/// Usage:
+///   var session = try LockstepSession(GameECS).init(&world, udp.transport(), .{ .local_player = 0 });
+///   while (running) {
+///       if (pacer.poll(std.time.ns_per_ms)) _ = try session.advance(readOrders(), &schedule);
+///   }
+pub fn LockstepSession(comptime EcsType: type) type {
+    const FrameInput = @FieldType(EcsType.Frame, "input");
+    const input_info = playerInputs(FrameInput);
+    const players = input_info.len;
+
+    return struct {
+        const Self = @This();
+
+        pub const Input = input_info.child;
+
+        pub const Options = struct {
+            /// Player whose input `advance` takes
+            local_player: u8,
+            /// Ticks between reading a local input and simulating it, at most 16 - enough to
+            /// cover the round trip keeps the session from stalling
+            input_delay: u8 = 4,
+            /// Fixed tick length
+            delta_time: f32 = 1.0 / 60.0,
+            /// Longest a session waits on a peer's input before giving up
+            timeout_ns: u64 = 5 * std.time.ns_per_s,
+            /// Ticks between checksum exchanges, 0 for none
+            checksum_interval: u32 = 30,
+        };
+
+        // Input ticks kept per player - a peer is never more than `input_delay` + 1 ticks ahead
+        const ring_size = 64;
+        const neutral = std.mem.zeroes(Input);
+
+        /// A tick's checksum, tick 0 for none
+        const Checksum = struct {
+            frame: u64 = 0,
+            hash: u64 = 0,
+        };
+        // Local checksums kept for peers that report theirs late
+        const checksum_ring = 4;
+
+        const Peer = struct {
+            /// Input of every tick up to `confirmed`
+            inputs: [ring_size]Input = [_]Input{neutral} ** ring_size,
+            /// Newest tick whose input, and every one before it, is known
+            confirmed: u64,
+            /// Newest tick of our input the peer has (remote peers only)
+            acked: u64,
+            /// The peer's tick as of its latest packet
+            remote_frame: u64,
+            /// Newest checksum the peer reported, and the newest tick compared
+            checksum: Checksum = .{},
+            verified: u64 = 0,
+        };
+
+        world: *EcsType,
+        transport: Transport,
+        options: Options,
+        peers: [players]Peer,
+        checksums: [checksum_ring]Checksum = [_]Checksum{.{}} ** checksum_ring,
+        /// When the session started waiting on a late input, null while it keeps up
+        waiting_since: ?u64 = null,
+
+        /// Time source for timeouts
+        clock: Clock = Clock.real,
+        /// Receives malformed packets and desyncs
+        logger: Logger = Logger.noop,
+        /// Ticks spent waiting on a peer, packets rejected, checksums compared and checksums
+        /// that differed
+        stalls: u64 = 0,
+        invalid_packets: u64 = 0,
+        verified: u64 = 0,
+        desyncs: u64 = 0,
+        /// Oldest tick a peer's checksum differed on
+        desync_frame: ?u64 = null,
+
+        /// Start a session from the world's current frame
+        pub fn init(world: *EcsType, transport: Transport, options: Options) !Self {
+            if (options.local_player >= players or options.input_delay > 16) return error.InvalidOptions;
+
+            // The delay ticks are known to everyone up front
+            const start = world.current_frame.frame_number;
+            const initial = Peer{
+                .confirmed = start + options.input_delay,
+                .acked = start + options.input_delay,
+                .remote_frame = start,
+            };
+            return .{
+                .world = world,
+                .transport = transport,
+                .options = options,
+                .peers = [_]Peer{initial} ** players,
+            };
+        }
+
+        /// Tick the world is at - every tick up to it is final
+        pub fn frame(self: *const Self) u64 {
+            return self.world.current_frame.frame_number;
+        }
+
+        /// Remote player the session is stuck on, null when every input for the next tick is in
+        pub fn waitingOn(self: *const Self) ?u8 {
+            const next = self.frame() + 1;
+            for (&self.peers, 0..) |*peer, player| {
+                if (player != self.options.local_player and peer.confirmed < next) return @intCast(player);
+            }
+            return null;
+        }
+
+        /// Simulate the next tick through `systems` (anything with `run(*Frame) !void`, like a
+        /// `Schedule`) once every player's input for it arrived. `input` is the local player's
+        /// input for the tick `input_delay` ticks ahead and goes out right away. Returns false
+        /// without simulating while a peer's input is missing - the inputs of further calls are
+        /// ignored until the tick runs - and fails with error.PeerTimedOut once that has lasted
+        /// `timeout_ns`.
+        pub fn advance(self: *Self, input: Input, systems: anytype) !bool {
+            self.poll();
+
+            const local = &self.peers[self.options.local_player];
+            if (local.confirmed < self.frame() + 1 + self.options.input_delay) {
+                local.confirmed += 1;
+                local.inputs[slot(local.confirmed)] = input;
+            }
+
+            if (self.waitingOn()) |player| {
+                self.stalls += 1;
+                self.sendInputs();
+                const now = self.clock.now();
+                const since = self.waiting_since orelse now;
+                self.waiting_since = since;
+                if (now - since > self.options.timeout_ns) {
+                    self.logger.warn("lockstep_peer_timed_out", &.{
+                        field("player", player),
+                        field("frame", self.frame()),
+                        field("waited_ns", now - since),
+                    });
+                    return error.PeerTimedOut;
+                }
+                return false;
+            }
+            self.waiting_since = null;
+
+            try self.simulate(systems);
+            self.sendInputs();
+            return true;
+        }
+
+        /// Take in every waiting packet. `advance` polls on its own.
+        pub fn poll(self: *Self) void {
+            pollPackets(self);
+        }
+
+        fn slot(frame_number: u64) usize {
+            return @intCast(frame_number % ring_size);
+        }
+
+        // Lockstep never predicts, so accepted inputs need no further check
+        fn ignoreInput(_: void, _: u64, _: Input) void {}
+
+        fn simulate(self: *Self, systems: anytype) !void {
+            const frame_number = self.frame() + 1;
+            var input: FrameInput = undefined;
+            for (&self.peers, &input) |*peer, *player_input| {
+                player_input.* = peer.inputs[slot(frame_number)];
+            }
+            const time = self.world.current_frame.time + self.options.delta_time;
+            self.world.update(input, self.options.delta_time, time);
+            try systems.run(self.world.getFrame());
+
+            const interval = self.options.checksum_interval;
+            if (interval == 0 or frame_number % interval != 0) return;
+            const local = Checksum{ .frame = frame_number, .hash = self.world.getFrame().checksum() };
+            self.checksums[(frame_number / interval) % checksum_ring] = local;
+            for (&self.peers, 0..) |*peer, player| {
+                if (player != self.options.local_player) self.verify(@intCast(player), peer.checksum);
+            }
+        }
+
+        // Compare a peer's checksum with ours for the same tick, once we have it
+        fn verify(self: *Self, player: u8, remote: Checksum) void {
+            const peer = &self.peers[player];
+            const interval = self.options.checksum_interval;
+            if (remote.frame == 0 or interval == 0 or remote.frame <= peer.verified) return;
+            const local = self.checksums[(remote.frame / interval) % checksum_ring];
+            if (local.frame != remote.frame) return;
+
+            peer.verified = remote.frame;
+            self.verified += 1;
+            if (local.hash == remote.hash) return;
+            self.desyncs += 1;
+            self.desync_frame = @min(self.desync_frame orelse remote.frame, remote.frame);
+            self.logger.warn("desync_detected", &.{
+                field("tick", remote.frame),
+                field("player", player),
+                field("local_hash", local.hash),
+                field("remote_hash", remote.hash),
+            });
+        }
+
+        // Packet fields after the shared header (see `PacketHeader`), little-endian:
+        //
+        //   u64 checksum tick (0 for none)  u64 checksum
+        fn sendInputs(self: *Self) void {
+            const local_player = self.options.local_player;
+            const local = &self.peers[local_player];
+            const interval = self.options.checksum_interval;
+            const latest = if (interval == 0) Checksum{} else self.checksums[(self.frame() / interval) % checksum_ring];
+
+            for (&self.peers, 0..) |*peer, player| {
+                if (player == local_player) continue;
+
+                var buffer: [max_packet_size]u8 = undefined;
+                var stream = std.io.fixedBufferStream(&buffer);
+                const writer = stream.writer();
+                const header = PacketHeader{ .sender = local_player, .tick = self.frame(), .ack = peer.confirmed };
+                header.write(lockstep_magic, writer);
+                writer.writeInt(u64, latest.frame, .little) catch unreachable;
+                writer.writeInt(u64, latest.hash, .little) catch unreachable;
+
+                // Everything the peer has not acknowledged
+                writeInputs(Input, &stream, &local.inputs, peer.acked + 1, local.confirmed);
+                self.transport.send(@intCast(player), buffer[0..stream.pos]);
+            }
+        }
+
+        fn handlePacket(self: *Self, bytes: []const u8) !void {
+            var stream = std.io.fixedBufferStream(bytes);
+            const reader = stream.reader();
+
+            const header = try PacketHeader.read(lockstep_magic, reader, players, self.options.local_player);
+            const checksum = Checksum{
+                .frame = try reader.readInt(u64, .little),
+                .hash = try reader.readInt(u64, .little),
+            };
+
+            const peer = &self.peers[header.sender];
+            header.apply(peer, self.peers[self.options.local_player].confirmed);
+            if (checksum.frame > peer.checksum.frame) {
+                peer.checksum = checksum;
+                self.verify(header.sender, checksum);
+            }
+
+            try readInputs(Input, reader, &peer.inputs, &peer.confirmed, self.frame(), {}, ignoreInput);
+        }
+    };
+}
@@ -0,0 +1,283 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const netcode = @import("netcode.zig");
+const ManualClock = @import("clock.zig").ManualClock;
+
+const Pad = struct {
+    dx: i8 = 0,
+    jump: bool = false,
+};
+
+const Position = struct { x: i32, y: i32 };
+const Player = struct { index: u8 };
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Player },
+    .input = [2]Pad,
+    .max_entities = .tiny,
+});
+
+const Session = netcode.Session(TestECS);
+const Lockstep = netcode.LockstepSession(TestECS);
+
+// Each player's avatar follows its pad
+const Movement = struct {
+    fn run(_: *const Movement, frame: *TestECS.Frame) !void {
+        var query = try frame.query(&.{ Position, Player });
+        while (query.next()) |result| {
+            const pad = frame.input[result.get(Player).index];
+            const position = result.get(Position);
+            position.x += pad.dx;
+            position.y = if (pad.jump) position.y + 3 else @max(position.y - 1, 0);
+        }
+    }
+};
+
+fn spawnPlayers(world: *TestECS) !void {
+    const frame = world.getFrame();
+    for (0..2) |index| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        try frame.addComponent(entity, Player{ .index = @intCast(index) });
+    }
+}
+
+// What each player presses on each tick - changes often enough to defeat the prediction
+fn scripted(player: u8, frame_number: u64) Pad {
+    if (player == 0) return .{ .dx = @as(i8, @intCast(frame_number % 5)) - 2, .jump = frame_number % 7 == 0 };
+    return .{ .dx = if (frame_number % 11 < 5) 1 else -1, .jump = frame_number % 4 == 1 };
+}
+
+test "Sessions over a lossy, laggy link agree on every confirmed tick" {
+    var clock = ManualClock{};
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+    network.latency = 3;
+    network.drop_every = 5;
+
+    var worlds: [2]TestECS = undefined;
+    var sessions: [2]Session = undefined;
+    for (&worlds, &sessions, 0..) |*world, *session, player| {
+        world.* = try TestECS.init(testing.allocator);
+        try spawnPlayers(world);
+        session.* = try Session.init(testing.allocator, world, network.transport(@intCast(player)), .{ .local_player = @intCast(player) });
+        session.clock = clock.clock();
+    }
+    defer for (&worlds, &sessions) |*world, *session| {
+        session.deinit();
+        world.deinit();
+    }
+
+    for (0..120) |_| {
+        for (&sessions, 0..) |*session, player| {
+            const input_frame = session.frame() + 1 + session.options.input_delay;
+            _ = try session.advance(scripted(@intCast(player), input_frame), &Movement{});
+        }
+        network.step();
+        clock.advance(16 * std.time.ns_per_ms);
+    }
+
+    // Three ticks of latency against two of delay - predictions were wrong and got corrected
+    try testing.expect(network.dropped > 0);
+    for (&sessions) |*session| {
+        try testing.expect(session.frame() > 100);
+        try testing.expect(session.rollbacks > 0);
+        try testing.expectEqual(@as(u64, 0), session.invalid_packets);
+        const stats = session.stats(1 - session.options.local_player);
+        try testing.expect(stats.rtt_ns > 0);
+        try testing.expect(@abs(stats.frame_advantage) <= 4);
+    }
+
+    // Both agree with a plain simulation of the real inputs up to the tick both confirmed
+    const confirmed = @min(sessions[0].confirmedFrame(), sessions[1].confirmedFrame());
+    try testing.expect(confirmed > 90);
+
+    var reference = try TestECS.init(testing.allocator);
+    defer reference.deinit();
+    try spawnPlayers(&reference);
+    var frame_number: u64 = 1;
+    while (frame_number <= confirmed) : (frame_number += 1) {
+        var input: [2]Pad = .{ .{}, .{} };
+        // Inputs start after the delay
+        if (frame_number > 2) input = .{ scripted(0, frame_number), scripted(1, frame_number) };
+        reference.update(input, 1.0 / 60.0, reference.getFrame().time + 1.0 / 60.0);
+        try (Movement{}).run(reference.getFrame());
+    }
+    for (&sessions) |*session| {
+        try testing.expectEqual(reference.getFrame().checksum(), session.history.get(confirmed).?.checksum());
+    }
+}
+
+test "A session waits once a silent peer is max_prediction ticks behind" {
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    try spawnPlayers(&world);
+    var session = try Session.init(testing.allocator, &world, network.transport(0), .{
+        .local_player = 0,
+        .input_delay = 1,
+        .max_prediction = 4,
+    });
+    defer session.deinit();
+
+    // The peer's delay tick is known, then four predicted ticks
+    var advanced: u32 = 0;
+    for (0..10) |_| {
+        if (try session.advance(.{ .dx = 1 }, &Movement{})) advanced += 1;
+    }
+    try testing.expectEqual(@as(u32, 5), advanced);
+    try testing.expectEqual(@as(u64, 5), session.stalls);
+    try testing.expectEqual(@as(u64, 1), session.confirmedFrame());
+    try testing.expectEqual(@as(u64, 5), session.stats(1).pending_inputs);
+
+    // Garbage is counted and ignored
+    network.transport(1).send(0, "not a session packet");
+    try testing.expect(!try session.advance(.{ .dx = 1 }, &Movement{}));
+    try testing.expectEqual(@as(u64, 1), session.invalid_packets);
+
+    try testing.expectError(error.InvalidOptions, Session.init(testing.allocator, &world, network.transport(0), .{ .local_player = 2 }));
+}
+
+test "Lockstep sessions simulate only confirmed ticks and agree on checksums" {
+    var clock = ManualClock{};
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+    network.latency = 2;
+    network.drop_every = 5;
+
+    var worlds: [2]TestECS = undefined;
+    var sessions: [2]Lockstep = undefined;
+    for (&worlds, &sessions, 0..) |*world, *session, player| {
+        world.* = try TestECS.init(testing.allocator);
+        try spawnPlayers(world);
+        session.* = try Lockstep.init(world, network.transport(@intCast(player)), .{
+            .local_player = @intCast(player),
+            .input_delay = 3,
+            .checksum_interval = 10,
+        });
+        session.clock = clock.clock();
+    }
+    defer for (&worlds) |*world| world.deinit();
+
+    for (0..150) |_| {
+        for (&sessions, 0..) |*session, player| {
+            const input_frame = session.frame() + 1 + session.options.input_delay;
+            _ = try session.advance(scripted(@intCast(player), input_frame), &Movement{});
+        }
+        network.step();
+        clock.advance(16 * std.time.ns_per_ms);
+    }
+
+    for (&sessions) |*session| {
+        try testing.expect(session.frame() > 100);
+        try testing.expect(session.verified >= 9);
+        try testing.expectEqual(@as(u64, 0), session.desyncs);
+        try testing.expectEqual(@as(u64, 0), session.invalid_packets);
+    }
+
+    // Every simulated tick is final: both worlds match a plain simulation of the real inputs
+    var reference = try TestECS.init(testing.allocator);
+    defer reference.deinit();
+    try spawnPlayers(&reference);
+    const behind: usize = if (sessions[0].frame() <= sessions[1].frame()) 0 else 1;
+    for ([_]usize{ behind, 1 - behind }) |index| {
+        while (reference.getFrame().frame_number < sessions[index].frame()) {
+            const frame_number = reference.getFrame().frame_number + 1;
+            var input: [2]Pad = .{ .{}, .{} };
+            if (frame_number > 3) input = .{ scripted(0, frame_number), scripted(1, frame_number) };
+            reference.update(input, 1.0 / 60.0, reference.getFrame().time + 1.0 / 60.0);
+            try (Movement{}).run(reference.getFrame());
+        }
+        try testing.expectEqual(reference.getFrame().checksum(), worlds[index].getFrame().checksum());
+    }
+}
+
+test "Lockstep sessions wait on a silent peer and give up after the timeout" {
+    var clock = ManualClock{};
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    try spawnPlayers(&world);
+    var session = try Lockstep.init(&world, network.transport(0), .{
+        .local_player = 0,
+        .input_delay = 1,
+        .timeout_ns = std.time.ns_per_s,
+    });
+    session.clock = clock.clock();
+
+    // Only the delay tick is known
+    var advanced: u32 = 0;
+    for (0..5) |_| {
+        if (try session.advance(.{ .dx = 1 }, &Movement{})) advanced += 1;
+        clock.advance(100 * std.time.ns_per_ms);
+    }
+    try testing.expectEqual(@as(u32, 1), advanced);
+    try testing.expectEqual(@as(u64, 4), session.stalls);
+    try testing.expectEqual(@as(?u8, 1), session.waitingOn());
+
+    // Rollback packets are not lockstep packets
+    network.transport(1).send(0, netcode.packet_magic ++ "\x01\x01");
+    clock.advance(std.time.ns_per_s);
+    try testing.expectError(error.PeerTimedOut, session.advance(.{ .dx = 1 }, &Movement{}));
+    try testing.expectEqual(@as(u64, 1), session.invalid_packets);
+
+    try testing.expectError(error.InvalidOptions, Lockstep.init(&world, network.transport(0), .{ .local_player = 2 }));
+}
+
+test "Lockstep sessions detect diverging simulations through checksums" {
+    var network = netcode.LoopbackNetwork.init(testing.allocator);
+    defer network.deinit();
+
+    var worlds: [2]TestECS = undefined;
+    var sessions: [2]Lockstep = undefined;
+    for (&worlds, &sessions, 0..) |*world, *session, player| {
+        world.* = try TestECS.init(testing.allocator);
+        try spawnPlayers(world);
+        session.* = try Lockstep.init(world, network.transport(@intCast(player)), .{
+            .local_player = @intCast(player),
+            .checksum_interval = 10,
+        });
+    }
+    defer for (&worlds) |*world| world.deinit();
+    // One machine starts from a different world
+    worlds[1].getFrame().getComponent(0, Position).?.x = 7;
+
+    for (0..40) |_| {
+        for (&sessions) |*session| _ = try session.advance(.{ .dx = 1 }, &Movement{});
+        network.step();
+    }
+    for (&sessions) |*session| {
+        try testing.expect(session.frame() >= 30);
+        try testing.expect(session.desyncs > 0);
+        try testing.expectEqual(@as(?u64, 10), session.desync_frame);
+    }
+}
+
+test "UDP transports deliver datagrams between sockets" {
+    const loopback = try std.net.Address.parseIp4("127.0.0.1", 0);
+    var a = try netcode.UdpTransport.open(loopback);
+    defer a.close();
+    var b = try netcode.UdpTransport.open(loopback);
+    defer b.close();
+    a.setPeer(1, try b.localAddress());
+
+    var buffer: [netcode.max_packet_size]u8 = undefined;
+    try testing.expectEqual(@as(?[]u8, null), b.transport().receive(&buffer));
+
+    a.transport().send(1, "tick 42");
+    // Unknown players are dropped instead of sent anywhere
+    a.transport().send(3, "lost");
+
+    var received: ?[]u8 = null;
+    for (0..100) |_| {
+        received = b.transport().receive(&buffer);
+        if (received != null) break;
+        std.time.sleep(std.time.ns_per_ms);
+    }
+    try testing.expectEqualStrings("tick 42", received.?);
+}
@@ -0,0 +1,105 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+const EntityID = ecs.EntityID;
+
+pub const PackOptions = struct {
+    /// Start every row on its own cache line. Worth it for rows a little under a line (they would
+    /// otherwise straddle two) and for rows written from several workers; wasteful for tiny rows.
+    pad_rows: bool = false,
+};
+
+/// Interleaved hot array for entities holding all of `Types` - e.g. Transform and Velocity side
+/// by side, so a system touching both reads one cache line per entity instead of one line in
+/// each dense array. Pick the pairs from `QueryAnalyzer.packingHints`.
+///
+/// The component storages stay the source of truth. `gather` copies the members' values into
+/// rows (ascending entity order), the hot systems run over the rows, and `scatter` writes them
+/// back before anything else reads those components. The member list is rebuilt only when
+/// membership changed, through a `CachedQuery`.
+///
+/// Usage:
+///   var pack = HotPack(GameECS, &.{ Transform, Velocity }, .{}).init(allocator);
+///   try pack.gather(frame);
+///   for (pack.rows()) |*row| row.get(Transform).position.addAssign(row.get(Velocity).linear);
+///   pack.scatter(frame);
+pub fn HotPack(comptime EcsType: type, comptime Types: []const type, comptime options: PackOptions) type {
+    const Values = std.meta.Tuple(Types);
+
+    return struct {
+        const Self = @This();
+
+        /// One member's components, laid out together
+        pub const Row = struct {
+            values: Values align(if (options.pad_rows) std.atomic.cache_line else @alignOf(Values)),
+
+            pub fn get(self: *Row, comptime T: type) *T {
+                return &self.values[comptime indexOf(T)];
+            }
+        };
+
+        rows_list: std.ArrayList(Row),
+        entity_list: std.ArrayList(EntityID),
+        members: EcsType.CachedQuery(Types) = .{},
+        /// Rebuilds of the membership seen by the rows; differs from the cache after a change
+        synced_rebuilds: u64 = 0,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .rows_list = std.ArrayList(Row).init(allocator),
+                .entity_list = std.ArrayList(EntityID).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.rows_list.deinit();
+            self.entity_list.deinit();
+        }
+
+        /// Copy the members' components into the rows
+        pub fn gather(self: *Self, frame: *EcsType.Frame) !void {
+            var query = self.members.query(frame);
+            if (self.members.rebuilds != self.synced_rebuilds) {
+                const count = query.count();
+                try self.rows_list.resize(count);
+                try self.entity_list.resize(count);
+                var index: usize = 0;
+                while (query.nextFast()) |result| : (index += 1) self.entity_list.items[index] = result.entity;
+                self.synced_rebuilds = self.members.rebuilds;
+            }
+
+            inline for (Types, 0..) |T, i| {
+                const storage = frame.getComponentStorage(T);
+                for (self.entity_list.items, self.rows_list.items) |entity, *row| {
+                    row.values[i] = storage.getDirect(entity).*;
+                }
+            }
+        }
+
+        /// Write the rows back into the component storages
+        pub fn scatter(self: *Self, frame: *EcsType.Frame) void {
+            inline for (Types, 0..) |T, i| {
+                const storage = frame.getComponentStorage(T);
+                for (self.entity_list.items, self.rows_list.items) |entity, *row| {
+                    storage.getDirect(entity).* = row.values[i];
+                }
+            }
+        }
+
+        pub fn rows(self: *Self) []Row {
+            return self.rows_list.items;
+        }
+
+        /// Entity of each row
+        pub fn entities(self: *const Self) []const EntityID {
+            return self.entity_list.items;
+        }
+
+        fn indexOf(comptime T: type) usize {
+            for (Types, 0..) |Member, i| {
+                if (Member == T) return i;
+            }
+            @compileError("Component type '" ++ @typeName(T) ++ "' is not part of this pack");
+        }
+    };
+}
@@ -0,0 +1,64 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const HotPack = @import("packing.zig").HotPack;
+
+const Position = struct { x: i32, y: i32 };
+const Velocity = struct { x: i32, y: i32 };
+const Health = struct { value: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity, Health },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+test "Hot packs gather, update and scatter co-accessed components" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    for (0..6) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = @intCast(i), .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity, Velocity{ .x = 1, .y = 2 });
+        try frame.addComponent(entity, Health{ .value = 10 });
+    }
+
+    var pack = HotPack(TestECS, &.{ Position, Velocity }, .{}).init(testing.allocator);
+    defer pack.deinit();
+
+    try pack.gather(frame);
+    try testing.expectEqualSlices(ecs.EntityID, &.{ 0, 2, 4 }, pack.entities());
+    for (pack.rows()) |*row| {
+        const velocity = row.get(Velocity);
+        const position = row.get(Position);
+        position.x += velocity.x;
+        position.y += velocity.y;
+    }
+    pack.scatter(frame);
+
+    try testing.expectEqual(Position{ .x = 1, .y = 2 }, frame.getComponent(0, Position).?.*);
+    try testing.expectEqual(Position{ .x = 1, .y = 0 }, frame.getComponent(1, Position).?.*);
+    try testing.expectEqual(Position{ .x = 5, .y = 2 }, frame.getComponent(4, Position).?.*);
+
+    // Same membership - the entity list is reused
+    try pack.gather(frame);
+    try testing.expectEqual(@as(u64, 1), pack.members.rebuilds);
+
+    try frame.addComponent(1, Velocity{ .x = 0, .y = 0 });
+    try pack.gather(frame);
+    try testing.expectEqual(@as(u64, 2), pack.members.rebuilds);
+    try testing.expectEqualSlices(ecs.EntityID, &.{ 0, 1, 2, 4 }, pack.entities());
+    try testing.expectEqual(Position{ .x = 1, .y = 0 }, pack.rows()[1].get(Position).*);
+}
+
+test "Padded rows start on their own cache line" {
+    const Pack = HotPack(TestECS, &.{ Position, Velocity }, .{ .pad_rows = true });
+    try testing.expectEqual(@as(usize, 0), @sizeOf(Pack.Row) % std.atomic.cache_line);
+    try testing.expect(@sizeOf(HotPack(TestECS, &.{ Position, Velocity }, .{}).Row) < std.atomic.cache_line);
+}
@@ -0,0 +1,156 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const Transform = @import("components.zig").Transform;
+
+const EntityID = ecs.EntityID;
+
+/// Spawns particles at the entity's Transform
+pub const ParticleEmitter = struct {
+    /// Particles per tick - fractions accumulate across ticks
+    rate: FP = fp(1),
+    accumulator: FP = fp(0),
+    lifetime_ticks: u16 = 60,
+    speed: FP = fp(2),
+    direction: FPVector2 = FPVector2.UP,
+    /// Half angle of the emission cone around `direction`, radians
+    spread: FP = FP.PI,
+    gravity: FPVector2 = FPVector2.ZERO,
+    max_particles: u16 = 256,
+    /// Particles from this emitter currently alive
+    live: u16 = 0,
+    /// Random state for emission angles - give emitters different seeds
+    seed: u64 = 0x9E3779B97F4A7C15,
+    active: bool = true,
+
+    fn nextRandom(self: *ParticleEmitter) u64 {
+        // splitmix64 - fixed integer math, identical on every peer
+        self.seed +%= 0x9E3779B97F4A7C15;
+        var z = self.seed;
+        z = (z ^ (z >> 30)) *% 0xBF58476D1CE4E5B9;
+        z = (z ^ (z >> 27)) *% 0x94D049BB133111EB;
+        return z ^ (z >> 31);
+    }
+
+    // Uniform in [-1, 1)
+    fn nextSigned(self: *ParticleEmitter) FP {
+        const bits: i64 = @intCast(self.nextRandom() >> (64 - (FP.PRECISION + 1)));
+        return FP.fromRaw(bits - FP.ONE_RAW);
+    }
+};
+
+pub const Particle = struct {
+    emitter: EntityID = ecs.INVALID_ENTITY,
+    velocity: FPVector2 = FPVector2.ZERO,
+    gravity: FPVector2 = FPVector2.ZERO,
+    /// 0 = dead and parked in the pool
+    remaining_ticks: u16 = 0,
+
+    /// Dead particles keep their entity for reuse - renderers should skip them
+    pub fn isAlive(self: Particle) bool {
+        return self.remaining_ticks > 0;
+    }
+};
+
+/// Pooled particle simulation on top of the ECS.
+///
+/// Particles are ordinary entities (Transform + Particle), so they are deterministic and roll
+/// back like everything else. Entity ids are never reused by `createEntity`, so instead of
+/// destroying a particle when its lifetime ends it is parked in the pool (`remaining_ticks == 0`)
+/// and the next spawn revives it. A world churning thousands of particles per second therefore
+/// settles at a fixed entity count equal to the peak number alive. The pool is rebuilt from the
+/// Particle components every step, so nothing outside the frame needs restoring after a rollback.
+///
+/// Each step ages and moves live particles first, then emitters spawn in entity order.
+pub fn ParticleSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        free: std.ArrayList(EntityID),
+        /// Particles alive after the last step
+        live_count: u32,
+        /// Particle entities (alive or pooled) after the last step
+        pool_count: u32,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .free = std.ArrayList(EntityID).init(allocator),
+                .live_count = 0,
+                .pool_count = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.free.deinit();
+        }
+
+        pub fn step(self: *Self, frame: *EcsType.Frame, dt: FP) !void {
+            self.free.clearRetainingCapacity();
+            var live: u32 = 0;
+            var pooled: u32 = 0;
+
+            var particles = try frame.query(&.{ Transform, Particle });
+            while (particles.nextFast()) |result| {
+                pooled += 1;
+                const particle = result.get(Particle);
+                if (!particle.isAlive()) {
+                    try self.free.append(result.entity);
+                    continue;
+                }
+
+                particle.remaining_ticks -= 1;
+                if (!particle.isAlive()) {
+                    if (frame.getComponent(particle.emitter, ParticleEmitter)) |emitter| emitter.live -|= 1;
+                    try self.free.append(result.entity);
+                    continue;
+                }
+
+                const transform = result.get(Transform);
+                particle.velocity = particle.velocity.add(particle.gravity.mul(dt));
+                transform.position = transform.position.add(particle.velocity.mul(dt));
+                live += 1;
+            }
+
+            var next_free: usize = 0;
+            var emitters = try frame.query(&.{ Transform, ParticleEmitter });
+            while (emitters.nextFast()) |result| {
+                const emitter = result.get(ParticleEmitter);
+                if (!emitter.active) continue;
+
+                emitter.accumulator = emitter.accumulator.add(emitter.rate);
+                while (emitter.accumulator.gte(fp(1))) {
+                    emitter.accumulator = emitter.accumulator.sub(fp(1));
+                    // At capacity the emission is dropped rather than queued
+                    if (emitter.live >= emitter.max_particles) continue;
+
+                    const entity = if (next_free < self.free.items.len) blk: {
+                        next_free += 1;
+                        break :blk self.free.items[next_free - 1];
+                    } else blk: {
+                        const created = try frame.createEntity();
+                        try frame.addComponent(created, Transform{});
+                        try frame.addComponent(created, Particle{});
+                        pooled += 1;
+                        break :blk created;
+                    };
+
+                    const angle = emitter.nextSigned().mul(emitter.spread);
+                    frame.getComponent(entity, Transform).?.* = .{ .position = result.get(Transform).position };
+                    frame.getComponent(entity, Particle).?.* = .{
+                        .emitter = result.entity,
+                        .velocity = emitter.direction.rotate(angle).mul(emitter.speed),
+                        .gravity = emitter.gravity,
+                        .remaining_ticks = emitter.lifetime_ticks,
+                    };
+                    emitter.live += 1;
+                    live += 1;
+                }
+            }
+
+            self.live_count = live;
+            self.pool_count = pooled;
+        }
+    };
+}
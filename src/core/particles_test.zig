@@ -0,0 +1,101 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const particles = @import("particles.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const ParticleEmitter = particles.ParticleEmitter;
+const Particle = particles.Particle;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, ParticleEmitter, Particle },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestParticles = particles.ParticleSystem(TestECS);
+const dt = fp(1.0 / 60.0);
+
+fn addEmitter(frame: *TestECS.Frame, emitter: ParticleEmitter) !ecs.EntityID {
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = fpVec2(3, 4) });
+    try frame.addComponent(entity, emitter);
+    return entity;
+}
+
+test "Particle churn settles into a fixed pool" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestParticles.init(testing.allocator);
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const emitter = try addEmitter(frame, .{ .rate = fp(2), .lifetime_ticks = 10 });
+
+    // 2 per tick living 10 ticks: 20 alive at any time, thousands spawned in total
+    for (0..1000) |_| try system.step(frame, dt);
+
+    try testing.expectEqual(@as(u32, 20), system.live_count);
+    try testing.expectEqual(@as(u32, 20), system.pool_count);
+    try testing.expectEqual(@as(u16, 20), frame.getComponent(emitter, ParticleEmitter).?.live);
+    try testing.expectEqual(@as(u32, 21), frame.getEntityCount());
+}
+
+test "Emitters respect their particle cap" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestParticles.init(testing.allocator);
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const emitter = try addEmitter(frame, .{ .rate = fp(5), .lifetime_ticks = 100, .max_particles = 8 });
+    for (0..10) |_| try system.step(frame, dt);
+    try testing.expectEqual(@as(u16, 8), frame.getComponent(emitter, ParticleEmitter).?.live);
+    try testing.expectEqual(@as(u32, 8), system.pool_count);
+
+    // Fractional rates accumulate: 0.25 per tick is one particle every 4 ticks
+    frame.getComponent(emitter, ParticleEmitter).?.* = .{ .rate = fp(0.25), .lifetime_ticks = 100 };
+    for (0..8) |_| try system.step(frame, dt);
+    try testing.expectEqual(@as(u16, 2), frame.getComponent(emitter, ParticleEmitter).?.live);
+}
+
+fn particleChecksum(frame: *TestECS.Frame) !i64 {
+    var sum: i64 = 0;
+    var query = try frame.query(&.{ Transform, Particle });
+    while (query.nextFast()) |result| {
+        const position = result.get(Transform).position;
+        sum +%= position.x.raw_value *% 31 +% position.y.raw_value +% result.get(Particle).remaining_ticks;
+    }
+    return sum;
+}
+
+test "Particles replay identically after rollback" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestParticles.init(testing.allocator);
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    _ = try addEmitter(frame, .{ .rate = fp(3), .lifetime_ticks = 20, .gravity = fpVec2(0, -10), .seed = 7 });
+    _ = try addEmitter(frame, .{ .rate = fp(1.5), .lifetime_ticks = 30, .spread = fp(0.5), .seed = 8 });
+    for (0..5) |_| try system.step(frame, dt);
+
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TestECS.freeSavedFrame(&saved);
+
+    for (0..60) |_| try system.step(frame, dt);
+    const first = try particleChecksum(frame);
+    const first_pool = system.pool_count;
+
+    try test_ecs.restoreFrame(&saved);
+    for (0..60) |_| try system.step(frame, dt);
+    try testing.expectEqual(first, try particleChecksum(frame));
+    try testing.expectEqual(first_pool, system.pool_count);
+}
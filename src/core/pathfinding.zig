@@ -0,0 +1,367 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const Cell = @import("spatial.zig").Cell;
+
+const EntityID = ecs.EntityID;
+
+pub const MAX_WAYPOINTS = 32;
+
+/// Walkability and movement cost for a rectangular grid of cells.
+///
+/// This is level data, not simulation state - it is not part of a frame snapshot. Change it only
+/// from confirmed state (or re-run pending requests after a rollback), otherwise a resimulated
+/// tick can search a different grid than the original one did.
+pub const NavGrid = struct {
+    allocator: std.mem.Allocator,
+    width: u32,
+    height: u32,
+    cell_size: FP,
+    /// World position of the corner of cell (0, 0)
+    origin: FPVector2,
+    /// Per-cell cost multiplier, 0 = blocked
+    costs: []u8,
+
+    pub fn init(allocator: std.mem.Allocator, width: u32, height: u32, cell_size: FP, origin: FPVector2) !NavGrid {
+        std.debug.assert(cell_size.raw_value > 0);
+        const costs = try allocator.alloc(u8, @as(usize, width) * height);
+        @memset(costs, 1);
+        return NavGrid{
+            .allocator = allocator,
+            .width = width,
+            .height = height,
+            .cell_size = cell_size,
+            .origin = origin,
+            .costs = costs,
+        };
+    }
+
+    pub fn deinit(self: *NavGrid) void {
+        self.allocator.free(self.costs);
+    }
+
+    pub fn inBounds(self: *const NavGrid, cell: Cell) bool {
+        return cell.x >= 0 and cell.y >= 0 and @as(i64, cell.x) < self.width and @as(i64, cell.y) < self.height;
+    }
+
+    /// Cost multiplier of the cell, 0 when blocked or outside the grid
+    pub fn cost(self: *const NavGrid, cell: Cell) u8 {
+        if (!self.inBounds(cell)) return 0;
+        return self.costs[self.indexOf(cell)];
+    }
+
+    pub fn isWalkable(self: *const NavGrid, cell: Cell) bool {
+        return self.cost(cell) != 0;
+    }
+
+    pub fn setCost(self: *NavGrid, cell: Cell, value: u8) void {
+        std.debug.assert(self.inBounds(cell));
+        self.costs[self.indexOf(cell)] = value;
+    }
+
+    pub fn setBlocked(self: *NavGrid, cell: Cell, blocked: bool) void {
+        self.setCost(cell, if (blocked) 0 else 1);
+    }
+
+    pub fn cellOf(self: *const NavGrid, position: FPVector2) Cell {
+        const local = position.sub(self.origin);
+        return .{
+            .x = @intCast(@divFloor(local.x.raw_value, self.cell_size.raw_value)),
+            .y = @intCast(@divFloor(local.y.raw_value, self.cell_size.raw_value)),
+        };
+    }
+
+    pub fn cellCenter(self: *const NavGrid, cell: Cell) FPVector2 {
+        const half = self.cell_size.div(fp(2));
+        return FPVector2.new(
+            self.origin.x.add(self.cell_size.mul(FP.fromInt(cell.x))).add(half),
+            self.origin.y.add(self.cell_size.mul(FP.fromInt(cell.y))).add(half),
+        );
+    }
+
+    fn indexOf(self: *const NavGrid, cell: Cell) u32 {
+        return @as(u32, @intCast(cell.y)) * self.width + @as(u32, @intCast(cell.x));
+    }
+
+    fn cellAt(self: *const NavGrid, index: u32) Cell {
+        return .{ .x = @intCast(index % self.width), .y = @intCast(index / self.width) };
+    }
+};
+
+/// Ask the pathfinder for a route. Replaced by a Path once the search runs.
+pub const PathRequest = struct {
+    start: FPVector2,
+    goal: FPVector2,
+};
+
+pub const PathStatus = enum(u8) {
+    found,
+    /// Start or goal blocked/outside the grid, or no route exists
+    no_route,
+    /// Search gave up after `max_nodes_per_search` expansions
+    too_far,
+};
+
+/// Search result. Waypoints are cell centers, excluding the start cell, with straight runs
+/// collapsed to their turning points.
+pub const Path = struct {
+    status: PathStatus = .no_route,
+    waypoints: [MAX_WAYPOINTS]FPVector2 = [_]FPVector2{FPVector2.ZERO} ** MAX_WAYPOINTS,
+    len: u8 = 0,
+    /// Index of the waypoint the agent is heading for - advanced by whoever follows the path
+    next: u8 = 0,
+    /// False when the route had more than MAX_WAYPOINTS turns and was cut short; request again
+    /// from the last waypoint to continue
+    complete: bool = true,
+
+    pub fn slice(self: *const Path) []const FPVector2 {
+        return self.waypoints[0..self.len];
+    }
+
+    /// Waypoint the agent is heading for, or null when the path is finished
+    pub fn current(self: *const Path) ?FPVector2 {
+        return if (self.next < self.len) self.waypoints[self.next] else null;
+    }
+};
+
+pub const PathfinderOptions = struct {
+    /// Node expansions allowed per update across all requests
+    nodes_per_tick: u32 = 1024,
+    /// Expansions after which a single search gives up with `.too_far`
+    max_nodes_per_search: u32 = 4096,
+    /// Allow diagonal moves (never through blocked corners)
+    allow_diagonal: bool = true,
+};
+
+/// Budgeted A* over a NavGrid, driven by PathRequest components.
+///
+/// `update` serves requests in ascending entity order until the tick's expansion budget is spent;
+/// the rest wait for the next tick. Each search runs to completion within the tick that starts
+/// it (overshooting the budget by at most `max_nodes_per_search`), so no search state outlives a
+/// tick and pending requests and results are plain components that snapshot and roll back with
+/// the frame. Costs are integers and ties break on cell index, so every peer finds the same path.
+///
+/// The ECS must register PathRequest and Path.
+pub fn Pathfinder(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        const ORTHOGONAL_COST = 10;
+        const DIAGONAL_COST = 14;
+
+        const OpenNode = struct {
+            f: u32,
+            h: u32,
+            index: u32,
+
+            fn order(_: void, a: OpenNode, b: OpenNode) std.math.Order {
+                if (a.f != b.f) return std.math.order(a.f, b.f);
+                if (a.h != b.h) return std.math.order(a.h, b.h);
+                return std.math.order(a.index, b.index);
+            }
+        };
+
+        allocator: std.mem.Allocator,
+        nav: *const NavGrid,
+        options: PathfinderOptions,
+        open: std.PriorityQueue(OpenNode, void, OpenNode.order),
+        // Per-cell search scratch; entries are valid only where `seen` matches the current stamp
+        g_cost: []u32,
+        came_from: []u32,
+        seen: []u32,
+        closed: []u32,
+        stamp: u32,
+        pending: std.ArrayList(EntityID),
+        trail: std.ArrayList(u32),
+        /// Node expansions spent during the last update
+        expanded_last_update: u32,
+
+        /// The pathfinder is sized for `nav` - its dimensions must not change afterwards
+        pub fn init(allocator: std.mem.Allocator, nav: *const NavGrid, options: PathfinderOptions) !Self {
+            const cells = nav.costs.len;
+            const g_cost = try allocator.alloc(u32, cells);
+            errdefer allocator.free(g_cost);
+            const came_from = try allocator.alloc(u32, cells);
+            errdefer allocator.free(came_from);
+            const seen = try allocator.alloc(u32, cells);
+            errdefer allocator.free(seen);
+            const closed = try allocator.alloc(u32, cells);
+            @memset(seen, 0);
+            @memset(closed, 0);
+
+            return Self{
+                .allocator = allocator,
+                .nav = nav,
+                .options = options,
+                .open = std.PriorityQueue(OpenNode, void, OpenNode.order).init(allocator, {}),
+                .g_cost = g_cost,
+                .came_from = came_from,
+                .seen = seen,
+                .closed = closed,
+                .stamp = 0,
+                .pending = std.ArrayList(EntityID).init(allocator),
+                .trail = std.ArrayList(u32).init(allocator),
+                .expanded_last_update = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.open.deinit();
+            self.allocator.free(self.g_cost);
+            self.allocator.free(self.came_from);
+            self.allocator.free(self.seen);
+            self.allocator.free(self.closed);
+            self.pending.deinit();
+            self.trail.deinit();
+        }
+
+        /// Serve queued requests within this tick's budget
+        pub fn update(self: *Self, frame: *EcsType.Frame) !void {
+            // Collect first - serving a request removes its component
+            self.pending.clearRetainingCapacity();
+            var query = try frame.query(&.{PathRequest});
+            while (query.nextFast()) |result| {
+                try self.pending.append(result.entity);
+            }
+
+            var spent: u32 = 0;
+            for (self.pending.items) |entity| {
+                if (spent >= self.options.nodes_per_tick) break;
+                const request = frame.getComponent(entity, PathRequest).?.*;
+
+                var path = Path{};
+                spent += try self.search(self.nav.cellOf(request.start), self.nav.cellOf(request.goal), &path);
+
+                _ = frame.removeComponent(entity, PathRequest);
+                if (frame.getComponent(entity, Path)) |existing| {
+                    existing.* = path;
+                } else {
+                    try frame.addComponent(entity, path);
+                }
+            }
+            self.expanded_last_update = spent;
+        }
+
+        /// Run one search, filling `path`. Returns the number of nodes expanded.
+        pub fn search(self: *Self, start: Cell, goal: Cell, path: *Path) !u32 {
+            const nav = self.nav;
+            if (!nav.isWalkable(start) or !nav.isWalkable(goal)) {
+                path.* = .{ .status = .no_route };
+                return 0;
+            }
+
+            self.nextStamp();
+            self.open.clearRetainingCapacity();
+
+            const start_index = nav.indexOf(start);
+            const goal_index = nav.indexOf(goal);
+            self.visit(start_index, 0, start_index);
+            try self.open.add(.{ .f = heuristic(start, goal), .h = heuristic(start, goal), .index = start_index });
+
+            var expanded: u32 = 0;
+            while (self.open.removeOrNull()) |node| {
+                if (self.closed[node.index] == self.stamp) continue;
+                if (node.index == goal_index) {
+                    try self.buildPath(start_index, goal_index, path);
+                    return expanded;
+                }
+                if (expanded >= self.options.max_nodes_per_search) {
+                    path.* = .{ .status = .too_far };
+                    return expanded;
+                }
+                self.closed[node.index] = self.stamp;
+                expanded += 1;
+
+                const cell = nav.cellAt(node.index);
+                for (neighbor_offsets, 0..) |offset, i| {
+                    const diagonal = i >= 4;
+                    if (diagonal and !self.options.allow_diagonal) break;
+
+                    const next = Cell{ .x = cell.x + offset.x, .y = cell.y + offset.y };
+                    const step_cost = nav.cost(next);
+                    if (step_cost == 0) continue;
+                    // No cutting blocked corners
+                    if (diagonal and (!nav.isWalkable(.{ .x = next.x, .y = cell.y }) or !nav.isWalkable(.{ .x = cell.x, .y = next.y }))) continue;
+
+                    const next_index = nav.indexOf(next);
+                    if (self.closed[next_index] == self.stamp) continue;
+
+                    const g = self.g_cost[node.index] + @as(u32, step_cost) * @as(u32, if (diagonal) DIAGONAL_COST else ORTHOGONAL_COST);
+                    if (self.seen[next_index] == self.stamp and g >= self.g_cost[next_index]) continue;
+
+                    self.visit(next_index, g, node.index);
+                    const h = heuristic(next, goal);
+                    try self.open.add(.{ .f = g + h, .h = h, .index = next_index });
+                }
+            }
+
+            path.* = .{ .status = .no_route };
+            return expanded;
+        }
+
+        const neighbor_offsets = [_]Cell{
+            .{ .x = 1, .y = 0 },
+            .{ .x = -1, .y = 0 },
+            .{ .x = 0, .y = 1 },
+            .{ .x = 0, .y = -1 },
+            .{ .x = 1, .y = 1 },
+            .{ .x = -1, .y = 1 },
+            .{ .x = 1, .y = -1 },
+            .{ .x = -1, .y = -1 },
+        };
+
+        // Octile distance at the cheapest cell cost - never overestimates
+        fn heuristic(from: Cell, to: Cell) u32 {
+            const dx: u32 = @abs(from.x - to.x);
+            const dy: u32 = @abs(from.y - to.y);
+            return ORTHOGONAL_COST * @max(dx, dy) + (DIAGONAL_COST - ORTHOGONAL_COST) * @min(dx, dy);
+        }
+
+        fn visit(self: *Self, index: u32, g: u32, from: u32) void {
+            self.seen[index] = self.stamp;
+            self.g_cost[index] = g;
+            self.came_from[index] = from;
+        }
+
+        fn nextStamp(self: *Self) void {
+            self.stamp +%= 1;
+            if (self.stamp == 0) {
+                // Wrapped - old stamps could alias the new one
+                @memset(self.seen, 0);
+                @memset(self.closed, 0);
+                self.stamp = 1;
+            }
+        }
+
+        fn buildPath(self: *Self, start_index: u32, goal_index: u32, path: *Path) !void {
+            self.trail.clearRetainingCapacity();
+            var index = goal_index;
+            while (index != start_index) : (index = self.came_from[index]) {
+                try self.trail.append(index);
+            }
+            std.mem.reverse(u32, self.trail.items);
+
+            path.* = .{ .status = .found };
+            var previous = self.nav.cellAt(start_index);
+            for (self.trail.items, 0..) |cell_index, i| {
+                const cell = self.nav.cellAt(cell_index);
+                const last = i + 1 == self.trail.items.len;
+                // Keep a cell only where the direction changes after it
+                if (!last) {
+                    const next = self.nav.cellAt(self.trail.items[i + 1]);
+                    const straight = next.x - cell.x == cell.x - previous.x and next.y - cell.y == cell.y - previous.y;
+                    previous = cell;
+                    if (straight) continue;
+                }
+                if (path.len == MAX_WAYPOINTS) {
+                    path.complete = false;
+                    return;
+                }
+                path.waypoints[path.len] = self.nav.cellCenter(cell);
+                path.len += 1;
+            }
+        }
+    };
+}
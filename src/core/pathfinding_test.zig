@@ -0,0 +1,110 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const pathfinding = @import("pathfinding.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const NavGrid = pathfinding.NavGrid;
+const Path = pathfinding.Path;
+const PathRequest = pathfinding.PathRequest;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ PathRequest, Path },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestPathfinder = pathfinding.Pathfinder(TestECS);
+
+fn expectWaypoint(expected: FPVector2, actual: FPVector2) !void {
+    try testing.expectEqual(expected.x.raw_value, actual.x.raw_value);
+    try testing.expectEqual(expected.y.raw_value, actual.y.raw_value);
+}
+
+test "A* finds routes around walls" {
+    var nav = try NavGrid.init(testing.allocator, 10, 10, fp(1), FPVector2.ZERO);
+    defer nav.deinit();
+    var pathfinder = try TestPathfinder.init(testing.allocator, &nav, .{});
+    defer pathfinder.deinit();
+
+    // Open ground: a straight run collapses to its end point
+    var path = Path{};
+    _ = try pathfinder.search(.{ .x = 0, .y = 0 }, .{ .x = 5, .y = 0 }, &path);
+    try testing.expectEqual(pathfinding.PathStatus.found, path.status);
+    try testing.expectEqual(@as(u8, 1), path.len);
+    try expectWaypoint(fpVec2(5.5, 0.5), path.waypoints[0]);
+
+    // Wall at x = 5 with a single gap at the top
+    for (0..9) |y| nav.setBlocked(.{ .x = 5, .y = @intCast(y) }, true);
+    _ = try pathfinder.search(.{ .x = 0, .y = 0 }, .{ .x = 9, .y = 0 }, &path);
+    try testing.expectEqual(pathfinding.PathStatus.found, path.status);
+    try expectWaypoint(fpVec2(9.5, 0.5), path.slice()[path.len - 1]);
+    var through_gap = false;
+    for (path.slice()) |waypoint| {
+        try testing.expect(nav.isWalkable(nav.cellOf(waypoint)));
+        if (nav.cellOf(waypoint).y == 9) through_gap = true;
+    }
+    try testing.expect(through_gap);
+
+    // Close the gap
+    nav.setBlocked(.{ .x = 5, .y = 9 }, true);
+    _ = try pathfinder.search(.{ .x = 0, .y = 0 }, .{ .x = 9, .y = 0 }, &path);
+    try testing.expectEqual(pathfinding.PathStatus.no_route, path.status);
+
+    // Blocked goal
+    _ = try pathfinder.search(.{ .x = 0, .y = 0 }, .{ .x = 5, .y = 5 }, &path);
+    try testing.expectEqual(pathfinding.PathStatus.no_route, path.status);
+}
+
+test "Searches past the node cap give up" {
+    var nav = try NavGrid.init(testing.allocator, 20, 20, fp(1), FPVector2.ZERO);
+    defer nav.deinit();
+    var pathfinder = try TestPathfinder.init(testing.allocator, &nav, .{ .max_nodes_per_search = 5 });
+    defer pathfinder.deinit();
+
+    var path = Path{};
+    const expanded = try pathfinder.search(.{ .x = 0, .y = 0 }, .{ .x = 19, .y = 19 }, &path);
+    try testing.expectEqual(pathfinding.PathStatus.too_far, path.status);
+    try testing.expectEqual(@as(u32, 5), expanded);
+}
+
+test "Requests are served in entity order within the tick budget" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var nav = try NavGrid.init(testing.allocator, 10, 10, fp(1), FPVector2.ZERO);
+    defer nav.deinit();
+    var pathfinder = try TestPathfinder.init(testing.allocator, &nav, .{ .nodes_per_tick = 1 });
+    defer pathfinder.deinit();
+
+    const frame = test_ecs.getFrame();
+    var agents: [3]ecs.EntityID = undefined;
+    for (&agents) |*agent| {
+        agent.* = try frame.createEntity();
+        try frame.addComponent(agent.*, PathRequest{ .start = fpVec2(0.5, 0.5), .goal = fpVec2(6.5, 0.5) });
+    }
+
+    // The first search spends the whole budget; the others wait their turn
+    for (agents, 0..) |_, served| {
+        try pathfinder.update(frame);
+        try testing.expect(pathfinder.expanded_last_update > 0);
+        for (agents, 0..) |agent, i| {
+            try testing.expectEqual(i <= served, frame.hasComponent(agent, Path));
+            try testing.expectEqual(i > served, frame.hasComponent(agent, PathRequest));
+        }
+    }
+
+    const path = frame.getComponent(agents[2], Path).?;
+    try testing.expectEqual(pathfinding.PathStatus.found, path.status);
+    try expectWaypoint(fpVec2(6.5, 0.5), path.current().?);
+
+    // A new request replaces the old path
+    try frame.addComponent(agents[0], PathRequest{ .start = fpVec2(0.5, 0.5), .goal = fpVec2(0.5, 3.5) });
+    try pathfinder.update(frame);
+    try expectWaypoint(fpVec2(0.5, 3.5), frame.getComponent(agents[0], Path).?.current().?);
+}
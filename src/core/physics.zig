@@ -0,0 +1,239 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+const collision = @import("collision.zig");
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Collider = components.Collider;
+const RigidBody = components.RigidBody;
+const Acceleration = components.Acceleration;
+const Drag = components.Drag;
+
+pub const PhysicsConfig = struct {
+    gravity: FPVector2 = FPVector2.new(fp(0), fp(-9.81)),
+    /// Velocity solver passes over all contacts per step
+    iterations: u8 = 4,
+    /// Fraction of penetration removed per step
+    correction_percent: FP = fp(0.8),
+    /// Penetration allowed before positional correction kicks in (prevents jitter)
+    slop: FP = fp(0.01),
+};
+
+/// Deterministic 2D rigid body physics in fixed point.
+///
+/// Everything that persists between steps lives in components (Transform, Velocity, RigidBody,
+/// Collider), so physics state is saved and restored with the rest of the frame. The contact
+/// list is scratch space rebuilt on every step.
+///
+/// Dynamics are linear only: contacts change linear velocity, and angular velocity just
+/// integrates into rotation. `Acceleration` and `Drag` are optional - worlds that do not register
+/// them simply skip those terms.
+///
+/// Per step:
+///   1. gravity and `Acceleration` are applied to dynamic bodies, then `Drag` damps and clamps
+///      the velocity of every moving body
+///   2. contacts are found through the spatial index (synced by the caller) in entity order
+///   3. impulses with restitution and friction are solved over `iterations` passes
+///   4. positions are integrated and remaining penetration is corrected
+pub fn Physics(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const Contact = struct {
+            a: EntityID,
+            b: EntityID,
+            manifold: collision.Manifold,
+        };
+
+        config: PhysicsConfig,
+        contacts: std.ArrayList(Contact),
+
+        pub fn init(allocator: std.mem.Allocator, config: PhysicsConfig) Self {
+            return Self{
+                .config = config,
+                .contacts = std.ArrayList(Contact).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.contacts.deinit();
+        }
+
+        /// Advance the simulation by `dt` (use a fixed timestep, e.g. `fp(1.0 / 60.0)`)
+        pub fn step(self: *Self, frame: *EcsType.Frame, index: anytype, dt: FP) !void {
+            self.integrateVelocities(frame, dt);
+            try self.findContacts(frame, index);
+
+            for (0..self.config.iterations) |_| {
+                for (self.contacts.items) |contact| {
+                    resolveVelocity(frame, contact);
+                }
+            }
+
+            integratePositions(frame, dt);
+            for (self.contacts.items) |contact| {
+                self.correctPosition(frame, contact);
+            }
+        }
+
+        fn integrateVelocities(self: *const Self, frame: *EcsType.Frame, dt: FP) void {
+            var query = frame.query(&.{ Velocity, RigidBody }) catch unreachable;
+            while (query.nextFast()) |result| {
+                const body = result.get(RigidBody);
+                if (body.kind == .static) continue;
+                const velocity = result.get(Velocity);
+
+                if (body.kind == .dynamic) {
+                    velocity.linear = velocity.linear.add(self.config.gravity.mul(body.gravity_scale.mul(dt)));
+                    if (comptime EcsType.isComponent(Acceleration)) {
+                        if (frame.getComponent(result.entity, Acceleration)) |acceleration| {
+                            velocity.linear = velocity.linear.add(acceleration.linear.mul(dt));
+                            velocity.angular = velocity.angular.add(acceleration.angular.mul(dt));
+                        }
+                    }
+                }
+                if (comptime EcsType.isComponent(Drag)) {
+                    if (frame.getComponent(result.entity, Drag)) |drag| applyDrag(velocity, drag.*, dt);
+                }
+            }
+        }
+
+        fn findContacts(self: *Self, frame: *EcsType.Frame, index: anytype) !void {
+            self.contacts.clearRetainingCapacity();
+            const reach = collision.maxColliderReach(EcsType, frame);
+
+            var query = try frame.query(&.{ Transform, Collider, RigidBody });
+            while (query.nextFast()) |result| {
+                const body = result.get(RigidBody);
+                const collider = result.get(Collider).*;
+                const transform = result.get(Transform).*;
+                const box = collision.AABB.of(transform, collider);
+
+                var candidates = EcsType.EntityBitSet.initEmpty();
+                index.queryAABB(box.min.sub(reach), box.max.add(reach), &candidates);
+
+                var iter = candidates.fastIterator();
+                while (iter.next()) |other| {
+                    if (other == result.entity) continue;
+                    const other_body = frame.getComponent(other, RigidBody);
+                    // Body-body pairs are visited from the lower id only
+                    if (other_body != null and other < result.entity) continue;
+                    // Nothing to resolve between two immovable bodies
+                    if (body.kind != .dynamic and (other_body == null or other_body.?.kind != .dynamic)) continue;
+
+                    const other_collider = frame.getComponent(other, Collider) orelse continue;
+                    const other_transform = frame.getComponent(other, Transform) orelse continue;
+                    if (!collision.layersCollide(collider, other_collider.*)) continue;
+
+                    const manifold = collision.contact(
+                        collision.colliderCenter(transform, collider),
+                        collider.shape,
+                        collision.colliderCenter(other_transform.*, other_collider.*),
+                        other_collider.shape,
+                    ) orelse continue;
+
+                    try self.contacts.append(.{ .a = result.entity, .b = other, .manifold = manifold });
+                }
+            }
+        }
+
+        fn correctPosition(self: *const Self, frame: *EcsType.Frame, contact: Contact) void {
+            const inv_a = inverseMass(frame, contact.a);
+            const inv_b = inverseMass(frame, contact.b);
+            const inv_sum = inv_a.add(inv_b);
+            if (inv_sum.raw_value == 0) return;
+
+            const excess = contact.manifold.penetration.sub(self.config.slop);
+            if (excess.raw_value <= 0) return;
+
+            const correction = contact.manifold.normal.mul(excess.div(inv_sum).mul(self.config.correction_percent));
+            if (frame.getComponent(contact.a, Transform)) |transform| {
+                transform.position = transform.position.sub(correction.mul(inv_a));
+            }
+            if (frame.getComponent(contact.b, Transform)) |transform| {
+                transform.position = transform.position.add(correction.mul(inv_b));
+            }
+        }
+    };
+}
+
+/// Damp `velocity` by `drag` over `dt` and clamp it to the speed limit. Linear damping never
+/// reverses the direction of travel, however large `drag * dt` gets.
+pub fn applyDrag(velocity: *Velocity, drag: Drag, dt: FP) void {
+    if (drag.linear.raw_value != 0) {
+        velocity.linear = velocity.linear.mul(FP.max(fp(0), fp(1).sub(drag.linear.mul(dt))));
+    }
+    if (drag.angular.raw_value != 0) {
+        velocity.angular = velocity.angular.mul(FP.max(fp(0), fp(1).sub(drag.angular.mul(dt))));
+    }
+    if (drag.max_speed.raw_value != 0) {
+        velocity.linear = velocity.linear.clampMagnitude(drag.max_speed);
+    }
+}
+
+fn inverseMass(frame: anytype, entity: EntityID) FP {
+    const body = frame.getComponent(entity, RigidBody) orelse return fp(0);
+    return body.solverInverseMass();
+}
+
+fn velocityOf(frame: anytype, entity: EntityID) FPVector2 {
+    const velocity = frame.getComponent(entity, Velocity) orelse return FPVector2.ZERO;
+    return velocity.linear;
+}
+
+fn resolveVelocity(frame: anytype, contact: anytype) void {
+    const inv_a = inverseMass(frame, contact.a);
+    const inv_b = inverseMass(frame, contact.b);
+    const inv_sum = inv_a.add(inv_b);
+    if (inv_sum.raw_value == 0) return;
+
+    const normal = contact.manifold.normal;
+    const relative = velocityOf(frame, contact.b).sub(velocityOf(frame, contact.a));
+    const closing = relative.dot(normal);
+    // Already separating
+    if (closing.raw_value > 0) return;
+
+    // Colliders without a RigidBody use the defaults
+    const body_a = if (frame.getComponent(contact.a, RigidBody)) |body| body.* else RigidBody{ .kind = .static };
+    const body_b = if (frame.getComponent(contact.b, RigidBody)) |body| body.* else RigidBody{ .kind = .static };
+    const restitution = FP.min(body_a.restitution, body_b.restitution);
+    const friction = body_a.friction.mul(body_b.friction).sqrt();
+
+    const j = fp(1).add(restitution).mul(closing).negate().div(inv_sum);
+    applyImpulse(frame, contact.a, contact.b, normal.mul(j), inv_a, inv_b);
+
+    // Friction along the contact tangent, clamped to the Coulomb cone
+    const after = velocityOf(frame, contact.b).sub(velocityOf(frame, contact.a));
+    const tangent_velocity = after.sub(normal.mul(after.dot(normal)));
+    if (tangent_velocity.eq(FPVector2.ZERO)) return;
+    const tangent = tangent_velocity.normalize();
+
+    const max_friction = j.mul(friction);
+    const jt = FP.clamp(after.dot(tangent).negate().div(inv_sum), max_friction.negate(), max_friction);
+    applyImpulse(frame, contact.a, contact.b, tangent.mul(jt), inv_a, inv_b);
+}
+
+fn applyImpulse(frame: anytype, a: EntityID, b: EntityID, impulse: FPVector2, inv_a: FP, inv_b: FP) void {
+    if (frame.getComponent(a, Velocity)) |velocity| {
+        velocity.linear = velocity.linear.sub(impulse.mul(inv_a));
+    }
+    if (frame.getComponent(b, Velocity)) |velocity| {
+        velocity.linear = velocity.linear.add(impulse.mul(inv_b));
+    }
+}
+
+fn integratePositions(frame: anytype, dt: FP) void {
+    var query = frame.query(&.{ Transform, Velocity, RigidBody }) catch unreachable;
+    while (query.nextFast()) |result| {
+        if (result.get(RigidBody).kind == .static) continue;
+        const transform = result.get(Transform);
+        const velocity = result.get(Velocity);
+        transform.position = transform.position.add(velocity.linear.mul(dt));
+        transform.rotation = transform.rotation.add(velocity.angular.mul(dt));
+    }
+}
@@ -0,0 +1,95 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const physics = @import("physics.zig");
+const components = @import("components.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Collider = components.Collider;
+const RigidBody = components.RigidBody;
+const Acceleration = components.Acceleration;
+const Drag = components.Drag;
+
+const PerfInput = struct {
+    deltaTime: f32 = 0.016,
+};
+
+const PerfECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Collider, RigidBody, Acceleration, Drag },
+    .input = PerfInput,
+    .max_entities = .vast,
+});
+
+const BODY_COUNTS = [_]u32{ 1000, 2500, 5000, 10000 };
+const STEPS = 120;
+const dt = fp(1.0 / 60.0);
+
+pub fn main() !void {
+    const allocator = std.heap.page_allocator;
+
+    std.debug.print("=== Physics Performance Test ===\n", .{});
+    std.debug.print("Rows of bodies dropped onto static floors, {} steps each\n\n", .{STEPS});
+
+    for (BODY_COUNTS) |count| {
+        var world = try PerfECS.init(allocator);
+        defer world.deinit();
+        try populate(world.getFrame(), count);
+
+        var grid = spatial.Grid(PerfECS).init(allocator, fp(4));
+        defer grid.deinit();
+        var sim = physics.Physics(PerfECS).init(allocator, .{});
+        defer sim.deinit();
+
+        var contacts: u64 = 0;
+        var slowest_ns: u64 = 0;
+        const start = std.time.nanoTimestamp();
+        for (0..STEPS) |_| {
+            const step_start = std.time.nanoTimestamp();
+            try grid.sync(world.getFrame());
+            try sim.step(world.getFrame(), &grid, dt);
+            slowest_ns = @max(slowest_ns, @as(u64, @intCast(std.time.nanoTimestamp() - step_start)));
+            contacts += sim.contacts.items.len;
+        }
+        const total_ns = std.time.nanoTimestamp() - start;
+
+        std.debug.print("{d:>6} bodies  step {d:>9.1}us  slowest {d:>9.1}us  {d:>7.1} contacts/step  checksum {x:0>16}\n", .{
+            count,
+            @as(f64, @floatFromInt(total_ns)) / 1000.0 / STEPS,
+            @as(f64, @floatFromInt(slowest_ns)) / 1000.0,
+            @as(f64, @floatFromInt(contacts)) / STEPS,
+            world.getFrame().checksum(),
+        });
+    }
+}
+
+// Rows of mixed boxes and circles above one floor per 100 bodies, every fourth pushed sideways
+// and every third dragged, so all the velocity terms and both shape pairs are exercised
+fn populate(frame: *PerfECS.Frame, count: u32) !void {
+    const columns = 100;
+    const floors = (count + columns - 1) / columns;
+    for (0..floors) |floor| {
+        const entity = try frame.createEntity();
+        const y = FP.fromInt(@intCast(floor * 40));
+        try frame.addComponent(entity, Transform{ .position = FPVector2.new(fp(150), y) });
+        try frame.addComponent(entity, Collider.box(FPVector2.new(fp(160), fp(0.5))));
+        try frame.addComponent(entity, RigidBody{ .kind = .static });
+    }
+
+    for (0..count) |i| {
+        const entity = try frame.createEntity();
+        const column: i32 = @intCast(i % columns);
+        const row: i32 = @intCast(i / columns);
+        const x = FP.fromInt(column * 3);
+        const y = FP.fromInt(row * 40 + 2 + @mod(column, 5));
+        try frame.addComponent(entity, Transform{ .position = FPVector2.new(x, y) });
+        try frame.addComponent(entity, Velocity{});
+        try frame.addComponent(entity, if (i % 2 == 0) Collider.box(FPVector2.new(fp(0.5), fp(0.5))) else Collider.circle(fp(0.5)));
+        try frame.addComponent(entity, RigidBody{ .restitution = fp(0.2) });
+        if (i % 4 == 0) try frame.addComponent(entity, Acceleration{ .linear = FPVector2.new(fp(2), fp(0)) });
+        if (i % 3 == 0) try frame.addComponent(entity, Drag{ .linear = fp(0.1), .max_speed = fp(20) });
+    }
+}
@@ -0,0 +1,217 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const components = @import("components.zig");
+const physics = @import("physics.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Collider = components.Collider;
+const RigidBody = components.RigidBody;
+const Acceleration = components.Acceleration;
+const Drag = components.Drag;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Collider, RigidBody },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestPhysics = physics.Physics(TestECS);
+
+const DrivenECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Collider, RigidBody, Acceleration, Drag },
+    .input = TestInput,
+    .max_entities = .small,
+});
+const dt = fp(1.0 / 60.0);
+
+fn simulate(sim: *TestPhysics, grid: *spatial.Grid(TestECS), frame: *TestECS.Frame, steps: usize) !void {
+    for (0..steps) |_| {
+        try grid.sync(frame);
+        try sim.step(frame, grid, dt);
+    }
+}
+
+// Wide static ground with its top surface at y = 0.5
+fn addGround(frame: *TestECS.Frame, body: ?RigidBody) !ecs.EntityID {
+    const ground = try frame.createEntity();
+    try frame.addComponent(ground, Transform{ .position = fpVec2(0, 0) });
+    try frame.addComponent(ground, Collider.box(fpVec2(20, 0.5)));
+    if (body) |b| try frame.addComponent(ground, b);
+    return ground;
+}
+
+test "Box comes to rest on static ground" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var sim = TestPhysics.init(testing.allocator, .{ .gravity = fpVec2(0, -10) });
+    defer sim.deinit();
+
+    const frame = test_ecs.getFrame();
+    _ = try addGround(frame, null);
+    const box = try frame.createEntity();
+    try frame.addComponent(box, Transform{ .position = fpVec2(0, 2) });
+    try frame.addComponent(box, Velocity{});
+    try frame.addComponent(box, Collider.box(fpVec2(0.5, 0.5)));
+    try frame.addComponent(box, RigidBody{});
+
+    try simulate(&sim, &grid, frame, 120);
+
+    // Resting on the surface (bottom at 0.5), sunk no deeper than the slop allows
+    const position = frame.getComponent(box, Transform).?.position;
+    try testing.expect(position.y.lte(fp(1)));
+    try testing.expect(position.y.gt(fp(0.98)));
+    try testing.expect(frame.getComponent(box, Velocity).?.linear.y.abs().lt(fp(0.2)));
+}
+
+test "Restitution reflects the closing velocity" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var sim = TestPhysics.init(testing.allocator, .{ .gravity = FPVector2.ZERO });
+    defer sim.deinit();
+
+    const frame = test_ecs.getFrame();
+    _ = try addGround(frame, RigidBody{ .kind = .static, .restitution = fp(1) });
+    const ball = try frame.createEntity();
+    try frame.addComponent(ball, Transform{ .position = fpVec2(0, 0.9) });
+    try frame.addComponent(ball, Velocity{ .linear = fpVec2(0, -5) });
+    try frame.addComponent(ball, Collider.circle(fp(0.5)));
+    try frame.addComponent(ball, RigidBody{ .restitution = fp(1) });
+
+    try simulate(&sim, &grid, frame, 1);
+
+    try testing.expectEqual(fp(5).raw_value, frame.getComponent(ball, Velocity).?.linear.y.raw_value);
+    // Moved up and pushed out of the ground
+    try testing.expect(frame.getComponent(ball, Transform).?.position.y.gt(fp(0.9)));
+}
+
+test "Friction slows a sliding box to a stop" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var sim = TestPhysics.init(testing.allocator, .{ .gravity = fpVec2(0, -10) });
+    defer sim.deinit();
+
+    const frame = test_ecs.getFrame();
+    _ = try addGround(frame, null);
+
+    const rough = try frame.createEntity();
+    try frame.addComponent(rough, Transform{ .position = fpVec2(-10, 1) });
+    try frame.addComponent(rough, Velocity{ .linear = fpVec2(2, 0) });
+    try frame.addComponent(rough, Collider.box(fpVec2(0.5, 0.5)));
+    try frame.addComponent(rough, RigidBody{});
+
+    const slick = try frame.createEntity();
+    try frame.addComponent(slick, Transform{ .position = fpVec2(5, 1) });
+    try frame.addComponent(slick, Velocity{ .linear = fpVec2(2, 0) });
+    try frame.addComponent(slick, Collider.box(fpVec2(0.5, 0.5)));
+    try frame.addComponent(slick, RigidBody{ .friction = fp(0) });
+
+    // mu = 0.5 under 10 m/s^2 gravity stops 2 m/s in 0.4s (24 steps)
+    try simulate(&sim, &grid, frame, 60);
+
+    try testing.expect(frame.getComponent(rough, Velocity).?.linear.x.abs().lt(fp(0.05)));
+    try testing.expectEqual(fp(2).raw_value, frame.getComponent(slick, Velocity).?.linear.x.raw_value);
+}
+
+test "Physics replays identically after rollback" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var sim = TestPhysics.init(testing.allocator, .{ .gravity = fpVec2(0, -10) });
+    defer sim.deinit();
+
+    const frame = test_ecs.getFrame();
+    _ = try addGround(frame, null);
+
+    // A loose pile of boxes and balls
+    var bodies: [6]ecs.EntityID = undefined;
+    for (&bodies, 0..) |*entity, i| {
+        entity.* = try frame.createEntity();
+        const offset = FP.fromInt(@as(i32, @intCast(i)));
+        try frame.addComponent(entity.*, Transform{ .position = FPVector2.new(offset.mul(fp(0.6)), fp(2).add(offset)) });
+        try frame.addComponent(entity.*, Velocity{ .linear = FPVector2.new(fp(1).sub(offset.mul(fp(0.4))), fp(0)) });
+        try frame.addComponent(entity.*, if (i % 2 == 0) Collider.box(fpVec2(0.5, 0.5)) else Collider.circle(fp(0.5)));
+        try frame.addComponent(entity.*, RigidBody{ .restitution = fp(0.3) });
+    }
+
+    try simulate(&sim, &grid, frame, 10);
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TestECS.freeSavedFrame(&saved);
+
+    try simulate(&sim, &grid, frame, 90);
+    var first: [bodies.len]FPVector2 = undefined;
+    for (bodies, 0..) |entity, i| first[i] = frame.getComponent(entity, Transform).?.position;
+
+    try test_ecs.restoreFrame(&saved);
+    try simulate(&sim, &grid, frame, 90);
+    for (bodies, 0..) |entity, i| {
+        const position = frame.getComponent(entity, Transform).?.position;
+        try testing.expectEqual(first[i].x.raw_value, position.x.raw_value);
+        try testing.expectEqual(first[i].y.raw_value, position.y.raw_value);
+    }
+}
+
+test "Acceleration, drag and the speed limit shape the velocity of moving bodies" {
+    var test_ecs = try DrivenECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(DrivenECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var sim = physics.Physics(DrivenECS).init(testing.allocator, .{ .gravity = FPVector2.ZERO });
+    defer sim.deinit();
+
+    const frame = test_ecs.getFrame();
+    const Spawn = struct {
+        fn body(f: *DrivenECS.Frame, kind: components.BodyKind, velocity: FPVector2) !ecs.EntityID {
+            const entity = try f.createEntity();
+            try f.addComponent(entity, Transform{});
+            try f.addComponent(entity, Velocity{ .linear = velocity });
+            try f.addComponent(entity, RigidBody{ .kind = kind });
+            return entity;
+        }
+    };
+
+    const rocket = try Spawn.body(frame, .dynamic, FPVector2.ZERO);
+    try frame.addComponent(rocket, Acceleration{ .linear = fpVec2(10, 0), .angular = fp(1) });
+    try frame.addComponent(rocket, Drag{ .max_speed = fp(3) });
+    const coasting = try Spawn.body(frame, .dynamic, fpVec2(4, 0));
+    try frame.addComponent(coasting, Drag{ .linear = fp(0.5) });
+    // Drag beyond 1/dt stops a body instead of reversing it
+    const anchored = try Spawn.body(frame, .dynamic, fpVec2(0, -4));
+    try frame.addComponent(anchored, Drag{ .linear = fp(120) });
+    // Kinematic bodies ignore acceleration but are still dragged
+    const platform = try Spawn.body(frame, .kinematic, fpVec2(2, 0));
+    try frame.addComponent(platform, Acceleration{ .linear = fpVec2(10, 0) });
+    try frame.addComponent(platform, Drag{ .max_speed = fp(1) });
+
+    for (0..60) |_| {
+        try grid.sync(frame);
+        try sim.step(frame, &grid, dt);
+    }
+
+    const rocket_velocity = frame.getComponent(rocket, Velocity).?;
+    try testing.expect(rocket_velocity.linear.x.lt(fp(3.01)) and rocket_velocity.linear.x.gt(fp(2.99)));
+    try testing.expect(rocket_velocity.angular.gt(fp(0.99)));
+    // 4 * (1 - 0.5 / 60)^60 is about 2.42
+    const coasting_speed = frame.getComponent(coasting, Velocity).?.linear.x;
+    try testing.expect(coasting_speed.gt(fp(2.38)) and coasting_speed.lt(fp(2.46)));
+    try testing.expect(frame.getComponent(anchored, Velocity).?.linear.eq(FPVector2.ZERO));
+    try testing.expect(frame.getComponent(platform, Velocity).?.linear.x.lt(fp(1.01)));
+    try testing.expect(frame.getComponent(platform, Transform).?.position.x.lt(fp(1.1)));
+}
@@ -0,0 +1,119 @@
+const std = @import("std");
+const builtin = @import("builtin");
+
+/// Runs per-tick snapshot work - serialization, replication, replay recording - on a worker
+/// thread while the next tick simulates.
+///
+/// `publish` copies the live frame into one of two back buffers (a memcpy per storage) and hands
+/// it to the worker, so the tick only pays for the copy and the expensive consumer overlaps the
+/// following simulation. With two buffers the worker may lag one frame behind; a tick that would
+/// overwrite the buffer still being consumed waits for it. Consumers see every published frame,
+/// in order, and must only read it.
+///
+/// Usage:
+///   var pipeline: Pipeline(GameECS) = undefined;
+///   try pipeline.init(allocator, .{ .context = &recorder, .consume = Recorder.write });
+///   defer pipeline.deinit();
+///   while (running) {
+///       world.update(input, dt, time);
+///       try schedule.run(world.getFrame());
+///       try pipeline.publish(&world);
+///   }
+pub fn Pipeline(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const ConsumeFn = *const fn (context: *anyopaque, frame: *EcsType.Frame) void;
+
+        pub const Options = struct {
+            context: *anyopaque,
+            consume: ConsumeFn,
+            /// Consume on the calling thread inside `publish` - for debugging and single-threaded builds
+            inline_consume: bool = builtin.single_threaded,
+        };
+
+        buffers: [2]EcsType.Frame,
+        options: Options,
+        thread: ?std.Thread = null,
+
+        mutex: std.Thread.Mutex = .{},
+        changed: std.Thread.Condition = .{},
+        published: u64 = 0,
+        consumed: u64 = 0,
+        stopping: bool = false,
+
+        /// Initialize in place - the worker thread keeps a pointer to `self`
+        pub fn init(self: *Self, allocator: std.mem.Allocator, options: Options) !void {
+            self.* = .{
+                .buffers = .{ try EcsType.createPreAllocatedFrame(allocator), undefined },
+                .options = options,
+            };
+            errdefer EcsType.freePreAllocatedFrame(&self.buffers[0]);
+            self.buffers[1] = try EcsType.createPreAllocatedFrame(allocator);
+            errdefer EcsType.freePreAllocatedFrame(&self.buffers[1]);
+
+            if (!options.inline_consume) self.thread = try std.Thread.spawn(.{}, work, .{self});
+        }
+
+        /// Wait for outstanding frames, stop the worker and free both buffers
+        pub fn deinit(self: *Self) void {
+            if (self.thread) |thread| {
+                self.mutex.lock();
+                self.stopping = true;
+                self.changed.broadcast();
+                self.mutex.unlock();
+                thread.join();
+            }
+            for (&self.buffers) |*buffer| EcsType.freePreAllocatedFrame(buffer);
+        }
+
+        /// Snapshot the live frame and queue it for the consumer
+        pub fn publish(self: *Self, world: *const EcsType) !void {
+            if (self.thread == null) {
+                try world.copyFrameTo(&self.buffers[0]);
+                self.options.consume(self.options.context, &self.buffers[0]);
+                self.published += 1;
+                self.consumed += 1;
+                return;
+            }
+
+            self.mutex.lock();
+            while (self.published - self.consumed >= self.buffers.len) self.changed.wait(&self.mutex);
+            const slot = self.published % self.buffers.len;
+            self.mutex.unlock();
+
+            // The worker only reads slots below `published`, so this one is ours until we bump it
+            try world.copyFrameTo(&self.buffers[slot]);
+
+            self.mutex.lock();
+            self.published += 1;
+            self.changed.broadcast();
+            self.mutex.unlock();
+        }
+
+        /// Block until the consumer has finished every published frame
+        pub fn flush(self: *Self) void {
+            self.mutex.lock();
+            defer self.mutex.unlock();
+            while (self.consumed != self.published) self.changed.wait(&self.mutex);
+        }
+
+        fn work(self: *Self) void {
+            self.mutex.lock();
+            defer self.mutex.unlock();
+            while (true) {
+                while (self.consumed == self.published and !self.stopping) self.changed.wait(&self.mutex);
+                // Drain everything published before stopping
+                if (self.consumed == self.published) return;
+
+                const slot = self.consumed % self.buffers.len;
+                self.mutex.unlock();
+                self.options.consume(self.options.context, &self.buffers[slot]);
+                self.mutex.lock();
+
+                self.consumed += 1;
+                self.changed.broadcast();
+            }
+        }
+    };
+}
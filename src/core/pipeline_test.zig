@@ -0,0 +1,71 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const Pipeline = @import("pipeline.zig").Pipeline;
+
+const Position = struct { x: i32, y: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Position},
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const Recorder = struct {
+    frame_numbers: std.BoundedArray(u64, 64) = .{},
+    entity_counts: std.BoundedArray(u32, 64) = .{},
+    position_sums: std.BoundedArray(i64, 64) = .{},
+
+    fn consume(context: *anyopaque, frame: *TestECS.Frame) void {
+        const self: *Recorder = @ptrCast(@alignCast(context));
+        var sum: i64 = 0;
+        for (frame.getComponentStorage(Position).getDenseArray()) |position| sum += position.x;
+        // Stand-in for slow serialization
+        std.Thread.sleep(100 * std.time.ns_per_us);
+        self.frame_numbers.appendAssumeCapacity(frame.frame_number);
+        self.entity_counts.appendAssumeCapacity(frame.getEntityCount());
+        self.position_sums.appendAssumeCapacity(sum);
+    }
+};
+
+fn simulate(inline_consume: bool) !Recorder {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var recorder = Recorder{};
+
+    var pipeline: Pipeline(TestECS) = undefined;
+    try pipeline.init(testing.allocator, .{ .context = &recorder, .consume = Recorder.consume, .inline_consume = inline_consume });
+    defer pipeline.deinit();
+
+    const frame = world.getFrame();
+    for (0..20) |_| {
+        world.update(.{}, 0.016, 0.016);
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        var query = try frame.query(&.{Position});
+        while (query.nextFast()) |result| result.get(Position).x += 1;
+        try pipeline.publish(&world);
+    }
+    pipeline.flush();
+    return recorder;
+}
+
+test "Published frames reach the consumer in order and isolated from later ticks" {
+    const threaded = try simulate(false);
+    const inline_run = try simulate(true);
+
+    try testing.expectEqual(@as(usize, 20), threaded.frame_numbers.len);
+    for (threaded.frame_numbers.constSlice(), threaded.entity_counts.constSlice(), threaded.position_sums.constSlice(), 1..) |number, count, sum, tick| {
+        try testing.expectEqual(@as(u64, tick), number);
+        try testing.expectEqual(@as(u32, @intCast(tick)), count);
+        // Entity k (0-based) has been moved tick - k times
+        try testing.expectEqual(@as(i64, @intCast(tick * (tick + 1) / 2)), sum);
+    }
+
+    try testing.expectEqualSlices(u64, inline_run.frame_numbers.constSlice(), threaded.frame_numbers.constSlice());
+    try testing.expectEqualSlices(i64, inline_run.position_sums.constSlice(), threaded.position_sums.constSlice());
+}
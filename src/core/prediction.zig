@@ -0,0 +1,197 @@
+const std = @import("std");
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+
+/// Client-side prediction against an authoritative server.
+///
+/// The client's world is the predicted one: `predict` simulates each local input on it right
+/// away instead of waiting a round trip. Next to it sits the confirmed frame - the newest tick the
+/// server vouched for. The server confirms ticks in order, either with the input it simulated
+/// (`confirm`) or with the resulting state (`confirmFrame`, for a snapshot or a
+/// `Relevancy.applyUpdate`d world). When the confirmation agrees with what was predicted the
+/// confirmed frame simply moves forward; when it does not, the world is reset to the confirmed
+/// frame and the inputs the server has not confirmed yet are simulated again on top of it.
+///
+/// Inputs are whole frame inputs: for several players, the local one plus a guess (usually the
+/// last known input) for everyone else.
+///
+/// Usage:
+///   var prediction = try Prediction(GameECS).init(allocator, &world, .{});
+///   defer prediction.deinit();
+///   while (running) {
+///       while (server.nextConfirmation()) |input| try prediction.confirm(input, &schedule);
+///       const pad = readPad();
+///       if (try prediction.predict(pad, &schedule)) server.sendInput(prediction.frame(), pad);
+///   }
+pub fn Prediction(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+        const History = FrameHistory(EcsType);
+
+        pub const Input = @FieldType(EcsType.Frame, "input");
+
+        pub const Options = struct {
+            /// Ticks the predicted world may run past the confirmed one, 1 to 32
+            max_prediction: u8 = 16,
+            /// Fixed tick length
+            delta_time: f32 = 1.0 / 60.0,
+        };
+
+        // Inputs kept for the unconfirmed ticks
+        const ring_size = 64;
+
+        world: *EcsType,
+        options: Options,
+        /// Newest tick the server confirmed
+        confirmed: EcsType.Frame,
+        /// Predicted ticks from `confirmed` on, for moving it forward without simulating
+        history: History,
+        /// Input each predicted tick was simulated with
+        inputs: [ring_size]Input = undefined,
+
+        /// Ticks predicted and confirmed, confirmations that disagreed with the prediction,
+        /// ticks simulated again to correct them and ticks refused for running too far ahead
+        predicted_ticks: u64 = 0,
+        confirmed_ticks: u64 = 0,
+        mispredictions: u64 = 0,
+        resimulated: u64 = 0,
+        stalls: u64 = 0,
+        /// Tick of the latest misprediction
+        last_misprediction: ?u64 = null,
+
+        /// Start predicting from the world's current frame, which counts as confirmed
+        pub fn init(allocator: std.mem.Allocator, world: *EcsType, options: Options) !Self {
+            if (options.max_prediction == 0 or options.max_prediction > 32) return error.InvalidOptions;
+
+            var confirmed = try EcsType.createPreAllocatedFrame(allocator);
+            errdefer EcsType.freePreAllocatedFrame(&confirmed);
+            try world.copyFrameTo(&confirmed);
+
+            var history = try History.init(allocator, @as(usize, options.max_prediction) + 1);
+            errdefer history.deinit();
+            try history.record(world);
+
+            return .{
+                .world = world,
+                .options = options,
+                .confirmed = confirmed,
+                .history = history,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.history.deinit();
+            EcsType.freePreAllocatedFrame(&self.confirmed);
+        }
+
+        /// Tick the predicted world is at
+        pub fn frame(self: *const Self) u64 {
+            return self.world.current_frame.frame_number;
+        }
+
+        pub fn confirmedFrame(self: *const Self) u64 {
+            return self.confirmed.frame_number;
+        }
+
+        /// Predicted ticks the server has not confirmed yet
+        pub fn pending(self: *const Self) u64 {
+            return self.frame() -| self.confirmed.frame_number;
+        }
+
+        /// Share of the confirmed ticks that had been predicted wrong, 0 before any
+        pub fn mispredictionRate(self: *const Self) f64 {
+            if (self.confirmed_ticks == 0) return 0;
+            return @as(f64, @floatFromInt(self.mispredictions)) / @as(f64, @floatFromInt(self.confirmed_ticks));
+        }
+
+        /// Simulate the next tick with `input` through `systems` (anything with
+        /// `run(*Frame) !void`, like a `Schedule`). Returns false without simulating when the
+        /// world is already `max_prediction` ticks past the confirmed frame - the input is not
+        /// taken, pass it again once the server caught up.
+        pub fn predict(self: *Self, input: Input, systems: anytype) !bool {
+            if (self.pending() >= self.options.max_prediction) {
+                self.stalls += 1;
+                return false;
+            }
+            try self.simulate(input, systems);
+            self.predicted_ticks += 1;
+            return true;
+        }
+
+        /// The server simulated the tick after the confirmed one with `input`
+        pub fn confirm(self: *Self, input: Input, systems: anytype) !void {
+            const frame_number = self.confirmed.frame_number + 1;
+            self.confirmed_ticks += 1;
+            if (frame_number <= self.frame()) {
+                if (std.meta.eql(self.inputs[slot(frame_number)], input)) {
+                    try copyFrame(&self.confirmed, self.history.get(frame_number).?);
+                    return;
+                }
+                self.recordMisprediction(frame_number);
+                self.inputs[slot(frame_number)] = input;
+            }
+
+            // Simulate the tick on the confirmed frame, then the predictions after it again
+            const newest = @max(self.frame(), frame_number);
+            try self.world.restoreFrame(&self.confirmed);
+            self.history.clear();
+            try self.history.record(self.world);
+            try self.simulate(input, systems);
+            try self.world.copyFrameTo(&self.confirmed);
+            try self.resimulate(newest, systems);
+        }
+
+        /// The server's state at the end of a tick after the confirmed one. Older frames are
+        /// ignored. Predictions past it are simulated again on top of it unless they started
+        /// from the same state.
+        pub fn confirmFrame(self: *Self, authoritative: *const EcsType.Frame, systems: anytype) !void {
+            const frame_number = authoritative.frame_number;
+            if (frame_number <= self.confirmed.frame_number) return;
+            self.confirmed_ticks += frame_number - self.confirmed.frame_number;
+            try copyFrame(&self.confirmed, authoritative);
+
+            if (frame_number <= self.frame()) {
+                if (self.history.get(frame_number).?.checksum() == authoritative.checksum()) return;
+                self.recordMisprediction(frame_number);
+            }
+
+            const newest = @max(self.frame(), frame_number);
+            try self.world.restoreFrame(&self.confirmed);
+            self.history.clear();
+            try self.history.record(self.world);
+            try self.resimulate(newest, systems);
+        }
+
+        fn slot(frame_number: u64) usize {
+            return @intCast(frame_number % ring_size);
+        }
+
+        fn simulate(self: *Self, input: Input, systems: anytype) !void {
+            self.inputs[slot(self.frame() + 1)] = input;
+            const time = self.world.current_frame.time + self.options.delta_time;
+            self.world.update(input, self.options.delta_time, time);
+            try systems.run(self.world.getFrame());
+            try self.history.record(self.world);
+        }
+
+        // Predict the ticks up to `newest` again with the inputs they were predicted with
+        fn resimulate(self: *Self, newest: u64, systems: anytype) !void {
+            while (self.frame() < newest) {
+                try self.simulate(self.inputs[slot(self.frame() + 1)], systems);
+                self.resimulated += 1;
+            }
+        }
+
+        fn recordMisprediction(self: *Self, frame_number: u64) void {
+            self.mispredictions += 1;
+            self.last_misprediction = frame_number;
+        }
+
+        fn copyFrame(dest: *EcsType.Frame, source: *const EcsType.Frame) !void {
+            try dest.state.copyFrom(&source.state);
+            dest.input = source.input;
+            dest.deltaTime = source.deltaTime;
+            dest.time = source.time;
+            dest.frame_number = source.frame_number;
+        }
+    };
+}
@@ -0,0 +1,136 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const prediction = @import("prediction.zig");
+
+const Pad = struct {
+    dx: i8 = 0,
+};
+
+const Position = struct { x: i32 = 0 };
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Position},
+    .input = Pad,
+    .max_entities = .tiny,
+});
+
+const Prediction = prediction.Prediction(TestECS);
+
+const Movement = struct {
+    fn run(_: *const Movement, frame: *TestECS.Frame) !void {
+        var query = try frame.query(&.{Position});
+        while (query.next()) |result| result.get(Position).x += frame.input.dx;
+    }
+};
+
+fn spawnAvatar(world: *TestECS) !ecs.EntityID {
+    const entity = try world.getFrame().createEntity();
+    try world.getFrame().addComponent(entity, Position{});
+    return entity;
+}
+
+// The server's side: the same simulation, fed the inputs it settled on
+fn serverTick(world: *TestECS, input: Pad) !void {
+    world.update(input, 1.0 / 60.0, world.getFrame().time + 1.0 / 60.0);
+    const movement = Movement{};
+    try movement.run(world.getFrame());
+}
+
+test "Confirmations that match the prediction only move the confirmed frame" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var server = try TestECS.init(testing.allocator);
+    defer server.deinit();
+    const avatar = try spawnAvatar(&world);
+    _ = try spawnAvatar(&server);
+
+    var predictor = try Prediction.init(testing.allocator, &world, .{ .max_prediction = 4 });
+    defer predictor.deinit();
+
+    for (1..5) |i| try testing.expect(try predictor.predict(.{ .dx = @intCast(i) }, &Movement{}));
+    // Four ahead of the server is the limit
+    try testing.expect(!try predictor.predict(.{ .dx = 5 }, &Movement{}));
+    try testing.expectEqual(@as(u64, 1), predictor.stalls);
+    try testing.expectEqual(@as(i32, 10), world.getFrame().getComponent(avatar, Position).?.x);
+
+    for (1..4) |i| {
+        try serverTick(&server, .{ .dx = @intCast(i) });
+        try predictor.confirm(.{ .dx = @intCast(i) }, &Movement{});
+    }
+    try testing.expectEqual(@as(u64, 3), predictor.confirmedFrame());
+    try testing.expectEqual(@as(u64, 1), predictor.pending());
+    try testing.expectEqual(server.getFrame().checksum(), predictor.confirmed.checksum());
+    try testing.expectEqual(@as(u64, 0), predictor.mispredictions);
+    try testing.expectEqual(@as(u64, 0), predictor.resimulated);
+    try testing.expectEqual(@as(f64, 0), predictor.mispredictionRate());
+    try testing.expect(try predictor.predict(.{ .dx = 5 }, &Movement{}));
+}
+
+test "A mispredicted input replays the unconfirmed ones on the server's result" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var server = try TestECS.init(testing.allocator);
+    defer server.deinit();
+    const avatar = try spawnAvatar(&world);
+    _ = try spawnAvatar(&server);
+
+    var predictor = try Prediction.init(testing.allocator, &world, .{});
+    defer predictor.deinit();
+    for (0..5) |_| _ = try predictor.predict(.{ .dx = 1 }, &Movement{});
+
+    // The server held the avatar in place on tick 2
+    try predictor.confirm(.{ .dx = 1 }, &Movement{});
+    try predictor.confirm(.{ .dx = 0 }, &Movement{});
+    try testing.expectEqual(@as(u64, 1), predictor.mispredictions);
+    try testing.expectEqual(@as(?u64, 2), predictor.last_misprediction);
+    try testing.expectEqual(@as(u64, 3), predictor.resimulated);
+    try testing.expectEqual(@as(u64, 5), predictor.frame());
+    try testing.expectEqual(@as(i32, 4), world.getFrame().getComponent(avatar, Position).?.x);
+    try testing.expectEqual(@as(i32, 1), predictor.confirmed.getComponent(avatar, Position).?.x);
+
+    // The replayed ticks keep their inputs - confirming them as predicted changes nothing
+    for ([_]i8{ 1, 0, 1, 1, 1 }) |dx| try serverTick(&server, .{ .dx = dx });
+    for (0..3) |_| try predictor.confirm(.{ .dx = 1 }, &Movement{});
+    try testing.expectEqual(@as(u64, 3), predictor.resimulated);
+    try testing.expectEqual(server.getFrame().checksum(), world.getFrame().checksum());
+
+    // A confirmation past the prediction simulates the tick outright
+    try predictor.confirm(.{ .dx = -2 }, &Movement{});
+    try testing.expectEqual(@as(u64, 6), predictor.frame());
+    try testing.expectEqual(@as(i32, 2), world.getFrame().getComponent(avatar, Position).?.x);
+    try testing.expectEqual(@as(u64, 1), predictor.mispredictions);
+}
+
+test "Authoritative frames correct the prediction when the states differ" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var server = try TestECS.init(testing.allocator);
+    defer server.deinit();
+    const avatar = try spawnAvatar(&world);
+    _ = try spawnAvatar(&server);
+
+    var predictor = try Prediction.init(testing.allocator, &world, .{});
+    defer predictor.deinit();
+    for (0..4) |_| _ = try predictor.predict(.{ .dx = 2 }, &Movement{});
+
+    try serverTick(&server, .{ .dx = 2 });
+    try predictor.confirmFrame(server.getFrame(), &Movement{});
+    try testing.expectEqual(@as(u64, 0), predictor.mispredictions);
+    try testing.expectEqual(@as(u64, 0), predictor.resimulated);
+
+    // Something only the server knew about pushed the avatar back
+    try serverTick(&server, .{ .dx = 2 });
+    server.getFrame().getComponent(avatar, Position).?.x -= 10;
+    try predictor.confirmFrame(server.getFrame(), &Movement{});
+    try testing.expectEqual(@as(u64, 1), predictor.mispredictions);
+    try testing.expectEqual(@as(u64, 2), predictor.resimulated);
+    try testing.expectEqual(@as(i32, -6 + 4), world.getFrame().getComponent(avatar, Position).?.x);
+
+    // Frames older than the confirmed one are stale
+    var stale = try TestECS.init(testing.allocator);
+    defer stale.deinit();
+    try predictor.confirmFrame(stale.getFrame(), &Movement{});
+    try testing.expectEqual(@as(u64, 2), predictor.confirmedFrame());
+    try testing.expectEqual(@as(u64, 2), predictor.confirmed_ticks);
+}
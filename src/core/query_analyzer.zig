@@ -0,0 +1,232 @@
+const std = @import("std");
+const Clock = @import("clock.zig").Clock;
+
+/// Per-frame query report. Attach to an ECS with `setQueryAnalyzer` and every query built during a
+/// frame is recorded with the components it required, how many live entities it started from,
+/// how many matched and how long the intersection took.
+///
+/// Two problems show up immediately in the report:
+///   - the same query rebuilt several times per frame (hoist it, or reuse the query object)
+///   - overly broad queries where few candidates match (reorder systems or add a narrower component)
+pub const QueryAnalyzer = struct {
+    allocator: std.mem.Allocator,
+    component_names: []const []const u8,
+    current: std.ArrayList(Entry),
+    last: std.ArrayList(Entry),
+    current_frame: u64,
+    last_frame: u64,
+    enabled: bool,
+
+    /// Queries matching less than this fraction of candidates are flagged as broad
+    broad_threshold_percent: u32 = 10,
+    /// Queries over fewer candidates than this are never flagged as broad
+    broad_min_candidates: u32 = 64,
+    /// Times each intersection (a `ManualClock` makes reports reproducible)
+    clock: Clock = Clock.real,
+
+    const Self = @This();
+
+    /// Aggregated statistics for one distinct query (component mask) within a frame
+    pub const Entry = struct {
+        mask: u64,
+        executions: u32,
+        candidates: u32,
+        matches: u32,
+        time_ns: u64,
+
+        /// Percentage of candidates that matched (100 when there were no candidates)
+        pub fn selectivityPercent(self: Entry) f64 {
+            if (self.candidates == 0) return 100.0;
+            return @as(f64, @floatFromInt(self.matches)) / @as(f64, @floatFromInt(self.candidates)) * 100.0;
+        }
+    };
+
+    pub fn init(allocator: std.mem.Allocator, component_names: []const []const u8) Self {
+        return Self{
+            .allocator = allocator,
+            .component_names = component_names,
+            .current = std.ArrayList(Entry).init(allocator),
+            .last = std.ArrayList(Entry).init(allocator),
+            .current_frame = 0,
+            .last_frame = 0,
+            .enabled = true,
+        };
+    }
+
+    pub fn deinit(self: *Self) void {
+        self.current.deinit();
+        self.last.deinit();
+    }
+
+    /// Close the current frame and start collecting for `frame_number`. Called by ECS.update.
+    pub fn beginFrame(self: *Self, frame_number: u64) void {
+        std.mem.swap(std.ArrayList(Entry), &self.current, &self.last);
+        self.current.clearRetainingCapacity();
+        self.last_frame = self.current_frame;
+        self.current_frame = frame_number;
+    }
+
+    /// Record one query construction. Called by the ECS query path when an analyzer is attached.
+    pub fn record(self: *Self, mask: u64, candidates: u32, matches: u32, time_ns: u64) void {
+        if (!self.enabled) return;
+
+        for (self.current.items) |*entry| {
+            if (entry.mask == mask) {
+                entry.executions += 1;
+                entry.candidates += candidates;
+                entry.matches += matches;
+                entry.time_ns += time_ns;
+                return;
+            }
+        }
+
+        self.current.append(.{
+            .mask = mask,
+            .executions = 1,
+            .candidates = candidates,
+            .matches = matches,
+            .time_ns = time_ns,
+        }) catch {
+            std.log.warn("Query analyzer out of memory, analysis disabled", .{});
+            self.enabled = false;
+        };
+    }
+
+    /// Entries for the most recently completed frame
+    pub fn lastFrameEntries(self: *const Self) []const Entry {
+        return self.last.items;
+    }
+
+    /// Entries collected so far for the frame in progress
+    pub fn currentFrameEntries(self: *const Self) []const Entry {
+        return self.current.items;
+    }
+
+    pub fn isRepeated(entry: Entry) bool {
+        return entry.executions > 1;
+    }
+
+    pub fn isBroad(self: *const Self, entry: Entry) bool {
+        const per_run_candidates = entry.candidates / @max(entry.executions, 1);
+        if (per_run_candidates < self.broad_min_candidates) return false;
+        return @as(u64, entry.matches) * 100 < @as(u64, entry.candidates) * self.broad_threshold_percent;
+    }
+
+    /// Two small components the last frame's queries kept reading together - candidates for a
+    /// `HotPack` so one cache line holds both instead of two lines in two arrays
+    pub const PackingHint = struct {
+        a: u32,
+        b: u32,
+        /// Entity visits that needed both (matches of every query requiring the pair)
+        co_accesses: u32,
+        /// Combined size in bytes
+        bytes: usize,
+    };
+
+    /// Component pairs co-queried in the last frame whose combined size fits a cache line, most
+    /// co-accessed first. `sizes` is `EcsType.component_sizes`. Returns the used prefix of `out`.
+    pub fn packingHints(self: *const Self, sizes: []const usize, out: []PackingHint) []PackingHint {
+        var len: usize = 0;
+        for (0..sizes.len) |a| {
+            for (a + 1..sizes.len) |b| {
+                const bytes = sizes[a] + sizes[b];
+                if (bytes > std.atomic.cache_line or sizes[a] == 0 or sizes[b] == 0) continue;
+                const pair = (@as(u64, 1) << @intCast(a)) | (@as(u64, 1) << @intCast(b));
+                var co_accesses: u32 = 0;
+                for (self.last.items) |entry| {
+                    if (entry.mask & pair == pair) co_accesses += entry.matches;
+                }
+                if (co_accesses == 0) continue;
+
+                const hint = PackingHint{ .a = @intCast(a), .b = @intCast(b), .co_accesses = co_accesses, .bytes = bytes };
+                // Insertion into the sorted prefix, dropping the weakest when full
+                var index = len;
+                while (index > 0 and out[index - 1].co_accesses < co_accesses) index -= 1;
+                if (index >= out.len) continue;
+                const end = @min(len + 1, out.len);
+                std.mem.copyBackwards(PackingHint, out[index + 1 .. end], out[index .. end - 1]);
+                out[index] = hint;
+                len = end;
+            }
+        }
+        return out[0..len];
+    }
+
+    /// Packing hints for the last frame as a table
+    pub fn writePackingReport(self: *const Self, writer: anytype, sizes: []const usize) !void {
+        var buffer: [8]PackingHint = undefined;
+        const hints = self.packingHints(sizes, &buffer);
+        try writer.print("Packing hints for frame {d}: {d} pairs fit a {d}-byte cache line\n", .{ self.last_frame, hints.len, std.atomic.cache_line });
+        for (hints) |hint| {
+            try writer.print("  {s}+{s}: {d} co-accesses, {d} bytes per entity\n", .{
+                self.componentName(hint.a),
+                self.componentName(hint.b),
+                hint.co_accesses,
+                hint.bytes,
+            });
+        }
+    }
+
+    fn componentName(self: *const Self, index: u32) []const u8 {
+        return if (index < self.component_names.len) self.component_names[index] else "?";
+    }
+
+    /// Write the component list of a query mask ("Position+Velocity")
+    pub fn writeMask(self: *const Self, writer: anytype, mask: u64) !void {
+        var first = true;
+        var bits = mask;
+        while (bits != 0) {
+            const index = @ctz(bits);
+            bits &= bits - 1;
+
+            if (!first) try writer.writeByte('+');
+            first = false;
+            if (index < self.component_names.len) {
+                try writer.writeAll(self.component_names[index]);
+            } else {
+                try writer.print("#{d}", .{index});
+            }
+        }
+        if (first) try writer.writeAll("<all entities>");
+    }
+
+    /// Report for the most recently completed frame
+    pub fn writeReport(self: *const Self, writer: anytype) !void {
+        try self.writeEntries(writer, self.last_frame, self.last.items);
+    }
+
+    /// Report for the frame in progress (useful when analyzing a single frame without calling update)
+    pub fn writeCurrentReport(self: *const Self, writer: anytype) !void {
+        try self.writeEntries(writer, self.current_frame, self.current.items);
+    }
+
+    fn writeEntries(self: *const Self, writer: anytype, frame_number: u64, entries: []const Entry) !void {
+        var executions: u32 = 0;
+        for (entries) |entry| executions += entry.executions;
+
+        try writer.print("Query report for frame {d}: {d} distinct queries, {d} executions\n", .{ frame_number, entries.len, executions });
+        try writer.print("  {s:<32} {s:>5} {s:>10} {s:>8} {s:>8} {s:>10}  {s}\n", .{ "components", "runs", "candidates", "matches", "select%", "time(us)", "notes" });
+
+        for (entries) |entry| {
+            var name_buffer: [256]u8 = undefined;
+            var name_stream = std.io.fixedBufferStream(&name_buffer);
+            self.writeMask(name_stream.writer(), entry.mask) catch {};
+
+            try writer.print("  {s:<32} {d:>5} {d:>10} {d:>8} {d:>8.1} {d:>10.2}  ", .{
+                name_stream.getWritten(),
+                entry.executions,
+                entry.candidates,
+                entry.matches,
+                entry.selectivityPercent(),
+                @as(f64, @floatFromInt(entry.time_ns)) / 1000.0,
+            });
+            if (isRepeated(entry)) {
+                try writer.print("rebuilt {d}x per frame; ", .{entry.executions});
+            }
+            if (self.isBroad(entry)) {
+                try writer.writeAll("broad intersection; ");
+            }
+            try writer.writeByte('\n');
+        }
+    }
+};
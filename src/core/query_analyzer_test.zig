@@ -0,0 +1,139 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const QueryAnalyzer = @import("query_analyzer.zig").QueryAnalyzer;
+
+const Position = struct { x: f32, y: f32 };
+const Velocity = struct { x: f32, y: f32 };
+const Boss = struct { phase: u8 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity, Boss },
+    .input = TestInput,
+    .max_entities = .medium,
+});
+
+test "Query analyzer aggregates queries per frame" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var analyzer = QueryAnalyzer.init(testing.allocator, &TestECS.component_names);
+    defer analyzer.deinit();
+    test_ecs.setQueryAnalyzer(&analyzer);
+
+    const frame = test_ecs.getFrame();
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+    }
+
+    test_ecs.update(.{}, 0.016, 0.016);
+
+    // Same query built twice in one frame
+    _ = try frame.query(&.{ Position, Velocity });
+    _ = try frame.query(&.{ Position, Velocity });
+    _ = try frame.query(&.{Position});
+
+    const entries = analyzer.currentFrameEntries();
+    try testing.expectEqual(@as(usize, 2), entries.len);
+
+    const pos_vel = entries[0];
+    try testing.expectEqual(@as(u32, 2), pos_vel.executions);
+    try testing.expectEqual(@as(u32, 20), pos_vel.candidates);
+    try testing.expectEqual(@as(u32, 10), pos_vel.matches);
+    try testing.expect(QueryAnalyzer.isRepeated(pos_vel));
+
+    const pos = entries[1];
+    try testing.expectEqual(@as(u32, 1), pos.executions);
+    try testing.expectEqual(@as(u32, 10), pos.matches);
+    try testing.expect(!QueryAnalyzer.isRepeated(pos));
+
+    // Closing the frame moves the entries to the report
+    test_ecs.update(.{}, 0.016, 0.032);
+    try testing.expectEqual(@as(usize, 2), analyzer.lastFrameEntries().len);
+    try testing.expectEqual(@as(usize, 0), analyzer.currentFrameEntries().len);
+
+    var report = std.ArrayList(u8).init(testing.allocator);
+    defer report.deinit();
+    try analyzer.writeReport(report.writer());
+    try testing.expect(std.mem.indexOf(u8, report.items, "Position+Velocity") != null);
+    try testing.expect(std.mem.indexOf(u8, report.items, "rebuilt 2x per frame") != null);
+}
+
+test "Query analyzer flags broad intersections" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var analyzer = QueryAnalyzer.init(testing.allocator, &TestECS.component_names);
+    defer analyzer.deinit();
+    test_ecs.setQueryAnalyzer(&analyzer);
+
+    const frame = test_ecs.getFrame();
+    for (0..200) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i == 0) try frame.addComponent(entity, Boss{ .phase = 1 });
+    }
+
+    _ = try frame.query(&.{ Position, Boss });
+    _ = try frame.query(&.{Position});
+
+    const entries = analyzer.currentFrameEntries();
+    try testing.expect(analyzer.isBroad(entries[0]));
+    try testing.expect(!analyzer.isBroad(entries[1]));
+}
+
+test "Queries are not recorded without an analyzer" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var analyzer = QueryAnalyzer.init(testing.allocator, &TestECS.component_names);
+    defer analyzer.deinit();
+    test_ecs.setQueryAnalyzer(&analyzer);
+    test_ecs.setQueryAnalyzer(null);
+
+    const frame = test_ecs.getFrame();
+    _ = try frame.query(&.{Position});
+    try testing.expectEqual(@as(usize, 0), analyzer.currentFrameEntries().len);
+}
+
+test "Packing hints rank small co-queried pairs" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var analyzer = QueryAnalyzer.init(testing.allocator, &TestECS.component_names);
+    defer analyzer.deinit();
+    test_ecs.setQueryAnalyzer(&analyzer);
+
+    const frame = test_ecs.getFrame();
+    for (0..10) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+        if (i == 0) try frame.addComponent(entity, Boss{ .phase = 1 });
+    }
+
+    test_ecs.update(.{}, 0.016, 0.016);
+    _ = try frame.query(&.{ Position, Velocity });
+    _ = try frame.query(&.{ Position, Boss });
+    test_ecs.update(.{}, 0.016, 0.032);
+
+    var buffer: [4]QueryAnalyzer.PackingHint = undefined;
+    const hints = analyzer.packingHints(&TestECS.component_sizes, &buffer);
+    try testing.expectEqual(@as(usize, 2), hints.len);
+    try testing.expectEqual(@as(u32, 0), hints[0].a);
+    try testing.expectEqual(@as(u32, 1), hints[0].b);
+    try testing.expectEqual(@as(u32, 10), hints[0].co_accesses);
+    try testing.expectEqual(@as(usize, 16), hints[0].bytes);
+    try testing.expectEqual(@as(u32, 2), hints[1].b);
+
+    var report = std.ArrayList(u8).init(testing.allocator);
+    defer report.deinit();
+    try analyzer.writePackingReport(report.writer(), &TestECS.component_sizes);
+    try testing.expect(std.mem.indexOf(u8, report.items, "Position+Velocity: 10 co-accesses") != null);
+}
@@ -0,0 +1,89 @@
+const std = @import("std");
+const FP = @import("fixed-math/FP.zig").FP;
+
+/// Seeded random numbers for gameplay, meant to be registered as a world resource:
+///
+///   const GameECS = ECS(.{ .components = ..., .input = ..., .resources = &.{Rand} });
+///   world.setResource(Rand.init(match_seed));
+///   // in a system
+///   const roll = frame.getResource(Rand).uintLessThan(6);
+///
+/// The generator is xoshiro256** over explicit state in the frame, so snapshots, rollback and
+/// `encodeFrame` carry it: a resimulated tick or a replay draws exactly the numbers the original
+/// did. Everything is integer or fixed-point math - peers agree bit for bit. A zeroed `Rand` (an
+/// unset resource) seeds itself with 0 on its first draw.
+pub const Rand = struct {
+    state: [4]u64 = .{ 0, 0, 0, 0 },
+
+    pub fn init(seed: u64) Rand {
+        // splitmix64 spreads the seed over the whole state - never all zero
+        var rand = Rand{};
+        var mix = seed;
+        for (&rand.state) |*word| {
+            mix +%= 0x9E3779B97F4A7C15;
+            var z = mix;
+            z = (z ^ (z >> 30)) *% 0xBF58476D1CE4E5B9;
+            z = (z ^ (z >> 27)) *% 0x94D049BB133111EB;
+            word.* = z ^ (z >> 31);
+        }
+        return rand;
+    }
+
+    pub fn next(self: *Rand) u64 {
+        if (std.mem.allEqual(u64, &self.state, 0)) self.* = init(0);
+        const s = &self.state;
+        const result = std.math.rotl(u64, s[1] *% 5, 7) *% 9;
+        const t = s[1] << 17;
+        s[2] ^= s[0];
+        s[3] ^= s[1];
+        s[1] ^= s[2];
+        s[0] ^= s[3];
+        s[2] ^= t;
+        s[3] = std.math.rotl(u64, s[3], 45);
+        return result;
+    }
+
+    /// Uniform in [0, bound), without modulo bias. `bound` must not be 0.
+    pub fn uintLessThan(self: *Rand, bound: u64) u64 {
+        std.debug.assert(bound > 0);
+        // Lemire: reject the low products that would over-represent small results
+        const threshold = (@as(u64, 0) -% bound) % bound;
+        while (true) {
+            const product = @as(u128, self.next()) * bound;
+            if (@as(u64, @truncate(product)) >= threshold) return @intCast(product >> 64);
+        }
+    }
+
+    /// Uniform in [min, max], both inclusive
+    pub fn intRange(self: *Rand, comptime T: type, min: T, max: T) T {
+        std.debug.assert(min <= max);
+        const span: u64 = @intCast(@as(i128, max) - @as(i128, min));
+        const offset: u64 = if (span == std.math.maxInt(u64)) self.next() else self.uintLessThan(span + 1);
+        return @intCast(@as(i128, min) + offset);
+    }
+
+    /// True `numerator` times in `denominator`
+    pub fn chance(self: *Rand, numerator: u64, denominator: u64) bool {
+        return self.uintLessThan(denominator) < numerator;
+    }
+
+    /// Uniform in [0, 1) at full fixed-point precision
+    pub fn fpUnit(self: *Rand) FP {
+        const shift: u6 = @intCast(64 - @as(u7, FP.PRECISION));
+        return FP.fromRaw(@intCast(self.next() >> shift));
+    }
+
+    /// Uniform in [min, max)
+    pub fn fpRange(self: *Rand, min: FP, max: FP) FP {
+        return min.add(max.sub(min).mul(self.fpUnit()));
+    }
+
+    /// Fisher-Yates, so every order is equally likely
+    pub fn shuffle(self: *Rand, comptime T: type, items: []T) void {
+        var i = items.len;
+        while (i > 1) {
+            i -= 1;
+            std.mem.swap(T, &items[i], &items[self.uintLessThan(i + 1)]);
+        }
+    }
+};
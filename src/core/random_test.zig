@@ -0,0 +1,89 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const Rand = @import("random.zig").Rand;
+const FrameHistory = @import("frame_history.zig").FrameHistory;
+const fp = @import("fixed-math/FP.zig").fp;
+
+const Loot = struct { roll: u64 = 0 };
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Loot},
+    .input = u8,
+    .resources = &.{Rand},
+    .max_entities = .tiny,
+});
+
+// Every tick each entity rolls new loot from the world's generator
+const Roll = struct {
+    fn run(_: *const Roll, frame: *TestECS.Frame) !void {
+        const rand = frame.getResource(Rand);
+        var query = try frame.query(&.{Loot});
+        while (query.next()) |result| result.get(Loot).roll = rand.uintLessThan(1000);
+    }
+};
+
+test "Equal seeds draw equal sequences and values stay in range" {
+    var a = Rand.init(7);
+    var b = Rand.init(7);
+    var other = Rand.init(8);
+    var differs = false;
+    for (0..1000) |_| {
+        const value = a.next();
+        try testing.expectEqual(value, b.next());
+        differs = differs or value != other.next();
+
+        const die = a.intRange(i32, -3, 3);
+        try testing.expect(die >= -3 and die <= 3);
+        const unit = a.fpUnit();
+        try testing.expect(unit.gte(fp(0)) and unit.lt(fp(1)));
+        try testing.expect(a.fpRange(fp(2), fp(5)).lt(fp(5)));
+    }
+    try testing.expect(differs);
+    try testing.expect(!a.chance(0, 10) and a.chance(10, 10));
+
+    // A zeroed generator (an unset resource) still produces numbers
+    var unset = Rand{};
+    var seeded = Rand.init(0);
+    try testing.expectEqual(seeded.next(), unset.next());
+    try testing.expectEqual(seeded.next(), unset.next());
+
+    var cards = [_]u8{ 0, 1, 2, 3, 4, 5, 6, 7 };
+    a.shuffle(u8, &cards);
+    var sorted = cards;
+    std.mem.sort(u8, &sorted, {}, std.sort.asc(u8));
+    try testing.expectEqualSlices(u8, &.{ 0, 1, 2, 3, 4, 5, 6, 7 }, &sorted);
+}
+
+test "A rolled-back tick redraws the same numbers" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var history = try FrameHistory(TestECS).init(testing.allocator, 8);
+    defer history.deinit();
+
+    world.setResource(Rand.init(1234));
+    for (0..4) |_| try world.getFrame().addComponent(try world.getFrame().createEntity(), Loot{});
+
+    var rolls: [6][4]u64 = undefined;
+    for (&rolls) |*tick| {
+        world.update(0, 1.0 / 60.0, 0);
+        try (Roll{}).run(world.getFrame());
+        try history.record(&world);
+        for (tick, 0..) |*roll, entity| roll.* = world.getFrame().getComponent(@intCast(entity), Loot).?.roll;
+    }
+    const checksum = world.getFrame().checksum();
+
+    try history.rollbackTo(&world, 2);
+    for (rolls[2..]) |tick| {
+        world.update(0, 1.0 / 60.0, 0);
+        try (Roll{}).run(world.getFrame());
+        for (tick, 0..) |roll, entity| {
+            try testing.expectEqual(roll, world.getFrame().getComponent(@intCast(entity), Loot).?.roll);
+        }
+    }
+    try testing.expectEqual(checksum, world.getFrame().checksum());
+}
+
+test {
+    std.testing.refAllDecls(@This());
+}
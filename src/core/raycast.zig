@@ -0,0 +1,215 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+const collision = @import("collision.zig");
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Collider = components.Collider;
+const AABB = collision.AABB;
+
+/// A ray (or the path of a swept box) through the world
+pub const Ray = struct {
+    origin: FPVector2,
+    /// Any non-zero length - normalized before casting
+    direction: FPVector2,
+    max_distance: FP,
+    /// Only colliders on one of these layers are hit
+    mask: u32 = std.math.maxInt(u32),
+};
+
+/// One collider hit along a ray
+pub const Hit = struct {
+    entity: EntityID,
+    /// Distance from the ray origin (0 when the cast starts inside the collider)
+    distance: FP,
+    /// Ray position at the hit - for box casts, the box center at first contact
+    point: FPVector2,
+    /// Surface normal at the hit, zero when the cast starts inside the collider
+    normal: FPVector2,
+
+    /// Sort order of hits: nearest first, ties broken by entity id
+    pub fn lessThan(_: void, a: Hit, b: Hit) bool {
+        if (a.distance.raw_value != b.distance.raw_value) return a.distance.lt(b.distance);
+        return a.entity < b.entity;
+    }
+};
+
+/// Distance and normal of a ray against a single shape
+pub const Contact = struct {
+    distance: FP,
+    normal: FPVector2,
+};
+
+/// Every collider hit by the ray within `max_distance`, appended to `hits` ordered by distance
+/// (ties broken by entity id). `index` is any synced spatial index (`spatial.Grid`,
+/// `spatial.Quadtree`, `spatial.SpatialIndex`).
+pub fn raycast(comptime EcsType: type, frame: *EcsType.Frame, index: anytype, ray: Ray, hits: *std.ArrayList(Hit)) !void {
+    try cast(EcsType, frame, index, ray, FPVector2.ZERO, hits);
+}
+
+/// Closest collider hit by the ray, if any
+pub fn raycastFirst(comptime EcsType: type, frame: *EcsType.Frame, index: anytype, ray: Ray, scratch: *std.ArrayList(Hit)) !?Hit {
+    scratch.clearRetainingCapacity();
+    try raycast(EcsType, frame, index, ray, scratch);
+    return if (scratch.items.len > 0) scratch.items[0] else null;
+}
+
+/// Sweep an axis-aligned box with `half_extents` along the ray. Exact against boxes and circles;
+/// capsules are treated as their bounding box.
+pub fn boxcast(comptime EcsType: type, frame: *EcsType.Frame, index: anytype, half_extents: FPVector2, ray: Ray, hits: *std.ArrayList(Hit)) !void {
+    try cast(EcsType, frame, index, ray, half_extents, hits);
+}
+
+fn cast(comptime EcsType: type, frame: *EcsType.Frame, index: anytype, ray: Ray, half_extents: FPVector2, hits: *std.ArrayList(Hit)) !void {
+    const direction = ray.direction.normalize();
+    if (direction.eq(FPVector2.ZERO)) return error.ZeroDirection;
+
+    // Broadphase over the box covering the whole path
+    const end = ray.origin.add(direction.mul(ray.max_distance));
+    const widen = collision.maxColliderReach(EcsType, frame).add(half_extents);
+    var candidates = EcsType.EntityBitSet.initEmpty();
+    index.queryAABB(
+        FPVector2.min(ray.origin, end).sub(widen),
+        FPVector2.max(ray.origin, end).add(widen),
+        &candidates,
+    );
+
+    const start = hits.items.len;
+    var iter = candidates.fastIterator();
+    while (iter.next()) |entity| {
+        const collider = frame.getComponent(entity, Collider) orelse continue;
+        const transform = frame.getComponent(entity, Transform) orelse continue;
+        if ((collider.layer & ray.mask) == 0) continue;
+
+        const center = collision.colliderCenter(transform.*, collider.*);
+        const contact = castAgainst(ray.origin, direction, half_extents, center, collider.shape) orelse continue;
+        if (contact.distance.gt(ray.max_distance)) continue;
+
+        try hits.append(.{
+            .entity = entity,
+            .distance = contact.distance,
+            .point = ray.origin.add(direction.mul(contact.distance)),
+            .normal = contact.normal,
+        });
+    }
+
+    std.sort.pdq(Hit, hits.items[start..], {}, Hit.lessThan);
+}
+
+/// Swept box (zero extents for a plain ray) against one shape, via the Minkowski sum.
+/// `direction` must be normalized.
+pub fn castAgainst(origin: FPVector2, direction: FPVector2, half_extents: FPVector2, center: FPVector2, shape: components.Shape) ?Contact {
+    return switch (shape) {
+        .box => |box_half| rayBox(origin, direction, AABB.fromCenter(center, box_half.add(half_extents))),
+        .circle => |radius| if (half_extents.eq(FPVector2.ZERO))
+            rayCircle(origin, direction, center, radius)
+        else
+            rayRoundedBox(origin, direction, center, half_extents, radius),
+        .capsule => |capsule| if (half_extents.eq(FPVector2.ZERO))
+            rayCapsule(origin, direction, center.sub(capsule.half_segment), center.add(capsule.half_segment), capsule.radius)
+        else
+            rayBox(origin, direction, AABB.fromCenter(center, shape.boundingHalfExtents().add(half_extents))),
+    };
+}
+
+/// Ray (normalized direction) against a box - slab method
+pub fn rayBox(origin: FPVector2, direction: FPVector2, box: AABB) ?Contact {
+    const inside = origin.x.gt(box.min.x) and origin.x.lt(box.max.x) and
+        origin.y.gt(box.min.y) and origin.y.lt(box.max.y);
+    if (inside) return .{ .distance = FP.fromRaw(0), .normal = FPVector2.ZERO };
+
+    var t_enter = FP.fromRaw(0);
+    var t_exit = FP.MAX_VALUE;
+    var normal = FPVector2.ZERO;
+
+    const axes = [_]struct { origin: FP, delta: FP, min: FP, max: FP, unit: FPVector2 }{
+        .{ .origin = origin.x, .delta = direction.x, .min = box.min.x, .max = box.max.x, .unit = FPVector2.RIGHT },
+        .{ .origin = origin.y, .delta = direction.y, .min = box.min.y, .max = box.max.y, .unit = FPVector2.UP },
+    };
+    for (axes) |axis| {
+        const previous_enter = t_enter;
+        if (!collision.clipAxis(axis.origin, axis.delta, axis.min, axis.max, &t_enter, &t_exit)) return null;
+        // This axis moved the entry point - the ray came in through one of its faces
+        if (t_enter.gt(previous_enter) or (normal.eq(FPVector2.ZERO) and axis.delta.raw_value != 0)) {
+            normal = if (axis.delta.raw_value > 0) axis.unit.negate() else axis.unit;
+        }
+    }
+    return .{ .distance = t_enter, .normal = normal };
+}
+
+/// Ray (normalized direction) against a circle
+pub fn rayCircle(origin: FPVector2, direction: FPVector2, center: FPVector2, radius: FP) ?Contact {
+    const m = origin.sub(center);
+    const c = m.sqrMagnitude().sub(radius.mul(radius));
+    if (c.raw_value < 0) return .{ .distance = FP.fromRaw(0), .normal = FPVector2.ZERO };
+
+    const b = m.dot(direction);
+    // Outside and pointing away
+    if (b.raw_value > 0) return null;
+    const discriminant = b.mul(b).sub(c);
+    if (discriminant.raw_value < 0) return null;
+
+    const t = FP.max(FP.fromRaw(0), b.negate().sub(discriminant.sqrt()));
+    const point = origin.add(direction.mul(t));
+    return .{ .distance = t, .normal = point.sub(center).normalize() };
+}
+
+/// Ray (normalized direction) against a capsule (segment p-q swept by `radius`)
+pub fn rayCapsule(origin: FPVector2, direction: FPVector2, p: FPVector2, q: FPVector2, radius: FP) ?Contact {
+    if (collision.pointSegmentDistanceSquared(origin, p, q).lt(radius.mul(radius))) {
+        return .{ .distance = FP.fromRaw(0), .normal = FPVector2.ZERO };
+    }
+
+    var best = closer(rayCircle(origin, direction, p, radius), rayCircle(origin, direction, q, radius));
+
+    const axis = q.sub(p);
+    if (!axis.eq(FPVector2.ZERO)) {
+        const side = axis.rotateLeft().normalize();
+        for ([_]FPVector2{ side, side.negate() }) |normal| {
+            const offset = normal.mul(radius);
+            best = closer(best, raySegment(origin, direction, p.add(offset), q.add(offset), normal));
+        }
+    }
+    return best;
+}
+
+// Ray against the segment a-b whose outward normal is `normal` (only hit from the front)
+fn raySegment(origin: FPVector2, direction: FPVector2, a: FPVector2, b: FPVector2, normal: FPVector2) ?Contact {
+    if (direction.dot(normal).raw_value >= 0) return null;
+
+    const edge = b.sub(a);
+    const denominator = direction.cross(edge);
+    if (denominator.raw_value == 0) return null;
+
+    const to_start = a.sub(origin);
+    const t = to_start.cross(edge).div(denominator);
+    const s = to_start.cross(direction).div(denominator);
+    if (t.raw_value < 0 or s.raw_value < 0 or s.gt(FP.fromInt(1))) return null;
+    return .{ .distance = t, .normal = normal };
+}
+
+// Box swept against a circle: the Minkowski sum is a box with rounded corners
+fn rayRoundedBox(origin: FPVector2, direction: FPVector2, center: FPVector2, half_extents: FPVector2, radius: FP) ?Contact {
+    var best = rayBox(origin, direction, AABB.fromCenter(center, half_extents.add(FPVector2.new(radius, FP.fromRaw(0)))));
+    best = closer(best, rayBox(origin, direction, AABB.fromCenter(center, half_extents.add(FPVector2.new(FP.fromRaw(0), radius)))));
+
+    const corners = [_]FPVector2{
+        half_extents,
+        half_extents.negate(),
+        FPVector2.new(half_extents.x, half_extents.y.negate()),
+        FPVector2.new(half_extents.x.negate(), half_extents.y),
+    };
+    for (corners) |corner| {
+        best = closer(best, rayCircle(origin, direction, center.add(corner), radius));
+    }
+    return best;
+}
+
+fn closer(a: ?Contact, b: ?Contact) ?Contact {
+    const left = a orelse return b;
+    const right = b orelse return a;
+    return if (right.distance.lt(left.distance)) right else left;
+}
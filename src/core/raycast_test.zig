@@ -0,0 +1,129 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const components = @import("components.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Collider = components.Collider;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Collider },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+fn spawn(frame: *TestECS.Frame, position: FPVector2, collider: Collider) !ecs.EntityID {
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = position });
+    try frame.addComponent(entity, collider);
+    return entity;
+}
+
+fn expectNear(expected: FP, actual: FP) !void {
+    // Fixed-point sqrt/normalize leave a few raw units of error
+    try testing.expect(@abs(expected.raw_value - actual.raw_value) < 64);
+}
+
+test "Raycast returns hits ordered by distance with normals" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    const frame = test_ecs.getFrame();
+    const far_box = try spawn(frame, fpVec2(10, 0), Collider.box(fpVec2(1, 1)));
+    const circle = try spawn(frame, fpVec2(5, 0), Collider.circle(fp(1)));
+    const wall = try spawn(frame, fpVec2(20, 0), Collider{ .shape = .{ .box = fpVec2(1, 5) }, .layer = 2 });
+    _ = try spawn(frame, fpVec2(5, 10), Collider.box(fpVec2(1, 1))); // Off the ray
+    try grid.sync(frame);
+
+    var hits = std.ArrayList(spatial.RaycastHit).init(testing.allocator);
+    defer hits.deinit();
+
+    try spatial.raycast(TestECS, frame, &grid, .{ .origin = fpVec2(0, 0), .direction = fpVec2(2, 0), .max_distance = fp(30) }, &hits);
+    try testing.expectEqual(@as(usize, 3), hits.items.len);
+
+    try testing.expectEqual(circle, hits.items[0].entity);
+    try expectNear(fp(4), hits.items[0].distance);
+    try expectNear(fp(-1), hits.items[0].normal.x);
+
+    try testing.expectEqual(far_box, hits.items[1].entity);
+    try testing.expectEqual(fp(9), hits.items[1].distance);
+    try testing.expect(hits.items[1].point.eq(fpVec2(9, 0)));
+    try testing.expect(hits.items[1].normal.eq(fpVec2(-1, 0)));
+
+    try testing.expectEqual(wall, hits.items[2].entity);
+
+    // Layer mask and max distance
+    hits.clearRetainingCapacity();
+    try spatial.raycast(TestECS, frame, &grid, .{ .origin = fpVec2(0, 0), .direction = fpVec2(1, 0), .max_distance = fp(30), .mask = 2 }, &hits);
+    try testing.expectEqual(@as(usize, 1), hits.items.len);
+    try testing.expectEqual(wall, hits.items[0].entity);
+
+    const first = try spatial.raycastFirst(TestECS, frame, &grid, .{ .origin = fpVec2(0, 0), .direction = fpVec2(1, 0), .max_distance = fp(3) }, &hits);
+    try testing.expect(first == null);
+}
+
+test "Raycast against capsules and from inside" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    const frame = test_ecs.getFrame();
+    const capsule = try spawn(frame, fpVec2(0, 5), Collider.capsule(fpVec2(3, 0), fp(1)));
+    try grid.sync(frame);
+
+    var hits = std.ArrayList(spatial.RaycastHit).init(testing.allocator);
+    defer hits.deinit();
+
+    // Straight up into the flat side of the capsule: hits y = 4 with a downward normal
+    try spatial.raycast(TestECS, frame, &grid, .{ .origin = fpVec2(1, 0), .direction = fpVec2(0, 1), .max_distance = fp(10) }, &hits);
+    try testing.expectEqual(capsule, hits.items[0].entity);
+    try expectNear(fp(4), hits.items[0].distance);
+    try expectNear(fp(-1), hits.items[0].normal.y);
+
+    // Starting inside reports distance 0
+    hits.clearRetainingCapacity();
+    try spatial.raycast(TestECS, frame, &grid, .{ .origin = fpVec2(0, 5), .direction = fpVec2(1, 0), .max_distance = fp(10) }, &hits);
+    try testing.expectEqual(@as(i64, 0), hits.items[0].distance.raw_value);
+}
+
+test "Boxcast sweeps extents against boxes and circles" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    const frame = test_ecs.getFrame();
+    const ground = try spawn(frame, fpVec2(0, -5), Collider.box(fpVec2(10, 1)));
+    const ball = try spawn(frame, fpVec2(3, 0), Collider.circle(fp(1)));
+    try grid.sync(frame);
+
+    var hits = std.ArrayList(spatial.RaycastHit).init(testing.allocator);
+    defer hits.deinit();
+
+    // Ground check: a 1x1 box falling from the origin lands with its center at y = -3.5
+    try spatial.boxcast(TestECS, frame, &grid, fpVec2(0.5, 0.5), .{ .origin = fpVec2(0, 0), .direction = fpVec2(0, -1), .max_distance = fp(10) }, &hits);
+    try testing.expectEqual(@as(usize, 1), hits.items.len);
+    try testing.expectEqual(ground, hits.items[0].entity);
+    try testing.expectEqual(fp(3.5), hits.items[0].distance);
+    try testing.expect(hits.items[0].normal.eq(fpVec2(0, 1)));
+
+    // Sweeping sideways: the ray itself passes y = 0.8 above the ball's center but the box clips it
+    hits.clearRetainingCapacity();
+    try spatial.boxcast(TestECS, frame, &grid, fpVec2(0.5, 0.5), .{ .origin = fpVec2(0, 0.8), .direction = fpVec2(1, 0), .max_distance = fp(10) }, &hits);
+    try testing.expectEqual(ball, hits.items[0].entity);
+}
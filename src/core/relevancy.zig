@@ -0,0 +1,253 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const netcode = @import("netcode.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const Transform = @import("components.zig").Transform;
+
+const EntityID = ecs.EntityID;
+
+/// Interest management for state replication: which entities each client hears about.
+///
+/// Every client has a `View` - an observer entity and a radius around its `Transform`, a custom
+/// predicate, or both - and `update` turns it into an `EntitySet` of relevant entities, plus the
+/// entities that entered and left the set since the previous update. `encodeUpdate` writes only
+/// what the client has not acknowledged yet: the ids that stopped being relevant and the
+/// relevant entities that are new to it or changed since its last `acknowledge`, so a lost
+/// packet is covered by the next one. `applyUpdate` mirrors an update into the client's world.
+///
+/// Usage:
+///   var interest = Relevancy(GameECS).init(allocator);
+///   defer interest.deinit();
+///   try interest.addClient(1, .{ .observer = hero, .radius = fp(30) });
+///   // every tick, after the spatial index is synced
+///   interest.update(frame, &index);
+///   try interest.encodeUpdate(1, frame, packet.writer());
+///   // when the client reports the frame number it applied
+///   interest.acknowledge(1, frame_number);
+pub fn Relevancy(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+    const Registry = schema.Registry(EcsType);
+
+    return struct {
+        const Self = @This();
+
+        pub const EntitySet = EcsType.EntityBitSet;
+
+        /// Custom relevance test, called for every live entity on each update
+        pub const Predicate = struct {
+            context: ?*const anyopaque = null,
+            func: *const fn (context: ?*const anyopaque, frame: *EcsType.Frame, client: u8, entity: EntityID) bool,
+        };
+
+        /// What makes an entity relevant to a client: being the observer, within `radius` of it,
+        /// accepted by the predicate, or in the client's `always` set
+        pub const View = struct {
+            observer: ?EntityID = null,
+            radius: FP = FP.fromInt(20),
+            predicate: ?Predicate = null,
+        };
+
+        pub const Client = struct {
+            view: View,
+            /// Relevant whatever the view says - teammates, the match state entity
+            always: EntitySet = EntitySet.initEmpty(),
+            relevant: EntitySet = EntitySet.initEmpty(),
+            /// Changes of `relevant` at the last update
+            entered: EntitySet = EntitySet.initEmpty(),
+            left: EntitySet = EntitySet.initEmpty(),
+            /// Left and not acknowledged yet
+            departed: EntitySet = EntitySet.initEmpty(),
+            /// Last frame number the client confirmed, null before the first
+            acked: ?u64 = null,
+            /// Per entity: frame it last became relevant, frame it last left, component mask at the
+            /// last update and the frame that mask last changed
+            joined_at: []u64,
+            left_at: []u64,
+            masks: []u64,
+            mask_changed_at: []u64,
+
+            fn unacknowledged(self: *const Client, frame_number: u64) bool {
+                return if (self.acked) |acked| frame_number > acked else true;
+            }
+        };
+
+        allocator: std.mem.Allocator,
+        clients: [netcode.max_players]?*Client = [_]?*Client{null} ** netcode.max_players,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return .{ .allocator = allocator };
+        }
+
+        pub fn deinit(self: *Self) void {
+            for (0..netcode.max_players) |client| self.removeClient(@intCast(client));
+        }
+
+        /// Start tracking a client; it begins with nothing relevant and nothing acknowledged
+        pub fn addClient(self: *Self, client: u8, view: View) !void {
+            std.debug.assert(self.clients[client] == null);
+            const created = try self.allocator.create(Client);
+            errdefer self.allocator.destroy(created);
+            const per_entity = try self.allocator.alloc(u64, 4 * EcsType.max_entities);
+            @memset(per_entity, 0);
+            created.* = .{
+                .view = view,
+                .joined_at = per_entity[0..EcsType.max_entities],
+                .left_at = per_entity[EcsType.max_entities..][0..EcsType.max_entities],
+                .masks = per_entity[2 * EcsType.max_entities ..][0..EcsType.max_entities],
+                .mask_changed_at = per_entity[3 * EcsType.max_entities ..][0..EcsType.max_entities],
+            };
+            self.clients[client] = created;
+        }
+
+        pub fn removeClient(self: *Self, client: u8) void {
+            const removed = self.clients[client] orelse return;
+            self.allocator.free(removed.joined_at.ptr[0 .. 4 * EcsType.max_entities]);
+            self.allocator.destroy(removed);
+            self.clients[client] = null;
+        }
+
+        pub fn getClient(self: *Self, client: u8) ?*Client {
+            return self.clients[client];
+        }
+
+        /// Recompute every client's relevant set. `index` is a synced `spatial` index (Grid,
+        /// Quadtree or SpatialIndex) of the same frame.
+        pub fn update(self: *Self, frame: *EcsType.Frame, index: anytype) void {
+            const frame_number = frame.frame_number;
+            for (self.clients, 0..) |maybe_client, client_index| {
+                const client = maybe_client orelse continue;
+
+                var current = EntitySet.initEmpty();
+                if (client.view.observer) |observer| {
+                    if (frame.getComponent(observer, Transform)) |transform| {
+                        index.queryRadius(transform.position, client.view.radius, &current);
+                    }
+                    current.set(observer);
+                }
+                if (client.view.predicate) |predicate| {
+                    var live = frame.state.active_entities.fastIterator();
+                    while (live.next()) |entity| {
+                        if (predicate.func(predicate.context, frame, @intCast(client_index), entity)) current.set(entity);
+                    }
+                }
+                current.unionInto(&client.always, &current);
+                current.intersectInto(&frame.state.active_entities, &current);
+
+                current.subtractInto(&client.relevant, &client.entered);
+                client.relevant.subtractInto(&current, &client.left);
+                client.relevant.copyFrom(&current);
+
+                var entered = client.entered.fastIterator();
+                while (entered.next()) |entity| {
+                    client.joined_at[entity] = frame_number;
+                    client.departed.unset(entity);
+                }
+                var left = client.left.fastIterator();
+                while (left.next()) |entity| {
+                    client.left_at[entity] = frame_number;
+                    client.masks[entity] = 0;
+                    client.departed.set(entity);
+                }
+                // Removals leave no change tick behind, so they are caught by the mask
+                var relevant = client.relevant.fastIterator();
+                while (relevant.next()) |entity| {
+                    const mask = componentMask(frame, entity);
+                    if (mask != client.masks[entity]) {
+                        client.masks[entity] = mask;
+                        client.mask_changed_at[entity] = frame_number;
+                    }
+                }
+            }
+        }
+
+        /// The client confirmed applying the update of `frame_number`; later updates leave out
+        /// what it already has
+        pub fn acknowledge(self: *Self, client: u8, frame_number: u64) void {
+            const target = self.clients[client] orelse return;
+            if (target.acked) |previous| {
+                if (frame_number <= previous) return;
+            }
+            target.acked = frame_number;
+            var settled = EntitySet.initEmpty();
+            var departed = target.departed.fastIterator();
+            while (departed.next()) |entity| {
+                if (target.left_at[entity] <= frame_number) settled.set(entity);
+            }
+            target.departed.subtractInto(&settled, &target.departed);
+        }
+
+        /// Everything the client has not acknowledged, as of the last `update`:
+        ///
+        ///   u64 frame_number
+        ///   u32 count, u32 id per entity that stopped being relevant
+        ///   u32 count, then [u32 id][encodeEntity record] per relevant entity that is new to the
+        ///   client or changed, in ascending id order
+        pub fn encodeUpdate(self: *Self, client: u8, frame: *EcsType.Frame, writer: anytype) !void {
+            const target = self.clients[client] orelse return error.UnknownClient;
+            try writer.writeInt(u64, frame.frame_number, .little);
+
+            var gone = EntitySet.initEmpty();
+            var departed = target.departed.fastIterator();
+            while (departed.next()) |entity| {
+                if (target.unacknowledged(target.left_at[entity])) gone.set(entity);
+            }
+            try writer.writeInt(u32, gone.count(), .little);
+            var gone_entities = gone.fastIterator();
+            while (gone_entities.next()) |entity| try writer.writeInt(u32, entity, .little);
+
+            var outgoing = EntitySet.initEmpty();
+            var relevant = target.relevant.fastIterator();
+            while (relevant.next()) |entity| {
+                if (target.unacknowledged(target.joined_at[entity]) or
+                    target.unacknowledged(target.mask_changed_at[entity]) or
+                    changedAfter(frame, entity, target.acked))
+                {
+                    outgoing.set(entity);
+                }
+            }
+            try writer.writeInt(u32, outgoing.count(), .little);
+            var outgoing_entities = outgoing.fastIterator();
+            while (outgoing_entities.next()) |entity| {
+                try writer.writeInt(u32, entity, .little);
+                try Registry.encodeEntity(frame, entity, writer);
+            }
+        }
+
+        /// Apply an `encodeUpdate` to the client's world: departed entities are destroyed and
+        /// records create or overwrite theirs under the same ids. Returns the update's frame
+        /// number to acknowledge; updates older than one already applied should be dropped.
+        pub fn applyUpdate(frame: *EcsType.Frame, reader: anytype) !u64 {
+            const frame_number = try reader.readInt(u64, .little);
+            const departed = try reader.readInt(u32, .little);
+            for (0..departed) |_| frame.destroyEntity(try reader.readInt(u32, .little));
+
+            const records = try reader.readInt(u32, .little);
+            for (0..records) |_| {
+                const entity = try reader.readInt(u32, .little);
+                if (entity >= EcsType.max_entities) return error.InvalidData;
+                if (!frame.state.active_entities.isSet(entity)) frame.state.restoreEntity(entity) catch return error.InvalidData;
+                try Registry.decodeEntity(frame, entity, reader);
+            }
+            return frame_number;
+        }
+
+        fn componentMask(frame: *EcsType.Frame, entity: EntityID) u64 {
+            var mask: u64 = 0;
+            inline for (Types, 0..) |T, i| {
+                if (frame.hasComponent(entity, T)) mask |= @as(u64, 1) << i;
+            }
+            return mask;
+        }
+
+        fn changedAfter(frame: *EcsType.Frame, entity: EntityID, acked: ?u64) bool {
+            const since = acked orelse return true;
+            inline for (Types) |T| {
+                if (frame.getComponentStorage(T).changedAt(entity)) |changed| {
+                    if (changed > since) return true;
+                }
+            }
+            return false;
+        }
+    };
+}
@@ -0,0 +1,145 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const relevancy = @import("relevancy.zig");
+const components = @import("components.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Health = struct { value: i32 = 100 };
+const Beacon = struct {};
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Health, Beacon },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const Interest = relevancy.Relevancy(TestECS);
+
+fn step(world: *TestECS) void {
+    world.update(.{}, 1.0 / 60.0, world.getFrame().time + 1.0 / 60.0);
+}
+
+fn spawn(frame: *TestECS.Frame, x: i32) !ecs.EntityID {
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = fpVec2(x, 0) });
+    try frame.addComponent(entity, Health{});
+    return entity;
+}
+
+// Departed and record counts of an encoded update
+fn counts(bytes: []const u8) [2]u32 {
+    const departed = std.mem.readInt(u32, bytes[8..12], .little);
+    const records_at = 12 + 4 * departed;
+    return .{ departed, std.mem.readInt(u32, bytes[records_at..][0..4], .little) };
+}
+
+fn hasBeacon(_: ?*const anyopaque, frame: *TestECS.Frame, _: u8, entity: ecs.EntityID) bool {
+    return frame.hasComponent(entity, Beacon);
+}
+
+test "Clients receive nearby entities and then only what changed" {
+    var server = try TestECS.init(testing.allocator);
+    defer server.deinit();
+    var client = try TestECS.init(testing.allocator);
+    defer client.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(8));
+    defer grid.deinit();
+    var interest = Interest.init(testing.allocator);
+    defer interest.deinit();
+
+    step(&server);
+    const frame = server.getFrame();
+    const hero = try spawn(frame, 0);
+    const near = try spawn(frame, 10);
+    const wanderer = try spawn(frame, 20);
+    const far = try spawn(frame, 30);
+    try interest.addClient(0, .{ .observer = hero, .radius = fp(25) });
+
+    var packet = std.ArrayList(u8).init(testing.allocator);
+    defer packet.deinit();
+    const Sync = struct {
+        fn run(world: *TestECS, index: *spatial.Grid(TestECS), filter: *Interest, out: *std.ArrayList(u8), receiver: *TestECS) !u64 {
+            try index.sync(world.getFrame());
+            filter.update(world.getFrame(), index);
+            out.clearRetainingCapacity();
+            try filter.encodeUpdate(0, world.getFrame(), out.writer());
+            var stream = std.io.fixedBufferStream(out.items);
+            return Interest.applyUpdate(receiver.getFrame(), stream.reader());
+        }
+    };
+
+    const first = try Sync.run(&server, &grid, &interest, &packet, &client);
+    const view = interest.getClient(0).?;
+    try testing.expectEqual(@as(u32, 3), view.relevant.count());
+    try testing.expectEqual(@as(u32, 3), view.entered.count());
+    try testing.expectEqual([2]u32{ 0, 3 }, counts(packet.items));
+    try testing.expectEqual(@as(u32, 3), client.getFrame().state.entity_count);
+    try testing.expect(client.getFrame().getComponent(wanderer, Health) != null);
+    try testing.expect(!client.getFrame().state.active_entities.isSet(far));
+
+    // Until the client acknowledges, every update repeats what it may have missed
+    step(&server);
+    _ = try Sync.run(&server, &grid, &interest, &packet, &client);
+    try testing.expectEqual([2]u32{ 0, 3 }, counts(packet.items));
+    interest.acknowledge(0, first);
+    _ = try Sync.run(&server, &grid, &interest, &packet, &client);
+    try testing.expectEqual([2]u32{ 0, 0 }, counts(packet.items));
+
+    // One entity wanders off, another takes damage
+    step(&server);
+    frame.getComponentMut(wanderer, Transform).?.position = fpVec2(40, 0);
+    frame.getComponentMut(near, Health).?.value = 60;
+    const moved = try Sync.run(&server, &grid, &interest, &packet, &client);
+    try testing.expectEqual([2]u32{ 1, 1 }, counts(packet.items));
+    try testing.expect(!client.getFrame().state.active_entities.isSet(wanderer));
+    try testing.expectEqual(@as(i32, 60), client.getFrame().getComponent(near, Health).?.value);
+    interest.acknowledge(0, moved);
+
+    // Removing a component leaves no change tick, the mask still gives it away
+    step(&server);
+    _ = frame.removeComponent(hero, Health);
+    const removed = try Sync.run(&server, &grid, &interest, &packet, &client);
+    try testing.expectEqual([2]u32{ 0, 1 }, counts(packet.items));
+    try testing.expect(!client.getFrame().hasComponent(hero, Health));
+    interest.acknowledge(0, removed);
+
+    // Destroyed entities leave too
+    step(&server);
+    frame.destroyEntity(near);
+    _ = try Sync.run(&server, &grid, &interest, &packet, &client);
+    try testing.expectEqual([2]u32{ 1, 0 }, counts(packet.items));
+    try testing.expectEqual(@as(u32, 1), client.getFrame().state.entity_count);
+}
+
+test "Predicates and always-relevant entities pick entities anywhere" {
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(8));
+    defer grid.deinit();
+    var interest = Interest.init(testing.allocator);
+    defer interest.deinit();
+
+    const frame = world.getFrame();
+    const hero = try spawn(frame, 0);
+    const lighthouse = try spawn(frame, 500);
+    _ = try spawn(frame, -500);
+    try frame.addComponent(lighthouse, Beacon{});
+
+    try interest.addClient(3, .{ .predicate = .{ .func = hasBeacon } });
+    interest.getClient(3).?.always.set(hero);
+    try grid.sync(frame);
+    interest.update(frame, &grid);
+
+    const relevant = &interest.getClient(3).?.relevant;
+    try testing.expectEqual(@as(u32, 2), relevant.count());
+    try testing.expect(relevant.isSet(hero) and relevant.isSet(lighthouse));
+    try testing.expectError(error.UnknownClient, interest.encodeUpdate(1, frame, std.io.null_writer));
+}
@@ -0,0 +1,219 @@
+const std = @import("std");
+const schema = @import("schema.zig");
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
+
+/// Leading bytes and format version of a saved `InputLog`
+pub const log_magic = "RWIL";
+pub const log_format_version: u16 = 1;
+
+/// Everything needed to play a session back: a snapshot of the world it started from and the input
+/// every tick after it was simulated with, together with the checksum the tick ended on.
+///
+/// Call `begin` before the first recorded tick and `record` once its systems ran; `Replay` feeds
+/// the log back through the same systems and stops at the first tick whose state came out
+/// different.
+///
+/// Usage:
+///   var log = InputLog(GameECS).init(allocator);
+///   defer log.deinit();
+///   try log.begin(world.getFrame());
+///   while (running) {
+///       world.update(input, dt, time);
+///       try schedule.run(world.getFrame());
+///       try log.record(world.getFrame());
+///   }
+///   try log.save(std.fs.cwd(), "match.rwil");
+pub fn InputLog(comptime EcsType: type) type {
+    const Registry = schema.Registry(EcsType);
+
+    return struct {
+        const Self = @This();
+
+        pub const Input = @FieldType(EcsType.Frame, "input");
+
+        /// One recorded tick
+        pub const Entry = struct {
+            input: Input,
+            delta_time: f32,
+            time: f64,
+            /// `Frame.checksum()` at the end of the tick
+            checksum: u64,
+        };
+
+        allocator: std.mem.Allocator,
+        /// `encodeFrame` snapshot the recording starts from
+        initial: std.ArrayListUnmanaged(u8) = .{},
+        /// Frame number of that snapshot; entry `i` is tick `start_frame + 1 + i`
+        start_frame: u64 = 0,
+        entries: std.ArrayListUnmanaged(Entry) = .{},
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return .{ .allocator = allocator };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.initial.deinit(self.allocator);
+            self.entries.deinit(self.allocator);
+        }
+
+        /// Start a new recording from the current state of `frame`, dropping any previous one
+        pub fn begin(self: *Self, frame: *EcsType.Frame) !void {
+            self.initial.clearRetainingCapacity();
+            self.entries.clearRetainingCapacity();
+            try Registry.encodeFrame(frame, self.initial.writer(self.allocator));
+            self.start_frame = frame.frame_number;
+        }
+
+        /// Append the tick `frame` just finished. Ticks must follow each other without gaps.
+        pub fn record(self: *Self, frame: *EcsType.Frame) !void {
+            if (frame.frame_number != self.start_frame + 1 + self.entries.items.len) return error.FrameOutOfOrder;
+            try self.entries.append(self.allocator, .{
+                .input = frame.input,
+                .delta_time = frame.deltaTime,
+                .time = frame.time,
+                .checksum = frame.checksum(),
+            });
+        }
+
+        /// Tick of the newest entry (`start_frame` while nothing was recorded)
+        pub fn lastFrame(self: *const Self) u64 {
+            return self.start_frame + self.entries.items.len;
+        }
+
+        /// Binary layout, little-endian:
+        ///
+        ///   "RWIL" u16 log_format_version  u64 schemaHash()
+        ///   u64 start_frame  u32 snapshot length, snapshot bytes
+        ///   u64 entry count, then per entry: input (encodeValue) f32 delta_time f64 time u64 checksum
+        pub fn writeTo(self: *const Self, writer: anytype) !void {
+            try writer.writeAll(log_magic);
+            try writer.writeInt(u16, log_format_version, .little);
+            try writer.writeInt(u64, Registry.schemaHash(), .little);
+
+            try writer.writeInt(u64, self.start_frame, .little);
+            try writer.writeInt(u32, @intCast(self.initial.items.len), .little);
+            try writer.writeAll(self.initial.items);
+
+            try writer.writeInt(u64, self.entries.items.len, .little);
+            for (self.entries.items) |entry| {
+                try schema.encodeValue(Input, entry.input, writer);
+                try schema.encodeValue(f32, entry.delta_time, writer);
+                try schema.encodeValue(f64, entry.time, writer);
+                try writer.writeInt(u64, entry.checksum, .little);
+            }
+        }
+
+        /// Read a log written with `writeTo`. Fails with error.InvalidInputLog on a foreign stream,
+        /// error.UnsupportedVersion on a newer format and error.SchemaMismatch when the components
+        /// changed since it was recorded.
+        pub fn readFrom(allocator: std.mem.Allocator, reader: anytype) !Self {
+            var magic: [log_magic.len]u8 = undefined;
+            try reader.readNoEof(&magic);
+            if (!std.mem.eql(u8, &magic, log_magic)) return error.InvalidInputLog;
+            if (try reader.readInt(u16, .little) != log_format_version) return error.UnsupportedVersion;
+            if (try reader.readInt(u64, .little) != Registry.schemaHash()) return error.SchemaMismatch;
+
+            var log = Self.init(allocator);
+            errdefer log.deinit();
+
+            log.start_frame = try reader.readInt(u64, .little);
+            const snapshot_len = try reader.readInt(u32, .little);
+            try log.initial.resize(allocator, snapshot_len);
+            try reader.readNoEof(log.initial.items);
+
+            const entry_count = try reader.readInt(u64, .little);
+            for (0..entry_count) |_| {
+                try log.entries.append(allocator, .{
+                    .input = try schema.decodeValue(Input, reader),
+                    .delta_time = try schema.decodeValue(f32, reader),
+                    .time = try schema.decodeValue(f64, reader),
+                    .checksum = try reader.readInt(u64, .little),
+                });
+            }
+            return log;
+        }
+
+        /// Write the log to a file relative to `dir`, replacing it
+        pub fn save(self: *const Self, dir: std.fs.Dir, path: []const u8) !void {
+            const file = try dir.createFile(path, .{});
+            defer file.close();
+            var buffered = std.io.bufferedWriter(file.writer());
+            try self.writeTo(buffered.writer());
+            try buffered.flush();
+        }
+
+        /// Read a log file relative to `dir`
+        pub fn load(allocator: std.mem.Allocator, dir: std.fs.Dir, path: []const u8) !Self {
+            const file = try dir.openFile(path, .{});
+            defer file.close();
+            var buffered = std.io.bufferedReader(file.reader());
+            return readFrom(allocator, buffered.reader());
+        }
+    };
+}
+
+/// Plays an `InputLog` back into a world and checks every tick against the recorded checksum - a
+/// desync test for the simulation. Any difference means a system read something outside the frame
+/// and its input (wall-clock time, unseeded randomness, iteration order of a hash map) or the
+/// systems changed since the recording.
+///
+/// Usage:
+///   var replay = try Replay(GameECS).init(&world, &log);
+///   replay.logger = Logger.std_log;
+///   try replay.run(&schedule); // error.ReplayDesync names the tick in `replay.desync_frame`
+pub fn Replay(comptime EcsType: type) type {
+    const Log = InputLog(EcsType);
+
+    return struct {
+        const Self = @This();
+
+        world: *EcsType,
+        log: *const Log,
+        /// Index of the next entry to play
+        cursor: usize = 0,
+        /// First tick whose checksum differed from the recording
+        desync_frame: ?u64 = null,
+        /// Receives the desync report
+        logger: Logger = Logger.noop,
+
+        /// Load the recording's initial snapshot into `world`
+        pub fn init(world: *EcsType, log: *const Log) !Self {
+            var stream = std.io.fixedBufferStream(log.initial.items);
+            try schema.Registry(EcsType).decodeFrame(world.getFrame(), stream.reader());
+            return .{ .world = world, .log = log };
+        }
+
+        /// Simulate the next recorded tick through `systems` (anything with `run(*Frame) !void`,
+        /// like a `Schedule`). Returns false once the log is played out; fails with
+        /// error.ReplayDesync when the tick ends on a different checksum than recorded.
+        pub fn step(self: *Self, systems: anytype) !bool {
+            if (self.desync_frame != null) return error.ReplayDesync;
+            if (self.cursor == self.log.entries.items.len) return false;
+
+            const entry = self.log.entries.items[self.cursor];
+            self.world.update(entry.input, entry.delta_time, entry.time);
+            const frame = self.world.getFrame();
+            try systems.run(frame);
+            self.cursor += 1;
+
+            const actual = frame.checksum();
+            if (actual != entry.checksum) {
+                self.desync_frame = frame.frame_number;
+                self.logger.err("replay_desync", &.{
+                    field("frame", frame.frame_number),
+                    field("expected", entry.checksum),
+                    field("actual", actual),
+                });
+                return error.ReplayDesync;
+            }
+            return true;
+        }
+
+        /// Play every remaining tick
+        pub fn run(self: *Self, systems: anytype) !void {
+            while (try self.step(systems)) {}
+        }
+    };
+}
@@ -0,0 +1,103 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const replay = @import("replay.zig");
+
+const Position = struct { x: i32 };
+const Velocity = struct { x: i32 };
+
+const TestInput = struct {
+    push: i32 = 0,
+    spawn: bool = false,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+const Log = replay.InputLog(TestECS);
+const Replay = replay.Replay(TestECS);
+
+// Spawns on request and moves every entity by its velocity plus the tick's push (times `scale`)
+const Movement = struct {
+    scale: i32 = 1,
+
+    fn run(self: *const Movement, frame: *TestECS.Frame) !void {
+        if (frame.input.spawn) {
+            const entity = try frame.createEntity();
+            try frame.addComponent(entity, Position{ .x = 0 });
+            try frame.addComponent(entity, Velocity{ .x = 2 });
+        }
+        var query = try frame.query(&.{ Position, Velocity });
+        while (query.next()) |result| {
+            result.get(Position).x += (result.get(Velocity).x + frame.input.push) * self.scale;
+        }
+    }
+};
+
+// Ten ticks from a world with one moving entity, spawning a second on tick 4
+fn recordSession(world: *TestECS, log: *Log) !void {
+    const frame = world.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Position{ .x = 5 });
+    try frame.addComponent(entity, Velocity{ .x = 1 });
+
+    try log.begin(frame);
+    for (1..11) |tick| {
+        const input = TestInput{ .push = @intCast(tick % 3), .spawn = tick == 4 };
+        world.update(input, 1, @floatFromInt(tick));
+        try (Movement{}).run(world.getFrame());
+        try log.record(world.getFrame());
+    }
+}
+
+test "A saved input log replays to the recorded checksums" {
+    var recorded = try TestECS.init(testing.allocator);
+    defer recorded.deinit();
+    var log = Log.init(testing.allocator);
+    defer log.deinit();
+    try recordSession(&recorded, &log);
+    try testing.expectEqual(@as(u64, 10), log.lastFrame());
+
+    var dir = testing.tmpDir(.{});
+    defer dir.cleanup();
+    try log.save(dir.dir, "session.rwil");
+    var loaded = try Log.load(testing.allocator, dir.dir, "session.rwil");
+    defer loaded.deinit();
+    try testing.expectEqual(log.entries.items.len, loaded.entries.items.len);
+    try testing.expectEqualSlices(u8, log.initial.items, loaded.initial.items);
+
+    // A fresh world ends exactly where the recording did
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var player = try Replay.init(&world, &loaded);
+    try player.run(&Movement{});
+    try testing.expectEqual(@as(?u64, null), player.desync_frame);
+    try testing.expectEqual(@as(u64, 10), world.getFrame().frame_number);
+    try testing.expectEqual(recorded.getFrame().checksum(), world.getFrame().checksum());
+    try testing.expect(!try player.step(&Movement{}));
+}
+
+test "Replay stops at the first tick that disagrees with the recording" {
+    var recorded = try TestECS.init(testing.allocator);
+    defer recorded.deinit();
+    var log = Log.init(testing.allocator);
+    defer log.deinit();
+    try recordSession(&recorded, &log);
+
+    // Systems that move twice as far diverge on the very first tick
+    var world = try TestECS.init(testing.allocator);
+    defer world.deinit();
+    var player = try Replay.init(&world, &log);
+    try testing.expectError(error.ReplayDesync, player.run(&Movement{ .scale = 2 }));
+    try testing.expectEqual(@as(?u64, 1), player.desync_frame);
+    try testing.expectError(error.ReplayDesync, player.step(&Movement{}));
+
+    // Recording must not skip ticks, and foreign streams are refused
+    world.update(.{}, 1, 0);
+    try testing.expectError(error.FrameOutOfOrder, log.record(world.getFrame()));
+    var stream = std.io.fixedBufferStream("RWSF\x01\x00");
+    try testing.expectError(error.InvalidInputLog, Log.readFrom(testing.allocator, stream.reader()));
+}
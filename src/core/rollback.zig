@@ -1,4 +1,7 @@
 const std = @import("std");
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
 
 /// High-performance rollback system optimized for netcode
 /// Uses single contiguous buffer with frame slots for maximum copy speed
@@ -17,6 +20,11 @@ pub fn NetcodeRollback(comptime EcsType: type, comptime window_size: u32, compti
         
         // Arena allocators for each frame slot (reused)
         arena_states: [WINDOW_SIZE]std.heap.FixedBufferAllocator,
+
+        /// Receives rollback depth and desync warnings
+        logger: Logger = Logger.noop,
+        /// Confirmed ticks whose remote hash differed from ours
+        desyncs: u32 = 0,
         
         pub fn init() Self {
             var self = Self{
@@ -67,6 +75,7 @@ pub fn NetcodeRollback(comptime EcsType: type, comptime window_size: u32, compti
         pub fn restoreToFrame(self: *const Self, ecs: *EcsType, frames_back: u32) !void {
             _ = ecs;
             if (frames_back >= self.frames_stored) {
+                self.warnDepth(frames_back);
                 return error.FrameNotAvailable;
             }
             
@@ -88,6 +97,7 @@ pub fn NetcodeRollback(comptime EcsType: type, comptime window_size: u32, compti
         /// Copy frame data from one slot to another (ultra fast single memcpy)
         pub fn copyFrame(self: *Self, from_frames_back: u32, to_frames_back: u32) !void {
             if (from_frames_back >= self.frames_stored or to_frames_back >= self.frames_stored) {
+                self.warnDepth(@max(from_frames_back, to_frames_back));
                 return error.FrameNotAvailable;
             }
             
@@ -107,6 +117,27 @@ pub fn NetcodeRollback(comptime EcsType: type, comptime window_size: u32, compti
             self.frame_sizes[to_index] = frame_size;
         }
         
+        /// Compare our hash of a tick (`frame.checksum()`) with the one a peer confirmed for it. A mismatch means the
+        /// simulations diverged: it is counted, logged as `desync_suspected` and returns false.
+        pub fn confirmHash(self: *Self, tick: u64, local_hash: u64, remote_hash: u64) bool {
+            if (local_hash == remote_hash) return true;
+            self.desyncs += 1;
+            self.logger.warn("desync_suspected", &.{
+                field("tick", tick),
+                field("local_hash", local_hash),
+                field("remote_hash", remote_hash),
+            });
+            return false;
+        }
+
+        fn warnDepth(self: *const Self, frames_back: u32) void {
+            self.logger.warn("rollback_depth_exceeded", &.{
+                field("frames_back", frames_back),
+                field("frames_stored", self.frames_stored),
+                field("window", WINDOW_SIZE),
+            });
+        }
+        
         /// Get memory usage statistics
         pub fn getStats(self: *const Self) struct { 
             total_memory: u32, 
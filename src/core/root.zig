@@ -0,0 +1,97 @@
+//! Rewind core - deterministic ECS, rollback and debugging tools.
+//! Root of the `rewind-core` module used by tools and games outside src/core.
+
+pub const ecs = @import("ecs.zig");
+pub const ECS = ecs.ECS;
+/// `World(Components, options)` - the ECS type a game instantiates with its component set. The type
+/// is the component registry; every `init` is an independent simulation (one per hosted match, or
+/// a predicted and a confirmed world on a client) sharing nothing but that registration.
+pub const World = ecs.ECS;
+pub const EntityID = ecs.EntityID;
+pub const Entity = ecs.Entity;
+pub const EntityHandle = ecs.EntityHandle;
+pub const Relation = ecs.Relation;
+pub const EntityLimit = ecs.EntityLimit;
+pub const INVALID_ENTITY = ecs.INVALID_ENTITY;
+
+pub const NetcodeRollback = @import("rollback.zig").NetcodeRollback;
+pub const netcode = @import("netcode.zig");
+pub const NetcodeSession = netcode.Session;
+pub const LockstepSession = netcode.LockstepSession;
+pub const websocket = @import("websocket.zig");
+pub const WebSocketTransport = websocket.WebSocketTransport;
+pub const Schedule = @import("schedule.zig").Schedule;
+pub const WorkerLocal = @import("schedule.zig").WorkerLocal;
+pub const SystemStats = @import("schedule.zig").SystemStats;
+pub const StrictAllocator = @import("strict_allocator.zig").StrictAllocator;
+pub const Pipeline = @import("pipeline.zig").Pipeline;
+pub const FrameHistory = @import("frame_history.zig").FrameHistory;
+pub const diff = @import("diff.zig");
+pub const StateDiff = diff.StateDiff;
+pub const CommandInbox = @import("command_inbox.zig").CommandInbox;
+pub const CommandBuffer = @import("command_buffer.zig").CommandBuffer;
+pub const Events = @import("events.zig").Events;
+pub const Rand = @import("random.zig").Rand;
+pub const replay = @import("replay.zig");
+pub const InputLog = replay.InputLog;
+pub const Replay = replay.Replay;
+pub const HotPack = @import("packing.zig").HotPack;
+pub const memory_budget = @import("memory_budget.zig");
+pub const MemoryBudget = memory_budget.MemoryBudget;
+pub const Logger = @import("logger.zig").Logger;
+pub const clock = @import("clock.zig");
+pub const Clock = clock.Clock;
+pub const ManualClock = clock.ManualClock;
+pub const ScaledClock = clock.ScaledClock;
+pub const TickPacer = clock.TickPacer;
+pub const config = @import("config.zig");
+
+pub const TraceLog = @import("trace.zig").TraceLog;
+pub const QueryAnalyzer = @import("query_analyzer.zig").QueryAnalyzer;
+pub const LeakDetector = @import("leak_detector.zig").LeakDetector;
+pub const metrics = @import("metrics.zig");
+pub const memory_report = @import("memory_report.zig");
+pub const MemoryReport = memory_report.MemoryReport;
+
+pub const components = @import("components.zig");
+pub const spatial = @import("spatial.zig");
+pub const collision = @import("collision.zig");
+pub const raycast = @import("raycast.zig");
+pub const physics = @import("physics.zig");
+pub const hierarchy = @import("hierarchy.zig");
+pub const TransformHierarchy = hierarchy.TransformHierarchy;
+pub const steering = @import("steering.zig");
+pub const pathfinding = @import("pathfinding.zig");
+pub const NavGrid = pathfinding.NavGrid;
+pub const Pathfinder = pathfinding.Pathfinder;
+pub const timers = @import("timers.zig");
+pub const Timers = timers.Timers;
+pub const animation = @import("animation.zig");
+pub const particles = @import("particles.zig");
+pub const lag_compensation = @import("lag_compensation.zig");
+pub const HitboxHistory = lag_compensation.HitboxHistory;
+pub const movement = @import("movement.zig");
+pub const schema = @import("schema.zig");
+pub const script = @import("script.zig");
+pub const ScriptSystem = script.ScriptSystem;
+pub const mirror = @import("mirror.zig");
+pub const tiled = @import("tiled.zig");
+pub const TiledImporter = tiled.TiledImporter;
+pub const arrow = @import("arrow.zig");
+pub const ArrowExport = arrow.ArrowExport;
+pub const flatbuffers = @import("flatbuffers.zig");
+pub const FlatBuffersCodec = flatbuffers.FlatBuffersCodec;
+pub const compression = @import("compression.zig");
+pub const Compressor = compression.Compressor;
+pub const CompressedTransport = compression.CompressedTransport;
+pub const relevancy = @import("relevancy.zig");
+pub const Relevancy = relevancy.Relevancy;
+pub const prediction = @import("prediction.zig");
+pub const Prediction = prediction.Prediction;
+pub const names = @import("names.zig");
+pub const Name = names.Name;
+
+pub const FP = @import("fixed-math/FP.zig").FP;
+pub const fp = @import("fixed-math/FP.zig").fp;
+pub const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+pub const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
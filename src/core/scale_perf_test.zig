@@ -0,0 +1,107 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+const Position = struct { x: i32, y: i32 };
+const Velocity = struct { x: i32, y: i32 };
+const Bullet = struct { ttl: u16 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const VastECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity, Bullet },
+    .input = TestInput,
+    .max_entities = .vast,
+});
+
+const MillionECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity, Bullet },
+    .input = TestInput,
+    .max_entities = .million,
+});
+
+const TICKS = 20;
+const CHUNK = 1024;
+
+pub fn main() !void {
+    const allocator = std.heap.page_allocator;
+
+    std.debug.print("=== Entity Scale Performance Test ===\n", .{});
+    std.debug.print("Every entity moves, one in 10 is a bullet; {} ticks each\n\n", .{TICKS});
+    std.debug.print("{s:>9} {s:>12} {s:>12} {s:>12} {s:>12} {s:>12}\n", .{ "entities", "create ms", "query us", "move ms", "bullets ms", "snapshot ms" });
+
+    try run(VastECS, allocator, 100_000);
+    try run(MillionECS, allocator, 1_000_000);
+}
+
+fn run(comptime World: type, allocator: std.mem.Allocator, entity_count: u32) !void {
+    // Worlds this size keep hundreds of KB of bitsets inline - keep them off the stack
+    const world = try allocator.create(World);
+    defer allocator.destroy(world);
+    world.* = try World.init(allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+
+    var timer = try std.time.Timer.start();
+    for (0..entity_count) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        try frame.addComponent(entity, Velocity{ .x = 1, .y = -1 });
+        if (i % 10 == 0) try frame.addComponent(entity, Bullet{ .ttl = 60 });
+    }
+    const create_ns = timer.read();
+
+    var chunk: [CHUNK]ecs.EntityID = undefined;
+    var query_ns: u64 = 0;
+    var move_ns: u64 = 0;
+    var bullet_ns: u64 = 0;
+    var sink: i64 = 0;
+    for (0..TICKS) |_| {
+        world.update(.{}, 0.016, 0.016);
+        const positions = frame.getComponentStorage(Position);
+        const velocities = frame.getComponentStorage(Velocity);
+
+        timer.reset();
+        var movers = try frame.query(&.{ Position, Velocity });
+        query_ns += timer.read();
+
+        timer.reset();
+        while (true) {
+            const entities = movers.nextChunk(&chunk);
+            if (entities.len == 0) break;
+            for (entities) |entity| {
+                const velocity = velocities.getDirect(entity);
+                const position = positions.getDirect(entity);
+                position.x += velocity.x;
+                position.y += velocity.y;
+            }
+        }
+        move_ns += timer.read();
+
+        // Sparse component - the summary level skips empty stretches
+        timer.reset();
+        var bullets = try frame.query(&.{ Position, Bullet });
+        while (bullets.nextFast()) |result| {
+            const bullet = result.get(Bullet);
+            bullet.ttl -|= 1;
+            sink += bullet.ttl;
+        }
+        bullet_ns += timer.read();
+    }
+    std.mem.doNotOptimizeAway(sink);
+
+    timer.reset();
+    var saved = try world.saveFrame(allocator);
+    const snapshot_ns = timer.read();
+    World.freeSavedFrame(&saved);
+
+    std.debug.print("{d:>9} {d:>12.2} {d:>12.2} {d:>12.3} {d:>12.3} {d:>12.2}\n", .{
+        entity_count,
+        @as(f64, @floatFromInt(create_ns)) / std.time.ns_per_ms,
+        @as(f64, @floatFromInt(query_ns / TICKS)) / std.time.ns_per_us,
+        @as(f64, @floatFromInt(move_ns / TICKS)) / std.time.ns_per_ms,
+        @as(f64, @floatFromInt(bullet_ns / TICKS)) / std.time.ns_per_ms,
+        @as(f64, @floatFromInt(snapshot_ns)) / std.time.ns_per_ms,
+    });
+}
@@ -0,0 +1,589 @@
+const std = @import("std");
+const TraceLog = @import("trace.zig").TraceLog;
+const Clock = @import("clock.zig").Clock;
+const logger_module = @import("logger.zig");
+const Logger = logger_module.Logger;
+const field = logger_module.field;
+
+const cache_line = std.atomic.cache_line;
+
+/// Most workers a chunked system is split across
+pub const max_workers = 64;
+
+/// Stage of the tick a system belongs to. Every system of a phase runs before any system of the
+/// next; `after`/`before` order systems within a phase.
+pub const Phase = enum(u8) {
+    /// Turning player and network input into components
+    input,
+    simulation,
+    /// Presentation, events, bookkeeping that reads the settled state
+    post_sim,
+};
+
+/// Dense slot range `[begin, end)` of one component array, handed to one worker
+pub const Chunk = struct {
+    worker: u32,
+    begin: u32,
+    end: u32,
+};
+
+/// Split `len` dense slots of `elem_size` bytes starting at address `base` into at most `workers`
+/// chunks of near-equal size. Every boundary between two chunks is a slot that starts a cache
+/// line, so no line of the array is written by two workers. When the base address never lines up
+/// with a line start (an element size the alignment cannot realign) boundaries fall back to plain
+/// slots. Returns the used prefix of `out`.
+pub fn partition(base: usize, elem_size: usize, len: u32, workers: u32, out: []Chunk) []Chunk {
+    const count: u32 = @intCast(@min(@max(workers, 1), out.len));
+
+    // Slots between consecutive line starts, and the first slot that starts a line
+    var step: u32 = if (elem_size == 0) 1 else @intCast(cache_line / std.math.gcd(elem_size, cache_line));
+    var first: u32 = 0;
+    while (first < step and (base + first * elem_size) % cache_line != 0) first += 1;
+    if (first == step) {
+        step = 1;
+        first = 0;
+    }
+
+    var used: usize = 0;
+    var begin: u32 = 0;
+    for (1..count + 1) |k| {
+        var end: u32 = len;
+        if (k < count) {
+            const ideal: u32 = @intCast(@as(u64, len) * k / count);
+            end = @min(len, if (ideal <= first) first else first + (ideal - first) / step * step);
+        }
+        if (end <= begin) continue;
+        out[used] = .{ .worker = @intCast(used), .begin = begin, .end = end };
+        used += 1;
+        begin = end;
+    }
+    return out[0..used];
+}
+
+/// Private accumulation slot per worker, each on its own cache line so workers adding into their
+/// slot never invalidate each other's. `merge` folds the slots in worker order - call it from the
+/// system's commit step so the result does not depend on which thread finished first.
+pub fn WorkerLocal(comptime T: type) type {
+    return struct {
+        const Self = @This();
+
+        const Slot = struct {
+            value: T align(cache_line),
+        };
+
+        /// Value every slot starts from and is reset to; must be the identity of the merge
+        initial: T,
+        slots: [max_workers]Slot,
+
+        pub fn init(initial: T) Self {
+            var self = Self{ .initial = initial, .slots = undefined };
+            for (&self.slots) |*slot| slot.value = initial;
+            return self;
+        }
+
+        pub fn get(self: *Self, worker: u32) *T {
+            return &self.slots[worker].value;
+        }
+
+        /// Fold every slot into `into` in worker order and reset the slots for the next run
+        pub fn merge(self: *Self, into: *T, comptime combine: fn (*T, T) void) void {
+            for (&self.slots) |*slot| {
+                combine(into, slot.value);
+                slot.value = self.initial;
+            }
+        }
+    };
+}
+
+/// What a profiled schedule measured for one system since it was added or `resetStats`
+pub const SystemStats = struct {
+    calls: u64 = 0,
+    /// Wall time of every call together, of the latest one and of the slowest one
+    total_ns: u64 = 0,
+    last_ns: u64 = 0,
+    max_ns: u64 = 0,
+    /// Live entities holding every component the system declared (reads and writes) when it
+    /// last ran - what a query over its access would visit
+    entities: u32 = 0,
+
+    pub fn averageNs(self: SystemStats) u64 {
+        if (self.calls == 0) return 0;
+        return self.total_ns / self.calls;
+    }
+};
+
+/// Ordered list of systems with declared component access.
+///
+/// Each system states its phase, which components it reads and writes and which systems it must
+/// run after or before. Systems run one at a time in phase order, then dependency order, then
+/// registration order - the same order on every machine and every run. The declared access is
+/// what lets `dot` show which systems conflict and which could run side by side.
+///
+/// A system can also be chunked: `each_chunk` runs once per worker over a cache-line aligned slice
+/// of the dense array of `chunks_over` (see `partition`), on `pool` when one is set and inline
+/// otherwise, and `run` then commits - typically merging `WorkerLocal` buffers. Chunk functions may
+/// only write the dense slots of their own chunk and their own worker's buffers; structural changes
+/// belong in the commit.
+///
+/// With `parallel` set and a `pool`, `run` goes parallel group by parallel group instead: the
+/// systems of a group (same phase, no ordering between them, no component one writes that another
+/// reads or writes) run concurrently on the pool, and the next group starts once all of them
+/// finished. Such systems must keep to their declared access and leave structural changes to a
+/// `CommandBuffer` flushed after the tick; the query analyzer and trace log aren't thread safe
+/// and keep a schedule serial.
+///
+/// With `profile` set every run of a system is timed into its `stats`, so a tick over budget
+/// points at the system responsible without attaching a profiler; `profile_log_interval` also
+/// logs one `system_profile` line per system every that many runs.
+pub fn Schedule(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const SystemFn = *const fn (frame: *EcsType.Frame) anyerror!void;
+        pub const ChunkFn = *const fn (frame: *EcsType.Frame, chunk: Chunk) anyerror!void;
+
+        /// Address, element size and length of the dense array a chunked system splits
+        pub const DenseSpan = struct {
+            base: usize,
+            elem_size: usize,
+            len: u32,
+        };
+        pub const SpanFn = *const fn (frame: *EcsType.Frame) DenseSpan;
+
+        /// Registered system with its access sets resolved to component masks
+        pub const System = struct {
+            name: []const u8,
+            run: SystemFn,
+            reads: u64,
+            writes: u64,
+            after: []const []const u8,
+            before: []const []const u8 = &.{},
+            phase: Phase = .simulation,
+            /// Registration index - the tie-break between unordered systems
+            sequence: u32 = 0,
+            each_chunk: ?ChunkFn = null,
+            span: ?SpanFn = null,
+            /// Disabled systems are skipped by `run`
+            enabled: bool = true,
+            /// Filled while the schedule's `profile` is set
+            stats: SystemStats = .{},
+        };
+
+        /// What `add` takes - component types are turned into masks at compile time
+        pub const SystemDesc = struct {
+            name: []const u8,
+            run: SystemFn,
+            reads: []const type = &.{},
+            writes: []const type = &.{},
+            phase: Phase = .simulation,
+            /// Systems that must finish before this one (must already be registered)
+            after: []const []const u8 = &.{},
+            /// Systems that must not start before this one finished (must already be registered)
+            before: []const []const u8 = &.{},
+            /// Component whose dense array `each_chunk` is split over
+            chunks_over: ?type = null,
+            /// Per-worker pass run before `run`; requires `chunks_over`
+            each_chunk: ?ChunkFn = null,
+        };
+
+        allocator: std.mem.Allocator,
+        /// Systems in execution order
+        systems: std.ArrayList(System),
+        registered: u32 = 0,
+        /// When set, structural changes are attributed to the running system
+        trace_log: ?*TraceLog = null,
+        /// Threads chunked systems run on; chunks run inline on the caller when null
+        pool: ?*std.Thread.Pool = null,
+        /// Chunks a chunked system is split into (at most `max_workers`)
+        workers: u32 = 1,
+        /// Run the systems of each parallel group concurrently on `pool`
+        parallel: bool = false,
+        /// `parallelGroups` of the current systems, and one error slot per system for parallel runs
+        groups: []u32 = &.{},
+        results: []?anyerror = &.{},
+        /// Receives failed systems and the entity limit warning
+        logger: Logger = Logger.noop,
+        /// Share of the entity limit in use that triggers `entity_limit_near`
+        entity_warn_percent: u8 = 90,
+        entity_limit_warned: bool = false,
+        /// Time every system run into its `stats`
+        profile: bool = false,
+        /// Times profiled systems (a `ManualClock` makes reports reproducible)
+        clock: Clock = Clock.real,
+        /// Runs between `system_profile` log lines while profiling; 0 never logs
+        profile_log_interval: u32 = 0,
+        /// Runs completed while profiling
+        profiled_runs: u64 = 0,
+
+        pub fn init(allocator: std.mem.Allocator) Self {
+            return Self{
+                .allocator = allocator,
+                .systems = std.ArrayList(System).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.systems.deinit();
+            self.allocator.free(self.groups);
+            self.allocator.free(self.results);
+        }
+
+        pub fn add(self: *Self, comptime desc: SystemDesc) !void {
+            if (self.indexOf(desc.name) != null) return error.DuplicateSystem;
+            for (desc.after ++ desc.before) |dependency| {
+                if (self.indexOf(dependency) == null) return error.UnknownSystem;
+            }
+
+            if ((desc.each_chunk == null) != (desc.chunks_over == null)) {
+                @compileError("System '" ++ desc.name ++ "' needs both chunks_over and each_chunk");
+            }
+
+            try self.systems.append(.{
+                .name = desc.name,
+                .run = desc.run,
+                .reads = comptime EcsType.componentMask(desc.reads),
+                .writes = comptime EcsType.componentMask(desc.writes),
+                .after = desc.after,
+                .before = desc.before,
+                .phase = desc.phase,
+                .sequence = self.registered,
+                .each_chunk = desc.each_chunk,
+                .span = comptime if (desc.chunks_over) |T| denseSpan(T) else null,
+            });
+            self.sort() catch |err| {
+                _ = self.systems.orderedRemove(self.indexOf(desc.name).?);
+                return err;
+            };
+            self.registered += 1;
+
+            const groups = try self.parallelGroups(self.allocator);
+            self.allocator.free(self.groups);
+            self.groups = groups;
+            self.results = try self.allocator.realloc(self.results, self.systems.items.len);
+        }
+
+        // Stable topological order: of the systems whose predecessors are all placed, the
+        // earliest registered goes next
+        fn sort(self: *Self) !void {
+            const systems = self.systems.items;
+            const sorted = try self.allocator.alloc(System, systems.len);
+            defer self.allocator.free(sorted);
+            const placed = try self.allocator.alloc(bool, systems.len);
+            defer self.allocator.free(placed);
+            @memset(placed, false);
+
+            for (sorted) |*slot| {
+                var pick: ?usize = null;
+                candidates: for (systems, 0..) |candidate, i| {
+                    if (placed[i]) continue;
+                    for (systems, 0..) |other, j| {
+                        if (!placed[j] and j != i and mustPrecede(other, candidate)) continue :candidates;
+                    }
+                    if (pick == null or candidate.sequence < systems[pick.?].sequence) pick = i;
+                }
+                const index = pick orelse return error.DependencyCycle;
+                placed[index] = true;
+                slot.* = systems[index];
+            }
+            @memcpy(systems, sorted);
+        }
+
+        fn mustPrecede(earlier: System, later: System) bool {
+            return @intFromEnum(earlier.phase) < @intFromEnum(later.phase) or isOrderedAfter(later, earlier);
+        }
+
+        /// Advance the world one tick and run every system on it
+        pub fn tick(self: *Self, world: *EcsType, input: @FieldType(EcsType.Frame, "input"), delta_time: f32) !void {
+            world.update(input, delta_time, world.getFrame().time + delta_time);
+            try self.run(world.getFrame());
+        }
+
+        /// Run every system once, in execution order
+        pub fn run(self: *Self, frame: *EcsType.Frame) !void {
+            if (self.parallel and self.trace_log == null and frame.state.query_analyzer == null) {
+                if (self.pool) |pool| return self.runParallel(pool, frame);
+            }
+
+            for (self.systems.items) |*system| {
+                if (!system.enabled) continue;
+                if (self.trace_log) |trace_log| try trace_log.beginSystem(system.name);
+                defer if (self.trace_log) |trace_log| trace_log.endSystem();
+
+                const start: u64 = if (self.profile) self.clock.now() else 0;
+                if (system.each_chunk) |each_chunk| {
+                    self.runChunks(frame, each_chunk, system.span.?(frame)) catch |err| return self.failed(system.*, err);
+                }
+                system.run(frame) catch |err| return self.failed(system.*, err);
+                if (self.profile) self.recordRun(system, frame, start);
+            }
+            self.checkEntityLimit(frame);
+            self.endProfiledRun();
+        }
+
+        fn runParallel(self: *Self, pool: *std.Thread.Pool, frame: *EcsType.Frame) !void {
+            const systems = self.systems.items;
+            var group_count: u32 = 0;
+            for (self.groups) |group| group_count = @max(group_count, group + 1);
+
+            for (0..group_count) |group| {
+                @memset(self.results, null);
+                var wait_group: std.Thread.WaitGroup = .{};
+                for (systems, self.groups, self.results) |*system, system_group, *result| {
+                    if (system_group != group or !system.enabled) continue;
+                    pool.spawnWg(&wait_group, runSystem, .{ self, system, frame, result });
+                }
+                pool.waitAndWork(&wait_group);
+
+                // First failing system in execution order, so the error is the same on every run
+                for (systems, self.results) |system, result| {
+                    if (result) |err| return self.failed(system, err);
+                }
+            }
+            self.checkEntityLimit(frame);
+            self.endProfiledRun();
+        }
+
+        // Each system of a group writes only its own stats, so profiling stays race free
+        fn runSystem(self: *Self, system: *System, frame: *EcsType.Frame, result: *?anyerror) void {
+            const start: u64 = if (self.profile) self.clock.now() else 0;
+            if (system.each_chunk) |each_chunk| {
+                self.runChunks(frame, each_chunk, system.span.?(frame)) catch |err| {
+                    result.* = err;
+                    return;
+                };
+            }
+            system.run(frame) catch |err| {
+                result.* = err;
+                return;
+            };
+            if (self.profile) self.recordRun(system, frame, start);
+        }
+
+        fn recordRun(self: *Self, system: *System, frame: *EcsType.Frame, start: u64) void {
+            const elapsed = self.clock.since(start);
+            const measured = &system.stats;
+            measured.calls += 1;
+            measured.total_ns += elapsed;
+            measured.last_ns = elapsed;
+            measured.max_ns = @max(measured.max_ns, elapsed);
+            measured.entities = declaredEntities(frame, system.reads | system.writes);
+        }
+
+        fn declaredEntities(frame: *EcsType.Frame, mask: u64) u32 {
+            var matched = frame.state.active_entities;
+            inline for (0..EcsType.component_types.len) |i| {
+                if (mask & (@as(u64, 1) << i) != 0) {
+                    matched.intersectInto(&frame.state.components[i].entity_bitset, &matched);
+                }
+            }
+            return matched.count();
+        }
+
+        fn endProfiledRun(self: *Self) void {
+            if (!self.profile) return;
+            self.profiled_runs += 1;
+            if (self.profile_log_interval == 0 or self.profiled_runs % self.profile_log_interval != 0) return;
+            for (self.systems.items) |system| {
+                if (system.stats.calls == 0) continue;
+                self.logger.info("system_profile", &.{
+                    field("system", system.name),
+                    field("calls", system.stats.calls),
+                    field("avg_ns", system.stats.averageNs()),
+                    field("max_ns", system.stats.max_ns),
+                    field("entities", system.stats.entities),
+                });
+            }
+        }
+
+        /// What profiling measured for the system called `name`
+        pub fn stats(self: *const Self, name: []const u8) ?SystemStats {
+            const index = self.indexOf(name) orelse return null;
+            return self.systems.items[index].stats;
+        }
+
+        /// Start every system's stats over, e.g. after a loading screen skewed them
+        pub fn resetStats(self: *Self) void {
+            for (self.systems.items) |*system| system.stats = .{};
+            self.profiled_runs = 0;
+        }
+
+        fn failed(self: *Self, system: System, err: anyerror) anyerror {
+            self.logger.err("system_failed", &.{ field("system", system.name), field("error", @errorName(err)) });
+            return err;
+        }
+
+        // Warn once each time the live entity count climbs past `entity_warn_percent` of the limit
+        fn checkEntityLimit(self: *Self, frame: *EcsType.Frame) void {
+            const count = frame.state.entity_count;
+            const near = @as(u64, count) * 100 >= @as(u64, EcsType.max_entities) * self.entity_warn_percent;
+            if (near and !self.entity_limit_warned) {
+                self.logger.warn("entity_limit_near", &.{ field("entities", count), field("limit", EcsType.max_entities) });
+            }
+            self.entity_limit_warned = near;
+        }
+
+        fn runChunks(self: *Self, frame: *EcsType.Frame, each_chunk: ChunkFn, span: DenseSpan) !void {
+            var buffer: [max_workers]Chunk = undefined;
+            const chunks = partition(span.base, span.elem_size, span.len, self.workers, &buffer);
+
+            const pool = self.pool orelse {
+                for (chunks) |chunk| try each_chunk(frame, chunk);
+                return;
+            };
+
+            var results = [_]?anyerror{null} ** max_workers;
+            var wait_group: std.Thread.WaitGroup = .{};
+            for (chunks) |chunk| {
+                pool.spawnWg(&wait_group, runChunk, .{ each_chunk, frame, chunk, &results[chunk.worker] });
+            }
+            pool.waitAndWork(&wait_group);
+
+            // First failing worker in worker order, so the error is the same on every run
+            for (results[0..chunks.len]) |result| {
+                if (result) |err| return err;
+            }
+        }
+
+        fn runChunk(each_chunk: ChunkFn, frame: *EcsType.Frame, chunk: Chunk, result: *?anyerror) void {
+            each_chunk(frame, chunk) catch |err| {
+                result.* = err;
+            };
+        }
+
+        fn denseSpan(comptime T: type) SpanFn {
+            return &struct {
+                fn span(frame: *EcsType.Frame) DenseSpan {
+                    const dense = frame.getComponentStorage(T).getDenseArray();
+                    return .{ .base = @intFromPtr(dense.ptr), .elem_size = @sizeOf(T), .len = @intCast(dense.len) };
+                }
+            }.span;
+        }
+
+        /// Switch a system on or off without changing the registration order
+        pub fn setEnabled(self: *Self, name: []const u8, enabled: bool) !void {
+            const index = self.indexOf(name) orelse return error.UnknownSystem;
+            self.systems.items[index].enabled = enabled;
+        }
+
+        pub fn indexOf(self: *const Self, name: []const u8) ?usize {
+            for (self.systems.items, 0..) |system, i| {
+                if (std.mem.eql(u8, system.name, name)) return i;
+            }
+            return null;
+        }
+
+        /// Components two systems cannot share concurrently (write/write or read/write overlap)
+        pub fn conflicts(a: System, b: System) u64 {
+            return (a.writes & (b.reads | b.writes)) | (b.writes & a.reads);
+        }
+
+        /// True when `later` declared it runs after `earlier`, or `earlier` that it runs before `later`
+        pub fn isOrderedAfter(later: System, earlier: System) bool {
+            for (later.after) |dependency| {
+                if (std.mem.eql(u8, dependency, earlier.name)) return true;
+            }
+            for (earlier.before) |dependent| {
+                if (std.mem.eql(u8, dependent, later.name)) return true;
+            }
+            return false;
+        }
+
+        /// Parallel group of every system: systems sharing a group have no conflicts or ordering
+        /// constraints between them and share a phase. Caller owns the returned slice.
+        pub fn parallelGroups(self: *const Self, allocator: std.mem.Allocator) ![]u32 {
+            const systems = self.systems.items;
+            const groups = try allocator.alloc(u32, systems.len);
+            for (systems, 0..) |system, i| {
+                groups[i] = 0;
+                for (systems[0..i], 0..) |earlier, j| {
+                    if (conflicts(system, earlier) != 0 or mustPrecede(earlier, system)) {
+                        groups[i] = @max(groups[i], groups[j] + 1);
+                    }
+                }
+            }
+            return groups;
+        }
+
+        /// Graphviz description of the schedule.
+        ///
+        /// Nodes list each system's read and write sets. Solid edges are declared ordering,
+        /// dashed red edges are data conflicts (labelled with the contested components) that
+        /// force registration order. Systems inside one "parallel group" cluster could run
+        /// concurrently.
+        pub fn dot(self: *const Self, writer: anytype) !void {
+            const systems = self.systems.items;
+            const groups = try self.parallelGroups(self.allocator);
+            defer self.allocator.free(groups);
+
+            try writer.writeAll("digraph schedule {\n");
+            try writer.writeAll("  rankdir=LR;\n");
+            try writer.writeAll("  node [shape=record, fontname=\"Helvetica\"];\n");
+
+            var group_count: u32 = 0;
+            for (groups) |group| group_count = @max(group_count, group + 1);
+
+            for (0..group_count) |group| {
+                try writer.print("  subgraph cluster_group_{d} {{\n", .{group});
+                try writer.print("    label=\"parallel group {d}\";\n", .{group});
+                try writer.writeAll("    style=dotted;\n");
+                for (systems, 0..) |system, i| {
+                    if (groups[i] != group) continue;
+                    try writer.print("    s{d} [label=\"{{", .{i});
+                    try writeEscaped(writer, system.name);
+                    try writer.writeAll("|reads: ");
+                    try writeComponents(writer, system.reads);
+                    try writer.writeAll("|writes: ");
+                    try writeComponents(writer, system.writes);
+                    try writer.writeAll("}\"];\n");
+                }
+                try writer.writeAll("  }\n");
+            }
+
+            for (systems, 0..) |system, i| {
+                for (systems[0..i], 0..) |earlier, j| {
+                    if (isOrderedAfter(system, earlier)) {
+                        try writer.print("  s{d} -> s{d};\n", .{ j, i });
+                        continue;
+                    }
+                    const contested = conflicts(system, earlier);
+                    if (contested != 0) {
+                        try writer.print("  s{d} -> s{d} [style=dashed, color=red, label=\"", .{ j, i });
+                        try writeComponents(writer, contested);
+                        try writer.writeAll("\"];\n");
+                    }
+                }
+            }
+
+            try writer.writeAll("}\n");
+        }
+
+        fn writeComponents(writer: anytype, mask: u64) !void {
+            if (mask == 0) {
+                try writer.writeAll("-");
+                return;
+            }
+            var first = true;
+            var bits = mask;
+            while (bits != 0) {
+                const index = @ctz(bits);
+                bits &= bits - 1;
+                if (!first) try writer.writeAll(", ");
+                first = false;
+                try writeEscaped(writer, EcsType.component_names[index]);
+            }
+        }
+
+        // Characters with meaning inside a record label
+        fn writeEscaped(writer: anytype, text: []const u8) !void {
+            for (text) |c| {
+                switch (c) {
+                    '{', '}', '|', '<', '>', '"', '\\' => {
+                        try writer.writeByte('\\');
+                        try writer.writeByte(c);
+                    },
+                    else => try writer.writeByte(c),
+                }
+            }
+        }
+    };
+}
@@ -0,0 +1,304 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const schedule_module = @import("schedule.zig");
+const Schedule = schedule_module.Schedule;
+const ManualClock = @import("clock.zig").ManualClock;
+
+const Position = struct { x: f32, y: f32 };
+const Velocity = struct { x: f32, y: f32 };
+const Health = struct { value: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity, Health },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+const TestSchedule = Schedule(TestECS);
+
+var run_log: std.BoundedArray(u8, 8) = .{};
+
+fn movement(frame: *TestECS.Frame) !void {
+    _ = frame;
+    try run_log.append('m');
+}
+
+fn damage(frame: *TestECS.Frame) !void {
+    _ = frame;
+    try run_log.append('d');
+}
+
+fn render(frame: *TestECS.Frame) !void {
+    _ = frame;
+    try run_log.append('r');
+}
+
+test "Schedule runs systems in registration order" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var schedule = TestSchedule.init(testing.allocator);
+    defer schedule.deinit();
+
+    try schedule.add(.{ .name = "movement", .run = &movement, .reads = &.{Velocity}, .writes = &.{Position} });
+    try schedule.add(.{ .name = "damage", .run = &damage, .writes = &.{Health} });
+    try schedule.add(.{ .name = "render", .run = &render, .reads = &.{ Position, Health }, .after = &.{"damage"} });
+
+    run_log = .{};
+    try schedule.run(test_ecs.getFrame());
+    try testing.expectEqualStrings("mdr", run_log.slice());
+
+    try testing.expectError(error.UnknownSystem, schedule.add(.{ .name = "late", .run = &render, .after = &.{"missing"} }));
+    try testing.expectError(error.DuplicateSystem, schedule.add(.{ .name = "render", .run = &render }));
+}
+
+fn input(frame: *TestECS.Frame) !void {
+    _ = frame;
+    try run_log.append('i');
+}
+
+test "Schedule orders systems by phase and declared edges" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var schedule = TestSchedule.init(testing.allocator);
+    defer schedule.deinit();
+
+    try schedule.add(.{ .name = "render", .run = &render, .phase = .post_sim, .reads = &.{Position} });
+    try schedule.add(.{ .name = "damage", .run = &damage, .writes = &.{Health} });
+    try schedule.add(.{ .name = "movement", .run = &movement, .writes = &.{Position}, .before = &.{"damage"} });
+    try schedule.add(.{ .name = "input", .run = &input, .phase = .input, .writes = &.{Velocity} });
+
+    run_log = .{};
+    try schedule.tick(&test_ecs, .{}, 1.0 / 60.0);
+    try testing.expectEqualStrings("imdr", run_log.slice());
+    try testing.expectEqual(@as(u64, 1), test_ecs.getFrame().frame_number);
+
+    // Phases are barriers: nothing groups across them
+    const groups = try schedule.parallelGroups(testing.allocator);
+    defer testing.allocator.free(groups);
+    try testing.expectEqualSlices(u32, &.{ 0, 1, 2, 3 }, groups);
+
+    // movement runs before damage, so damage can't also run before movement; a simulation system
+    // can't run before an input one either
+    try testing.expectError(error.DependencyCycle, schedule.add(.{ .name = "loop", .run = &render, .after = &.{"damage"}, .before = &.{"movement"} }));
+    try testing.expectError(error.DependencyCycle, schedule.add(.{ .name = "early", .run = &render, .before = &.{"input"} }));
+    try testing.expectEqual(@as(usize, 4), schedule.systems.items.len);
+}
+
+test "Schedule dot output shows conflicts and parallel groups" {
+    var schedule = TestSchedule.init(testing.allocator);
+    defer schedule.deinit();
+
+    try schedule.add(.{ .name = "movement", .run = &movement, .reads = &.{Velocity}, .writes = &.{Position} });
+    try schedule.add(.{ .name = "damage", .run = &damage, .writes = &.{Health} });
+    try schedule.add(.{ .name = "render", .run = &render, .reads = &.{ Position, Health }, .after = &.{"damage"} });
+
+    const groups = try schedule.parallelGroups(testing.allocator);
+    defer testing.allocator.free(groups);
+    try testing.expectEqualSlices(u32, &.{ 0, 0, 1 }, groups);
+
+    var output = std.ArrayList(u8).init(testing.allocator);
+    defer output.deinit();
+    try schedule.dot(output.writer());
+    const text = output.items;
+
+    try testing.expect(std.mem.startsWith(u8, text, "digraph schedule {\n"));
+    try testing.expect(std.mem.indexOf(u8, text, "s0 [label=\"{movement|reads: Velocity|writes: Position}\"];") != null);
+    // Declared ordering
+    try testing.expect(std.mem.indexOf(u8, text, "  s1 -> s2;\n") != null);
+    // Undeclared data conflict
+    try testing.expect(std.mem.indexOf(u8, text, "  s0 -> s2 [style=dashed, color=red, label=\"Position\"];\n") != null);
+    // movement and damage touch disjoint components
+    try testing.expect(std.mem.indexOf(u8, text, "s0 -> s1") == null);
+}
+
+// Systems that take a known time on the profiling clock
+var profile_clock = ManualClock{};
+
+fn slowPhysics(_: *TestECS.Frame) !void {
+    profile_clock.advance(9 * std.time.ns_per_ms);
+}
+
+fn quickAi(_: *TestECS.Frame) !void {
+    profile_clock.advance(std.time.ns_per_ms);
+}
+
+test "Profiled schedules time each system and count the entities it covers" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    for (0..6) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+        if (i % 2 == 0) try frame.addComponent(entity, Velocity{ .x = 1, .y = 0 });
+    }
+
+    var schedule = TestSchedule.init(testing.allocator);
+    defer schedule.deinit();
+    try schedule.add(.{ .name = "physics", .run = &slowPhysics, .reads = &.{Velocity}, .writes = &.{Position} });
+    try schedule.add(.{ .name = "ai", .run = &quickAi, .reads = &.{Position} });
+
+    // Unprofiled runs measure nothing
+    try schedule.run(frame);
+    try testing.expectEqual(@as(u64, 0), schedule.stats("physics").?.calls);
+
+    profile_clock = .{};
+    schedule.profile = true;
+    schedule.clock = profile_clock.clock();
+    for (0..3) |_| try schedule.run(frame);
+    _ = try frame.createEntity();
+    try schedule.run(frame);
+
+    const physics = schedule.stats("physics").?;
+    try testing.expectEqual(@as(u64, 4), physics.calls);
+    try testing.expectEqual(@as(u64, 36 * std.time.ns_per_ms), physics.total_ns);
+    try testing.expectEqual(@as(u64, 9 * std.time.ns_per_ms), physics.averageNs());
+    try testing.expectEqual(@as(u32, 3), physics.entities);
+    try testing.expectEqual(@as(u32, 6), schedule.stats("ai").?.entities);
+    try testing.expectEqual(@as(u64, std.time.ns_per_ms), schedule.stats("ai").?.max_ns);
+    try testing.expectEqual(@as(?schedule_module.SystemStats, null), schedule.stats("missing"));
+    try testing.expectEqual(@as(u64, 4), schedule.profiled_runs);
+
+    schedule.resetStats();
+    try testing.expectEqual(@as(u64, 0), schedule.stats("physics").?.total_ns);
+}
+
+test "Partition boundaries start cache lines" {
+    var buffer: [schedule_module.max_workers]schedule_module.Chunk = undefined;
+    const line = std.atomic.cache_line;
+
+    // 4-byte slots from a line-aligned base: every boundary is a multiple of line / 4
+    const chunks = schedule_module.partition(line * 10, 4, 1000, 3, &buffer);
+    try testing.expectEqual(@as(usize, 3), chunks.len);
+    try testing.expectEqual(@as(u32, 0), chunks[0].begin);
+    try testing.expectEqual(@as(u32, 1000), chunks[2].end);
+    for (chunks[1..], chunks[0 .. chunks.len - 1], 1..) |chunk, previous, worker| {
+        try testing.expectEqual(@as(u32, @intCast(worker)), chunk.worker);
+        try testing.expectEqual(previous.end, chunk.begin);
+        try testing.expectEqual(@as(usize, 0), (line * 10 + chunk.begin * 4) % line);
+    }
+
+    // Too few slots for every worker to get a line of its own
+    try testing.expectEqual(@as(usize, 1), schedule_module.partition(0, 4, 10, 4, &buffer).len);
+    try testing.expectEqual(@as(usize, 0), schedule_module.partition(0, 4, 0, 4, &buffer).len);
+}
+
+var health_total = schedule_module.WorkerLocal(i64).init(0);
+var healed_total: i64 = 0;
+
+fn healChunk(frame: *TestECS.Frame, chunk: schedule_module.Chunk) !void {
+    const healths = frame.getComponentStorage(Health).getDenseArray()[chunk.begin..chunk.end];
+    const total = health_total.get(chunk.worker);
+    for (healths) |*health| {
+        health.value += 1;
+        total.* += health.value;
+    }
+}
+
+fn addInto(into: *i64, value: i64) void {
+    into.* += value;
+}
+
+fn healCommit(frame: *TestECS.Frame) !void {
+    _ = frame;
+    health_total.merge(&healed_total, addInto);
+}
+
+test "Chunked systems match on a thread pool and inline" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    for (0..200) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Health{ .value = @intCast(i) });
+    }
+
+    var schedule = TestSchedule.init(testing.allocator);
+    defer schedule.deinit();
+    try schedule.add(.{ .name = "heal", .run = &healCommit, .writes = &.{Health}, .chunks_over = Health, .each_chunk = &healChunk });
+
+    // Inline: 1..200
+    schedule.workers = 4;
+    healed_total = 0;
+    try schedule.run(frame);
+    try testing.expectEqual(@as(i64, 200 * 201 / 2), healed_total);
+
+    var pool: std.Thread.Pool = undefined;
+    try pool.init(.{ .allocator = testing.allocator, .n_jobs = 4 });
+    defer pool.deinit();
+    schedule.pool = &pool;
+
+    // Pooled: 2..201
+    healed_total = 0;
+    try schedule.run(frame);
+    try testing.expectEqual(@as(i64, 201 * 202 / 2 - 1), healed_total);
+    for (0..200) |i| {
+        try testing.expectEqual(@as(i32, @intCast(i + 2)), frame.getComponent(@intCast(i), Health).?.value);
+    }
+}
+
+fn moveAll(frame: *TestECS.Frame) !void {
+    var query = try frame.query(&.{ Position, Velocity });
+    while (query.next()) |result| result.get(Position).x += result.get(Velocity).x;
+}
+
+fn decayAll(frame: *TestECS.Frame) !void {
+    var query = try frame.query(&.{Health});
+    while (query.next()) |result| result.get(Health).value -= 1;
+}
+
+fn mustNotFail(frame: *TestECS.Frame) !void {
+    if (frame.getComponent(0, Health).?.value < 0) return error.HealthBelowZero;
+}
+
+test "Parallel groups run on the pool and match a serial run" {
+    var serial_ecs = try TestECS.init(testing.allocator);
+    defer serial_ecs.deinit();
+    var parallel_ecs = try TestECS.init(testing.allocator);
+    defer parallel_ecs.deinit();
+    for ([_]*TestECS{ &serial_ecs, &parallel_ecs }) |world| {
+        const frame = world.getFrame();
+        for (0..50) |i| {
+            const entity = try frame.createEntity();
+            try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+            try frame.addComponent(entity, Velocity{ .x = @floatFromInt(i), .y = 0 });
+            try frame.addComponent(entity, Health{ .value = 3 });
+        }
+    }
+
+    var pool: std.Thread.Pool = undefined;
+    try pool.init(.{ .allocator = testing.allocator, .n_jobs = 4 });
+    defer pool.deinit();
+
+    var serial = TestSchedule.init(testing.allocator);
+    defer serial.deinit();
+    var parallel = TestSchedule.init(testing.allocator);
+    defer parallel.deinit();
+    parallel.pool = &pool;
+    parallel.parallel = true;
+    for ([_]*TestSchedule{ &serial, &parallel }) |schedule| {
+        try schedule.add(.{ .name = "move", .run = &moveAll, .reads = &.{Velocity}, .writes = &.{Position} });
+        try schedule.add(.{ .name = "decay", .run = &decayAll, .writes = &.{Health} });
+        try schedule.add(.{ .name = "check", .run = &mustNotFail, .reads = &.{Health} });
+    }
+    // move and decay share a group; check reads what decay writes
+    try testing.expectEqualSlices(u32, &.{ 0, 0, 1 }, parallel.groups);
+
+    for (0..3) |_| {
+        try serial.run(serial_ecs.getFrame());
+        try parallel.run(parallel_ecs.getFrame());
+    }
+    for (0..50) |i| {
+        const entity: ecs.EntityID = @intCast(i);
+        try testing.expectEqual(serial_ecs.getFrame().getComponent(entity, Position).?.*, parallel_ecs.getFrame().getComponent(entity, Position).?.*);
+        try testing.expectEqual(@as(i32, 0), parallel_ecs.getFrame().getComponent(entity, Health).?.value);
+    }
+
+    try testing.expectError(error.HealthBelowZero, parallel.run(parallel_ecs.getFrame()));
+}
@@ -0,0 +1,636 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const EntityID = ecs.EntityID;
+const StructField = std.builtin.Type.StructField;
+
+/// How a component field is exposed through the registry
+pub const FieldKind = enum(u8) {
+    int,
+    uint,
+    boolean,
+    enumeration,
+    fixed,
+    vector2,
+    /// Listed in the schema but not addressable - floats differ across peers
+    float,
+    /// Arrays, nested structs, optionals and unions - listed and encoded, not addressable
+    composite,
+
+    pub fn addressable(self: FieldKind) bool {
+        return self != .float and self != .composite;
+    }
+};
+
+/// A field value read or written by index. Enums travel as their integer tag in `int`.
+pub const Value = union(enum) {
+    int: i64,
+    uint: u64,
+    boolean: bool,
+    fixed: FP,
+    vector2: FPVector2,
+};
+
+pub const FieldSchema = struct {
+    name: []const u8,
+    type_name: []const u8,
+    kind: FieldKind,
+    size: usize,
+};
+
+pub const ComponentSchema = struct {
+    name: []const u8,
+    size: usize,
+    alignment: usize,
+    fields: []const FieldSchema,
+    version: u32 = 1,
+
+    pub fn fieldIndex(self: ComponentSchema, name: []const u8) ?usize {
+        for (self.fields, 0..) |field, i| {
+            if (std.mem.eql(u8, field.name, name)) return i;
+        }
+        return null;
+    }
+};
+
+pub fn kindOf(comptime T: type) FieldKind {
+    if (T == FP) return .fixed;
+    if (T == FPVector2) return .vector2;
+    return switch (@typeInfo(T)) {
+        .bool => .boolean,
+        .int => |info| if (info.bits > 64) .composite else if (info.signedness == .signed) .int else .uint,
+        .@"enum" => |info| if (std.math.minInt(info.tag_type) >= std.math.minInt(i64) and
+            std.math.maxInt(info.tag_type) <= std.math.maxInt(i64)) .enumeration else .composite,
+        .float => .float,
+        else => .composite,
+    };
+}
+
+pub fn componentSchema(comptime T: type) ComponentSchema {
+    comptime {
+        const fields = runtimeFields(T);
+        var schemas: [fields.len]FieldSchema = undefined;
+        for (fields, 0..) |field, i| {
+            schemas[i] = .{
+                .name = field.name,
+                .type_name = ecs.shortTypeName(field.type),
+                .kind = kindOf(field.type),
+                .size = @sizeOf(field.type),
+            };
+        }
+        const final = schemas;
+        return .{
+            .name = ecs.shortTypeName(T),
+            .size = @sizeOf(T),
+            .alignment = @alignOf(T),
+            .fields = &final,
+            .version = componentVersion(T),
+        };
+    }
+}
+
+/// Snapshot version of a component: its `pub const schema_version`, 1 when it declares none.
+/// Bumping it while declaring `pub const Previous` (the struct as the older version laid it out)
+/// and `pub fn migrate(old: Previous) @This()` keeps snapshots of the older version loading.
+/// Chains work too - a Previous may have its own Previous.
+pub fn componentVersion(comptime T: type) u32 {
+    return if (hasDecl(T, "schema_version")) T.schema_version else 1;
+}
+
+fn hasDecl(comptime T: type, comptime name: []const u8) bool {
+    return switch (@typeInfo(T)) {
+        .@"struct", .@"enum", .@"union", .@"opaque" => @hasDecl(T, name),
+        else => false,
+    };
+}
+
+fn checkMigrations(comptime T: type) void {
+    if (!hasDecl(T, "Previous")) return;
+    if (componentVersion(T.Previous) >= componentVersion(T)) {
+        @compileError("'" ++ @typeName(T) ++ "' must have a higher schema_version than its Previous");
+    }
+    if (!hasDecl(T, "migrate")) @compileError("'" ++ @typeName(T) ++ "' declares Previous without migrate");
+    checkMigrations(T.Previous);
+}
+
+/// Schema of the struct that was T's layout at `version`, or null when T has no migration from it
+fn schemaAtVersion(comptime T: type, version: u32) ?ComponentSchema {
+    if (version == comptime componentVersion(T)) return comptime componentSchema(T);
+    if (comptime hasDecl(T, "Previous")) return schemaAtVersion(T.Previous, version);
+    return null;
+}
+
+/// Read a T encoded at `version`, migrating it up one Previous at a time
+fn decodeAtVersion(comptime T: type, version: u32, reader: anytype) !T {
+    if (version == comptime componentVersion(T)) return decodeValue(T, reader);
+    if (comptime hasDecl(T, "Previous")) return T.migrate(try decodeAtVersion(T.Previous, version, reader));
+    return error.SchemaMismatch;
+}
+
+/// Fields with storage - comptime fields are part of the type, not the data
+pub fn runtimeFields(comptime T: type) []const StructField {
+    comptime {
+        const all = switch (@typeInfo(T)) {
+            .@"struct" => |info| info.fields,
+            else => return &.{},
+        };
+        var fields: []const StructField = &.{};
+        for (all) |field| {
+            if (!field.is_comptime) fields = fields ++ &[_]StructField{field};
+        }
+        return fields;
+    }
+}
+
+fn toValue(comptime T: type, value: T) Value {
+    return switch (comptime kindOf(T)) {
+        .int => .{ .int = value },
+        .uint => .{ .uint = value },
+        .boolean => .{ .boolean = value },
+        .enumeration => .{ .int = @intFromEnum(value) },
+        .fixed => .{ .fixed = value },
+        .vector2 => .{ .vector2 = value },
+        .float, .composite => @compileError("'" ++ @typeName(T) ++ "' is not addressable"),
+    };
+}
+
+fn fromValue(comptime T: type, value: Value) !T {
+    return switch (comptime kindOf(T)) {
+        .int, .uint => switch (value) {
+            .int => |v| std.math.cast(T, v) orelse error.Overflow,
+            .uint => |v| std.math.cast(T, v) orelse error.Overflow,
+            else => error.TypeMismatch,
+        },
+        .boolean => if (value == .boolean) value.boolean else error.TypeMismatch,
+        .enumeration => if (value == .int) try std.meta.intToEnum(T, value.int) else error.TypeMismatch,
+        .fixed => if (value == .fixed) value.fixed else error.TypeMismatch,
+        .vector2 => if (value == .vector2) value.vector2 else error.TypeMismatch,
+        .float, .composite => @compileError("'" ++ @typeName(T) ++ "' is not addressable"),
+    };
+}
+
+/// Field `field` (schema order) of any struct, or null when it is not addressable
+pub fn readField(comptime T: type, value: *const T, field: usize) ?Value {
+    inline for (comptime runtimeFields(T), 0..) |f, i| {
+        if (i == field) {
+            if (comptime !kindOf(f.type).addressable()) return null;
+            return toValue(f.type, @field(value.*, f.name));
+        }
+    }
+    return null;
+}
+
+pub fn writeField(comptime T: type, target: *T, field: usize, value: Value) !void {
+    inline for (comptime runtimeFields(T), 0..) |f, i| {
+        if (i == field) {
+            if (comptime !kindOf(f.type).addressable()) return error.NotAddressable;
+            @field(target.*, f.name) = try fromValue(f.type, value);
+            return;
+        }
+    }
+    return error.UnknownField;
+}
+
+/// Write `value` field by field, little-endian, with no padding. Floats are written bit-exact.
+pub fn encodeValue(comptime T: type, value: T, writer: anytype) !void {
+    switch (@typeInfo(T)) {
+        .void => {},
+        .bool => try writer.writeByte(@intFromBool(value)),
+        .int => try writer.writeInt(std.math.ByteAlignedInt(T), value, .little),
+        .float => |info| try encodeValue(std.meta.Int(.unsigned, info.bits), @bitCast(value), writer),
+        .@"enum" => |info| try encodeValue(info.tag_type, @intFromEnum(value), writer),
+        .array => |info| for (value) |item| try encodeValue(info.child, item, writer),
+        .@"struct" => |info| {
+            if (info.backing_integer) |Backing| return encodeValue(Backing, @bitCast(value), writer);
+            inline for (comptime runtimeFields(T)) |field| {
+                try encodeValue(field.type, @field(value, field.name), writer);
+            }
+        },
+        .optional => |info| if (value) |payload| {
+            try writer.writeByte(1);
+            try encodeValue(info.child, payload, writer);
+        } else {
+            try writer.writeByte(0);
+        },
+        .@"union" => |info| {
+            const Tag = info.tag_type orelse @compileError("Untagged union '" ++ @typeName(T) ++ "' cannot be encoded");
+            try encodeValue(Tag, value, writer);
+            switch (value) {
+                inline else => |payload| try encodeValue(@TypeOf(payload), payload, writer),
+            }
+        },
+        else => @compileError("'" ++ @typeName(T) ++ "' cannot be encoded - components must be plain data"),
+    }
+}
+
+/// Inverse of `encodeValue`. Out-of-range tags and booleans fail with error.InvalidData.
+pub fn decodeValue(comptime T: type, reader: anytype) !T {
+    switch (@typeInfo(T)) {
+        .void => return {},
+        .bool => return switch (try reader.readByte()) {
+            0 => false,
+            1 => true,
+            else => error.InvalidData,
+        },
+        .int => return std.math.cast(T, try reader.readInt(std.math.ByteAlignedInt(T), .little)) orelse error.InvalidData,
+        .float => |info| return @bitCast(try decodeValue(std.meta.Int(.unsigned, info.bits), reader)),
+        .@"enum" => |info| return std.meta.intToEnum(T, try decodeValue(info.tag_type, reader)) catch error.InvalidData,
+        .array => |info| {
+            var result: T = undefined;
+            for (&result) |*item| item.* = try decodeValue(info.child, reader);
+            return result;
+        },
+        .@"struct" => |info| {
+            if (info.backing_integer) |Backing| return @bitCast(try decodeValue(Backing, reader));
+            var result: T = undefined;
+            inline for (comptime runtimeFields(T)) |field| {
+                @field(result, field.name) = try decodeValue(field.type, reader);
+            }
+            return result;
+        },
+        .optional => |info| return switch (try reader.readByte()) {
+            0 => null,
+            1 => try decodeValue(info.child, reader),
+            else => error.InvalidData,
+        },
+        .@"union" => |info| {
+            const Tag = info.tag_type orelse @compileError("Untagged union '" ++ @typeName(T) ++ "' cannot be decoded");
+            switch (try decodeValue(Tag, reader)) {
+                inline else => |tag| {
+                    const name = @tagName(tag);
+                    return @unionInit(T, name, try decodeValue(@FieldType(T, name), reader));
+                },
+            }
+        },
+        else => @compileError("'" ++ @typeName(T) ++ "' cannot be decoded - components must be plain data"),
+    }
+}
+
+/// Leading bytes of an `encodeFrame` snapshot
+pub const frame_magic = "RWSF";
+/// Bumped whenever the snapshot layout changes. 2 added the entity free list, 3 the resources,
+/// 4 the component versions.
+pub const frame_format_version: u16 = 4;
+
+const HashWriter = std.io.Writer(*std.hash.Fnv1a_64, error{}, hashWrite);
+
+fn hashWrite(hasher: *std.hash.Fnv1a_64, bytes: []const u8) error{}!usize {
+    hasher.update(bytes);
+    return bytes.len;
+}
+
+fn writeJsonValue(value: Value, writer: anytype) !void {
+    switch (value) {
+        .int => |v| try writer.print("{d}", .{v}),
+        .uint => |v| try writer.print("{d}", .{v}),
+        .boolean => |v| try writer.writeAll(if (v) "true" else "false"),
+        .fixed => |v| try writer.print("{d}", .{v.toFloat(f64)}),
+        .vector2 => |v| try writer.print("[{d},{d}]", .{ v.x.toFloat(f64), v.y.toFloat(f64) }),
+    }
+}
+
+// CSV cells carry no quoting - numbers and booleans only
+fn writeCsvValue(value: Value, writer: anytype) !void {
+    switch (value) {
+        .vector2 => |v| try writer.print("{d},{d}", .{ v.x.toFloat(f64), v.y.toFloat(f64) }),
+        else => try writeJsonValue(value, writer),
+    }
+}
+
+/// Schemas, field access by index and byte codecs for every component registered in `EcsType`.
+///
+/// Everything is derived at compile time from `EcsType.component_types`, so adding a component to
+/// the ECS config is the only step - there is no generated code to rerun or keep in sync. Typed
+/// code keeps using `frame.getComponent(entity, T)`; the registry is for code that only knows a
+/// component by name or index at runtime: inspectors, scripts, save files and replays.
+///
+/// Usage:
+///   const Reg = schema.Registry(GameECS);
+///   const health = Reg.componentIndex("Health").?;
+///   try Reg.setField(frame, entity, health, Reg.components[health].fieldIndex("current").?, .{ .int = 50 });
+///   try Reg.encodeEntity(frame, entity, writer);
+pub fn Registry(comptime EcsType: type) type {
+    const Types = EcsType.component_types;
+    const Input = @FieldType(EcsType.Frame, "input");
+
+    return struct {
+        pub const components: [Types.len]ComponentSchema = blk: {
+            var schemas: [Types.len]ComponentSchema = undefined;
+            for (Types, 0..) |T, i| {
+                checkMigrations(T);
+                schemas[i] = componentSchema(T);
+            }
+            break :blk schemas;
+        };
+
+        /// `componentVersion` of each component in registration order
+        pub const versions: [Types.len]u32 = blk: {
+            var result: [Types.len]u32 = undefined;
+            for (components, 0..) |component, i| result[i] = component.version;
+            break :blk result;
+        };
+
+        pub fn componentIndex(name: []const u8) ?usize {
+            for (components, 0..) |component, i| {
+                if (std.mem.eql(u8, component.name, name)) return i;
+            }
+            return null;
+        }
+
+        /// Field value, or null when the entity lacks the component or the field is not addressable
+        pub fn getField(frame: *EcsType.Frame, entity: EntityID, component: usize, field: usize) ?Value {
+            inline for (Types, 0..) |T, i| {
+                if (i == component) {
+                    const value = frame.getComponent(entity, T) orelse return null;
+                    return readField(T, value, field);
+                }
+            }
+            return null;
+        }
+
+        pub fn setField(frame: *EcsType.Frame, entity: EntityID, component: usize, field: usize, value: Value) !void {
+            inline for (Types, 0..) |T, i| {
+                if (i == component) {
+                    const target = frame.getComponent(entity, T) orelse return error.NoComponent;
+                    return writeField(T, target, field, value);
+                }
+            }
+            return error.UnknownComponent;
+        }
+
+        pub fn hasComponent(frame: *EcsType.Frame, entity: EntityID, component: usize) bool {
+            inline for (Types, 0..) |T, i| {
+                if (i == component) return frame.hasComponent(entity, T);
+            }
+            return false;
+        }
+
+        /// Write the entity's component mask followed by each present component in registration order
+        pub fn encodeEntity(frame: *EcsType.Frame, entity: EntityID, writer: anytype) !void {
+            var mask: u64 = 0;
+            inline for (Types, 0..) |T, i| {
+                if (frame.hasComponent(entity, T)) mask |= @as(u64, 1) << i;
+            }
+            try writer.writeInt(u64, mask, .little);
+            inline for (Types) |T| {
+                if (frame.getComponent(entity, T)) |value| try encodeValue(T, value.*, writer);
+            }
+        }
+
+        /// Overwrite the entity's components with an `encodeEntity` record - components missing
+        /// from the record are removed, so the entity ends up exactly as encoded
+        pub fn decodeEntity(frame: *EcsType.Frame, entity: EntityID, reader: anytype) !void {
+            return decodeEntityAt(frame, entity, reader, &versions);
+        }
+
+        /// `decodeEntity` for a record written with the given component versions, migrating
+        /// each component to its current layout
+        fn decodeEntityAt(frame: *EcsType.Frame, entity: EntityID, reader: anytype, encoded: *const [Types.len]u32) !void {
+            const mask = try reader.readInt(u64, .little);
+            if (Types.len < 64 and mask >> Types.len != 0) return error.InvalidData;
+
+            inline for (Types, 0..) |T, i| {
+                if (mask & (@as(u64, 1) << i) != 0) {
+                    const value = try decodeAtVersion(T, encoded[i], reader);
+                    // A relation is re-added so the frame's target index follows the new target
+                    if (comptime ecs.isRelation(T)) _ = frame.removeComponent(entity, T);
+                    if (frame.getComponent(entity, T)) |existing| {
+                        existing.* = value;
+                    } else {
+                        try frame.addComponent(entity, value);
+                    }
+                } else {
+                    _ = frame.removeComponent(entity, T);
+                }
+            }
+        }
+
+        /// Fingerprint of the component schemas - frames only decode into the layout they were
+        /// encoded from
+        pub fn schemaHash() u64 {
+            return schemaHashAt(&versions).?;
+        }
+
+        /// `schemaHash` as it was when the components had the given versions, or null when some
+        /// version has no migration path to the current one
+        pub fn schemaHashAt(encoded: *const [Types.len]u32) ?u64 {
+            var hasher = std.hash.Fnv1a_64.init();
+            writeSchemaAt(HashWriter{ .context = &hasher }, encoded) catch return null;
+            return hasher.final();
+        }
+
+        /// Whole frame as a versioned binary snapshot, for save files, network state transfer and
+        /// diffing two ticks:
+        ///
+        ///   "RWSF" u16 frame_format_version  u64 schemaHash()
+        ///   u32 component count, u32 componentVersion per component in registration order
+        ///   u64 frame_number  f64 time  input (encodeValue)
+        ///   u32 next_entity  u32 generation count, u32 per generation
+        ///   u32 entity count, then [u32 id][encodeEntity record] per live entity in ascending id order
+        ///   u32 free slot count, u32 per free slot in free list order (reused from the back)
+        ///   each resource (encodeValue) in registration order
+        ///
+        /// Little-endian throughout; dense storage order is not kept, so decoding yields the same
+        /// state (and `hashFrame`) with possibly different dense layouts.
+        pub fn encodeFrame(frame: *EcsType.Frame, writer: anytype) !void {
+            try writer.writeAll(frame_magic);
+            try writer.writeInt(u16, frame_format_version, .little);
+            try writer.writeInt(u64, schemaHash(), .little);
+            try writer.writeInt(u32, Types.len, .little);
+            for (versions) |component_version| try writer.writeInt(u32, component_version, .little);
+
+            try writer.writeInt(u64, frame.frame_number, .little);
+            try encodeValue(f64, frame.time, writer);
+            try encodeValue(Input, frame.input, writer);
+
+            const state = &frame.state;
+            try writer.writeInt(u32, state.next_entity, .little);
+            try writer.writeInt(u32, @intCast(state.generations.items.len), .little);
+            for (state.generations.items) |generation| try writer.writeInt(u32, generation, .little);
+
+            try writer.writeInt(u32, state.entity_count, .little);
+            var entities = state.active_entities.fastIterator();
+            while (entities.next()) |entity| {
+                try writer.writeInt(u32, entity, .little);
+                try encodeEntity(frame, entity, writer);
+            }
+
+            // The order decides which ids later spawns get, so it is part of the state
+            try writer.writeInt(u32, @intCast(state.free_entities.items.len), .little);
+            for (state.free_entities.items) |entity| try writer.writeInt(u32, entity, .little);
+
+            inline for (EcsType.resource_types, 0..) |R, i| try encodeValue(R, state.resources[i], writer);
+        }
+
+        /// Replace the frame's contents with an `encodeFrame` snapshot. Fails with
+        /// error.InvalidData on a foreign or corrupt stream and error.SchemaMismatch when the
+        /// components changed since it was written without a migration (see `componentVersion`)
+        /// covering the change; the frame is left empty or partly loaded then.
+        pub fn decodeFrame(frame: *EcsType.Frame, reader: anytype) !void {
+            var magic: [frame_magic.len]u8 = undefined;
+            try reader.readNoEof(&magic);
+            if (!std.mem.eql(u8, &magic, frame_magic)) return error.InvalidData;
+            const version = try reader.readInt(u16, .little);
+            if (version == 0 or version > frame_format_version) return error.UnsupportedVersion;
+            const stored_hash = try reader.readInt(u64, .little);
+
+            // Older formats predate component versions, so every component was at version 1
+            var encoded = [_]u32{1} ** Types.len;
+            if (version >= 4) {
+                if (try reader.readInt(u32, .little) != Types.len) return error.SchemaMismatch;
+                for (&encoded) |*component_version| component_version.* = try reader.readInt(u32, .little);
+            }
+            // The hash covers the layouts the snapshot was written with, which migrations bring up to date
+            if (schemaHashAt(&encoded) != stored_hash) return error.SchemaMismatch;
+
+            const frame_number = try reader.readInt(u64, .little);
+            const time = try decodeValue(f64, reader);
+            const input = try decodeValue(Input, reader);
+
+            const state = &frame.state;
+            var cursor: u32 = 0;
+            while (state.active_entities.nextSet(cursor)) |entity| : (cursor = entity + 1) {
+                state.destroyEntity(entity);
+            }
+
+            const next_entity = try reader.readInt(u32, .little);
+            const generation_count = try reader.readInt(u32, .little);
+            if (generation_count > EcsType.max_entities or next_entity > EcsType.max_entities) return error.InvalidData;
+            try state.free_entities.ensureTotalCapacity(state.allocator, generation_count);
+            state.free_entities.clearRetainingCapacity();
+            try state.generations.resize(state.allocator, generation_count);
+            for (state.generations.items) |*generation| generation.* = try reader.readInt(u32, .little);
+
+            const entity_count = try reader.readInt(u32, .little);
+            for (0..entity_count) |_| {
+                const entity = try reader.readInt(u32, .little);
+                if (entity >= generation_count) return error.InvalidData;
+                state.restoreEntity(entity) catch return error.InvalidData;
+                try decodeEntityAt(frame, entity, reader, &encoded);
+            }
+
+            if (version >= 2) {
+                const free_count = try reader.readInt(u32, .little);
+                if (free_count > generation_count) return error.InvalidData;
+                for (0..free_count) |_| {
+                    const entity = try reader.readInt(u32, .little);
+                    if (entity >= generation_count or state.active_entities.isSet(entity)) return error.InvalidData;
+                    state.free_entities.appendAssumeCapacity(entity);
+                }
+            } else {
+                // Version 1 predates the free list: every dead slot is free, lowest reused first
+                var entity = generation_count;
+                while (entity > 0) {
+                    entity -= 1;
+                    if (!state.active_entities.isSet(entity)) state.free_entities.appendAssumeCapacity(entity);
+                }
+            }
+
+            // Older snapshots come from worlds without resources - the schema hash covers them
+            if (version >= 3) {
+                inline for (EcsType.resource_types, 0..) |R, i| state.resources[i] = try decodeValue(R, reader);
+            }
+
+            state.next_entity = next_entity;
+            frame.frame_number = frame_number;
+            frame.time = time;
+            frame.input = input;
+        }
+
+        /// FNV-1a 64 over `[u32 id][encodeEntity record]` for every live entity in ascending id order.
+        /// Defined on the codec bytes, so any implementation of the codec can reproduce it.
+        pub fn hashFrame(frame: *EcsType.Frame) u64 {
+            var hasher = std.hash.Fnv1a_64.init();
+            const writer = HashWriter{ .context = &hasher };
+            var entities = frame.state.active_entities.fastIterator();
+            while (entities.next()) |entity| {
+                writer.writeInt(u32, entity, .little) catch unreachable;
+                encodeEntity(frame, entity, writer) catch unreachable;
+            }
+            return hasher.final();
+        }
+
+        /// `{"Component":{"field":value}}` for every component the entity has. Fixed-point values
+        /// are written as decimals and vectors as `[x,y]`; fields that are not addressable are left out.
+        pub fn writeEntityJson(frame: *EcsType.Frame, entity: EntityID, writer: anytype) !void {
+            try writer.writeByte('{');
+            var first_component = true;
+            for (components, 0..) |component, c| {
+                if (!hasComponent(frame, entity, c)) continue;
+                if (!first_component) try writer.writeByte(',');
+                first_component = false;
+
+                try writer.print("\"{s}\":{{", .{component.name});
+                var first_field = true;
+                for (component.fields, 0..) |field, f| {
+                    const value = getField(frame, entity, c, f) orelse continue;
+                    if (!first_field) try writer.writeByte(',');
+                    first_field = false;
+                    try writer.print("\"{s}\":", .{field.name});
+                    try writeJsonValue(value, writer);
+                }
+                try writer.writeByte('}');
+            }
+            try writer.writeByte('}');
+        }
+
+        /// `tick,entity` then one column per addressable field, named `Component.field` - vectors
+        /// take two columns, `Component.field.x` and `Component.field.y`
+        pub fn writeCsvHeader(writer: anytype) !void {
+            try writer.writeAll("tick,entity");
+            for (components) |component| {
+                for (component.fields) |field| {
+                    switch (field.kind) {
+                        .vector2 => try writer.print(",{s}.{s}.x,{s}.{s}.y", .{ component.name, field.name, component.name, field.name }),
+                        .float, .composite => {},
+                        else => try writer.print(",{s}.{s}", .{ component.name, field.name }),
+                    }
+                }
+            }
+            try writer.writeByte('\n');
+        }
+
+        /// One row matching `writeCsvHeader`. Cells of components the entity lacks are empty.
+        pub fn writeCsvRow(frame: *EcsType.Frame, tick: u64, entity: EntityID, writer: anytype) !void {
+            try writer.print("{d},{d}", .{ tick, entity });
+            for (components, 0..) |component, c| {
+                const present = hasComponent(frame, entity, c);
+                for (component.fields, 0..) |field, f| {
+                    if (!field.kind.addressable()) continue;
+                    try writer.writeByte(',');
+                    if (present) {
+                        try writeCsvValue(getField(frame, entity, c, f).?, writer);
+                    } else if (field.kind == .vector2) {
+                        try writer.writeByte(',');
+                    }
+                }
+            }
+            try writer.writeByte('\n');
+        }
+
+        /// Human-readable schema listing, one component per block
+        pub fn writeSchema(writer: anytype) !void {
+            return writeSchemaAt(writer, &versions);
+        }
+
+        fn writeSchemaAt(writer: anytype, encoded: *const [Types.len]u32) !void {
+            inline for (Types, 0..) |T, i| {
+                // Older versions are listed under the current name, as they were when current
+                const component = schemaAtVersion(T, encoded[i]) orelse return error.SchemaMismatch;
+                try writer.print("{s} ({d} bytes)", .{ components[i].name, component.size });
+                // Only versioned components show it, so existing schema hashes stay valid
+                if (component.version > 1) try writer.print(" v{d}", .{component.version});
+                try writer.writeByte('\n');
+                for (component.fields) |field| {
+                    try writer.print("  {s}: {s} [{s}]\n", .{ field.name, field.type_name, @tagName(field.kind) });
+                }
+            }
+            // Only worlds with resources list them, so existing schema hashes stay valid
+            inline for (EcsType.resource_types) |R| {
+                try writer.print("resource {s} ({d} bytes)\n", .{ ecs.shortTypeName(R), @sizeOf(R) });
+            }
+        }
+    };
+}
@@ -0,0 +1,317 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const components = @import("components.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+
+const Team = enum(u8) { red, blue };
+
+const Unit = struct {
+    health: i32 = 100,
+    armor: u16 = 0,
+    alive: bool = true,
+    team: Team = .red,
+    speed: FP = fp(1),
+    slots: [3]u8 = .{ 0, 0, 0 },
+    target: ?ecs.EntityID = null,
+};
+
+const Marker = struct {
+    tag: u8 = 0,
+};
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Unit, Marker },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const Registry = schema.Registry(TestECS);
+
+test "Schemas mirror the registered component structs" {
+    try testing.expectEqual(@as(usize, 3), Registry.components.len);
+    try testing.expectEqual(@as(?usize, 1), Registry.componentIndex("Unit"));
+    try testing.expectEqual(@as(?usize, null), Registry.componentIndex("Missing"));
+
+    const unit = Registry.components[1];
+    try testing.expectEqual(@as(usize, 7), unit.fields.len);
+    try testing.expectEqual(@sizeOf(Unit), unit.size);
+    try testing.expectEqualStrings("team", unit.fields[3].name);
+    try testing.expectEqual(schema.FieldKind.enumeration, unit.fields[3].kind);
+    try testing.expectEqual(schema.FieldKind.fixed, unit.fields[unit.fieldIndex("speed").?].kind);
+    try testing.expectEqual(schema.FieldKind.composite, unit.fields[unit.fieldIndex("slots").?].kind);
+    try testing.expectEqualStrings("u8", Registry.components[2].fields[0].type_name);
+}
+
+test "Fields are read and written by index" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Unit{});
+
+    const unit = Registry.componentIndex("Unit").?;
+    const fields = Registry.components[unit];
+    const health = fields.fieldIndex("health").?;
+
+    try Registry.setField(frame, entity, unit, health, .{ .int = 42 });
+    try Registry.setField(frame, entity, unit, fields.fieldIndex("team").?, .{ .int = 1 });
+    try testing.expectEqual(@as(i32, 42), frame.getComponent(entity, Unit).?.health);
+    try testing.expectEqual(Team.blue, frame.getComponent(entity, Unit).?.team);
+    try testing.expectEqual(schema.Value{ .int = 42 }, Registry.getField(frame, entity, unit, health).?);
+
+    try testing.expectError(error.Overflow, Registry.setField(frame, entity, unit, fields.fieldIndex("armor").?, .{ .int = -1 }));
+    try testing.expectError(error.TypeMismatch, Registry.setField(frame, entity, unit, health, .{ .boolean = true }));
+    try testing.expectError(error.NotAddressable, Registry.setField(frame, entity, unit, fields.fieldIndex("slots").?, .{ .uint = 1 }));
+    try testing.expectError(error.NoComponent, Registry.setField(frame, entity, 0, 0, .{ .vector2 = fpVec2(1, 2) }));
+    try testing.expectEqual(@as(?schema.Value, null), Registry.getField(frame, entity, 0, 0));
+}
+
+test "Entities render as JSON" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = fpVec2(1.5, -2) });
+    try frame.addComponent(entity, Marker{ .tag = 3 });
+
+    var json = std.ArrayList(u8).init(testing.allocator);
+    defer json.deinit();
+    try Registry.writeEntityJson(frame, entity, json.writer());
+    try testing.expectEqualStrings("{\"Transform\":{\"position\":[1.5,-2],\"rotation\":0},\"Marker\":{\"tag\":3}}", json.items);
+}
+
+test "Frames export as CSV rows" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const first = try frame.createEntity();
+    try frame.addComponent(first, Transform{ .position = fpVec2(0.5, 4) });
+    const second = try frame.createEntity();
+    try frame.addComponent(second, Unit{ .health = 80, .team = .blue });
+    try frame.addComponent(second, Marker{ .tag = 2 });
+
+    var csv = std.ArrayList(u8).init(testing.allocator);
+    defer csv.deinit();
+    try Registry.writeCsvHeader(csv.writer());
+    try Registry.writeCsvRow(frame, 7, first, csv.writer());
+    try Registry.writeCsvRow(frame, 7, second, csv.writer());
+    try testing.expectEqualStrings(
+        \\tick,entity,Transform.position.x,Transform.position.y,Transform.rotation,Unit.health,Unit.armor,Unit.alive,Unit.team,Unit.speed,Marker.tag
+        \\7,0,0.5,4,0,,,,,,
+        \\7,1,,,,80,0,true,1,1,2
+        \\
+    , csv.items);
+}
+
+test "Entities round-trip through the codec" {
+    var source_ecs = try TestECS.init(testing.allocator);
+    defer source_ecs.deinit();
+    var dest_ecs = try TestECS.init(testing.allocator);
+    defer dest_ecs.deinit();
+
+    const source = source_ecs.getFrame();
+    const entity = try source.createEntity();
+    try source.addComponent(entity, Transform{ .position = fpVec2(3, -4), .rotation = fp(0.5) });
+    try source.addComponent(entity, Unit{ .health = -7, .team = .blue, .slots = .{ 1, 2, 3 }, .target = 9 });
+
+    var buffer: [256]u8 = undefined;
+    var stream = std.io.fixedBufferStream(&buffer);
+    try Registry.encodeEntity(source, entity, stream.writer());
+    const encoded = stream.getWritten();
+
+    // The destination starts with a Marker the record does not have - decoding removes it
+    const dest = dest_ecs.getFrame();
+    const copy = try dest.createEntity();
+    try dest.addComponent(copy, Marker{});
+
+    var reader = std.io.fixedBufferStream(encoded);
+    try Registry.decodeEntity(dest, copy, reader.reader());
+    try testing.expectEqual(source.getComponent(entity, Transform).?.*, dest.getComponent(copy, Transform).?.*);
+    try testing.expectEqual(source.getComponent(entity, Unit).?.*, dest.getComponent(copy, Unit).?.*);
+    try testing.expect(!dest.hasComponent(copy, Marker));
+
+    var truncated = std.io.fixedBufferStream(encoded[0 .. encoded.len - 1]);
+    try testing.expectError(error.EndOfStream, Registry.decodeEntity(dest, copy, truncated.reader()));
+}
+
+test "Frame hashes follow component state" {
+    var first_ecs = try TestECS.init(testing.allocator);
+    defer first_ecs.deinit();
+    var second_ecs = try TestECS.init(testing.allocator);
+    defer second_ecs.deinit();
+
+    const first = first_ecs.getFrame();
+    const second = second_ecs.getFrame();
+    for ([_]*TestECS.Frame{ first, second }) |frame| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Transform{ .position = fpVec2(2, 3) });
+        try frame.addComponent(entity, Unit{ .health = 12 });
+    }
+    try testing.expectEqual(Registry.hashFrame(first), Registry.hashFrame(second));
+
+    second.getComponent(0, Unit).?.health = 13;
+    try testing.expect(Registry.hashFrame(first) != Registry.hashFrame(second));
+}
+
+test "Frames round-trip through the snapshot format" {
+    var source_ecs = try TestECS.init(testing.allocator);
+    defer source_ecs.deinit();
+    var dest_ecs = try TestECS.init(testing.allocator);
+    defer dest_ecs.deinit();
+
+    source_ecs.update(.{ .value = 0.25 }, 1.0 / 60.0, 3.5);
+    const source = source_ecs.getFrame();
+    for (0..5) |i| {
+        const entity = try source.createEntity();
+        try source.addComponent(entity, Transform{ .position = fpVec2(@intCast(i), 1) });
+        if (i % 2 == 0) try source.addComponent(entity, Unit{ .health = @intCast(i * 10) });
+    }
+    const stale = source.handle(1);
+    source.destroyEntity(1);
+
+    var buffer: [1024]u8 = undefined;
+    var stream = std.io.fixedBufferStream(&buffer);
+    try Registry.encodeFrame(source, stream.writer());
+    const encoded = stream.getWritten();
+    try testing.expectEqualStrings(schema.frame_magic, encoded[0..4]);
+
+    // Whatever the destination held is replaced
+    const dest = dest_ecs.getFrame();
+    for (0..8) |_| _ = try dest.createEntity();
+
+    var reader = std.io.fixedBufferStream(encoded);
+    try Registry.decodeFrame(dest, reader.reader());
+    try testing.expectEqual(Registry.hashFrame(source), Registry.hashFrame(dest));
+    try testing.expectEqual(source.frame_number, dest.frame_number);
+    try testing.expectEqual(@as(f32, 0.25), dest.input.value);
+    try testing.expectEqual(@as(u32, 4), dest.state.entity_count);
+    try testing.expectEqual(source.state.next_entity, dest.state.next_entity);
+    try testing.expectEqualSlices(ecs.EntityID, source.state.free_entities.items, dest.state.free_entities.items);
+    try testing.expectEqual(@as(?ecs.EntityID, null), dest.resolve(stale));
+    try testing.expectEqual(@as(i32, 40), dest.getComponent(4, Unit).?.health);
+
+    // Foreign bytes are refused before anything is touched
+    buffer[0] = 'X';
+    var foreign = std.io.fixedBufferStream(encoded);
+    try testing.expectError(error.InvalidData, Registry.decodeFrame(dest, foreign.reader()));
+    try testing.expectEqual(@as(u32, 4), dest.state.entity_count);
+
+    // Both sides hand the next spawn the same recycled id
+    try testing.expectEqual(try source.createEntity(), try dest.createEntity());
+}
+
+const Weather = struct { wind: FP = fp(0), raining: bool = false };
+
+test "Resources travel with the snapshot and change the schema hash" {
+    const WeatherECS = ecs.ECS(.{
+        .components = &.{ Transform, Unit, Marker },
+        .input = TestInput,
+        .resources = &.{Weather},
+        .max_entities = .small,
+    });
+    const WeatherRegistry = schema.Registry(WeatherECS);
+    try testing.expect(WeatherRegistry.schemaHash() != Registry.schemaHash());
+
+    var source_ecs = try WeatherECS.init(testing.allocator);
+    defer source_ecs.deinit();
+    var dest_ecs = try WeatherECS.init(testing.allocator);
+    defer dest_ecs.deinit();
+    source_ecs.setResource(Weather{ .wind = fp(3), .raining = true });
+
+    var buffer: [256]u8 = undefined;
+    var stream = std.io.fixedBufferStream(&buffer);
+    try WeatherRegistry.encodeFrame(source_ecs.getFrame(), stream.writer());
+    var reader = std.io.fixedBufferStream(stream.getWritten());
+    try WeatherRegistry.decodeFrame(dest_ecs.getFrame(), reader.reader());
+    try testing.expect(dest_ecs.getResource(Weather).raining);
+    try testing.expectEqual(source_ecs.getFrame().checksum(), dest_ecs.getFrame().checksum());
+
+    // A world without the resource refuses the snapshot
+    reader.reset();
+    var plain = try TestECS.init(testing.allocator);
+    defer plain.deinit();
+    try testing.expectError(error.SchemaMismatch, Registry.decodeFrame(plain.getFrame(), reader.reader()));
+}
+
+// Armor as an older build saved it
+const saved = struct {
+    const Armor = struct { points: u8 = 0 };
+};
+
+const current = struct {
+    // Points outgrew a byte and armor can break now
+    const Armor = struct {
+        points: u16 = 0,
+        broken: bool = false,
+
+        pub const schema_version = 2;
+        pub const Previous = saved.Armor;
+
+        pub fn migrate(old: Previous) @This() {
+            return .{ .points = @as(u16, old.points) * 10 };
+        }
+    };
+};
+
+// The same change without a version bump
+const unversioned = struct {
+    const Armor = struct { points: u16 = 0 };
+};
+
+test "Snapshots of older component versions migrate on decode" {
+    const SavedECS = ecs.ECS(.{ .components = &.{ Transform, saved.Armor }, .input = TestInput, .max_entities = .small });
+    const CurrentECS = ecs.ECS(.{ .components = &.{ Transform, current.Armor }, .input = TestInput, .max_entities = .small });
+    const UnversionedECS = ecs.ECS(.{ .components = &.{ Transform, unversioned.Armor }, .input = TestInput, .max_entities = .small });
+    const SavedRegistry = schema.Registry(SavedECS);
+    const CurrentRegistry = schema.Registry(CurrentECS);
+
+    try testing.expectEqualSlices(u32, &.{ 1, 2 }, &CurrentRegistry.versions);
+    try testing.expect(CurrentRegistry.schemaHash() != SavedRegistry.schemaHash());
+    try testing.expectEqual(SavedRegistry.schemaHash(), CurrentRegistry.schemaHashAt(&.{ 1, 1 }).?);
+    try testing.expectEqual(@as(?u64, null), CurrentRegistry.schemaHashAt(&.{ 1, 3 }));
+
+    var saved_ecs = try SavedECS.init(testing.allocator);
+    defer saved_ecs.deinit();
+    const entity = try saved_ecs.getFrame().createEntity();
+    try saved_ecs.getFrame().addComponent(entity, Transform{ .position = fpVec2(4, 2) });
+    try saved_ecs.getFrame().addComponent(entity, saved.Armor{ .points = 7 });
+
+    var buffer: [256]u8 = undefined;
+    var stream = std.io.fixedBufferStream(&buffer);
+    try SavedRegistry.encodeFrame(saved_ecs.getFrame(), stream.writer());
+    const encoded = stream.getWritten();
+
+    var current_ecs = try CurrentECS.init(testing.allocator);
+    defer current_ecs.deinit();
+    var reader = std.io.fixedBufferStream(encoded);
+    try CurrentRegistry.decodeFrame(current_ecs.getFrame(), reader.reader());
+    const armor = current_ecs.getFrame().getComponent(entity, current.Armor).?;
+    try testing.expectEqual(@as(u16, 70), armor.points);
+    try testing.expect(!armor.broken);
+    try testing.expectEqual(fpVec2(4, 2), current_ecs.getFrame().getComponent(entity, Transform).?.position);
+
+    // A layout change nobody versioned is still refused
+    var unversioned_ecs = try UnversionedECS.init(testing.allocator);
+    defer unversioned_ecs.deinit();
+    reader.reset();
+    try testing.expectError(error.SchemaMismatch, schema.Registry(UnversionedECS).decodeFrame(unversioned_ecs.getFrame(), reader.reader()));
+
+    // There is no way back down
+    stream.reset();
+    try CurrentRegistry.encodeFrame(current_ecs.getFrame(), stream.writer());
+    var newer = std.io.fixedBufferStream(stream.getWritten());
+    try testing.expectError(error.SchemaMismatch, SavedRegistry.decodeFrame(saved_ecs.getFrame(), newer.reader()));
+}
@@ -0,0 +1,397 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+
+const EntityID = ecs.EntityID;
+const Value = schema.Value;
+
+pub const MAX_STACK = 32;
+
+pub const Op = enum(u8) {
+    /// Operand: raw fixed-point value
+    push,
+    /// Operand: field reference (see `fieldRef`)
+    load,
+    store,
+    /// Operand: input field reference
+    input,
+    dt,
+    tick,
+    entity,
+    /// Operand: component index - pushes 1 when the entity has it
+    has,
+    dup,
+    pop,
+    swap,
+    over,
+    add,
+    sub,
+    mul,
+    div,
+    neg,
+    abs,
+    min,
+    max,
+    sqrt,
+    lt,
+    gt,
+    eq,
+    not,
+    /// Operand: instruction index
+    jmp,
+    /// Pops, jumps when zero
+    jz,
+    /// Done with this entity
+    end,
+};
+
+pub const Instruction = struct {
+    op: Op,
+    operand: i64 = 0,
+};
+
+/// Which part of a field a load/store touches - only vector2 fields have parts
+const Part = enum(u2) { whole, x, y };
+
+fn fieldRef(component: usize, field: usize, part: Part) i64 {
+    return @intCast(component << 16 | field << 2 | @intFromEnum(part));
+}
+
+pub const ScriptOptions = struct {
+    /// Instructions one entity may execute per step before the script is aborted
+    max_steps_per_entity: u32 = 10_000,
+    /// Receives the 1-based source line of a compile error
+    diagnostic: ?*Diagnostic = null,
+};
+
+pub const Diagnostic = struct {
+    line: u32 = 0,
+};
+
+/// Gameplay systems written as text and compiled at runtime into a small stack bytecode.
+///
+/// Scripts reach components only through the schema registry, so anything registered in the
+/// ECS is scriptable without bindings. Determinism is enforced rather than trusted: every value is
+/// fixed-point (number literals are parsed with integer math), float fields and input are rejected
+/// at compile time, arithmetic overflow and division by zero fail the step instead of wrapping
+/// differently per platform, entities run in ascending id order, and each entity has an
+/// instruction budget so a runaway loop fails on every peer the same way instead of hanging one.
+///
+/// Source is one instruction per line, `#` starts a comment and `name:` defines a jump label:
+///
+///   each Transform Velocity      # entities the script runs on
+///   load Velocity.linear.x
+///   push 0.98
+///   mul
+///   store Velocity.linear.x
+///
+/// Vector fields load as two values (x then y) unless `.x` or `.y` picks one. Booleans load as
+/// 0/1 and store as "non-zero"; integer fields store the value rounded toward negative infinity.
+pub fn ScriptSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+        pub const Registry = schema.Registry(EcsType);
+        const Input = @FieldType(EcsType.Frame, "input");
+        const input_schema = schema.componentSchema(Input);
+        const Stack = std.BoundedArray(FP, MAX_STACK);
+
+        allocator: std.mem.Allocator,
+        code: []Instruction,
+        /// Component indices an entity needs to run the script
+        required: u64,
+        max_steps: u32,
+
+        pub fn compile(allocator: std.mem.Allocator, source: []const u8, options: ScriptOptions) !Self {
+            var code = std.ArrayList(Instruction).init(allocator);
+            errdefer code.deinit();
+            var labels = std.StringHashMap(usize).init(allocator);
+            defer labels.deinit();
+            var fixups = std.ArrayList(struct { index: usize, label: []const u8, line: u32 }).init(allocator);
+            defer fixups.deinit();
+
+            var required: ?u64 = null;
+            var line_number: u32 = 0;
+            errdefer if (options.diagnostic) |diagnostic| {
+                diagnostic.line = line_number;
+            };
+
+            var lines = std.mem.splitScalar(u8, source, '\n');
+            while (lines.next()) |raw_line| {
+                line_number += 1;
+                const line = if (std.mem.indexOfScalar(u8, raw_line, '#')) |hash| raw_line[0..hash] else raw_line;
+                var tokens = std.mem.tokenizeAny(u8, line, " \t\r");
+
+                var word = tokens.next() orelse continue;
+                if (word[word.len - 1] == ':') {
+                    const result = try labels.getOrPut(word[0 .. word.len - 1]);
+                    if (result.found_existing) return error.DuplicateLabel;
+                    result.value_ptr.* = code.items.len;
+                    word = tokens.next() orelse continue;
+                }
+
+                if (std.mem.eql(u8, word, "each")) {
+                    if (required != null) return error.DuplicateEach;
+                    var mask: u64 = 0;
+                    while (tokens.next()) |name| {
+                        mask |= @as(u64, 1) << @intCast(Registry.componentIndex(name) orelse return error.UnknownComponent);
+                    }
+                    required = mask;
+                    continue;
+                }
+
+                const op = std.meta.stringToEnum(Op, word) orelse return error.UnknownOp;
+                var instruction = Instruction{ .op = op };
+                switch (op) {
+                    .push => instruction.operand = (try parseFixed(tokens.next() orelse return error.MissingOperand)).raw_value,
+                    .load, .store => instruction.operand = try parseFieldRef(tokens.next() orelse return error.MissingOperand),
+                    .input => instruction.operand = try parseInputRef(tokens.next() orelse return error.MissingOperand),
+                    .has => {
+                        const name = tokens.next() orelse return error.MissingOperand;
+                        instruction.operand = @intCast(Registry.componentIndex(name) orelse return error.UnknownComponent);
+                    },
+                    .jmp, .jz => try fixups.append(.{
+                        .index = code.items.len,
+                        .label = tokens.next() orelse return error.MissingOperand,
+                        .line = line_number,
+                    }),
+                    else => {},
+                }
+                if (tokens.next() != null) return error.UnexpectedOperand;
+                try code.append(instruction);
+            }
+
+            for (fixups.items) |fixup| {
+                line_number = fixup.line;
+                const target = labels.get(fixup.label) orelse return error.UnknownLabel;
+                code.items[fixup.index].operand = @intCast(target);
+            }
+            line_number = 0;
+            const each_mask = required orelse return error.MissingEach;
+
+            return Self{
+                .allocator = allocator,
+                .code = try code.toOwnedSlice(),
+                .required = each_mask,
+                .max_steps = options.max_steps_per_entity,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.allocator.free(self.code);
+        }
+
+        /// Run the script once for every entity with the required components, in ascending id order
+        pub fn step(self: *const Self, frame: *EcsType.Frame, dt: FP) !void {
+            var entities = frame.state.active_entities.fastIterator();
+            entity_loop: while (entities.next()) |entity| {
+                for (0..Registry.components.len) |i| {
+                    if (self.required & (@as(u64, 1) << @intCast(i)) != 0 and !Registry.hasComponent(frame, entity, i)) {
+                        continue :entity_loop;
+                    }
+                }
+                try self.runEntity(frame, entity, dt);
+            }
+        }
+
+        fn runEntity(self: *const Self, frame: *EcsType.Frame, entity: EntityID, dt: FP) !void {
+            var stack = Stack{};
+            var pc: usize = 0;
+            var steps: u32 = 0;
+
+            while (pc < self.code.len) {
+                steps += 1;
+                if (steps > self.max_steps) return error.BudgetExceeded;
+                const instruction = self.code[pc];
+                pc += 1;
+
+                switch (instruction.op) {
+                    .push => try push(&stack, FP.fromRaw(instruction.operand)),
+                    .load => try load(frame, entity, instruction.operand, &stack),
+                    .store => try store(frame, entity, instruction.operand, &stack),
+                    .input => {
+                        const ref: usize = @intCast(instruction.operand);
+                        const value = schema.readField(Input, &frame.input, ref >> 2) orelse return error.NotAddressable;
+                        try pushValue(&stack, value, @enumFromInt(ref & 3));
+                    },
+                    .dt => try push(&stack, dt),
+                    .tick => try push(&stack, try fromInt(frame.frame_number)),
+                    .entity => try push(&stack, try fromInt(entity)),
+                    .has => try push(&stack, FP.fromInt(@intFromBool(Registry.hasComponent(frame, entity, @intCast(instruction.operand))))),
+                    .dup => try push(&stack, try peek(&stack, 0)),
+                    .over => try push(&stack, try peek(&stack, 1)),
+                    .pop => _ = try pop(&stack),
+                    .swap => {
+                        const b = try pop(&stack);
+                        const a = try pop(&stack);
+                        try push(&stack, b);
+                        try push(&stack, a);
+                    },
+                    .neg, .abs, .sqrt, .not => {
+                        const a = try pop(&stack);
+                        try push(&stack, try unary(instruction.op, a));
+                    },
+                    .add, .sub, .mul, .div, .min, .max, .lt, .gt, .eq => {
+                        const b = try pop(&stack);
+                        const a = try pop(&stack);
+                        try push(&stack, try binary(instruction.op, a, b));
+                    },
+                    .jmp => pc = @intCast(instruction.operand),
+                    .jz => if ((try pop(&stack)).raw_value == 0) {
+                        pc = @intCast(instruction.operand);
+                    },
+                    .end => return,
+                }
+            }
+        }
+
+        fn load(frame: *EcsType.Frame, entity: EntityID, operand: i64, stack: *Stack) !void {
+            const ref: usize = @intCast(operand);
+            const value = Registry.getField(frame, entity, ref >> 16, (ref >> 2) & 0x3FFF) orelse return error.NoComponent;
+            try pushValue(stack, value, @enumFromInt(ref & 3));
+        }
+
+        fn store(frame: *EcsType.Frame, entity: EntityID, operand: i64, stack: *Stack) !void {
+            const ref: usize = @intCast(operand);
+            const component = ref >> 16;
+            const field = (ref >> 2) & 0x3FFF;
+            const part: Part = @enumFromInt(ref & 3);
+
+            const value: Value = switch (Registry.components[component].fields[field].kind) {
+                .int, .enumeration => .{ .int = (try pop(stack)).asLong() },
+                .uint => .{ .uint = std.math.cast(u64, (try pop(stack)).asLong()) orelse return error.Overflow },
+                .boolean => .{ .boolean = (try pop(stack)).raw_value != 0 },
+                .fixed => .{ .fixed = try pop(stack) },
+                .vector2 => blk: {
+                    var vector = (Registry.getField(frame, entity, component, field) orelse return error.NoComponent).vector2;
+                    switch (part) {
+                        .whole => {
+                            vector.y = try pop(stack);
+                            vector.x = try pop(stack);
+                        },
+                        .x => vector.x = try pop(stack),
+                        .y => vector.y = try pop(stack),
+                    }
+                    break :blk .{ .vector2 = vector };
+                },
+                .float, .composite => return error.NotAddressable,
+            };
+            try Registry.setField(frame, entity, component, field, value);
+        }
+
+        fn parseFieldRef(text: []const u8) !i64 {
+            var parts = std.mem.splitScalar(u8, text, '.');
+            const component = Registry.componentIndex(parts.first()) orelse return error.UnknownComponent;
+            const fields = Registry.components[component];
+            const field = fields.fieldIndex(parts.next() orelse return error.MissingField) orelse return error.UnknownField;
+            const part = try parsePart(fields.fields[field].kind, parts.rest());
+            return fieldRef(component, field, part);
+        }
+
+        fn parseInputRef(text: []const u8) !i64 {
+            var parts = std.mem.splitScalar(u8, text, '.');
+            const field = input_schema.fieldIndex(parts.first()) orelse return error.UnknownField;
+            const part = try parsePart(input_schema.fields[field].kind, parts.rest());
+            return fieldRef(0, field, part);
+        }
+    };
+}
+
+fn parsePart(kind: schema.FieldKind, suffix: []const u8) !Part {
+    if (!kind.addressable()) return error.NotAddressable;
+    if (suffix.len == 0) return .whole;
+    if (kind != .vector2) return error.UnknownField;
+    if (std.mem.eql(u8, suffix, "x")) return .x;
+    if (std.mem.eql(u8, suffix, "y")) return .y;
+    return error.UnknownField;
+}
+
+/// Decimal literal to fixed point using integer math only, rounding the fraction to nearest
+pub fn parseFixed(text: []const u8) !FP {
+    const negative = text.len > 0 and text[0] == '-';
+    const digits = if (negative) text[1..] else text;
+    const dot = std.mem.indexOfScalar(u8, digits, '.') orelse digits.len;
+    const whole_text = digits[0..dot];
+    const fraction_text = if (dot < digits.len) digits[dot + 1 ..] else "";
+    if (whole_text.len == 0 and fraction_text.len == 0) return error.InvalidNumber;
+    if (fraction_text.len > 18) return error.InvalidNumber;
+
+    const whole: i64 = if (whole_text.len == 0) 0 else std.fmt.parseUnsigned(i64, whole_text, 10) catch return error.InvalidNumber;
+    var raw = std.math.shlExact(i64, whole, FP.PRECISION) catch return error.InvalidNumber;
+    if (fraction_text.len > 0) {
+        const fraction = std.fmt.parseUnsigned(u64, fraction_text, 10) catch return error.InvalidNumber;
+        const scale = std.math.powi(u64, 10, fraction_text.len) catch unreachable;
+        const scaled: i64 = @intCast((@as(u128, fraction) * FP.ONE_RAW + scale / 2) / scale);
+        raw = std.math.add(i64, raw, scaled) catch return error.InvalidNumber;
+    }
+    return FP.fromRaw(if (negative) -raw else raw);
+}
+
+fn fromInt(value: anytype) !FP {
+    const wide = std.math.cast(i64, value) orelse return error.Overflow;
+    return FP.fromRaw(std.math.shlExact(i64, wide, FP.PRECISION) catch return error.Overflow);
+}
+
+fn pushValue(stack: anytype, value: Value, part: Part) !void {
+    switch (value) {
+        .int => |v| try push(stack, try fromInt(v)),
+        .uint => |v| try push(stack, try fromInt(v)),
+        .boolean => |v| try push(stack, FP.fromInt(@intFromBool(v))),
+        .fixed => |v| try push(stack, v),
+        .vector2 => |v| switch (part) {
+            .whole => {
+                try push(stack, v.x);
+                try push(stack, v.y);
+            },
+            .x => try push(stack, v.x),
+            .y => try push(stack, v.y),
+        },
+    }
+}
+
+fn push(stack: anytype, value: FP) !void {
+    stack.append(value) catch return error.StackOverflow;
+}
+
+fn pop(stack: anytype) !FP {
+    if (stack.len == 0) return error.StackUnderflow;
+    stack.len -= 1;
+    return stack.buffer[stack.len];
+}
+
+fn peek(stack: anytype, depth: usize) !FP {
+    if (stack.len <= depth) return error.StackUnderflow;
+    return stack.get(stack.len - 1 - depth);
+}
+
+fn unary(op: Op, a: FP) !FP {
+    return switch (op) {
+        .neg => if (a.raw_value == std.math.minInt(i64)) error.Overflow else a.negate(),
+        .abs => if (a.raw_value == std.math.minInt(i64)) error.Overflow else a.abs(),
+        .sqrt => if (a.raw_value < 0) error.NegativeSqrt else a.sqrt(),
+        .not => FP.fromInt(@intFromBool(a.raw_value == 0)),
+        else => unreachable,
+    };
+}
+
+fn binary(op: Op, a: FP, b: FP) !FP {
+    return switch (op) {
+        .add => FP.fromRaw(std.math.add(i64, a.raw_value, b.raw_value) catch return error.Overflow),
+        .sub => FP.fromRaw(std.math.sub(i64, a.raw_value, b.raw_value) catch return error.Overflow),
+        .mul => blk: {
+            const product = std.math.mul(i64, a.raw_value, b.raw_value) catch return error.Overflow;
+            break :blk FP.fromRaw((product +| FP.HALF_RAW) >> FP.PRECISION);
+        },
+        .div => blk: {
+            if (b.raw_value == 0) return error.DivisionByZero;
+            const shifted = std.math.shlExact(i64, a.raw_value, FP.PRECISION) catch return error.Overflow;
+            break :blk FP.fromRaw(@divTrunc(shifted, b.raw_value));
+        },
+        .min => a.min(b),
+        .max => a.max(b),
+        .lt => FP.fromInt(@intFromBool(a.lt(b))),
+        .gt => FP.fromInt(@intFromBool(a.gt(b))),
+        .eq => FP.fromInt(@intFromBool(a.eq(b))),
+        else => unreachable,
+    };
+}
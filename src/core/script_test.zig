@@ -0,0 +1,131 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const components = @import("components.zig");
+const script = @import("script.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+
+const Health = struct {
+    current: i32 = 100,
+    max: i32 = 100,
+    regen: FP = fp(0),
+};
+
+const TestInput = struct {
+    boost: bool = false,
+    analog: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Health },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestScript = script.ScriptSystem(TestECS);
+const dt = fp(1.0 / 60.0);
+
+const move_source =
+    \\# Semi-implicit Euler, doubled while the boost button is held
+    \\each Transform Velocity
+    \\load Velocity.linear
+    \\input boost
+    \\jz integrate
+    \\push 2
+    \\mul
+    \\swap
+    \\push 2
+    \\mul
+    \\swap
+    \\integrate:
+    \\dt
+    \\mul
+    \\load Transform.position.y
+    \\add
+    \\store Transform.position.y
+    \\dt
+    \\mul
+    \\load Transform.position.x
+    \\add
+    \\store Transform.position.x
+;
+
+test "Scripts read and write components through the registry" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var mover = try TestScript.compile(testing.allocator, move_source, .{});
+    defer mover.deinit();
+
+    const frame = test_ecs.getFrame();
+    const ship = try frame.createEntity();
+    try frame.addComponent(ship, Transform{});
+    try frame.addComponent(ship, Velocity{ .linear = fpVec2(6, -3) });
+    // No Velocity - skipped
+    const rock = try frame.createEntity();
+    try frame.addComponent(rock, Transform{ .position = fpVec2(1, 1) });
+
+    for (0..60) |_| try mover.step(frame, dt);
+    frame.input.boost = true;
+    for (0..30) |_| try mover.step(frame, dt);
+
+    var expected = Transform{};
+    for (0..90) |tick| {
+        const scale = if (tick < 60) fp(1) else fp(2);
+        expected.position.x = expected.position.x.add(fp(6).mul(scale).mul(dt));
+        expected.position.y = expected.position.y.add(fp(-3).mul(scale).mul(dt));
+    }
+    try testing.expectEqual(expected.position, frame.getComponent(ship, Transform).?.position);
+    try testing.expectEqual(fpVec2(1, 1), frame.getComponent(rock, Transform).?.position);
+}
+
+test "Determinism limits are enforced" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Health{ .current = 40, .regen = fp(2.5) });
+
+    // Integer fields store rounded down, clamped against max
+    var regen = try TestScript.compile(testing.allocator,
+        \\each Health
+        \\load Health.current
+        \\load Health.regen
+        \\add
+        \\load Health.max
+        \\min
+        \\store Health.current
+    , .{});
+    defer regen.deinit();
+    for (0..3) |_| try regen.step(frame, dt);
+    try testing.expectEqual(@as(i32, 46), frame.getComponent(entity, Health).?.current);
+
+    var runaway = try TestScript.compile(testing.allocator, "each Health\nloop: jmp loop", .{ .max_steps_per_entity = 100 });
+    defer runaway.deinit();
+    try testing.expectError(error.BudgetExceeded, runaway.step(frame, dt));
+
+    var divide = try TestScript.compile(testing.allocator, "each Health\npush 1\npush 0\ndiv", .{});
+    defer divide.deinit();
+    try testing.expectError(error.DivisionByZero, divide.step(frame, dt));
+
+    var diagnostic = script.Diagnostic{};
+    try testing.expectError(error.NotAddressable, TestScript.compile(testing.allocator, "each Health\n\ninput analog", .{ .diagnostic = &diagnostic }));
+    try testing.expectEqual(@as(u32, 3), diagnostic.line);
+    try testing.expectError(error.UnknownField, TestScript.compile(testing.allocator, "each Health\nload Health.armor", .{ .diagnostic = &diagnostic }));
+    try testing.expectError(error.UnknownLabel, TestScript.compile(testing.allocator, "each Health\njz nowhere", .{}));
+    try testing.expectError(error.MissingEach, TestScript.compile(testing.allocator, "push 1", .{}));
+}
+
+test "Number literals parse without floats" {
+    try testing.expectEqual(FP.ONE_RAW / 2, (try script.parseFixed("0.5")).raw_value);
+    try testing.expectEqual(fp(-1.25), try script.parseFixed("-1.25"));
+    try testing.expectEqual(fp(3), try script.parseFixed("3"));
+    // Rounded to nearest: 0.1 * 65536 = 6553.6
+    try testing.expectEqual(@as(i64, 6554), (try script.parseFixed(".1")).raw_value);
+    try testing.expectError(error.InvalidNumber, script.parseFixed("1.2.3"));
+    try testing.expectError(error.InvalidNumber, script.parseFixed("-"));
+}
@@ -0,0 +1,456 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const Transform = @import("components.zig").Transform;
+
+const EntityID = ecs.EntityID;
+
+// Ray and box casts against colliders, broadphased through any index below
+const casts = @import("raycast.zig");
+pub const Ray = casts.Ray;
+pub const RaycastHit = casts.Hit;
+pub const raycast = casts.raycast;
+pub const raycastFirst = casts.raycastFirst;
+pub const boxcast = casts.boxcast;
+
+/// Integer cell coordinates in a uniform grid
+pub const Cell = struct {
+    x: i32,
+    y: i32,
+};
+
+/// Uniform spatial hash over entity `Transform` positions.
+///
+/// `sync` compares each entity's position against the cell it was last filed under and only
+/// relinks the ones that crossed a cell boundary, so a mostly static world costs one pass over
+/// positions and no bucket churn. Lookups fill an `EntityBitSet` that can be passed straight to
+/// `Query.restrictTo`, or intersected with any other query result.
+///
+/// Usage:
+///   var grid = spatial.Grid(GameECS).init(allocator, fp(4));
+///   defer grid.deinit();
+///   try grid.sync(frame);
+///
+///   var nearby = GameECS.EntityBitSet.initEmpty();
+///   grid.queryRadius(player_pos, fp(10), &nearby);
+///   var enemies = try frame.query(&.{ Transform, Enemy });
+///   enemies.restrictTo(&nearby);
+pub fn Grid(comptime EcsType: type) type {
+    const MAX_ENTITIES = EcsType.max_entities;
+
+    return struct {
+        const Self = @This();
+        const Bucket = std.ArrayListUnmanaged(EntityID);
+
+        pub const EntitySet = EcsType.EntityBitSet;
+
+        allocator: std.mem.Allocator,
+        cell_size: FP,
+        cells: std.AutoHashMap(Cell, Bucket),
+        /// Entities currently filed in the grid
+        tracked: EntitySet,
+        entity_cells: [MAX_ENTITIES]Cell,
+        /// Position seen at the last sync - exact tests run against this, not the live frame
+        positions: [MAX_ENTITIES]FPVector2,
+        /// Entities that changed cell (or were added/removed) during the last sync
+        relinked_last_sync: u32,
+
+        pub fn init(allocator: std.mem.Allocator, cell_size: FP) Self {
+            std.debug.assert(cell_size.raw_value > 0);
+            return Self{
+                .allocator = allocator,
+                .cell_size = cell_size,
+                .cells = std.AutoHashMap(Cell, Bucket).init(allocator),
+                .tracked = EntitySet.initEmpty(),
+                .entity_cells = undefined,
+                .positions = undefined,
+                .relinked_last_sync = 0,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            var iter = self.cells.valueIterator();
+            while (iter.next()) |bucket| bucket.deinit(self.allocator);
+            self.cells.deinit();
+        }
+
+        /// Bring the grid up to date with every entity holding a Transform
+        pub fn sync(self: *Self, frame: *EcsType.Frame) !void {
+            const storage = frame.getComponentStorage(Transform);
+            var relinked: u32 = 0;
+
+            // Entities that lost their Transform (or were destroyed) since the last sync
+            var stale_iter = self.tracked.fastIterator();
+            while (stale_iter.next()) |entity| {
+                if (storage.entity_bitset.isSet(entity)) continue;
+                self.unlink(entity);
+                self.tracked.unset(entity);
+                relinked += 1;
+            }
+
+            var iter = storage.entity_bitset.fastIterator();
+            while (iter.next()) |entity| {
+                const position = storage.getDirectConst(entity).position;
+                const cell = self.cellOf(position);
+                self.positions[entity] = position;
+
+                if (self.tracked.isSet(entity)) {
+                    const old = self.entity_cells[entity];
+                    if (old.x == cell.x and old.y == cell.y) continue;
+                    self.unlink(entity);
+                }
+
+                try self.link(entity, cell);
+                self.tracked.set(entity);
+                relinked += 1;
+            }
+
+            self.relinked_last_sync = relinked;
+        }
+
+        /// Forget every entity (keeps bucket memory for reuse)
+        pub fn clear(self: *Self) void {
+            var iter = self.cells.valueIterator();
+            while (iter.next()) |bucket| bucket.clearRetainingCapacity();
+            self.tracked.clear();
+        }
+
+        pub fn cellOf(self: *const Self, position: FPVector2) Cell {
+            return .{
+                .x = @intCast(@divFloor(position.x.raw_value, self.cell_size.raw_value)),
+                .y = @intCast(@divFloor(position.y.raw_value, self.cell_size.raw_value)),
+            };
+        }
+
+        /// Entities filed in one cell, in insertion order
+        pub fn queryCell(self: *const Self, cell: Cell) []const EntityID {
+            const bucket = self.cells.getPtr(cell) orelse return &.{};
+            return bucket.items;
+        }
+
+        /// Entities whose position lies inside the box (inclusive bounds)
+        pub fn queryAABB(self: *const Self, min: FPVector2, max: FPVector2, out: *EntitySet) void {
+            const lo = self.cellOf(min);
+            const hi = self.cellOf(max);
+            var cx = lo.x;
+            while (cx <= hi.x) : (cx += 1) {
+                var cy = lo.y;
+                while (cy <= hi.y) : (cy += 1) {
+                    for (self.queryCell(.{ .x = cx, .y = cy })) |entity| {
+                        const p = self.positions[entity];
+                        if (p.x.gte(min.x) and p.x.lte(max.x) and p.y.gte(min.y) and p.y.lte(max.y)) {
+                            out.set(entity);
+                        }
+                    }
+                }
+            }
+        }
+
+        /// Entities within `radius` of `center` (inclusive)
+        pub fn queryRadius(self: *const Self, center: FPVector2, radius: FP, out: *EntitySet) void {
+            const extent = FPVector2.new(radius, radius);
+            const lo = self.cellOf(center.sub(extent));
+            const hi = self.cellOf(center.add(extent));
+            const radius_sq = radius.mul(radius);
+
+            var cx = lo.x;
+            while (cx <= hi.x) : (cx += 1) {
+                var cy = lo.y;
+                while (cy <= hi.y) : (cy += 1) {
+                    for (self.queryCell(.{ .x = cx, .y = cy })) |entity| {
+                        if (FPVector2.distanceSquared(center, self.positions[entity]).lte(radius_sq)) {
+                            out.set(entity);
+                        }
+                    }
+                }
+            }
+        }
+
+        pub fn trackedCount(self: *const Self) u32 {
+            return self.tracked.count();
+        }
+
+        fn link(self: *Self, entity: EntityID, cell: Cell) !void {
+            const slot = try self.cells.getOrPut(cell);
+            if (!slot.found_existing) slot.value_ptr.* = .{};
+            try slot.value_ptr.append(self.allocator, entity);
+            self.entity_cells[entity] = cell;
+        }
+
+        fn unlink(self: *Self, entity: EntityID) void {
+            const bucket = self.cells.getPtr(self.entity_cells[entity]) orelse return;
+            for (bucket.items, 0..) |member, i| {
+                if (member == entity) {
+                    _ = bucket.swapRemove(i);
+                    return;
+                }
+            }
+        }
+    };
+}
+
+/// Quadtree over entity `Transform` positions, for worlds where entities bunch up (towns, swarms,
+/// bullet patterns) and a uniform grid ends up with a few overfull cells and many empty ones.
+///
+/// The tree is rebuilt from scratch on every `sync` by partitioning a flat item array in place,
+/// so it needs no per-node allocations and its layout depends only on positions and entity order.
+/// Root bounds are the bounding box of all positions, so there is no world size to configure.
+/// Lookups have the same shape as `Grid` and fill the same `EntityBitSet`.
+pub fn Quadtree(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const EntitySet = EcsType.EntityBitSet;
+
+        pub const Options = struct {
+            /// Leaves holding more items than this are split (until max_depth)
+            max_items_per_leaf: u32 = 8,
+            max_depth: u8 = 10,
+        };
+
+        // Traversal stack holds at most three siblings per level plus the four children of a leaf parent
+        const MAX_SUPPORTED_DEPTH = 20;
+        const STACK_SIZE = MAX_SUPPORTED_DEPTH * 3 + 4;
+
+        const Circle = struct {
+            center: FPVector2,
+            radius_sq: FP,
+        };
+
+        pub const Item = struct {
+            entity: EntityID,
+            position: FPVector2,
+        };
+
+        pub const Node = struct {
+            min: FPVector2,
+            max: FPVector2,
+            /// Index of the first of four consecutive children, 0 for leaves
+            first_child: u32,
+            /// Range of `items` under this node
+            start: u32,
+            end: u32,
+        };
+
+        allocator: std.mem.Allocator,
+        options: Options,
+        nodes: std.ArrayList(Node),
+        items: std.ArrayList(Item),
+        scratch: std.ArrayList(Item),
+
+        pub fn init(allocator: std.mem.Allocator, options: Options) Self {
+            std.debug.assert(options.max_depth <= MAX_SUPPORTED_DEPTH);
+            return Self{
+                .allocator = allocator,
+                .options = options,
+                .nodes = std.ArrayList(Node).init(allocator),
+                .items = std.ArrayList(Item).init(allocator),
+                .scratch = std.ArrayList(Item).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.nodes.deinit();
+            self.items.deinit();
+            self.scratch.deinit();
+        }
+
+        /// Rebuild the tree from every entity holding a Transform
+        pub fn sync(self: *Self, frame: *EcsType.Frame) !void {
+            const storage = frame.getComponentStorage(Transform);
+            self.clear();
+
+            var iter = storage.entity_bitset.fastIterator();
+            while (iter.next()) |entity| {
+                try self.items.append(.{ .entity = entity, .position = storage.getDirectConst(entity).position });
+            }
+            if (self.items.items.len == 0) return;
+
+            var min = self.items.items[0].position;
+            var max = min;
+            for (self.items.items[1..]) |item| {
+                min = FPVector2.min(min, item.position);
+                max = FPVector2.max(max, item.position);
+            }
+
+            try self.scratch.resize(self.items.items.len);
+            try self.nodes.append(.{ .min = min, .max = max, .first_child = 0, .start = 0, .end = @intCast(self.items.items.len) });
+            try self.split(0, 0);
+        }
+
+        pub fn clear(self: *Self) void {
+            self.nodes.clearRetainingCapacity();
+            self.items.clearRetainingCapacity();
+        }
+
+        fn split(self: *Self, node_index: u32, depth: u8) std.mem.Allocator.Error!void {
+            const node = self.nodes.items[node_index];
+            const count = node.end - node.start;
+            if (count <= self.options.max_items_per_leaf or depth >= self.options.max_depth) return;
+
+            const mid = node.min.add(node.max).divInt(2);
+            // Every item sits on the split point - splitting further cannot separate them
+            if (mid.eq(node.min) or mid.eq(node.max)) return;
+
+            // Stable partition into quadrants (0: low x low y, 1: high x low y, 2: low x high y, 3: high x high y)
+            const items = self.items.items[node.start..node.end];
+            var counts = [_]u32{0} ** 4;
+            for (items) |item| counts[quadrantOf(mid, item.position)] += 1;
+
+            var offsets: [4]u32 = undefined;
+            var running: u32 = 0;
+            for (0..4) |q| {
+                offsets[q] = running;
+                running += counts[q];
+            }
+
+            const scratch = self.scratch.items[0..items.len];
+            var cursor = offsets;
+            for (items) |item| {
+                const q = quadrantOf(mid, item.position);
+                scratch[cursor[q]] = item;
+                cursor[q] += 1;
+            }
+            @memcpy(items, scratch);
+
+            const first_child: u32 = @intCast(self.nodes.items.len);
+            self.nodes.items[node_index].first_child = first_child;
+            for (0..4) |q| {
+                const high_x = q & 1 != 0;
+                const high_y = q & 2 != 0;
+                try self.nodes.append(.{
+                    .min = .{ .x = if (high_x) mid.x else node.min.x, .y = if (high_y) mid.y else node.min.y },
+                    .max = .{ .x = if (high_x) node.max.x else mid.x, .y = if (high_y) node.max.y else mid.y },
+                    .first_child = 0,
+                    .start = node.start + offsets[q],
+                    .end = node.start + offsets[q] + counts[q],
+                });
+            }
+            for (0..4) |q| {
+                try self.split(first_child + @as(u32, @intCast(q)), depth + 1);
+            }
+        }
+
+        fn quadrantOf(mid: FPVector2, position: FPVector2) usize {
+            const high_x: usize = if (position.x.gte(mid.x)) 1 else 0;
+            const high_y: usize = if (position.y.gte(mid.y)) 2 else 0;
+            return high_x | high_y;
+        }
+
+        /// Entities whose position lies inside the box (inclusive bounds)
+        pub fn queryAABB(self: *const Self, min: FPVector2, max: FPVector2, out: *EntitySet) void {
+            self.visit(min, max, null, out);
+        }
+
+        /// Entities within `radius` of `center` (inclusive)
+        pub fn queryRadius(self: *const Self, center: FPVector2, radius: FP, out: *EntitySet) void {
+            const extent = FPVector2.new(radius, radius);
+            self.visit(center.sub(extent), center.add(extent), .{ .center = center, .radius_sq = radius.mul(radius) }, out);
+        }
+
+        // Walk nodes overlapping [box_min, box_max]. With a circle, items are tested against it
+        // instead of the box.
+        fn visit(self: *const Self, box_min: FPVector2, box_max: FPVector2, circle: ?Circle, out: *EntitySet) void {
+            if (self.nodes.items.len == 0) return;
+
+            var stack: [STACK_SIZE]u32 = undefined;
+            var top: usize = 1;
+            stack[0] = 0;
+            while (top > 0) {
+                top -= 1;
+                const node = self.nodes.items[stack[top]];
+                if (node.max.x.lt(box_min.x) or node.min.x.gt(box_max.x) or
+                    node.max.y.lt(box_min.y) or node.min.y.gt(box_max.y)) continue;
+
+                if (node.first_child != 0) {
+                    for (0..4) |q| {
+                        stack[top] = node.first_child + @as(u32, @intCast(q));
+                        top += 1;
+                    }
+                    continue;
+                }
+
+                for (self.items.items[node.start..node.end]) |item| {
+                    const p = item.position;
+                    const inside = if (circle) |c|
+                        FPVector2.distanceSquared(c.center, p).lte(c.radius_sq)
+                    else
+                        p.x.gte(box_min.x) and p.x.lte(box_max.x) and p.y.gte(box_min.y) and p.y.lte(box_max.y);
+                    if (inside) out.set(item.entity);
+                }
+            }
+        }
+
+        pub fn trackedCount(self: *const Self) u32 {
+            return @intCast(self.items.items.len);
+        }
+
+        pub fn nodeCount(self: *const Self) u32 {
+            return @intCast(self.nodes.items.len);
+        }
+    };
+}
+
+/// Which spatial index a world uses
+pub const IndexKind = enum {
+    grid,
+    quadtree,
+};
+
+/// Spatial index selected per world at runtime. Systems take a `*SpatialIndex` and do not care
+/// which structure is behind it.
+pub fn SpatialIndex(comptime EcsType: type) type {
+    return union(IndexKind) {
+        const Self = @This();
+
+        pub const EntitySet = EcsType.EntityBitSet;
+
+        pub const Options = struct {
+            kind: IndexKind = .grid,
+            /// Grid cell size
+            cell_size: FP = FP.fromInt(4),
+            quadtree: Quadtree(EcsType).Options = .{},
+        };
+
+        grid: Grid(EcsType),
+        quadtree: Quadtree(EcsType),
+
+        pub fn init(allocator: std.mem.Allocator, options: Options) Self {
+            return switch (options.kind) {
+                .grid => .{ .grid = Grid(EcsType).init(allocator, options.cell_size) },
+                .quadtree => .{ .quadtree = Quadtree(EcsType).init(allocator, options.quadtree) },
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            switch (self.*) {
+                inline else => |*index| index.deinit(),
+            }
+        }
+
+        pub fn sync(self: *Self, frame: *EcsType.Frame) !void {
+            switch (self.*) {
+                inline else => |*index| try index.sync(frame),
+            }
+        }
+
+        pub fn queryAABB(self: *const Self, min: FPVector2, max: FPVector2, out: *EntitySet) void {
+            switch (self.*) {
+                inline else => |*index| index.queryAABB(min, max, out),
+            }
+        }
+
+        pub fn queryRadius(self: *const Self, center: FPVector2, radius: FP, out: *EntitySet) void {
+            switch (self.*) {
+                inline else => |*index| index.queryRadius(center, radius, out),
+            }
+        }
+
+        pub fn trackedCount(self: *const Self) u32 {
+            return switch (self.*) {
+                inline else => |*index| index.trackedCount(),
+            };
+        }
+    };
+}
@@ -0,0 +1,119 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const Transform = @import("components.zig").Transform;
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const PerfInput = struct {
+    deltaTime: f32 = 0.016,
+};
+
+const PerfECS = ecs.ECS(.{
+    .components = &.{Transform},
+    .input = PerfInput,
+    .max_entities = .massive,
+});
+
+const Index = spatial.SpatialIndex(PerfECS);
+
+const Distribution = enum {
+    uniform,
+    clustered,
+};
+
+const WORLD_HALF_SIZE = 1000;
+const ENTITY_COUNT = 4000;
+const QUERY_COUNT = 2000;
+const SYNC_ITERATIONS = 100;
+
+pub fn main() !void {
+    const allocator = std.heap.page_allocator;
+
+    std.debug.print("=== Spatial Index Performance Test ===\n", .{});
+    std.debug.print("{} entities, world +/-{}, {} radius queries\n", .{ ENTITY_COUNT, WORLD_HALF_SIZE, QUERY_COUNT });
+
+    for ([_]Distribution{ .uniform, .clustered }) |distribution| {
+        std.debug.print("\n--- {s} distribution ---\n", .{@tagName(distribution)});
+
+        var world = try PerfECS.init(allocator);
+        defer world.deinit();
+        try populate(world.getFrame(), distribution);
+
+        for ([_]spatial.IndexKind{ .grid, .quadtree }) |kind| {
+            var index = Index.init(allocator, .{ .kind = kind, .cell_size = fp(32) });
+            defer index.deinit();
+            try runBenchmark(&index, world.getFrame(), kind);
+        }
+    }
+}
+
+fn populate(frame: *PerfECS.Frame, distribution: Distribution) !void {
+    var prng = std.Random.DefaultPrng.init(1234);
+    const random = prng.random();
+
+    // Clustered: 90% of entities around 8 hotspots, the rest spread out
+    var hotspots: [8]FPVector2 = undefined;
+    for (&hotspots) |*hotspot| {
+        hotspot.* = randomPoint(random, WORLD_HALF_SIZE - 50);
+    }
+
+    for (0..ENTITY_COUNT) |i| {
+        const entity = try frame.createEntity();
+        const position = switch (distribution) {
+            .uniform => randomPoint(random, WORLD_HALF_SIZE),
+            .clustered => if (i % 10 == 0)
+                randomPoint(random, WORLD_HALF_SIZE)
+            else
+                hotspots[i % hotspots.len].add(randomPoint(random, 25)),
+        };
+        try frame.addComponent(entity, Transform{ .position = position });
+    }
+}
+
+fn randomPoint(random: std.Random, half_size: i32) FPVector2 {
+    return FPVector2.new(
+        FP.fromInt(random.intRangeAtMost(i32, -half_size, half_size)),
+        FP.fromInt(random.intRangeAtMost(i32, -half_size, half_size)),
+    );
+}
+
+fn runBenchmark(index: *Index, frame: *PerfECS.Frame, kind: spatial.IndexKind) !void {
+    // Initial build, then steady-state syncs with nothing moving
+    const build_start = std.time.nanoTimestamp();
+    try index.sync(frame);
+    const build_ns = std.time.nanoTimestamp() - build_start;
+
+    const sync_start = std.time.nanoTimestamp();
+    for (0..SYNC_ITERATIONS) |_| {
+        try index.sync(frame);
+    }
+    const sync_ns = std.time.nanoTimestamp() - sync_start;
+
+    // Queries centered on entities so clustered worlds hit their dense areas
+    const storage = frame.getComponentStorage(Transform);
+    const positions = storage.getDenseArray();
+    var found_total: u64 = 0;
+    var result = PerfECS.EntityBitSet.initEmpty();
+
+    const query_start = std.time.nanoTimestamp();
+    for (0..QUERY_COUNT) |i| {
+        result.clear();
+        index.queryRadius(positions[(i * 7919) % positions.len].position, fp(40), &result);
+        found_total += result.count();
+    }
+    const query_ns = std.time.nanoTimestamp() - query_start;
+
+    std.debug.print("{s:<9} build {d:>8.1}us  sync {d:>8.1}us  query {d:>6.2}us  ({d:.1} hits/query)\n", .{
+        @tagName(kind),
+        nsToUs(build_ns, 1),
+        nsToUs(sync_ns, SYNC_ITERATIONS),
+        nsToUs(query_ns, QUERY_COUNT),
+        @as(f64, @floatFromInt(found_total)) / QUERY_COUNT,
+    });
+}
+
+fn nsToUs(ns: i128, iterations: u32) f64 {
+    return @as(f64, @floatFromInt(ns)) / 1000.0 / @as(f64, @floatFromInt(iterations));
+}
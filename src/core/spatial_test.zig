@@ -0,0 +1,154 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const components = @import("components.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Enemy = struct { kind: u8 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Enemy },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestGrid = spatial.Grid(TestECS);
+
+test "Grid tracks moved, added and removed entities" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = TestGrid.init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    const frame = test_ecs.getFrame();
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(1, 1) });
+    try frame.addComponent(b, Transform{ .position = fpVec2(-1, 5) });
+
+    try grid.sync(frame);
+    try testing.expectEqual(@as(u32, 2), grid.relinked_last_sync);
+    try testing.expectEqualSlices(ecs.EntityID, &.{a}, grid.queryCell(.{ .x = 0, .y = 0 }));
+    try testing.expectEqualSlices(ecs.EntityID, &.{b}, grid.queryCell(.{ .x = -1, .y = 1 }));
+
+    // Moving within a cell does not relink
+    frame.getComponent(a, Transform).?.position = fpVec2(2, 2);
+    try grid.sync(frame);
+    try testing.expectEqual(@as(u32, 0), grid.relinked_last_sync);
+
+    // Crossing a cell boundary does
+    frame.getComponent(a, Transform).?.position = fpVec2(9, 2);
+    try grid.sync(frame);
+    try testing.expectEqual(@as(u32, 1), grid.relinked_last_sync);
+    try testing.expectEqual(@as(usize, 0), grid.queryCell(.{ .x = 0, .y = 0 }).len);
+    try testing.expectEqualSlices(ecs.EntityID, &.{a}, grid.queryCell(.{ .x = 2, .y = 0 }));
+
+    frame.destroyEntity(b);
+    try grid.sync(frame);
+    try testing.expectEqual(@as(u32, 1), grid.trackedCount());
+    try testing.expectEqual(@as(usize, 0), grid.queryCell(.{ .x = -1, .y = 1 }).len);
+}
+
+test "Grid radius and AABB lookups combine with queries" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var grid = TestGrid.init(testing.allocator, fp(4));
+    defer grid.deinit();
+
+    const frame = test_ecs.getFrame();
+    var entities: [10]ecs.EntityID = undefined;
+    for (&entities, 0..) |*entity, i| {
+        entity.* = try frame.createEntity();
+        try frame.addComponent(entity.*, Transform{ .position = .{ .x = FP.fromInt(@as(i32, @intCast(i * 3))), .y = fp(0) } });
+        if (i % 2 == 1) try frame.addComponent(entity.*, Enemy{ .kind = 0 });
+    }
+    try grid.sync(frame);
+
+    // Positions 0, 3, 6, ... 27 along the x axis
+    var nearby = TestGrid.EntitySet.initEmpty();
+    grid.queryRadius(fpVec2(6, 0), fp(6), &nearby);
+    try testing.expectEqual(@as(u32, 5), nearby.count()); // 0, 3, 6, 9, 12
+
+    var enemies = try frame.query(&.{ Transform, Enemy });
+    enemies.restrictTo(&nearby);
+    try testing.expectEqual(@as(u32, 2), enemies.count()); // 3 and 9
+
+    var boxed = TestGrid.EntitySet.initEmpty();
+    grid.queryAABB(fpVec2(10, -1), fpVec2(20, 1), &boxed);
+    try testing.expectEqual(@as(u32, 3), boxed.count()); // 12, 15, 18
+    try testing.expect(boxed.isSet(entities[4]));
+    try testing.expect(!boxed.isSet(entities[3]));
+}
+
+test "Quadtree lookups match the grid" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    var prng = std.Random.DefaultPrng.init(42);
+    const random = prng.random();
+    for (0..200) |i| {
+        const entity = try frame.createEntity();
+        // Half the entities packed into a small cluster
+        const spread: i32 = if (i % 2 == 0) 8 else 200;
+        const x = random.intRangeAtMost(i32, -spread, spread);
+        const y = random.intRangeAtMost(i32, -spread, spread);
+        try frame.addComponent(entity, Transform{ .position = .{ .x = FP.fromInt(x), .y = FP.fromInt(y) } });
+    }
+
+    var grid = TestGrid.init(testing.allocator, fp(16));
+    defer grid.deinit();
+    try grid.sync(frame);
+
+    var tree = spatial.Quadtree(TestECS).init(testing.allocator, .{ .max_items_per_leaf = 4 });
+    defer tree.deinit();
+    try tree.sync(frame);
+    try testing.expectEqual(@as(u32, 200), tree.trackedCount());
+    try testing.expect(tree.nodeCount() > 1);
+
+    const centers = [_]FPVector2{ fpVec2(0, 0), fpVec2(100, -50), fpVec2(-190, 190) };
+    for (centers) |center| {
+        var from_grid = TestGrid.EntitySet.initEmpty();
+        var from_tree = TestGrid.EntitySet.initEmpty();
+        grid.queryRadius(center, fp(30), &from_grid);
+        tree.queryRadius(center, fp(30), &from_tree);
+        try testing.expectEqualSlices(u64, &from_grid.words, &from_tree.words);
+
+        from_grid.clear();
+        from_tree.clear();
+        grid.queryAABB(center.sub(fpVec2(20, 10)), center.add(fpVec2(20, 10)), &from_grid);
+        tree.queryAABB(center.sub(fpVec2(20, 10)), center.add(fpVec2(20, 10)), &from_tree);
+        try testing.expectEqualSlices(u64, &from_grid.words, &from_tree.words);
+    }
+}
+
+test "Spatial index is selectable per world" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Transform{ .position = fpVec2(5, 5) });
+
+    for ([_]spatial.IndexKind{ .grid, .quadtree }) |kind| {
+        var index = spatial.SpatialIndex(TestECS).init(testing.allocator, .{ .kind = kind });
+        defer index.deinit();
+        try index.sync(frame);
+
+        var found = TestGrid.EntitySet.initEmpty();
+        index.queryRadius(fpVec2(4, 4), fp(2), &found);
+        try testing.expect(found.isSet(entity));
+        try testing.expectEqual(@as(u32, 1), index.trackedCount());
+    }
+}
@@ -0,0 +1,153 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const components = @import("components.zig");
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Steering = components.Steering;
+
+/// Velocity change that turns `velocity` toward full speed at `target`
+pub fn seek(position: FPVector2, velocity: FPVector2, target: FPVector2, max_speed: FP) FPVector2 {
+    return desiredAlong(target.sub(position), velocity, max_speed);
+}
+
+/// Velocity change that turns `velocity` toward full speed away from `threat`
+pub fn flee(position: FPVector2, velocity: FPVector2, threat: FPVector2, max_speed: FP) FPVector2 {
+    return desiredAlong(position.sub(threat), velocity, max_speed);
+}
+
+/// Like seek, but the desired speed falls off linearly inside `slowing_radius` so the agent stops on the target
+pub fn arrive(position: FPVector2, velocity: FPVector2, target: FPVector2, max_speed: FP, slowing_radius: FP) FPVector2 {
+    const offset = target.sub(position);
+    const distance = offset.magnitude();
+    if (distance.raw_value == 0) return velocity.negate();
+
+    var speed = max_speed;
+    if (distance.lt(slowing_radius)) {
+        speed = max_speed.mul(distance).div(slowing_radius);
+    }
+    return offset.mul(speed.div(distance)).sub(velocity);
+}
+
+// Full speed along `direction` minus the current velocity; zero direction means no preference
+fn desiredAlong(direction: FPVector2, velocity: FPVector2, max_speed: FP) FPVector2 {
+    if (direction.eq(FPVector2.ZERO)) return FPVector2.ZERO;
+    return direction.normalize().mul(max_speed).sub(velocity);
+}
+
+pub const SteeringConfig = struct {
+    /// Move Transform by the new velocity. Turn off when the physics module integrates positions.
+    integrate_positions: bool = true,
+};
+
+/// Drives Velocity from each entity's Steering component.
+///
+/// All forces are computed from the state at the start of the step and applied afterwards, so the
+/// result does not depend on the order agents are visited in. Neighbors for separation, alignment
+/// and cohesion come from a spatial index synced by the caller (`spatial.Grid`, `spatial.Quadtree`,
+/// `spatial.SpatialIndex`).
+pub fn SteeringSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        const Force = struct {
+            entity: EntityID,
+            force: FPVector2,
+        };
+
+        config: SteeringConfig,
+        forces: std.ArrayList(Force),
+
+        pub fn init(allocator: std.mem.Allocator, config: SteeringConfig) Self {
+            return Self{
+                .config = config,
+                .forces = std.ArrayList(Force).init(allocator),
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.forces.deinit();
+        }
+
+        pub fn step(self: *Self, frame: *EcsType.Frame, index: anytype, dt: FP) !void {
+            self.forces.clearRetainingCapacity();
+
+            var query = try frame.query(&.{ Transform, Velocity, Steering });
+            while (query.nextFast()) |result| {
+                const force = steeringForce(frame, index, result.entity, result.get(Transform).position, result.get(Velocity).linear, result.get(Steering).*);
+                try self.forces.append(.{ .entity = result.entity, .force = force });
+            }
+
+            for (self.forces.items) |entry| {
+                const steering = frame.getComponent(entry.entity, Steering).?;
+                const velocity = frame.getComponent(entry.entity, Velocity).?;
+                velocity.linear = velocity.linear.add(entry.force.mul(dt)).clampMagnitude(steering.max_speed);
+
+                if (self.config.integrate_positions) {
+                    const transform = frame.getComponent(entry.entity, Transform).?;
+                    transform.position = transform.position.add(velocity.linear.mul(dt));
+                }
+            }
+        }
+
+        fn steeringForce(frame: *EcsType.Frame, index: anytype, entity: EntityID, position: FPVector2, velocity: FPVector2, steering: Steering) FPVector2 {
+            var force = switch (steering.behavior) {
+                .none => FPVector2.ZERO,
+                .seek => seek(position, velocity, steering.target, steering.max_speed),
+                .flee => flee(position, velocity, steering.target, steering.max_speed),
+                .arrive => arrive(position, velocity, steering.target, steering.max_speed, steering.slowing_radius),
+            };
+
+            const flocking = steering.separation_weight.raw_value != 0 or
+                steering.alignment_weight.raw_value != 0 or
+                steering.cohesion_weight.raw_value != 0;
+            if (flocking) {
+                force = force.add(flockForce(frame, index, entity, position, velocity, steering));
+            }
+            return force.clampMagnitude(steering.max_force);
+        }
+
+        fn flockForce(frame: *EcsType.Frame, index: anytype, entity: EntityID, position: FPVector2, velocity: FPVector2, steering: Steering) FPVector2 {
+            var neighbors = EcsType.EntityBitSet.initEmpty();
+            index.queryRadius(position, steering.neighbor_radius, &neighbors);
+
+            var away = FPVector2.ZERO;
+            var heading_sum = FPVector2.ZERO;
+            var center_sum = FPVector2.ZERO;
+            var count: i32 = 0;
+
+            var iter = neighbors.fastIterator();
+            while (iter.next()) |other| {
+                if (other == entity) continue;
+                const other_steering = frame.getComponent(other, Steering) orelse continue;
+                if (other_steering.flock != steering.flock) continue;
+                const other_position = (frame.getComponent(other, Transform) orelse continue).position;
+                const other_velocity = if (frame.getComponent(other, Velocity)) |v| v.linear else FPVector2.ZERO;
+
+                // Push harder the closer the neighbor is
+                const offset = position.sub(other_position);
+                const distance = offset.magnitude();
+                if (distance.lt(steering.separation_radius) and distance.raw_value > 0) {
+                    away = away.add(offset.div(distance).mul(fp(1).sub(distance.div(steering.separation_radius))));
+                }
+
+                heading_sum = heading_sum.add(other_velocity);
+                center_sum = center_sum.add(other_position);
+                count += 1;
+            }
+            if (count == 0) return FPVector2.ZERO;
+
+            const separation = desiredAlong(away, velocity, steering.max_speed);
+            const alignment = desiredAlong(heading_sum, velocity, steering.max_speed);
+            const cohesion = seek(position, velocity, center_sum.divInt(count), steering.max_speed);
+
+            return separation.mul(steering.separation_weight)
+                .add(alignment.mul(steering.alignment_weight))
+                .add(cohesion.mul(steering.cohesion_weight));
+        }
+    };
+}
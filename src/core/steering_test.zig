@@ -0,0 +1,107 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const spatial = @import("spatial.zig");
+const components = @import("components.zig");
+const steering = @import("steering.zig");
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Velocity = components.Velocity;
+const Steering = components.Steering;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Velocity, Steering },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestSteering = steering.SteeringSystem(TestECS);
+const dt = fp(1.0 / 60.0);
+
+fn expectVec(expected: FPVector2, actual: FPVector2) !void {
+    try testing.expectEqual(expected.x.raw_value, actual.x.raw_value);
+    try testing.expectEqual(expected.y.raw_value, actual.y.raw_value);
+}
+
+test "Seek, flee and arrive" {
+    const origin = fpVec2(0, 0);
+    const still = FPVector2.ZERO;
+
+    try expectVec(fpVec2(5, 0), steering.seek(origin, still, fpVec2(10, 0), fp(5)));
+    try expectVec(fpVec2(-5, 0), steering.flee(origin, still, fpVec2(10, 0), fp(5)));
+    // Already moving at full speed toward the target - nothing to change
+    try expectVec(fpVec2(0, 0), steering.seek(origin, fpVec2(5, 0), fpVec2(10, 0), fp(5)));
+
+    // Half way into the slowing radius wants half speed
+    try expectVec(fpVec2(2.5, 0), steering.arrive(origin, still, fpVec2(1, 0), fp(5), fp(2)));
+    // On the target: cancel the remaining velocity
+    try expectVec(fpVec2(-1, 2), steering.arrive(origin, fpVec2(1, -2), origin, fp(5), fp(2)));
+}
+
+test "Arriving agent settles on its target" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var system = TestSteering.init(testing.allocator, .{});
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const agent = try frame.createEntity();
+    try frame.addComponent(agent, Transform{});
+    try frame.addComponent(agent, Velocity{});
+    try frame.addComponent(agent, Steering{ .behavior = .arrive, .target = fpVec2(10, 0) });
+
+    for (0..300) |_| {
+        try grid.sync(frame);
+        try system.step(frame, &grid, dt);
+        // Never exceeds its top speed
+        try testing.expect(frame.getComponent(agent, Velocity).?.linear.magnitude().lte(fp(5.01)));
+    }
+
+    const position = frame.getComponent(agent, Transform).?.position;
+    try testing.expect(position.approxEq(fpVec2(10, 0), fp(0.1)));
+    try testing.expect(frame.getComponent(agent, Velocity).?.linear.magnitude().lt(fp(0.1)));
+}
+
+test "Separation pushes flockmates apart symmetrically" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var grid = spatial.Grid(TestECS).init(testing.allocator, fp(4));
+    defer grid.deinit();
+    var system = TestSteering.init(testing.allocator, .{});
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const flockmate = Steering{ .separation_weight = fp(1) };
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    const stranger = try frame.createEntity();
+    try frame.addComponent(a, Transform{ .position = fpVec2(-0.25, 0) });
+    try frame.addComponent(b, Transform{ .position = fpVec2(0.25, 0) });
+    try frame.addComponent(stranger, Transform{ .position = fpVec2(0, 0.25) });
+    for ([_]ecs.EntityID{ a, b }) |entity| {
+        try frame.addComponent(entity, Velocity{});
+        try frame.addComponent(entity, flockmate);
+    }
+    try frame.addComponent(stranger, Velocity{});
+    try frame.addComponent(stranger, Steering{ .separation_weight = fp(1), .flock = 1 });
+
+    try grid.sync(frame);
+    try system.step(frame, &grid, dt);
+
+    const va = frame.getComponent(a, Velocity).?.linear;
+    const vb = frame.getComponent(b, Velocity).?.linear;
+    try testing.expect(va.x.raw_value < 0);
+    try testing.expectEqual(va.x.negate().raw_value, vb.x.raw_value);
+    try testing.expectEqual(@as(i64, 0), va.y.raw_value);
+    // Different flock - ignores both
+    try expectVec(FPVector2.ZERO, frame.getComponent(stranger, Velocity).?.linear);
+}
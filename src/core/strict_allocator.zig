@@ -0,0 +1,94 @@
+const std = @import("std");
+
+/// What a locked `StrictAllocator` does with a request for more memory
+pub const Violation = enum {
+    /// Stop with the size and return address of the offending allocation
+    panic,
+    /// Fail the request like an exhausted allocator (error.OutOfMemory for the caller)
+    fail,
+};
+
+/// Allocator wrapper for latency-critical servers that must not allocate once running.
+///
+/// Hand it to everything built at startup - world, rollback history, spatial grids, pathfinder
+/// pools - then `lock` it before the first tick. From then on any request for more memory is a
+/// bug: it panics in safe builds and fails in release ones, so a steady-state frame never reaches
+/// the system allocator. Frees and shrinks still pass through.
+///
+/// Usage:
+///   var strict = StrictAllocator.init(std.heap.page_allocator);
+///   var world = try GameECS.initStrict(strict.allocator(), history_len);
+///   var grid = try SpatialGrid.init(strict.allocator(), ...);
+///   strict.lock();
+pub const StrictAllocator = struct {
+    child: std.mem.Allocator,
+    locked: bool = false,
+    on_violation: Violation = if (std.debug.runtime_safety) .panic else .fail,
+    /// Requests refused since `lock` (only ever nonzero with `.fail`)
+    violations: u32 = 0,
+
+    pub fn init(child: std.mem.Allocator) StrictAllocator {
+        return .{ .child = child };
+    }
+
+    pub fn allocator(self: *StrictAllocator) std.mem.Allocator {
+        return .{
+            .ptr = self,
+            .vtable = &.{
+                .alloc = alloc,
+                .resize = resize,
+                .remap = remap,
+                .free = free,
+            },
+        };
+    }
+
+    /// End of startup - every later allocation is a violation
+    pub fn lock(self: *StrictAllocator) void {
+        self.locked = true;
+    }
+
+    /// Allow allocations again, e.g. while loading the next level
+    pub fn unlock(self: *StrictAllocator) void {
+        self.locked = false;
+    }
+
+    fn refuse(self: *StrictAllocator, len: usize, ret_addr: usize) void {
+        self.violations += 1;
+        if (self.on_violation == .panic) {
+            std.debug.panic("allocation of {} bytes after StrictAllocator.lock (from 0x{x})", .{ len, ret_addr });
+        }
+    }
+
+    fn alloc(ctx: *anyopaque, len: usize, alignment: std.mem.Alignment, ret_addr: usize) ?[*]u8 {
+        const self: *StrictAllocator = @ptrCast(@alignCast(ctx));
+        if (self.locked) {
+            self.refuse(len, ret_addr);
+            return null;
+        }
+        return self.child.rawAlloc(len, alignment, ret_addr);
+    }
+
+    fn resize(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, new_len: usize, ret_addr: usize) bool {
+        const self: *StrictAllocator = @ptrCast(@alignCast(ctx));
+        if (self.locked and new_len > memory.len) {
+            self.refuse(new_len, ret_addr);
+            return false;
+        }
+        return self.child.rawResize(memory, alignment, new_len, ret_addr);
+    }
+
+    fn remap(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, new_len: usize, ret_addr: usize) ?[*]u8 {
+        const self: *StrictAllocator = @ptrCast(@alignCast(ctx));
+        if (self.locked and new_len > memory.len) {
+            self.refuse(new_len, ret_addr);
+            return null;
+        }
+        return self.child.rawRemap(memory, alignment, new_len, ret_addr);
+    }
+
+    fn free(ctx: *anyopaque, memory: []u8, alignment: std.mem.Alignment, ret_addr: usize) void {
+        const self: *StrictAllocator = @ptrCast(@alignCast(ctx));
+        self.child.rawFree(memory, alignment, ret_addr);
+    }
+};
@@ -0,0 +1,67 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const StrictAllocator = @import("strict_allocator.zig").StrictAllocator;
+
+const Position = struct { x: i32, y: i32 };
+const Health = struct { value: i32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Health },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+test "Locked allocators refuse growth but still free" {
+    var strict = StrictAllocator.init(testing.allocator);
+    strict.on_violation = .fail;
+    const allocator = strict.allocator();
+
+    var list = std.ArrayList(u32).init(allocator);
+    defer list.deinit();
+    try list.ensureTotalCapacity(16);
+    const scratch = try allocator.alloc(u8, 64);
+
+    strict.lock();
+    for (0..16) |i| list.appendAssumeCapacity(@intCast(i));
+    try testing.expectError(error.OutOfMemory, list.append(16));
+    try testing.expectEqual(@as(usize, 16), list.items.len);
+    try testing.expect(strict.violations > 0);
+    allocator.free(scratch);
+
+    strict.unlock();
+    try list.append(16);
+}
+
+test "Strict worlds run steady-state ticks from startup memory" {
+    var strict = StrictAllocator.init(testing.allocator);
+    strict.on_violation = .fail;
+    var world = try TestECS.initStrict(strict.allocator(), 4);
+    defer world.deinit();
+    strict.lock();
+
+    const frame = world.getFrame();
+    for (0..TestECS.max_entities) |i| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = @intCast(i), .y = 0 });
+    }
+    for (0..20) |tick| {
+        world.update(.{}, 0.016, 0.016);
+        try world.saveSnapshot(tick % 4);
+        var query = try frame.query(&.{Position});
+        while (query.nextFast()) |result| {
+            result.get(Position).y += 1;
+            if (result.entity % 5 == tick % 5) {
+                if (!frame.removeComponent(result.entity, Health)) try frame.addComponent(result.entity, Health{ .value = 1 });
+            }
+        }
+    }
+    // Slot 1 was last saved at the start of tick 17
+    try world.restoreSnapshot(1);
+    try testing.expectEqual(@as(i32, 17), frame.getComponent(0, Position).?.y);
+    try testing.expectEqual(@as(u32, 0), strict.violations);
+}
@@ -0,0 +1,374 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+const schema = @import("schema.zig");
+const components = @import("components.zig");
+const parseFixed = @import("script.zig").parseFixed;
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+
+const EntityID = ecs.EntityID;
+const Transform = components.Transform;
+const Collider = components.Collider;
+const ObjectMap = std.json.ObjectMap;
+const JsonValue = std.json.Value;
+
+// Tiled stores flip and rotation flags in the top bits of a tile gid
+const GID_MASK: u32 = 0x0FFF_FFFF;
+
+pub const ImportOptions = struct {
+    /// World units per tile. Object coordinates are pixels and scale by `tile_size / tilewidth`.
+    tile_size: FP = fp(1),
+    /// Tiled's y axis points down - flip it so the top row of the map is the highest in a y-up world
+    flip_y: bool = true,
+    /// Boolean property that gives a tile or object a box (or circle, for ellipses) Collider
+    collides_property: []const u8 = "collides",
+    diagnostic: ?*Diagnostic = null,
+};
+
+/// Where an import failed - the layer and, for property errors, the property name
+pub const Diagnostic = struct {
+    layer: [64]u8 = undefined,
+    layer_len: usize = 0,
+    property: [64]u8 = undefined,
+    property_len: usize = 0,
+
+    pub fn layerName(self: *const Diagnostic) []const u8 {
+        return self.layer[0..self.layer_len];
+    }
+
+    pub fn propertyName(self: *const Diagnostic) []const u8 {
+        return self.property[0..self.property_len];
+    }
+
+    fn set(buffer: []u8, len: *usize, text: []const u8) void {
+        len.* = @min(text.len, buffer.len);
+        @memcpy(buffer[0..len.*], text[0..len.*]);
+    }
+};
+
+pub const ImportResult = struct {
+    tiles: u32 = 0,
+    objects: u32 = 0,
+};
+
+/// Imports Tiled maps saved as JSON (.tmj) into a frame.
+///
+/// Every non-empty tile becomes an entity with a Transform at the tile's center; every object
+/// becomes one at its center (rectangles and ellipses) or position (points and polygons).
+/// Custom properties drive the rest through the schema registry:
+///
+///   collides         bool    add a Collider covering the tile or object
+///   Health           bool    add the component with its default values
+///   Health           class   add the component and set the class members as fields
+///   Health.current   int     set one field, adding the component with defaults first
+///   Body.offset.x    float   set one axis of a vector field
+///
+/// Floats are parsed from the JSON text with integer math, so a map imports to the same raw
+/// values on every platform. Layer properties apply to every tile or object in the layer, then
+/// tile (tileset) and object properties on top - including `collides`, which a tile or object can
+/// turn back off. Components without defaults for every field
+/// can only be added through the `collides` property (Collider) or by game code.
+///
+/// TMX maps need saving as JSON first (File > Export As > .tmj in Tiled). Tilesets must be
+/// embedded, tile layers CSV-encoded and the map finite.
+pub fn TiledImporter(comptime EcsType: type) type {
+    const Schema = schema.Registry(EcsType);
+    const Types = EcsType.component_types;
+
+    return struct {
+        const Self = @This();
+
+        frame: *EcsType.Frame,
+        options: ImportOptions,
+        root: ObjectMap,
+        result: ImportResult = .{},
+        map_height: i64 = 0,
+        tile_width: i64 = 0,
+        tile_height: i64 = 0,
+
+        pub fn import(allocator: std.mem.Allocator, frame: *EcsType.Frame, source: []const u8, options: ImportOptions) !ImportResult {
+            // Numbers stay text so fractional coordinates convert without floats
+            const parsed = std.json.parseFromSlice(JsonValue, allocator, source, .{ .parse_numbers = false }) catch return error.InvalidMap;
+            defer parsed.deinit();
+            if (parsed.value != .object) return error.InvalidMap;
+
+            var importer = Self{
+                .frame = frame,
+                .options = options,
+                .root = parsed.value.object,
+            };
+            if (importer.root.get("infinite")) |infinite| {
+                if (infinite == .bool and infinite.bool) return error.UnsupportedInfiniteMap;
+            }
+            importer.map_height = try intOf(try member(importer.root, "height"));
+            importer.tile_width = try intOf(try member(importer.root, "tilewidth"));
+            importer.tile_height = try intOf(try member(importer.root, "tileheight"));
+            if (importer.tile_width <= 0 or importer.tile_height <= 0) return error.InvalidMap;
+
+            if (importer.root.get("tilesets")) |tilesets| {
+                for (try arrayOf(tilesets)) |tileset| {
+                    if ((try objectOf(tileset)).get("source") != null) return error.ExternalTileset;
+                }
+            }
+            try importer.importLayers(try arrayOf(try member(importer.root, "layers")));
+            return importer.result;
+        }
+
+        fn importLayers(self: *Self, layers: []const JsonValue) !void {
+            for (layers) |layer_value| {
+                const layer = try objectOf(layer_value);
+                if (self.options.diagnostic) |diagnostic| {
+                    const name = if (layer.get("name")) |value| stringOf(value) catch "" else "";
+                    Diagnostic.set(&diagnostic.layer, &diagnostic.layer_len, name);
+                }
+
+                const kind = try stringOf(try member(layer, "type"));
+                if (std.mem.eql(u8, kind, "tilelayer")) {
+                    try self.importTiles(layer);
+                } else if (std.mem.eql(u8, kind, "objectgroup")) {
+                    try self.importObjects(layer);
+                } else if (std.mem.eql(u8, kind, "group")) {
+                    try self.importLayers(try arrayOf(try member(layer, "layers")));
+                }
+                // Image layers are presentation only
+            }
+        }
+
+        fn importTiles(self: *Self, layer: ObjectMap) !void {
+            if (layer.get("encoding")) |encoding| {
+                if (!std.mem.eql(u8, try stringOf(encoding), "csv")) return error.UnsupportedEncoding;
+            }
+            if (layer.get("chunks") != null) return error.UnsupportedInfiniteMap;
+            const width = try intOf(try member(layer, "width"));
+            if (width <= 0) return error.InvalidMap;
+            const half = self.options.tile_size.div(fp(2));
+
+            for (try arrayOf(try member(layer, "data")), 0..) |cell, i| {
+                const gid = std.math.cast(u32, try intOf(cell)) orelse return error.InvalidMap;
+                if (gid & GID_MASK == 0) continue;
+                const column: i64 = @intCast(i % @as(usize, @intCast(width)));
+                const row: i64 = @intCast(i / @as(usize, @intCast(width)));
+                const tile_row = if (self.options.flip_y) self.map_height - 1 - row else row;
+
+                const entity = try self.frame.createEntity();
+                try self.frame.addComponent(entity, Transform{ .position = FPVector2.new(
+                    self.options.tile_size.mul(FP.fromInt(column)).add(half),
+                    self.options.tile_size.mul(FP.fromInt(tile_row)).add(half),
+                ) });
+
+                var collides = false;
+                if (layer.get("properties")) |properties| {
+                    if (try self.applyProperties(entity, properties)) |set| collides = set;
+                }
+                if (try self.tileProperties(gid & GID_MASK)) |properties| {
+                    if (try self.applyProperties(entity, properties)) |set| collides = set;
+                }
+                if (collides) try self.addCollider(entity, Collider.box(FPVector2.new(half, half)));
+                self.result.tiles += 1;
+            }
+        }
+
+        fn importObjects(self: *Self, layer: ObjectMap) !void {
+            for (try arrayOf(try member(layer, "objects"))) |object_value| {
+                const object = try objectOf(object_value);
+                const width = try self.toWorld(object.get("width") orelse JsonValue{ .integer = 0 }, self.tile_width);
+                const height = try self.toWorld(object.get("height") orelse JsonValue{ .integer = 0 }, self.tile_height);
+                var x = try self.toWorld(try member(object, "x"), self.tile_width);
+                var y = try self.toWorld(try member(object, "y"), self.tile_height);
+
+                const is_point = boolMember(object, "point");
+                const is_ellipse = boolMember(object, "ellipse");
+                const is_shape = object.get("polygon") != null or object.get("polyline") != null;
+                // Tile objects are anchored at their bottom-left corner, everything else at the top-left
+                if (object.get("gid") != null) y = y.sub(height);
+                if (!is_point and !is_shape) {
+                    x = x.add(width.div(fp(2)));
+                    y = y.add(height.div(fp(2)));
+                }
+                if (self.options.flip_y) {
+                    y = self.options.tile_size.mul(FP.fromInt(self.map_height)).sub(y);
+                }
+
+                const entity = try self.frame.createEntity();
+                try self.frame.addComponent(entity, Transform{ .position = FPVector2.new(x, y) });
+
+                var collides = false;
+                if (layer.get("properties")) |properties| {
+                    if (try self.applyProperties(entity, properties)) |set| collides = set;
+                }
+                if (object.get("properties")) |properties| {
+                    if (try self.applyProperties(entity, properties)) |set| collides = set;
+                }
+                if (collides and !is_point and !is_shape) {
+                    const collider = if (is_ellipse)
+                        Collider.circle(FP.min(width, height).div(fp(2)))
+                    else
+                        Collider.box(FPVector2.new(width.div(fp(2)), height.div(fp(2))));
+                    try self.addCollider(entity, collider);
+                }
+                self.result.objects += 1;
+            }
+        }
+
+        /// Properties of an embedded tileset tile, if it has any
+        fn tileProperties(self: *Self, gid: u32) !?JsonValue {
+            const tilesets = self.root.get("tilesets") orelse return null;
+            var best: ?ObjectMap = null;
+            var best_first: i64 = 0;
+            for (try arrayOf(tilesets)) |tileset_value| {
+                const tileset = try objectOf(tileset_value);
+                const first = try intOf(try member(tileset, "firstgid"));
+                if (first <= gid and first >= best_first) {
+                    best = tileset;
+                    best_first = first;
+                }
+            }
+            const tileset = best orelse return null;
+            const tiles = tileset.get("tiles") orelse return null;
+            for (try arrayOf(tiles)) |tile_value| {
+                const tile = try objectOf(tile_value);
+                if (try intOf(try member(tile, "id")) == gid - best_first) return tile.get("properties");
+            }
+            return null;
+        }
+
+        /// Apply a Tiled property list to the entity. Returns the collides property, null when the
+        /// list doesn't have it - callers apply the layer's, then the tile's or object's, so the
+        /// more specific source overrides in either direction.
+        fn applyProperties(self: *Self, entity: EntityID, properties: JsonValue) !?bool {
+            var collides: ?bool = null;
+            for (try arrayOf(properties)) |property_value| {
+                const property = try objectOf(property_value);
+                const name = try stringOf(try member(property, "name"));
+                const value = try member(property, "value");
+                if (self.options.diagnostic) |diagnostic| Diagnostic.set(&diagnostic.property, &diagnostic.property_len, name);
+
+                if (std.mem.eql(u8, name, self.options.collides_property)) {
+                    collides = value == .bool and value.bool;
+                    continue;
+                }
+
+                const dot = std.mem.indexOfScalar(u8, name, '.') orelse name.len;
+                const component = Schema.componentIndex(name[0..dot]) orelse return error.UnknownComponent;
+                if (dot < name.len) {
+                    try ensureComponent(self.frame, entity, component);
+                    try setFieldPath(self.frame, entity, component, name[dot + 1 ..], value);
+                } else switch (value) {
+                    .bool => |add| if (add) try ensureComponent(self.frame, entity, component),
+                    .object => |members| {
+                        try ensureComponent(self.frame, entity, component);
+                        var it = members.iterator();
+                        while (it.next()) |entry| try setFieldPath(self.frame, entity, component, entry.key_ptr.*, entry.value_ptr.*);
+                    },
+                    else => return error.TypeMismatch,
+                }
+            }
+            return collides;
+        }
+
+        fn addCollider(self: *Self, entity: EntityID, collider: Collider) !void {
+            if (comptime !registered(Collider)) return error.ColliderNotRegistered;
+            if (self.frame.getComponent(entity, Collider)) |existing| {
+                // Layer and mask may already come from properties - only the shape is the map's
+                existing.shape = collider.shape;
+            } else {
+                try self.frame.addComponent(entity, collider);
+            }
+        }
+
+        fn toWorld(self: *Self, pixels: JsonValue, tile_pixels: i64) !FP {
+            return self.options.tile_size.mul(try fixedOf(pixels)).div(FP.fromInt(tile_pixels));
+        }
+
+        fn registered(comptime T: type) bool {
+            inline for (Types) |Registered| {
+                if (Registered == T) return true;
+            }
+            return false;
+        }
+
+        fn ensureComponent(frame: *EcsType.Frame, entity: EntityID, component: usize) !void {
+            inline for (Types, 0..) |T, i| {
+                if (i == component) {
+                    if (frame.hasComponent(entity, T)) return;
+                    if (comptime !defaultable(T)) return error.ComponentNeedsDefaults;
+                    return frame.addComponent(entity, T{});
+                }
+            }
+        }
+
+        fn setFieldPath(frame: *EcsType.Frame, entity: EntityID, component: usize, path: []const u8, value: JsonValue) !void {
+            const fields = Schema.components[component];
+            if (fields.fieldIndex(path)) |field| {
+                return Schema.setField(frame, entity, component, field, try toValue(fields.fields[field].kind, value));
+            }
+
+            // `field.x` / `field.y` on a vector
+            if (path.len < 3 or path[path.len - 2] != '.') return error.UnknownField;
+            const field = fields.fieldIndex(path[0 .. path.len - 2]) orelse return error.UnknownField;
+            const current = Schema.getField(frame, entity, component, field) orelse return error.NotAddressable;
+            if (current != .vector2) return error.TypeMismatch;
+            var vector = current.vector2;
+            switch (path[path.len - 1]) {
+                'x' => vector.x = try fixedOf(value),
+                'y' => vector.y = try fixedOf(value),
+                else => return error.UnknownField,
+            }
+            try Schema.setField(frame, entity, component, field, .{ .vector2 = vector });
+        }
+    };
+}
+
+fn defaultable(comptime T: type) bool {
+    for (schema.runtimeFields(T)) |field| {
+        if (field.default_value_ptr == null) return false;
+    }
+    return true;
+}
+
+fn toValue(kind: schema.FieldKind, value: JsonValue) !schema.Value {
+    return switch (kind) {
+        .int, .uint, .enumeration => .{ .int = try intOf(value) },
+        .boolean => if (value == .bool) .{ .boolean = value.bool } else error.TypeMismatch,
+        .fixed => .{ .fixed = try fixedOf(value) },
+        .vector2, .float, .composite => error.NotAddressable,
+    };
+}
+
+fn member(object: ObjectMap, name: []const u8) !JsonValue {
+    return object.get(name) orelse error.InvalidMap;
+}
+
+fn boolMember(object: ObjectMap, name: []const u8) bool {
+    const value = object.get(name) orelse return false;
+    return value == .bool and value.bool;
+}
+
+fn objectOf(value: JsonValue) !ObjectMap {
+    return if (value == .object) value.object else error.InvalidMap;
+}
+
+fn arrayOf(value: JsonValue) ![]const JsonValue {
+    return if (value == .array) value.array.items else error.InvalidMap;
+}
+
+fn stringOf(value: JsonValue) ![]const u8 {
+    return if (value == .string) value.string else error.InvalidMap;
+}
+
+fn intOf(value: JsonValue) !i64 {
+    return switch (value) {
+        .integer => |v| v,
+        .number_string => |text| std.fmt.parseInt(i64, text, 10) catch error.TypeMismatch,
+        else => error.TypeMismatch,
+    };
+}
+
+fn fixedOf(value: JsonValue) !FP {
+    return switch (value) {
+        .integer => |v| FP.fromInt(v),
+        .number_string => |text| parseFixed(text) catch error.TypeMismatch,
+        else => error.TypeMismatch,
+    };
+}
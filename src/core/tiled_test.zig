@@ -0,0 +1,159 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const tiled = @import("tiled.zig");
+const components = @import("components.zig");
+const FP = @import("fixed-math/FP.zig").FP;
+const fp = @import("fixed-math/FP.zig").fp;
+const FPVector2 = @import("fixed-math/FPVector2.zig").FPVector2;
+const fpVec2 = @import("fixed-math/FPVector2.zig").fpVec2;
+
+const Transform = components.Transform;
+const Collider = components.Collider;
+
+const Health = struct {
+    current: i32 = 100,
+    max: i32 = 100,
+};
+
+const Marker = struct {
+    tag: u8 = 0,
+};
+
+const Spawn = struct {
+    offset: FPVector2 = FPVector2.ZERO,
+};
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Transform, Collider, Health, Marker, Spawn },
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const Importer = tiled.TiledImporter(TestECS);
+
+// 3x2 tiles of 16px. Tile 2 (local id 1) is solid and tagged; one rectangle and one point object.
+const map =
+    \\{
+    \\  "width": 3, "height": 2, "tilewidth": 16, "tileheight": 16, "infinite": false,
+    \\  "tilesets": [{
+    \\    "firstgid": 1, "name": "terrain",
+    \\    "tiles": [{ "id": 1, "properties": [
+    \\      { "name": "collides", "type": "bool", "value": true },
+    \\      { "name": "Marker.tag", "type": "int", "value": 4 }
+    \\    ] }]
+    \\  }],
+    \\  "layers": [
+    \\    { "type": "tilelayer", "name": "ground", "width": 3, "height": 2, "data": [1, 0, 2, 0, 0, 1] },
+    \\    { "type": "objectgroup", "name": "actors", "objects": [
+    \\      { "id": 1, "x": 16, "y": 0, "width": 32, "height": 16, "properties": [
+    \\        { "name": "collides", "type": "bool", "value": true },
+    \\        { "name": "Health", "type": "class", "value": { "current": 50 } }
+    \\      ] },
+    \\      { "id": 2, "x": 8.0, "y": 24, "point": true, "properties": [
+    \\        { "name": "Spawn.offset.x", "type": "float", "value": 0.25 }
+    \\      ] }
+    \\    ] }
+    \\  ]
+    \\}
+;
+
+test "Tiles and objects become entities" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    const result = try Importer.import(testing.allocator, frame, map, .{});
+    try testing.expectEqual(@as(u32, 3), result.tiles);
+    try testing.expectEqual(@as(u32, 2), result.objects);
+    try testing.expectEqual(@as(u32, 5), frame.getEntityCount());
+
+    // Row 0 is the top of the map
+    try testing.expectEqual(fpVec2(0.5, 1.5), frame.getComponent(0, Transform).?.position);
+    try testing.expectEqual(fpVec2(2.5, 1.5), frame.getComponent(1, Transform).?.position);
+    try testing.expectEqual(fpVec2(2.5, 0.5), frame.getComponent(2, Transform).?.position);
+    try testing.expect(!frame.hasComponent(0, Collider));
+    try testing.expectEqual(fpVec2(0.5, 0.5), frame.getComponent(1, Collider).?.shape.box);
+    try testing.expectEqual(@as(u8, 4), frame.getComponent(1, Marker).?.tag);
+
+    const rectangle = 3;
+    try testing.expectEqual(fpVec2(2, 1.5), frame.getComponent(rectangle, Transform).?.position);
+    try testing.expectEqual(fpVec2(1, 0.5), frame.getComponent(rectangle, Collider).?.shape.box);
+    try testing.expectEqual(Health{ .current = 50, .max = 100 }, frame.getComponent(rectangle, Health).?.*);
+
+    const point = 4;
+    try testing.expectEqual(fpVec2(0.5, 0.5), frame.getComponent(point, Transform).?.position);
+    try testing.expectEqual(fpVec2(0.25, 0), frame.getComponent(point, Spawn).?.offset);
+    try testing.expect(!frame.hasComponent(point, Collider));
+}
+
+test "Tiles and objects override the layer's collides property" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+
+    // The whole layer collides except tile 2, which is decoration; the second object opts back in
+    _ = try Importer.import(testing.allocator, frame,
+        \\{
+        \\  "width": 2, "height": 1, "tilewidth": 16, "tileheight": 16,
+        \\  "tilesets": [{ "firstgid": 1, "tiles": [
+        \\    { "id": 1, "properties": [{ "name": "collides", "type": "bool", "value": false }] }
+        \\  ] }],
+        \\  "layers": [
+        \\    { "type": "tilelayer", "name": "walls", "width": 2, "height": 1, "data": [1, 2], "properties": [
+        \\      { "name": "collides", "type": "bool", "value": true }
+        \\    ] },
+        \\    { "type": "objectgroup", "name": "props", "properties": [
+        \\      { "name": "collides", "type": "bool", "value": false }
+        \\    ], "objects": [
+        \\      { "id": 1, "x": 0, "y": 0, "width": 16, "height": 16 },
+        \\      { "id": 2, "x": 16, "y": 0, "width": 16, "height": 16, "properties": [
+        \\        { "name": "collides", "type": "bool", "value": true }
+        \\      ] }
+        \\    ] }
+        \\  ]
+        \\}
+    , .{});
+
+    try testing.expect(frame.hasComponent(0, Collider));
+    try testing.expect(!frame.hasComponent(1, Collider));
+    try testing.expect(!frame.hasComponent(2, Collider));
+    try testing.expect(frame.hasComponent(3, Collider));
+}
+
+test "Import errors name the layer and property" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    const frame = test_ecs.getFrame();
+    var diagnostic = tiled.Diagnostic{};
+
+    try testing.expectError(error.UnknownComponent, Importer.import(testing.allocator, frame,
+        \\{ "height": 1, "tilewidth": 8, "tileheight": 8, "layers": [
+        \\  { "type": "objectgroup", "name": "enemies", "objects": [
+        \\    { "x": 0, "y": 0, "properties": [{ "name": "Armor.value", "type": "int", "value": 2 }] }
+        \\  ] }
+        \\] }
+    , .{ .diagnostic = &diagnostic }));
+    try testing.expectEqualStrings("enemies", diagnostic.layerName());
+    try testing.expectEqualStrings("Armor.value", diagnostic.propertyName());
+
+    // Collider has no default shape - only the collides property can add one
+    try testing.expectError(error.ComponentNeedsDefaults, Importer.import(testing.allocator, frame,
+        \\{ "height": 1, "tilewidth": 8, "tileheight": 8, "layers": [
+        \\  { "type": "objectgroup", "name": "walls", "objects": [
+        \\    { "x": 0, "y": 0, "properties": [{ "name": "Collider", "type": "bool", "value": true }] }
+        \\  ] }
+        \\] }
+    , .{}));
+
+    try testing.expectError(error.UnsupportedEncoding, Importer.import(testing.allocator, frame,
+        \\{ "height": 1, "tilewidth": 8, "tileheight": 8, "layers": [
+        \\  { "type": "tilelayer", "name": "ground", "width": 1, "encoding": "base64", "data": "AQAAAA==" }
+        \\] }
+    , .{}));
+    try testing.expectError(error.InvalidMap, Importer.import(testing.allocator, frame, "<map/>", .{}));
+}
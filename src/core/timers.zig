@@ -0,0 +1,149 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+const EntityID = ecs.EntityID;
+
+pub const MAX_TIMERS = 8;
+
+/// Timer name, hashed at compile time - `timerId("attack_cooldown")`
+pub const TimerId = u32;
+
+pub fn timerId(comptime name: []const u8) TimerId {
+    return comptime std.hash.Fnv1a_32.hash(name);
+}
+
+/// Whole ticks covering `seconds` at `tick_rate` ticks per second, rounded up
+pub fn ticksFromSeconds(comptime seconds: f64, comptime tick_rate: u32) u32 {
+    return comptime @intFromFloat(@ceil(seconds * @as(f64, @floatFromInt(tick_rate))));
+}
+
+pub const Timer = struct {
+    id: TimerId = 0,
+    /// Ticks until the timer fires
+    remaining: u32 = 0,
+    /// Restart value after firing, 0 for one-shot timers
+    period: u32 = 0,
+    running: bool = false,
+};
+
+/// Per-entity named timers and cooldowns, counted in fixed ticks.
+///
+/// Timers live in the component, so they save, restore and resimulate with the rest of the frame -
+/// never drive gameplay from wall-clock time.
+pub const Timers = struct {
+    slots: [MAX_TIMERS]Timer = [_]Timer{.{}} ** MAX_TIMERS,
+
+    /// Start (or restart) a one-shot timer firing after `ticks` - 0 fires on the next tick, like 1
+    pub fn start(self: *Timers, id: TimerId, ticks: u32) !void {
+        try self.set(id, ticks, 0);
+    }
+
+    /// Start (or restart) a timer firing every `period` ticks
+    pub fn startRepeating(self: *Timers, id: TimerId, period: u32) !void {
+        std.debug.assert(period > 0);
+        try self.set(id, period, period);
+    }
+
+    pub fn cancel(self: *Timers, id: TimerId) void {
+        if (self.find(id)) |timer| timer.running = false;
+    }
+
+    pub fn isRunning(self: *const Timers, id: TimerId) bool {
+        for (self.slots) |timer| {
+            if (timer.running and timer.id == id) return true;
+        }
+        return false;
+    }
+
+    /// Ticks left before the timer fires, 0 when it is not running
+    pub fn remaining(self: *const Timers, id: TimerId) u32 {
+        for (self.slots) |timer| {
+            if (timer.running and timer.id == id) return timer.remaining;
+        }
+        return 0;
+    }
+
+    /// Cooldown check: when `id` is not running, start it for `cooldown` ticks and return true.
+    /// A 0 cooldown still runs until the next tick, so one use per tick gets through.
+    pub fn tryUse(self: *Timers, id: TimerId, cooldown: u32) !bool {
+        if (self.isRunning(id)) return false;
+        try self.start(id, cooldown);
+        return true;
+    }
+
+    fn set(self: *Timers, id: TimerId, ticks: u32, period: u32) !void {
+        const timer = self.find(id) orelse self.freeSlot() orelse return error.TooManyTimers;
+        // The system counts down before checking, so a timer never fires on the tick it started
+        timer.* = .{ .id = id, .remaining = @max(ticks, 1), .period = period, .running = true };
+    }
+
+    fn find(self: *Timers, id: TimerId) ?*Timer {
+        for (&self.slots) |*timer| {
+            if (timer.running and timer.id == id) return timer;
+        }
+        return null;
+    }
+
+    fn freeSlot(self: *Timers) ?*Timer {
+        for (&self.slots) |*timer| {
+            if (!timer.running) return timer;
+        }
+        return null;
+    }
+};
+
+pub const TimerEvent = struct {
+    entity: EntityID,
+    id: TimerId,
+};
+
+/// Advances every Timers component by one tick and reports the ones that fired.
+///
+/// Fired timers are listed in `expired` in entity order (slot order within an entity) and passed
+/// to `on_expire` after all timers have advanced, so callbacks can freely restart timers.
+pub fn TimerSystem(comptime EcsType: type) type {
+    return struct {
+        const Self = @This();
+
+        pub const Callback = *const fn (frame: *EcsType.Frame, event: TimerEvent) void;
+
+        expired: std.ArrayList(TimerEvent),
+        on_expire: ?Callback,
+
+        pub fn init(allocator: std.mem.Allocator, on_expire: ?Callback) Self {
+            return Self{
+                .expired = std.ArrayList(TimerEvent).init(allocator),
+                .on_expire = on_expire,
+            };
+        }
+
+        pub fn deinit(self: *Self) void {
+            self.expired.deinit();
+        }
+
+        pub fn tick(self: *Self, frame: *EcsType.Frame) !void {
+            self.expired.clearRetainingCapacity();
+
+            var query = try frame.query(&.{Timers});
+            while (query.nextFast()) |result| {
+                const timers = result.get(Timers);
+                for (&timers.slots) |*timer| {
+                    if (!timer.running) continue;
+                    timer.remaining -= 1;
+                    if (timer.remaining > 0) continue;
+
+                    try self.expired.append(.{ .entity = result.entity, .id = timer.id });
+                    if (timer.period > 0) {
+                        timer.remaining = timer.period;
+                    } else {
+                        timer.running = false;
+                    }
+                }
+            }
+
+            if (self.on_expire) |callback| {
+                for (self.expired.items) |event| callback(frame, event);
+            }
+        }
+    };
+}
@@ -0,0 +1,118 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const timers = @import("timers.zig");
+
+const Timers = timers.Timers;
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{Timers},
+    .input = TestInput,
+    .max_entities = .small,
+});
+
+const TestTimerSystem = timers.TimerSystem(TestECS);
+
+const SPAWN = timers.timerId("spawn");
+const ATTACK = timers.timerId("attack");
+
+test "One-shot and repeating timers fire on their tick" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestTimerSystem.init(testing.allocator, null);
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const a = try frame.createEntity();
+    const b = try frame.createEntity();
+    try frame.addComponent(a, Timers{});
+    try frame.addComponent(b, Timers{});
+    try frame.getComponent(a, Timers).?.start(SPAWN, 3);
+    try frame.getComponent(b, Timers).?.startRepeating(SPAWN, 2);
+
+    var fired: [6]usize = undefined;
+    for (&fired) |*count| {
+        try system.tick(frame);
+        count.* = system.expired.items.len;
+    }
+    // Tick 2: b, tick 3: a, tick 4: b, tick 6: b
+    try testing.expectEqualSlices(usize, &.{ 0, 1, 1, 1, 0, 1 }, &fired);
+    try testing.expect(!frame.getComponent(a, Timers).?.isRunning(SPAWN));
+    try testing.expectEqual(@as(u32, 2), frame.getComponent(b, Timers).?.remaining(SPAWN));
+}
+
+test "Cooldowns and slot limits" {
+    var cooldowns = Timers{};
+    try testing.expect(try cooldowns.tryUse(ATTACK, 10));
+    try testing.expect(!try cooldowns.tryUse(ATTACK, 10));
+    cooldowns.cancel(ATTACK);
+    try testing.expect(try cooldowns.tryUse(ATTACK, 10));
+
+    // Restarting reuses the slot
+    try cooldowns.start(ATTACK, 5);
+    try testing.expectEqual(@as(u32, 5), cooldowns.remaining(ATTACK));
+
+    var full = Timers{};
+    for (0..timers.MAX_TIMERS) |i| try full.start(@intCast(i + 1), 1);
+    try testing.expectError(error.TooManyTimers, full.start(ATTACK, 1));
+
+    try testing.expectEqual(@as(u32, 30), timers.ticksFromSeconds(0.5, 60));
+}
+
+var callback_log: std.BoundedArray(timers.TimerEvent, 8) = .{};
+
+test "Zero-tick timers and cooldowns expire on the next tick" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestTimerSystem.init(testing.allocator, null);
+    defer system.deinit();
+
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Timers{});
+    const entity_timers = frame.getComponent(entity, Timers).?;
+
+    try entity_timers.start(SPAWN, 0);
+    try testing.expect(try entity_timers.tryUse(ATTACK, 0));
+    try testing.expect(!try entity_timers.tryUse(ATTACK, 0));
+    try testing.expectEqual(@as(u32, 1), entity_timers.remaining(SPAWN));
+
+    try system.tick(frame);
+    try testing.expectEqual(@as(usize, 2), system.expired.items.len);
+    try testing.expect(!entity_timers.isRunning(SPAWN));
+    try testing.expect(try entity_timers.tryUse(ATTACK, 0));
+}
+
+fn restartOnExpire(frame: *TestECS.Frame, event: timers.TimerEvent) void {
+    callback_log.append(event) catch unreachable;
+    frame.getComponent(event.entity, Timers).?.start(event.id, 4) catch unreachable;
+}
+
+test "Callbacks run after the tick and timers rewind with the frame" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+    var system = TestTimerSystem.init(testing.allocator, restartOnExpire);
+    defer system.deinit();
+    callback_log.len = 0;
+
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Timers{});
+    try frame.getComponent(entity, Timers).?.start(ATTACK, 1);
+
+    var saved = try test_ecs.saveFrame(testing.allocator);
+    defer TestECS.freeSavedFrame(&saved);
+
+    try system.tick(frame);
+    try testing.expectEqual(@as(usize, 1), callback_log.len);
+    try testing.expectEqual(ATTACK, callback_log.get(0).id);
+    try testing.expectEqual(@as(u32, 4), frame.getComponent(entity, Timers).?.remaining(ATTACK));
+
+    // Rolling back restores the timer as it was before the tick
+    try test_ecs.restoreFrame(&saved);
+    try testing.expectEqual(@as(u32, 1), frame.getComponent(entity, Timers).?.remaining(ATTACK));
+}
@@ -0,0 +1,292 @@
+const std = @import("std");
+const ecs = @import("ecs.zig");
+
+/// Opt-in recorder for structural changes (entity create/destroy, component add/remove).
+/// Every change is stored with its tick, the system that was running and the call site,
+/// so questions like "who deleted entity 17 on tick 4812?" can be answered after the fact.
+///
+/// Usage:
+///   var trace_log = TraceLog.init(allocator, &GameECS.component_names);
+///   defer trace_log.deinit();
+///   try game_ecs.addObserver(trace_log.observer());
+///
+///   try trace_log.beginSystem("movement");
+///   movementSystem(frame);
+///   trace_log.endSystem();
+pub const TraceLog = struct {
+    allocator: std.mem.Allocator,
+    records: std.ArrayList(Record),
+    system_names: std.ArrayList([]const u8),
+    component_names: []const []const u8,
+    current_tick: u64,
+    current_system: u16,
+    enabled: bool,
+
+    const Self = @This();
+
+    /// File header magic and format version for the binary log
+    pub const MAGIC = [4]u8{ 'R', 'W', 'T', 'R' };
+    pub const VERSION: u16 = 1;
+
+    /// System id used for changes made outside of any named system
+    pub const NO_SYSTEM: u16 = 0;
+    /// Component id used for entity-level changes
+    pub const NO_COMPONENT: u8 = std.math.maxInt(u8);
+
+    /// One structural change - 24 bytes in memory and on disk
+    pub const Record = struct {
+        tick: u64,
+        call_site: u64,
+        entity: ecs.EntityID,
+        system: u16,
+        component: u8,
+        kind: ecs.ChangeKind,
+
+        pub const ENCODED_SIZE = 24;
+    };
+
+    pub fn init(allocator: std.mem.Allocator, component_names: []const []const u8) Self {
+        return Self{
+            .allocator = allocator,
+            .records = std.ArrayList(Record).init(allocator),
+            .system_names = std.ArrayList([]const u8).init(allocator),
+            .component_names = component_names,
+            .current_tick = 0,
+            .current_system = NO_SYSTEM,
+            .enabled = true,
+        };
+    }
+
+    pub fn deinit(self: *Self) void {
+        for (self.system_names.items) |name| {
+            self.allocator.free(name);
+        }
+        self.system_names.deinit();
+        self.records.deinit();
+    }
+
+    /// Observer to attach to an ECS with `addObserver`
+    pub fn observer(self: *Self) ecs.Observer {
+        return .{ .ptr = self, .vtable = &observer_vtable };
+    }
+
+    const observer_vtable = ecs.Observer.VTable{
+        .onTick = onTick,
+        .onChange = onChange,
+    };
+
+    fn onTick(ptr: *anyopaque, tick: u64) void {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        self.current_tick = tick;
+    }
+
+    fn onChange(ptr: *anyopaque, change: ecs.StructuralChange) void {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        if (!self.enabled) return;
+
+        self.records.append(.{
+            .tick = self.current_tick,
+            .call_site = change.call_site,
+            .entity = change.entity,
+            .system = self.current_system,
+            .component = change.component orelse NO_COMPONENT,
+            .kind = change.kind,
+        }) catch {
+            // Tracing must never take the simulation down - stop recording instead
+            std.log.warn("Trace log out of memory after {} records, tracing disabled", .{self.records.items.len});
+            self.enabled = false;
+        };
+    }
+
+    /// Mark the start of a system - subsequent changes are attributed to it
+    pub fn beginSystem(self: *Self, name: []const u8) !void {
+        self.current_system = try self.internSystem(name);
+    }
+
+    /// Mark the end of the current system
+    pub fn endSystem(self: *Self) void {
+        self.current_system = NO_SYSTEM;
+    }
+
+    fn internSystem(self: *Self, name: []const u8) !u16 {
+        for (self.system_names.items, 0..) |existing, i| {
+            if (std.mem.eql(u8, existing, name)) return @intCast(i + 1);
+        }
+        if (self.system_names.items.len >= std.math.maxInt(u16) - 1) return error.TooManySystems;
+
+        const owned = try self.allocator.dupe(u8, name);
+        errdefer self.allocator.free(owned);
+        try self.system_names.append(owned);
+        return @intCast(self.system_names.items.len);
+    }
+
+    /// Name of a recorded system id ("<none>" for changes outside systems)
+    pub fn systemName(self: *const Self, system: u16) []const u8 {
+        if (system == NO_SYSTEM or system > self.system_names.items.len) return "<none>";
+        return self.system_names.items[system - 1];
+    }
+
+    /// Name of a recorded component id ("-" for entity-level changes)
+    pub fn componentName(self: *const Self, component: u8) []const u8 {
+        if (component == NO_COMPONENT or component >= self.component_names.len) return "-";
+        return self.component_names[component];
+    }
+
+    pub fn clear(self: *Self) void {
+        self.records.clearRetainingCapacity();
+    }
+
+    /// Iterate over recorded changes, optionally filtered by entity, kind and tick
+    pub fn find(self: *const Self, filter: Filter) FilterIterator {
+        return .{ .log = self, .filter = filter, .index = 0 };
+    }
+
+    pub const Filter = struct {
+        entity: ?ecs.EntityID = null,
+        kind: ?ecs.ChangeKind = null,
+        tick: ?u64 = null,
+    };
+
+    pub const FilterIterator = struct {
+        log: *const Self,
+        filter: Filter,
+        index: usize,
+
+        pub fn next(self: *FilterIterator) ?Record {
+            while (self.index < self.log.records.items.len) {
+                const record = self.log.records.items[self.index];
+                self.index += 1;
+
+                if (self.filter.entity) |entity| {
+                    if (record.entity != entity) continue;
+                }
+                if (self.filter.kind) |kind| {
+                    if (record.kind != kind) continue;
+                }
+                if (self.filter.tick) |tick| {
+                    if (record.tick != tick) continue;
+                }
+                return record;
+            }
+            return null;
+        }
+    };
+
+    /// Write the compact binary log: header, name tables, then fixed-size records
+    pub fn writeTo(self: *const Self, writer: anytype) !void {
+        try writer.writeAll(&MAGIC);
+        try writer.writeInt(u16, VERSION, .little);
+
+        try writer.writeInt(u8, @intCast(self.component_names.len), .little);
+        for (self.component_names) |name| {
+            try writer.writeInt(u8, @intCast(@min(name.len, std.math.maxInt(u8))), .little);
+            try writer.writeAll(name[0..@min(name.len, std.math.maxInt(u8))]);
+        }
+
+        try writer.writeInt(u16, @intCast(self.system_names.items.len), .little);
+        for (self.system_names.items) |name| {
+            try writer.writeInt(u16, @intCast(@min(name.len, std.math.maxInt(u16))), .little);
+            try writer.writeAll(name[0..@min(name.len, std.math.maxInt(u16))]);
+        }
+
+        try writer.writeInt(u64, self.records.items.len, .little);
+        for (self.records.items) |record| {
+            try writer.writeInt(u64, record.tick, .little);
+            try writer.writeInt(u64, record.call_site, .little);
+            try writer.writeInt(u32, record.entity, .little);
+            try writer.writeInt(u16, record.system, .little);
+            try writer.writeInt(u8, record.component, .little);
+            try writer.writeInt(u8, @intFromEnum(record.kind), .little);
+        }
+    }
+
+    /// A trace log loaded from disk - owns its name tables
+    pub const Loaded = struct {
+        log: Self,
+        component_names: [][]const u8,
+
+        pub fn deinit(self: *Loaded) void {
+            for (self.component_names) |name| {
+                self.log.allocator.free(name);
+            }
+            self.log.allocator.free(self.component_names);
+            self.log.deinit();
+        }
+    };
+
+    /// Read a log previously written with `writeTo`
+    pub fn readFrom(allocator: std.mem.Allocator, reader: anytype) !Loaded {
+        var magic: [4]u8 = undefined;
+        try reader.readNoEof(&magic);
+        if (!std.mem.eql(u8, &magic, &MAGIC)) return error.InvalidTraceLog;
+        const version = try reader.readInt(u16, .little);
+        if (version != VERSION) return error.UnsupportedTraceVersion;
+
+        const component_count = try reader.readInt(u8, .little);
+        const component_names = try allocator.alloc([]const u8, component_count);
+        var names_read: usize = 0;
+        errdefer {
+            for (component_names[0..names_read]) |name| allocator.free(name);
+            allocator.free(component_names);
+        }
+        for (component_names) |*name| {
+            const len = try reader.readInt(u8, .little);
+            const buffer = try allocator.alloc(u8, len);
+            errdefer allocator.free(buffer);
+            try reader.readNoEof(buffer);
+            name.* = buffer;
+            names_read += 1;
+        }
+
+        var log = Self.init(allocator, component_names);
+        errdefer log.deinit();
+
+        const system_count = try reader.readInt(u16, .little);
+        for (0..system_count) |_| {
+            const len = try reader.readInt(u16, .little);
+            const buffer = try allocator.alloc(u8, len);
+            errdefer allocator.free(buffer);
+            try reader.readNoEof(buffer);
+            try log.system_names.append(buffer);
+        }
+
+        // The count is untrusted - records are appended as they are read, so a corrupt count ends
+        // the stream early instead of reserving memory it never fills
+        const record_count = try reader.readInt(u64, .little);
+        for (0..record_count) |_| {
+            const tick = try reader.readInt(u64, .little);
+            const call_site = try reader.readInt(u64, .little);
+            const entity = try reader.readInt(u32, .little);
+            const system = try reader.readInt(u16, .little);
+            const component = try reader.readInt(u8, .little);
+            const kind = std.meta.intToEnum(ecs.ChangeKind, try reader.readInt(u8, .little)) catch return error.InvalidTraceLog;
+            if (system != NO_SYSTEM and system > log.system_names.items.len) return error.InvalidTraceLog;
+            if (component != NO_COMPONENT and component >= component_count) return error.InvalidTraceLog;
+            try log.records.append(.{
+                .tick = tick,
+                .call_site = call_site,
+                .entity = entity,
+                .system = system,
+                .component = component,
+                .kind = kind,
+            });
+        }
+
+        return Loaded{ .log = log, .component_names = component_names };
+    }
+
+    /// Human-readable dump, one line per record
+    pub fn writeText(self: *const Self, writer: anytype, filter: Filter) !void {
+        var iter = self.find(filter);
+        while (iter.next()) |record| {
+            try writer.print("tick {d:>8}  {s:<16}  entity {d:>6}  {s:<20}  system {s:<20}  at 0x{x}\n", .{
+                record.tick,
+                @tagName(record.kind),
+                record.entity,
+                self.componentName(record.component),
+                self.systemName(record.system),
+                record.call_site,
+            });
+        }
+    }
+};
@@ -0,0 +1,150 @@
+const std = @import("std");
+const testing = std.testing;
+const ecs = @import("ecs.zig");
+const TraceLog = @import("trace.zig").TraceLog;
+
+const Position = struct { x: f32, y: f32 };
+const Velocity = struct { x: f32, y: f32 };
+
+const TestInput = struct {
+    value: f32 = 0,
+};
+
+const TestECS = ecs.ECS(.{
+    .components = &.{ Position, Velocity },
+    .input = TestInput,
+    .max_entities = .tiny,
+});
+
+test "Trace log records structural changes with tick and system" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var trace_log = TraceLog.init(testing.allocator, &TestECS.component_names);
+    defer trace_log.deinit();
+    try test_ecs.addObserver(trace_log.observer());
+
+    const frame = test_ecs.getFrame();
+
+    const e1 = try frame.createEntity();
+    try frame.addComponent(e1, Position{ .x = 1, .y = 2 });
+
+    test_ecs.update(.{}, 0.016, 0.016);
+
+    try trace_log.beginSystem("cleanup");
+    _ = frame.removeComponent(e1, Position);
+    frame.destroyEntity(e1);
+    trace_log.endSystem();
+
+    try testing.expectEqual(@as(usize, 4), trace_log.records.items.len);
+
+    const create = trace_log.records.items[0];
+    try testing.expectEqual(ecs.ChangeKind.create_entity, create.kind);
+    try testing.expectEqual(@as(u64, 0), create.tick);
+    try testing.expectEqualStrings("<none>", trace_log.systemName(create.system));
+    try testing.expect(create.call_site != 0);
+
+    const add = trace_log.records.items[1];
+    try testing.expectEqual(ecs.ChangeKind.add_component, add.kind);
+    try testing.expectEqualStrings("Position", trace_log.componentName(add.component));
+
+    // "Who deleted entity e1 on tick 1?"
+    var iter = trace_log.find(.{ .entity = e1, .kind = .destroy_entity, .tick = 1 });
+    const destroy = iter.next().?;
+    try testing.expectEqualStrings("cleanup", trace_log.systemName(destroy.system));
+    try testing.expect(iter.next() == null);
+}
+
+test "Trace log ignores no-op changes" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var trace_log = TraceLog.init(testing.allocator, &TestECS.component_names);
+    defer trace_log.deinit();
+    try test_ecs.addObserver(trace_log.observer());
+
+    const frame = test_ecs.getFrame();
+    const entity = try frame.createEntity();
+    try frame.addComponent(entity, Velocity{ .x = 0, .y = 0 });
+    try frame.addComponent(entity, Velocity{ .x = 1, .y = 1 }); // Already present
+    _ = frame.removeComponent(entity, Position); // Never added
+
+    try testing.expectEqual(@as(usize, 2), trace_log.records.items.len);
+
+    test_ecs.removeObserver(&trace_log);
+    _ = try frame.createEntity();
+    try testing.expectEqual(@as(usize, 2), trace_log.records.items.len);
+}
+
+test "Trace log binary round trip" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var trace_log = TraceLog.init(testing.allocator, &TestECS.component_names);
+    defer trace_log.deinit();
+    try test_ecs.addObserver(trace_log.observer());
+
+    const frame = test_ecs.getFrame();
+    try trace_log.beginSystem("spawner");
+    for (0..5) |_| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Position{ .x = 0, .y = 0 });
+    }
+    trace_log.endSystem();
+
+    var buffer = std.ArrayList(u8).init(testing.allocator);
+    defer buffer.deinit();
+    try trace_log.writeTo(buffer.writer());
+
+    var stream = std.io.fixedBufferStream(buffer.items);
+    var loaded = try TraceLog.readFrom(testing.allocator, stream.reader());
+    defer loaded.deinit();
+
+    try testing.expectEqual(trace_log.records.items.len, loaded.log.records.items.len);
+    for (trace_log.records.items, loaded.log.records.items) |original, decoded| {
+        try testing.expectEqual(original, decoded);
+    }
+    try testing.expectEqualStrings("spawner", loaded.log.systemName(loaded.log.records.items[0].system));
+    try testing.expectEqualStrings("Position", loaded.log.componentName(loaded.log.records.items[1].component));
+}
+
+test "Trace logs with a corrupt count or out-of-range ids are rejected" {
+    var test_ecs = try TestECS.init(testing.allocator);
+    defer test_ecs.deinit();
+
+    var trace_log = TraceLog.init(testing.allocator, &TestECS.component_names);
+    defer trace_log.deinit();
+    try test_ecs.addObserver(trace_log.observer());
+
+    try trace_log.beginSystem("spawner");
+    _ = try test_ecs.getFrame().createEntity();
+    trace_log.endSystem();
+    try testing.expectEqual(@as(usize, 1), trace_log.records.items.len);
+
+    var buffer = std.ArrayList(u8).init(testing.allocator);
+    defer buffer.deinit();
+    try trace_log.writeTo(buffer.writer());
+
+    // The record: tick, call site, entity, system, component, kind
+    const record_size = 8 + 8 + 4 + 2 + 1 + 1;
+    const count_at = buffer.items.len - record_size - 8;
+    const system_at = count_at + 8 + 8 + 8 + 4;
+
+    // A count far past the data runs out of stream instead of reserving memory for it
+    const corrupt = try testing.allocator.dupe(u8, buffer.items);
+    defer testing.allocator.free(corrupt);
+    std.mem.writeInt(u64, corrupt[count_at..][0..8], std.math.maxInt(u64), .little);
+    var stream = std.io.fixedBufferStream(corrupt);
+    try testing.expectError(error.EndOfStream, TraceLog.readFrom(testing.allocator, stream.reader()));
+
+    // Only one system was named
+    @memcpy(corrupt, buffer.items);
+    std.mem.writeInt(u16, corrupt[system_at..][0..2], 2, .little);
+    stream = std.io.fixedBufferStream(corrupt);
+    try testing.expectError(error.InvalidTraceLog, TraceLog.readFrom(testing.allocator, stream.reader()));
+
+    @memcpy(corrupt, buffer.items);
+    corrupt[system_at + 2] = TestECS.component_names.len;
+    stream = std.io.fixedBufferStream(corrupt);
+    try testing.expectError(error.InvalidTraceLog, TraceLog.readFrom(testing.allocator, stream.reader()));
+}
@@ -0,0 +1,458 @@
+const std = @import("std");
+const netcode = @import("netcode.zig");
+
+/// Appended to a client's key before hashing it into the accept value (RFC 6455, 1.3)
+pub const accept_guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11";
+
+pub const Opcode = enum(u4) {
+    continuation = 0x0,
+    text = 0x1,
+    binary = 0x2,
+    close = 0x8,
+    ping = 0x9,
+    pong = 0xA,
+    _,
+};
+
+/// One decoded frame; `payload` points into the decoded bytes
+pub const Frame = struct {
+    fin: bool,
+    opcode: Opcode,
+    masked: bool,
+    payload: []u8,
+    /// Bytes of header and payload the frame took up
+    len: usize,
+};
+
+/// Longest frame header: two bytes, a 64-bit length and a mask
+pub const max_header_size = 14;
+
+/// `Sec-WebSocket-Accept` value a server answers `key` with
+pub fn acceptKey(key: []const u8) [28]u8 {
+    var sha = std.crypto.hash.Sha1.init(.{});
+    sha.update(key);
+    sha.update(accept_guid);
+    var digest: [std.crypto.hash.Sha1.digest_length]u8 = undefined;
+    sha.final(&digest);
+    var accept: [28]u8 = undefined;
+    _ = std.base64.standard.Encoder.encode(&accept, &digest);
+    return accept;
+}
+
+/// A single final frame of `payload` written into `out`. Clients mask every frame they send,
+/// servers never do. Null when `out` is too small.
+pub fn encodeFrame(opcode: Opcode, payload: []const u8, mask: ?[4]u8, out: []u8) ?[]u8 {
+    var header: [max_header_size]u8 = undefined;
+    header[0] = 0x80 | @as(u8, @intFromEnum(opcode));
+    const mask_bit: u8 = if (mask != null) 0x80 else 0;
+    var len: usize = 2;
+    if (payload.len < 126) {
+        header[1] = mask_bit | @as(u8, @intCast(payload.len));
+    } else if (payload.len <= std.math.maxInt(u16)) {
+        header[1] = mask_bit | 126;
+        std.mem.writeInt(u16, header[2..4], @intCast(payload.len), .big);
+        len = 4;
+    } else {
+        header[1] = mask_bit | 127;
+        std.mem.writeInt(u64, header[2..10], payload.len, .big);
+        len = 10;
+    }
+    if (mask) |key| {
+        header[len..][0..4].* = key;
+        len += 4;
+    }
+
+    if (out.len < len + payload.len) return null;
+    @memcpy(out[0..len], header[0..len]);
+    @memcpy(out[len..][0..payload.len], payload);
+    if (mask) |key| applyMask(out[len..][0..payload.len], key);
+    return out[0 .. len + payload.len];
+}
+
+/// The frame at the start of `bytes` with its payload unmasked in place, or null while it hasn't
+/// fully arrived. Payloads longer than a session packet are refused - nothing this transport
+/// carries is bigger, so one is a confused or hostile peer.
+pub fn decodeFrame(bytes: []u8) error{ InvalidFrame, FrameTooLarge }!?Frame {
+    if (bytes.len < 2) return null;
+    // Extensions (and their reserved bits) are never negotiated
+    if (bytes[0] & 0x70 != 0) return error.InvalidFrame;
+
+    const masked = bytes[1] & 0x80 != 0;
+    var len: usize = 2;
+    var payload_len: u64 = bytes[1] & 0x7F;
+    if (payload_len == 126) {
+        if (bytes.len < 4) return null;
+        payload_len = std.mem.readInt(u16, bytes[2..4], .big);
+        len = 4;
+    } else if (payload_len == 127) {
+        if (bytes.len < 10) return null;
+        payload_len = std.mem.readInt(u64, bytes[2..10], .big);
+        len = 10;
+    }
+    if (payload_len > netcode.max_packet_size) return error.FrameTooLarge;
+
+    var mask: [4]u8 = undefined;
+    if (masked) {
+        if (bytes.len < len + 4) return null;
+        mask = bytes[len..][0..4].*;
+        len += 4;
+    }
+    const end = len + @as(usize, @intCast(payload_len));
+    if (bytes.len < end) return null;
+
+    const payload = bytes[len..end];
+    if (masked) applyMask(payload, mask);
+    return .{
+        .fin = bytes[0] & 0x80 != 0,
+        .opcode = @enumFromInt(@as(u4, @truncate(bytes[0]))),
+        .masked = masked,
+        .payload = payload,
+        .len = end,
+    };
+}
+
+fn applyMask(payload: []u8, mask: [4]u8) void {
+    for (payload, 0..) |*byte, i| byte.* ^= mask[i % 4];
+}
+
+/// Value of header `name` (case-insensitive) in an HTTP head, trimmed
+fn headerValue(head: []const u8, name: []const u8) ?[]const u8 {
+    var lines = std.mem.splitSequence(u8, head, "\r\n");
+    _ = lines.next();
+    while (lines.next()) |line| {
+        const colon = std.mem.indexOfScalar(u8, line, ':') orelse continue;
+        if (std.ascii.eqlIgnoreCase(std.mem.trim(u8, line[0..colon], " "), name)) {
+            return std.mem.trim(u8, line[colon + 1 ..], " \t");
+        }
+    }
+    return null;
+}
+
+/// Session transport over WebSocket connections, so browser builds - which can't open UDP
+/// sockets - play in the same rollback and lockstep sessions as native clients.
+///
+/// Every session packet travels as one binary message. A transport `listen`s for players
+/// connecting to `ws://host:port/<their player index>`, `dial`s players that listen, or both; the
+/// browser side is the browser's own `WebSocket` with `binaryType = "arraybuffer"`, sending each
+/// packet with `send` and queueing `onmessage` data for the session's `receive`. Browsers can't
+/// listen, so every browser needs a native peer to connect to - a host for two players, or the
+/// peer each of them talks to directly.
+///
+/// TCP delivers in order and never drops, so the session's resends only cost bandwidth; packets
+/// sent to a player whose connection is still opening or whose send buffer is full are dropped
+/// like a lost datagram.
+///
+/// Usage:
+///   var ws = try WebSocketTransport.init(allocator, 0);
+///   defer ws.deinit();
+///   try ws.listen(try std.net.Address.parseIp4("0.0.0.0", 7000));
+///   var session = try Session(GameECS).init(allocator, &world, ws.transport(), .{ .local_player = 0 });
+pub const WebSocketTransport = struct {
+    const Self = @This();
+
+    const inbound_capacity = 4096;
+    const outbound_capacity = 16 * 1024;
+    const send_flags: u32 = if (@hasDecl(std.posix.MSG, "NOSIGNAL")) std.posix.MSG.NOSIGNAL else 0;
+
+    const State = enum { closed, handshake, open };
+
+    const Connection = struct {
+        socket: std.posix.socket_t = undefined,
+        state: State = .closed,
+        /// Player at the other end - known from the start when dialed, from the request path
+        /// when accepted
+        player: ?u8 = null,
+        /// Opened by `dial`: masks what it sends and expects unmasked frames back
+        dialed: bool = false,
+        expected_accept: [28]u8 = undefined,
+        inbound: [inbound_capacity]u8 = undefined,
+        inbound_len: usize = 0,
+        outbound: [outbound_capacity]u8 = undefined,
+        outbound_len: usize = 0,
+    };
+
+    allocator: std.mem.Allocator,
+    local_player: u8,
+    listener: ?std.posix.socket_t = null,
+    connections: []Connection,
+    /// Connections dropped for breaking the protocol, and packets lost to a missing or
+    /// backed-up connection
+    invalid_connections: u64 = 0,
+    dropped_packets: u64 = 0,
+
+    pub fn init(allocator: std.mem.Allocator, local_player: u8) !Self {
+        const connections = try allocator.alloc(Connection, netcode.max_players);
+        for (connections) |*connection| connection.* = .{};
+        return .{ .allocator = allocator, .local_player = local_player, .connections = connections };
+    }
+
+    pub fn deinit(self: *Self) void {
+        for (self.connections) |*connection| {
+            if (connection.state != .closed) std.posix.close(connection.socket);
+        }
+        if (self.listener) |listener| std.posix.close(listener);
+        self.allocator.free(self.connections);
+    }
+
+    /// Accept players connecting to `address` (port 0 picks a free one, see `localAddress`)
+    pub fn listen(self: *Self, address: std.net.Address) !void {
+        if (self.listener != null) return error.AlreadyListening;
+        const socket = try std.posix.socket(
+            address.any.family,
+            std.posix.SOCK.STREAM | std.posix.SOCK.NONBLOCK | std.posix.SOCK.CLOEXEC,
+            std.posix.IPPROTO.TCP,
+        );
+        errdefer std.posix.close(socket);
+        try std.posix.setsockopt(socket, std.posix.SOL.SOCKET, std.posix.SO.REUSEADDR, &std.mem.toBytes(@as(c_int, 1)));
+        try std.posix.bind(socket, &address.any, address.getOsSockLen());
+        try std.posix.listen(socket, netcode.max_players);
+        self.listener = socket;
+    }
+
+    /// Address the listening socket is bound to
+    pub fn localAddress(self: *const Self) !std.net.Address {
+        const listener = self.listener orelse return error.NotListening;
+        var address: std.net.Address = undefined;
+        var len: std.posix.socklen_t = @sizeOf(std.net.Address);
+        try std.posix.getsockname(listener, &address.any, &len);
+        return address;
+    }
+
+    /// Connect to `player` listening at `address`. Returns once the connection is underway;
+    /// packets flow after `receive` completed the handshake (see `connected`).
+    pub fn dial(self: *Self, address: std.net.Address, player: u8) !void {
+        if (self.find(player) != null) return error.AlreadyConnected;
+        const connection = self.freeConnection() orelse return error.TooManyConnections;
+        const socket = try std.posix.socket(
+            address.any.family,
+            std.posix.SOCK.STREAM | std.posix.SOCK.NONBLOCK | std.posix.SOCK.CLOEXEC,
+            std.posix.IPPROTO.TCP,
+        );
+        errdefer std.posix.close(socket);
+        std.posix.connect(socket, &address.any, address.getOsSockLen()) catch |err| switch (err) {
+            error.WouldBlock => {},
+            else => return err,
+        };
+
+        var nonce: [16]u8 = undefined;
+        std.crypto.random.bytes(&nonce);
+        var key: [24]u8 = undefined;
+        _ = std.base64.standard.Encoder.encode(&key, &nonce);
+
+        connection.* = .{
+            .socket = socket,
+            .state = .handshake,
+            .player = player,
+            .dialed = true,
+            .expected_accept = acceptKey(&key),
+        };
+        const request = std.fmt.bufPrint(&connection.outbound, "GET /{d} HTTP/1.1\r\nHost: {}\r\n" ++
+            "Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: {s}\r\nSec-WebSocket-Version: 13\r\n\r\n", .{
+            self.local_player,
+            address,
+            key,
+        }) catch unreachable;
+        connection.outbound_len = request.len;
+        // Sends once the TCP handshake finished, on this or a later `receive`
+        self.flush(connection);
+    }
+
+    /// Whether packets to `player` go anywhere yet
+    pub fn connected(self: *Self, player: u8) bool {
+        const connection = self.find(player) orelse return false;
+        return connection.state == .open;
+    }
+
+    pub fn transport(self: *Self) netcode.Transport {
+        return .{ .ptr = self, .vtable = &.{ .send = send, .receive = receive } };
+    }
+
+    fn send(ptr: *anyopaque, player: u8, bytes: []const u8) void {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        const connection = self.find(player) orelse {
+            self.dropped_packets += 1;
+            return;
+        };
+        if (connection.state != .open or !self.queue(connection, .binary, bytes)) {
+            self.dropped_packets += 1;
+            return;
+        }
+        self.flush(connection);
+    }
+
+    fn receive(ptr: *anyopaque, buffer: []u8) ?[]u8 {
+        const self: *Self = @ptrCast(@alignCast(ptr));
+        self.acceptPending();
+        for (self.connections) |*connection| {
+            if (connection.state == .closed) continue;
+            self.flush(connection);
+            self.fill(connection);
+            if (connection.state == .handshake) self.handshake(connection);
+            if (connection.state == .open) {
+                if (self.nextMessage(connection, buffer)) |message| return message;
+            }
+        }
+        return null;
+    }
+
+    // Frames of an open connection until a binary message turned up; control frames are
+    // answered on the way
+    fn nextMessage(self: *Self, connection: *Connection, buffer: []u8) ?[]u8 {
+        while (connection.state == .open) {
+            const frame = (decodeFrame(connection.inbound[0..connection.inbound_len]) catch {
+                self.reject(connection);
+                return null;
+            }) orelse return null;
+            // Browsers mask every frame, servers none; session packets are never fragmented
+            if (frame.masked == connection.dialed or !frame.fin) {
+                self.reject(connection);
+                return null;
+            }
+
+            switch (frame.opcode) {
+                .binary => {
+                    if (frame.payload.len > buffer.len) {
+                        self.reject(connection);
+                        return null;
+                    }
+                    const message = buffer[0..frame.payload.len];
+                    @memcpy(message, frame.payload);
+                    consume(connection, frame.len);
+                    return message;
+                },
+                .ping => {
+                    _ = self.queue(connection, .pong, frame.payload);
+                    consume(connection, frame.len);
+                    self.flush(connection);
+                },
+                .pong => consume(connection, frame.len),
+                .close => {
+                    _ = self.queue(connection, .close, &.{});
+                    self.flush(connection);
+                    self.close(connection);
+                },
+                else => self.reject(connection),
+            }
+        }
+        return null;
+    }
+
+    fn handshake(self: *Self, connection: *Connection) void {
+        const received = connection.inbound[0..connection.inbound_len];
+        const end = std.mem.indexOf(u8, received, "\r\n\r\n") orelse {
+            if (connection.inbound_len == inbound_capacity) self.reject(connection);
+            return;
+        };
+        const head = received[0..end];
+
+        if (connection.dialed) {
+            const accept = headerValue(head, "Sec-WebSocket-Accept") orelse "";
+            if (!std.mem.startsWith(u8, head, "HTTP/1.1 101") or !std.mem.eql(u8, accept, &connection.expected_accept)) {
+                return self.reject(connection);
+            }
+        } else {
+            const player = requestedPlayer(head) orelse return self.reject(connection);
+            if (player == self.local_player or self.find(player) != null) return self.reject(connection);
+            const key = headerValue(head, "Sec-WebSocket-Key") orelse return self.reject(connection);
+            const response = std.fmt.bufPrint(connection.outbound[connection.outbound_len..], "HTTP/1.1 101 Switching Protocols\r\n" ++
+                "Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: {s}\r\n\r\n", .{&acceptKey(key)}) catch return self.reject(connection);
+            connection.outbound_len += response.len;
+            connection.player = player;
+            self.flush(connection);
+            if (connection.state == .closed) return;
+        }
+        consume(connection, end + 4);
+        connection.state = .open;
+    }
+
+    // Player index in "GET /3 HTTP/1.1"
+    fn requestedPlayer(head: []const u8) ?u8 {
+        var parts = std.mem.splitScalar(u8, head[0 .. std.mem.indexOf(u8, head, "\r\n") orelse head.len], ' ');
+        if (!std.mem.eql(u8, parts.next() orelse return null, "GET")) return null;
+        const target = parts.next() orelse return null;
+        if (target.len < 2 or target[0] != '/') return null;
+        const player = std.fmt.parseInt(u8, target[1..], 10) catch return null;
+        return if (player < netcode.max_players) player else null;
+    }
+
+    fn acceptPending(self: *Self) void {
+        const listener = self.listener orelse return;
+        while (true) {
+            const socket = std.posix.accept(listener, null, null, std.posix.SOCK.NONBLOCK | std.posix.SOCK.CLOEXEC) catch return;
+            const connection = self.freeConnection() orelse {
+                std.posix.close(socket);
+                continue;
+            };
+            connection.* = .{ .socket = socket, .state = .handshake };
+        }
+    }
+
+    // Read whatever arrived; a closed or failed socket closes the connection
+    fn fill(self: *Self, connection: *Connection) void {
+        while (connection.inbound_len < inbound_capacity) {
+            const len = std.posix.recv(connection.socket, connection.inbound[connection.inbound_len..], 0) catch |err| switch (err) {
+                error.WouldBlock => return,
+                else => return self.close(connection),
+            };
+            if (len == 0) return self.close(connection);
+            connection.inbound_len += len;
+        }
+    }
+
+    // Write as much of the send buffer as the socket takes
+    fn flush(self: *Self, connection: *Connection) void {
+        while (connection.outbound_len > 0) {
+            const len = std.posix.send(connection.socket, connection.outbound[0..connection.outbound_len], send_flags) catch |err| switch (err) {
+                error.WouldBlock => return,
+                else => return self.close(connection),
+            };
+            std.mem.copyForwards(u8, connection.outbound[0 .. connection.outbound_len - len], connection.outbound[len..connection.outbound_len]);
+            connection.outbound_len -= len;
+        }
+    }
+
+    // Frame `payload` into the send buffer; false when it doesn't fit
+    fn queue(_: *Self, connection: *Connection, opcode: Opcode, payload: []const u8) bool {
+        var mask: ?[4]u8 = null;
+        if (connection.dialed) {
+            var key: [4]u8 = undefined;
+            std.crypto.random.bytes(&key);
+            mask = key;
+        }
+        const frame = encodeFrame(opcode, payload, mask, connection.outbound[connection.outbound_len..]) orelse return false;
+        connection.outbound_len += frame.len;
+        return true;
+    }
+
+    fn consume(connection: *Connection, len: usize) void {
+        std.mem.copyForwards(u8, connection.inbound[0 .. connection.inbound_len - len], connection.inbound[len..connection.inbound_len]);
+        connection.inbound_len -= len;
+    }
+
+    fn reject(self: *Self, connection: *Connection) void {
+        self.invalid_connections += 1;
+        self.close(connection);
+    }
+
+    fn close(_: *Self, connection: *Connection) void {
+        std.posix.close(connection.socket);
+        connection.* = .{};
+    }
+
+    fn find(self: *Self, player: u8) ?*Connection {
+        for (self.connections) |*connection| {
+            if (connection.state == .closed) continue;
+            if (connection.player) |other| {
+                if (other == player) return connection;
+            }
+        }
+        return null;
+    }
+
+    fn freeConnection(self: *Self) ?*Connection {
+        for (self.connections) |*connection| {
+            if (connection.state == .closed) return connection;
+        }
+        return null;
+    }
+};
@@ -0,0 +1,101 @@
+const std = @import("std");
+const testing = std.testing;
+const netcode = @import("netcode.zig");
+const websocket = @import("websocket.zig");
+
+const WebSocketTransport = websocket.WebSocketTransport;
+
+test "Accept keys follow the RFC 6455 example" {
+    try testing.expectEqualStrings("s3pPLMBiTxaQ9kZG2Ho5FDJsHR0=", &websocket.acceptKey("dGhlIHNhbXBsZSBub25jZQ=="));
+}
+
+test "Frames round-trip masked and unmasked and wait for their last byte" {
+    var out: [netcode.max_packet_size + websocket.max_header_size]u8 = undefined;
+
+    // What a browser sends: masked, short length
+    const masked = websocket.encodeFrame(.binary, "tick 42", .{ 1, 2, 3, 4 }, &out).?;
+    try testing.expectEqual(@as(usize, 2 + 4 + 7), masked.len);
+    try testing.expect(!std.mem.eql(u8, masked[6..], "tick 42"));
+    const frame = (try websocket.decodeFrame(masked)).?;
+    try testing.expect(frame.fin and frame.masked);
+    try testing.expectEqual(websocket.Opcode.binary, frame.opcode);
+    try testing.expectEqualStrings("tick 42", frame.payload);
+    try testing.expectEqual(masked.len, frame.len);
+
+    // A full session packet needs the 16-bit length
+    var packet: [netcode.max_packet_size]u8 = undefined;
+    for (&packet, 0..) |*byte, i| byte.* = @truncate(i);
+    const plain = websocket.encodeFrame(.binary, &packet, null, &out).?;
+    try testing.expectEqual(@as(u8, 126), plain[1]);
+    try testing.expectEqual(@as(?websocket.Frame, null), try websocket.decodeFrame(plain[0 .. plain.len - 1]));
+    try testing.expectEqualSlices(u8, &packet, (try websocket.decodeFrame(plain)).?.payload);
+    try testing.expectEqual(@as(?[]u8, null), websocket.encodeFrame(.binary, &packet, null, out[0..100]));
+
+    var oversized = [_]u8{ 0x82, 127, 0, 0, 0, 0, 0, 1, 0, 0 };
+    try testing.expectError(error.FrameTooLarge, websocket.decodeFrame(&oversized));
+    var extension = [_]u8{ 0xC2, 0 };
+    try testing.expectError(error.InvalidFrame, websocket.decodeFrame(&extension));
+}
+
+// Receive on both ends until `into` got a packet
+fn exchange(into: *WebSocketTransport, other: *WebSocketTransport, buffer: []u8) ?[]u8 {
+    for (0..200) |_| {
+        if (into.transport().receive(buffer)) |message| return message;
+        _ = other.transport().receive(buffer);
+        std.time.sleep(std.time.ns_per_ms);
+    }
+    return null;
+}
+
+test "WebSocket transports connect and carry packets both ways" {
+    var host = try WebSocketTransport.init(testing.allocator, 0);
+    defer host.deinit();
+    try host.listen(try std.net.Address.parseIp4("127.0.0.1", 0));
+    var guest = try WebSocketTransport.init(testing.allocator, 1);
+    defer guest.deinit();
+    try guest.dial(try host.localAddress(), 0);
+
+    var buffer: [netcode.max_packet_size]u8 = undefined;
+    for (0..200) |_| {
+        _ = host.transport().receive(&buffer);
+        _ = guest.transport().receive(&buffer);
+        if (host.connected(1) and guest.connected(0)) break;
+        std.time.sleep(std.time.ns_per_ms);
+    }
+    try testing.expect(host.connected(1) and guest.connected(0));
+
+    guest.transport().send(0, "input 7");
+    try testing.expectEqualStrings("input 7", exchange(&host, &guest, &buffer).?);
+    host.transport().send(1, "confirm 7");
+    try testing.expectEqualStrings("confirm 7", exchange(&guest, &host, &buffer).?);
+
+    // Nobody is player 3
+    host.transport().send(3, "lost");
+    try testing.expectEqual(@as(u64, 1), host.dropped_packets);
+    try testing.expectEqual(@as(u64, 0), host.invalid_connections);
+}
+
+test "Listeners turn away requests that name no free player" {
+    var host = try WebSocketTransport.init(testing.allocator, 0);
+    defer host.deinit();
+    try host.listen(try std.net.Address.parseIp4("127.0.0.1", 0));
+
+    // Player 9 doesn't exist, player 0 is the host itself
+    for ([_][]const u8{ "/9", "/0" }) |path| {
+        const stream = try std.net.tcpConnectToAddress(try host.localAddress());
+        defer stream.close();
+        try stream.writer().print("GET {s} HTTP/1.1\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n", .{path});
+
+        var buffer: [netcode.max_packet_size]u8 = undefined;
+        const before = host.invalid_connections;
+        for (0..200) |_| {
+            _ = host.transport().receive(&buffer);
+            if (host.invalid_connections > before) break;
+            std.time.sleep(std.time.ns_per_ms);
+        }
+        try testing.expectEqual(before + 1, host.invalid_connections);
+        // The socket was closed without an upgrade
+        var response: [64]u8 = undefined;
+        try testing.expectEqual(@as(usize, 0), try stream.read(&response));
+    }
+}
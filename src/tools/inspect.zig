@@ -0,0 +1,131 @@
+const std = @import("std");
+const core = @import("rewind-core");
+
+// Reference world used by the inspector - the same component set as the perf tests.
+// Games get numbers for their own world by calling `core.MemoryReport(GameECS).collect`.
+const Transform = struct {
+    x: f32,
+    y: f32,
+    rotation: f32,
+};
+
+const Velocity = struct {
+    dx: f32,
+    dy: f32,
+    angular: f32,
+};
+
+const Health = struct {
+    current: i32,
+    max: i32,
+};
+
+const InspectInput = struct {
+    deltaTime: f32 = 0.016,
+};
+
+const InspectECS = core.ECS(.{
+    .components = &.{ Transform, Velocity, Health },
+    .input = InspectInput,
+    .max_entities = .large,
+});
+
+// 60 frame window, 64KB max per frame - matches the rollback perf test
+const InspectRollback = core.NetcodeRollback(InspectECS, 60, 64 * 1024);
+
+const usage =
+    \\Usage: rewind-inspect <command> [options]
+    \\
+    \\Commands:
+    \\  mem [--entities N]   Memory table for the reference world with N entities (default 1000)
+    \\  schema               Component schemas of the reference world
+    \\  mirror ts|cs [NS]    TypeScript or C# (namespace NS, default Rewind.Mirror) mirrors of the components
+    \\
+;
+
+pub fn main() !void {
+    var gpa = std.heap.GeneralPurposeAllocator(.{}){};
+    defer _ = gpa.deinit();
+    const allocator = gpa.allocator();
+
+    const args = try std.process.argsAlloc(allocator);
+    defer std.process.argsFree(allocator, args);
+
+    const stdout = std.io.getStdOut().writer();
+    const stderr = std.io.getStdErr().writer();
+
+    if (args.len < 2) {
+        try stderr.writeAll(usage);
+        std.process.exit(2);
+    }
+
+    const command = args[1];
+    if (std.mem.eql(u8, command, "mem")) {
+        try memCommand(allocator, args[2..], stdout);
+    } else if (std.mem.eql(u8, command, "schema")) {
+        try core.schema.Registry(InspectECS).writeSchema(stdout);
+    } else if (std.mem.eql(u8, command, "mirror")) {
+        try mirrorCommand(args[2..], stdout);
+    } else if (std.mem.eql(u8, command, "help") or std.mem.eql(u8, command, "--help")) {
+        try stdout.writeAll(usage);
+    } else {
+        try stderr.print("Unknown command '{s}'\n\n", .{command});
+        try stderr.writeAll(usage);
+        std.process.exit(2);
+    }
+}
+
+fn memCommand(allocator: std.mem.Allocator, args: []const []const u8, writer: anytype) !void {
+    var entity_count: u32 = 1000;
+
+    var i: usize = 0;
+    while (i < args.len) : (i += 1) {
+        if (std.mem.eql(u8, args[i], "--entities") and i + 1 < args.len) {
+            i += 1;
+            entity_count = try std.fmt.parseInt(u32, args[i], 10);
+        } else {
+            std.log.err("Unknown mem option '{s}'", .{args[i]});
+            return error.InvalidArgument;
+        }
+    }
+
+    var world = try InspectECS.init(allocator);
+    defer world.deinit();
+
+    const frame = world.getFrame();
+    for (0..entity_count) |n| {
+        const entity = try frame.createEntity();
+        try frame.addComponent(entity, Transform{ .x = 0, .y = 0, .rotation = 0 });
+        if (n % 2 == 0) try frame.addComponent(entity, Velocity{ .dx = 1, .dy = 0, .angular = 0 });
+        if (n % 10 == 0) try frame.addComponent(entity, Health{ .current = 100, .max = 100 });
+    }
+
+    // History buffer is several MB - keep it off the stack
+    const rollback = try allocator.create(InspectRollback);
+    defer allocator.destroy(rollback);
+    rollback.* = InspectRollback.init();
+    try rollback.saveFrame(&world);
+
+    var report = core.MemoryReport(InspectECS).collect(&world);
+    report.addHistory(rollback);
+
+    try writer.print("Reference world: {d} entities, {d} component types\n\n", .{ entity_count, InspectECS.component_names.len });
+    try report.writeTable(writer);
+}
+
+fn mirrorCommand(args: []const []const u8, writer: anytype) !void {
+    const Mirror = core.mirror.Mirror(InspectECS);
+    if (args.len == 0) {
+        std.log.err("mirror needs a language: ts or cs", .{});
+        return error.InvalidArgument;
+    }
+
+    if (std.mem.eql(u8, args[0], "ts")) {
+        try Mirror.writeTypeScript(writer);
+    } else if (std.mem.eql(u8, args[0], "cs")) {
+        try Mirror.writeCSharp(writer, if (args.len > 1) args[1] else "Rewind.Mirror");
+    } else {
+        std.log.err("Unknown mirror language '{s}'", .{args[0]});
+        return error.InvalidArgument;
+    }
+}
@@ -0,0 +1,270 @@
+const std = @import("std");
+const core = @import("rewind-core");
+
+const FP = core.FP;
+const fp = core.fp;
+const FPVector2 = core.FPVector2;
+const Transform = core.components.Transform;
+const Velocity = core.components.Velocity;
+const Movement = core.components.Movement;
+
+// Reference scenario both implementations run. The peer receives the initial entities and every
+// tick's input over the protocol, so only `step` has to be ported - state setup and input
+// generation stay on this side.
+const Sim = struct {
+    const Input = struct {
+        /// Bit 0..3: thrust right, left, up, down
+        buttons: u32 = 0,
+    };
+
+    const ECS = core.ECS(.{
+        .components = &.{ Transform, Velocity, Movement },
+        .input = Input,
+        .max_entities = .medium,
+    });
+
+    const Schema = core.schema.Registry(ECS);
+    const dt = fp(1.0 / 60.0);
+
+    fn populate(frame: *ECS.Frame, seed: u64, count: u32) !void {
+        var state = seed;
+        for (0..count) |_| {
+            const entity = try frame.createEntity();
+            try frame.addComponent(entity, Transform{ .position = FPVector2.new(randomFP(&state, 100), randomFP(&state, 100)) });
+            try frame.addComponent(entity, Velocity{ .linear = FPVector2.new(randomFP(&state, 5), randomFP(&state, 5)) });
+            try frame.addComponent(entity, Movement{ .drag = fp(0.25), .max_speed = fp(12) });
+        }
+    }
+
+    fn inputFor(seed: u64, tick: u64) Input {
+        var state = seed ^ (tick *% 0x9E3779B97F4A7C15);
+        return .{ .buttons = @truncate(splitmix(&state) & 0xF) };
+    }
+
+    /// The code under comparison - ports implement exactly this
+    fn step(frame: *ECS.Frame) !void {
+        const buttons = frame.input.buttons;
+        const thrust = FPVector2.new(
+            FP.fromInt(@as(i32, @intCast(buttons & 1)) - @as(i32, @intCast((buttons >> 1) & 1))),
+            FP.fromInt(@as(i32, @intCast((buttons >> 2) & 1)) - @as(i32, @intCast((buttons >> 3) & 1))),
+        ).mul(fp(8));
+
+        var query = try frame.query(&.{Movement});
+        while (query.nextFast()) |result| result.get(Movement).acceleration = thrust;
+        try core.movement.MovementSystem(ECS).step(frame, dt);
+    }
+
+    fn splitmix(state: *u64) u64 {
+        state.* +%= 0x9E3779B97F4A7C15;
+        var z = state.*;
+        z = (z ^ (z >> 30)) *% 0xBF58476D1CE4E5B9;
+        z = (z ^ (z >> 27)) *% 0x94D049BB133111EB;
+        return z ^ (z >> 31);
+    }
+
+    // Uniform in [-range, range)
+    fn randomFP(state: *u64, comptime range: i64) FP {
+        const span: u64 = 2 * range * FP.ONE_RAW;
+        return FP.fromRaw(@as(i64, @intCast(splitmix(state) % span)) - range * FP.ONE_RAW);
+    }
+};
+
+const usage =
+    \\Usage: rewind-parity [options] -- <peer command...>
+    \\       rewind-parity --serve
+    \\
+    \\Runs the reference scenario here and in a peer implementation (for example the C# port) and
+    \\compares the state hash after every tick. Exits 0 when every tick matches, 1 on the first
+    \\divergence (printing the tick and this side's entities), 2 on usage or protocol errors.
+    \\
+    \\Options:
+    \\  --seed N       Scenario seed (default 1)
+    \\  --ticks N      Ticks to compare (default 600)
+    \\  --entities N   Entities in the scenario (default 64)
+    \\  --serve        Act as the peer, speaking the protocol on stdin/stdout
+    \\
+    \\Protocol - one line per message, hex is lowercase:
+    \\  > begin COUNT             reset to an empty world
+    \\  > entity ID HEX           encodeEntity record, ids arrive in ascending order
+    \\  > tick N HEX              apply the encoded input, run one step
+    \\  < hash N HEX              16 hex digits of the frame hash after tick N
+    \\  > end
+    \\
+    \\The frame hash is FNV-1a 64 over [u32 id][encodeEntity record] for each live entity in
+    \\ascending id order (see `schema.Registry.hashFrame`).
+    \\
+;
+
+const Options = struct {
+    seed: u64 = 1,
+    ticks: u64 = 600,
+    entities: u32 = 64,
+    serve: bool = false,
+    peer: []const []const u8 = &.{},
+};
+
+pub fn main() !void {
+    var gpa = std.heap.GeneralPurposeAllocator(.{}){};
+    defer _ = gpa.deinit();
+    const allocator = gpa.allocator();
+
+    const args = try std.process.argsAlloc(allocator);
+    defer std.process.argsFree(allocator, args);
+
+    const stderr = std.io.getStdErr().writer();
+    const options = parseOptions(args[1..]) catch |err| {
+        if (err != error.Help) try stderr.print("Invalid arguments: {s}\n\n", .{@errorName(err)});
+        try stderr.writeAll(usage);
+        std.process.exit(if (err == error.Help) 0 else 2);
+    };
+
+    const matched = run(allocator, options) catch |err| {
+        try stderr.print("Parity run failed: {s}\n", .{@errorName(err)});
+        std.process.exit(2);
+    };
+    if (!matched) std.process.exit(1);
+}
+
+fn run(allocator: std.mem.Allocator, options: Options) !bool {
+    if (options.serve) return serve(allocator, std.io.getStdIn().reader(), std.io.getStdOut().writer());
+    return compare(allocator, options, std.io.getStdOut().writer());
+}
+
+fn parseOptions(args: []const []const u8) !Options {
+    var options = Options{};
+    var i: usize = 0;
+    while (i < args.len) : (i += 1) {
+        const arg = args[i];
+        if (std.mem.eql(u8, arg, "--help") or std.mem.eql(u8, arg, "help")) return error.Help;
+        if (std.mem.eql(u8, arg, "--serve")) {
+            options.serve = true;
+            continue;
+        }
+        if (std.mem.eql(u8, arg, "--")) {
+            options.peer = args[i + 1 ..];
+            break;
+        }
+        if (i + 1 >= args.len) return error.MissingValue;
+        i += 1;
+        const value = args[i];
+
+        if (std.mem.eql(u8, arg, "--seed")) {
+            options.seed = try std.fmt.parseInt(u64, value, 10);
+        } else if (std.mem.eql(u8, arg, "--ticks")) {
+            options.ticks = try std.fmt.parseInt(u64, value, 10);
+        } else if (std.mem.eql(u8, arg, "--entities")) {
+            options.entities = try std.fmt.parseInt(u32, value, 10);
+        } else {
+            return error.UnknownOption;
+        }
+    }
+    if (!options.serve and options.peer.len == 0) return error.MissingPeerCommand;
+    return options;
+}
+
+/// Drive the peer through the scenario. True when every tick's hash matched.
+fn compare(allocator: std.mem.Allocator, options: Options, out: anytype) !bool {
+    var peer = std.process.Child.init(options.peer, allocator);
+    peer.stdin_behavior = .Pipe;
+    peer.stdout_behavior = .Pipe;
+    try peer.spawn();
+    defer _ = peer.kill() catch {};
+
+    var world = try Sim.ECS.init(allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+    try Sim.populate(frame, options.seed, options.entities);
+
+    var to_peer = std.io.bufferedWriter(peer.stdin.?.writer());
+    const send = to_peer.writer();
+    var from_peer = std.io.bufferedReader(peer.stdout.?.reader());
+    var record: [1024]u8 = undefined;
+    var line: [4096]u8 = undefined;
+
+    try send.print("begin {d}\n", .{frame.getEntityCount()});
+    var entities = frame.state.active_entities.fastIterator();
+    while (entities.next()) |entity| {
+        var stream = std.io.fixedBufferStream(&record);
+        try Sim.Schema.encodeEntity(frame, entity, stream.writer());
+        try send.print("entity {d} {s}\n", .{ entity, std.fmt.fmtSliceHexLower(stream.getWritten()) });
+    }
+
+    for (1..options.ticks + 1) |tick| {
+        const input = Sim.inputFor(options.seed, tick);
+        var stream = std.io.fixedBufferStream(&record);
+        try core.schema.encodeValue(Sim.Input, input, stream.writer());
+        try send.print("tick {d} {s}\n", .{ tick, std.fmt.fmtSliceHexLower(stream.getWritten()) });
+        try to_peer.flush();
+
+        world.update(input, Sim.dt.toFloat(f32), 0);
+        try Sim.step(frame);
+        const local = Sim.Schema.hashFrame(frame);
+
+        const reply = (try from_peer.reader().readUntilDelimiterOrEof(&line, '\n')) orelse return error.PeerClosed;
+        var fields = std.mem.tokenizeScalar(u8, std.mem.trimRight(u8, reply, "\r"), ' ');
+        if (!std.mem.eql(u8, fields.next() orelse "", "hash")) return error.UnexpectedReply;
+        const peer_tick = try std.fmt.parseInt(u64, fields.next() orelse return error.UnexpectedReply, 10);
+        const remote = try std.fmt.parseInt(u64, fields.next() orelse return error.UnexpectedReply, 16);
+        if (peer_tick != tick) return error.UnexpectedReply;
+
+        if (local != remote) {
+            try out.print("Diverged at tick {d}: local {x:0>16}, peer {x:0>16}\n", .{ tick, local, remote });
+            try out.writeAll("Local entities:\n");
+            var dump = frame.state.active_entities.fastIterator();
+            while (dump.next()) |entity| {
+                try out.print("  {d}: ", .{entity});
+                try Sim.Schema.writeEntityJson(frame, entity, out);
+                try out.writeByte('\n');
+            }
+            return false;
+        }
+    }
+
+    try send.writeAll("end\n");
+    try to_peer.flush();
+    try out.print("{d} ticks matched (seed {d}, {d} entities)\n", .{ options.ticks, options.seed, options.entities });
+    return true;
+}
+
+/// Reference peer: the Zig implementation answering the protocol, for checking the harness and
+/// as the executable specification for ports
+fn serve(allocator: std.mem.Allocator, in: anytype, out: anytype) !bool {
+    var world = try Sim.ECS.init(allocator);
+    defer world.deinit();
+
+    var input_reader = std.io.bufferedReader(in);
+    var line: [4096]u8 = undefined;
+    var bytes: [1024]u8 = undefined;
+
+    while (try input_reader.reader().readUntilDelimiterOrEof(&line, '\n')) |raw| {
+        var fields = std.mem.tokenizeScalar(u8, std.mem.trimRight(u8, raw, "\r"), ' ');
+        const message = fields.next() orelse continue;
+
+        if (std.mem.eql(u8, message, "begin")) {
+            const fresh = try Sim.ECS.init(allocator);
+            world.deinit();
+            world = fresh;
+        } else if (std.mem.eql(u8, message, "entity")) {
+            const id = try std.fmt.parseInt(core.EntityID, fields.next() orelse return error.MalformedMessage, 10);
+            const record = try std.fmt.hexToBytes(&bytes, fields.next() orelse return error.MalformedMessage);
+            const frame = world.getFrame();
+            if (try frame.createEntity() != id) return error.EntityIdMismatch;
+            var stream = std.io.fixedBufferStream(record);
+            try Sim.Schema.decodeEntity(frame, id, stream.reader());
+        } else if (std.mem.eql(u8, message, "tick")) {
+            const tick = try std.fmt.parseInt(u64, fields.next() orelse return error.MalformedMessage, 10);
+            const encoded = try std.fmt.hexToBytes(&bytes, fields.next() orelse return error.MalformedMessage);
+            var stream = std.io.fixedBufferStream(encoded);
+            const input = try core.schema.decodeValue(Sim.Input, stream.reader());
+
+            world.update(input, Sim.dt.toFloat(f32), 0);
+            try Sim.step(world.getFrame());
+            try out.print("hash {d} {x:0>16}\n", .{ tick, Sim.Schema.hashFrame(world.getFrame()) });
+        } else if (std.mem.eql(u8, message, "end")) {
+            return true;
+        } else {
+            return error.MalformedMessage;
+        }
+    }
+    return true;
+}
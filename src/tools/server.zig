@@ -0,0 +1,809 @@
+const std = @import("std");
+const core = @import("rewind-core");
+
+const FP = core.FP;
+const fp = core.fp;
+const FPVector2 = core.FPVector2;
+const Transform = core.components.Transform;
+const Velocity = core.components.Velocity;
+const Movement = core.components.Movement;
+
+// The game module the server hosts. Games run their own server by swapping this namespace for
+// theirs - the ECS, the input type, scene setup and the per-tick step are all the host needs.
+const Game = struct {
+    /// One player's controls
+    const Pad = struct {
+        buttons: u32 = 0,
+    };
+
+    /// Player 0 is the server itself and never presses anything - clients play the rest
+    const players = 2;
+    const Input = [players]Pad;
+
+    const ECS = core.ECS(.{
+        .components = &.{ Transform, Velocity, Movement },
+        .input = Input,
+        .max_entities = .large,
+    });
+
+    /// Reference scene: `count` drifting bodies with a little drag
+    fn setup(frame: *ECS.Frame, count: u32) !void {
+        for (0..count) |n| {
+            const i: i32 = @intCast(n);
+            const entity = try frame.createEntity();
+            try frame.addComponent(entity, Transform{ .position = FPVector2.new(FP.fromInt(@mod(i, 32)), FP.fromInt(@divTrunc(i, 32))) });
+            try frame.addComponent(entity, Velocity{ .linear = FPVector2.new(FP.fromInt(@mod(i, 5) - 2), FP.fromInt(@mod(i, 3) - 1)) });
+            try frame.addComponent(entity, Movement{ .drag = fp(0.1) });
+        }
+    }
+
+    fn step(frame: *ECS.Frame, dt: FP) !void {
+        try core.movement.MovementSystem(ECS).step(frame, dt);
+    }
+
+    /// `step` in the shape a session runs systems in
+    const Systems = struct {
+        dt: FP,
+
+        pub fn run(self: Systems, frame: *ECS.Frame) !void {
+            try step(frame, self.dt);
+        }
+    };
+};
+
+const usage =
+    \\Usage: rewind-server [options]
+    \\
+    \\Runs the game simulation headless at a fixed tick and serves operational endpoints over HTTP.
+    \\
+    \\Options:
+    \\  --port N         Endpoint port (default 7777)
+    \\  --bind IP        Endpoint address (default 127.0.0.1). Admin routes are only served here
+    \\                   while it is a loopback address.
+    \\  --admin-bind IP:PORT
+    \\                   Also serve admin routes at IP:PORT, to requests carrying
+    \\                   "Authorization: Bearer TOKEN" with the token from $REWIND_ADMIN_TOKEN
+    \\  --snapshot-dir PATH
+    \\                   Where admin snapshots are written (default: working directory)
+    \\  --tick-rate N    Ticks per second (default 60)
+    \\  --ticks N        Stop after N ticks (default 0 = run until killed)
+    \\  --entities N     Reference scene size (default 1000)
+    \\  --replay PATH    Record every player's input applied on each tick to PATH
+    \\  --export PATH    Re-simulate a recorded replay and write every tick's entities to stdout
+    \\                   as CSV (tick, entity, one column per component field), then exit
+    \\  --config PATH    Take port and tick rate from the netcode section of a JSON config file,
+    \\                   replacing --port and --tick-rate
+    \\  --scenario NAME  Take entities and ticks from a scenario of the config file
+    \\  --transport KIND Host a lockstep session for clients over udp or websocket (default none:
+    \\                   every tick applies idle input)
+    \\  --session-port N Port clients connect to (default 7778)
+    \\  --peer P=IP:PORT UDP address of player P, once per client
+    \\  --client-timeout N
+    \\                   Seconds to wait on a client's input before stopping (default 60)
+    \\
+    \\Endpoints:
+    \\  /status          Tick, entity count and tick timing as JSON
+    \\  /metrics         Prometheus text format
+    \\  /metrics.json    expvar-style JSON
+    \\  /inspect/mem     Memory table
+    \\  /inspect/schema  Component schemas
+    \\
+    \\Admin (JSON):
+    \\  GET  /admin/entities                      Live entity ids
+    \\  GET  /admin/entity/ID                     Components and fields of one entity
+    \\  GET  /admin/query?components=A,B          Entities with every listed component
+    \\  POST /admin/pause | /admin/resume         Stop or restart the tick
+    \\  POST /admin/snapshot                      Write snapshot-TICK.bin to the snapshot directory
+    \\
+;
+
+/// How clients reach the session
+const TransportKind = enum {
+    none,
+    udp,
+    websocket,
+};
+
+const Options = struct {
+    port: u16 = 7777,
+    bind: []const u8 = "127.0.0.1",
+    admin_bind: ?std.net.Address = null,
+    admin_token: ?[]const u8 = null,
+    snapshot_dir: []const u8 = ".",
+    tick_rate: u32 = 60,
+    ticks: u64 = 0,
+    entities: u32 = 1000,
+    replay_path: ?[]const u8 = null,
+    export_path: ?[]const u8 = null,
+    config_path: ?[]const u8 = null,
+    scenario: ?[]const u8 = null,
+    transport: TransportKind = .none,
+    session_port: u16 = 7778,
+    peers: [core.netcode.max_players]?std.net.Address = [_]?std.net.Address{null} ** core.netcode.max_players,
+    client_timeout: u64 = 60,
+
+    /// Overlay the netcode settings and the chosen scenario of a loaded config
+    fn applyConfig(self: *Options, loaded: core.config.Config) !void {
+        self.port = loaded.netcode.port;
+        self.tick_rate = loaded.netcode.tick_rate;
+        if (self.scenario) |name| {
+            const scenario = loaded.scenario(name) orelse return error.UnknownScenario;
+            self.entities = scenario.entities;
+            self.ticks = scenario.ticks;
+        }
+    }
+};
+
+/// Replay file: magic, version, tick rate and scene size, then one record per tick - the frame
+/// number followed by the input encoded with `schema.encodeValue`
+const REPLAY_MAGIC = "RWRP";
+const REPLAY_VERSION: u16 = 3;
+
+/// Snapshot file: magic and tick, entity count, then each entity id followed by its
+/// `schema.Registry.encodeEntity` record
+const SNAPSHOT_MAGIC = "RWSN";
+
+const Schema = core.schema.Registry(Game.ECS);
+
+/// Endpoint requests served at once; further connections wait in the listen backlog
+const MAX_REQUESTS = 16;
+/// Largest request head, and how long a client may take to send it and read the response
+const REQUEST_CAPACITY = 4096;
+const REQUEST_TIMEOUT_NS = 5 * std.time.ns_per_s;
+
+/// Admin commands that can wait for the next tick before requests are refused
+const COMMAND_CAPACITY = 64;
+
+/// Admin mutations. Requests only enqueue them; the tick loop applies them between ticks, so
+/// the simulation is never changed halfway through a step.
+///
+/// They go through a lock-free inbox so endpoints can move off the tick thread without a mutex
+/// the frame would have to take.
+const Command = enum {
+    pause,
+    resume_ticks,
+    snapshot,
+};
+
+const Session = core.LockstepSession(Game.ECS);
+
+/// Socket the session talks to clients through
+const Link = union(TransportKind) {
+    none,
+    udp: core.netcode.UdpTransport,
+    websocket: core.WebSocketTransport,
+
+    fn open(allocator: std.mem.Allocator, options: Options) !Link {
+        // Clients play from other machines - the session port listens on every interface
+        const address = try std.net.Address.parseIp("0.0.0.0", options.session_port);
+        switch (options.transport) {
+            .none => return .none,
+            .udp => {
+                // UDP has no connections to learn client addresses from
+                for (1..Game.players) |player| {
+                    if (options.peers[player] == null) return error.MissingPeer;
+                }
+                var udp = try core.netcode.UdpTransport.open(address);
+                for (options.peers, 0..) |peer, player| {
+                    if (peer) |peer_address| udp.setPeer(@intCast(player), peer_address);
+                }
+                return .{ .udp = udp };
+            },
+            .websocket => {
+                var websocket = try core.WebSocketTransport.init(allocator, 0);
+                errdefer websocket.deinit();
+                try websocket.listen(address);
+                return .{ .websocket = websocket };
+            },
+        }
+    }
+
+    fn close(self: *Link) void {
+        switch (self.*) {
+            .none => {},
+            .udp => |*udp| udp.close(),
+            .websocket => |*websocket| websocket.deinit(),
+        }
+    }
+
+    fn transport(self: *Link) core.netcode.Transport {
+        return switch (self.*) {
+            .none => unreachable,
+            .udp => |*udp| udp.transport(),
+            .websocket => |*websocket| websocket.transport(),
+        };
+    }
+};
+
+/// An endpoint connection: the request head is read and the response written as far as the
+/// socket takes them on each poll, so a slow client never holds up a tick
+const Request = struct {
+    socket: std.posix.socket_t,
+    /// Came in through the admin listener, where every request needs the token
+    admin: bool,
+    opened_ns: u64,
+    head: [REQUEST_CAPACITY]u8 = undefined,
+    head_len: usize = 0,
+    response: std.ArrayListUnmanaged(u8) = .{},
+    sent: usize = 0,
+};
+
+// With a transport the server is player 0 of a lockstep session - a tick runs once every client's
+// input for it arrived, so nothing simulated is ever rolled back and the replay records the
+// inputs exactly as clients sent them. Without one every tick applies idle input.
+const Server = struct {
+    allocator: std.mem.Allocator,
+    world: Game.ECS,
+    world_metrics: core.metrics.WorldMetrics(Game.ECS),
+    registry: core.metrics.Registry,
+    listener: std.net.Server,
+    /// Answers admin routes on `listener` - it only listens on this machine
+    local_admin: bool,
+    admin_listener: ?std.net.Server,
+    admin_token: ?[]const u8,
+    snapshot_dir: std.fs.Dir,
+    requests: std.ArrayListUnmanaged(Request),
+    link: Link,
+    session: ?Session,
+    replay: ?std.fs.File,
+    tick_rate: u32,
+    dt: FP,
+    clock: core.Clock,
+    pacer: core.TickPacer,
+    last_tick_ns: u64,
+    paused: bool,
+    commands: core.CommandInbox(Command),
+
+    fn init(self: *Server, allocator: std.mem.Allocator, options: Options) !void {
+        const address = try std.net.Address.parseIp(options.bind, options.port);
+        self.* = .{
+            .allocator = allocator,
+            .world = try Game.ECS.init(allocator),
+            .world_metrics = undefined,
+            .registry = core.metrics.Registry.init(allocator),
+            .listener = undefined,
+            .local_admin = isLoopback(address),
+            .admin_listener = null,
+            .admin_token = options.admin_token,
+            .snapshot_dir = undefined,
+            .requests = .{},
+            .link = .none,
+            .session = null,
+            .replay = null,
+            .tick_rate = options.tick_rate,
+            .dt = FP.div(fp(1), FP.fromInt(options.tick_rate)),
+            .clock = core.Clock.real,
+            .pacer = core.TickPacer.init(core.Clock.real, options.tick_rate),
+            .last_tick_ns = 0,
+            .paused = false,
+            .commands = undefined,
+        };
+        errdefer self.world.deinit();
+        errdefer self.registry.deinit();
+        self.commands = try core.CommandInbox(Command).init(allocator, COMMAND_CAPACITY);
+        errdefer self.commands.deinit();
+
+        self.world_metrics = core.metrics.WorldMetrics(Game.ECS).init(allocator, &self.world);
+        errdefer self.world_metrics.deinit();
+        try self.registry.register(self.world_metrics.collector());
+
+        try Game.setup(self.world.getFrame(), options.entities);
+
+        // Endpoints are polled between ticks - accept must never block the simulation
+        self.listener = try address.listen(.{ .reuse_address = true, .force_nonblocking = true });
+        errdefer self.listener.deinit();
+        if (options.admin_bind) |admin_address| {
+            if (options.admin_token == null) return error.MissingAdminToken;
+            self.admin_listener = try admin_address.listen(.{ .reuse_address = true, .force_nonblocking = true });
+        }
+        errdefer if (self.admin_listener) |*admin_listener| admin_listener.deinit();
+        self.snapshot_dir = try std.fs.cwd().makeOpenPath(options.snapshot_dir, .{});
+        errdefer self.snapshot_dir.close();
+        try self.requests.ensureTotalCapacity(allocator, MAX_REQUESTS);
+        errdefer self.requests.deinit(allocator);
+
+        self.link = try Link.open(allocator, options);
+        errdefer self.link.close();
+        if (self.link != .none) {
+            var session = try Session.init(&self.world, self.link.transport(), .{
+                .local_player = 0,
+                .delta_time = self.dt.toFloat(f32),
+                .timeout_ns = options.client_timeout * std.time.ns_per_s,
+            });
+            session.logger = core.Logger.std_log;
+            self.session = session;
+        }
+
+        if (options.replay_path) |path| {
+            const file = try std.fs.cwd().createFile(path, .{});
+            errdefer file.close();
+            const writer = file.writer();
+            try writer.writeAll(REPLAY_MAGIC);
+            try writer.writeInt(u16, REPLAY_VERSION, .little);
+            try writer.writeInt(u32, options.tick_rate, .little);
+            try writer.writeInt(u32, options.entities, .little);
+            self.replay = file;
+        }
+    }
+
+    fn deinit(self: *Server) void {
+        if (self.replay) |file| file.close();
+        self.commands.deinit();
+        self.link.close();
+        for (self.requests.items) |*request| self.closeRequest(request);
+        self.requests.deinit(self.allocator);
+        self.snapshot_dir.close();
+        if (self.admin_listener) |*admin_listener| admin_listener.deinit();
+        self.listener.deinit();
+        self.registry.deinit();
+        self.world_metrics.deinit();
+        self.world.deinit();
+    }
+
+    fn run(self: *Server, max_ticks: u64) !void {
+        self.pacer.restart();
+        while (max_ticks == 0 or self.world.current_frame.frame_number < max_ticks) {
+            self.serveEndpoints();
+            if (try self.applyCommands()) self.pacer.restart();
+
+            if (self.paused) {
+                self.clock.sleep(std.time.ns_per_ms);
+                continue;
+            }
+            if (self.pacer.poll(std.time.ns_per_ms)) try self.tick();
+        }
+    }
+
+    /// Apply queued admin commands. True when the tick resumed and its clock needs resetting.
+    fn applyCommands(self: *Server) !bool {
+        var resumed = false;
+        while (self.commands.pop()) |command| {
+            switch (command) {
+                .pause => self.paused = true,
+                .resume_ticks => {
+                    resumed = self.paused;
+                    self.paused = false;
+                },
+                .snapshot => self.writeSnapshot() catch |err| {
+                    std.log.err("Snapshot failed: {s}", .{@errorName(err)});
+                },
+            }
+        }
+        return resumed;
+    }
+
+    fn writeSnapshot(self: *Server) !void {
+        const frame = self.world.getFrame();
+        var name_buffer: [64]u8 = undefined;
+        const name = try std.fmt.bufPrint(&name_buffer, "snapshot-{d}.bin", .{frame.frame_number});
+
+        const file = try self.snapshot_dir.createFile(name, .{});
+        defer file.close();
+        var buffered = std.io.bufferedWriter(file.writer());
+        const writer = buffered.writer();
+
+        try writer.writeAll(SNAPSHOT_MAGIC);
+        try writer.writeInt(u64, frame.frame_number, .little);
+        try writer.writeInt(u32, frame.getEntityCount(), .little);
+        var entities = frame.state.active_entities.fastIterator();
+        while (entities.next()) |entity| {
+            try writer.writeInt(u32, entity, .little);
+            try Schema.encodeEntity(frame, entity, writer);
+        }
+        try buffered.flush();
+        std.log.info("Wrote {s}", .{name});
+    }
+
+    /// Run the next tick - with a session, once every client's input for it is in
+    fn tick(self: *Server) !void {
+        const started = self.clock.now();
+
+        const systems = Game.Systems{ .dt = self.dt };
+        if (self.session) |*session| {
+            // The server's own pad stays idle
+            if (!try session.advance(.{}, systems)) return;
+        } else {
+            self.world.update([_]Game.Pad{.{}} ** Game.players, self.dt.toFloat(f32), 0);
+            try systems.run(self.world.getFrame());
+        }
+
+        const frame = self.world.getFrame();
+        if (self.replay) |file| {
+            var buffered = std.io.bufferedWriter(file.writer());
+            try buffered.writer().writeInt(u64, frame.frame_number, .little);
+            try core.schema.encodeValue(Game.Input, frame.input, buffered.writer());
+            try buffered.flush();
+        }
+
+        self.last_tick_ns = self.clock.since(started);
+        try self.world_metrics.recordSystemTime("game", self.last_tick_ns);
+    }
+
+    fn serveEndpoints(self: *Server) void {
+        self.acceptRequests(self.listener, false);
+        if (self.admin_listener) |admin_listener| self.acceptRequests(admin_listener, true);
+
+        var i = self.requests.items.len;
+        while (i > 0) {
+            i -= 1;
+            const request = &self.requests.items[i];
+            const done = self.progress(request) catch |err| failed: {
+                std.log.warn("Endpoint request failed: {s}", .{@errorName(err)});
+                break :failed true;
+            };
+            if (done or self.clock.since(request.opened_ns) > REQUEST_TIMEOUT_NS) {
+                self.closeRequest(request);
+                _ = self.requests.swapRemove(i);
+            }
+        }
+    }
+
+    fn acceptRequests(self: *Server, listener: std.net.Server, admin: bool) void {
+        // Accepted sockets don't inherit the listener's non-blocking flag - ask for it
+        while (self.requests.items.len < MAX_REQUESTS) {
+            const socket = std.posix.accept(listener.stream.handle, null, null, std.posix.SOCK.NONBLOCK | std.posix.SOCK.CLOEXEC) catch |err| switch (err) {
+                error.WouldBlock => return,
+                else => {
+                    std.log.warn("Accept failed: {s}", .{@errorName(err)});
+                    return;
+                },
+            };
+            self.requests.appendAssumeCapacity(.{ .socket = socket, .admin = admin, .opened_ns = self.clock.now() });
+        }
+    }
+
+    fn closeRequest(self: *Server, request: *Request) void {
+        std.posix.close(request.socket);
+        request.response.deinit(self.allocator);
+    }
+
+    /// Read and answer as much of a request as the socket allows. True once it is finished with.
+    fn progress(self: *Server, request: *Request) !bool {
+        if (request.response.items.len == 0) {
+            const len = std.posix.read(request.socket, request.head[request.head_len..]) catch |err| switch (err) {
+                error.WouldBlock => return false,
+                else => return err,
+            };
+            if (len == 0) return true;
+            request.head_len += len;
+
+            const head = request.head[0..request.head_len];
+            if (std.mem.indexOf(u8, head, "\r\n\r\n") == null) {
+                if (request.head_len < request.head.len) return false;
+                try self.respond(request, "431 Request Header Fields Too Large", "text/plain", "");
+            } else {
+                try self.handleRequest(request, head);
+            }
+        }
+
+        while (request.sent < request.response.items.len) {
+            request.sent += std.posix.write(request.socket, request.response.items[request.sent..]) catch |err| switch (err) {
+                error.WouldBlock => return false,
+                else => return err,
+            };
+        }
+        return true;
+    }
+
+    fn respond(self: *Server, request: *Request, status: []const u8, content_type: []const u8, body: []const u8) !void {
+        const writer = request.response.writer(self.allocator);
+        try writer.print("HTTP/1.1 {s}\r\nContent-Type: {s}\r\nContent-Length: {d}\r\nConnection: close\r\n\r\n", .{
+            status,
+            content_type,
+            body.len,
+        });
+        try writer.writeAll(body);
+    }
+
+    fn handleRequest(self: *Server, request: *Request, head: []const u8) !void {
+        var parts = std.mem.tokenizeScalar(u8, std.mem.sliceTo(head, '\r'), ' ');
+        const method = parts.next() orelse return error.BadRequest;
+        const path = parts.next() orelse return error.BadRequest;
+
+        var body = std.ArrayList(u8).init(self.allocator);
+        defer body.deinit();
+        const writer = body.writer();
+
+        var status: []const u8 = "200 OK";
+        var content_type: []const u8 = "text/plain; version=0.0.4";
+        if (request.admin) {
+            // The admin listener answers nothing else, and nothing without the token
+            content_type = "application/json";
+            if (!self.authorized(head)) {
+                status = "401 Unauthorized";
+            } else if (!std.mem.startsWith(u8, path, "/admin/")) {
+                status = "404 Not Found";
+            } else {
+                status = try self.handleAdmin(method, path["/admin/".len..], writer);
+            }
+        } else if (std.mem.startsWith(u8, path, "/admin/")) {
+            content_type = "application/json";
+            status = if (self.local_admin) try self.handleAdmin(method, path["/admin/".len..], writer) else "403 Forbidden";
+        } else if (!std.mem.eql(u8, method, "GET")) {
+            status = "405 Method Not Allowed";
+        } else if (std.mem.eql(u8, path, "/status")) {
+            content_type = "application/json";
+            try writer.print("{{\"tick\":{d},\"entities\":{d},\"tick_rate\":{d},\"last_tick_ns\":{d},\"overruns\":{d},\"paused\":{}", .{
+                self.world.current_frame.frame_number,
+                self.world.current_frame.getEntityCount(),
+                self.tick_rate,
+                self.last_tick_ns,
+                self.pacer.overruns,
+                self.paused,
+            });
+            if (self.session) |*session| {
+                try writer.print(",\"session\":{{\"transport\":\"{s}\",\"waiting_on\":{?d},\"stalls\":{d},\"invalid_packets\":{d},\"desyncs\":{d}}}", .{
+                    @tagName(self.link),
+                    session.waitingOn(),
+                    session.stalls,
+                    session.invalid_packets,
+                    session.desyncs,
+                });
+            }
+            try writer.writeByte('}');
+        } else if (std.mem.eql(u8, path, "/metrics")) {
+            try self.registry.writePrometheus(writer);
+        } else if (std.mem.eql(u8, path, "/metrics.json")) {
+            content_type = "application/json";
+            try self.registry.writeJson(writer);
+        } else if (std.mem.eql(u8, path, "/inspect/mem")) {
+            const report = core.MemoryReport(Game.ECS).collect(&self.world);
+            try report.writeTable(writer);
+        } else if (std.mem.eql(u8, path, "/inspect/schema")) {
+            try Schema.writeSchema(writer);
+        } else {
+            status = "404 Not Found";
+        }
+
+        try self.respond(request, status, content_type, body.items);
+    }
+
+    /// Whether the request head carries the admin bearer token
+    fn authorized(self: *Server, head: []const u8) bool {
+        const token = self.admin_token orelse return false;
+        var lines = std.mem.splitSequence(u8, head, "\r\n");
+        _ = lines.next();
+        while (lines.next()) |line| {
+            const colon = std.mem.indexOfScalar(u8, line, ':') orelse continue;
+            if (!std.ascii.eqlIgnoreCase(line[0..colon], "authorization")) continue;
+            const value = std.mem.trim(u8, line[colon + 1 ..], " \t");
+            if (!std.mem.startsWith(u8, value, "Bearer ")) return false;
+            const given = value["Bearer ".len..];
+            // Compare every byte so the time taken says nothing about the token
+            if (given.len != token.len) return false;
+            var difference: u8 = 0;
+            for (given, token) |a, b| difference |= a ^ b;
+            return difference == 0;
+        }
+        return false;
+    }
+
+    /// Admin routes - reads are answered from the current frame, mutations are queued as commands
+    fn handleAdmin(self: *Server, method: []const u8, route: []const u8, writer: anytype) ![]const u8 {
+        const frame = self.world.getFrame();
+
+        if (std.mem.eql(u8, method, "POST")) {
+            const command: Command = if (std.mem.eql(u8, route, "pause"))
+                .pause
+            else if (std.mem.eql(u8, route, "resume"))
+                .resume_ticks
+            else if (std.mem.eql(u8, route, "snapshot"))
+                .snapshot
+            else
+                return "404 Not Found";
+            self.commands.push(command) catch return "503 Service Unavailable";
+            try writer.print("{{\"queued\":\"{s}\"}}", .{route});
+            return "202 Accepted";
+        }
+        if (!std.mem.eql(u8, method, "GET")) return "405 Method Not Allowed";
+
+        if (std.mem.eql(u8, route, "entities")) {
+            try writeEntityList(frame, 0, writer);
+        } else if (std.mem.startsWith(u8, route, "entity/")) {
+            const entity = std.fmt.parseInt(core.EntityID, route["entity/".len..], 10) catch return "400 Bad Request";
+            if (entity >= Game.ECS.max_entities or !frame.state.active_entities.isSet(entity)) return "404 Not Found";
+            try Schema.writeEntityJson(frame, entity, writer);
+        } else if (std.mem.startsWith(u8, route, "query?components=")) {
+            var mask: u64 = 0;
+            var names = std.mem.tokenizeScalar(u8, route["query?components=".len..], ',');
+            while (names.next()) |name| {
+                const index = Schema.componentIndex(name) orelse return "400 Bad Request";
+                mask |= @as(u64, 1) << @intCast(index);
+            }
+            try writeEntityList(frame, mask, writer);
+        } else {
+            return "404 Not Found";
+        }
+        return "200 OK";
+    }
+};
+
+/// Replay a recording from the scene it started with and write the state after every tick as
+/// CSV - tick 0 is the scene before the first step
+fn exportReplay(allocator: std.mem.Allocator, path: []const u8, writer: anytype) !void {
+    const file = try std.fs.cwd().openFile(path, .{});
+    defer file.close();
+    var buffered = std.io.bufferedReader(file.reader());
+    const reader = buffered.reader();
+
+    var magic: [REPLAY_MAGIC.len]u8 = undefined;
+    try reader.readNoEof(&magic);
+    if (!std.mem.eql(u8, &magic, REPLAY_MAGIC)) return error.NotAReplay;
+    if (try reader.readInt(u16, .little) != REPLAY_VERSION) return error.UnsupportedReplayVersion;
+    const tick_rate = try reader.readInt(u32, .little);
+    if (tick_rate == 0) return error.InvalidTickRate;
+    const entities = try reader.readInt(u32, .little);
+    const dt = FP.div(fp(1), FP.fromInt(tick_rate));
+
+    const world = try allocator.create(Game.ECS);
+    defer allocator.destroy(world);
+    world.* = try Game.ECS.init(allocator);
+    defer world.deinit();
+    const frame = world.getFrame();
+    try Game.setup(frame, entities);
+
+    try Schema.writeCsvHeader(writer);
+    while (true) {
+        var live = frame.state.active_entities.fastIterator();
+        while (live.next()) |entity| try Schema.writeCsvRow(frame, frame.frame_number, entity, writer);
+
+        const tick = reader.readInt(u64, .little) catch |err| switch (err) {
+            error.EndOfStream => break,
+            else => return err,
+        };
+        const input = try core.schema.decodeValue(Game.Input, reader);
+        world.update(input, dt.toFloat(f32), 0);
+        try Game.step(frame, dt);
+        if (frame.frame_number != tick) return error.ReplayOutOfSequence;
+    }
+}
+
+fn isLoopback(address: std.net.Address) bool {
+    return switch (address.any.family) {
+        std.posix.AF.INET => std.mem.asBytes(&address.in.sa.addr)[0] == 127,
+        std.posix.AF.INET6 => std.mem.eql(u8, &address.in6.sa.addr, &([_]u8{0} ** 15 ++ [_]u8{1})),
+        else => false,
+    };
+}
+
+/// JSON array of live entities having every component in `mask`
+fn writeEntityList(frame: *Game.ECS.Frame, mask: u64, writer: anytype) !void {
+    try writer.writeByte('[');
+    var first = true;
+    var entities = frame.state.active_entities.fastIterator();
+    entity_loop: while (entities.next()) |entity| {
+        for (0..Schema.components.len) |i| {
+            if (mask & (@as(u64, 1) << @intCast(i)) != 0 and !Schema.hasComponent(frame, entity, i)) continue :entity_loop;
+        }
+        if (!first) try writer.writeByte(',');
+        first = false;
+        try writer.print("{d}", .{entity});
+    }
+    try writer.writeByte(']');
+}
+
+pub fn main() !void {
+    var gpa = std.heap.GeneralPurposeAllocator(.{}){};
+    defer _ = gpa.deinit();
+    const allocator = gpa.allocator();
+
+    const args = try std.process.argsAlloc(allocator);
+    defer std.process.argsFree(allocator, args);
+
+    var options = parseOptions(args[1..]) catch |err| {
+        const stderr = std.io.getStdErr().writer();
+        if (err != error.Help) try stderr.print("Invalid arguments: {s}\n\n", .{@errorName(err)});
+        try stderr.writeAll(usage);
+        std.process.exit(if (err == error.Help) 0 else 2);
+    };
+
+    var loaded: ?std.json.Parsed(core.config.Config) = null;
+    defer if (loaded) |parsed| parsed.deinit();
+    if (options.config_path) |path| {
+        var diagnostic = core.config.Diagnostic{};
+        loaded = core.config.load(allocator, std.fs.cwd(), path, &diagnostic) catch |err| {
+            const reason = if (err == error.InvalidConfig) diagnostic.message() else @errorName(err);
+            std.log.err("Config {s}:{d}:{d}: {s}", .{ path, diagnostic.line, diagnostic.column, reason });
+            std.process.exit(2);
+        };
+        options.applyConfig(loaded.?.value) catch |err| {
+            std.log.err("Config {s}: {s}", .{ path, @errorName(err) });
+            std.process.exit(2);
+        };
+    } else if (options.scenario != null) {
+        std.log.err("--scenario needs --config", .{});
+        std.process.exit(2);
+    }
+
+    const admin_token = std.process.getEnvVarOwned(allocator, "REWIND_ADMIN_TOKEN") catch |err| switch (err) {
+        error.EnvironmentVariableNotFound => null,
+        else => return err,
+    };
+    defer if (admin_token) |token| allocator.free(token);
+    if (options.admin_bind != null) {
+        if (admin_token == null or admin_token.?.len == 0) {
+            std.log.err("--admin-bind needs a bearer token in REWIND_ADMIN_TOKEN", .{});
+            std.process.exit(2);
+        }
+        options.admin_token = admin_token;
+    }
+
+    if (options.export_path) |path| {
+        var stdout = std.io.bufferedWriter(std.io.getStdOut().writer());
+        try exportReplay(allocator, path, stdout.writer());
+        try stdout.flush();
+        return;
+    }
+
+    // World storage is sized for the entity limit - keep it off the stack
+    const server = try allocator.create(Server);
+    defer allocator.destroy(server);
+    try server.init(allocator, options);
+    defer server.deinit();
+
+    std.log.info("Serving {d} entities at {d} ticks/s, endpoints on {s}:{d}", .{ options.entities, options.tick_rate, options.bind, options.port });
+    if (options.admin_bind) |address| std.log.info("Admin routes on {} (bearer token required)", .{address});
+    if (options.transport != .none) {
+        std.log.info("Waiting for {d} client(s) over {s} on :{d}", .{ Game.players - 1, @tagName(options.transport), options.session_port });
+    }
+    try server.run(options.ticks);
+}
+
+fn parseOptions(args: []const []const u8) !Options {
+    var options = Options{};
+    var i: usize = 0;
+    while (i < args.len) : (i += 1) {
+        const arg = args[i];
+        if (std.mem.eql(u8, arg, "--help") or std.mem.eql(u8, arg, "help")) return error.Help;
+        if (i + 1 >= args.len) return error.MissingValue;
+        i += 1;
+        const value = args[i];
+
+        if (std.mem.eql(u8, arg, "--port")) {
+            options.port = try std.fmt.parseInt(u16, value, 10);
+        } else if (std.mem.eql(u8, arg, "--tick-rate")) {
+            options.tick_rate = try std.fmt.parseInt(u32, value, 10);
+            if (options.tick_rate == 0) return error.InvalidTickRate;
+        } else if (std.mem.eql(u8, arg, "--ticks")) {
+            options.ticks = try std.fmt.parseInt(u64, value, 10);
+        } else if (std.mem.eql(u8, arg, "--entities")) {
+            options.entities = try std.fmt.parseInt(u32, value, 10);
+        } else if (std.mem.eql(u8, arg, "--replay")) {
+            options.replay_path = value;
+        } else if (std.mem.eql(u8, arg, "--export")) {
+            options.export_path = value;
+        } else if (std.mem.eql(u8, arg, "--config")) {
+            options.config_path = value;
+        } else if (std.mem.eql(u8, arg, "--scenario")) {
+            options.scenario = value;
+        } else if (std.mem.eql(u8, arg, "--transport")) {
+            options.transport = std.meta.stringToEnum(TransportKind, value) orelse return error.UnknownTransport;
+        } else if (std.mem.eql(u8, arg, "--session-port")) {
+            options.session_port = try std.fmt.parseInt(u16, value, 10);
+        } else if (std.mem.eql(u8, arg, "--peer")) {
+            const split = std.mem.indexOfScalar(u8, value, '=') orelse return error.InvalidPeer;
+            const player = try std.fmt.parseInt(u8, value[0..split], 10);
+            if (player == 0 or player >= Game.players) return error.InvalidPeer;
+            options.peers[player] = try parseEndpoint(value[split + 1 ..]);
+        } else if (std.mem.eql(u8, arg, "--bind")) {
+            _ = try std.net.Address.parseIp(value, 0);
+            options.bind = value;
+        } else if (std.mem.eql(u8, arg, "--admin-bind")) {
+            options.admin_bind = try parseEndpoint(value);
+        } else if (std.mem.eql(u8, arg, "--snapshot-dir")) {
+            options.snapshot_dir = value;
+        } else if (std.mem.eql(u8, arg, "--client-timeout")) {
+            options.client_timeout = try std.fmt.parseInt(u64, value, 10);
+        } else {
+            return error.UnknownOption;
+        }
+    }
+    return options;
+}
+
+/// IP:PORT
+fn parseEndpoint(value: []const u8) !std.net.Address {
+    const colon = std.mem.lastIndexOfScalar(u8, value, ':') orelse return error.MissingPort;
+    const port = try std.fmt.parseInt(u16, value[colon + 1 ..], 10);
+    return std.net.Address.parseIp(value[0..colon], port);
+}